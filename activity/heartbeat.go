@@ -0,0 +1,52 @@
+package activity
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/activity"
+)
+
+// ErrHeartbeatTimeout fails an execution of an activity that did not call RecordHeartbeat within its heartbeat
+// timeout. Workflows see it as an activity failure with the same message, and retry the activity according to
+// its retry options.
+var ErrHeartbeatTimeout = activity.ErrHeartbeatTimeout
+
+// RecordHeartbeat reports that the activity is making progress. Activities scheduled with a heartbeat timeout,
+// see workflow.ActivityOptions.HeartbeatTimeout and WithHeartbeatTimeout, need to call it regularly. Otherwise
+// they are failed once the timeout elapses and their context is canceled.
+func RecordHeartbeat(ctx context.Context) {
+	activity.GetActivityState(ctx).RecordHeartbeat(nil)
+}
+
+// Heartbeat reports that the activity is making progress like RecordHeartbeat, together with details about its
+// progress. Backends implementing backend.ActivityHeartbeater store the details of the last heartbeat, so when
+// the worker executing the activity stops, the execution of the task on another worker can continue from them,
+// see HeartbeatDetails.
+func Heartbeat(ctx context.Context, details interface{}) error {
+	as := activity.GetActivityState(ctx)
+
+	p, err := as.Converter.To(details)
+	if err != nil {
+		return fmt.Errorf("converting heartbeat details: %w", err)
+	}
+
+	as.RecordHeartbeat(p)
+
+	return nil
+}
+
+// HeartbeatDetails decodes the details of the last heartbeat of an earlier execution of the activity task into
+// vptr. It returns false if there are none, for example because this is the first execution of the task.
+func HeartbeatDetails(ctx context.Context, vptr interface{}) (bool, error) {
+	as := activity.GetActivityState(ctx)
+	if as.HeartbeatDetails == nil {
+		return false, nil
+	}
+
+	if err := as.Converter.From(as.HeartbeatDetails, vptr); err != nil {
+		return false, fmt.Errorf("converting heartbeat details: %w", err)
+	}
+
+	return true, nil
+}
@@ -5,9 +5,21 @@ import (
 
 	"github.com/cschleiden/go-workflows/internal/activity"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/trace"
 )
 
 // Logger returns a logger with the workflow instance this activity is executed for set as default fields
 func Logger(ctx context.Context) log.Logger {
 	return activity.GetActivityState(ctx).Logger
 }
+
+// Metrics returns a metrics client with the activity and the workflow instance it is executed for set as default tags
+func Metrics(ctx context.Context) metrics.Client {
+	return activity.GetActivityState(ctx).Metrics
+}
+
+// Tracer returns a tracer that adds the activity and the workflow instance it is executed for to every span
+func Tracer(ctx context.Context) trace.Tracer {
+	return activity.GetActivityState(ctx).Tracer
+}
@@ -0,0 +1,57 @@
+package activity
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	internal "github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// DynamicActivity handles activities without a specific registration. It receives the name of the scheduled
+// activity and its inputs encoded by the converter, and returns the encoded result. Use it to proxy activities
+// to other systems, see Worker.RegisterDynamicActivity.
+type DynamicActivity = internal.DynamicActivity
+
+// RegistrationOption configures the defaults of an activity when registering it with a worker
+type RegistrationOption = core.ActivityRegistrationOption
+
+// WithDefaultRetryPolicy sets the retry options used when the activity is scheduled without retry options
+func WithDefaultRetryPolicy(retryOptions workflow.RetryOptions) RegistrationOption {
+	return func(o *core.ActivityRegistrationOptions) {
+		o.RetryOptions = &retryOptions
+	}
+}
+
+// WithStartToCloseTimeout sets the timeout for a single execution of the activity, unless overridden
+// when scheduling the activity.
+func WithStartToCloseTimeout(timeout time.Duration) RegistrationOption {
+	return func(o *core.ActivityRegistrationOptions) {
+		o.StartToCloseTimeout = timeout
+	}
+}
+
+// WithHeartbeatTimeout fails an execution of the activity if it does not call RecordHeartbeat within the
+// timeout, unless overridden when scheduling the activity.
+func WithHeartbeatTimeout(timeout time.Duration) RegistrationOption {
+	return func(o *core.ActivityRegistrationOptions) {
+		o.HeartbeatTimeout = timeout
+	}
+}
+
+// WithQueue routes the activity to the given activity queue, unless overridden when scheduling the activity.
+// Only workers polling that queue execute the activity. Workflows use the queue if the activity is registered
+// with the worker executing the workflow.
+func WithQueue(queue string) RegistrationOption {
+	return func(o *core.ActivityRegistrationOptions) {
+		o.Queue = queue
+	}
+}
+
+// WithValidator validates the inputs of the activity before it's executed, and its result before it's returned
+// to the workflow. If validation fails, the activity fails with the validation error. See the validation package.
+func WithValidator(v core.Validator) RegistrationOption {
+	return func(o *core.ActivityRegistrationOptions) {
+		o.Validator = v
+	}
+}
@@ -0,0 +1,21 @@
+package activity
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/stream"
+)
+
+// StreamWriter writes a large result in chunks to the backend. Return the reference returned by Stream from the
+// activity, and read the result with workflow.ReadStreamChunk or Client.OpenStream.
+type StreamWriter = stream.Writer
+
+// ErrStreamsNotSupported is returned by NewStream if the backend cannot store streams
+var ErrStreamsNotSupported = stream.ErrNotSupported
+
+// NewStream creates a stream for a large result of the activity. Data written to the stream is stored in chunks
+// by the backend instead of in the history, the history only contains the reference to the stream. Close the
+// stream before returning its reference. Streams are stored as workflow state, so the backend has to support it.
+func NewStream(ctx context.Context) (*StreamWriter, error) {
+	return stream.NewWriter(ctx)
+}
@@ -56,11 +56,6 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			if n.Type.Results == nil || len(n.Type.Results.List) == 0 {
 				pass.Reportf(n.Pos(), "workflow `%v` doesn't return anything. needs to return at least `error`", n.Name.Name)
 			} else {
-				if len(n.Type.Results.List) > 2 {
-					pass.Reportf(n.Pos(), "workflow `%v` returns more than two values", n.Name.Name)
-					return true
-				}
-
 				lastResult := n.Type.Results.List[len(n.Type.Results.List)-1]
 				if types.ExprString(lastResult.Type) != "error" {
 					pass.Reportf(n.Pos(), "workflow `%v` doesn't return `error` as last return value", n.Name.Name)
@@ -124,6 +119,12 @@ func run(pass *analysis.Pass) (interface{}, error) {
 				case "Sleep":
 					pass.Reportf(n.Pos(), "`time.Sleep` is not allowed in workflows, use `workflow.Sleep` instead")
 				}
+
+			case "github.com/google/uuid":
+				switch id.Name {
+				case "New", "NewString", "NewRandom":
+					pass.Reportf(n.Pos(), "`uuid.%s` is not allowed in workflows, use `workflow.NewGUID` instead", id.Name)
+				}
 			}
 		}
 
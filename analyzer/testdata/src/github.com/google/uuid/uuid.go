@@ -0,0 +1,5 @@
+package uuid
+
+func NewString() string {
+	return ""
+}
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"sync"
+
+	"github.com/google/uuid"
 )
 
 var foo int = 42
@@ -23,7 +25,7 @@ func wfWithResult(ctx workflow.Context) (string, error) {
 	return "", nil
 }
 
-func wfWithTooManyResults(ctx workflow.Context) (int, string, error) { // want "workflow `wfWithTooManyResults` returns more than two values"
+func wfWithMultipleResults(ctx workflow.Context) (int, string, error) {
 	return 42, "", nil
 }
 
@@ -98,8 +100,9 @@ func wfChanNestedFunc(ctx workflow.Context) error {
 }
 
 func wfFunctionUsage(ctx workflow.Context) error {
-	time.Sleep(10 * time.Second) // want "`time.Sleep` is not allowed in workflows, use `workflow.Sleep` instead"
-	fmt.Println(time.Now())      // want "`time.Now` is not allowed in workflows, use `workflow.Now` instead"
+	time.Sleep(10 * time.Second)  // want "`time.Sleep` is not allowed in workflows, use `workflow.Sleep` instead"
+	fmt.Println(time.Now())       // want "`time.Now` is not allowed in workflows, use `workflow.Now` instead"
+	fmt.Println(uuid.NewString()) // want "`uuid.NewString` is not allowed in workflows, use `workflow.NewGUID` instead"
 
 	return nil
 }
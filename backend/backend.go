@@ -2,16 +2,42 @@ package backend
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/cschleiden/go-workflows/internal/converter"
 	core "github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/trace"
 	"github.com/cschleiden/go-workflows/workflow"
 )
 
 var ErrInstanceNotFound = errors.New("workflow instance not found")
+var ErrInstanceAlreadyExists = errors.New("workflow instance already exists")
+var ErrMaxActiveInstancesReached = errors.New("maximum number of active workflow instances reached")
+var ErrInstanceFinished = errors.New("workflow instance already finished")
+var ErrInstanceNotFinished = errors.New("workflow instance has not finished")
+
+// InstanceAlreadyExistsError is returned by CreateWorkflowInstance when an instance with the same instance ID
+// already exists. Instance is the existing workflow instance. It matches ErrInstanceAlreadyExists when using
+// errors.Is.
+type InstanceAlreadyExistsError struct {
+	Instance *workflow.Instance
+}
+
+func (e *InstanceAlreadyExistsError) Error() string {
+	return ErrInstanceAlreadyExists.Error()
+}
+
+func (e *InstanceAlreadyExistsError) Is(target error) bool {
+	return target == ErrInstanceAlreadyExists
+}
 
 type WorkflowState int
 
@@ -20,6 +46,17 @@ const (
 	WorkflowStateFinished
 )
 
+func (s WorkflowState) String() string {
+	switch s {
+	case WorkflowStateActive:
+		return "active"
+	case WorkflowStateFinished:
+		return "finished"
+	default:
+		return fmt.Sprintf("WorkflowState(%d)", int(s))
+	}
+}
+
 //go:generate mockery --name=Backend --inpackage
 type Backend interface {
 	// CreateWorkflowInstance creates a new workflow instance
@@ -49,6 +86,9 @@ type Backend interface {
 	// This checkpoints the execution. events are new events from the last workflow execution
 	// which will be added to the workflow instance history. workflowEvents are new events for the
 	// completed or other workflow instances.
+	//
+	// If the workflow continues as new, workflowEvents contain a WorkflowExecutionStarted event for the same
+	// instance ID with a new execution ID. The finished execution needs to be replaced with the new one.
 	CompleteWorkflowTask(
 		ctx context.Context, taskID string, instance *workflow.Instance, state WorkflowState,
 		executedEvents []history.Event, activityEvents []history.Event, workflowEvents []history.WorkflowEvent) error
@@ -64,4 +104,433 @@ type Backend interface {
 
 	// Logger returns the configured logger for the backend
 	Logger() log.Logger
+
+	// Metrics returns the configured metrics client for the backend
+	Metrics() metrics.Client
+
+	// Tracer returns the configured tracer for the backend
+	Tracer() trace.Tracer
+
+	// Converter returns the configured converter for the backend
+	Converter() converter.Converter
+}
+
+// IncrementalHistoryTaskProvider is an optional interface a backend can implement to deliver history events
+// together with workflow tasks. Workers with a cached executor for an instance then don't have to fetch the
+// history separately when another worker made progress in the meantime.
+type IncrementalHistoryTaskProvider interface {
+	// GetWorkflowTaskWithHistory works like GetWorkflowTask. If knownSequenceID returns the sequence ID of the
+	// history the worker already has for the instance of the returned task, the task includes all history
+	// events after that sequence ID.
+	GetWorkflowTaskWithHistory(ctx context.Context, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error)
+}
+
+// InstanceError describes an error that occurred while executing a workflow instance
+type InstanceError struct {
+	// Message is the error message
+	Message string `json:"message,omitempty"`
+
+	// Source is where the error occurred, either InstanceErrorSourceWorkflow or InstanceErrorSourceActivity
+	Source string `json:"source,omitempty"`
+
+	// Name is the name of the failed activity, if the error occurred in an activity
+	Name string `json:"name,omitempty"`
+
+	// Attempt is the number of consecutive errors with the same source and name
+	Attempt int `json:"attempt,omitempty"`
+
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+const (
+	InstanceErrorSourceWorkflow = "workflow"
+	InstanceErrorSourceActivity = "activity"
+)
+
+// NextAttempt returns a copy of e with the attempt set based on the previously recorded error
+func (e *InstanceError) NextAttempt(prev *InstanceError) *InstanceError {
+	r := *e
+	r.Attempt = 1
+
+	if prev != nil && prev.Source == e.Source && prev.Name == e.Name {
+		r.Attempt = prev.Attempt + 1
+	}
+
+	return &r
+}
+
+// InstanceErrorRecorder is an optional interface a backend can implement to store the last error of a
+// workflow instance. This allows diagnosing instances that are not making progress.
+type InstanceErrorRecorder interface {
+	// RecordInstanceError stores err as the last error of the given instance
+	RecordInstanceError(ctx context.Context, instance *workflow.Instance, err *InstanceError) error
+}
+
+// WorkflowInstanceWaiter is an optional interface a backend can implement if it can notify
+// about finished workflow instances, instead of the client having to poll for the instance state.
+type WorkflowInstanceWaiter interface {
+	// WaitForWorkflowInstance blocks until the given workflow instance is finished or the
+	// context is canceled.
+	WaitForWorkflowInstance(ctx context.Context, instance *workflow.Instance) error
+}
+
+// FinishedInstanceCleaner is an optional interface a backend can implement if it supports removing
+// finished workflow instances and all of their data.
+type FinishedInstanceCleaner interface {
+	// CleanupFinishedInstances removes all workflow instances that finished more than olderThan ago.
+	CleanupFinishedInstances(ctx context.Context, olderThan time.Duration) error
+}
+
+// WorkflowInstanceRemover is an optional interface a backend can implement if it supports removing a single
+// finished workflow instance and all of its data.
+type WorkflowInstanceRemover interface {
+	// RemoveWorkflowInstance removes the given instance together with its history, pending events, and earlier
+	// runs. It returns ErrInstanceNotFound if the instance does not exist, or ErrInstanceNotFinished if it is
+	// still active.
+	RemoveWorkflowInstance(ctx context.Context, instance *workflow.Instance) error
+}
+
+// RetentionProvider is an optional interface a backend can implement to report how long finished workflow
+// instances are kept, see WithRetention. Workers periodically remove older instances from backends that also
+// implement FinishedInstanceCleaner.
+type RetentionProvider interface {
+	// Retention returns the retention of finished instances, or 0 if they are kept forever
+	Retention() time.Duration
+}
+
+// HistoryCompactor is an optional interface a backend can implement if it supports removing superseded
+// events from the history of a workflow instance, to reduce the storage used by long-running instances.
+type HistoryCompactor interface {
+	// CompactWorkflowInstanceHistory removes events from the history of the given instance that do not affect
+	// replaying it, and returns the number of removed events. Note that the history length reported to the
+	// workflow by workflow.GetInfo is based on the compacted history after the next replay.
+	CompactWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance) (int, error)
+}
+
+// PayloadRewriter is an optional interface a backend can implement if it supports rewriting stored payloads, for
+// example, to re-encrypt them after rotating the encryption key of the converter. See converter.AESCodec.
+type PayloadRewriter interface {
+	// RewritePayloads calls rewrite for every payload stored in the events of all workflow instances, active and
+	// finished, in the cached activity results, heartbeat details, workflow state, and workflow queries, and stores
+	// the payloads that changed. It returns the number of rewritten payloads. Rows are rewritten in small batches, each in its own transaction, so workers can
+	// keep running. If rewriting fails, the payloads rewritten so far remain rewritten.
+	RewritePayloads(ctx context.Context, rewrite func(payload.Payload) (payload.Payload, error)) (int, error)
+}
+
+// ActivityResultCache is an optional interface a backend can implement to cache activity results. It's
+// used for activities scheduled with ActivityOptions.MemoizeFor.
+type ActivityResultCache interface {
+	// GetActivityResult returns the cached result for the given key, and whether a result was found
+	GetActivityResult(ctx context.Context, key string) (payload.Payload, bool, error)
+
+	// StoreActivityResult caches the result for the given key for the duration of ttl
+	StoreActivityResult(ctx context.Context, key string, result payload.Payload, ttl time.Duration) error
+}
+
+// LeaseProvider is an optional interface a backend can implement to provide leases shared by all workers using
+// the backend. It's used by the leader package to elect a single worker for background jobs.
+type LeaseProvider interface {
+	// AcquireLease acquires the lease with the given name for holder until ttl from now, or extends it if holder
+	// already holds it. It returns false if another holder holds the lease and it hasn't expired yet.
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+
+	// ReleaseLease releases the lease with the given name, if it's held by holder
+	ReleaseLease(ctx context.Context, name, holder string) error
+}
+
+// StateStore is an optional interface a backend can implement to store key-value state shared between workflow
+// instances. It's used by workflow.GetState, workflow.SetState, workflow.DeleteState, and workflow.IncrementState.
+// Keys are scoped to a key space chosen by the application.
+type StateStore interface {
+	// GetState returns the value stored for the key in the given key space, and whether a value was found
+	GetState(ctx context.Context, space, key string) (payload.Payload, bool, error)
+
+	// SetState stores the value for the key in the given key space, replacing any existing value
+	SetState(ctx context.Context, space, key string, value payload.Payload) error
+
+	// DeleteState removes the key from the given key space. Removing a key that does not exist is not an error.
+	DeleteState(ctx context.Context, space, key string) error
+
+	// IncrementState atomically adds delta to the counter stored for the key in the given key space, and returns
+	// the new value. Keys that do not exist start at 0. Counters are stored as JSON numbers, so they can be read
+	// with GetState.
+	IncrementState(ctx context.Context, space, key string, delta int64) (int64, error)
+}
+
+// BacklogStats describes the amount of outstanding work in a backend
+type BacklogStats struct {
+	// PendingWorkflowTasks is the number of workflow tasks that are ready to be processed or are being processed
+	PendingWorkflowTasks int64
+
+	// PendingActivityTasks is the number of activity tasks that are ready to be processed or are being processed
+	PendingActivityTasks int64
+
+	// FutureEvents is the number of events, e.g., fired timers, that are scheduled but not yet visible
+	FutureEvents int64
+}
+
+// BacklogReporter is an optional interface a backend can implement to report its backlog, for example, to
+// scale workers based on the amount of outstanding work.
+type BacklogReporter interface {
+	GetBacklogStats(ctx context.Context) (*BacklogStats, error)
+}
+
+// InstanceStats describes the size and outstanding work of a single workflow instance
+type InstanceStats struct {
+	// HistoryEvents is the number of events in the history of the instance
+	HistoryEvents int64
+
+	// HistorySize is the size of the serialized attributes of all history events in bytes
+	HistorySize int64
+
+	// PendingEvents is the number of events that have not been processed by a workflow task yet, including
+	// events that only become visible in the future
+	PendingEvents int64
+
+	// PendingActivities is the number of scheduled activities that have not completed yet
+	PendingActivities int64
+
+	// PendingTimers is the number of scheduled timers that have not fired or been canceled yet
+	PendingTimers int64
+
+	// BufferedSignals is the number of signals that have not been processed by a workflow task yet
+	BufferedSignals int64
+
+	// FailedAttempts is the number of consecutive failed attempts recorded for the instance, if the backend
+	// implements InstanceErrorRecorder
+	FailedAttempts int
+
+	// WorkflowTaskScheduledAt is the time the oldest visible pending event was enqueued, nil if no workflow task is
+	// waiting. The time since then is the current schedule-to-start latency of the instance.
+	WorkflowTaskScheduledAt *time.Time
+
+	// ActivityTaskScheduledAt is the time the oldest activity task not picked up by a worker yet was enqueued, nil
+	// if no activity task is waiting. Not reported by the Redis backend.
+	ActivityTaskScheduledAt *time.Time
+}
+
+// InstanceStatsProvider is an optional interface a backend can implement to return statistics about a
+// workflow instance, for example, for capacity planning and debugging.
+type InstanceStatsProvider interface {
+	// GetWorkflowInstanceStats returns statistics for the given instance or ErrInstanceNotFound
+	GetWorkflowInstanceStats(ctx context.Context, instance *workflow.Instance) (*InstanceStats, error)
+}
+
+// TransactionalInstanceCreator is an optional interface a SQL backend can implement to create workflow
+// instances as part of a transaction owned by the caller. The instance is only created once the caller
+// commits the transaction.
+type TransactionalInstanceCreator interface {
+	// CreateWorkflowInstanceTx creates a new workflow instance using the given transaction. The transaction
+	// is not committed or rolled back.
+	//
+	// The backend can't tell when the transaction is committed, so workers waiting for tasks only pick up the
+	// instance when they check for tasks again, after up to MaxTimerSkew, unless NotifyWorkflowCreated is called
+	// after the commit.
+	CreateWorkflowInstanceTx(ctx context.Context, tx *sql.Tx, event history.WorkflowEvent) error
+
+	// NotifyWorkflowCreated wakes up workers of this process waiting for workflow tasks. Call it after
+	// committing a transaction that created workflow instances.
+	NotifyWorkflowCreated()
+}
+
+// InstanceForceCompleter is an optional interface a backend can implement to finish stuck workflow instances
+// without executing them.
+type InstanceForceCompleter interface {
+	// ForceCompleteWorkflowInstance appends event to the history of the given instance and marks the instance
+	// as finished. Pending events and activities of the instance are discarded. workflowEvents are delivered
+	// to other instances, e.g., to notify the parent of a sub-workflow. Returns ErrInstanceNotFound, or
+	// ErrInstanceFinished if the instance has already finished.
+	ForceCompleteWorkflowInstance(ctx context.Context, instance *workflow.Instance, event history.Event, workflowEvents []history.WorkflowEvent) error
+}
+
+// InstanceTerminator is an optional interface a backend can implement to forcefully stop workflow instances
+type InstanceTerminator interface {
+	// TerminateWorkflowInstance appends event, a WorkflowExecutionTerminated event, to the history of the given
+	// instance and marks the instance as finished, like ForceCompleteWorkflowInstance. Returns
+	// ErrInstanceNotFound, or ErrInstanceFinished if the instance has already finished.
+	TerminateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event history.Event, workflowEvents []history.WorkflowEvent) error
+}
+
+// LockTimeoutProvider is an optional interface a backend can implement to report how long it keeps workflow and
+// activity tasks locked without a heartbeat. Workers use it to validate their heartbeat intervals.
+type LockTimeoutProvider interface {
+	// LockTimeouts returns the lock timeouts for workflow and activity tasks
+	LockTimeouts() (workflowLockTimeout, activityLockTimeout time.Duration)
+}
+
+// InstanceTagIndex is an optional interface a backend can implement to index workflow instances by their tags.
+// Tags are set when creating an instance, see ExecutionStartedAttributes.Tags, or added from workflow code, which
+// records a WorkflowTagsAdded event in the history.
+type InstanceTagIndex interface {
+	// GetWorkflowInstancesByTags returns the workflow instances that have all of the given tags. If activeOnly is
+	// true, finished instances are omitted.
+	GetWorkflowInstancesByTags(ctx context.Context, tags []string, activeOnly bool) ([]*workflow.Instance, error)
+}
+
+// InstanceFilter selects workflow instances. Instances have to match all of the given criteria.
+type InstanceFilter struct {
+	// States restricts the instances to the given states. Empty for instances in any state.
+	States []WorkflowState
+
+	// Name restricts the instances to the workflow with the given name. Empty for any workflow.
+	Name string
+
+	// Tags restricts the instances to those having all of the given tags
+	Tags []string
+}
+
+// MatchesState returns whether an instance in the given state matches the state criteria of the filter
+func (f InstanceFilter) MatchesState(state WorkflowState) bool {
+	if len(f.States) == 0 {
+		return true
+	}
+
+	for _, s := range f.States {
+		if s == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InstanceLister is an optional interface a backend can implement to list workflow instances by their state,
+// workflow name, and tags.
+type InstanceLister interface {
+	// ListWorkflowInstances returns the workflow instances matching the given filter, oldest first
+	ListWorkflowInstances(ctx context.Context, filter InstanceFilter) ([]*workflow.Instance, error)
+}
+
+// InstanceResolver is an optional interface a backend can implement to look up workflow instances by their
+// instance ID alone, without knowing the execution ID.
+type InstanceResolver interface {
+	// ResolveWorkflowInstance returns the current execution of the workflow instance with the given ID, or
+	// ErrInstanceNotFound
+	ResolveWorkflowInstance(ctx context.Context, instanceID string) (*workflow.Instance, error)
+}
+
+// WorkflowRun is a single execution of a workflow instance
+type WorkflowRun struct {
+	// Instance identifies the run by the instance ID and the execution ID of the run
+	Instance *workflow.Instance
+
+	State WorkflowState
+
+	CreatedAt time.Time
+
+	// CompletedAt is when the run finished, nil if it is still active
+	CompletedAt *time.Time
+}
+
+// RunProvider is an optional interface a backend can implement to store multiple executions, or runs, of a
+// workflow instance under the same instance ID. Only the latest run is current: resolving, signaling, and canceling
+// an instance by its instance ID address the current run. Earlier runs are kept until the instance is removed.
+type RunProvider interface {
+	// StartWorkflowInstanceRun starts a new run of the instance in event, with the execution ID of the event's
+	// instance. If the current run of the instance has finished, it is archived first. Returns an
+	// InstanceAlreadyExistsError if the current run is still active.
+	StartWorkflowInstanceRun(ctx context.Context, event history.WorkflowEvent) error
+
+	// ListWorkflowInstanceRuns returns all runs of the instance with the given ID, oldest first, or
+	// ErrInstanceNotFound
+	ListWorkflowInstanceRuns(ctx context.Context, instanceID string) ([]*WorkflowRun, error)
+
+	// GetWorkflowInstanceRunHistory returns the full history of the run identified by the execution ID of the
+	// given instance, whether it's the current run or an earlier one, or ErrInstanceNotFound
+	GetWorkflowInstanceRunHistory(ctx context.Context, instance *workflow.Instance) ([]history.Event, error)
+}
+
+// RunReplacer is an optional interface a backend can implement to start a new run of a workflow instance in place
+// of a run that is still active
+type RunReplacer interface {
+	// ReplaceWorkflowInstanceRun starts a new run of the instance in event like StartWorkflowInstanceRun. If the
+	// current run is still active, it is terminated first with the event and workflow events terminate returns
+	// for it, like TerminateWorkflowInstance. Terminating the current run and starting the new one happen
+	// atomically. A current run with the execution ID of event is not terminated, an InstanceAlreadyExistsError is
+	// returned instead.
+	ReplaceWorkflowInstanceRun(ctx context.Context, event history.WorkflowEvent, terminate func(run *workflow.Instance) (history.Event, []history.WorkflowEvent)) error
+}
+
+// DefaultWorkflowQueue is the queue workflow instances are placed on if no queue is specified. GetWorkflowTask
+// only returns tasks of instances on this queue.
+const DefaultWorkflowQueue = ""
+
+// WorkflowQueueProvider is an optional interface a backend can implement to support placing workflow instances
+// on dedicated queues, see ExecutionStartedAttributes.Queue. This allows running some workflows on a separate
+// set of workers.
+type WorkflowQueueProvider interface {
+	// GetWorkflowTaskFromQueues works like GetWorkflowTaskWithHistory, but returns a task of a workflow instance
+	// on any of the given queues. knownSequenceID may be nil.
+	GetWorkflowTaskFromQueues(ctx context.Context, queues []string, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error)
+}
+
+// DefaultActivityQueue is the queue activities are scheduled on if no queue is specified. GetActivityTask only
+// returns activities from this queue.
+const DefaultActivityQueue = ""
+
+// ActivityQueueProvider is an optional interface a backend can implement to support routing activities to
+// dedicated queues, see ActivityScheduledAttributes.Queue. This allows running some activities on a separate
+// set of workers.
+type ActivityQueueProvider interface {
+	// GetActivityTaskFromQueues works like GetActivityTask, but returns a pending activity from any of the
+	// given queues
+	GetActivityTaskFromQueues(ctx context.Context, queues []string) (*task.Activity, error)
+}
+
+// ActivityQueueMigrator is an optional interface a backend can implement to move activity tasks between queues,
+// for example, to consolidate or split pools of workers without losing scheduled activities.
+type ActivityQueueMigrator interface {
+	// MoveActivityQueue moves the pending activity tasks of queue from to queue to, and returns the number of
+	// moved tasks. If activities is not empty, only tasks of activities with the given names are moved. Tasks
+	// locked by a worker are not moved, they complete as usual. If redirect is true, activities scheduled on
+	// from later, by any workflow instance, are routed to to as well, until the redirect is removed with
+	// RemoveActivityQueueRedirect. A redirect always applies to all activities of the queue.
+	MoveActivityQueue(ctx context.Context, from, to string, activities []string, redirect bool) (int, error)
+
+	// RemoveActivityQueueRedirect removes the redirect of the given queue, activities scheduled on it afterwards
+	// stay on it again
+	RemoveActivityQueueRedirect(ctx context.Context, queue string) error
+
+	// ActivityQueueRedirects returns the target queue of every redirected queue
+	ActivityQueueRedirects(ctx context.Context) (map[string]string, error)
+}
+
+// WorkerCapabilities describe the tasks a worker is able to execute. An empty list places no restriction on
+// the respective kind of task.
+type WorkerCapabilities struct {
+	// Workflows are the names of the workflows the worker can execute
+	Workflows []string
+
+	// Activities are the names of the activities the worker can execute
+	Activities []string
+}
+
+// CapabilityTaskProvider is an optional interface a backend can implement to only deliver tasks a worker is
+// able to execute. This allows running a fleet of workers with different sets of registered workflows and
+// activities against the same backend.
+type CapabilityTaskProvider interface {
+	// GetWorkflowTaskForCapabilities works like GetWorkflowTaskFromQueues, but only returns tasks for workflow
+	// instances of one of the workflows in capabilities. knownSequenceID may be nil.
+	GetWorkflowTaskForCapabilities(ctx context.Context, capabilities WorkerCapabilities, queues []string, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error)
+
+	// GetActivityTaskForCapabilities works like GetActivityTaskFromQueues, but only returns tasks for activities
+	// in capabilities
+	GetActivityTaskForCapabilities(ctx context.Context, capabilities WorkerCapabilities, queues []string) (*task.Activity, error)
+}
+
+// WorkflowTaskReleaser is an optional interface a backend can implement to give up workflow tasks a worker
+// cannot process right away
+type WorkflowTaskReleaser interface {
+	// ReleaseWorkflowTask unlocks a workflow task retrieved using GetWorkflowTask without completing it, so it
+	// can be picked up again immediately instead of after the lock timeout
+	ReleaseWorkflowTask(ctx context.Context, taskID string, instance *workflow.Instance) error
+}
+
+// BatchSignaler is an optional interface a backend can implement to deliver multiple signals to a workflow instance
+// at once
+type BatchSignaler interface {
+	// SignalWorkflowBatch appends the given signal events to the pending events of the instance atomically, in
+	// order. The instance is woken up once for all of them. Returns ErrInstanceNotFound if the instance does not
+	// exist.
+	SignalWorkflowBatch(ctx context.Context, instanceID string, events []history.Event) error
 }
@@ -0,0 +1,82 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrBackpressure = errors.New("backend is overloaded")
+
+// BackpressureError is returned when creating or signaling a workflow instance while the backend load exceeds one
+// of the thresholds in BackpressureOptions. It matches ErrBackpressure when using errors.Is. Producers can use it to
+// shed or delay load.
+type BackpressureError struct {
+	// Backlog is the backlog of the backend at the time of the request, if it has been checked
+	Backlog *BacklogStats
+
+	// PendingEvents is the number of events of the signaled instance that have not been processed yet, if it has
+	// been checked
+	PendingEvents int64
+
+	// Reason describes which threshold has been exceeded
+	Reason string
+}
+
+func (e *BackpressureError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrBackpressure.Error(), e.Reason)
+}
+
+func (e *BackpressureError) Is(target error) bool {
+	return target == ErrBackpressure
+}
+
+// BackpressureOptions configure when the backend rejects creating and signaling workflow instances. 0 disables
+// a threshold.
+type BackpressureOptions struct {
+	// MaxPendingWorkflowTasks is the maximum number of pending workflow tasks, see BacklogStats
+	MaxPendingWorkflowTasks int64
+
+	// MaxPendingActivityTasks is the maximum number of pending activity tasks, see BacklogStats
+	MaxPendingActivityTasks int64
+
+	// MaxPendingEventsPerInstance is the maximum number of unprocessed events of a single workflow instance.
+	// Only checked when signaling an instance.
+	MaxPendingEventsPerInstance int64
+}
+
+// CheckBacklog returns whether any backlog threshold is configured
+func (o BackpressureOptions) CheckBacklog() bool {
+	return o.MaxPendingWorkflowTasks > 0 || o.MaxPendingActivityTasks > 0
+}
+
+// BacklogError returns a BackpressureError if the given backlog exceeds the configured thresholds
+func (o BackpressureOptions) BacklogError(stats *BacklogStats) error {
+	if o.MaxPendingWorkflowTasks > 0 && stats.PendingWorkflowTasks >= o.MaxPendingWorkflowTasks {
+		return &BackpressureError{
+			Backlog: stats,
+			Reason:  fmt.Sprintf("%v pending workflow tasks", stats.PendingWorkflowTasks),
+		}
+	}
+
+	if o.MaxPendingActivityTasks > 0 && stats.PendingActivityTasks >= o.MaxPendingActivityTasks {
+		return &BackpressureError{
+			Backlog: stats,
+			Reason:  fmt.Sprintf("%v pending activity tasks", stats.PendingActivityTasks),
+		}
+	}
+
+	return nil
+}
+
+// PendingEventsError returns a BackpressureError if the number of pending events of an instance exceeds the
+// configured threshold
+func (o BackpressureOptions) PendingEventsError(pendingEvents int64) error {
+	if o.MaxPendingEventsPerInstance > 0 && pendingEvents >= o.MaxPendingEventsPerInstance {
+		return &BackpressureError{
+			PendingEvents: pendingEvents,
+			Reason:        fmt.Sprintf("%v pending events for instance", pendingEvents),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,304 @@
+// Package chaos provides a backend decorator that injects failures into calls to another backend. It
+// can be used to test the resilience of workers and workflows before running them in production.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/trace"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// ErrInjected is returned for all failures injected by the chaos backend
+var ErrInjected = errors.New("chaos: injected failure")
+
+type Options struct {
+	// ErrorRate is the probability (0-1) that a call fails with ErrInjected without reaching the
+	// wrapped backend.
+	ErrorRate float64
+
+	// MaxLatency is the upper bound of the random latency added to every call.
+	MaxLatency time.Duration
+
+	// PartialCompletionRate is the probability (0-1) that completing a task is persisted by the wrapped
+	// backend, but ErrInjected is returned to the caller anyway.
+	PartialCompletionRate float64
+
+	// DuplicateDeliveryRate is the probability (0-1) that a task returned from GetWorkflowTask or
+	// GetActivityTask is delivered a second time.
+	DuplicateDeliveryRate float64
+
+	// Seed is used to initialize the random source. Use a fixed seed for reproducible failures.
+	Seed int64
+}
+
+type Option func(*Options)
+
+func WithErrorRate(rate float64) Option {
+	return func(o *Options) {
+		o.ErrorRate = rate
+	}
+}
+
+func WithMaxLatency(latency time.Duration) Option {
+	return func(o *Options) {
+		o.MaxLatency = latency
+	}
+}
+
+func WithPartialCompletionRate(rate float64) Option {
+	return func(o *Options) {
+		o.PartialCompletionRate = rate
+	}
+}
+
+func WithDuplicateDeliveryRate(rate float64) Option {
+	return func(o *Options) {
+		o.DuplicateDeliveryRate = rate
+	}
+}
+
+func WithSeed(seed int64) Option {
+	return func(o *Options) {
+		o.Seed = seed
+	}
+}
+
+// New wraps the given backend and injects failures according to the given options.
+//
+// The returned backend only implements the Backend interface, optional interfaces implemented by the
+// wrapped backend are not exposed.
+func New(b backend.Backend, opts ...Option) backend.Backend {
+	options := Options{
+		Seed: time.Now().UnixNano(),
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &chaosBackend{
+		b:       b,
+		options: options,
+		r:       rand.New(rand.NewSource(options.Seed)),
+	}
+}
+
+type chaosBackend struct {
+	b       backend.Backend
+	options Options
+
+	mu sync.Mutex
+	r  *rand.Rand
+
+	duplicateWorkflowTask *task.Workflow
+	duplicateActivityTask *task.Activity
+}
+
+var _ backend.Backend = (*chaosBackend)(nil)
+
+func (cb *chaosBackend) chance(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.r.Float64() < rate
+}
+
+// before adds latency and determines whether the current call should fail
+func (cb *chaosBackend) before(ctx context.Context) error {
+	if cb.options.MaxLatency > 0 {
+		cb.mu.Lock()
+		d := time.Duration(cb.r.Int63n(int64(cb.options.MaxLatency)))
+		cb.mu.Unlock()
+
+		t := time.NewTimer(d)
+		defer t.Stop()
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if cb.chance(cb.options.ErrorRate) {
+		return ErrInjected
+	}
+
+	return nil
+}
+
+func (cb *chaosBackend) CreateWorkflowInstance(ctx context.Context, event history.WorkflowEvent) error {
+	if err := cb.before(ctx); err != nil {
+		return err
+	}
+
+	return cb.b.CreateWorkflowInstance(ctx, event)
+}
+
+func (cb *chaosBackend) CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
+	if err := cb.before(ctx); err != nil {
+		return err
+	}
+
+	return cb.b.CancelWorkflowInstance(ctx, instance, event)
+}
+
+func (cb *chaosBackend) GetWorkflowInstanceState(ctx context.Context, instance *workflow.Instance) (backend.WorkflowState, error) {
+	if err := cb.before(ctx); err != nil {
+		return backend.WorkflowStateActive, err
+	}
+
+	return cb.b.GetWorkflowInstanceState(ctx, instance)
+}
+
+func (cb *chaosBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64) ([]history.Event, error) {
+	if err := cb.before(ctx); err != nil {
+		return nil, err
+	}
+
+	return cb.b.GetWorkflowInstanceHistory(ctx, instance, lastSequenceID)
+}
+
+func (cb *chaosBackend) SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error {
+	if err := cb.before(ctx); err != nil {
+		return err
+	}
+
+	return cb.b.SignalWorkflow(ctx, instanceID, event)
+}
+
+func (cb *chaosBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	cb.mu.Lock()
+	if t := cb.duplicateWorkflowTask; t != nil {
+		cb.duplicateWorkflowTask = nil
+		cb.mu.Unlock()
+		return t, nil
+	}
+	cb.mu.Unlock()
+
+	if err := cb.before(ctx); err != nil {
+		return nil, err
+	}
+
+	t, err := cb.b.GetWorkflowTask(ctx)
+	if err != nil || t == nil {
+		return t, err
+	}
+
+	if cb.chance(cb.options.DuplicateDeliveryRate) {
+		cb.mu.Lock()
+		cb.duplicateWorkflowTask = t
+		cb.mu.Unlock()
+	}
+
+	return t, nil
+}
+
+func (cb *chaosBackend) ExtendWorkflowTask(ctx context.Context, taskID string, instance *core.WorkflowInstance) error {
+	if err := cb.before(ctx); err != nil {
+		return err
+	}
+
+	return cb.b.ExtendWorkflowTask(ctx, taskID, instance)
+}
+
+func (cb *chaosBackend) CompleteWorkflowTask(
+	ctx context.Context, taskID string, instance *workflow.Instance, state backend.WorkflowState,
+	executedEvents []history.Event, activityEvents []history.Event, workflowEvents []history.WorkflowEvent) error {
+	if err := cb.before(ctx); err != nil {
+		return err
+	}
+
+	if err := cb.b.CompleteWorkflowTask(ctx, taskID, instance, state, executedEvents, activityEvents, workflowEvents); err != nil {
+		return err
+	}
+
+	if cb.chance(cb.options.PartialCompletionRate) {
+		return ErrInjected
+	}
+
+	return nil
+}
+
+func (cb *chaosBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
+	cb.mu.Lock()
+	if t := cb.duplicateActivityTask; t != nil {
+		cb.duplicateActivityTask = nil
+		cb.mu.Unlock()
+		return t, nil
+	}
+	cb.mu.Unlock()
+
+	if err := cb.before(ctx); err != nil {
+		return nil, err
+	}
+
+	t, err := cb.b.GetActivityTask(ctx)
+	if err != nil || t == nil {
+		return t, err
+	}
+
+	if cb.chance(cb.options.DuplicateDeliveryRate) {
+		cb.mu.Lock()
+		cb.duplicateActivityTask = t
+		cb.mu.Unlock()
+	}
+
+	return t, nil
+}
+
+func (cb *chaosBackend) CompleteActivityTask(ctx context.Context, instance *workflow.Instance, activityID string, event history.Event) error {
+	if err := cb.before(ctx); err != nil {
+		return err
+	}
+
+	if err := cb.b.CompleteActivityTask(ctx, instance, activityID, event); err != nil {
+		return err
+	}
+
+	if cb.chance(cb.options.PartialCompletionRate) {
+		return ErrInjected
+	}
+
+	return nil
+}
+
+func (cb *chaosBackend) ExtendActivityTask(ctx context.Context, activityID string) error {
+	if err := cb.before(ctx); err != nil {
+		return err
+	}
+
+	return cb.b.ExtendActivityTask(ctx, activityID)
+}
+
+func (cb *chaosBackend) Logger() log.Logger {
+	return cb.b.Logger()
+}
+
+func (cb *chaosBackend) Metrics() metrics.Client {
+	return cb.b.Metrics()
+}
+
+func (cb *chaosBackend) Tracer() trace.Tracer {
+	return cb.b.Tracer()
+}
+
+func (cb *chaosBackend) Converter() converter.Converter {
+	return cb.b.Converter()
+}
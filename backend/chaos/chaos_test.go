@@ -0,0 +1,75 @@
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Chaos_ErrorRate(t *testing.T) {
+	b := &backend.MockBackend{}
+
+	cb := New(b, WithErrorRate(1))
+
+	err := cb.SignalWorkflow(context.Background(), uuid.NewString(), history.Event{})
+	require.ErrorIs(t, err, ErrInjected)
+	b.AssertNotCalled(t, "SignalWorkflow", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func Test_Chaos_PartialCompletion(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	b := &backend.MockBackend{}
+	b.On("CompleteActivityTask", mock.Anything, instance, "activityID", mock.Anything).Return(nil)
+
+	cb := New(b, WithPartialCompletionRate(1))
+
+	err := cb.CompleteActivityTask(context.Background(), instance, "activityID", history.Event{})
+	require.ErrorIs(t, err, ErrInjected)
+	b.AssertExpectations(t)
+}
+
+func Test_Chaos_DuplicateDelivery(t *testing.T) {
+	wt := &task.Workflow{
+		ID:               uuid.NewString(),
+		WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+	}
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowTask", mock.Anything).Return(wt, nil).Once()
+
+	cb := New(b, WithDuplicateDeliveryRate(1))
+
+	t1, err := cb.GetWorkflowTask(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, wt, t1)
+
+	t2, err := cb.GetWorkflowTask(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, wt, t2)
+
+	b.AssertExpectations(t)
+}
+
+func Test_Chaos_Latency(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(backend.WorkflowStateActive, nil)
+
+	cb := New(b, WithMaxLatency(time.Hour), WithSeed(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	_, err := cb.GetWorkflowInstanceState(ctx, instance)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
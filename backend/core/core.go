@@ -0,0 +1,14 @@
+// Package core exposes the workflow instance type used by backends, for implementing backends and tools
+// outside of this module.
+package core
+
+import (
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+type WorkflowInstance = core.WorkflowInstance
+
+var (
+	NewWorkflowInstance    = core.NewWorkflowInstance
+	NewSubWorkflowInstance = core.NewSubWorkflowInstance
+)
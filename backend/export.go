@@ -0,0 +1,21 @@
+package backend
+
+import (
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+// HistoryExporter is notified about all events appended to the history of workflow instances, after they have
+// been committed. ExportHistory is called synchronously by the backend, so implementations need to return
+// quickly and must not modify the events. See the exporter package for an implementation delivering the events
+// to a sink.
+type HistoryExporter interface {
+	ExportHistory(instance *core.WorkflowInstance, state WorkflowState, events []history.Event)
+}
+
+// WithHistoryExporter passes all events appended to the history of workflow instances to the given exporter
+func WithHistoryExporter(e HistoryExporter) BackendOption {
+	return func(o *Options) {
+		o.HistoryExporter = e
+	}
+}
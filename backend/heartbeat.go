@@ -0,0 +1,16 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// ActivityHeartbeater is an optional interface a backend can implement to store the details of activity
+// heartbeats, see activity.Heartbeat. The details of the last heartbeat are returned with the activity task, so
+// an execution of the task on another worker, after the lock of the previous one expired, can continue from them.
+type ActivityHeartbeater interface {
+	// HeartbeatActivityTask extends the lock of an activity task like ExtendActivityTask, and stores the details
+	// of the last heartbeat of the activity
+	HeartbeatActivityTask(ctx context.Context, activityID string, details payload.Payload) error
+}
@@ -0,0 +1,78 @@
+// Package history exposes the history events of workflow instances, for implementing backends and tools
+// outside of this module.
+package history
+
+import (
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+type (
+	// Payload is an encoded input or result
+	Payload = payload.Payload
+
+	Event                                      = history.Event
+	EventType                                  = history.EventType
+	WorkflowEvent                              = history.WorkflowEvent
+	HistoryEventOption                         = history.HistoryEventOption
+	Failure                                    = history.Failure
+	ExecutionStartedAttributes                 = history.ExecutionStartedAttributes
+	ExecutionCompletedAttributes               = history.ExecutionCompletedAttributes
+	ExecutionForceCompletedAttributes          = history.ExecutionForceCompletedAttributes
+	ExecutionTerminatedAttributes              = history.ExecutionTerminatedAttributes
+	ExecutionContinuedAsNewAttributes          = history.ExecutionContinuedAsNewAttributes
+	ExecutionCanceledAttributes                = history.ExecutionCanceledAttributes
+	WorkflowTaskStartedAttributes              = history.WorkflowTaskStartedAttributes
+	ActivityScheduledAttributes                = history.ActivityScheduledAttributes
+	ActivityCompletedAttributes                = history.ActivityCompletedAttributes
+	ActivityFailedAttributes                   = history.ActivityFailedAttributes
+	SubWorkflowScheduledAttributes             = history.SubWorkflowScheduledAttributes
+	SubWorkflowCancellationRequestedAttributes = history.SubWorkflowCancellationRequestedAttributes
+	SubWorkflowCompletedAttributes             = history.SubWorkflowCompletedAttributes
+	SubWorkflowFailedAttributes                = history.SubWorkflowFailedAttributes
+	TimerScheduledAttributes                   = history.TimerScheduledAttributes
+	TimerFiredAttributes                       = history.TimerFiredAttributes
+	TimerCanceledAttributes                    = history.TimerCanceledAttributes
+	SignalReceivedAttributes                   = history.SignalReceivedAttributes
+	SideEffectResultAttributes                 = history.SideEffectResultAttributes
+	HistoryLimitWarningAttributes              = history.HistoryLimitWarningAttributes
+	WorkflowTagsAddedAttributes                = history.WorkflowTagsAddedAttributes
+	MarkerRecordedAttributes                   = history.MarkerRecordedAttributes
+)
+
+const (
+	EventType_WorkflowExecutionStarted         = history.EventType_WorkflowExecutionStarted
+	EventType_WorkflowExecutionFinished        = history.EventType_WorkflowExecutionFinished
+	EventType_WorkflowExecutionTerminated      = history.EventType_WorkflowExecutionTerminated
+	EventType_WorkflowExecutionCanceled        = history.EventType_WorkflowExecutionCanceled
+	EventType_WorkflowTaskStarted              = history.EventType_WorkflowTaskStarted
+	EventType_SubWorkflowScheduled             = history.EventType_SubWorkflowScheduled
+	EventType_SubWorkflowCancellationRequested = history.EventType_SubWorkflowCancellationRequested
+	EventType_SubWorkflowCompleted             = history.EventType_SubWorkflowCompleted
+	EventType_SubWorkflowFailed                = history.EventType_SubWorkflowFailed
+	EventType_ActivityScheduled                = history.EventType_ActivityScheduled
+	EventType_ActivityCompleted                = history.EventType_ActivityCompleted
+	EventType_ActivityFailed                   = history.EventType_ActivityFailed
+	EventType_TimerScheduled                   = history.EventType_TimerScheduled
+	EventType_TimerFired                       = history.EventType_TimerFired
+	EventType_TimerCanceled                    = history.EventType_TimerCanceled
+	EventType_SignalReceived                   = history.EventType_SignalReceived
+	EventType_SideEffectResult                 = history.EventType_SideEffectResult
+	EventType_WorkflowExecutionForceCompleted  = history.EventType_WorkflowExecutionForceCompleted
+	EventType_HistoryLimitWarning              = history.EventType_HistoryLimitWarning
+	EventType_WorkflowTagsAdded                = history.EventType_WorkflowTagsAdded
+	EventType_MarkerRecorded                   = history.EventType_MarkerRecorded
+	EventType_WorkflowExecutionContinuedAsNew  = history.EventType_WorkflowExecutionContinuedAsNew
+)
+
+var (
+	NewHistoryEvent              = history.NewHistoryEvent
+	NewPendingEvent              = history.NewPendingEvent
+	NewWorkflowCancellationEvent = history.NewWorkflowCancellationEvent
+	ScheduleEventID              = history.ScheduleEventID
+	VisibleAt                    = history.VisibleAt
+	NewFailure                   = history.NewFailure
+	SerializeAttributes          = history.SerializeAttributes
+	DeserializeAttributes        = history.DeserializeAttributes
+	AttributesSize               = history.AttributesSize
+)
@@ -0,0 +1,32 @@
+package backend
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/metrics"
+)
+
+// Operations of a backend, used to tag the metrics.BackendOperationDuration metric
+const (
+	OperationCreateWorkflowInstance = "create_workflow_instance"
+	OperationSignalWorkflow         = "signal_workflow"
+	OperationGetWorkflowTask        = "get_workflow_task"
+	OperationCompleteWorkflowTask   = "complete_workflow_task"
+	OperationGetActivityTask        = "get_activity_task"
+	OperationCompleteActivityTask   = "complete_activity_task"
+)
+
+// MeasureOperation starts measuring the duration of an operation of the backend with the given name. The
+// returned function records the duration in the metrics.BackendOperationDuration metric:
+//
+//	defer backend.MeasureOperation(m, "sqlite", backend.OperationGetWorkflowTask)()
+func MeasureOperation(m metrics.Client, backendName, operation string) func() {
+	start := time.Now()
+
+	return func() {
+		m.Distribution(metrics.BackendOperationDuration, map[string]string{
+			"backend":   backendName,
+			"operation": operation,
+		}, float64(time.Since(start).Milliseconds()))
+	}
+}
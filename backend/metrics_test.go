@@ -0,0 +1,33 @@
+package backend
+
+import (
+	"testing"
+
+	mi "github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMetricsClient struct {
+	metrics.Client
+
+	name string
+	tags map[string]string
+}
+
+func (c *recordingMetricsClient) Distribution(name string, tags map[string]string, value float64) {
+	c.name = name
+	c.tags = tags
+}
+
+func Test_MeasureOperation(t *testing.T) {
+	c := &recordingMetricsClient{Client: mi.NewNoopMetricsClient()}
+
+	done := MeasureOperation(c, "sqlite", OperationGetWorkflowTask)
+	require.Empty(t, c.name)
+
+	done()
+
+	require.Equal(t, metrics.BackendOperationDuration, c.name)
+	require.Equal(t, map[string]string{"backend": "sqlite", "operation": OperationGetWorkflowTask}, c.tags)
+}
@@ -5,14 +5,19 @@ package backend
 import (
 	context "context"
 
+	converter "github.com/cschleiden/go-workflows/internal/converter"
 	core "github.com/cschleiden/go-workflows/internal/core"
 	history "github.com/cschleiden/go-workflows/internal/history"
 
 	log "github.com/cschleiden/go-workflows/log"
 
+	metrics "github.com/cschleiden/go-workflows/metrics"
+
 	mock "github.com/stretchr/testify/mock"
 
 	task "github.com/cschleiden/go-workflows/internal/task"
+
+	trace "github.com/cschleiden/go-workflows/trace"
 )
 
 // MockBackend is an autogenerated mock type for the Backend type
@@ -210,6 +215,38 @@ func (_m *MockBackend) Logger() log.Logger {
 	return r0
 }
 
+// Converter provides a mock function with given fields:
+func (_m *MockBackend) Converter() converter.Converter {
+	ret := _m.Called()
+
+	var r0 converter.Converter
+	if rf, ok := ret.Get(0).(func() converter.Converter); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(converter.Converter)
+		}
+	}
+
+	return r0
+}
+
+// Metrics provides a mock function with given fields:
+func (_m *MockBackend) Metrics() metrics.Client {
+	ret := _m.Called()
+
+	var r0 metrics.Client
+	if rf, ok := ret.Get(0).(func() metrics.Client); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(metrics.Client)
+		}
+	}
+
+	return r0
+}
+
 // SignalWorkflow provides a mock function with given fields: ctx, instanceID, event
 func (_m *MockBackend) SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error {
 	ret := _m.Called(ctx, instanceID, event)
@@ -223,3 +260,19 @@ func (_m *MockBackend) SignalWorkflow(ctx context.Context, instanceID string, ev
 
 	return r0
 }
+
+// Tracer provides a mock function with given fields:
+func (_m *MockBackend) Tracer() trace.Tracer {
+	ret := _m.Called()
+
+	var r0 trace.Tracer
+	if rf, ok := ret.Get(0).(func() trace.Tracer); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(trace.Tracer)
+		}
+	}
+
+	return r0
+}
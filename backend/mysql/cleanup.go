@@ -0,0 +1,150 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+var _ backend.FinishedInstanceCleaner = (*mysqlBackend)(nil)
+
+const cleanupBatchSize = 100
+
+// CleanupFinishedInstances removes all workflow instances that finished more than olderThan ago,
+// together with their history, pending events, activities, and earlier runs.
+//
+// Instances are removed in batches, each in its own transaction, to avoid holding locks for a long time.
+func (b *mysqlBackend) CleanupFinishedInstances(ctx context.Context, olderThan time.Duration) error {
+	cutoff := time.Now().Add(-olderThan)
+
+	for {
+		n, err := b.cleanupFinishedInstancesBatch(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+
+		if n < cleanupBatchSize {
+			return nil
+		}
+	}
+}
+
+var _ backend.WorkflowInstanceRemover = (*mysqlBackend)(nil)
+
+// RemoveWorkflowInstance removes the given finished instance, together with its history, pending events, and
+// earlier runs
+func (b *mysqlBackend) RemoveWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var completedAt sql.NullTime
+	if err := tx.QueryRowContext(
+		ctx,
+		"SELECT completed_at FROM `instances` WHERE instance_id = ? AND execution_id = ?",
+		instance.InstanceID,
+		instance.ExecutionID,
+	).Scan(&completedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return backend.ErrInstanceNotFound
+		}
+
+		return fmt.Errorf("reading instance: %w", err)
+	}
+
+	if !completedAt.Valid {
+		return backend.ErrInstanceNotFinished
+	}
+
+	if err := removeInstances(ctx, tx, []interface{}{instance.InstanceID}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+var _ backend.RetentionProvider = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) Retention() time.Duration {
+	return b.options.Retention
+}
+
+func (b *mysqlBackend) cleanupFinishedInstancesBatch(ctx context.Context, cutoff time.Time) (int, error) {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT instance_id FROM `instances` WHERE completed_at IS NOT NULL AND completed_at < ? LIMIT ?",
+		cutoff,
+		cleanupBatchSize,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("finding finished instances: %w", err)
+	}
+
+	instanceIDs := make([]interface{}, 0)
+	for rows.Next() {
+		var instanceID string
+		if err := rows.Scan(&instanceID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning instance id: %w", err)
+		}
+
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("finding finished instances: %w", err)
+	}
+
+	if len(instanceIDs) == 0 {
+		return 0, nil
+	}
+
+	if err := removeInstances(ctx, tx, instanceIDs); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("removing finished instances: %w", err)
+	}
+
+	return len(instanceIDs), nil
+}
+
+// removeInstances removes the given workflow instances and all of their data
+func removeInstances(ctx context.Context, tx *sql.Tx, instanceIDs []interface{}) error {
+	placeholders := "?" + strings.Repeat(",?", len(instanceIDs)-1)
+
+	for _, q := range []string{
+		"DELETE FROM `history` WHERE instance_id IN (%v)",
+		"DELETE FROM `pending_events` WHERE instance_id IN (%v)",
+		"DELETE FROM `activities` WHERE instance_id IN (%v)",
+		"DELETE FROM `instance_tags` WHERE instance_id IN (%v)",
+		"DELETE FROM `run_history` WHERE instance_id IN (%v)",
+		"DELETE FROM `instance_runs` WHERE instance_id IN (%v)",
+		"DELETE FROM `workflow_queries` WHERE instance_id IN (%v)",
+		"DELETE FROM `instances` WHERE instance_id IN (%v)",
+	} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(q, placeholders), instanceIDs...); err != nil {
+			return fmt.Errorf("removing instances: %w", err)
+		}
+	}
+
+	return nil
+}
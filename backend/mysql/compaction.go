@@ -0,0 +1,48 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+var _ backend.HistoryCompactor = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) CompactWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance) (int, error) {
+	// History is only appended to, events that are superseded now stay superseded
+	h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	superseded := history.SupersededEvents(h)
+	if len(superseded) == 0 {
+		return 0, nil
+	}
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, event := range superseded {
+		if _, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM `history` WHERE instance_id = ? AND event_id = ?",
+			instance.InstanceID,
+			event.ID,
+		); err != nil {
+			return 0, fmt.Errorf("removing history event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(superseded), nil
+}
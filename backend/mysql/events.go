@@ -3,11 +3,57 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"strings"
 
 	"github.com/cschleiden/go-workflows/internal/history"
 )
 
+// getPendingSignals returns the signals pending for the given instance, including signals that are not visible yet
+func getPendingSignals(ctx context.Context, tx *sql.Tx, instanceID string) ([]history.Event, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT event_id, sequence_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM `pending_events` WHERE instance_id = ? AND event_type = ? ORDER BY id",
+		instanceID,
+		history.EventType_SignalReceived,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("getting pending signals: %w", err)
+	}
+	defer rows.Close()
+
+	signals := make([]history.Event, 0)
+
+	for rows.Next() {
+		var attributes []byte
+
+		signal := history.Event{}
+
+		if err := rows.Scan(
+			&signal.ID,
+			&signal.SequenceID,
+			&signal.Type,
+			&signal.Timestamp,
+			&signal.ScheduleEventID,
+			&attributes,
+			&signal.VisibleAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning event: %w", err)
+		}
+
+		a, err := history.DeserializeAttributes(signal.Type, attributes)
+		if err != nil {
+			return nil, fmt.Errorf("deserializing attributes: %w", err)
+		}
+
+		signal.Attributes = a
+
+		signals = append(signals, signal)
+	}
+
+	return signals, rows.Err()
+}
+
 func insertNewEvents(ctx context.Context, tx *sql.Tx, instanceID string, newEvents []history.Event) error {
 	return insertEvents(ctx, tx, "pending_events", instanceID, newEvents)
 }
@@ -0,0 +1,110 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+var _ backend.InstanceForceCompleter = (*mysqlBackend)(nil)
+var _ backend.InstanceTerminator = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) ForceCompleteWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, event history.Event, workflowEvents []history.WorkflowEvent) error {
+	return b.retryTx(ctx, func() error {
+		return b.forceCompleteWorkflowInstance(ctx, instance, event, workflowEvents)
+	})
+}
+
+func (b *mysqlBackend) forceCompleteWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, event history.Event, workflowEvents []history.WorkflowEvent) error {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := forceCompleteInstance(ctx, tx, instance, &event, workflowEvents); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	b.workflowNotifier.Notify()
+	b.completionNotifier.Notify()
+
+	if b.options.HistoryExporter != nil {
+		b.options.HistoryExporter.ExportHistory(instance, backend.WorkflowStateFinished, []history.Event{event})
+	}
+
+	return nil
+}
+
+// forceCompleteInstance appends event to the history of the given instance and marks it as finished in the given
+// transaction. The sequence ID of event is set to the next one of the instance.
+func forceCompleteInstance(ctx context.Context, tx *sql.Tx, instance *core.WorkflowInstance, event *history.Event, workflowEvents []history.WorkflowEvent) error {
+	row := tx.QueryRowContext(ctx, "SELECT completed_at FROM `instances` WHERE instance_id = ? AND execution_id = ? FOR UPDATE", instance.InstanceID, instance.ExecutionID)
+
+	var completedAt sql.NullTime
+	if err := row.Scan(&completedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return backend.ErrInstanceNotFound
+		}
+
+		return fmt.Errorf("reading instance: %w", err)
+	}
+
+	if completedAt.Valid {
+		return backend.ErrInstanceFinished
+	}
+
+	row = tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(sequence_id), 0) FROM `history` WHERE instance_id = ?", instance.InstanceID)
+	if err := row.Scan(&event.SequenceID); err != nil {
+		return fmt.Errorf("reading last sequence id: %w", err)
+	}
+
+	event.SequenceID++
+
+	if err := insertHistoryEvents(ctx, tx, instance.InstanceID, []history.Event{*event}); err != nil {
+		return fmt.Errorf("inserting history event: %w", err)
+	}
+
+	// Release any lock, so that a worker still executing the instance cannot complete its task
+	if _, err := tx.ExecContext(
+		ctx,
+		"UPDATE `instances` SET completed_at = ?, locked_until = NULL, sticky_until = NULL, worker = NULL WHERE instance_id = ? AND execution_id = ?",
+		time.Now(),
+		instance.InstanceID,
+		instance.ExecutionID,
+	); err != nil {
+		return fmt.Errorf("completing instance: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM `pending_events` WHERE instance_id = ?", instance.InstanceID); err != nil {
+		return fmt.Errorf("removing pending events: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM `activities` WHERE instance_id = ?", instance.InstanceID); err != nil {
+		return fmt.Errorf("removing pending activities: %w", err)
+	}
+
+	for _, e := range workflowEvents {
+		if err := insertNewEvents(ctx, tx, e.WorkflowInstance.InstanceID, []history.Event{e.HistoryEvent}); err != nil {
+			return fmt.Errorf("inserting workflow events: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// TerminateWorkflowInstance finishes the given instance like force-completing it, only the recorded event differs
+func (b *mysqlBackend) TerminateWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, event history.Event, workflowEvents []history.WorkflowEvent) error {
+	return b.ForceCompleteWorkflowInstance(ctx, instance, event, workflowEvents)
+}
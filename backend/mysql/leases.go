@@ -0,0 +1,68 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+)
+
+var _ backend.LeaseProvider = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	var current string
+	var expiresAt time.Time
+	err = tx.QueryRowContext(
+		ctx, "SELECT holder, expires_at FROM leases WHERE name = ? FOR UPDATE", name).Scan(&current, &expiresAt)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// Another holder might insert the lease concurrently, only one insert succeeds
+		res, err := tx.ExecContext(
+			ctx, "INSERT IGNORE INTO leases (name, holder, expires_at) VALUES (?, ?, ?)", name, holder, now.Add(ttl))
+		if err != nil {
+			return false, fmt.Errorf("acquiring lease: %w", err)
+		}
+
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return false, err
+		}
+
+	case err != nil:
+		return false, fmt.Errorf("reading lease: %w", err)
+
+	case current != holder && expiresAt.After(now):
+		return false, nil
+
+	default:
+		if _, err := tx.ExecContext(
+			ctx, "UPDATE leases SET holder = ?, expires_at = ? WHERE name = ?", holder, now.Add(ttl), name); err != nil {
+			return false, fmt.Errorf("acquiring lease: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (b *mysqlBackend) ReleaseLease(ctx context.Context, name, holder string) error {
+	if _, err := b.db.ExecContext(ctx, "DELETE FROM leases WHERE name = ? AND holder = ?", name, holder); err != nil {
+		return fmt.Errorf("releasing lease: %w", err)
+	}
+
+	return nil
+}
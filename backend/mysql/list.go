@@ -0,0 +1,111 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+var _ backend.InstanceLister = (*mysqlBackend)(nil)
+var _ backend.InstanceResolver = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) ListWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) ([]*core.WorkflowInstance, error) {
+	where, args, ok := instanceFilterClause(filter)
+	if !ok {
+		return []*core.WorkflowInstance{}, nil
+	}
+
+	query := "SELECT i.instance_id, i.execution_id, i.parent_instance_id, i.parent_schedule_event_id FROM `instances` i WHERE " + where
+
+	rows, err := b.options.ReadDB(ctx, b.db).QueryContext(ctx, query+" ORDER BY i.created_at", args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing instances: %w", err)
+	}
+	defer rows.Close()
+
+	instances := make([]*core.WorkflowInstance, 0)
+	for rows.Next() {
+		var instanceID, executionID string
+		var parentInstanceID sql.NullString
+		var parentEventID sql.NullInt64
+		if err := rows.Scan(&instanceID, &executionID, &parentInstanceID, &parentEventID); err != nil {
+			return nil, fmt.Errorf("scanning instance: %w", err)
+		}
+
+		if parentInstanceID.Valid {
+			instances = append(instances, core.NewSubWorkflowInstance(instanceID, executionID, parentInstanceID.String, parentEventID.Int64))
+		} else {
+			instances = append(instances, core.NewWorkflowInstance(instanceID, executionID))
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing instances: %w", err)
+	}
+
+	return instances, nil
+}
+
+func (b *mysqlBackend) ResolveWorkflowInstance(ctx context.Context, instanceID string) (*core.WorkflowInstance, error) {
+	row := b.options.ReadDB(ctx, b.db).QueryRowContext(
+		ctx,
+		"SELECT execution_id, parent_instance_id, parent_schedule_event_id FROM `instances` WHERE instance_id = ?",
+		instanceID,
+	)
+
+	var executionID string
+	var parentInstanceID sql.NullString
+	var parentEventID sql.NullInt64
+	if err := row.Scan(&executionID, &parentInstanceID, &parentEventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, fmt.Errorf("reading instance: %w", err)
+	}
+
+	if parentInstanceID.Valid {
+		return core.NewSubWorkflowInstance(instanceID, executionID, parentInstanceID.String, parentEventID.Int64), nil
+	}
+
+	return core.NewWorkflowInstance(instanceID, executionID), nil
+}
+
+// instanceFilterClause returns the WHERE clause selecting the instances of the instances table, aliased as i,
+// matching the given filter. ok is false if the filter cannot match any instance.
+func instanceFilterClause(filter backend.InstanceFilter) (clause string, args []interface{}, ok bool) {
+	clause = "1 = 1"
+	args = make([]interface{}, 0)
+
+	if active, finished := filter.MatchesState(backend.WorkflowStateActive), filter.MatchesState(backend.WorkflowStateFinished); !active || !finished {
+		switch {
+		case active:
+			clause += " AND i.completed_at IS NULL"
+		case finished:
+			clause += " AND i.completed_at IS NOT NULL"
+		default:
+			return "", nil, false
+		}
+	}
+
+	if filter.Name != "" {
+		clause += " AND i.name = ?"
+		args = append(args, filter.Name)
+	}
+
+	if len(filter.Tags) > 0 {
+		clause += fmt.Sprintf(
+			" AND i.instance_id IN (SELECT instance_id FROM `instance_tags` WHERE tag IN (?%v) GROUP BY instance_id HAVING COUNT(*) = ?)",
+			strings.Repeat(",?", len(filter.Tags)-1))
+		for _, tag := range filter.Tags {
+			args = append(args, tag)
+		}
+		args = append(args, len(filter.Tags))
+	}
+
+	return clause, args, true
+}
@@ -10,10 +10,15 @@ import (
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/notify"
+	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/trace"
 	"github.com/cschleiden/go-workflows/workflow"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
@@ -44,10 +49,17 @@ func NewMysqlBackend(host string, port int, user, password, database string, opt
 		panic(err)
 	}
 
+	options := backend.ApplyOptions(opts...)
+
 	return &mysqlBackend{
 		db:         db,
 		workerName: fmt.Sprintf("worker-%v", uuid.NewString()),
-		options:    backend.ApplyOptions(opts...),
+		options:    options,
+		throttler:  backend.NewInstanceCreationThrottler(options.InstanceCreationLimits),
+
+		workflowNotifier:   notify.NewNotifier(),
+		activityNotifier:   notify.NewNotifier(),
+		completionNotifier: notify.NewNotifier(),
 	}
 }
 
@@ -55,40 +67,92 @@ type mysqlBackend struct {
 	db         *sql.DB
 	workerName string
 	options    backend.Options
+	throttler  *backend.InstanceCreationThrottler
+
+	// Notifiers wake up pollers in this process waiting for new tasks
+	workflowNotifier *notify.Notifier
+	activityNotifier *notify.Notifier
+
+	// completionNotifier wakes up clients in this process waiting for workflow instances to finish
+	completionNotifier *notify.Notifier
 }
 
-// CreateWorkflowInstance creates a new workflow instance
-func (b *mysqlBackend) CreateWorkflowInstance(ctx context.Context, m history.WorkflowEvent) error {
-	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
-		Isolation: sql.LevelReadCommitted,
-	})
-	if err != nil {
-		return fmt.Errorf("starting transaction: %w", err)
+// pollOptions returns the options for waiting for new tasks. Other processes sharing the database cannot
+// notify this one, so poll with a short, adaptive interval. The interval is bounded by MaxTimerSkew so that
+// due timers are picked up in time.
+func (b *mysqlBackend) pollOptions() notify.PollOptions {
+	minInterval := 10 * time.Millisecond
+	if minInterval > b.options.MaxTimerSkew {
+		minInterval = b.options.MaxTimerSkew
 	}
-	defer tx.Rollback()
 
-	// Create workflow instance
-	if err := createInstance(ctx, tx, m.WorkflowInstance, false); err != nil {
-		return err
+	return notify.PollOptions{
+		Timeout:     b.options.TaskPollTimeout,
+		MinInterval: minInterval,
+		MaxInterval: b.options.MaxTimerSkew,
 	}
+}
 
-	// Initial history is empty, store only new events
-	if err := insertNewEvents(ctx, tx, m.WorkflowInstance.InstanceID, []history.Event{m.HistoryEvent}); err != nil {
-		return fmt.Errorf("inserting new event: %w", err)
-	}
+// CreateWorkflowInstance creates a new workflow instance
+func (b *mysqlBackend) CreateWorkflowInstance(ctx context.Context, m history.WorkflowEvent) error {
+	defer backend.MeasureOperation(b.options.Metrics, "mysql", backend.OperationCreateWorkflowInstance)()
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("creating workflow instance: %w", err)
+	if err := b.retryTx(ctx, func() error {
+		tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+			Isolation: sql.LevelReadCommitted,
+		})
+		if err != nil {
+			return fmt.Errorf("starting transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if err := b.createWorkflowInstance(ctx, tx, m); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("creating workflow instance: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
 	}
 
+	b.workflowNotifier.Notify()
+
 	return nil
 }
 
+var _ backend.LockTimeoutProvider = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) LockTimeouts() (time.Duration, time.Duration) {
+	return b.options.WorkflowLockTimeout, b.options.ActivityLockTimeout
+}
+
 func (b *mysqlBackend) Logger() log.Logger {
 	return b.options.Logger
 }
 
+func (b *mysqlBackend) Metrics() metrics.Client {
+	return b.options.Metrics
+}
+
+func (b *mysqlBackend) Tracer() trace.Tracer {
+	return b.options.Tracer
+}
+
+func (b *mysqlBackend) Converter() converter.Converter {
+	return b.options.Converter
+}
+
 func (b *mysqlBackend) CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
+	return b.retryTx(ctx, func() error {
+		return b.cancelWorkflowInstance(ctx, instance, event)
+	})
+}
+
+func (b *mysqlBackend) cancelWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
 	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelReadCommitted,
 	})
@@ -114,39 +178,51 @@ func (b *mysqlBackend) CancelWorkflowInstance(ctx context.Context, instance *wor
 		return fmt.Errorf("inserting cancellation event: %w", err)
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	b.workflowNotifier.Notify()
+
+	return nil
 }
 
 func (b *mysqlBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64) ([]history.Event, error) {
-	tx, err := b.db.BeginTx(ctx, nil)
+	tx, err := b.options.ReadDB(ctx, b.db).BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
+	return getHistory(ctx, tx, instance.InstanceID, lastSequenceID)
+}
+
+func getHistory(ctx context.Context, tx *sql.Tx, instanceID string, lastSequenceID *int64) ([]history.Event, error) {
 	var historyEvents *sql.Rows
+	var err error
 	if lastSequenceID != nil {
 		historyEvents, err = tx.QueryContext(
 			ctx,
 			"SELECT event_id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM `history` WHERE instance_id = ? AND sequence_id > ? ORDER BY sequence_id",
-			instance.InstanceID,
+			instanceID,
 			*lastSequenceID,
 		)
 	} else {
 		historyEvents, err = tx.QueryContext(
 			ctx,
 			"SELECT event_id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM `history` WHERE instance_id = ? ORDER BY sequence_id",
-			instance.InstanceID,
+			instanceID,
 		)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("getting history: %w", err)
 	}
+	defer historyEvents.Close()
 
 	h := make([]history.Event, 0)
 
 	for historyEvents.Next() {
-		var instanceID string
+		var eventInstanceID string
 		var attributes []byte
 
 		historyEvent := history.Event{}
@@ -154,7 +230,7 @@ func (b *mysqlBackend) GetWorkflowInstanceHistory(ctx context.Context, instance
 		if err := historyEvents.Scan(
 			&historyEvent.ID,
 			&historyEvent.SequenceID,
-			&instanceID,
+			&eventInstanceID,
 			&historyEvent.Type,
 			&historyEvent.Timestamp,
 			&historyEvent.ScheduleEventID,
@@ -178,7 +254,9 @@ func (b *mysqlBackend) GetWorkflowInstanceHistory(ctx context.Context, instance
 }
 
 func (b *mysqlBackend) GetWorkflowInstanceState(ctx context.Context, instance *workflow.Instance) (backend.WorkflowState, error) {
-	row := b.db.QueryRowContext(
+	db := b.options.ReadDB(ctx, b.db)
+
+	row := db.QueryRowContext(
 		ctx,
 		"SELECT completed_at FROM instances WHERE instance_id = ? AND execution_id = ?",
 		instance.InstanceID,
@@ -188,6 +266,12 @@ func (b *mysqlBackend) GetWorkflowInstanceState(ctx context.Context, instance *w
 	var completedAt sql.NullTime
 	if err := row.Scan(&completedAt); err != nil {
 		if err == sql.ErrNoRows {
+			// Earlier runs of the instance have finished
+			row := db.QueryRowContext(ctx, "SELECT 1 FROM `instance_runs` WHERE instance_id = ? AND execution_id = ?", instance.InstanceID, instance.ExecutionID)
+			if err := row.Scan(new(int)); err == nil {
+				return backend.WorkflowStateFinished, nil
+			}
+
 			return backend.WorkflowStateActive, backend.ErrInstanceNotFound
 		}
 	}
@@ -199,7 +283,68 @@ func (b *mysqlBackend) GetWorkflowInstanceState(ctx context.Context, instance *w
 	return backend.WorkflowStateActive, nil
 }
 
-func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, ignoreDuplicate bool) error {
+var _ backend.TransactionalInstanceCreator = (*mysqlBackend)(nil)
+
+// CreateWorkflowInstanceTx creates a new workflow instance as part of the given transaction. This allows
+// starting a workflow atomically with other changes made in the same transaction. The caller is responsible
+// for committing or rolling back the transaction. Workers waiting for tasks only pick up the instance when they
+// check for tasks again, after up to MaxTimerSkew, unless NotifyWorkflowCreated is called after committing.
+func (b *mysqlBackend) CreateWorkflowInstanceTx(ctx context.Context, tx *sql.Tx, m history.WorkflowEvent) error {
+	return b.createWorkflowInstance(ctx, tx, m)
+}
+
+// NotifyWorkflowCreated wakes up workers of this process waiting for workflow tasks, see CreateWorkflowInstanceTx
+func (b *mysqlBackend) NotifyWorkflowCreated() {
+	b.workflowNotifier.Notify()
+}
+
+func (b *mysqlBackend) createWorkflowInstance(ctx context.Context, tx *sql.Tx, m history.WorkflowEvent) error {
+	if err := b.throttler.Allow(m.HistoryEvent); err != nil {
+		return err
+	}
+
+	if err := checkBackpressure(ctx, tx, b.options.Backpressure); err != nil {
+		return err
+	}
+
+	if b.options.MaxActiveInstances > 0 {
+		var active int
+		row := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM `instances` WHERE completed_at IS NULL")
+		if err := row.Scan(&active); err != nil {
+			return fmt.Errorf("counting active workflow instances: %w", err)
+		}
+
+		if active >= b.options.MaxActiveInstances {
+			return backend.ErrMaxActiveInstancesReached
+		}
+	}
+
+	// Create workflow instance
+	var priority int
+	var name, queue string
+	if a, ok := m.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes); ok {
+		priority = a.Priority
+		name = a.Name
+		queue = a.Queue
+	}
+
+	if err := createInstance(ctx, tx, m.WorkflowInstance, name, queue, priority, false); err != nil {
+		return err
+	}
+
+	// Initial history is empty, store only new events
+	if err := insertNewEvents(ctx, tx, m.WorkflowInstance.InstanceID, []history.Event{m.HistoryEvent}); err != nil {
+		return fmt.Errorf("inserting new event: %w", err)
+	}
+
+	if err := insertInstanceTags(ctx, tx, m.WorkflowInstance.InstanceID, history.AddedTags([]history.Event{m.HistoryEvent})); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, name, queue string, priority int, ignoreDuplicate bool) error {
 	var parentInstanceID *string
 	var parentEventID *int64
 	if wfi.SubWorkflow() {
@@ -212,11 +357,14 @@ func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, ign
 
 	res, err := tx.ExecContext(
 		ctx,
-		"INSERT IGNORE INTO `instances` (instance_id, execution_id, parent_instance_id, parent_schedule_event_id) VALUES (?, ?, ?, ?)",
+		"INSERT IGNORE INTO `instances` (instance_id, execution_id, parent_instance_id, parent_schedule_event_id, priority, name, queue) VALUES (?, ?, ?, ?, ?, ?, ?)",
 		wfi.InstanceID,
 		wfi.ExecutionID,
 		parentInstanceID,
 		parentEventID,
+		priority,
+		name,
+		queue,
 	)
 	if err != nil {
 		return fmt.Errorf("inserting workflow instance: %w", err)
@@ -229,15 +377,70 @@ func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, ign
 		}
 
 		if rows != 1 {
-			return errors.New("could not insert workflow instance")
+			// Instance already exists, return it to the caller
+			var executionID string
+			row := tx.QueryRowContext(ctx, "SELECT execution_id FROM `instances` WHERE instance_id = ?", wfi.InstanceID)
+			if err := row.Scan(&executionID); err != nil {
+				return fmt.Errorf("reading existing workflow instance: %w", err)
+			}
+
+			return &backend.InstanceAlreadyExistsError{
+				Instance: core.NewWorkflowInstance(wfi.InstanceID, executionID),
+			}
 		}
 	}
 
 	return nil
 }
 
+func instancePriority(ctx context.Context, tx *sql.Tx, instanceID string) (int, error) {
+	var priority int
+	row := tx.QueryRowContext(ctx, "SELECT priority FROM `instances` WHERE instance_id = ?", instanceID)
+	if err := row.Scan(&priority); err != nil {
+		return 0, fmt.Errorf("reading instance priority: %w", err)
+	}
+
+	return priority, nil
+}
+
 // SignalWorkflow signals a running workflow instance
+var _ backend.WorkflowInstanceWaiter = (*mysqlBackend)(nil)
+
+// WaitForWorkflowInstance returns as soon as an instance finishing in this process is committed. Instances finished
+// by other processes sharing the database are picked up by polling their state with a growing interval.
+func (b *mysqlBackend) WaitForWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	return notify.Wait(ctx, b.completionNotifier, notify.PollOptions{
+		MinInterval: 50 * time.Millisecond,
+		MaxInterval: time.Second,
+	}, func(ctx context.Context) (bool, error) {
+		state, err := b.GetWorkflowInstanceState(ctx, instance)
+		if err != nil {
+			return false, err
+		}
+
+		return state == backend.WorkflowStateFinished, nil
+	})
+}
+
 func (b *mysqlBackend) SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error {
+	defer backend.MeasureOperation(b.options.Metrics, "mysql", backend.OperationSignalWorkflow)()
+
+	return b.retryTx(ctx, func() error {
+		return b.signalWorkflow(ctx, instanceID, []history.Event{event})
+	})
+}
+
+var _ backend.BatchSignaler = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) SignalWorkflowBatch(ctx context.Context, instanceID string, events []history.Event) error {
+	defer backend.MeasureOperation(b.options.Metrics, "mysql", backend.OperationSignalWorkflow)()
+
+	return b.retryTx(ctx, func() error {
+		return b.signalWorkflow(ctx, instanceID, events)
+	})
+}
+
+func (b *mysqlBackend) signalWorkflow(ctx context.Context, instanceID string, events []history.Event) error {
 	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelReadCommitted,
 	})
@@ -252,15 +455,53 @@ func (b *mysqlBackend) SignalWorkflow(ctx context.Context, instanceID string, ev
 		return backend.ErrInstanceNotFound
 	}
 
-	if err := insertNewEvents(ctx, tx, instanceID, []history.Event{event}); err != nil {
-		return fmt.Errorf("inserting signal event: %w", err)
+	if err := checkBackpressure(ctx, tx, b.options.Backpressure); err != nil {
+		return err
 	}
 
-	return tx.Commit()
+	if err := checkPendingEventsBackpressure(ctx, tx, instanceID, b.options.Backpressure); err != nil {
+		return err
+	}
+
+	if err := insertNewEvents(ctx, tx, instanceID, events); err != nil {
+		return fmt.Errorf("inserting signal events: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	b.workflowNotifier.Notify()
+
+	return nil
 }
 
 // GetWorkflowInstance returns a pending workflow task or nil if there are no pending worflow executions
+var _ backend.IncrementalHistoryTaskProvider = (*mysqlBackend)(nil)
+
 func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	return b.GetWorkflowTaskWithHistory(ctx, nil)
+}
+
+func (b *mysqlBackend) GetWorkflowTaskWithHistory(ctx context.Context, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error) {
+	return b.GetWorkflowTaskFromQueues(ctx, []string{backend.DefaultWorkflowQueue}, knownSequenceID)
+}
+
+var _ backend.WorkflowQueueProvider = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) GetWorkflowTaskFromQueues(ctx context.Context, queues []string, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error) {
+	if len(queues) == 0 {
+		queues = []string{backend.DefaultWorkflowQueue}
+	}
+
+	return notify.Poll(ctx, b.workflowNotifier, b.pollOptions(), func(ctx context.Context) (*task.Workflow, error) {
+		return b.getWorkflowTask(ctx, backend.WorkerCapabilities{}, queues, knownSequenceID)
+	})
+}
+
+func (b *mysqlBackend) getWorkflowTask(ctx context.Context, capabilities backend.WorkerCapabilities, queues []string, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error) {
+	defer backend.MeasureOperation(b.options.Metrics, "mysql", backend.OperationGetWorkflowTask)()
+
 	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelReadCommitted,
 	})
@@ -271,6 +512,20 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 
 	// Lock next workflow task by finding an unlocked instance with new events to process.
 	now := time.Now()
+	workflows, workflowArgs := nameFilter("i.name", capabilities.Workflows)
+	queueFilter, queueArgs := nameFilter("i.queue", queues)
+	args := []interface{}{
+		now,          // event.visible_at
+		now,          // locked_until
+		now,          // sticky_until
+		b.workerName, // worker
+	}
+	args = append(args, queueArgs...)
+	args = append(args, workflowArgs...)
+
+	concurrency, concurrencyArgs := workflowConcurrencyFilter(b.options.WorkflowConcurrencyLimits, "i.name", "i.instance_id", now)
+	args = append(args, concurrencyArgs...)
+
 	row := tx.QueryRowContext(
 		ctx,
 		`SELECT i.id, i.instance_id, i.execution_id, i.parent_instance_id, i.parent_schedule_event_id, i.sticky_until
@@ -281,12 +536,13 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 				AND (pe.visible_at IS NULL OR pe.visible_at <= ?)
 				AND (i.locked_until IS NULL OR i.locked_until < ?)
 				AND (i.sticky_until IS NULL OR i.sticky_until < ? OR i.worker = ?)
+				`+queueFilter+`
+				`+workflows+`
+				`+concurrency+`
+			ORDER BY i.priority DESC
 			LIMIT 1
 			FOR UPDATE OF i SKIP LOCKED`,
-		now,          // event.visible_at
-		now,          // locked_until
-		now,          // sticky_until
-		b.workerName, // worker
+		args...,
 	)
 
 	var id int
@@ -382,6 +638,8 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 		return nil, nil
 	}
 
+	t.ScheduledAt = task.ScheduledAt(t.NewEvents...)
+
 	// Get most recent sequence id
 	row = tx.QueryRowContext(ctx, "SELECT sequence_id FROM `history` WHERE instance_id = ? ORDER BY id DESC LIMIT 1", instanceID)
 	if err := row.Scan(
@@ -392,6 +650,18 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 		}
 	}
 
+	// Include the history the worker is missing
+	if knownSequenceID != nil {
+		if sequenceID, ok := knownSequenceID(t.WorkflowInstance); ok && sequenceID < t.LastSequenceID {
+			t.History, err = getHistory(ctx, tx, instanceID, &sequenceID)
+			if err != nil {
+				return nil, fmt.Errorf("getting workflow history: %w", err)
+			}
+
+			t.HistorySequenceID = sequenceID
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -412,6 +682,22 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 	executedEvents []history.Event,
 	activityEvents []history.Event,
 	workflowEvents []history.WorkflowEvent,
+) error {
+	defer backend.MeasureOperation(b.options.Metrics, "mysql", backend.OperationCompleteWorkflowTask)()
+
+	return b.retryTx(ctx, func() error {
+		return b.completeWorkflowTask(ctx, taskID, instance, state, executedEvents, activityEvents, workflowEvents)
+	})
+}
+
+func (b *mysqlBackend) completeWorkflowTask(
+	ctx context.Context,
+	taskID string,
+	instance *workflow.Instance,
+	state backend.WorkflowState,
+	executedEvents []history.Event,
+	activityEvents []history.Event,
+	workflowEvents []history.WorkflowEvent,
 ) error {
 	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelReadCommitted,
@@ -465,11 +751,31 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 		}
 	}
 
+	// Remove fired events of timers canceled by the workflow, so they are not delivered anymore
+	for _, e := range executedEvents {
+		if e.Type != history.EventType_TimerCanceled {
+			continue
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM pending_events WHERE instance_id = ? AND schedule_event_id = ? AND event_type = ?",
+			instance.InstanceID, e.ScheduleEventID, history.EventType_TimerFired,
+		); err != nil {
+			return fmt.Errorf("removing canceled timer: %w", err)
+		}
+	}
+
 	// Insert new events generated during this workflow execution to the history
 	if err := insertHistoryEvents(ctx, tx, instance.InstanceID, executedEvents); err != nil {
 		return fmt.Errorf("inserting new history events: %w", err)
 	}
 
+	// Index tags added by the workflow
+	if err := insertInstanceTags(ctx, tx, instance.InstanceID, history.AddedTags(executedEvents)); err != nil {
+		return err
+	}
+
 	// Schedule activities
 	for _, e := range activityEvents {
 		if err := scheduleActivity(ctx, tx, instance, e); err != nil {
@@ -488,9 +794,23 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 	}
 
 	for targetInstance, events := range groupedEvents {
+		if targetInstance.InstanceID == instance.InstanceID && targetInstance.ExecutionID != instance.ExecutionID {
+			// The workflow continues as new, replace the finished execution with the new one
+			if err := continueAsNew(ctx, tx, targetInstance, events); err != nil {
+				return err
+			}
+
+			continue
+		}
+
 		if targetInstance.InstanceID != instance.InstanceID {
-			// Create new instance
-			if err := createInstance(ctx, tx, targetInstance, true); err != nil {
+			// Create new instance, sub-workflows inherit the priority of their parent
+			priority, err := instancePriority(ctx, tx, instance.InstanceID)
+			if err != nil {
+				return err
+			}
+
+			if err := createInstance(ctx, tx, targetInstance, history.WorkflowName(events), history.WorkflowQueue(events), priority, true); err != nil {
 				return err
 			}
 		}
@@ -504,6 +824,17 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 		return fmt.Errorf("committing complete workflow transaction: %w", err)
 	}
 
+	b.workflowNotifier.Notify()
+	b.activityNotifier.Notify()
+
+	if state == backend.WorkflowStateFinished {
+		b.completionNotifier.Notify()
+	}
+
+	if b.options.HistoryExporter != nil && len(executedEvents) > 0 {
+		b.options.HistoryExporter.ExportHistory(instance, state, executedEvents)
+	}
+
 	return nil
 }
 
@@ -536,8 +867,51 @@ func (b *mysqlBackend) ExtendWorkflowTask(ctx context.Context, taskID string, in
 	return tx.Commit()
 }
 
+var _ backend.WorkflowTaskReleaser = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) ReleaseWorkflowTask(ctx context.Context, taskID string, instance *core.WorkflowInstance) error {
+	res, err := b.db.ExecContext(
+		ctx,
+		`UPDATE instances SET locked_until = NULL, sticky_until = NULL WHERE instance_id = ? AND execution_id = ? AND worker = ?`,
+		instance.InstanceID,
+		instance.ExecutionID,
+		b.workerName,
+	)
+	if err != nil {
+		return fmt.Errorf("releasing workflow task: %w", err)
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("determining if workflow task was released: %w", err)
+	} else if rowsAffected == 0 {
+		return errors.New("could not release workflow task")
+	}
+
+	b.workflowNotifier.Notify()
+
+	return nil
+}
+
 // GetActivityTask returns a pending activity task or nil if there are no pending activities
 func (b *mysqlBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
+	return b.GetActivityTaskFromQueues(ctx, []string{backend.DefaultActivityQueue})
+}
+
+var _ backend.ActivityQueueProvider = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) GetActivityTaskFromQueues(ctx context.Context, queues []string) (*task.Activity, error) {
+	if len(queues) == 0 {
+		queues = []string{backend.DefaultActivityQueue}
+	}
+
+	return notify.Poll(ctx, b.activityNotifier, b.pollOptions(), func(ctx context.Context) (*task.Activity, error) {
+		return b.getActivityTask(ctx, backend.WorkerCapabilities{}, queues)
+	})
+}
+
+func (b *mysqlBackend) getActivityTask(ctx context.Context, capabilities backend.WorkerCapabilities, queues []string) (*task.Activity, error) {
+	defer backend.MeasureOperation(b.options.Metrics, "mysql", backend.OperationGetActivityTask)()
+
 	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelReadCommitted,
 	})
@@ -548,22 +922,42 @@ func (b *mysqlBackend) GetActivityTask(ctx context.Context) (*task.Activity, err
 
 	// Lock next activity
 	now := time.Now()
+	args := []interface{}{now}
+	for _, queue := range queues {
+		args = append(args, queue)
+	}
+
+	activities, activityArgs := nameFilter("name", capabilities.Activities)
+	args = append(args, activityArgs...)
+
+	// Skip activities of instances that already have the maximum number of activities in progress
+	var fairness string
+	if b.options.MaxConcurrentActivitiesPerInstance > 0 {
+		fairness = "AND (SELECT COUNT(*) FROM activities la WHERE la.instance_id = a.instance_id AND la.locked_until >= ?) < ?"
+		args = append(args, now, b.options.MaxConcurrentActivitiesPerInstance)
+	}
+
+	concurrency, concurrencyArgs := workflowConcurrencyFilter(
+		b.options.WorkflowConcurrencyLimits, "COALESCE((SELECT name FROM instances WHERE instance_id = a.instance_id), '')", "a.instance_id", now)
+	args = append(args, concurrencyArgs...)
+
 	res := tx.QueryRowContext(
 		ctx,
-		`SELECT id, activity_id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at
-			FROM activities
-			WHERE locked_until IS NULL OR locked_until < ?
+		`SELECT id, activity_id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at, heartbeat_details
+			FROM activities a
+			WHERE (locked_until IS NULL OR locked_until < ?) AND queue IN (?`+strings.Repeat(",?", len(queues)-1)+`) `+activities+` `+fairness+` `+concurrency+`
+			ORDER BY priority DESC
 			LIMIT 1
 			FOR UPDATE SKIP LOCKED`,
-		now,
+		args...,
 	)
 
 	var id int64
 	var instanceID, executionID string
-	var attributes []byte
+	var attributes, heartbeatDetails []byte
 	event := history.Event{}
 
-	if err := res.Scan(&id, &event.ID, &instanceID, &executionID, &event.Type, &event.Timestamp, &event.ScheduleEventID, &attributes, &event.VisibleAt); err != nil {
+	if err := res.Scan(&id, &event.ID, &instanceID, &executionID, &event.Type, &event.Timestamp, &event.ScheduleEventID, &attributes, &event.VisibleAt, &heartbeatDetails); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -592,6 +986,8 @@ func (b *mysqlBackend) GetActivityTask(ctx context.Context) (*task.Activity, err
 		ID:               event.ID,
 		WorkflowInstance: core.NewWorkflowInstance(instanceID, executionID),
 		Event:            event,
+		ScheduledAt:      task.ScheduledAt(event),
+		HeartbeatDetails: heartbeatDetails,
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -603,6 +999,14 @@ func (b *mysqlBackend) GetActivityTask(ctx context.Context) (*task.Activity, err
 
 // CompleteActivityTask completes a activity task retrieved using GetActivityTask
 func (b *mysqlBackend) CompleteActivityTask(ctx context.Context, instance *workflow.Instance, id string, event history.Event) error {
+	defer backend.MeasureOperation(b.options.Metrics, "mysql", backend.OperationCompleteActivityTask)()
+
+	return b.retryTx(ctx, func() error {
+		return b.completeActivityTask(ctx, instance, id, event)
+	})
+}
+
+func (b *mysqlBackend) completeActivityTask(ctx context.Context, instance *workflow.Instance, id string, event history.Event) error {
 	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelReadCommitted,
 	})
@@ -641,21 +1045,42 @@ func (b *mysqlBackend) CompleteActivityTask(ctx context.Context, instance *workf
 		return err
 	}
 
+	b.workflowNotifier.Notify()
+	b.activityNotifier.Notify()
+
 	return nil
 }
 
 func (b *mysqlBackend) ExtendActivityTask(ctx context.Context, activityID string) error {
+	return b.extendActivityTask(ctx, activityID, nil)
+}
+
+var _ backend.ActivityHeartbeater = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) HeartbeatActivityTask(ctx context.Context, activityID string, details payload.Payload) error {
+	return b.extendActivityTask(ctx, activityID, details)
+}
+
+// extendActivityTask extends the lock of the activity, and replaces its heartbeat details unless they are nil
+func (b *mysqlBackend) extendActivityTask(ctx context.Context, activityID string, details payload.Payload) error {
 	tx, err := b.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	// Keep the stored details when there are no new ones
+	var heartbeatDetails interface{}
+	if details != nil {
+		heartbeatDetails = []byte(details)
+	}
+
 	until := time.Now().Add(b.options.ActivityLockTimeout)
 	res, err := tx.ExecContext(
 		ctx,
-		`UPDATE activities SET locked_until = ? WHERE activity_id = ? AND worker = ?`,
+		`UPDATE activities SET locked_until = ?, heartbeat_details = COALESCE(?, heartbeat_details) WHERE activity_id = ? AND worker = ?`,
 		until,
+		heartbeatDetails,
 		activityID,
 		b.workerName,
 	)
@@ -678,10 +1103,17 @@ func scheduleActivity(ctx context.Context, tx *sql.Tx, instance *core.WorkflowIn
 		return err
 	}
 
+	var queue, name string
+	if a, ok := event.Attributes.(*history.ActivityScheduledAttributes); ok {
+		queue = a.Queue
+		name = a.Name
+	}
+
 	_, err = tx.ExecContext(
 		ctx,
 		`INSERT INTO activities
-			(activity_id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			(activity_id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at, priority, queue, name)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE((SELECT priority FROM instances WHERE instance_id = ?), 0), COALESCE((SELECT target FROM activity_queue_redirects WHERE queue = ?), ?), ?)`,
 		event.ID,
 		instance.InstanceID,
 		instance.ExecutionID,
@@ -690,6 +1122,10 @@ func scheduleActivity(ctx context.Context, tx *sql.Tx, instance *core.WorkflowIn
 		event.ScheduleEventID,
 		a,
 		event.VisibleAt,
+		instance.InstanceID,
+		queue,
+		queue,
+		name,
 	)
 
 	return err
@@ -1,14 +1,18 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
 )
 
 const testUser = "root"
@@ -24,7 +28,7 @@ func Test_MysqlBackend(t *testing.T) {
 
 	var dbName string
 
-	test.BackendTest(t, func() backend.Backend {
+	test.BackendTest(t, func(options ...backend.BackendOption) backend.Backend {
 		db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@/?parseTime=true&interpolateParams=true", testUser, testPassword))
 		if err != nil {
 			panic(err)
@@ -39,7 +43,7 @@ func Test_MysqlBackend(t *testing.T) {
 			panic(err)
 		}
 
-		return NewMysqlBackend("localhost", 3306, testUser, testPassword, dbName, backend.WithStickyTimeout(0))
+		return NewMysqlBackend("localhost", 3306, testUser, testPassword, dbName, append([]backend.BackendOption{backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0)}, options...)...)
 	}, func(b backend.Backend) {
 		db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@/?parseTime=true&interpolateParams=true", testUser, testPassword))
 		if err != nil {
@@ -94,3 +98,35 @@ func TestMySqlBackendE2E(t *testing.T) {
 		}
 	})
 }
+
+func Test_MysqlBackend_RetryTx(t *testing.T) {
+	b := &mysqlBackend{options: backend.ApplyOptions()}
+
+	attempts := 0
+	err := b.retryTx(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("completing workflow task: %w", &mysql.MySQLError{Number: errDeadlock})
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = b.retryTx(context.Background(), func() error {
+		attempts++
+		return &mysql.MySQLError{Number: errLockWaitTimeout}
+	})
+	require.ErrorIs(t, err, backend.ErrTransient)
+	require.Equal(t, maxTxAttempts, attempts)
+
+	attempts = 0
+	err = b.retryTx(context.Background(), func() error {
+		attempts++
+		return errors.New("other error")
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, attempts)
+}
@@ -0,0 +1,309 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+var _ backend.PayloadRewriter = (*mysqlBackend)(nil)
+
+// rewriteBatchSize is the number of rows rewritten in a single transaction
+const rewriteBatchSize = 100
+
+// eventTables are the tables storing events with payloads in their attributes
+var eventTables = []string{"pending_events", "history", "activities", "run_history"}
+
+func (b *mysqlBackend) RewritePayloads(ctx context.Context, rewrite func(payload.Payload) (payload.Payload, error)) (int, error) {
+	total := 0
+
+	for _, table := range eventTables {
+		var after int64
+		for {
+			n, last, err := b.rewriteEventPayloads(ctx, table, after, rewrite)
+			total += n
+			if err != nil {
+				return total, fmt.Errorf("rewriting payloads in %v: %w", table, err)
+			}
+
+			if last == after {
+				break
+			}
+
+			after = last
+		}
+	}
+
+	for _, column := range payloadColumns {
+		var after []interface{}
+		for {
+			n, last, err := b.rewriteColumnPayloads(ctx, column, after, rewrite)
+			total += n
+			if err != nil {
+				return total, fmt.Errorf("rewriting payloads in %v.%v: %w", column.table, column.column, err)
+			}
+
+			if last == nil {
+				break
+			}
+
+			after = last
+		}
+	}
+
+	return total, nil
+}
+
+// rewriteEventPayloads rewrites the payloads of the next batch of events after the given ID. It returns the
+// number of rewritten payloads and the ID of the last event of the batch.
+func (b *mysqlBackend) rewriteEventPayloads(
+	ctx context.Context, table string, after int64, rewrite func(payload.Payload) (payload.Payload, error),
+) (int, int64, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, after, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT id, event_type, attributes FROM `"+table+"` WHERE id > ? ORDER BY id LIMIT ?",
+		after,
+		rewriteBatchSize,
+	)
+	if err != nil {
+		return 0, after, err
+	}
+
+	type update struct {
+		id         int64
+		attributes []byte
+	}
+
+	var updates []update
+	rewritten := 0
+	last := after
+
+	for rows.Next() {
+		var eventType history.EventType
+		var data []byte
+		if err := rows.Scan(&last, &eventType, &data); err != nil {
+			rows.Close()
+			return 0, after, err
+		}
+
+		attributes, err := history.DeserializeAttributes(eventType, data)
+		if err != nil {
+			rows.Close()
+			return 0, after, fmt.Errorf("deserializing attributes: %w", err)
+		}
+
+		n, err := history.RewritePayloads(attributes, rewrite)
+		if err != nil {
+			rows.Close()
+			return 0, after, err
+		}
+
+		if n == 0 {
+			continue
+		}
+
+		data, err = history.SerializeAttributes(attributes)
+		if err != nil {
+			rows.Close()
+			return 0, after, fmt.Errorf("serializing attributes: %w", err)
+		}
+
+		updates = append(updates, update{last, data})
+		rewritten += n
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, after, err
+	}
+
+	for _, u := range updates {
+		if _, err := tx.ExecContext(ctx, "UPDATE `"+table+"` SET attributes = ? WHERE id = ?", u.attributes, u.id); err != nil {
+			return 0, after, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, after, err
+	}
+
+	return rewritten, last, nil
+}
+
+// payloadColumn is a column storing payloads outside of event attributes. keys are the primary key columns of the
+// table. rewrite rewrites the payloads in a value of the column, and returns the new value and the number of rewritten
+// payloads.
+type payloadColumn struct {
+	table   string
+	keys    []string
+	column  string
+	rewrite func(data []byte, rewrite func(payload.Payload) (payload.Payload, error)) ([]byte, int, error)
+}
+
+var payloadColumns = []payloadColumn{
+	{table: "activity_results", keys: []string{"key"}, column: "result", rewrite: rewritePayload},
+	{table: "activities", keys: []string{"id"}, column: "heartbeat_details", rewrite: rewritePayload},
+	{table: "workflow_state", keys: []string{"space", "key"}, column: "value", rewrite: rewritePayload},
+	{table: "workflow_queries", keys: []string{"id"}, column: "inputs", rewrite: rewriteQueryInputs},
+	{table: "workflow_queries", keys: []string{"id"}, column: "result", rewrite: rewriteQueryResult},
+}
+
+// rewriteColumnPayloads rewrites the payloads of the next batch of rows after the row with the given key. It returns
+// the number of rewritten payloads and the key of the last row of the batch, or nil if there are no more rows.
+func (b *mysqlBackend) rewriteColumnPayloads(
+	ctx context.Context, c payloadColumn, after []interface{}, rewrite func(payload.Payload) (payload.Payload, error),
+) (int, []interface{}, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	keys := "`" + strings.Join(c.keys, "`, `") + "`"
+	keyFilter := "`" + strings.Join(c.keys, "` = ? AND `") + "` = ?"
+
+	query := "SELECT " + keys + ", `" + c.column + "` FROM `" + c.table + "`"
+	args := []interface{}{}
+	if after != nil {
+		query += " WHERE (" + keys + ") > (?" + strings.Repeat(", ?", len(c.keys)-1) + ")"
+		args = append(args, after...)
+	}
+	query += " ORDER BY " + keys + " LIMIT ?"
+	args = append(args, rewriteBatchSize)
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	type update struct {
+		key  []interface{}
+		data []byte
+	}
+
+	var updates []update
+	var last []interface{}
+	rewritten := 0
+
+	for rows.Next() {
+		key := make([]interface{}, len(c.keys))
+		dest := make([]interface{}, 0, len(c.keys)+1)
+		for i := range key {
+			dest = append(dest, &key[i])
+		}
+
+		var data []byte
+		if err := rows.Scan(append(dest, &data)...); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+
+		last = key
+
+		if len(data) == 0 {
+			continue
+		}
+
+		r, n, err := c.rewrite(data, rewrite)
+		if err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+
+		if n > 0 {
+			updates = append(updates, update{key, r})
+			rewritten += n
+		}
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	for _, u := range updates {
+		if _, err := tx.ExecContext(ctx, "UPDATE `"+c.table+"` SET `"+c.column+"` = ? WHERE "+keyFilter, append([]interface{}{u.data}, u.key...)...); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+
+	return rewritten, last, nil
+}
+
+// rewritePayload rewrites a column storing a single payload
+func rewritePayload(data []byte, rewrite func(payload.Payload) (payload.Payload, error)) ([]byte, int, error) {
+	r, err := rewrite(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if bytes.Equal(r, data) {
+		return data, 0, nil
+	}
+
+	return r, 1, nil
+}
+
+// rewriteQueryInputs rewrites the inputs of a workflow query
+func rewriteQueryInputs(data []byte, rewrite func(payload.Payload) (payload.Payload, error)) ([]byte, int, error) {
+	var inputs []payload.Payload
+	if err := json.Unmarshal(data, &inputs); err != nil {
+		return nil, 0, fmt.Errorf("unmarshaling query inputs: %w", err)
+	}
+
+	changed := 0
+	for i, input := range inputs {
+		r, n, err := rewritePayload(input, rewrite)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		inputs[i] = r
+		changed += n
+	}
+
+	if changed == 0 {
+		return data, 0, nil
+	}
+
+	r, err := json.Marshal(inputs)
+	return r, changed, err
+}
+
+// rewriteQueryResult rewrites the result of a workflow query
+func rewriteQueryResult(data []byte, rewrite func(payload.Payload) (payload.Payload, error)) ([]byte, int, error) {
+	var result backend.WorkflowQueryResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, 0, fmt.Errorf("unmarshaling query result: %w", err)
+	}
+
+	if len(result.Result) == 0 {
+		return data, 0, nil
+	}
+
+	r, n, err := rewritePayload(result.Result, rewrite)
+	if err != nil || n == 0 {
+		return data, 0, err
+	}
+
+	result.Result = r
+
+	data, err = json.Marshal(&result)
+	return data, n, err
+}
@@ -0,0 +1,114 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+var _ backend.InstanceQuerier = (*mysqlBackend)(nil)
+var _ backend.InstanceQuerier = (*visibilityStore)(nil)
+
+// likeEscaper escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (b *mysqlBackend) QueryWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*backend.InstanceQueryResult, error) {
+	cursor, err := backend.DecodePageToken(query.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &backend.InstanceQueryResult{
+		Instances: make([]*backend.WorkflowInstanceSummary, 0),
+	}
+
+	where, args, ok := instanceFilterClause(backend.InstanceFilter{States: query.States})
+	if !ok {
+		return result, nil
+	}
+
+	if query.InstanceIDPrefix != "" {
+		where += " AND i.instance_id LIKE ?"
+		args = append(args, likeEscaper.Replace(query.InstanceIDPrefix)+"%")
+	}
+
+	if !query.CreatedAfter.IsZero() {
+		where += " AND i.created_at >= ?"
+		args = append(args, query.CreatedAfter.UTC())
+	}
+
+	if !query.CreatedBefore.IsZero() {
+		where += " AND i.created_at < ?"
+		args = append(args, query.CreatedBefore.UTC())
+	}
+
+	if cursor != nil {
+		createdAt := cursor.CreatedAt.UTC()
+		where += " AND (i.created_at < ? OR (i.created_at = ? AND i.instance_id < ?))"
+		args = append(args, createdAt, createdAt, cursor.InstanceID)
+	}
+
+	limit := query.Limit()
+	args = append(args, limit+1)
+
+	rows, err := b.options.ReadDB(ctx, b.db).QueryContext(
+		ctx,
+		"SELECT i.instance_id, i.execution_id, i.parent_instance_id, i.parent_schedule_event_id, i.name, i.created_at, i.completed_at "+
+			"FROM `instances` i WHERE "+where+" ORDER BY i.created_at DESC, i.instance_id DESC LIMIT ?",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying instances: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var instanceID, executionID, name string
+		var parentInstanceID sql.NullString
+		var parentEventID sql.NullInt64
+		var createdAt time.Time
+		var completedAt *time.Time
+		if err := rows.Scan(&instanceID, &executionID, &parentInstanceID, &parentEventID, &name, &createdAt, &completedAt); err != nil {
+			return nil, fmt.Errorf("scanning instance: %w", err)
+		}
+
+		if len(result.Instances) == limit {
+			last := result.Instances[limit-1]
+			result.NextPageToken = backend.EncodePageToken(last.CreatedAt, last.Instance.InstanceID)
+			break
+		}
+
+		instance := core.NewWorkflowInstance(instanceID, executionID)
+		if parentInstanceID.Valid {
+			instance = core.NewSubWorkflowInstance(instanceID, executionID, parentInstanceID.String, parentEventID.Int64)
+		}
+
+		state := backend.WorkflowStateActive
+		if completedAt != nil {
+			state = backend.WorkflowStateFinished
+		}
+
+		result.Instances = append(result.Instances, &backend.WorkflowInstanceSummary{
+			Instance:    instance,
+			Name:        name,
+			State:       state,
+			CreatedAt:   createdAt,
+			CompletedAt: completedAt,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying instances: %w", err)
+	}
+
+	return result, nil
+}
+
+func (v *visibilityStore) QueryWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*backend.InstanceQueryResult, error) {
+	return v.b.QueryWorkflowInstances(backend.PreferReadReplica(ctx), query)
+}
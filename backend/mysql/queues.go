@@ -0,0 +1,103 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+)
+
+var _ backend.ActivityQueueMigrator = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) MoveActivityQueue(ctx context.Context, from, to string, activities []string, redirect bool) (int, error) {
+	if from == to {
+		return 0, errors.New("source and target queue are the same")
+	}
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Redirecting to a redirected queue would require following chains of redirects when scheduling activities
+	var target string
+	if err := tx.QueryRowContext(ctx, "SELECT target FROM activity_queue_redirects WHERE queue = ?", to).Scan(&target); err == nil {
+		return 0, fmt.Errorf("queue %q is redirected to %q", to, target)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("reading redirect: %w", err)
+	}
+
+	if redirect {
+		if _, err := tx.ExecContext(
+			ctx, "INSERT INTO activity_queue_redirects (queue, target) VALUES (?, ?) ON DUPLICATE KEY UPDATE target = VALUES(target)", from, to); err != nil {
+			return 0, fmt.Errorf("redirecting queue: %w", err)
+		}
+
+		// Queues redirected to from are redirected to the new target directly
+		if _, err := tx.ExecContext(
+			ctx, "UPDATE activity_queue_redirects SET target = ? WHERE target = ?", to, from); err != nil {
+			return 0, fmt.Errorf("updating redirects: %w", err)
+		}
+	}
+
+	query := "UPDATE activities SET queue = ? WHERE queue = ? AND (locked_until IS NULL OR locked_until < ?)"
+	args := []interface{}{to, from, time.Now()}
+
+	if len(activities) > 0 {
+		query += " AND name IN (?" + strings.Repeat(",?", len(activities)-1) + ")"
+		for _, a := range activities {
+			args = append(args, a)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("moving activity tasks: %w", err)
+	}
+
+	moved, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	b.activityNotifier.Notify()
+
+	return int(moved), nil
+}
+
+func (b *mysqlBackend) RemoveActivityQueueRedirect(ctx context.Context, queue string) error {
+	if _, err := b.db.ExecContext(ctx, "DELETE FROM activity_queue_redirects WHERE queue = ?", queue); err != nil {
+		return fmt.Errorf("removing redirect: %w", err)
+	}
+
+	return nil
+}
+
+func (b *mysqlBackend) ActivityQueueRedirects(ctx context.Context) (map[string]string, error) {
+	rows, err := b.db.QueryContext(ctx, "SELECT queue, target FROM activity_queue_redirects")
+	if err != nil {
+		return nil, fmt.Errorf("reading redirects: %w", err)
+	}
+	defer rows.Close()
+
+	redirects := make(map[string]string)
+	for rows.Next() {
+		var queue, target string
+		if err := rows.Scan(&queue, &target); err != nil {
+			return nil, fmt.Errorf("scanning redirect: %w", err)
+		}
+
+		redirects[queue] = target
+	}
+
+	return redirects, rows.Err()
+}
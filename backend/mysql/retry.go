@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	// maxTxAttempts is how often a transaction is attempted when it fails because of a deadlock or a lock
+	// wait timeout
+	maxTxAttempts = 5
+
+	// txRetryBackoff is the delay before the first retry, it doubles for every further retry
+	txRetryBackoff = 10 * time.Millisecond
+
+	errDeadlock        = 1213
+	errLockWaitTimeout = 1205
+)
+
+// isRetryableError returns true if the error is a deadlock or a lock wait timeout. MySQL rolls back the
+// transaction in that case, so it can be retried as a whole.
+func isRetryableError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == errDeadlock || mysqlErr.Number == errLockWaitTimeout
+	}
+
+	return false
+}
+
+// retryTx calls fn, which has to run a complete transaction, again if it fails with a retryable error. Retries
+// are bounded and use a jittered exponential backoff. Once they are exhausted, the error is returned as a
+// backend.TransientError.
+func (b *mysqlBackend) retryTx(ctx context.Context, fn func() error) error {
+	backoff := txRetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isRetryableError(err) {
+			return err
+		}
+
+		if attempt >= maxTxAttempts {
+			return &backend.TransientError{Err: err}
+		}
+
+		b.Logger().Debug("Retrying transaction", "attempt", attempt, "error", err)
+
+		t := time.NewTimer(backoff/2 + time.Duration(rand.Int63n(int64(backoff))))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return &backend.TransientError{Err: err}
+		case <-t.C:
+		}
+
+		backoff *= 2
+	}
+}
@@ -4,11 +4,15 @@ CREATE TABLE IF NOT EXISTS `instances` (
   `execution_id` NVARCHAR(128) NOT NULL,
   `parent_instance_id` NVARCHAR(128) NULL,
   `parent_schedule_event_id` BIGINT NULL,
+  `priority` INT NOT NULL DEFAULT 0,
+  `last_error` TEXT NULL,
   `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `completed_at` DATETIME NULL,
   `locked_until` DATETIME NULL,
   `sticky_until` DATETIME NULL,
   `worker` NVARCHAR(64) NULL,
+  `name` NVARCHAR(256) NOT NULL DEFAULT '',
+  `queue` NVARCHAR(128) NOT NULL DEFAULT '',
 
   UNIQUE INDEX `idx_instances_instance_id` (`instance_id`),
   INDEX `idx_instances_locked_until_completed_at` (`completed_at`, `locked_until`, `sticky_until`, `worker`),
@@ -60,7 +64,86 @@ CREATE TABLE IF NOT EXISTS `activities` (
   `visible_at` DATETIME NULL,
   `locked_until` DATETIME NULL,
   `worker` NVARCHAR(64) NULL,
+  `priority` INT NOT NULL DEFAULT 0,
+  `queue` NVARCHAR(128) NOT NULL DEFAULT '',
+  `name` NVARCHAR(256) NOT NULL DEFAULT '',
+  `heartbeat_details` BLOB NULL,
 
   UNIQUE INDEX `idx_activities_instance_id` (`instance_id`, `activity_id`, `execution_id`, `worker`),
   INDEX `idx_activities_locked_until` (`locked_until`)
-);
\ No newline at end of file
+);
+
+
+CREATE TABLE IF NOT EXISTS `activity_results` (
+  `key` NVARCHAR(64) NOT NULL PRIMARY KEY,
+  `result` BLOB NULL,
+  `expires_at` DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS `workflow_state` (
+  `space` NVARCHAR(128) NOT NULL,
+  `key` NVARCHAR(256) NOT NULL,
+  `value` MEDIUMBLOB NOT NULL,
+
+  PRIMARY KEY(`space`, `key`)
+);
+
+CREATE TABLE IF NOT EXISTS `instance_tags` (
+  `instance_id` NVARCHAR(128) NOT NULL,
+  `tag` NVARCHAR(255) NOT NULL,
+
+  PRIMARY KEY(`instance_id`, `tag`),
+  INDEX `idx_instance_tags_tag` (`tag`)
+);
+
+CREATE TABLE IF NOT EXISTS `instance_runs` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `instance_id` NVARCHAR(128) NOT NULL,
+  `execution_id` NVARCHAR(128) NOT NULL,
+  `parent_instance_id` NVARCHAR(128) NULL,
+  `parent_schedule_event_id` BIGINT NULL,
+  `name` NVARCHAR(256) NOT NULL DEFAULT '',
+  `created_at` DATETIME NOT NULL,
+  `completed_at` DATETIME NOT NULL,
+
+  UNIQUE INDEX `idx_instance_runs_instance_id_execution_id` (`instance_id`, `execution_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `run_history` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `event_id` NVARCHAR(64) NOT NULL,
+  `sequence_id` BIGINT NOT NULL,
+  `instance_id` NVARCHAR(128) NOT NULL,
+  `execution_id` NVARCHAR(128) NOT NULL,
+  `event_type` INT NOT NULL,
+  `timestamp` DATETIME NOT NULL,
+  `schedule_event_id` BIGINT NOT NULL,
+  `attributes` BLOB NOT NULL,
+  `visible_at` DATETIME NULL,
+
+  INDEX `idx_run_history_instance_id_execution_id_sequence_id` (`instance_id`, `execution_id`, `sequence_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `activity_queue_redirects` (
+  `queue` NVARCHAR(128) NOT NULL PRIMARY KEY,
+  `target` NVARCHAR(128) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS `leases` (
+  `name` NVARCHAR(128) NOT NULL PRIMARY KEY,
+  `holder` NVARCHAR(128) NOT NULL,
+  `expires_at` DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS `workflow_queries` (
+  `id` NVARCHAR(64) NOT NULL PRIMARY KEY,
+  `instance_id` NVARCHAR(128) NOT NULL,
+  `execution_id` NVARCHAR(128) NOT NULL,
+  `name` NVARCHAR(256) NOT NULL,
+  `inputs` BLOB NOT NULL,
+  `deadline` DATETIME NOT NULL,
+  `locked_until` DATETIME NULL,
+  `result` BLOB NULL,
+
+  INDEX `idx_workflow_queries_deadline` (`deadline`)
+);
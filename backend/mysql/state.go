@@ -0,0 +1,101 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+var _ backend.StateStore = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) GetState(ctx context.Context, space, key string) (payload.Payload, bool, error) {
+	row := b.db.QueryRowContext(ctx, "SELECT value FROM `workflow_state` WHERE space = ? AND `key` = ?", space, key)
+
+	var value []byte
+	if err := row.Scan(&value); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("reading state: %w", err)
+	}
+
+	return value, true, nil
+}
+
+func (b *mysqlBackend) SetState(ctx context.Context, space, key string, value payload.Payload) error {
+	if _, err := b.db.ExecContext(
+		ctx,
+		"INSERT INTO `workflow_state` (space, `key`, value) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE value = VALUES(value)",
+		space,
+		key,
+		[]byte(value),
+	); err != nil {
+		return fmt.Errorf("storing state: %w", err)
+	}
+
+	return nil
+}
+
+func (b *mysqlBackend) DeleteState(ctx context.Context, space, key string) error {
+	if _, err := b.db.ExecContext(ctx, "DELETE FROM `workflow_state` WHERE space = ? AND `key` = ?", space, key); err != nil {
+		return fmt.Errorf("deleting state: %w", err)
+	}
+
+	return nil
+}
+
+func (b *mysqlBackend) IncrementState(ctx context.Context, space, key string, delta int64) (int64, error) {
+	var n int64
+
+	if err := b.retryTx(ctx, func() error {
+		tx, err := b.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("starting transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Make sure the row exists, so it can be locked
+		if _, err := tx.ExecContext(
+			ctx,
+			"INSERT IGNORE INTO `workflow_state` (space, `key`, value) VALUES (?, ?, '0')",
+			space,
+			key,
+		); err != nil {
+			return fmt.Errorf("storing state: %w", err)
+		}
+
+		row := tx.QueryRowContext(ctx, "SELECT value FROM `workflow_state` WHERE space = ? AND `key` = ? FOR UPDATE", space, key)
+
+		var value []byte
+		if err := row.Scan(&value); err != nil {
+			return fmt.Errorf("reading state: %w", err)
+		}
+
+		if n, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+			return fmt.Errorf("state %s/%s is not a counter: %w", space, key, err)
+		}
+
+		n += delta
+
+		if _, err := tx.ExecContext(
+			ctx,
+			"UPDATE `workflow_state` SET value = ? WHERE space = ? AND `key` = ?",
+			[]byte(strconv.FormatInt(n, 10)),
+			space,
+			key,
+		); err != nil {
+			return fmt.Errorf("storing state: %w", err)
+		}
+
+		return tx.Commit()
+	}); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+)
+
+var _ backend.BacklogReporter = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) GetBacklogStats(ctx context.Context) (*backend.BacklogStats, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return getBacklogStats(ctx, tx)
+}
+
+func getBacklogStats(ctx context.Context, tx *sql.Tx) (*backend.BacklogStats, error) {
+	now := time.Now()
+	stats := &backend.BacklogStats{}
+
+	// Instances with at least one visible pending event
+	row := tx.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM instances i
+			WHERE i.completed_at IS NULL AND EXISTS (
+				SELECT 1 FROM pending_events WHERE instance_id = i.instance_id AND (visible_at IS NULL OR visible_at <= ?)
+			)`,
+		now,
+	)
+	if err := row.Scan(&stats.PendingWorkflowTasks); err != nil {
+		return nil, fmt.Errorf("counting pending workflow tasks: %w", err)
+	}
+
+	row = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM `activities`")
+	if err := row.Scan(&stats.PendingActivityTasks); err != nil {
+		return nil, fmt.Errorf("counting pending activity tasks: %w", err)
+	}
+
+	row = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM `pending_events` WHERE visible_at > ?", now)
+	if err := row.Scan(&stats.FutureEvents); err != nil {
+		return nil, fmt.Errorf("counting future events: %w", err)
+	}
+
+	return stats, nil
+}
+
+// checkBackpressure returns a BackpressureError if the backlog exceeds the configured thresholds
+func checkBackpressure(ctx context.Context, tx *sql.Tx, options backend.BackpressureOptions) error {
+	if !options.CheckBacklog() {
+		return nil
+	}
+
+	stats, err := getBacklogStats(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	return options.BacklogError(stats)
+}
+
+// checkPendingEventsBackpressure returns a BackpressureError if the given instance has too many unprocessed events
+func checkPendingEventsBackpressure(ctx context.Context, tx *sql.Tx, instanceID string, options backend.BackpressureOptions) error {
+	if options.MaxPendingEventsPerInstance <= 0 {
+		return nil
+	}
+
+	var pendingEvents int64
+	row := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM `pending_events` WHERE instance_id = ?", instanceID)
+	if err := row.Scan(&pendingEvents); err != nil {
+		return fmt.Errorf("counting pending events: %w", err)
+	}
+
+	return options.PendingEventsError(pendingEvents)
+}
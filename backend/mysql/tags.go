@@ -0,0 +1,80 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+var _ backend.InstanceTagIndex = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) GetWorkflowInstancesByTags(ctx context.Context, tags []string, activeOnly bool) ([]*core.WorkflowInstance, error) {
+	if len(tags) == 0 {
+		return []*core.WorkflowInstance{}, nil
+	}
+
+	args := make([]interface{}, 0, len(tags)+1)
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	args = append(args, len(tags))
+
+	var completedFilter string
+	if activeOnly {
+		completedFilter = "AND i.completed_at IS NULL "
+	}
+
+	rows, err := b.options.ReadDB(ctx, b.db).QueryContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT i.instance_id, i.execution_id, i.parent_instance_id, i.parent_schedule_event_id FROM `instances` i INNER JOIN `instance_tags` t ON t.instance_id = i.instance_id "+
+				"WHERE t.tag IN (?%v) %vGROUP BY i.id HAVING COUNT(*) = ? ORDER BY i.created_at",
+			strings.Repeat(",?", len(tags)-1), completedFilter),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("finding instances by tags: %w", err)
+	}
+	defer rows.Close()
+
+	instances := make([]*core.WorkflowInstance, 0)
+	for rows.Next() {
+		var instanceID, executionID string
+		var parentInstanceID sql.NullString
+		var parentEventID sql.NullInt64
+		if err := rows.Scan(&instanceID, &executionID, &parentInstanceID, &parentEventID); err != nil {
+			return nil, fmt.Errorf("scanning instance: %w", err)
+		}
+
+		if parentInstanceID.Valid {
+			instances = append(instances, core.NewSubWorkflowInstance(instanceID, executionID, parentInstanceID.String, parentEventID.Int64))
+		} else {
+			instances = append(instances, core.NewWorkflowInstance(instanceID, executionID))
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("finding instances by tags: %w", err)
+	}
+
+	return instances, nil
+}
+
+func insertInstanceTags(ctx context.Context, tx *sql.Tx, instanceID string, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(
+			ctx,
+			"INSERT IGNORE INTO `instance_tags` (instance_id, tag) VALUES (?, ?)",
+			instanceID,
+			tag,
+		); err != nil {
+			return fmt.Errorf("inserting instance tag: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -1,42 +1,225 @@
 package backend
 
 import (
+	"database/sql"
 	"time"
 
+	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/logger"
+	mi "github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/trace"
 )
 
 type Options struct {
 	Logger log.Logger
 
+	// Metrics is used to emit metrics. Defaults to a client discarding all metrics.
+	Metrics metrics.Client
+
+	// Tracer is used to record trace spans. Defaults to a tracer not recording any spans.
+	Tracer trace.Tracer
+
+	// Converter is used to serialize inputs and results of workflows and activities. Clients and workers
+	// using this backend share the converter. Defaults to the JSON converter.
+	Converter converter.Converter
+
+	// StickyTimeout is how long the SQL backends route the tasks of an instance only to the worker that processed
+	// its last task, which has the executor of the instance cached. Other workers pick up the tasks once it has
+	// passed, for example when that worker stopped. 0 disables sticky execution. Defaults to 30s.
 	StickyTimeout time.Duration
 
 	WorkflowLockTimeout time.Duration
 
 	ActivityLockTimeout time.Duration
+
+	// TaskPollTimeout is the maximum time GetWorkflowTask and GetActivityTask wait for a task to become available
+	// before returning nil. The SQL backends wake up waiting pollers when new work is added in the same process,
+	// and otherwise poll with an adaptive interval. 0 returns immediately if no task is available.
+	TaskPollTimeout time.Duration
+
+	// MaxTimerSkew is the maximum time a timer fires after it is due while workers are polling for tasks.
+	// Timers never fire early. Lower values make timers more precise, at the cost of polling the backend
+	// more often. Defaults to 1s.
+	MaxTimerSkew time.Duration
+
+	// MaxActiveInstances limits the number of workflow instances that can be active at the same time. Creating
+	// a new workflow instance when the limit is reached fails with ErrMaxActiveInstancesReached. Sub-workflows
+	// are not limited, but count towards the active instances. 0 disables the limit.
+	MaxActiveInstances int
+
+	// MaxConcurrentActivitiesPerInstance limits how many activity tasks of a single workflow instance can be
+	// locked by workers at the same time. This keeps instances that schedule a large number of activities from
+	// monopolizing workers, so other instances keep making progress. 0 disables the limit.
+	MaxConcurrentActivitiesPerInstance int
+
+	// WorkflowConcurrencyLimits limit how many instances of a workflow, by workflow name, can execute tasks at
+	// the same time. An instance is executing while one of its workflow or activity tasks is locked by a worker.
+	// Tasks of other instances of the workflow stay pending until an executing instance has no locked tasks.
+	WorkflowConcurrencyLimits map[string]int
+
+	// InstanceCreationLimits limit the rate at which workflow instances are created, to protect the backend from
+	// runaway clients. Creating an instance exceeding a limit fails with a ThrottledError. Sub-workflows are not
+	// limited.
+	InstanceCreationLimits []InstanceCreationLimit
+
+	// Backpressure configures load thresholds above which creating and signaling workflow instances fails with
+	// a BackpressureError
+	Backpressure BackpressureOptions
+
+	// ReadReplica is a connection to a read replica of the database of the SQL backends. Read-only operations
+	// called with a context marked by PreferReadReplica are served from it. nil serves all reads from the primary.
+	ReadReplica *sql.DB
+
+	// HistoryExporter is notified about all events appended to the history of workflow instances. nil disables
+	// exporting history events.
+	HistoryExporter HistoryExporter
+
+	// Retention is how long finished workflow instances are kept before they are removed together with their
+	// history. 0 keeps finished instances forever.
+	Retention time.Duration
 }
 
 var DefaultOptions Options = Options{
 	StickyTimeout:       30 * time.Second,
 	WorkflowLockTimeout: time.Minute,
 	ActivityLockTimeout: time.Minute * 2,
+	TaskPollTimeout:     time.Second * 5,
+	MaxTimerSkew:        time.Second,
 }
 
 type BackendOption func(*Options)
 
+// WithStickyTimeout sets how long the tasks of an instance are only handed to the worker that processed its last
+// task. 0 disables sticky execution.
 func WithStickyTimeout(timeout time.Duration) BackendOption {
 	return func(o *Options) {
 		o.StickyTimeout = timeout
 	}
 }
 
+// WithWorkflowLockTimeout sets the time after which a locked workflow task is considered abandoned and
+// can be picked up by another worker.
+func WithWorkflowLockTimeout(timeout time.Duration) BackendOption {
+	return func(o *Options) {
+		o.WorkflowLockTimeout = timeout
+	}
+}
+
+// WithActivityLockTimeout sets the time after which a locked activity task is considered abandoned and
+// can be picked up by another worker.
+func WithActivityLockTimeout(timeout time.Duration) BackendOption {
+	return func(o *Options) {
+		o.ActivityLockTimeout = timeout
+	}
+}
+
+// WithTaskPollTimeout sets the maximum time to wait for a workflow or activity task to become available
+func WithTaskPollTimeout(timeout time.Duration) BackendOption {
+	return func(o *Options) {
+		o.TaskPollTimeout = timeout
+	}
+}
+
+// WithMaxTimerSkew sets the maximum time a timer fires after it is due while workers are polling
+func WithMaxTimerSkew(skew time.Duration) BackendOption {
+	return func(o *Options) {
+		o.MaxTimerSkew = skew
+	}
+}
+
+// WithMaxActiveInstances limits the number of concurrently active workflow instances
+func WithMaxActiveInstances(n int) BackendOption {
+	return func(o *Options) {
+		o.MaxActiveInstances = n
+	}
+}
+
+// WithMaxConcurrentActivitiesPerInstance limits the number of activity tasks of a single workflow instance
+// that are executed at the same time
+func WithMaxConcurrentActivitiesPerInstance(n int) BackendOption {
+	return func(o *Options) {
+		o.MaxConcurrentActivitiesPerInstance = n
+	}
+}
+
+// WithWorkflowConcurrencyLimit limits the number of instances of the workflow with the given name that execute
+// tasks at the same time
+func WithWorkflowConcurrencyLimit(workflowName string, n int) BackendOption {
+	return func(o *Options) {
+		if o.WorkflowConcurrencyLimits == nil {
+			o.WorkflowConcurrencyLimits = make(map[string]int)
+		}
+
+		o.WorkflowConcurrencyLimits[workflowName] = n
+	}
+}
+
+// WithInstanceCreationLimit limits the creation of workflow instances with a name starting with workflowPrefix to
+// rate instances per second, allowing bursts of up to burst instances. An empty prefix limits all instances.
+func WithInstanceCreationLimit(workflowPrefix string, rate float64, burst int) BackendOption {
+	return func(o *Options) {
+		o.InstanceCreationLimits = append(o.InstanceCreationLimits, InstanceCreationLimit{
+			WorkflowPrefix: workflowPrefix,
+			Rate:           rate,
+			Burst:          burst,
+		})
+	}
+}
+
+// WithBackpressure rejects creating and signaling workflow instances while the backend load exceeds the given
+// thresholds
+func WithBackpressure(o BackpressureOptions) BackendOption {
+	return func(opts *Options) {
+		opts.Backpressure = o
+	}
+}
+
+// WithReadReplica sets a read replica of the database of the SQL backends, see PreferReadReplica
+func WithReadReplica(db *sql.DB) BackendOption {
+	return func(o *Options) {
+		o.ReadReplica = db
+	}
+}
+
 func WithLogger(logger log.Logger) BackendOption {
 	return func(o *Options) {
 		o.Logger = logger
 	}
 }
 
+// WithMetrics sets the client used to emit metrics
+func WithMetrics(client metrics.Client) BackendOption {
+	return func(o *Options) {
+		o.Metrics = client
+	}
+}
+
+// WithTracer sets the tracer used to record trace spans
+func WithTracer(tracer trace.Tracer) BackendOption {
+	return func(o *Options) {
+		o.Tracer = tracer
+	}
+}
+
+// WithConverter sets the converter used for workflow and activity inputs and results
+func WithConverter(c converter.Converter) BackendOption {
+	return func(o *Options) {
+		o.Converter = c
+	}
+}
+
+// WithRetention removes finished workflow instances and their history once they finished more than retention
+// ago. The SQL backends are cleaned up by a background job of the workers, the Redis backend lets the keys of
+// finished instances expire.
+func WithRetention(retention time.Duration) BackendOption {
+	return func(o *Options) {
+		o.Retention = retention
+	}
+}
+
 func ApplyOptions(opts ...BackendOption) Options {
 	options := DefaultOptions
 
@@ -44,9 +227,25 @@ func ApplyOptions(opts ...BackendOption) Options {
 		opt(&options)
 	}
 
+	if options.MaxTimerSkew <= 0 {
+		options.MaxTimerSkew = time.Second
+	}
+
 	if options.Logger == nil {
 		options.Logger = logger.NewDefaultLogger()
 	}
 
+	if options.Metrics == nil {
+		options.Metrics = mi.NewNoopMetricsClient()
+	}
+
+	if options.Tracer == nil {
+		options.Tracer = tracing.NewNoopTracer()
+	}
+
+	if options.Converter == nil {
+		options.Converter = converter.DefaultConverter
+	}
+
 	return options
 }
@@ -0,0 +1,149 @@
+package backend
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+const (
+	// DefaultQueryPageSize is the number of instances returned per page if a query doesn't set a page size
+	DefaultQueryPageSize = 100
+
+	// MaxQueryPageSize is the maximum number of instances returned per page
+	MaxQueryPageSize = 1000
+)
+
+// ErrInvalidPageToken is returned when querying instances with a page token not returned by a previous query
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// InstanceQuery selects a page of workflow instances. Instances have to match all of the given criteria.
+type InstanceQuery struct {
+	// States restricts the instances to the given states. Empty for instances in any state.
+	States []WorkflowState
+
+	// InstanceIDPrefix restricts the instances to those with an instance ID starting with the prefix
+	InstanceIDPrefix string
+
+	// CreatedAfter restricts the instances to those created at or after the given time, if set
+	CreatedAfter time.Time
+
+	// CreatedBefore restricts the instances to those created before the given time, if set
+	CreatedBefore time.Time
+
+	// PageSize is the maximum number of instances returned. Defaults to DefaultQueryPageSize, and is limited to
+	// MaxQueryPageSize.
+	PageSize int
+
+	// PageToken continues a previous query with the same criteria, pass the NextPageToken of its result
+	PageToken string
+}
+
+// Limit returns the number of instances to return for the query
+func (q InstanceQuery) Limit() int {
+	switch {
+	case q.PageSize <= 0:
+		return DefaultQueryPageSize
+	case q.PageSize > MaxQueryPageSize:
+		return MaxQueryPageSize
+	}
+
+	return q.PageSize
+}
+
+// MatchesState returns whether an instance in the given state matches the state criteria of the query
+func (q InstanceQuery) MatchesState(state WorkflowState) bool {
+	return InstanceFilter{States: q.States}.MatchesState(state)
+}
+
+// Matches returns whether an instance with the given ID, state, and creation time matches the criteria of the
+// query, not taking the page token into account
+func (q InstanceQuery) Matches(instanceID string, state WorkflowState, createdAt time.Time) bool {
+	if !q.MatchesState(state) || !strings.HasPrefix(instanceID, q.InstanceIDPrefix) {
+		return false
+	}
+
+	if !q.CreatedAfter.IsZero() && createdAt.Before(q.CreatedAfter) {
+		return false
+	}
+
+	if !q.CreatedBefore.IsZero() && !createdAt.Before(q.CreatedBefore) {
+		return false
+	}
+
+	return true
+}
+
+// WorkflowInstanceSummary describes a workflow instance returned by a query
+type WorkflowInstanceSummary struct {
+	Instance *core.WorkflowInstance `json:"instance"`
+
+	// Name is the name of the workflow
+	Name string `json:"name,omitempty"`
+
+	State WorkflowState `json:"state"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// CompletedAt is when the instance finished, nil if it is still active
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// InstanceQueryResult is a page of workflow instances
+type InstanceQueryResult struct {
+	// Instances are the instances of the page, newest first
+	Instances []*WorkflowInstanceSummary `json:"instances"`
+
+	// NextPageToken continues the query with the next page, empty if this is the last page
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+// InstanceQuerier is an optional interface a backend or visibility store can implement to query workflow
+// instances page by page
+type InstanceQuerier interface {
+	// QueryWorkflowInstances returns a page of the workflow instances matching the query, newest first. Returns
+	// ErrInvalidPageToken if the page token of the query is invalid.
+	QueryWorkflowInstances(ctx context.Context, query InstanceQuery) (*InstanceQueryResult, error)
+}
+
+// PageCursor is the position of the last instance of a page. The next page starts with the instances created
+// before it, or created at the same time with a smaller instance ID.
+type PageCursor struct {
+	CreatedAt  time.Time `json:"c"`
+	InstanceID string    `json:"i"`
+}
+
+// After returns whether an instance with the given creation time and ID belongs to a page after the cursor
+func (c *PageCursor) After(createdAt time.Time, instanceID string) bool {
+	return createdAt.Before(c.CreatedAt) || (createdAt.Equal(c.CreatedAt) && instanceID < c.InstanceID)
+}
+
+// EncodePageToken returns the page token for continuing a query after the given instance
+func EncodePageToken(createdAt time.Time, instanceID string) string {
+	data, _ := json.Marshal(&PageCursor{CreatedAt: createdAt, InstanceID: instanceID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePageToken returns the cursor of the given page token, nil for an empty token
+func DecodePageToken(token string) (*PageCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+
+	var c PageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.InstanceID == "" {
+		return nil, ErrInvalidPageToken
+	}
+
+	return &c, nil
+}
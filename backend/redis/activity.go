@@ -2,38 +2,166 @@ package redis
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/redis/taskqueue"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/go-redis/redis/v8"
 )
 
+var _ backend.ActivityQueueProvider = (*redisBackend)(nil)
+
+const activityHeartbeatExpiration = 24 * time.Hour
+
 func (rb *redisBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
-	activityTask, err := rb.activityQueue.Dequeue(ctx, rb.options.ActivityLockTimeout, rb.options.BlockTimeout)
-	if err != nil {
-		return nil, err
+	return rb.GetActivityTaskFromQueues(ctx, []string{backend.DefaultActivityQueue})
+}
+
+func (rb *redisBackend) GetActivityTaskFromQueues(ctx context.Context, queues []string) (*task.Activity, error) {
+	defer backend.MeasureOperation(rb.options.Metrics, "redis", backend.OperationGetActivityTask)()
+
+	if len(queues) == 0 {
+		queues = []string{backend.DefaultActivityQueue}
+	}
+
+	// Split the block timeout between the queues, since each queue is a separate stream
+	blockTimeout := rb.options.BlockTimeout
+	if len(queues) > 1 {
+		blockTimeout /= time.Duration(len(queues))
+		if blockTimeout < time.Millisecond {
+			blockTimeout = time.Millisecond
+		}
 	}
 
-	if activityTask == nil {
-		return nil, nil
+	for _, queueName := range queues {
+		activityQueue, err := rb.activityQueueFor(queueName)
+		if err != nil {
+			return nil, err
+		}
+
+		activityTask, err := rb.activityTasks.dequeue(ctx, activityQueue, queueName, rb.options.BatchSize, rb.options.ActivityLockTimeout, blockTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if activityTask == nil {
+			continue
+		}
+
+		id := queueTaskID(queueName, activityTask.TaskID) // Use the queue generated ID here
+
+		heartbeatDetails, err := rb.rdb.Get(ctx, activityHeartbeatKey(id)).Bytes()
+		if err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("reading heartbeat details: %w", err)
+		}
+
+		return &task.Activity{
+			WorkflowInstance: activityTask.Data.Instance,
+			ID:               id,
+			Event:            activityTask.Data.Event,
+			ScheduledAt:      task.ScheduledAt(activityTask.Data.Event),
+			HeartbeatDetails: heartbeatDetails,
+		}, nil
 	}
 
-	return &task.Activity{
-		WorkflowInstance: activityTask.Data.Instance,
-		ID:               activityTask.TaskID, // Use the queue generated ID here
-		Event:            activityTask.Data.Event,
-	}, nil
+	return nil, nil
 }
 
 func (rb *redisBackend) ExtendActivityTask(ctx context.Context, activityID string) error {
-	return rb.activityQueue.Extend(ctx, activityID)
+	activityQueue, taskID, err := rb.activityQueueForTask(activityID)
+	if err != nil {
+		return err
+	}
+
+	return activityQueue.Extend(ctx, taskID)
+}
+
+var _ backend.ActivityHeartbeater = (*redisBackend)(nil)
+
+func (rb *redisBackend) HeartbeatActivityTask(ctx context.Context, activityID string, details payload.Payload) error {
+	if err := rb.ExtendActivityTask(ctx, activityID); err != nil {
+		return err
+	}
+
+	// The details are only needed while the task is pending, expire them in case the task is never completed
+	if err := rb.rdb.Set(ctx, activityHeartbeatKey(activityID), []byte(details), activityHeartbeatExpiration).Err(); err != nil {
+		return fmt.Errorf("storing heartbeat details: %w", err)
+	}
+
+	return nil
 }
 
 func (rb *redisBackend) CompleteActivityTask(ctx context.Context, instance *core.WorkflowInstance, activityID string, event history.Event) error {
+	defer backend.MeasureOperation(rb.options.Metrics, "redis", backend.OperationCompleteActivityTask)()
+
+	activityQueue, taskID, err := rb.activityQueueForTask(activityID)
+	if err != nil {
+		return err
+	}
+
 	if err := rb.addWorkflowInstanceEvent(ctx, instance, &event); err != nil {
 		return err
 	}
 
+	if err := rb.rdb.Del(ctx, activityHeartbeatKey(activityID)).Err(); err != nil {
+		return fmt.Errorf("deleting heartbeat details: %w", err)
+	}
+
 	// Unlock activity
-	return rb.activityQueue.Complete(ctx, activityID)
+	return activityQueue.Complete(ctx, taskID)
+}
+
+// activityQueueFor returns the task queue for the given activity queue name
+func (rb *redisBackend) activityQueueFor(queueName string) (taskqueue.TaskQueue[activityData], error) {
+	if queueName == backend.DefaultActivityQueue {
+		return rb.activityQueue, nil
+	}
+
+	rb.activityQueuesMu.Lock()
+	defer rb.activityQueuesMu.Unlock()
+
+	if q, ok := rb.activityQueues[queueName]; ok {
+		return q, nil
+	}
+
+	q, err := taskqueue.New[activityData](rb.rdb, "activities:"+queueName, rb.options.queueOptions(rb.options.ActivityQueueOptions)...)
+	if err != nil {
+		return nil, fmt.Errorf("creating activity task queue: %w", err)
+	}
+
+	rb.activityQueues[queueName] = q
+
+	return q, nil
+}
+
+// queueTaskID returns the ID handed out to workers for the given task. Tasks from a non-default queue are
+// prefixed with the queue name, stream generated task IDs never contain a '/'.
+func queueTaskID(queueName, taskID string) string {
+	if queueName == "" {
+		return taskID
+	}
+
+	return queueName + "/" + taskID
+}
+
+// splitQueueTaskID returns the queue name and the stream generated task ID of an ID returned by queueTaskID
+func splitQueueTaskID(id string) (string, string) {
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		return id[:i], id[i+1:]
+	}
+
+	return "", id
+}
+
+func (rb *redisBackend) activityQueueForTask(activityID string) (taskqueue.TaskQueue[activityData], string, error) {
+	queueName, taskID := splitQueueTaskID(activityID)
+
+	q, err := rb.activityQueueFor(queueName)
+	return q, taskID, err
 }
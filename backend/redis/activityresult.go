@@ -0,0 +1,34 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/go-redis/redis/v8"
+)
+
+var _ backend.ActivityResultCache = (*redisBackend)(nil)
+
+func (rb *redisBackend) GetActivityResult(ctx context.Context, key string) (payload.Payload, bool, error) {
+	result, err := rb.rdb.Get(ctx, activityResultKey(key)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("reading activity result: %w", err)
+	}
+
+	return result, true, nil
+}
+
+func (rb *redisBackend) StoreActivityResult(ctx context.Context, key string, result payload.Payload, ttl time.Duration) error {
+	if err := rb.rdb.Set(ctx, activityResultKey(key), []byte(result), ttl).Err(); err != nil {
+		return fmt.Errorf("storing activity result: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/redis/taskqueue"
+)
+
+// taskBuffer holds tasks dequeued in a batch, which have not been handed out yet, by queue
+type taskBuffer[T any] struct {
+	mu    sync.Mutex
+	tasks map[string][]bufferedTask[T]
+}
+
+type bufferedTask[T any] struct {
+	task       *taskqueue.TaskItem[T]
+	dequeuedAt time.Time
+}
+
+// dequeue returns the next task of the given queue. With a batch size larger than 1, up to batchSize tasks are
+// dequeued at once and the remaining ones are handed out by the next calls.
+func (b *taskBuffer[T]) dequeue(
+	ctx context.Context, q taskqueue.TaskQueue[T], queueName string, batchSize int, lockTimeout, blockTimeout time.Duration,
+) (*taskqueue.TaskItem[T], error) {
+	if batchSize <= 1 {
+		return q.Dequeue(ctx, lockTimeout, blockTimeout)
+	}
+
+	if task, err := b.next(ctx, q, queueName, lockTimeout); err != nil || task != nil {
+		return task, err
+	}
+
+	tasks, err := q.DequeueBatch(ctx, lockTimeout, blockTimeout, batchSize)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tasks == nil {
+		b.tasks = map[string][]bufferedTask[T]{}
+	}
+
+	now := time.Now()
+	for _, t := range tasks[1:] {
+		b.tasks[queueName] = append(b.tasks[queueName], bufferedTask[T]{task: t, dequeuedAt: now})
+	}
+
+	return tasks[0], nil
+}
+
+// next returns the next buffered task of the given queue. Tasks whose lock has expired are skipped, they might
+// have been recovered by another worker in the meantime.
+func (b *taskBuffer[T]) next(ctx context.Context, q taskqueue.TaskQueue[T], queueName string, lockTimeout time.Duration) (*taskqueue.TaskItem[T], error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.tasks[queueName]) > 0 {
+		t := b.tasks[queueName][0]
+		b.tasks[queueName] = b.tasks[queueName][1:]
+
+		waited := time.Since(t.dequeuedAt)
+		if waited >= lockTimeout {
+			continue
+		}
+
+		// Renew the lock of tasks that have been waiting for a while, so the worker gets the full lock timeout
+		if waited >= lockTimeout/2 {
+			if err := q.Extend(ctx, t.task.TaskID); err != nil {
+				return nil, err
+			}
+		}
+
+		return t.task, nil
+	}
+
+	return nil, nil
+}
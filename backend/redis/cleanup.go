@@ -0,0 +1,116 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/go-redis/redis/v8"
+)
+
+var _ backend.FinishedInstanceCleaner = (*redisBackend)(nil)
+
+const cleanupBatchSize = 100
+
+// removeExpiredInstancesCmd removes the given instances from the instance indexes if their keys have expired, and
+// returns the instances that still exist.
+//
+// KEYS[1] = instances by creation
+// KEYS[2] = instances by completion
+// KEYS[3..n] = instance keys
+// ARGV[1..n] = instance IDs
+var removeExpiredInstancesCmd = redis.NewScript(`
+	local existing = {}
+	for i = 1, #ARGV do
+		if redis.call("EXISTS", KEYS[i + 2]) == 1 then
+			table.insert(existing, ARGV[i])
+		else
+			redis.call("ZREM", KEYS[1], ARGV[i])
+			redis.call("ZREM", KEYS[2], ARGV[i])
+		end
+	end
+
+	return existing
+`)
+
+// CleanupFinishedInstances removes all workflow instances that finished more than olderThan ago. Redis removes the
+// keys of finished instances once they expire, see WithAutoExpiration, this removes the expired instances from the
+// index used to list instances, and removes instances that have not expired yet.
+func (rb *redisBackend) CleanupFinishedInstances(ctx context.Context, olderThan time.Duration) error {
+	cutoff := time.Now().Add(-olderThan)
+
+	for {
+		instanceIDs, err := rb.rdb.ZRangeByScore(ctx, instancesByCompletion(), &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   strconv.FormatInt(cutoff.UnixMilli(), 10),
+			Count: cleanupBatchSize,
+		}).Result()
+		if err != nil {
+			return fmt.Errorf("finding finished instances: %w", err)
+		}
+
+		if len(instanceIDs) == 0 {
+			return nil
+		}
+
+		if err := rb.cleanupFinishedInstancesBatch(ctx, instanceIDs); err != nil {
+			return err
+		}
+
+		if len(instanceIDs) < cleanupBatchSize {
+			return nil
+		}
+	}
+}
+
+var _ backend.RetentionProvider = (*redisBackend)(nil)
+
+// Retention returns how long finished instances are kept before they expire, see WithAutoExpiration
+func (rb *redisBackend) Retention() time.Duration {
+	return rb.options.AutoExpiration
+}
+
+func (rb *redisBackend) cleanupFinishedInstancesBatch(ctx context.Context, instanceIDs []string) error {
+	keys := []string{instancesByCreation(), instancesByCompletion()}
+	args := make([]interface{}, 0, len(instanceIDs))
+	for _, instanceID := range instanceIDs {
+		keys = append(keys, instanceKey(instanceID))
+		args = append(args, instanceID)
+	}
+
+	existing, err := removeExpiredInstancesCmd.Run(ctx, rb.rdb, keys, args...).StringSlice()
+	if err != nil {
+		return fmt.Errorf("removing expired instances: %w", err)
+	}
+
+	// Remove instances that have not expired yet
+	for _, instanceID := range existing {
+		state, err := readInstance(ctx, rb.rdb, instanceID)
+		if err != nil {
+			if errors.Is(err, backend.ErrInstanceNotFound) {
+				// Expired in the meantime, the next cleanup removes the reference
+				continue
+			}
+
+			return err
+		}
+
+		if state.State != backend.WorkflowStateFinished {
+			// The instance has been started again, it's added back once it finishes
+			if err := rb.rdb.ZRem(ctx, instancesByCompletion(), instanceID).Err(); err != nil {
+				return fmt.Errorf("removing finished instance: %w", err)
+			}
+
+			continue
+		}
+
+		if err := rb.RemoveWorkflowInstance(ctx, state.Instance); err != nil && !errors.Is(err, backend.ErrInstanceNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+var _ backend.HistoryCompactor = (*redisBackend)(nil)
+
+func (rb *redisBackend) CompactWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance) (int, error) {
+	msgs, err := rb.rdb.XRange(ctx, historyKey(instance.InstanceID), "-", "+").Result()
+	if err != nil {
+		return 0, err
+	}
+
+	events := make([]history.Event, 0, len(msgs))
+	messageIDs := make(map[string]string, len(msgs))
+
+	for _, msg := range msgs {
+		var event history.Event
+		if err := json.Unmarshal([]byte(msg.Values["event"].(string)), &event); err != nil {
+			return 0, fmt.Errorf("unmarshaling event: %w", err)
+		}
+
+		events = append(events, event)
+		messageIDs[event.ID] = msg.ID
+	}
+
+	superseded := history.SupersededEvents(events)
+	if len(superseded) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]string, 0, len(superseded))
+	for _, event := range superseded {
+		ids = append(ids, messageIDs[event.ID])
+	}
+
+	if err := rb.rdb.XDel(ctx, historyKey(instance.InstanceID), ids...).Err(); err != nil {
+		return 0, fmt.Errorf("removing history events: %w", err)
+	}
+
+	return len(superseded), nil
+}
@@ -53,6 +53,11 @@ func (rb *redisBackend) GetWorkflowInstances(ctx context.Context, afterInstanceI
 
 	var instanceRefs []*diag.WorkflowInstanceRef
 	for _, instance := range instances {
+		if instance == nil {
+			// Instance has expired, but the reference in the instancesByCreation set is still there
+			continue
+		}
+
 		var state instanceState
 		if err := json.Unmarshal([]byte(instance.(string)), &state); err != nil {
 			return nil, fmt.Errorf("unmarshaling instance state: %w", err)
@@ -63,6 +68,7 @@ func (rb *redisBackend) GetWorkflowInstances(ctx context.Context, afterInstanceI
 			CreatedAt:   state.CreatedAt,
 			CompletedAt: state.CompletedAt,
 			State:       state.State,
+			LastError:   state.LastError,
 		})
 	}
 
@@ -80,5 +86,6 @@ func (rb *redisBackend) GetWorkflowInstance(ctx context.Context, instanceID stri
 		CreatedAt:   instance.CreatedAt,
 		CompletedAt: instance.CompletedAt,
 		State:       instance.State,
+		LastError:   instance.LastError,
 	}, nil
 }
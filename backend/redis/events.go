@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
@@ -30,6 +32,63 @@ func addEventToStream(ctx context.Context, rdb redis.UniversalClient, streamKey
 	return &msgID, nil
 }
 
+// addEventsToStream adds the given events to the stream in a single transaction and returns the message ID of the
+// last event
+func addEventsToStream(ctx context.Context, rdb redis.UniversalClient, streamKey string, events []history.Event) (*string, error) {
+	cmds := make([]*redis.StringCmd, 0, len(events))
+
+	if _, err := rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for _, event := range events {
+			eventData, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+
+			cmds = append(cmds, p.XAdd(ctx, &redis.XAddArgs{
+				Stream: streamKey,
+				ID:     "*",
+				Values: map[string]interface{}{
+					"event": string(eventData),
+				},
+			}))
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("adding events to stream: %w", err)
+	}
+
+	msgID := cmds[len(cmds)-1].Val()
+
+	return &msgID, nil
+}
+
+// addEventsToStreamInPipeline adds the given events to the stream as part of the given pipeline
+func addEventsToStreamInPipeline(ctx context.Context, p redis.Pipeliner, streamKey string, events []history.Event) error {
+	for _, event := range events {
+		eventData, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		p.XAdd(ctx, &redis.XAddArgs{
+			Stream: streamKey,
+			ID:     "*",
+			Values: map[string]interface{}{
+				"event": string(eventData),
+			},
+		})
+	}
+
+	return nil
+}
+
+// futureEventScore returns the score of a future event becoming visible at the given time. Scores are Unix
+// seconds with millisecond precision, so that events are not delivered early.
+func futureEventScore(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', 3, 64)
+}
+
 // KEYS[1] - future event zset key
 // KEYS[2] - future event key
 // ARGV[1] - timestamp
@@ -39,7 +98,8 @@ var addFutureEventCmd = redis.NewScript(`
 	redis.call("SET", KEYS[2], ARGV[2])
 `)
 
-func addFutureEvent(ctx context.Context, rdb redis.UniversalClient, instance *core.WorkflowInstance, event *history.Event) error {
+// addFutureEvent adds the given event to the future events as part of the given pipeline
+func addFutureEvent(ctx context.Context, p redis.Pipeliner, instance *core.WorkflowInstance, event *history.Event) error {
 	futureEvent := &futureEvent{
 		Instance: instance,
 		Event:    event,
@@ -50,15 +110,14 @@ func addFutureEvent(ctx context.Context, rdb redis.UniversalClient, instance *co
 		return err
 	}
 
-	if err := addFutureEventCmd.Run(
+	// Scripts cannot be loaded on demand in a pipeline, so send the full script
+	addFutureEventCmd.Eval(
 		ctx,
-		rdb,
+		p,
 		[]string{futureEventsKey(), futureEventKey(instance.InstanceID, event.ScheduleEventID)},
-		event.VisibleAt.Unix(),
+		futureEventScore(*event.VisibleAt),
 		string(eventData),
-	).Err(); err != nil && err != redis.Nil {
-		return fmt.Errorf("adding future event: %w", err)
-	}
+	)
 
 	return nil
 }
@@ -70,12 +129,9 @@ var removeFutureEventCmd = redis.NewScript(`
 	redis.call("DEL", KEYS[2])
 `)
 
-func removeFutureEvent(ctx context.Context, rdb redis.UniversalClient, instance *core.WorkflowInstance, event *history.Event) error {
+// removeFutureEvent removes the future event scheduled by the given event as part of the given pipeline
+func removeFutureEvent(ctx context.Context, p redis.Pipeliner, instance *core.WorkflowInstance, event *history.Event) {
 	key := futureEventKey(instance.InstanceID, event.ScheduleEventID)
 
-	if err := removeFutureEventCmd.Run(ctx, rdb, []string{futureEventsKey(), key}).Err(); err != nil && err != redis.Nil {
-		return fmt.Errorf("removing future event: %w", err)
-	}
-
-	return nil
+	removeFutureEventCmd.Eval(ctx, p, []string{futureEventsKey(), key})
 }
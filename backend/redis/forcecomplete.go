@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+var _ backend.InstanceForceCompleter = (*redisBackend)(nil)
+var _ backend.InstanceTerminator = (*redisBackend)(nil)
+
+// ForceCompleteWorkflowInstance finishes the given instance. Activities that have already been queued for
+// the instance are still executed, their results are ignored.
+func (rb *redisBackend) ForceCompleteWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, event history.Event, workflowEvents []history.WorkflowEvent) error {
+	instanceState, err := readInstance(ctx, rb.rdb, instance.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	if instanceState.Instance.ExecutionID != instance.ExecutionID {
+		return backend.ErrInstanceNotFound
+	}
+
+	if instanceState.State == backend.WorkflowStateFinished {
+		return backend.ErrInstanceFinished
+	}
+
+	event.SequenceID = instanceState.LastSequenceID + 1
+	if _, err := addEventToStream(ctx, rb.rdb, historyKey(instance.InstanceID), &event); err != nil {
+		return err
+	}
+
+	t := time.Now()
+	instanceState.State = backend.WorkflowStateFinished
+	instanceState.CompletedAt = &t
+	instanceState.LastSequenceID = event.SequenceID
+
+	if err := updateInstance(ctx, rb.rdb, instance.InstanceID, instanceState); err != nil {
+		return fmt.Errorf("updating workflow instance: %w", err)
+	}
+
+	if err := rb.rdb.SRem(ctx, activeInstancesKey(), instance.InstanceID).Err(); err != nil {
+		return fmt.Errorf("removing instance from active instances: %w", err)
+	}
+
+	if err := rb.rdb.Del(ctx, pendingEventsKey(instance.InstanceID)).Err(); err != nil {
+		return fmt.Errorf("removing pending events: %w", err)
+	}
+
+	for _, e := range workflowEvents {
+		msgID, err := addEventToStream(ctx, rb.rdb, pendingEventsKey(e.WorkflowInstance.InstanceID), &e.HistoryEvent)
+		if err != nil {
+			return err
+		}
+
+		if err := rb.queueWorkflowTask(ctx, e.WorkflowInstance.InstanceID, *msgID); err != nil {
+			return fmt.Errorf("queueing workflow task: %w", err)
+		}
+	}
+
+	// Notify any waiting clients that the instance is done
+	if err := rb.rdb.Publish(ctx, instanceCompletionChannel(instance.InstanceID), instance.ExecutionID).Err(); err != nil {
+		return fmt.Errorf("publishing workflow instance completion: %w", err)
+	}
+
+	if rb.options.AutoExpiration > 0 {
+		if err := setInstanceExpiration(ctx, rb.rdb, instance.InstanceID, rb.options.AutoExpiration); err != nil {
+			return fmt.Errorf("setting workflow instance expiration: %w", err)
+		}
+	}
+
+	if rb.options.HistoryExporter != nil {
+		rb.options.HistoryExporter.ExportHistory(instance, backend.WorkflowStateFinished, []history.Event{event})
+	}
+
+	return nil
+}
+
+// TerminateWorkflowInstance finishes the given instance like force-completing it, only the recorded event differs
+func (rb *redisBackend) TerminateWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, event history.Event, workflowEvents []history.WorkflowEvent) error {
+	return rb.ForceCompleteWorkflowInstance(ctx, instance, event, workflowEvents)
+}
@@ -3,19 +3,46 @@ package redis
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
-	"github.com/cschleiden/go-workflows/backend/redis/taskqueue"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/go-redis/redis/v8"
 )
 
 func (rb *redisBackend) CreateWorkflowInstance(ctx context.Context, event history.WorkflowEvent) error {
-	if err := createInstance(ctx, rb.rdb, event.WorkflowInstance, false); err != nil {
+	defer backend.MeasureOperation(rb.options.Metrics, "redis", backend.OperationCreateWorkflowInstance)()
+
+	if err := rb.throttler.Allow(event.HistoryEvent); err != nil {
+		return err
+	}
+
+	if err := rb.checkBackpressure(ctx); err != nil {
+		return err
+	}
+
+	if rb.options.MaxActiveInstances > 0 {
+		active, err := rb.rdb.SCard(ctx, activeInstancesKey()).Result()
+		if err != nil {
+			return fmt.Errorf("counting active workflow instances: %w", err)
+		}
+
+		if active >= int64(rb.options.MaxActiveInstances) {
+			return backend.ErrMaxActiveInstancesReached
+		}
+	}
+
+	return rb.createWorkflowInstance(ctx, event)
+}
+
+func (rb *redisBackend) createWorkflowInstance(ctx context.Context, event history.WorkflowEvent) error {
+	if err := createInstance(ctx, rb.rdb, event.WorkflowInstance, history.WorkflowQueue([]history.Event{event.HistoryEvent}), false); err != nil {
+		return err
+	}
+
+	if err := addInstanceTags(ctx, rb.rdb, event.WorkflowInstance.InstanceID, history.AddedTags([]history.Event{event.HistoryEvent})); err != nil {
 		return err
 	}
 
@@ -37,12 +64,8 @@ func (rb *redisBackend) CreateWorkflowInstance(ctx context.Context, event histor
 	}
 
 	// Queue workflow instance task
-	if _, err := rb.workflowQueue.Enqueue(ctx, event.WorkflowInstance.InstanceID, &workflowTaskData{
-		LastPendingEventMessageID: msgID,
-	}); err != nil {
-		if err != taskqueue.ErrTaskAlreadyInQueue {
-			return fmt.Errorf("queueing workflow task: %w", err)
-		}
+	if err := rb.queueWorkflowTask(ctx, event.WorkflowInstance.InstanceID, msgID); err != nil {
+		return fmt.Errorf("queueing workflow task: %w", err)
 	}
 
 	rb.options.Logger.Debug("Created new workflow instance")
@@ -51,6 +74,10 @@ func (rb *redisBackend) CreateWorkflowInstance(ctx context.Context, event histor
 }
 
 func (rb *redisBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64) ([]history.Event, error) {
+	if lastSequenceID != nil {
+		return readHistoryAfter(ctx, rb.rdb, historyKey(instance.InstanceID), *lastSequenceID)
+	}
+
 	msgs, err := rb.rdb.XRange(ctx, historyKey(instance.InstanceID), "-", "+").Result()
 	if err != nil {
 		return nil, err
@@ -69,15 +96,127 @@ func (rb *redisBackend) GetWorkflowInstanceHistory(ctx context.Context, instance
 	return events, nil
 }
 
+// historyPageSize is the number of history events read at once when reading the end of a history
+const historyPageSize = 100
+
+// readHistoryAfter returns the events of the given history stream with a sequence ID after lastSequenceID. The
+// stream is read from its end, so only the requested events and at most one page more are read.
+func readHistoryAfter(ctx context.Context, rdb redis.UniversalClient, key string, lastSequenceID int64) ([]history.Event, error) {
+	var events []history.Event
+
+	end := "+"
+	for {
+		msgs, err := rdb.XRevRangeN(ctx, key, end, "-", historyPageSize).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, msg := range msgs {
+			var event history.Event
+			if err := json.Unmarshal([]byte(msg.Values["event"].(string)), &event); err != nil {
+				return nil, fmt.Errorf("unmarshaling event: %w", err)
+			}
+
+			if event.SequenceID <= lastSequenceID {
+				return reverseEvents(events), nil
+			}
+
+			events = append(events, event)
+		}
+
+		if len(msgs) < historyPageSize {
+			return reverseEvents(events), nil
+		}
+
+		// Continue before the oldest message read
+		end = "(" + msgs[len(msgs)-1].ID
+	}
+}
+
+func reverseEvents(events []history.Event) []history.Event {
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+
+	return events
+}
+
 func (rb *redisBackend) GetWorkflowInstanceState(ctx context.Context, instance *core.WorkflowInstance) (backend.WorkflowState, error) {
 	instanceState, err := readInstance(ctx, rb.rdb, instance.InstanceID)
 	if err != nil {
 		return backend.WorkflowStateActive, err
 	}
 
+	if instance.ExecutionID != "" && instanceState.Instance.ExecutionID != instance.ExecutionID {
+		// Earlier runs of the instance have finished
+		if _, err := readRun(ctx, rb.rdb, instance); err != nil {
+			return backend.WorkflowStateActive, err
+		}
+
+		return backend.WorkflowStateFinished, nil
+	}
+
 	return instanceState.State, nil
 }
 
+var _ backend.WorkflowInstanceWaiter = (*redisBackend)(nil)
+
+// waitPollInterval is how often WaitForWorkflowInstance checks the state of the instance, in case the completion
+// notification is lost. Pub/sub delivers messages at most once, they are dropped when the connection is lost.
+var waitPollInterval = 5 * time.Second
+
+// WaitForWorkflowInstance returns when the completion of the instance is published, or when the instance is found
+// to be finished by checking its state every waitPollInterval.
+func (rb *redisBackend) WaitForWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance) error {
+	// Subscribe before checking the current state, otherwise we might miss the completion
+	// notification if the instance finishes in-between.
+	pubsub := rb.rdb.Subscribe(ctx, instanceCompletionChannel(instance.InstanceID))
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("subscribing to workflow instance completion: %w", err)
+	}
+
+	state, err := rb.GetWorkflowInstanceState(ctx, instance)
+	if err != nil {
+		return err
+	}
+
+	if state == backend.WorkflowStateFinished {
+		return nil
+	}
+
+	t := time.NewTicker(waitPollInterval)
+	defer t.Stop()
+
+	ch := pubsub.Channel()
+
+	for {
+		select {
+		case _, ok := <-ch:
+			if ok {
+				return nil
+			}
+
+			// The subscription has been closed, keep polling
+			ch = nil
+
+		case <-t.C:
+			state, err := rb.GetWorkflowInstanceState(ctx, instance)
+			if err != nil {
+				return err
+			}
+
+			if state == backend.WorkflowStateFinished {
+				return nil
+			}
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 func (rb *redisBackend) CancelWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, event *history.Event) error {
 	// Read the instance to check if it exists
 	_, err := readInstance(ctx, rb.rdb, instance.InstanceID)
@@ -99,53 +238,105 @@ type instanceState struct {
 	CreatedAt      time.Time              `json:"created_at,omitempty"`
 	CompletedAt    *time.Time             `json:"completed_at,omitempty"`
 	LastSequenceID int64                  `json:"last_sequence_id,omitempty"`
+	Queue          string                 `json:"queue,omitempty"`
+	LastError      *backend.InstanceError `json:"last_error,omitempty"`
 }
 
-func createInstance(ctx context.Context, rdb redis.UniversalClient, instance *core.WorkflowInstance, ignoreDuplicate bool) error {
-	key := instanceKey(instance.InstanceID)
-
+// Store the state of a new instance and index it, unless the instance already exists. Returns 1 if the instance was
+// created, 0 if it already existed.
+// KEYS[1] - instance key
+// KEYS[2] - active instances set key
+// KEYS[3] - instances by creation zset key
+// KEYS[4] - sub-workflow instances list key of the parent instance
+// ARGV[1] - instance id
+// ARGV[2] - instance state
+// ARGV[3] - creation timestamp in milliseconds
+// ARGV[4] - instance, if it's a sub-workflow instance
+var createInstanceCmd = redis.NewScript(`
+	if not redis.call("SET", KEYS[1], ARGV[2], "NX") then
+		return 0
+	end
+
+	redis.call("SADD", KEYS[2], ARGV[1])
+	redis.call("ZADD", KEYS[3], ARGV[3], ARGV[1])
+
+	if ARGV[4] ~= "" then
+		redis.call("RPUSH", KEYS[4], ARGV[4])
+	end
+
+	return 1
+`)
+
+func createInstanceArgs(instance *core.WorkflowInstance, queue string) ([]string, []interface{}, error) {
 	createdAt := time.Now()
 
 	b, err := json.Marshal(&instanceState{
 		Instance:  instance,
 		State:     backend.WorkflowStateActive,
 		CreatedAt: createdAt,
+		Queue:     queue,
 	})
 	if err != nil {
-		return fmt.Errorf("marshaling instance state: %w", err)
+		return nil, nil, fmt.Errorf("marshaling instance state: %w", err)
+	}
+
+	var instanceStr []byte
+	if instance.SubWorkflow() {
+		instanceStr, err = json.Marshal(instance)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	keys := []string{
+		instanceKey(instance.InstanceID),
+		activeInstancesKey(),
+		instancesByCreation(),
+		subInstanceKey(instance.ParentInstanceID),
 	}
 
-	ok, err := rdb.SetNX(ctx, key, string(b), 0).Result()
+	return keys, []interface{}{instance.InstanceID, string(b), createdAt.UnixMilli(), string(instanceStr)}, nil
+}
+
+func createInstance(ctx context.Context, rdb redis.UniversalClient, instance *core.WorkflowInstance, queue string, ignoreDuplicate bool) error {
+	keys, args, err := createInstanceArgs(instance, queue)
 	if err != nil {
-		return fmt.Errorf("storing instance: %w", err)
+		return err
 	}
 
-	if !ignoreDuplicate && !ok {
-		return errors.New("workflow instance already exists")
+	created, err := createInstanceCmd.Run(ctx, rdb, keys, args...).Int64()
+	if err != nil {
+		return fmt.Errorf("storing instance: %w", err)
 	}
 
-	if instance.SubWorkflow() {
-		instanceStr, err := json.Marshal(instance)
+	if !ignoreDuplicate && created == 0 {
+		existing, err := readInstance(ctx, rdb, instance.InstanceID)
 		if err != nil {
-			return err
+			return fmt.Errorf("reading existing workflow instance: %w", err)
 		}
 
-		if err := rdb.RPush(ctx, subInstanceKey(instance.ParentInstanceID), instanceStr).Err(); err != nil {
-			return fmt.Errorf("tracking sub-workflow: %w", err)
+		return &backend.InstanceAlreadyExistsError{
+			Instance: existing.Instance,
 		}
 	}
 
-	if err := rdb.ZAdd(ctx, instancesByCreation(), &redis.Z{
-		Member: instance.InstanceID,
-		Score:  float64(createdAt.UnixMilli()),
-	}).Err(); err != nil {
-		return fmt.Errorf("storing instance reference: %w", err)
+	return nil
+}
+
+// createInstanceInPipeline creates the given instance as part of the given pipeline, if it doesn't exist yet
+func createInstanceInPipeline(ctx context.Context, p redis.Pipeliner, instance *core.WorkflowInstance, queue string) error {
+	keys, args, err := createInstanceArgs(instance, queue)
+	if err != nil {
+		return err
 	}
 
+	// Scripts cannot be loaded on demand in a pipeline, so send the full script
+	createInstanceCmd.Eval(ctx, p, keys, args...)
+
 	return nil
 }
 
-func updateInstance(ctx context.Context, rdb redis.UniversalClient, instanceID string, state *instanceState) error {
+func updateInstance(ctx context.Context, rdb redis.Cmdable, instanceID string, state *instanceState) error {
 	key := instanceKey(instanceID)
 
 	b, err := json.Marshal(state)
@@ -163,6 +354,33 @@ func updateInstance(ctx context.Context, rdb redis.UniversalClient, instanceID s
 	return nil
 }
 
+func setInstanceExpiration(ctx context.Context, rdb redis.UniversalClient, instanceID string, expiration time.Duration) error {
+	runs, err := readRuns(ctx, rdb, instanceID)
+	if err != nil {
+		return err
+	}
+
+	_, err = rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Expire(ctx, instanceKey(instanceID), expiration)
+		p.Expire(ctx, historyKey(instanceID), expiration)
+		p.Expire(ctx, pendingEventsKey(instanceID), expiration)
+		p.Expire(ctx, subInstanceKey(instanceID), expiration)
+		p.Expire(ctx, instanceRunsKey(instanceID), expiration)
+
+		for _, run := range runs {
+			p.Expire(ctx, runHistoryKey(instanceID, run.Instance.ExecutionID), expiration)
+		}
+
+		// Expired keys are removed by Redis, the reference in instancesByCreation() is removed by
+		// CleanupFinishedInstances
+		p.ZAdd(ctx, instancesByCompletion(), &redis.Z{Score: float64(time.Now().UnixMilli()), Member: instanceID})
+
+		return nil
+	})
+
+	return err
+}
+
 func readInstance(ctx context.Context, rdb redis.UniversalClient, instanceID string) (*instanceState, error) {
 	key := instanceKey(instanceID)
 
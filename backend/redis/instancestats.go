@@ -0,0 +1,97 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/task"
+)
+
+var _ backend.InstanceStatsProvider = (*redisBackend)(nil)
+
+func (rb *redisBackend) GetWorkflowInstanceStats(ctx context.Context, instance *core.WorkflowInstance) (*backend.InstanceStats, error) {
+	state, err := readInstance(ctx, rb.rdb, instance.InstanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &backend.InstanceStats{}
+
+	if state.LastError != nil {
+		stats.FailedAttempts = state.LastError.Attempt
+	}
+
+	h, err := rb.GetWorkflowInstanceHistory(ctx, instance, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	// Activities and timers are pending until the history contains their result
+	activities := map[int64]bool{}
+	timers := map[int64]bool{}
+
+	for _, event := range h {
+		stats.HistoryEvents++
+		stats.HistorySize += history.AttributesSize(event)
+
+		switch event.Type {
+		case history.EventType_ActivityScheduled:
+			activities[event.ScheduleEventID] = true
+		case history.EventType_ActivityCompleted, history.EventType_ActivityFailed:
+			delete(activities, event.ScheduleEventID)
+
+		case history.EventType_TimerScheduled:
+			timers[event.ScheduleEventID] = true
+		case history.EventType_TimerFired, history.EventType_TimerCanceled:
+			delete(timers, event.ScheduleEventID)
+		}
+	}
+
+	stats.PendingActivities = int64(len(activities))
+	stats.PendingTimers = int64(len(timers))
+
+	msgs, err := rb.rdb.XRange(ctx, pendingEventsKey(instance.InstanceID), "-", "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("reading pending events: %w", err)
+	}
+
+	pendingEvents := make([]history.Event, 0, len(msgs))
+
+	for _, msg := range msgs {
+		var event history.Event
+		if err := json.Unmarshal([]byte(msg.Values["event"].(string)), &event); err != nil {
+			return nil, fmt.Errorf("unmarshaling event: %w", err)
+		}
+
+		pendingEvents = append(pendingEvents, event)
+
+		stats.PendingEvents++
+
+		if event.Type == history.EventType_SignalReceived {
+			stats.BufferedSignals++
+		}
+	}
+
+	if len(pendingEvents) > 0 {
+		scheduledAt := task.ScheduledAt(pendingEvents...)
+		stats.WorkflowTaskScheduledAt = &scheduledAt
+	}
+
+	// Future events are only added to the pending events once they become visible
+	iter := rb.rdb.ZScan(ctx, futureEventsKey(), 0, instanceFutureEventsPattern(instance.InstanceID), 0).Iterator()
+	for iter.Next(ctx) {
+		// ZSCAN returns members and scores
+		stats.PendingEvents++
+		iter.Next(ctx)
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("counting future events: %w", err)
+	}
+
+	return stats, nil
+}
@@ -12,6 +12,16 @@ func instancesByCreation() string {
 	return "instances-by-creation"
 }
 
+// instancesByCompletion holds finished instances scored by the time they finished, to remove them from
+// instancesByCreation once they have expired
+func instancesByCompletion() string {
+	return "instances-by-completion"
+}
+
+func activeInstancesKey() string {
+	return "active-instances"
+}
+
 func subInstanceKey(instanceID string) string {
 	return fmt.Sprintf("sub-instance:%v", instanceID)
 }
@@ -24,6 +34,15 @@ func historyKey(instanceID string) string {
 	return fmt.Sprintf("history:%v", instanceID)
 }
 
+// instanceRunsKey is the list of earlier, finished runs of the given instance
+func instanceRunsKey(instanceID string) string {
+	return fmt.Sprintf("instance-runs:%v", instanceID)
+}
+
+func runHistoryKey(instanceID, executionID string) string {
+	return fmt.Sprintf("run-history:%v:%v", instanceID, executionID)
+}
+
 func futureEventsKey() string {
 	return "future-events"
 }
@@ -31,3 +50,42 @@ func futureEventsKey() string {
 func futureEventKey(instanceID string, scheduleEventID int64) string {
 	return fmt.Sprintf("future-event:%v:%v", instanceID, scheduleEventID)
 }
+
+// instanceFutureEventsPattern matches all future event keys of the given instance
+func instanceFutureEventsPattern(instanceID string) string {
+	return fmt.Sprintf("future-event:%v:*", instanceID)
+}
+
+func instanceCompletionChannel(instanceID string) string {
+	return fmt.Sprintf("instance-completed:%v", instanceID)
+}
+
+func activityResultKey(key string) string {
+	return fmt.Sprintf("activity-result:%v", key)
+}
+
+// activityHeartbeatKey holds the details of the last heartbeat of the given activity task
+func activityHeartbeatKey(activityID string) string {
+	return fmt.Sprintf("activity-heartbeat:%v", activityID)
+}
+
+func instanceTagKey(tag string) string {
+	return fmt.Sprintf("tag:%v", tag)
+}
+
+func stateKey(space, key string) string {
+	return fmt.Sprintf("state:%v:%v", space, key)
+}
+
+func leaseKey(name string) string {
+	return fmt.Sprintf("lease:%v", name)
+}
+
+// workflowQueriesKey is the list of IDs of queries waiting for a worker
+func workflowQueriesKey() string {
+	return "workflow-queries"
+}
+
+func workflowQueryKey(id string) string {
+	return fmt.Sprintf("workflow-query:%v", id)
+}
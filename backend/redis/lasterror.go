@@ -0,0 +1,21 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+var _ backend.InstanceErrorRecorder = (*redisBackend)(nil)
+
+func (rb *redisBackend) RecordInstanceError(ctx context.Context, instance *core.WorkflowInstance, instanceErr *backend.InstanceError) error {
+	state, err := readInstance(ctx, rb.rdb, instance.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	state.LastError = instanceErr.NextAttempt(state.LastError)
+
+	return updateInstance(ctx, rb.rdb, instance.InstanceID, state)
+}
@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/go-redis/redis/v8"
+)
+
+var _ backend.LeaseProvider = (*redisBackend)(nil)
+
+// Set the lease if it's not held, or held by the same holder
+//
+// KEYS[1] - lease key
+// ARGV[1] - holder
+// ARGV[2] - ttl in milliseconds
+var acquireLeaseCmd = redis.NewScript(`
+	local holder = redis.call("GET", KEYS[1])
+	if holder == false or holder == ARGV[1] then
+		redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+		return 1
+	end
+
+	return 0
+`)
+
+// Delete the lease if it's held by the given holder
+//
+// KEYS[1] - lease key
+// ARGV[1] - holder
+var releaseLeaseCmd = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("DEL", KEYS[1])
+	end
+
+	return 0
+`)
+
+func (rb *redisBackend) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	acquired, err := acquireLeaseCmd.Run(ctx, rb.rdb, []string{leaseKey(name)}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("acquiring lease: %w", err)
+	}
+
+	return acquired == 1, nil
+}
+
+func (rb *redisBackend) ReleaseLease(ctx context.Context, name, holder string) error {
+	if err := releaseLeaseCmd.Run(ctx, rb.rdb, []string{leaseKey(name)}, holder).Err(); err != nil {
+		return fmt.Errorf("releasing lease: %w", err)
+	}
+
+	return nil
+}
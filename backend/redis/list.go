@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+var _ backend.InstanceLister = (*redisBackend)(nil)
+var _ backend.InstanceResolver = (*redisBackend)(nil)
+
+func (rb *redisBackend) ListWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) ([]*core.WorkflowInstance, error) {
+	var instanceIDs []string
+	var err error
+
+	if len(filter.Tags) > 0 {
+		keys := make([]string, 0, len(filter.Tags))
+		for _, tag := range filter.Tags {
+			keys = append(keys, instanceTagKey(tag))
+		}
+
+		instanceIDs, err = rb.rdb.SInter(ctx, keys...).Result()
+	} else {
+		instanceIDs, err = rb.rdb.ZRange(ctx, instancesByCreation(), 0, -1).Result()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding instances: %w", err)
+	}
+
+	states := make([]*instanceState, 0, len(instanceIDs))
+
+	for _, instanceID := range instanceIDs {
+		state, err := readInstance(ctx, rb.rdb, instanceID)
+		if err != nil {
+			if errors.Is(err, backend.ErrInstanceNotFound) {
+				// Instance has expired
+				continue
+			}
+
+			return nil, err
+		}
+
+		if !filter.MatchesState(state.State) {
+			continue
+		}
+
+		if filter.Name != "" {
+			name, err := rb.workflowName(ctx, instanceID)
+			if err != nil {
+				return nil, err
+			}
+
+			if name != filter.Name {
+				continue
+			}
+		}
+
+		states = append(states, state)
+	}
+
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].CreatedAt.Before(states[j].CreatedAt)
+	})
+
+	instances := make([]*core.WorkflowInstance, 0, len(states))
+	for _, state := range states {
+		instances = append(instances, state.Instance)
+	}
+
+	return instances, nil
+}
+
+func (rb *redisBackend) ResolveWorkflowInstance(ctx context.Context, instanceID string) (*core.WorkflowInstance, error) {
+	state, err := readInstance(ctx, rb.rdb, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return state.Instance, nil
+}
+
+// workflowName returns the name of the workflow of the given instance. The started event is at the start of the
+// history, or still pending if the instance has not been executed yet.
+func (rb *redisBackend) workflowName(ctx context.Context, instanceID string) (string, error) {
+	for _, key := range []string{historyKey(instanceID), pendingEventsKey(instanceID)} {
+		// The started event might be preceded by the WorkflowTaskStarted event of the first task
+		msgs, err := rb.rdb.XRangeN(ctx, key, "-", "+", 2).Result()
+		if err != nil {
+			return "", fmt.Errorf("reading events: %w", err)
+		}
+
+		events := make([]history.Event, 0, len(msgs))
+		for _, msg := range msgs {
+			var event history.Event
+			if err := json.Unmarshal([]byte(msg.Values["event"].(string)), &event); err != nil {
+				return "", fmt.Errorf("unmarshaling event: %w", err)
+			}
+
+			events = append(events, event)
+		}
+
+		if name := history.WorkflowName(events); name != "" {
+			return name, nil
+		}
+	}
+
+	return "", nil
+}
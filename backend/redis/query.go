@@ -0,0 +1,113 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/go-redis/redis/v8"
+)
+
+var _ backend.InstanceQuerier = (*redisBackend)(nil)
+
+// QueryWorkflowInstances walks the instances by creation time, newest first. Instances created in the same
+// millisecond are ordered by their instance ID, like the ZSET orders members with the same score.
+func (rb *redisBackend) QueryWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*backend.InstanceQueryResult, error) {
+	cursor, err := backend.DecodePageToken(query.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	max := "+inf"
+	if !query.CreatedBefore.IsZero() {
+		max = "(" + strconv.FormatInt(query.CreatedBefore.UnixMilli(), 10)
+	}
+
+	if cursor != nil && (query.CreatedBefore.IsZero() || cursor.CreatedAt.Before(query.CreatedBefore)) {
+		max = strconv.FormatInt(cursor.CreatedAt.UnixMilli(), 10)
+	}
+
+	min := "-inf"
+	if !query.CreatedAfter.IsZero() {
+		min = strconv.FormatInt(query.CreatedAfter.UnixMilli(), 10)
+	}
+
+	limit := query.Limit()
+
+	result := &backend.InstanceQueryResult{
+		Instances: make([]*backend.WorkflowInstanceSummary, 0),
+	}
+
+	// Position of the last instance in the result, for the page token
+	var lastCreatedAt time.Time
+
+	for offset := int64(0); ; offset += int64(limit) {
+		batch, err := rb.rdb.ZRangeArgsWithScores(ctx, redis.ZRangeArgs{
+			Key:     instancesByCreation(),
+			Start:   min,
+			Stop:    max,
+			ByScore: true,
+			Rev:     true,
+			Offset:  offset,
+			Count:   int64(limit),
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("finding instances: %w", err)
+		}
+
+		for _, z := range batch {
+			instanceID := z.Member.(string)
+			createdAt := time.UnixMilli(int64(z.Score)).UTC()
+
+			if cursor != nil && !cursor.After(createdAt, instanceID) {
+				continue
+			}
+
+			if !strings.HasPrefix(instanceID, query.InstanceIDPrefix) {
+				continue
+			}
+
+			state, err := readInstance(ctx, rb.rdb, instanceID)
+			if err != nil {
+				if errors.Is(err, backend.ErrInstanceNotFound) {
+					// Instance has expired
+					continue
+				}
+
+				return nil, err
+			}
+
+			if !query.MatchesState(state.State) {
+				continue
+			}
+
+			if len(result.Instances) == limit {
+				last := result.Instances[limit-1]
+				result.NextPageToken = backend.EncodePageToken(lastCreatedAt, last.Instance.InstanceID)
+				return result, nil
+			}
+
+			name, err := rb.workflowName(ctx, instanceID)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Instances = append(result.Instances, &backend.WorkflowInstanceSummary{
+				Instance:    state.Instance,
+				Name:        name,
+				State:       state.State,
+				CreatedAt:   state.CreatedAt,
+				CompletedAt: state.CompletedAt,
+			})
+			lastCreatedAt = createdAt
+		}
+
+		if len(batch) < limit {
+			return result, nil
+		}
+	}
+}
@@ -2,13 +2,17 @@ package redis
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/redis/taskqueue"
+	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/trace"
 	"github.com/go-redis/redis/v8"
 )
 
@@ -16,6 +20,35 @@ type RedisOptions struct {
 	backend.Options
 
 	BlockTimeout time.Duration
+
+	// AutoExpiration determines how long finished workflow instances are kept in Redis
+	// before their keys expire. 0 disables expiration. Defaults to the retention of the backend options, see
+	// backend.WithRetention.
+	AutoExpiration time.Duration
+
+	// WorkerName is the name the backend reads tasks from the task queues as. Defaults to a random name per
+	// backend instance.
+	WorkerName string
+
+	// ConsumerIdleTimeout determines how long consumers of the task queues can be idle before they are
+	// removed. 0 disables the cleanup.
+	ConsumerIdleTimeout time.Duration
+
+	// WorkflowQueueOptions configure the task queues for workflow tasks
+	WorkflowQueueOptions []taskqueue.Option
+
+	// ActivityQueueOptions configure the task queues for activity tasks
+	ActivityQueueOptions []taskqueue.Option
+
+	// Replica is the secondary Redis the data is replicated to by Replicate, nil disables replication
+	Replica redis.UniversalClient
+
+	// ReplicationInterval is how often Replicate copies the data to the replica
+	ReplicationInterval time.Duration
+
+	// BatchSize is the number of tasks read from a task queue at once. Tasks not handed out right away are kept
+	// for the next polls. Defaults to 1, which disables batching.
+	BatchSize int
 }
 
 type RedisBackendOption func(*RedisOptions)
@@ -26,6 +59,65 @@ func WithBlockTimeout(timeout time.Duration) RedisBackendOption {
 	}
 }
 
+// WithAutoExpiration sets a TTL on the keys of a workflow instance once it has finished. This
+// includes the instance state, its history, and its pending events.
+func WithAutoExpiration(expireFinishedInstancesAfter time.Duration) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.AutoExpiration = expireFinishedInstancesAfter
+	}
+}
+
+// WithWorkerName sets a stable name for reading tasks from the task queues, for example the host name. A
+// restarted worker then reuses its consumers instead of registering new ones.
+func WithWorkerName(name string) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.WorkerName = name
+	}
+}
+
+// WithConsumerIdleTimeout sets how long consumers of the task queues can be idle before they are removed.
+// Consumers with locked tasks are kept until the tasks are recovered. 0 disables the cleanup.
+func WithConsumerIdleTimeout(timeout time.Duration) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.ConsumerIdleTimeout = timeout
+	}
+}
+
+// WithWorkflowQueueOptions configures the task queue for workflow tasks, for example to park tasks after a
+// number of failed delivery attempts. These options take precedence over the other backend options.
+func WithWorkflowQueueOptions(opts ...taskqueue.Option) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.WorkflowQueueOptions = append(o.WorkflowQueueOptions, opts...)
+	}
+}
+
+// WithActivityQueueOptions configures the task queues for activity tasks. These options take precedence over
+// the other backend options.
+func WithActivityQueueOptions(opts ...taskqueue.Option) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.ActivityQueueOptions = append(o.ActivityQueueOptions, opts...)
+	}
+}
+
+// WithReplica sets a secondary Redis, for example in another region, to replicate the data of the backend to.
+// Replication runs asynchronously in Replicate, copying the data every interval. After losing the primary, promote
+// the replica with PromoteReplica and create a backend for it.
+func WithReplica(replica redis.UniversalClient, interval time.Duration) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.Replica = replica
+		o.ReplicationInterval = interval
+	}
+}
+
+// WithBatchSize sets the number of workflow and activity tasks read from a task queue at once, to save round trips
+// to Redis under high load. Tasks that are not handed out to a worker within their lock timeout are recovered by
+// other workers.
+func WithBatchSize(n int) RedisBackendOption {
+	return func(o *RedisOptions) {
+		o.BatchSize = n
+	}
+}
+
 func WithBackendOptions(opts ...backend.BackendOption) RedisBackendOption {
 	return func(o *RedisOptions) {
 		for _, opt := range opts {
@@ -42,43 +134,77 @@ func NewRedisBackend(address, username, password string, db int, opts ...RedisBa
 		DB:       db,
 	})
 
-	workflowQueue, err := taskqueue.New[workflowTaskData](client, "workflows")
-	if err != nil {
-		return nil, fmt.Errorf("creating workflow task queue: %w", err)
-	}
-
-	activityQueue, err := taskqueue.New[activityData](client, "activities")
-	if err != nil {
-		return nil, fmt.Errorf("creating activity task queue: %w", err)
-	}
-
 	// Default options
 	options := &RedisOptions{
-		Options:      backend.ApplyOptions(),
-		BlockTimeout: time.Second * 5,
+		Options:             backend.ApplyOptions(),
+		BlockTimeout:        time.Second * 5,
+		ConsumerIdleTimeout: time.Hour,
+		ReplicationInterval: time.Second * 10,
+		BatchSize:           1,
 	}
 
 	for _, opt := range opts {
 		opt(options)
 	}
 
+	if options.AutoExpiration == 0 {
+		options.AutoExpiration = options.Retention
+	}
+
+	workflowQueue, err := taskqueue.New[workflowTaskData](client, "workflows", options.queueOptions(options.WorkflowQueueOptions)...)
+	if err != nil {
+		return nil, fmt.Errorf("creating workflow task queue: %w", err)
+	}
+
+	activityQueue, err := taskqueue.New[activityData](client, "activities", options.queueOptions(options.ActivityQueueOptions)...)
+	if err != nil {
+		return nil, fmt.Errorf("creating activity task queue: %w", err)
+	}
+
 	rb := &redisBackend{
-		rdb:     client,
-		options: options,
+		rdb:       client,
+		options:   options,
+		throttler: backend.NewInstanceCreationThrottler(options.InstanceCreationLimits),
 
 		workflowQueue: workflowQueue,
 		activityQueue: activityQueue,
+
+		workflowQueues: map[string]taskqueue.TaskQueue[workflowTaskData]{},
+		activityQueues: map[string]taskqueue.TaskQueue[activityData]{},
 	}
 
 	return rb, nil
 }
 
+// queueOptions returns the options for a task queue, the given queue specific options are applied last
+func (o *RedisOptions) queueOptions(queueOpts []taskqueue.Option) []taskqueue.Option {
+	opts := []taskqueue.Option{
+		taskqueue.WithWorkerName(o.WorkerName),
+		taskqueue.WithConsumerIdleTimeout(o.ConsumerIdleTimeout),
+	}
+
+	return append(opts, queueOpts...)
+}
+
 type redisBackend struct {
-	rdb     redis.UniversalClient
-	options *RedisOptions
+	rdb       redis.UniversalClient
+	options   *RedisOptions
+	throttler *backend.InstanceCreationThrottler
 
 	workflowQueue taskqueue.TaskQueue[workflowTaskData]
 	activityQueue taskqueue.TaskQueue[activityData]
+
+	// workflowQueues are the task queues for instances placed on a non-default queue
+	workflowQueuesMu sync.Mutex
+	workflowQueues   map[string]taskqueue.TaskQueue[workflowTaskData]
+
+	// activityQueues are the task queues for activities scheduled on a non-default queue
+	activityQueuesMu sync.Mutex
+	activityQueues   map[string]taskqueue.TaskQueue[activityData]
+
+	// workflowTasks and activityTasks hold tasks dequeued in a batch, see RedisOptions.BatchSize
+	workflowTasks taskBuffer[workflowTaskData]
+	activityTasks taskBuffer[activityData]
 }
 
 type activityData struct {
@@ -91,6 +217,24 @@ type workflowTaskData struct {
 	LastPendingEventMessageID string `json:"last_pending_event_message_id,omitempty"`
 }
 
+var _ backend.LockTimeoutProvider = (*redisBackend)(nil)
+
+func (rb *redisBackend) LockTimeouts() (time.Duration, time.Duration) {
+	return rb.options.WorkflowLockTimeout, rb.options.ActivityLockTimeout
+}
+
 func (rb *redisBackend) Logger() log.Logger {
 	return rb.options.Logger
 }
+
+func (rb *redisBackend) Metrics() metrics.Client {
+	return rb.options.Metrics
+}
+
+func (rb *redisBackend) Tracer() trace.Tracer {
+	return rb.options.Tracer
+}
+
+func (rb *redisBackend) Converter() converter.Converter {
+	return rb.options.Converter
+}
@@ -2,12 +2,20 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_RedisBackend(t *testing.T) {
@@ -23,10 +31,12 @@ func Test_EndToEndRedisBackend(t *testing.T) {
 		t.Skip()
 	}
 
-	test.EndToEndBackendTest(t, createBackend, nil)
+	test.EndToEndBackendTest(t, func() backend.Backend {
+		return createBackend()
+	}, nil)
 }
 
-func createBackend() backend.Backend {
+func createBackend(options ...backend.BackendOption) backend.Backend {
 	address := "localhost:6379"
 	user := ""
 	password := "RedisPassw0rd"
@@ -43,10 +53,251 @@ func createBackend() backend.Backend {
 		panic(err)
 	}
 
-	b, err := NewRedisBackend(address, user, password, 0, WithBlockTimeout(time.Millisecond*2))
+	b, err := NewRedisBackend(address, user, password, 0, WithBlockTimeout(time.Millisecond*2), WithBackendOptions(options...))
 	if err != nil {
 		panic(err)
 	}
 
 	return b
 }
+
+func Test_RedisBackend_CleanupFinishedInstances(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	b := createBackend().(*redisBackend)
+
+	expired := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	active := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	for _, instance := range []*core.WorkflowInstance{expired, active} {
+		err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+			WorkflowInstance: instance,
+			HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+		})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, setInstanceExpiration(ctx, b.rdb, expired.InstanceID, time.Minute))
+
+	// Simulate the keys of the instance expiring
+	require.NoError(t, b.rdb.Del(ctx, instanceKey(expired.InstanceID)).Err())
+
+	require.NoError(t, b.CleanupFinishedInstances(ctx, 0))
+
+	instanceIDs, err := b.rdb.ZRange(ctx, instancesByCreation(), 0, -1).Result()
+	require.NoError(t, err)
+	require.Equal(t, []string{active.InstanceID}, instanceIDs)
+
+	n, err := b.rdb.ZCard(ctx, instancesByCompletion()).Result()
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func Test_RedisBackend_WaitForWorkflowInstance_WithoutNotification(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	defer func(d time.Duration) { waitPollInterval = d }(waitPollInterval)
+	waitPollInterval = 10 * time.Millisecond
+
+	ctx := context.Background()
+	b := createBackend().(*redisBackend)
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- b.WaitForWorkflowInstance(ctx, instance)
+	}()
+
+	// Let the waiter subscribe and check the state first
+	time.Sleep(50 * time.Millisecond)
+
+	// Finish the instance without publishing its completion, like a lost notification
+	state, err := readInstance(ctx, b.rdb, instance.InstanceID)
+	require.NoError(t, err)
+	state.State = backend.WorkflowStateFinished
+	require.NoError(t, updateInstance(ctx, b.rdb, instance.InstanceID, state))
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "waiting for the instance did not return")
+	}
+}
+
+func Test_RedisBackend_Replication(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+
+	// Use another database of the same server as the replica
+	replica := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:    []string{"localhost:6379"},
+		Password: "RedisPassw0rd",
+		DB:       1,
+	})
+	require.NoError(t, replica.FlushDB(ctx).Err())
+
+	// Flushes the primary database
+	createBackend()
+
+	b, err := NewRedisBackend("localhost:6379", "", "RedisPassw0rd", 0, WithReplica(replica, time.Second))
+	require.NoError(t, err)
+
+	require.NoError(t, b.SetState(ctx, "space", "key", []byte(`"value"`)))
+	require.NoError(t, b.rdb.Set(ctx, "expiring", "1", time.Hour).Err())
+	require.NoError(t, replica.Set(ctx, "stale", "1", 0).Err())
+
+	require.NoError(t, b.SyncReplica(ctx))
+
+	v, err := replica.Get(ctx, stateKey("space", "key")).Result()
+	require.NoError(t, err)
+	require.Equal(t, `"value"`, v)
+
+	ttl, err := replica.TTL(ctx, "expiring").Result()
+	require.NoError(t, err)
+	require.Greater(t, ttl, time.Duration(0))
+
+	n, err := replica.Exists(ctx, "stale").Result()
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// Replication stops once the replica has been promoted
+	require.NoError(t, PromoteReplica(ctx, replica))
+	require.ErrorIs(t, b.SyncReplica(ctx), ErrReplicaPromoted)
+	require.ErrorIs(t, b.Replicate(ctx), ErrReplicaPromoted)
+}
+
+func Test_RedisBackend_ReplicationDuringWrites(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+
+	replica := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:    []string{"localhost:6379"},
+		Password: "RedisPassw0rd",
+		DB:       1,
+	})
+	require.NoError(t, replica.FlushDB(ctx).Err())
+
+	// Flushes the primary database
+	createBackend()
+
+	b, err := NewRedisBackend("localhost:6379", "", "RedisPassw0rd", 0, WithBlockTimeout(time.Millisecond*2), WithReplica(replica, time.Second))
+	require.NoError(t, err)
+
+	a := func(ctx context.Context) (int, error) {
+		return 42, nil
+	}
+
+	wf := func(ctx workflow.Context) error {
+		for i := 0; i < 3; i++ {
+			if _, err := workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.RegisterActivity(a))
+	require.NoError(t, w.Start(ctx))
+
+	// Keep starting workflows while replicating
+	c := client.New(b)
+	stop := make(chan struct{})
+	created := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				created <- nil
+				return
+			default:
+			}
+
+			if _, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, wf); err != nil {
+				created <- err
+				return
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	// Copy keys one by one, like for a large database
+	defer func(n int) { scanBatchSize = n }(scanBatchSize)
+	scanBatchSize = 1
+
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, b.SyncReplica(ctx))
+	}
+
+	close(stop)
+	require.NoError(t, <-created)
+	require.NoError(t, w.Shutdown(ctx))
+
+	require.NoError(t, PromoteReplica(ctx, replica))
+
+	rb, err := NewRedisBackend("localhost:6379", "", "RedisPassw0rd", 1)
+	require.NoError(t, err)
+
+	instanceIDs, err := replica.ZRange(ctx, instancesByCreation(), 0, -1).Result()
+	require.NoError(t, err)
+	require.NotEmpty(t, instanceIDs)
+
+	// Every instance on the replica is a consistent copy of the instance on the primary at some point in time
+	for _, instanceID := range instanceIDs {
+		state, err := readInstance(ctx, replica, instanceID)
+		if errors.Is(err, backend.ErrInstanceNotFound) {
+			// The index is copied separately from the instances
+			continue
+		}
+		require.NoError(t, err)
+
+		h, err := rb.GetWorkflowInstanceHistory(ctx, state.Instance, nil)
+		require.NoError(t, err)
+		if len(h) == 0 {
+			// The instance hasn't been executed yet, it's started by its pending events
+			pending, err := replica.XLen(ctx, pendingEventsKey(instanceID)).Result()
+			require.NoError(t, err)
+			require.NotZero(t, pending, "instance %v", instanceID)
+			require.Equal(t, backend.WorkflowStateActive, state.State)
+
+			continue
+		}
+
+		require.Contains(t, eventTypes(h), history.EventType_WorkflowExecutionStarted, "instance %v", instanceID)
+
+		if state.State == backend.WorkflowStateFinished {
+			require.Equal(t, history.EventType_WorkflowExecutionFinished, h[len(h)-1].Type, "instance %v", instanceID)
+		}
+	}
+}
+
+func eventTypes(h []history.Event) []history.EventType {
+	types := make([]history.EventType, 0, len(h))
+	for _, e := range h {
+		types = append(types, e.Type)
+	}
+
+	return types
+}
@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/go-redis/redis/v8"
+)
+
+var _ backend.WorkflowInstanceRemover = (*redisBackend)(nil)
+
+// RemoveWorkflowInstance removes the keys of the given finished instance, including its history, pending events,
+// and earlier runs
+func (rb *redisBackend) RemoveWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance) error {
+	state, err := readInstance(ctx, rb.rdb, instance.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	if state.Instance.ExecutionID != instance.ExecutionID {
+		return backend.ErrInstanceNotFound
+	}
+
+	if state.State != backend.WorkflowStateFinished {
+		return backend.ErrInstanceNotFinished
+	}
+
+	h, err := rb.GetWorkflowInstanceHistory(ctx, state.Instance, nil)
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+
+	if err := removeInstanceTags(ctx, rb.rdb, instance.InstanceID, history.AddedTags(h)); err != nil {
+		return err
+	}
+
+	runs, err := readRuns(ctx, rb.rdb, instance.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := rb.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(
+			ctx,
+			instanceKey(instance.InstanceID),
+			historyKey(instance.InstanceID),
+			pendingEventsKey(instance.InstanceID),
+			subInstanceKey(instance.InstanceID),
+			instanceRunsKey(instance.InstanceID),
+		)
+
+		for _, run := range runs {
+			p.Del(ctx, runHistoryKey(instance.InstanceID, run.Instance.ExecutionID))
+		}
+
+		p.ZRem(ctx, instancesByCreation(), instance.InstanceID)
+		p.ZRem(ctx, instancesByCompletion(), instance.InstanceID)
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("removing instance: %w", err)
+	}
+
+	return nil
+}
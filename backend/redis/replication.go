@@ -0,0 +1,379 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrNoReplica is returned when replicating without a replica configured, see WithReplica
+var ErrNoReplica = errors.New("no replica configured")
+
+// ErrReplicaPromoted is returned when replicating to a replica that has been promoted with PromoteReplica
+var ErrReplicaPromoted = errors.New("replica has been promoted")
+
+// replicationPrefix is the prefix of keys used for replication itself. They are never copied or removed.
+const replicationPrefix = "replication:"
+
+func promotedKey() string {
+	return replicationPrefix + "promoted"
+}
+
+// scanBatchSize is the number of keys or instances copied per round trip
+var scanBatchSize = 500
+
+// Replicate copies the data of the backend to the replica configured with WithReplica every replication interval,
+// until the context is canceled or the replica is promoted. Replication is asynchronous, the replica can lag behind
+// by up to the replication interval plus the time it takes to copy all keys.
+func (rb *redisBackend) Replicate(ctx context.Context) error {
+	if rb.options.Replica == nil {
+		return ErrNoReplica
+	}
+
+	t := time.NewTicker(rb.options.ReplicationInterval)
+	defer t.Stop()
+
+	for {
+		if err := rb.SyncReplica(ctx); err != nil {
+			if errors.Is(err, ErrReplicaPromoted) || ctx.Err() != nil {
+				return err
+			}
+
+			rb.Logger().Error("replicating to replica", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// SyncReplica copies all keys of the backend to the replica once, and removes keys from the replica that no
+// longer exist. Use it to bring the replica up to date before a planned failover.
+//
+// The keys of each workflow instance, its state, history, pending events, earlier runs, and future events, are
+// copied together, so the replica holds a point-in-time copy of every instance. Task queues, indexes, and other
+// keys are copied one by one, and different instances are copied at different times.
+func (rb *redisBackend) SyncReplica(ctx context.Context) error {
+	replica := rb.options.Replica
+	if replica == nil {
+		return ErrNoReplica
+	}
+
+	promoted, err := replica.Exists(ctx, promotedKey()).Result()
+	if err != nil {
+		return fmt.Errorf("checking replica: %w", err)
+	}
+
+	if promoted > 0 {
+		return ErrReplicaPromoted
+	}
+
+	start := time.Now()
+
+	// Find all keys, remembering them to remove stale keys from the replica afterwards
+	copied := make(map[string]struct{})
+	instances := make(map[string][]string)
+	other := make([]string, 0)
+
+	if err := scanKeys(ctx, rb.rdb, func(keys []string) error {
+		for _, key := range keys {
+			copied[key] = struct{}{}
+
+			if instanceID, ok := keyInstance(key); ok {
+				instances[instanceID] = append(instances[instanceID], key)
+			} else {
+				other = append(other, key)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	batch := make(map[string][]string, scanBatchSize)
+	for instanceID, keys := range instances {
+		batch[instanceID] = instanceKeys(instanceID, keys)
+
+		// Keys of the instance that didn't exist while scanning might have been copied
+		for _, key := range batch[instanceID] {
+			copied[key] = struct{}{}
+		}
+
+		if len(batch) == scanBatchSize {
+			if err := copyInstances(ctx, rb.rdb, replica, batch); err != nil {
+				return err
+			}
+
+			batch = make(map[string][]string, scanBatchSize)
+		}
+	}
+
+	if err := copyInstances(ctx, rb.rdb, replica, batch); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(other); i += scanBatchSize {
+		end := i + scanBatchSize
+		if end > len(other) {
+			end = len(other)
+		}
+
+		if err := copyKeys(ctx, rb.rdb, replica, other[i:end]); err != nil {
+			return err
+		}
+	}
+
+	removed := 0
+
+	if err := scanKeys(ctx, replica, func(keys []string) error {
+		stale := make([]string, 0)
+		for _, key := range keys {
+			if _, ok := copied[key]; !ok {
+				stale = append(stale, key)
+			}
+		}
+
+		if len(stale) == 0 {
+			return nil
+		}
+
+		removed += len(stale)
+
+		if err := replica.Del(ctx, stale...).Err(); err != nil {
+			return fmt.Errorf("removing stale keys from replica: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	rb.Logger().Debug("synced replica", "keys", len(copied), "instances", len(instances), "removed", removed, "duration", time.Since(start))
+
+	return nil
+}
+
+// PromoteReplica makes the replica the primary after the primary is lost. Afterwards, replication to the replica
+// stops with ErrReplicaPromoted, so a primary coming back cannot overwrite workflows that continued on the
+// replica. Create a backend for the replica to continue. Tasks locked by workers of the lost primary are picked
+// up again once their locks expire.
+func PromoteReplica(ctx context.Context, replica redis.UniversalClient) error {
+	if err := replica.Set(ctx, promotedKey(), time.Now().UTC().Format(time.RFC3339), 0).Err(); err != nil {
+		return fmt.Errorf("promoting replica: %w", err)
+	}
+
+	return nil
+}
+
+// scanKeys calls f with batches of all keys in the database, except for keys used for replication
+func scanKeys(ctx context.Context, rdb redis.UniversalClient, f func(keys []string) error) error {
+	var cursor uint64
+
+	for {
+		keys, next, err := rdb.Scan(ctx, cursor, "*", int64(scanBatchSize)).Result()
+		if err != nil {
+			return fmt.Errorf("scanning keys: %w", err)
+		}
+
+		batch := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if !strings.HasPrefix(key, replicationPrefix) {
+				batch = append(batch, key)
+			}
+		}
+
+		if len(batch) > 0 {
+			if err := f(batch); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+
+		cursor = next
+	}
+}
+
+// copyKeys copies the given keys including their TTLs from the primary to the replica. Dumping and restoring keys
+// keeps consumer groups and pending tasks of the task queue streams.
+func copyKeys(ctx context.Context, primary, replica redis.UniversalClient, keys []string) error {
+	dumps := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+
+	if _, err := primary.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, key := range keys {
+			dumps[i] = p.Dump(ctx, key)
+			ttls[i] = p.PTTL(ctx, key)
+		}
+
+		return nil
+	}); err != nil && err != redis.Nil {
+		return fmt.Errorf("dumping keys: %w", err)
+	}
+
+	if _, err := replica.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, key := range keys {
+			dump, err := dumps[i].Result()
+			if err != nil {
+				if err == redis.Nil {
+					// Key was removed since scanning
+					continue
+				}
+
+				return fmt.Errorf("dumping key: %w", err)
+			}
+
+			ttl := ttls[i].Val()
+			if ttl == -2 {
+				// Key was removed after dumping it
+				continue
+			}
+
+			if ttl < 0 {
+				// No expiration
+				ttl = 0
+			}
+
+			p.RestoreReplace(ctx, key, ttl, dump)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("restoring keys on replica: %w", err)
+	}
+
+	return nil
+}
+
+// keyInstance returns the ID of the workflow instance the given key belongs to, if any
+func keyInstance(key string) (string, bool) {
+	for _, prefix := range []string{"instance:", "history:", "pending-events:", "sub-instance:", "instance-runs:"} {
+		if strings.HasPrefix(key, prefix) {
+			return key[len(prefix):], true
+		}
+	}
+
+	// Keys of runs and future events end with the ID of the run or event
+	for _, prefix := range []string{"run-history:", "future-event:"} {
+		if strings.HasPrefix(key, prefix) {
+			if i := strings.LastIndex(key, ":"); i >= len(prefix) {
+				return key[len(prefix):i], true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// instanceKeys returns the keys of the given instance that have to be copied together. Keys that always exist
+// for an instance are included even if they weren't found, so they are removed from the replica together with
+// the other keys of the instance.
+func instanceKeys(instanceID string, found []string) []string {
+	keys := []string{
+		instanceKey(instanceID),
+		historyKey(instanceID),
+		pendingEventsKey(instanceID),
+		subInstanceKey(instanceID),
+		instanceRunsKey(instanceID),
+	}
+
+	for _, key := range found {
+		if strings.HasPrefix(key, "run-history:") || strings.HasPrefix(key, "future-event:") {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// dumpKeysCmd dumps the given keys at the same point in time, and returns the dump and TTL of every key. The
+// dump of keys that don't exist is nil, and their TTL -2.
+var dumpKeysCmd = redis.NewScript(`
+	local result = {}
+	for i, key in ipairs(KEYS) do
+		result[2 * i - 1] = redis.call("DUMP", key)
+		result[2 * i] = redis.call("PTTL", key)
+	end
+
+	return result
+`)
+
+// restoreKeysCmd restores the given keys at once, removing keys with a TTL of -2, and returns the number of keys.
+//
+// KEYS[1..n] = keys
+// ARGV[2i-1] = dump of KEYS[i]
+// ARGV[2i] = TTL of KEYS[i] in milliseconds
+var restoreKeysCmd = redis.NewScript(`
+	for i, key in ipairs(KEYS) do
+		local ttl = tonumber(ARGV[2 * i])
+		if ttl == -2 then
+			redis.call("DEL", key)
+		else
+			if ttl < 0 then
+				ttl = 0
+			end
+
+			redis.call("RESTORE", key, ttl, ARGV[2 * i - 1], "REPLACE")
+		end
+	end
+
+	return #KEYS
+`)
+
+// copyInstances copies the keys of the given instances from the primary to the replica. The keys of an instance
+// are dumped and restored together, so the replica never holds a partially copied instance.
+func copyInstances(ctx context.Context, primary, replica redis.UniversalClient, instances map[string][]string) error {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	dumps := make(map[string]*redis.Cmd, len(instances))
+
+	if _, err := primary.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for instanceID, keys := range instances {
+			dumps[instanceID] = dumpKeysCmd.Eval(ctx, p, keys)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("dumping instances: %w", err)
+	}
+
+	if _, err := replica.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for instanceID, keys := range instances {
+			result, err := dumps[instanceID].Slice()
+			if err != nil {
+				return fmt.Errorf("dumping instance: %w", err)
+			}
+
+			args := make([]interface{}, 0, len(result))
+			for i := 0; i < len(keys); i++ {
+				ttl, _ := result[2*i+1].(int64)
+				dump, ok := result[2*i].(string)
+				if !ok {
+					ttl = -2
+				}
+
+				args = append(args, dump, ttl)
+			}
+
+			restoreKeysCmd.Eval(ctx, p, keys, args...)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("restoring instances on replica: %w", err)
+	}
+
+	return nil
+}
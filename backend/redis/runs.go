@@ -0,0 +1,220 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/go-redis/redis/v8"
+)
+
+var _ backend.RunProvider = (*redisBackend)(nil)
+var _ backend.RunReplacer = (*redisBackend)(nil)
+
+func (rb *redisBackend) StartWorkflowInstanceRun(ctx context.Context, event history.WorkflowEvent) error {
+	if err := rb.archiveRun(ctx, event.WorkflowInstance.InstanceID); err != nil {
+		return err
+	}
+
+	return rb.CreateWorkflowInstance(ctx, event)
+}
+
+// ReplaceWorkflowInstanceRun terminates the current run, if it's still active, and starts a new run. The steps are
+// not a single transaction, but the new run is only created if no other run has been started in the meantime.
+func (rb *redisBackend) ReplaceWorkflowInstanceRun(
+	ctx context.Context, event history.WorkflowEvent, terminate func(run *core.WorkflowInstance) (history.Event, []history.WorkflowEvent),
+) error {
+	state, err := readInstance(ctx, rb.rdb, event.WorkflowInstance.InstanceID)
+	if err != nil && !errors.Is(err, backend.ErrInstanceNotFound) {
+		return err
+	}
+
+	// A run with the execution ID of the new run has been started by an earlier attempt, creating the instance
+	// reports it as existing
+	if err == nil && state.State == backend.WorkflowStateActive && state.Instance.ExecutionID != event.WorkflowInstance.ExecutionID {
+		terminateEvent, workflowEvents := terminate(state.Instance)
+
+		// The run might have finished in the meantime
+		if err := rb.ForceCompleteWorkflowInstance(ctx, state.Instance, terminateEvent, workflowEvents); err != nil &&
+			!errors.Is(err, backend.ErrInstanceFinished) {
+			return fmt.Errorf("terminating current run: %w", err)
+		}
+	}
+
+	return rb.StartWorkflowInstanceRun(ctx, event)
+}
+
+// archiveRun moves the current run of the given instance to the list of runs, if it has finished. Active runs are
+// left in place, so creating a new run fails with an InstanceAlreadyExistsError.
+func (rb *redisBackend) archiveRun(ctx context.Context, instanceID string) error {
+	state, err := readInstance(ctx, rb.rdb, instanceID)
+	if err != nil {
+		if errors.Is(err, backend.ErrInstanceNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	if state.State != backend.WorkflowStateFinished {
+		return nil
+	}
+
+	h, err := rb.GetWorkflowInstanceHistory(ctx, state.Instance, nil)
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+
+	runs, err := readRuns(ctx, rb.rdb, instanceID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := rb.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		if err := archiveRunInPipeline(ctx, p, state, history.AddedTags(h), runs, len(h) > 0); err != nil {
+			return err
+		}
+
+		p.Del(ctx, instanceKey(instanceID), pendingEventsKey(instanceID))
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("archiving run: %w", err)
+	}
+
+	return nil
+}
+
+// archiveRunInPipeline moves the given finished run to the list of runs as part of the given pipeline. tags are the
+// tags added by the run, runs are the runs archived before. The state and pending events of the instance are left
+// for the caller to remove.
+func archiveRunInPipeline(ctx context.Context, p redis.Pipeliner, state *instanceState, tags []string, runs []*instanceState, hasHistory bool) error {
+	instanceID := state.Instance.InstanceID
+
+	run, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshaling run: %w", err)
+	}
+
+	removeInstanceTagsInPipeline(ctx, p, instanceID, tags)
+
+	// Archived runs are kept as long as the instance, the finished run might have been set to expire already.
+	// See setInstanceExpiration.
+	p.RPush(ctx, instanceRunsKey(instanceID), string(run))
+	p.Persist(ctx, instanceRunsKey(instanceID))
+
+	for _, r := range runs {
+		p.Persist(ctx, runHistoryKey(instanceID, r.Instance.ExecutionID))
+	}
+
+	if hasHistory {
+		p.Rename(ctx, historyKey(instanceID), runHistoryKey(instanceID, state.Instance.ExecutionID))
+		p.Persist(ctx, runHistoryKey(instanceID, state.Instance.ExecutionID))
+	}
+
+	return nil
+}
+
+func (rb *redisBackend) ListWorkflowInstanceRuns(ctx context.Context, instanceID string) ([]*backend.WorkflowRun, error) {
+	states, err := readRuns(ctx, rb.rdb, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := readInstance(ctx, rb.rdb, instanceID)
+	if err != nil {
+		if !errors.Is(err, backend.ErrInstanceNotFound) {
+			return nil, err
+		}
+	} else {
+		states = append(states, current)
+	}
+
+	if len(states) == 0 {
+		return nil, backend.ErrInstanceNotFound
+	}
+
+	runs := make([]*backend.WorkflowRun, 0, len(states))
+	for _, state := range states {
+		runs = append(runs, &backend.WorkflowRun{
+			Instance:    state.Instance,
+			State:       state.State,
+			CreatedAt:   state.CreatedAt,
+			CompletedAt: state.CompletedAt,
+		})
+	}
+
+	return runs, nil
+}
+
+func (rb *redisBackend) GetWorkflowInstanceRunHistory(ctx context.Context, instance *core.WorkflowInstance) ([]history.Event, error) {
+	current, err := readInstance(ctx, rb.rdb, instance.InstanceID)
+	if err != nil && !errors.Is(err, backend.ErrInstanceNotFound) {
+		return nil, err
+	}
+
+	if current != nil && current.Instance.ExecutionID == instance.ExecutionID {
+		return rb.GetWorkflowInstanceHistory(ctx, instance, nil)
+	}
+
+	if _, err := readRun(ctx, rb.rdb, instance); err != nil {
+		return nil, err
+	}
+
+	msgs, err := rb.rdb.XRange(ctx, runHistoryKey(instance.InstanceID, instance.ExecutionID), "-", "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("reading run history: %w", err)
+	}
+
+	events := make([]history.Event, 0, len(msgs))
+	for _, msg := range msgs {
+		var event history.Event
+		if err := json.Unmarshal([]byte(msg.Values["event"].(string)), &event); err != nil {
+			return nil, fmt.Errorf("unmarshaling event: %w", err)
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// readRuns returns the earlier, finished runs of the given instance, oldest first
+func readRuns(ctx context.Context, rdb redis.UniversalClient, instanceID string) ([]*instanceState, error) {
+	vals, err := rdb.LRange(ctx, instanceRunsKey(instanceID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("reading runs: %w", err)
+	}
+
+	runs := make([]*instanceState, 0, len(vals))
+	for _, val := range vals {
+		var state instanceState
+		if err := json.Unmarshal([]byte(val), &state); err != nil {
+			return nil, fmt.Errorf("unmarshaling run: %w", err)
+		}
+
+		runs = append(runs, &state)
+	}
+
+	return runs, nil
+}
+
+// readRun returns the earlier run with the execution ID of the given instance, or ErrInstanceNotFound
+func readRun(ctx context.Context, rdb redis.UniversalClient, instance *core.WorkflowInstance) (*instanceState, error) {
+	runs, err := readRuns(ctx, rdb, instance.InstanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, run := range runs {
+		if run.Instance.ExecutionID == instance.ExecutionID {
+			return run, nil
+		}
+	}
+
+	return nil, backend.ErrInstanceNotFound
+}
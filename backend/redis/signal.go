@@ -4,27 +4,49 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/cschleiden/go-workflows/backend/redis/taskqueue"
+	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/history"
 )
 
 func (rb *redisBackend) SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error {
+	defer backend.MeasureOperation(rb.options.Metrics, "redis", backend.OperationSignalWorkflow)()
+
+	return rb.signalWorkflow(ctx, instanceID, []history.Event{event})
+}
+
+var _ backend.BatchSignaler = (*redisBackend)(nil)
+
+func (rb *redisBackend) SignalWorkflowBatch(ctx context.Context, instanceID string, events []history.Event) error {
+	defer backend.MeasureOperation(rb.options.Metrics, "redis", backend.OperationSignalWorkflow)()
+
+	return rb.signalWorkflow(ctx, instanceID, events)
+}
+
+func (rb *redisBackend) signalWorkflow(ctx context.Context, instanceID string, events []history.Event) error {
 	_, err := readInstance(ctx, rb.rdb, instanceID)
 	if err != nil {
 		return err
 	}
 
-	msgID, err := addEventToStream(ctx, rb.rdb, pendingEventsKey(instanceID), &event)
+	if err := rb.checkBackpressure(ctx); err != nil {
+		return err
+	}
+
+	if err := rb.checkPendingEventsBackpressure(ctx, instanceID); err != nil {
+		return err
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	msgID, err := addEventsToStream(ctx, rb.rdb, pendingEventsKey(instanceID), events)
 	if err != nil {
-		return fmt.Errorf("adding event to stream: %w", err)
+		return fmt.Errorf("adding events to stream: %w", err)
 	}
 
-	if _, err := rb.workflowQueue.Enqueue(ctx, instanceID, &workflowTaskData{
-		LastPendingEventMessageID: *msgID,
-	}); err != nil {
-		if err != taskqueue.ErrTaskAlreadyInQueue {
-			return fmt.Errorf("queueing workflow task: %w", err)
-		}
+	if err := rb.queueWorkflowTask(ctx, instanceID, *msgID); err != nil {
+		return fmt.Errorf("queueing workflow task: %w", err)
 	}
 
 	return nil
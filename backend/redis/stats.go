@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+)
+
+var _ backend.BacklogReporter = (*redisBackend)(nil)
+
+func (rb *redisBackend) GetBacklogStats(ctx context.Context) (*backend.BacklogStats, error) {
+	workflowTasks, err := rb.workflowQueue.Size(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow task queue size: %w", err)
+	}
+
+	activityTasks, err := rb.activityQueue.Size(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting activity task queue size: %w", err)
+	}
+
+	futureEvents, err := rb.rdb.ZCard(ctx, futureEventsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("counting future events: %w", err)
+	}
+
+	return &backend.BacklogStats{
+		PendingWorkflowTasks: workflowTasks,
+		PendingActivityTasks: activityTasks,
+		FutureEvents:         futureEvents,
+	}, nil
+}
+
+// checkBackpressure returns a BackpressureError if the backlog exceeds the configured thresholds
+func (rb *redisBackend) checkBackpressure(ctx context.Context) error {
+	if !rb.options.Backpressure.CheckBacklog() {
+		return nil
+	}
+
+	stats, err := rb.GetBacklogStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	return rb.options.Backpressure.BacklogError(stats)
+}
+
+// checkPendingEventsBackpressure returns a BackpressureError if the given instance has too many unprocessed events
+func (rb *redisBackend) checkPendingEventsBackpressure(ctx context.Context, instanceID string) error {
+	if rb.options.Backpressure.MaxPendingEventsPerInstance <= 0 {
+		return nil
+	}
+
+	pendingEvents, err := rb.rdb.XLen(ctx, pendingEventsKey(instanceID)).Result()
+	if err != nil {
+		return fmt.Errorf("counting pending events: %w", err)
+	}
+
+	return rb.options.Backpressure.PendingEventsError(pendingEvents)
+}
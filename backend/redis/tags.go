@@ -0,0 +1,97 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/go-redis/redis/v8"
+)
+
+var _ backend.InstanceTagIndex = (*redisBackend)(nil)
+
+func (rb *redisBackend) GetWorkflowInstancesByTags(ctx context.Context, tags []string, activeOnly bool) ([]*core.WorkflowInstance, error) {
+	instances := make([]*core.WorkflowInstance, 0)
+
+	if len(tags) == 0 {
+		return instances, nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		keys = append(keys, instanceTagKey(tag))
+	}
+
+	instanceIDs, err := rb.rdb.SInter(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("finding instances by tags: %w", err)
+	}
+
+	for _, instanceID := range instanceIDs {
+		instanceState, err := readInstance(ctx, rb.rdb, instanceID)
+		if err != nil {
+			if errors.Is(err, backend.ErrInstanceNotFound) {
+				// Instance has expired, remove it from the index
+				if err := removeInstanceTags(ctx, rb.rdb, instanceID, tags); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			return nil, err
+		}
+
+		if activeOnly && instanceState.State == backend.WorkflowStateFinished {
+			continue
+		}
+
+		instances = append(instances, instanceState.Instance)
+	}
+
+	return instances, nil
+}
+
+func addInstanceTags(ctx context.Context, rdb redis.UniversalClient, instanceID string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		addInstanceTagsInPipeline(ctx, p, instanceID, tags)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("adding instance tags: %w", err)
+	}
+
+	return nil
+}
+
+func addInstanceTagsInPipeline(ctx context.Context, p redis.Pipeliner, instanceID string, tags []string) {
+	for _, tag := range tags {
+		p.SAdd(ctx, instanceTagKey(tag), instanceID)
+	}
+}
+
+func removeInstanceTags(ctx context.Context, rdb redis.UniversalClient, instanceID string, tags []string) error {
+	_, err := rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		removeInstanceTagsInPipeline(ctx, p, instanceID, tags)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("removing instance tags: %w", err)
+	}
+
+	return nil
+}
+
+func removeInstanceTagsInPipeline(ctx context.Context, p redis.Pipeliner, instanceID string, tags []string) {
+	for _, tag := range tags {
+		p.SRem(ctx, instanceTagKey(tag), instanceID)
+	}
+}
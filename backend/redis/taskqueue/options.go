@@ -0,0 +1,82 @@
+package taskqueue
+
+import "time"
+
+// Options configure a task queue
+type Options struct {
+	// GroupName is the name of the consumer group workers read tasks as. Defaults to "task-workers".
+	GroupName string
+
+	// WorkerName is the name of the consumer reading tasks from the queue. Defaults to a random name, which
+	// registers a new consumer every time a queue is created.
+	WorkerName string
+
+	// LockTimeout is how long a dequeued task stays locked, if Dequeue is called without a lock timeout.
+	// Defaults to 30s.
+	LockTimeout time.Duration
+
+	// MaxDeliveryAttempts is how often a task is delivered before it's parked. Parked tasks are removed from
+	// the queue and can be inspected with ParkedTasks. 0 delivers tasks until they are completed.
+	MaxDeliveryAttempts int64
+
+	// ClaimBatchSize is the maximum number of abandoned tasks claimed at once. Claimed tasks are handed out by
+	// the following calls to Dequeue. Defaults to 1.
+	ClaimBatchSize int64
+
+	// ConsumerIdleTimeout is how long other consumers of the queue can be idle before they are removed. Consumers
+	// with locked tasks are kept until the tasks are recovered. 0 keeps all consumers.
+	ConsumerIdleTimeout time.Duration
+}
+
+var DefaultOptions = Options{
+	GroupName:      "task-workers",
+	LockTimeout:    time.Second * 30,
+	ClaimBatchSize: 1,
+}
+
+type Option func(*Options)
+
+// WithGroupName sets the name of the consumer group workers read tasks as
+func WithGroupName(name string) Option {
+	return func(o *Options) {
+		o.GroupName = name
+	}
+}
+
+// WithWorkerName sets a stable name for the consumer reading tasks from the queue, for example the host name of
+// the worker. A restarted worker then continues as the same consumer instead of registering a new one.
+func WithWorkerName(name string) Option {
+	return func(o *Options) {
+		o.WorkerName = name
+	}
+}
+
+// WithLockTimeout sets how long a dequeued task stays locked, if Dequeue is called without a lock timeout
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.LockTimeout = timeout
+	}
+}
+
+// WithMaxDeliveryAttempts parks tasks that have been delivered the given number of times without being
+// completed, for example because every worker picking them up crashed.
+func WithMaxDeliveryAttempts(attempts int64) Option {
+	return func(o *Options) {
+		o.MaxDeliveryAttempts = attempts
+	}
+}
+
+// WithClaimBatchSize sets the maximum number of abandoned tasks claimed at once
+func WithClaimBatchSize(size int64) Option {
+	return func(o *Options) {
+		o.ClaimBatchSize = size
+	}
+}
+
+// WithConsumerIdleTimeout removes consumers that have been idle for longer than the given timeout, and that
+// have no locked tasks. The queue checks for idle consumers at most once per timeout while dequeueing.
+func WithConsumerIdleTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.ConsumerIdleTimeout = timeout
+	}
+}
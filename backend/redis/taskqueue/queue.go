@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -16,8 +17,22 @@ type taskQueue[T any] struct {
 	rdb        redis.UniversalClient
 	setKey     string
 	streamKey  string
+	parkedKey  string
 	groupName  string
 	workerName string
+	options    *Options
+
+	cleanupMu   sync.Mutex
+	lastCleanup time.Time
+
+	// claimed are abandoned tasks claimed by this worker, which have not been handed out yet
+	claimedMu sync.Mutex
+	claimed   []claimedTask[T]
+}
+
+type claimedTask[T any] struct {
+	task      *TaskItem[T]
+	claimedAt time.Time
 }
 
 type TaskItem[T any] struct {
@@ -34,21 +49,86 @@ type TaskItem[T any] struct {
 var ErrTaskAlreadyInQueue = errors.New("task already in queue")
 
 type TaskQueue[T any] interface {
+	// Enqueue adds a task with the given id to the queue and returns the generated task ID. If a task with the
+	// same id is already in the queue, the queue is not changed and ErrTaskAlreadyInQueue is returned.
 	Enqueue(ctx context.Context, id string, data *T) (*string, error)
+
+	// EnqueueInPipeline adds enqueueing a task to the given pipeline, to combine it with other commands in a single
+	// round trip. The returned function reports the result of the enqueue once the pipeline has been executed.
+	EnqueueInPipeline(ctx context.Context, p redis.Pipeliner, id string, data *T) func() error
+
+	// Dequeue returns the next task and locks it for lockTimeout. A lockTimeout of 0 uses the lock timeout of
+	// the queue options.
 	Dequeue(ctx context.Context, lockTimeout, timeout time.Duration) (*TaskItem[T], error)
+
+	// DequeueBatch works like Dequeue, but returns up to n tasks at once. Abandoned tasks are recovered first, it
+	// only waits for new tasks if there are no abandoned ones.
+	DequeueBatch(ctx context.Context, lockTimeout, timeout time.Duration, n int) ([]*TaskItem[T], error)
 	Extend(ctx context.Context, taskID string) error
+
+	// Release unlocks a dequeued task without completing it. The task is added to the end of the queue again.
+	Release(ctx context.Context, taskID string) error
 	Complete(ctx context.Context, taskID string) error
+
+	// CompleteInPipeline adds completing the task to the given pipeline, to combine it with other commands in a
+	// single round trip. The returned function reports the result of the completion once the pipeline has been
+	// executed.
+	CompleteInPipeline(ctx context.Context, p redis.Pipeliner, taskID string) func() error
 	Data(ctx context.Context, taskID string) (*TaskItem[T], error)
+
+	// Size returns the number of tasks in the queue, including tasks that are currently locked by a worker
+	Size(ctx context.Context) (int64, error)
+
+	// ParkedTasks returns the tasks that were removed from the queue after reaching the maximum number of
+	// delivery attempts, oldest first. The TaskID of a parked task is its ID in the list of parked tasks.
+	ParkedTasks(ctx context.Context) ([]*TaskItem[T], error)
+
+	// RemoveIdleConsumers removes other consumers of the queue that have been idle for longer than idleTimeout
+	// and have no locked tasks. It returns the number of removed consumers.
+	RemoveIdleConsumers(ctx context.Context, idleTimeout time.Duration) (int, error)
+
+	// Keys returns the keys of the queue, for scripts that enqueue tasks together with other commands
+	Keys() Keys
 }
 
-func New[T any](rdb redis.UniversalClient, tasktype string) (TaskQueue[T], error) {
+// Keys are the keys a task queue is stored in. A task is enqueued by adding its id to the set, and adding an entry
+// with the fields "id" and "data" to the stream. Data is the JSON encoded data of the task.
+type Keys struct {
+	SetKey    string
+	StreamKey string
+}
+
+func New[T any](rdb redis.UniversalClient, tasktype string, opts ...Option) (TaskQueue[T], error) {
+	options := DefaultOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.GroupName == "" {
+		options.GroupName = DefaultOptions.GroupName
+	}
+
+	if options.WorkerName == "" {
+		options.WorkerName = uuid.NewString()
+	}
+
+	if options.LockTimeout <= 0 {
+		options.LockTimeout = DefaultOptions.LockTimeout
+	}
+
+	if options.ClaimBatchSize <= 0 {
+		options.ClaimBatchSize = DefaultOptions.ClaimBatchSize
+	}
+
 	tq := &taskQueue[T]{
 		tasktype:   tasktype,
 		rdb:        rdb,
 		setKey:     "task-set:" + tasktype,
 		streamKey:  "task-stream:" + tasktype,
-		groupName:  "task-workers",
-		workerName: uuid.NewString(),
+		parkedKey:  "task-parked:" + tasktype,
+		groupName:  options.GroupName,
+		workerName: options.WorkerName,
+		options:    &options,
 	}
 
 	// Create the consumer group
@@ -64,7 +144,7 @@ func New[T any](rdb redis.UniversalClient, tasktype string) (TaskQueue[T], error
 	return tq, nil
 }
 
-// KEYS[1] = stream
+// KEYS[1] = set
 // KEYS[2] = stream
 // ARGV[1] = caller provided id of the task
 // ARGV[2] = additional data to store with the task
@@ -83,54 +163,127 @@ func (q *taskQueue[T]) Enqueue(ctx context.Context, id string, data *T) (*string
 		return nil, err
 	}
 
-	taskID, err := enqueueCmd.Run(ctx, q.rdb, []string{q.setKey, q.streamKey}, id, string(ds)).Result()
-	if err != nil && err != redis.Nil {
+	// The script returns nil, which is reported as redis.Nil, if the task is already in the set
+	taskID, err := enqueueCmd.Run(ctx, q.rdb, []string{q.setKey, q.streamKey}, id, string(ds)).Text()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrTaskAlreadyInQueue
+		}
+
 		return nil, fmt.Errorf("enqueueing task: %w", err)
 	}
 
-	if taskID == nil {
-		return nil, ErrTaskAlreadyInQueue
+	return &taskID, nil
+}
+
+func (q *taskQueue[T]) EnqueueInPipeline(ctx context.Context, p redis.Pipeliner, id string, data *T) func() error {
+	ds, err := json.Marshal(data)
+	if err != nil {
+		return func() error { return err }
 	}
 
-	tidStr := taskID.(string)
-	return &tidStr, nil
+	// Scripts cannot be loaded on demand in a pipeline, so send the full script
+	cmd := enqueueCmd.Eval(ctx, p, []string{q.setKey, q.streamKey}, id, string(ds))
+
+	return func() error {
+		if err := cmd.Err(); err != nil {
+			if err == redis.Nil {
+				return ErrTaskAlreadyInQueue
+			}
+
+			return fmt.Errorf("enqueueing task: %w", err)
+		}
+
+		return nil
+	}
+}
+
+func (q *taskQueue[T]) Keys() Keys {
+	return Keys{
+		SetKey:    q.setKey,
+		StreamKey: q.streamKey,
+	}
 }
 
 func (q *taskQueue[T]) Dequeue(ctx context.Context, lockTimeout, timeout time.Duration) (*TaskItem[T], error) {
+	tasks, err := q.DequeueBatch(ctx, lockTimeout, timeout, 1)
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+
+	return tasks[0], nil
+}
+
+func (q *taskQueue[T]) DequeueBatch(ctx context.Context, lockTimeout, timeout time.Duration, n int) ([]*TaskItem[T], error) {
+	if lockTimeout == 0 {
+		lockTimeout = q.options.LockTimeout
+	}
+
+	if n < 1 {
+		n = 1
+	}
+
+	if err := q.cleanupConsumers(ctx); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*TaskItem[T], 0, n)
+
 	// Try to recover abandoned messages
-	task, err := q.recover(ctx, lockTimeout)
-	if err != nil {
-		return nil, fmt.Errorf("checking for abandoned tasks: %w", err)
+	for len(tasks) < n {
+		task, err := q.recover(ctx, lockTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("checking for abandoned tasks: %w", err)
+		}
+
+		if task == nil {
+			break
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	if len(tasks) == n {
+		return tasks, nil
 	}
 
-	if task != nil {
-		return task, nil
+	// Check for new tasks, don't wait for them if we already have recovered tasks to return
+	block := timeout
+	if len(tasks) > 0 {
+		block = -1
 	}
 
-	// Check for new tasks
 	ids, err := q.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
 		Streams:  []string{q.streamKey, ">"},
 		Group:    q.groupName,
 		Consumer: q.workerName,
-		Count:    1,
-		Block:    timeout,
+		Count:    int64(n - len(tasks)),
+		Block:    block,
 	}).Result()
 	if err != nil && err != redis.Nil {
 		return nil, fmt.Errorf("dequeueing task: %w", err)
 	}
 
-	if len(ids) == 0 || len(ids[0].Messages) == 0 || err == redis.Nil {
-		return nil, nil
+	if len(ids) == 0 || err == redis.Nil {
+		return tasks, nil
+	}
+
+	for i := range ids[0].Messages {
+		task, err := msgToTaskItem[T](&ids[0].Messages[i])
+		if err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, task)
 	}
 
-	msg := ids[0].Messages[0]
-	return msgToTaskItem[T](&msg)
+	return tasks, nil
 }
 
 func (q *taskQueue[T]) Extend(ctx context.Context, taskID string) error {
-	// Claiming a message resets the idle timer. Don't use the `JUSTID` variant, we
-	// want to increase the retry counter.
-	_, err := q.rdb.XClaim(ctx, &redis.XClaimArgs{
+	// Claiming a message resets the idle timer. Use the `JUSTID` variant, which does not
+	// increase the delivery counter, extending a task is not another delivery attempt.
+	_, err := q.rdb.XClaimJustID(ctx, &redis.XClaimArgs{
 		Stream:   q.streamKey,
 		Group:    q.groupName,
 		Consumer: q.workerName,
@@ -144,6 +297,32 @@ func (q *taskQueue[T]) Extend(ctx context.Context, taskID string) error {
 	return nil
 }
 
+// KEYS[1] = stream
+// ARGV[1] = task id
+// ARGV[2] = group
+var releaseCmd = redis.NewScript(`
+	local task = redis.call("XRANGE", KEYS[1], ARGV[1], ARGV[1])
+	if #task == 0 then
+		return nil
+	end
+	redis.call("XACK", KEYS[1], ARGV[2], ARGV[1])
+	redis.call("XDEL", KEYS[1], ARGV[1])
+	return redis.call("XADD", KEYS[1], "*", "id", task[1][2][2], "data", task[1][2][4])
+`)
+
+func (q *taskQueue[T]) Release(ctx context.Context, taskID string) error {
+	// The task stays in the set, it's only moved to the end of the stream
+	if err := releaseCmd.Run(ctx, q.rdb, []string{q.streamKey}, taskID, q.groupName).Err(); err != nil {
+		if err == redis.Nil {
+			return errors.New("could not find task to release")
+		}
+
+		return fmt.Errorf("releasing task: %w", err)
+	}
+
+	return nil
+}
+
 // We need TaskIDs for the stream and caller provided IDs for the set. So first look up
 // the ID in the stream using the TaskID, then remove from the set and the stream
 // KEYS[1] = set
@@ -177,6 +356,24 @@ func (q *taskQueue[T]) Complete(ctx context.Context, taskID string) error {
 	return nil
 }
 
+func (q *taskQueue[T]) CompleteInPipeline(ctx context.Context, p redis.Pipeliner, taskID string) func() error {
+	// Scripts cannot be loaded on demand in a pipeline, so send the full script
+	cmd := completeCmd.Eval(ctx, p, []string{q.setKey, q.streamKey}, taskID, q.groupName)
+
+	return func() error {
+		c, err := cmd.Int64()
+		if err != nil && err != redis.Nil {
+			return fmt.Errorf("completing task: %w", err)
+		}
+
+		if c == 0 || err == redis.Nil {
+			return errors.New("could not find task to complete")
+		}
+
+		return nil
+	}
+}
+
 func (q *taskQueue[T]) Data(ctx context.Context, taskID string) (*TaskItem[T], error) {
 	msg, err := q.rdb.XRange(ctx, q.streamKey, taskID, taskID).Result()
 	if err != nil && err != redis.Nil {
@@ -186,7 +383,85 @@ func (q *taskQueue[T]) Data(ctx context.Context, taskID string) (*TaskItem[T], e
 	return msgToTaskItem[T](&msg[0])
 }
 
+func (q *taskQueue[T]) Size(ctx context.Context) (int64, error) {
+	n, err := q.rdb.XLen(ctx, q.streamKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("getting queue size: %w", err)
+	}
+
+	return n, nil
+}
+
+func (q *taskQueue[T]) ParkedTasks(ctx context.Context) ([]*TaskItem[T], error) {
+	msgs, err := q.rdb.XRange(ctx, q.parkedKey, "-", "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("reading parked tasks: %w", err)
+	}
+
+	tasks := make([]*TaskItem[T], 0, len(msgs))
+	for i := range msgs {
+		task, err := msgToTaskItem[T](&msgs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+func (q *taskQueue[T]) RemoveIdleConsumers(ctx context.Context, idleTimeout time.Duration) (int, error) {
+	consumers, err := xinfoConsumers(ctx, q.rdb, q.streamKey, q.groupName)
+	if err != nil {
+		return 0, fmt.Errorf("listing consumers: %w", err)
+	}
+
+	removed := 0
+	for _, c := range consumers {
+		// Removing a consumer drops its pending messages, keep consumers with locked tasks around until
+		// the tasks have been recovered by another worker.
+		if c.Name == q.workerName || c.Pending > 0 || c.Idle < idleTimeout {
+			continue
+		}
+
+		if err := q.rdb.XGroupDelConsumer(ctx, q.streamKey, q.groupName, c.Name).Err(); err != nil {
+			return removed, fmt.Errorf("removing consumer: %w", err)
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
+
+// cleanupConsumers removes idle consumers, if the last check was more than the configured idle timeout ago
+func (q *taskQueue[T]) cleanupConsumers(ctx context.Context) error {
+	idleTimeout := q.options.ConsumerIdleTimeout
+	if idleTimeout <= 0 {
+		return nil
+	}
+
+	q.cleanupMu.Lock()
+	if time.Since(q.lastCleanup) < idleTimeout {
+		q.cleanupMu.Unlock()
+		return nil
+	}
+	q.lastCleanup = time.Now()
+	q.cleanupMu.Unlock()
+
+	if _, err := q.RemoveIdleConsumers(ctx, idleTimeout); err != nil {
+		return fmt.Errorf("removing idle consumers: %w", err)
+	}
+
+	return nil
+}
+
 func (q *taskQueue[T]) recover(ctx context.Context, idleTimeout time.Duration) (*TaskItem[T], error) {
+	if task, err := q.nextClaimed(ctx, idleTimeout); err != nil || task != nil {
+		return task, err
+	}
+
 	// Ignore the start argument, we are deleting tasks as they are completed, so we'll always
 	// start this scan from the beginning.
 	msgs, _, err := q.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
@@ -194,7 +469,7 @@ func (q *taskQueue[T]) recover(ctx context.Context, idleTimeout time.Duration) (
 		Group:    q.groupName,
 		Consumer: q.workerName,
 		MinIdle:  idleTimeout,
-		Count:    1,   // Get at most one abandoned task
+		Count:    q.options.ClaimBatchSize,
 		Start:    "0", // Start at the beginning of the pending items
 	}).Result()
 
@@ -206,7 +481,118 @@ func (q *taskQueue[T]) recover(ctx context.Context, idleTimeout time.Duration) (
 		return nil, nil
 	}
 
-	return msgToTaskItem[T](&msgs[0])
+	msgs, err = q.parkUndeliverable(ctx, msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	task, err := msgToTaskItem[T](&msgs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the remaining claimed tasks for the next calls
+	q.claimedMu.Lock()
+	defer q.claimedMu.Unlock()
+
+	for i := range msgs[1:] {
+		t, err := msgToTaskItem[T](&msgs[i+1])
+		if err != nil {
+			return nil, err
+		}
+
+		q.claimed = append(q.claimed, claimedTask[T]{task: t, claimedAt: time.Now()})
+	}
+
+	return task, nil
+}
+
+// nextClaimed returns the next task claimed by an earlier recovery. Tasks claimed longer than idleTimeout ago
+// are skipped, they might have been recovered by another worker in the meantime.
+func (q *taskQueue[T]) nextClaimed(ctx context.Context, idleTimeout time.Duration) (*TaskItem[T], error) {
+	q.claimedMu.Lock()
+	defer q.claimedMu.Unlock()
+
+	for len(q.claimed) > 0 {
+		c := q.claimed[0]
+		q.claimed = q.claimed[1:]
+
+		if time.Since(c.claimedAt) >= idleTimeout {
+			continue
+		}
+
+		// Reset the idle timer, the task might have been waiting for a while
+		if err := q.Extend(ctx, c.task.TaskID); err != nil {
+			return nil, err
+		}
+
+		return c.task, nil
+	}
+
+	return nil, nil
+}
+
+// KEYS[1] = set
+// KEYS[2] = stream
+// KEYS[3] = parked stream
+// ARGV[1] = task id
+// ARGV[2] = group
+var parkCmd = redis.NewScript(`
+	local task = redis.call("XRANGE", KEYS[2], ARGV[1], ARGV[1])
+	if #task == 0 then
+		return 0
+	end
+	local id = task[1][2][2]
+	local data = task[1][2][4]
+	redis.call("XADD", KEYS[3], "*", "id", id, "data", data, "task_id", ARGV[1])
+	redis.call("SREM", KEYS[1], id)
+	redis.call("XACK", KEYS[2], ARGV[2], ARGV[1])
+	return redis.call("XDEL", KEYS[2], ARGV[1])
+`)
+
+// parkUndeliverable parks the given claimed tasks that have reached the maximum number of delivery attempts,
+// and returns the remaining ones.
+func (q *taskQueue[T]) parkUndeliverable(ctx context.Context, msgs []redis.XMessage) ([]redis.XMessage, error) {
+	if q.options.MaxDeliveryAttempts <= 0 {
+		return msgs, nil
+	}
+
+	pending, err := q.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   q.streamKey,
+		Group:    q.groupName,
+		Start:    msgs[0].ID,
+		End:      msgs[len(msgs)-1].ID,
+		Count:    int64(len(msgs)),
+		Consumer: q.workerName,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("reading delivery attempts: %w", err)
+	}
+
+	attempts := make(map[string]int64, len(pending))
+	for _, p := range pending {
+		attempts[p.ID] = p.RetryCount
+	}
+
+	remaining := make([]redis.XMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		// Claiming the task counts as a delivery, so this is the number of attempts including the one
+		// that would be made now.
+		if attempts[msg.ID] <= q.options.MaxDeliveryAttempts {
+			remaining = append(remaining, msg)
+			continue
+		}
+
+		if err := parkCmd.Run(ctx, q.rdb, []string{q.setKey, q.streamKey, q.parkedKey}, msg.ID, q.groupName).Err(); err != nil {
+			return nil, fmt.Errorf("parking task: %w", err)
+		}
+	}
+
+	return remaining, nil
 }
 
 func msgToTaskItem[T any](msg *redis.XMessage) (*TaskItem[T], error) {
@@ -224,3 +610,47 @@ func msgToTaskItem[T any](msg *redis.XMessage) (*TaskItem[T], error) {
 		Data:   t,
 	}, nil
 }
+
+// consumer is an entry of the reply to XINFO CONSUMERS
+type consumer struct {
+	Name    string
+	Pending int64
+	Idle    time.Duration
+}
+
+// xinfoConsumers lists the consumers of the given group. The reply is parsed here instead of using XInfoConsumers of
+// go-redis, which rejects consumers with other fields than name, pending, and idle, like the inactive field
+// Redis 7.2 added.
+func xinfoConsumers(ctx context.Context, rdb redis.UniversalClient, stream, group string) ([]consumer, error) {
+	reply, err := rdb.Do(ctx, "XINFO", "CONSUMERS", stream, group).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	consumers := make([]consumer, 0, len(reply))
+	for _, entry := range reply {
+		fields, ok := entry.([]interface{})
+		if !ok || len(fields)%2 != 0 {
+			return nil, fmt.Errorf("unexpected XINFO CONSUMERS entry %v", entry)
+		}
+
+		var c consumer
+		for i := 0; i < len(fields); i += 2 {
+			key, _ := fields[i].(string)
+
+			switch key {
+			case "name":
+				c.Name, _ = fields[i+1].(string)
+			case "pending":
+				c.Pending, _ = fields[i+1].(int64)
+			case "idle":
+				idle, _ := fields[i+1].(int64)
+				c.Idle = time.Duration(idle) * time.Millisecond
+			}
+		}
+
+		consumers = append(consumers, c)
+	}
+
+	return consumers, nil
+}
@@ -62,7 +62,7 @@ func Test_TaskQueue(t *testing.T) {
 				require.NoError(t, err)
 
 				_, err = q.Enqueue(context.Background(), "t1", nil)
-				require.Error(t, ErrTaskAlreadyInQueue, err)
+				require.ErrorIs(t, err, ErrTaskAlreadyInQueue)
 
 				task, err := q.Dequeue(context.Background(), lockTimeout, blockTimeout)
 				require.NoError(t, err)
@@ -194,6 +194,176 @@ func Test_TaskQueue(t *testing.T) {
 				require.Nil(t, recoveredTask)
 			},
 		},
+		{
+			name: "Remove idle consumers",
+			f: func(t *testing.T) {
+				q, _ := New[any](client, "test", WithWorkerName("worker-1"))
+				q2, _ := New[any](client, "test", WithWorkerName("worker-2"))
+
+				_, err := q2.Dequeue(context.Background(), lockTimeout, blockTimeout)
+				require.NoError(t, err)
+
+				time.Sleep(time.Millisecond * 10)
+
+				removed, err := q.RemoveIdleConsumers(context.Background(), time.Millisecond*5)
+				require.NoError(t, err)
+				require.Equal(t, 1, removed)
+
+				consumers, err := xinfoConsumers(context.Background(), client, "task-stream:test", "task-workers")
+				require.NoError(t, err)
+				require.Empty(t, consumers)
+			},
+		},
+		{
+			name: "Keep idle consumers with locked tasks",
+			f: func(t *testing.T) {
+				q, _ := New[any](client, "test")
+				q2, _ := New[any](client, "test")
+
+				_, err := q.Enqueue(context.Background(), "t1", nil)
+				require.NoError(t, err)
+
+				task, err := q2.Dequeue(context.Background(), lockTimeout, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				time.Sleep(time.Millisecond * 10)
+
+				removed, err := q.RemoveIdleConsumers(context.Background(), time.Millisecond*5)
+				require.NoError(t, err)
+				require.Equal(t, 0, removed)
+			},
+		},
+		{
+			name: "Park task after max delivery attempts",
+			f: func(t *testing.T) {
+				q, _ := New[any](client, "test", WithMaxDeliveryAttempts(1))
+				q2, _ := New[any](client, "test", WithMaxDeliveryAttempts(1))
+
+				_, err := q.Enqueue(context.Background(), "t1", nil)
+				require.NoError(t, err)
+
+				task, err := q2.Dequeue(context.Background(), lockTimeout, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				time.Sleep(time.Millisecond * 10)
+
+				recoveredTask, err := q.Dequeue(context.Background(), time.Millisecond*1, blockTimeout)
+				require.NoError(t, err)
+				require.Nil(t, recoveredTask)
+
+				parked, err := q.ParkedTasks(context.Background())
+				require.NoError(t, err)
+				require.Len(t, parked, 1)
+				require.Equal(t, "t1", parked[0].ID)
+
+				size, err := q.Size(context.Background())
+				require.NoError(t, err)
+				require.Equal(t, int64(0), size)
+
+				_, err = q.Enqueue(context.Background(), "t1", nil)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "Claim abandoned tasks in batches",
+			f: func(t *testing.T) {
+				q, _ := New[any](client, "test", WithClaimBatchSize(2))
+				q2, _ := New[any](client, "test")
+
+				for _, id := range []string{"t1", "t2"} {
+					_, err := q.Enqueue(context.Background(), id, nil)
+					require.NoError(t, err)
+
+					task, err := q2.Dequeue(context.Background(), lockTimeout, blockTimeout)
+					require.NoError(t, err)
+					require.NotNil(t, task)
+				}
+
+				time.Sleep(time.Millisecond * 10)
+
+				task, err := q.Dequeue(context.Background(), time.Millisecond*1, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, "t1", task.ID)
+
+				task, err = q.Dequeue(context.Background(), time.Second, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, "t2", task.ID)
+			},
+		},
+		{
+			name: "Dequeue batch",
+			f: func(t *testing.T) {
+				q, _ := New[any](client, "test")
+
+				for _, id := range []string{"t1", "t2", "t3"} {
+					_, err := q.Enqueue(context.Background(), id, nil)
+					require.NoError(t, err)
+				}
+
+				tasks, err := q.DequeueBatch(context.Background(), lockTimeout, blockTimeout, 2)
+				require.NoError(t, err)
+				require.Len(t, tasks, 2)
+				require.Equal(t, "t1", tasks[0].ID)
+				require.Equal(t, "t2", tasks[1].ID)
+
+				tasks, err = q.DequeueBatch(context.Background(), lockTimeout, blockTimeout, 2)
+				require.NoError(t, err)
+				require.Len(t, tasks, 1)
+				require.Equal(t, "t3", tasks[0].ID)
+			},
+		},
+		{
+			name: "Complete task in pipeline",
+			f: func(t *testing.T) {
+				q, _ := New[any](client, "test")
+
+				_, err := q.Enqueue(context.Background(), "t1", nil)
+				require.NoError(t, err)
+
+				task, err := q.Dequeue(context.Background(), lockTimeout, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				var complete func() error
+				_, err = client.Pipelined(context.Background(), func(p redis.Pipeliner) error {
+					complete = q.CompleteInPipeline(context.Background(), p, task.TaskID)
+					return nil
+				})
+				require.NoError(t, err)
+				require.NoError(t, complete())
+
+				size, err := q.Size(context.Background())
+				require.NoError(t, err)
+				require.Zero(t, size)
+			},
+		},
+		{
+			name: "Enqueue task in pipeline",
+			f: func(t *testing.T) {
+				q, _ := New[string](client, "test")
+
+				data := "data"
+				var enqueue1, enqueue2 func() error
+				_, err := client.Pipelined(context.Background(), func(p redis.Pipeliner) error {
+					enqueue1 = q.EnqueueInPipeline(context.Background(), p, "t1", &data)
+					enqueue2 = q.EnqueueInPipeline(context.Background(), p, "t1", &data)
+					return nil
+				})
+				require.ErrorIs(t, err, redis.Nil)
+				require.NoError(t, enqueue1())
+				require.ErrorIs(t, enqueue2(), ErrTaskAlreadyInQueue)
+
+				task, err := q.Dequeue(context.Background(), lockTimeout, blockTimeout)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, "t1", task.ID)
+				require.Equal(t, "data", task.Data)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
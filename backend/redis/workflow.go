@@ -3,8 +3,8 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
@@ -28,14 +28,33 @@ var futureEventsCmd = redis.NewScript(`
 	local events = redis.call("ZRANGE", KEYS[1], "-inf", ARGV[1], "BYSCORE")
 	if events ~= false and #events ~= 0 then
 		redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
-		return redis.call("MGET", unpack(events))
+		local payloads = redis.call("MGET", unpack(events))
+		redis.call("DEL", unpack(events))
+		return payloads
 	end
 `)
 
+var _ backend.WorkflowQueueProvider = (*redisBackend)(nil)
+
 func (rb *redisBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	return rb.GetWorkflowTaskFromQueues(ctx, []string{backend.DefaultWorkflowQueue}, nil)
+}
+
+var _ backend.IncrementalHistoryTaskProvider = (*redisBackend)(nil)
+
+func (rb *redisBackend) GetWorkflowTaskWithHistory(ctx context.Context, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error) {
+	return rb.GetWorkflowTaskFromQueues(ctx, []string{backend.DefaultWorkflowQueue}, knownSequenceID)
+}
+
+func (rb *redisBackend) GetWorkflowTaskFromQueues(ctx context.Context, queues []string, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error) {
+	defer backend.MeasureOperation(rb.options.Metrics, "redis", backend.OperationGetWorkflowTask)()
+
+	if len(queues) == 0 {
+		queues = []string{backend.DefaultWorkflowQueue}
+	}
+
 	// Check for future events
-	now := time.Now().Unix()
-	nowStr := strconv.Itoa(int(now))
+	nowStr := futureEventScore(time.Now())
 
 	result, err := futureEventsCmd.Run(ctx, rb.rdb, []string{futureEventsKey()}, nowStr).Result()
 	if err != nil && err != redis.Nil {
@@ -65,32 +84,61 @@ func (rb *redisBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, er
 				continue
 			}
 
+			if futureEvent.Instance.ExecutionID != "" && futureEvent.Instance.ExecutionID != instanceState.Instance.ExecutionID {
+				rb.options.Logger.Debug("Ignoring future event for previous run of instance", "instance_id", futureEvent.Instance.InstanceID, "event_id", futureEvent.Event.ID)
+				continue
+			}
+
 			msgID, err := addEventToStream(ctx, rb.rdb, pendingEventsKey(futureEvent.Instance.InstanceID), futureEvent.Event)
 			if err != nil {
 				return nil, fmt.Errorf("adding future event to stream: %w", err)
 			}
 
 			// Instance now has at least one pending event, try to queue task
-			if _, err := rb.workflowQueue.Enqueue(ctx, futureEvent.Instance.InstanceID, &workflowTaskData{
-				LastPendingEventMessageID: *msgID,
-			}); err != nil {
-				if err != taskqueue.ErrTaskAlreadyInQueue {
-					return nil, fmt.Errorf("queueing workflow task: %w", err)
-				}
+			if err := rb.queueInstanceTask(ctx, instanceState, *msgID); err != nil {
+				return nil, fmt.Errorf("queueing workflow task: %w", err)
 			}
 		}
 	}
 
 	// Try to get a workflow task
-	instanceTask, err := rb.workflowQueue.Dequeue(ctx, rb.options.WorkflowLockTimeout, rb.options.BlockTimeout)
-	if err != nil {
-		return nil, err
+	// Don't block longer than the allowed timer skew, future events are only checked between attempts
+	blockTimeout := rb.options.BlockTimeout
+	if rb.options.MaxTimerSkew > 0 && blockTimeout > rb.options.MaxTimerSkew {
+		blockTimeout = rb.options.MaxTimerSkew
+	}
+
+	// Split the block timeout between the queues, since each queue is a separate stream
+	if len(queues) > 1 {
+		blockTimeout /= time.Duration(len(queues))
+		if blockTimeout < time.Millisecond {
+			blockTimeout = time.Millisecond
+		}
 	}
 
-	if instanceTask == nil {
-		return nil, nil
+	for _, queueName := range queues {
+		workflowQueue, err := rb.workflowQueueFor(queueName)
+		if err != nil {
+			return nil, err
+		}
+
+		instanceTask, err := rb.workflowTasks.dequeue(ctx, workflowQueue, queueName, rb.options.BatchSize, rb.options.WorkflowLockTimeout, blockTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if instanceTask == nil {
+			continue
+		}
+
+		return rb.workflowTask(ctx, queueName, instanceTask, knownSequenceID)
 	}
 
+	return nil, nil
+}
+
+// workflowTask returns the task handed out to workers for the given dequeued instance task
+func (rb *redisBackend) workflowTask(ctx context.Context, queueName string, instanceTask *taskqueue.TaskItem[workflowTaskData], knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error) {
 	instanceState, err := readInstance(ctx, rb.rdb, instanceTask.ID)
 	if err != nil {
 		return nil, fmt.Errorf("reading workflow instance: %w", err)
@@ -114,16 +162,47 @@ func (rb *redisBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, er
 		newEvents = append(newEvents, event)
 	}
 
-	return &task.Workflow{
-		ID:               instanceTask.TaskID,
+	t := &task.Workflow{
+		ID:               queueTaskID(queueName, instanceTask.TaskID),
 		WorkflowInstance: instanceState.Instance,
 		LastSequenceID:   instanceState.LastSequenceID,
 		NewEvents:        newEvents,
-	}, nil
+		ScheduledAt:      task.ScheduledAt(newEvents...),
+	}
+
+	// Include the history the worker is missing
+	if knownSequenceID != nil {
+		if sequenceID, ok := knownSequenceID(t.WorkflowInstance); ok && sequenceID < t.LastSequenceID {
+			t.History, err = readHistoryAfter(ctx, rb.rdb, historyKey(instanceTask.ID), sequenceID)
+			if err != nil {
+				return nil, fmt.Errorf("reading workflow history: %w", err)
+			}
+
+			t.HistorySequenceID = sequenceID
+		}
+	}
+
+	return t, nil
 }
 
 func (rb *redisBackend) ExtendWorkflowTask(ctx context.Context, taskID string, instance *core.WorkflowInstance) error {
-	return rb.workflowQueue.Extend(ctx, taskID)
+	workflowQueue, taskID, err := rb.workflowQueueForTask(taskID)
+	if err != nil {
+		return err
+	}
+
+	return workflowQueue.Extend(ctx, taskID)
+}
+
+var _ backend.WorkflowTaskReleaser = (*redisBackend)(nil)
+
+func (rb *redisBackend) ReleaseWorkflowTask(ctx context.Context, taskID string, instance *core.WorkflowInstance) error {
+	workflowQueue, taskID, err := rb.workflowQueueForTask(taskID)
+	if err != nil {
+		return err
+	}
+
+	return workflowQueue.Release(ctx, taskID)
 }
 
 // Remove all pending events before (and including) a given message id
@@ -136,134 +215,391 @@ var removePendingEventsCmd = redis.NewScript(`
 	return removed
 `)
 
+// Add events to the pending events of an instance, and queue a workflow task for it if there are pending events and
+// no task is queued yet. The data of the task is encoded like workflowTaskData. Returns 1 if a task was queued.
+// KEYS[1] - pending events stream key
+// KEYS[2] - workflow task queue set key
+// KEYS[3] - workflow task queue stream key
+// ARGV[1] - instance id
+// ARGV[2..n] - events
+var queuePendingEventsCmd = redis.NewScript(`
+	for i = 2, #ARGV do
+		redis.call("XADD", KEYS[1], "*", "event", ARGV[i])
+	end
+
+	local last = redis.call("XREVRANGE", KEYS[1], "+", "-", "COUNT", 1)
+	if #last == 0 then
+		return 0
+	end
+
+	if redis.call("SADD", KEYS[2], ARGV[1]) == 0 then
+		return 0
+	end
+
+	local data = cjson.encode({ last_pending_event_message_id = last[1][1] })
+	redis.call("XADD", KEYS[3], "*", "id", ARGV[1], "data", data)
+
+	return 1
+`)
+
+// queuePendingEvents adds the given events to the pending events of the instance and queues a workflow task for it,
+// as part of the given pipeline. If there already is a task queued for the instance, it will pick up the events.
+func queuePendingEvents(ctx context.Context, p redis.Pipeliner, workflowQueue taskqueue.TaskQueue[workflowTaskData], instanceID string, events []history.Event) error {
+	args := []interface{}{instanceID}
+	for _, event := range events {
+		eventData, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		args = append(args, string(eventData))
+	}
+
+	keys := workflowQueue.Keys()
+
+	// Scripts cannot be loaded on demand in a pipeline, so send the full script
+	queuePendingEventsCmd.Eval(ctx, p, []string{pendingEventsKey(instanceID), keys.SetKey, keys.StreamKey}, args...)
+
+	return nil
+}
+
+// Replace the pending events of an instance that continues as new with the started event of its next run. Signals
+// that have not been delivered to the finished run are delivered to the next run.
+// KEYS[1] - pending events stream key
+// ARGV[1] - started event of the next run
+// ARGV[2] - event type of signals
+var continuePendingEventsCmd = redis.NewScript(`
+	local signals = {}
+	for _, msg in ipairs(redis.call("XRANGE", KEYS[1], "-", "+")) do
+		local event = msg[2][2]
+		if cjson.decode(event).t == tonumber(ARGV[2]) then
+			table.insert(signals, event)
+		end
+	end
+
+	redis.call("DEL", KEYS[1])
+	redis.call("XADD", KEYS[1], "*", "event", ARGV[1])
+	for _, event in ipairs(signals) do
+		redis.call("XADD", KEYS[1], "*", "event", event)
+	end
+
+	return #signals
+`)
+
 func (rb *redisBackend) CompleteWorkflowTask(ctx context.Context, taskID string, instance *core.WorkflowInstance, state backend.WorkflowState, executedEvents []history.Event, activityEvents []history.Event, workflowEvents []history.WorkflowEvent) error {
-	task, err := rb.workflowQueue.Data(ctx, taskID)
+	defer backend.MeasureOperation(rb.options.Metrics, "redis", backend.OperationCompleteWorkflowTask)()
+
+	workflowQueue, queueTaskID, err := rb.workflowQueueForTask(taskID)
+	if err != nil {
+		return err
+	}
+
+	task, err := workflowQueue.Data(ctx, queueTaskID)
 	if err != nil {
 		return fmt.Errorf("getting workflow task: %w", err)
 	}
 
-	// Add executed events to the history
-	// TODO: Use pipeline
-	for _, executedEvent := range executedEvents {
-		if _, err := addEventToStream(ctx, rb.rdb, historyKey(instance.InstanceID), &executedEvent); err != nil {
-			return err
-		}
+	// Update instance state with last message
+	instanceState, err := readInstance(ctx, rb.rdb, instance.InstanceID)
+	if err != nil {
+		return fmt.Errorf("reading workflow instance: %w", err)
 	}
 
-	// Send new workflow events to the respective streams
-	groupedEvents := make(map[*workflow.Instance][]history.Event)
+	instanceState.State = state
+	if state == backend.WorkflowStateFinished {
+		t := time.Now()
+		instanceState.CompletedAt = &t
+	}
+	instanceState.LastSequenceID = executedEvents[len(executedEvents)-1].SequenceID
+
+	// Group new workflow events by target instance
+	var targetIDs []string
+	targetInstances := make(map[string]*workflow.Instance)
+	groupedEvents := make(map[string][]history.Event)
+	var nextRun *history.WorkflowEvent
 	for _, m := range workflowEvents {
-		if _, ok := groupedEvents[m.WorkflowInstance]; !ok {
-			groupedEvents[m.WorkflowInstance] = []history.Event{}
+		if m.WorkflowInstance.InstanceID == instance.InstanceID && m.WorkflowInstance.ExecutionID != instance.ExecutionID {
+			// The workflow continues as new, its next run is started once this task has been completed
+			m := m
+			nextRun = &m
+			continue
 		}
 
-		groupedEvents[m.WorkflowInstance] = append(groupedEvents[m.WorkflowInstance], m.HistoryEvent)
+		targetID := m.WorkflowInstance.InstanceID
+		if _, ok := targetInstances[targetID]; !ok {
+			targetIDs = append(targetIDs, targetID)
+			targetInstances[targetID] = m.WorkflowInstance
+		}
+
+		groupedEvents[targetID] = append(groupedEvents[targetID], m.HistoryEvent)
 	}
 
-	for targetInstance, events := range groupedEvents {
-		if instance.InstanceID != targetInstance.InstanceID {
-			// Instance might not exist, try to create a new instance ignoring any duplicates
-			if err := createInstance(ctx, rb.rdb, targetInstance, true); err != nil {
-				return err
+	// Look up the workflow queues of the target instances, so that tasks can be queued for them below
+	targetQueues, err := rb.targetWorkflowQueues(ctx, instance.InstanceID, targetIDs, groupedEvents)
+	if err != nil {
+		return err
+	}
+
+	instanceQueue, err := rb.workflowQueueFor(instanceState.Queue)
+	if err != nil {
+		return err
+	}
+
+	var nextRunState *continuedRun
+	if nextRun != nil {
+		nextRunState, err = rb.readContinuedRun(ctx, instanceState, executedEvents, *nextRun)
+		if err != nil {
+			return fmt.Errorf("continuing workflow instance as new: %w", err)
+		}
+	}
+
+	// Write all changes in a single transaction
+	var completeTask func() error
+	enqueueActivities := make([]func() error, 0, len(activityEvents))
+	if _, err := rb.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		// Add executed events to the history
+		if err := addEventsToStreamInPipeline(ctx, p, historyKey(instance.InstanceID), executedEvents); err != nil {
+			return err
+		}
+
+		for _, executedEvent := range executedEvents {
+			// Remove fired events of timers canceled by the workflow, so they are not delivered anymore
+			if executedEvent.Type == history.EventType_TimerCanceled {
+				removeFutureEvent(ctx, p, instance, &executedEvent)
 			}
 		}
 
-		// Insert pending events for target instance
-		var lastPendingMessageID *string
+		// Index tags added by the workflow
+		addInstanceTagsInPipeline(ctx, p, instance.InstanceID, history.AddedTags(executedEvents))
 
-		// TODO: use pipelines
-		for _, event := range events {
-			switch event.Type {
-			case history.EventType_TimerCanceled:
-				if err := removeFutureEvent(ctx, rb.rdb, targetInstance, &event); err != nil {
-					return err
-				}
+		// Send new workflow events to the respective streams
+		var ownPendingEvents []history.Event
+		for _, targetID := range targetIDs {
+			targetInstance := targetInstances[targetID]
 
-			case history.EventType_SubWorkflowCancellationRequested:
-				if err := rb.CancelWorkflowInstance(ctx, targetInstance, &event); err != nil {
+			if targetID != instance.InstanceID {
+				// Instance might not exist, try to create a new instance ignoring any duplicates
+				if err := createInstanceInPipeline(ctx, p, targetInstance, history.WorkflowQueue(groupedEvents[targetID])); err != nil {
 					return err
 				}
 			}
 
-			if event.VisibleAt != nil {
-				// Add future event
-				if err := addFutureEvent(ctx, rb.rdb, targetInstance, &event); err != nil {
-					return err
+			var pendingEvents []history.Event
+			for _, event := range groupedEvents[targetID] {
+				event := event
+
+				if event.Type == history.EventType_TimerCanceled {
+					removeFutureEvent(ctx, p, targetInstance, &event)
+				}
+
+				if event.VisibleAt != nil {
+					if err := addFutureEvent(ctx, p, targetInstance, &event); err != nil {
+						return err
+					}
+				} else {
+					pendingEvents = append(pendingEvents, event)
 				}
-			} else {
-				// Add pending event to stream
-				lastPendingMessageID, err = addEventToStream(ctx, rb.rdb, pendingEventsKey(targetInstance.InstanceID), &event)
-				if err != nil {
+			}
+
+			if targetID == instance.InstanceID {
+				ownPendingEvents = pendingEvents
+				continue
+			}
+
+			if len(pendingEvents) > 0 {
+				if err := queuePendingEvents(ctx, p, targetQueues[targetID], targetID, pendingEvents); err != nil {
 					return err
 				}
 			}
 		}
 
-		// If any pending message was added, try to queue workflow task
-		if lastPendingMessageID != nil && targetInstance != instance {
-			if _, err := rb.workflowQueue.Enqueue(ctx, targetInstance.InstanceID, &workflowTaskData{
-				LastPendingEventMessageID: *lastPendingMessageID,
-			}); err != nil {
-				if err != taskqueue.ErrTaskAlreadyInQueue {
-					return fmt.Errorf("adding instance to locked instances set: %w", err)
-				}
+		if err := updateInstance(ctx, p, instance.InstanceID, instanceState); err != nil {
+			return err
+		}
+
+		if state == backend.WorkflowStateFinished {
+			p.SRem(ctx, activeInstancesKey(), instance.InstanceID)
+		}
+
+		// Store activity data
+		for _, activityEvent := range activityEvents {
+			var queueName string
+			if a, ok := activityEvent.Attributes.(*history.ActivityScheduledAttributes); ok {
+				queueName = a.Queue
+			}
+
+			activityQueue, err := rb.activityQueueFor(queueName)
+			if err != nil {
+				return err
 			}
+
+			enqueueActivities = append(enqueueActivities, activityQueue.EnqueueInPipeline(ctx, p, activityEvent.ID, &activityData{
+				Instance: instance,
+				ID:       activityEvent.ID,
+				Event:    activityEvent,
+			}))
 		}
-	}
 
-	// Update instance state with last message
-	instanceState, err := readInstance(ctx, rb.rdb, instance.InstanceID)
-	if err != nil {
-		return fmt.Errorf("reading workflow instance: %w", err)
+		// Remove executed pending events, and complete the workflow task to unlock the instance
+		removePendingEventsCmd.Eval(ctx, p, []string{pendingEventsKey(instance.InstanceID)}, task.Data.LastPendingEventMessageID)
+		completeTask = workflowQueue.CompleteInPipeline(ctx, p, queueTaskID)
+
+		if nextRunState != nil {
+			// Archive the finished run and start the next run in the same transaction, so that waiting clients
+			// and new events find the next run as soon as the finished run is completed
+			return nextRunState.start(ctx, p)
+		}
+
+		if state == backend.WorkflowStateFinished {
+			return addEventsToStreamInPipeline(ctx, p, pendingEventsKey(instance.InstanceID), ownPendingEvents)
+		}
+
+		// If there are pending events, queue the instance again
+		return queuePendingEvents(ctx, p, instanceQueue, instance.InstanceID, ownPendingEvents)
+	}); err != nil && err != redis.Nil {
+		// Scripts without a result, and activities that are already queued, are reported as redis.Nil
+		return fmt.Errorf("completing workflow task: %w", err)
 	}
 
-	instanceState.State = state
-	instanceState.LastSequenceID = executedEvents[len(executedEvents)-1].SequenceID
+	if err := completeTask(); err != nil {
+		return fmt.Errorf("completing workflow task: %w", err)
+	}
 
-	if err := updateInstance(ctx, rb.rdb, instance.InstanceID, instanceState); err != nil {
-		return fmt.Errorf("updating workflow instance: %w", err)
+	if state == backend.WorkflowStateFinished {
+		// Notify any waiting clients that the instance is done. The task has been completed at this point, so a failed
+		// notification cannot fail it anymore.
+		if err := rb.rdb.Publish(ctx, instanceCompletionChannel(instance.InstanceID), instance.ExecutionID).Err(); err != nil {
+			rb.options.Logger.Warn("Could not publish workflow instance completion", "instance_id", instance.InstanceID, "error", err)
+		}
 	}
 
-	// Store activity data
-	for _, activityEvent := range activityEvents {
-		if _, err := rb.activityQueue.Enqueue(ctx, activityEvent.ID, &activityData{
-			Instance: instance,
-			ID:       activityEvent.ID,
-			Event:    activityEvent,
-		}); err != nil {
+	for _, enqueueActivity := range enqueueActivities {
+		// The activity is already queued if completing the workflow task is retried
+		if err := enqueueActivity(); err != nil && !errors.Is(err, taskqueue.ErrTaskAlreadyInQueue) {
 			return fmt.Errorf("queueing activity task: %w", err)
 		}
 	}
 
-	// Remove executed pending events
-	_, err = removePendingEventsCmd.Run(ctx, rb.rdb, []string{pendingEventsKey(instance.InstanceID)}, task.Data.LastPendingEventMessageID).Result()
+	if nextRun == nil && state == backend.WorkflowStateFinished && rb.options.AutoExpiration > 0 {
+		if err := setInstanceExpiration(ctx, rb.rdb, instance.InstanceID, rb.options.AutoExpiration); err != nil {
+			return fmt.Errorf("setting workflow instance expiration: %w", err)
+		}
+	}
+
+	if rb.options.HistoryExporter != nil && len(executedEvents) > 0 {
+		rb.options.HistoryExporter.ExportHistory(instance, state, executedEvents)
+	}
+
+	return nil
+}
+
+// continuedRun is the next run of an instance that continues as new
+type continuedRun struct {
+	// finished is the state of the run that continues as new
+	finished *instanceState
+
+	// tags are the tags added by the finished run
+	tags []string
+
+	// runs are the runs archived before
+	runs []*instanceState
+
+	event history.WorkflowEvent
+	queue taskqueue.TaskQueue[workflowTaskData]
+}
+
+// readContinuedRun reads the data required to archive the finished run of an instance, that continues as new with the
+// given event
+func (rb *redisBackend) readContinuedRun(ctx context.Context, finished *instanceState, executedEvents []history.Event, event history.WorkflowEvent) (*continuedRun, error) {
+	h, err := rb.GetWorkflowInstanceHistory(ctx, finished.Instance, nil)
 	if err != nil {
-		return fmt.Errorf("removing pending events: %w", err)
+		return nil, fmt.Errorf("reading history: %w", err)
 	}
-	// log.Printf("Removed %v pending events", removed)
 
-	// Complete workflow task and unlock instance
-	if err := rb.workflowQueue.Complete(ctx, taskID); err != nil {
-		return fmt.Errorf("completing workflow task: %w", err)
+	runs, err := readRuns(ctx, rb.rdb, finished.Instance.InstanceID)
+	if err != nil {
+		return nil, err
 	}
 
-	// If there are pending events, queue the instance again
-	msgIDs, err := rb.rdb.XRevRangeN(ctx, pendingEventsKey(instance.InstanceID), "+", "-", 1).Result()
+	queue, err := rb.workflowQueueFor(history.WorkflowQueue([]history.Event{event.HistoryEvent}))
 	if err != nil {
-		return fmt.Errorf("reading event stream: %w", err)
+		return nil, err
 	}
 
-	if state != backend.WorkflowStateFinished && len(msgIDs) > 0 {
-		if _, err := rb.workflowQueue.Enqueue(ctx, instance.InstanceID, &workflowTaskData{
-			LastPendingEventMessageID: msgIDs[0].ID,
-		}); err != nil {
-			if err != taskqueue.ErrTaskAlreadyInQueue {
-				return fmt.Errorf("queueing workflow: %w", err)
+	return &continuedRun{
+		finished: finished,
+		tags:     history.AddedTags(append(h, executedEvents...)),
+		runs:     runs,
+		event:    event,
+		queue:    queue,
+	}, nil
+}
+
+// start archives the finished run and starts the next run as part of the given pipeline. It has to be added after
+// the executed events of the finished run have been removed from its pending events.
+func (r *continuedRun) start(ctx context.Context, p redis.Pipeliner) error {
+	instance := r.event.WorkflowInstance
+
+	if err := archiveRunInPipeline(ctx, p, r.finished, r.tags, r.runs, true); err != nil {
+		return err
+	}
+
+	p.Del(ctx, instanceKey(instance.InstanceID))
+
+	if err := createInstanceInPipeline(ctx, p, instance, history.WorkflowQueue([]history.Event{r.event.HistoryEvent})); err != nil {
+		return err
+	}
+
+	addInstanceTagsInPipeline(ctx, p, instance.InstanceID, history.AddedTags([]history.Event{r.event.HistoryEvent}))
+
+	eventData, err := json.Marshal(r.event.HistoryEvent)
+	if err != nil {
+		return err
+	}
+
+	continuePendingEventsCmd.Eval(ctx, p, []string{pendingEventsKey(instance.InstanceID)}, string(eventData), int(history.EventType_SignalReceived))
+
+	return queuePendingEvents(ctx, p, r.queue, instance.InstanceID, nil)
+}
+
+// targetWorkflowQueues returns the workflow task queues of the instances that new workflow events are sent to. The
+// queue of instances that don't exist yet is the queue their events request.
+func (rb *redisBackend) targetWorkflowQueues(ctx context.Context, instanceID string, targetIDs []string, groupedEvents map[string][]history.Event) (map[string]taskqueue.TaskQueue[workflowTaskData], error) {
+	cmds := make(map[string]*redis.StringCmd, len(targetIDs))
+	if _, err := rb.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, targetID := range targetIDs {
+			if targetID != instanceID {
+				cmds[targetID] = p.Get(ctx, instanceKey(targetID))
+			}
+		}
+
+		return nil
+	}); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("reading target workflow instances: %w", err)
+	}
+
+	queues := make(map[string]taskqueue.TaskQueue[workflowTaskData], len(cmds))
+	for targetID, cmd := range cmds {
+		queueName := history.WorkflowQueue(groupedEvents[targetID])
+
+		if val, err := cmd.Result(); err == nil {
+			var state instanceState
+			if err := json.Unmarshal([]byte(val), &state); err != nil {
+				return nil, fmt.Errorf("unmarshaling instance state: %w", err)
 			}
+
+			queueName = state.Queue
+		}
+
+		q, err := rb.workflowQueueFor(queueName)
+		if err != nil {
+			return nil, err
 		}
+
+		queues[targetID] = q
 	}
 
-	return nil
+	return queues, nil
 }
 
 func (rb *redisBackend) addWorkflowInstanceEvent(ctx context.Context, instance *core.WorkflowInstance, event *history.Event) error {
@@ -274,13 +610,71 @@ func (rb *redisBackend) addWorkflowInstanceEvent(ctx context.Context, instance *
 	}
 
 	// Queue workflow task
-	if _, err := rb.workflowQueue.Enqueue(ctx, instance.InstanceID, &workflowTaskData{
-		LastPendingEventMessageID: *msgID,
-	}); err != nil {
-		if err != taskqueue.ErrTaskAlreadyInQueue {
-			return fmt.Errorf("queueing workflow: %w", err)
+	if err := rb.queueWorkflowTask(ctx, instance.InstanceID, *msgID); err != nil {
+		return fmt.Errorf("queueing workflow: %w", err)
+	}
+
+	return nil
+}
+
+// queueWorkflowTask queues a task for the given instance. If there already is a task queued for the instance, it
+// will pick up the new pending events when it's executed, so that is not an error.
+func (rb *redisBackend) queueWorkflowTask(ctx context.Context, instanceID, lastPendingEventMessageID string) error {
+	state, err := readInstance(ctx, rb.rdb, instanceID)
+	if err != nil {
+		if !errors.Is(err, backend.ErrInstanceNotFound) {
+			return err
 		}
+
+		// Without an instance there is no queue to look up, use the default queue
+		state = &instanceState{Instance: core.NewWorkflowInstance(instanceID, "")}
+	}
+
+	return rb.queueInstanceTask(ctx, state, lastPendingEventMessageID)
+}
+
+// queueInstanceTask works like queueWorkflowTask for an instance that has already been read
+func (rb *redisBackend) queueInstanceTask(ctx context.Context, instanceState *instanceState, lastPendingEventMessageID string) error {
+	workflowQueue, err := rb.workflowQueueFor(instanceState.Queue)
+	if err != nil {
+		return err
+	}
+
+	if _, err := workflowQueue.Enqueue(ctx, instanceState.Instance.InstanceID, &workflowTaskData{
+		LastPendingEventMessageID: lastPendingEventMessageID,
+	}); err != nil && !errors.Is(err, taskqueue.ErrTaskAlreadyInQueue) {
+		return err
 	}
 
 	return nil
 }
+
+// workflowQueueFor returns the task queue for the given workflow queue name
+func (rb *redisBackend) workflowQueueFor(queueName string) (taskqueue.TaskQueue[workflowTaskData], error) {
+	if queueName == backend.DefaultWorkflowQueue {
+		return rb.workflowQueue, nil
+	}
+
+	rb.workflowQueuesMu.Lock()
+	defer rb.workflowQueuesMu.Unlock()
+
+	if q, ok := rb.workflowQueues[queueName]; ok {
+		return q, nil
+	}
+
+	q, err := taskqueue.New[workflowTaskData](rb.rdb, "workflows:"+queueName, rb.options.queueOptions(rb.options.WorkflowQueueOptions)...)
+	if err != nil {
+		return nil, fmt.Errorf("creating workflow task queue: %w", err)
+	}
+
+	rb.workflowQueues[queueName] = q
+
+	return q, nil
+}
+
+func (rb *redisBackend) workflowQueueForTask(taskID string) (taskqueue.TaskQueue[workflowTaskData], string, error) {
+	queueName, taskID := splitQueueTaskID(taskID)
+
+	q, err := rb.workflowQueueFor(queueName)
+	return q, taskID, err
+}
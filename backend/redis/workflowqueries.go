@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/go-redis/redis/v8"
+)
+
+var _ backend.WorkflowQuerier = (*redisBackend)(nil)
+
+// workflowQueryState is stored for every query until its result has been read or its deadline has passed
+type workflowQueryState struct {
+	Query  *backend.WorkflowQuery       `json:"query"`
+	Result *backend.WorkflowQueryResult `json:"result,omitempty"`
+}
+
+func (rb *redisBackend) AddWorkflowQuery(ctx context.Context, query *backend.WorkflowQuery) error {
+	data, err := json.Marshal(&workflowQueryState{Query: query})
+	if err != nil {
+		return fmt.Errorf("marshaling query: %w", err)
+	}
+
+	ttl := time.Until(query.Deadline)
+	if ttl <= 0 {
+		ttl = time.Millisecond
+	}
+
+	if _, err := rb.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, workflowQueryKey(query.ID), data, ttl)
+		p.RPush(ctx, workflowQueriesKey(), query.ID)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("adding query: %w", err)
+	}
+
+	return nil
+}
+
+// GetWorkflowQuery hands each query to a single worker. If the worker stops before answering it, the client waiting
+// for the result runs into its deadline.
+func (rb *redisBackend) GetWorkflowQuery(ctx context.Context) (*backend.WorkflowQuery, error) {
+	for {
+		id, err := rb.rdb.LPop(ctx, workflowQueriesKey()).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return nil, nil
+			}
+
+			return nil, fmt.Errorf("dequeuing query: %w", err)
+		}
+
+		state, err := rb.getWorkflowQueryState(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if state == nil {
+			// The query has expired, try the next one
+			continue
+		}
+
+		return state.Query, nil
+	}
+}
+
+func (rb *redisBackend) CompleteWorkflowQuery(ctx context.Context, queryID string, result *backend.WorkflowQueryResult) error {
+	state, err := rb.getWorkflowQueryState(ctx, queryID)
+	if err != nil || state == nil {
+		return err
+	}
+
+	state.Result = result
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshaling query result: %w", err)
+	}
+
+	// Keep the expiration of the query, nobody waits for the result after the deadline
+	if err := rb.rdb.Set(ctx, workflowQueryKey(queryID), data, redis.KeepTTL).Err(); err != nil {
+		return fmt.Errorf("storing query result: %w", err)
+	}
+
+	return nil
+}
+
+func (rb *redisBackend) GetWorkflowQueryResult(ctx context.Context, queryID string) (*backend.WorkflowQueryResult, error) {
+	state, err := rb.getWorkflowQueryState(ctx, queryID)
+	if err != nil || state == nil || state.Result == nil {
+		return nil, err
+	}
+
+	if err := rb.rdb.Del(ctx, workflowQueryKey(queryID)).Err(); err != nil {
+		return nil, fmt.Errorf("deleting query: %w", err)
+	}
+
+	return state.Result, nil
+}
+
+func (rb *redisBackend) getWorkflowQueryState(ctx context.Context, queryID string) (*workflowQueryState, error) {
+	data, err := rb.rdb.Get(ctx, workflowQueryKey(queryID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("reading query: %w", err)
+	}
+
+	var state workflowQueryState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("unmarshaling query: %w", err)
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,30 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+)
+
+type readReplicaKey struct{}
+
+// PreferReadReplica marks the context to serve read-only operations from the read replica configured with
+// WithReadReplica. Use it for reads that tolerate replication lag, for example inspecting histories or listing
+// instances for diagnostics. Reads are only served from the replica when asked for, since workers and clients
+// waiting for results have to see their own writes.
+func PreferReadReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readReplicaKey{}, true)
+}
+
+// ReadDB returns the database to serve a read-only operation from: the read replica if one is configured and the
+// context is marked with PreferReadReplica, the given primary otherwise
+func (o *Options) ReadDB(ctx context.Context, primary *sql.DB) *sql.DB {
+	if o.ReadReplica == nil {
+		return primary
+	}
+
+	if prefer, _ := ctx.Value(readReplicaKey{}).(bool); !prefer {
+		return primary
+	}
+
+	return o.ReadReplica
+}
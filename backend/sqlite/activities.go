@@ -13,10 +13,17 @@ func scheduleActivity(ctx context.Context, tx *sql.Tx, instanceID, executionID s
 		return err
 	}
 
+	var queue, name string
+	if a, ok := event.Attributes.(*history.ActivityScheduledAttributes); ok {
+		queue = a.Queue
+		name = a.Name
+	}
+
 	_, err = tx.ExecContext(
 		ctx,
 		`INSERT INTO activities
-			(id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			(id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at, priority, queue, name)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE((SELECT priority FROM instances WHERE id = ?), 0), COALESCE((SELECT target FROM activity_queue_redirects WHERE queue = ?), ?), ?)`,
 		event.ID,
 		instanceID,
 		executionID,
@@ -25,6 +32,10 @@ func scheduleActivity(ctx context.Context, tx *sql.Tx, instanceID, executionID s
 		event.ScheduleEventID,
 		attributes,
 		event.VisibleAt,
+		instanceID,
+		queue,
+		queue,
+		name,
 	)
 
 	return err
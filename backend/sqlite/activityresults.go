@@ -0,0 +1,42 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+var _ backend.ActivityResultCache = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) GetActivityResult(ctx context.Context, key string) (payload.Payload, bool, error) {
+	row := sb.db.QueryRowContext(ctx, "SELECT result FROM `activity_results` WHERE `key` = ? AND expires_at > ?", key, time.Now())
+
+	var result []byte
+	if err := row.Scan(&result); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("reading activity result: %w", err)
+	}
+
+	return result, true, nil
+}
+
+func (sb *sqliteBackend) StoreActivityResult(ctx context.Context, key string, result payload.Payload, ttl time.Duration) error {
+	if _, err := sb.db.ExecContext(
+		ctx,
+		"INSERT OR REPLACE INTO `activity_results` (`key`, result, expires_at) VALUES (?, ?, ?)",
+		key,
+		[]byte(result),
+		time.Now().Add(ttl),
+	); err != nil {
+		return fmt.Errorf("storing activity result: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	// busyTimeout is how long sqlite waits for a lock held by another connection before returning SQLITE_BUSY
+	busyTimeout = 5 * time.Second
+
+	// maxBusyRetries is the number of times starting a transaction is retried after busyTimeout elapsed
+	maxBusyRetries = 5
+)
+
+// beginTx starts a new transaction. On-disk databases start all transactions with BEGIN IMMEDIATE, so that
+// only a single connection, across all processes sharing the database, can write at a time. If another
+// connection holds the write lock for longer than the busy timeout, beginning the transaction is retried. Once
+// the retries are exhausted, the error is returned as a backend.TransientError.
+func (sb *sqliteBackend) beginTx(ctx context.Context) (*sql.Tx, error) {
+	backoff := 10 * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		tx, err := sb.db.BeginTx(ctx, nil)
+		if err == nil || !isBusy(err) {
+			return tx, err
+		}
+
+		if attempt >= maxBusyRetries {
+			return nil, &backend.TransientError{Err: err}
+		}
+
+		sb.options.Logger.Debug("database is busy, retrying", "attempt", attempt+1)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// beginReadTx starts a transaction for a read-only operation. It's started on the read replica if the context
+// prefers it, see backend.PreferReadReplica.
+func (sb *sqliteBackend) beginReadTx(ctx context.Context) (*sql.Tx, error) {
+	if db := sb.options.ReadDB(ctx, sb.db); db != sb.db {
+		return db.BeginTx(ctx, nil)
+	}
+
+	return sb.beginTx(ctx)
+}
+
+// isBusy returns whether err indicates that the database is locked by another connection
+func isBusy(err error) bool {
+	var serr sqlite3.Error
+	if errors.As(err, &serr) {
+		return serr.Code == sqlite3.ErrBusy || serr.Code == sqlite3.ErrLocked
+	}
+
+	return false
+}
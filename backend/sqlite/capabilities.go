@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/notify"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+var _ backend.CapabilityTaskProvider = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) GetWorkflowTaskForCapabilities(ctx context.Context, capabilities backend.WorkerCapabilities, queues []string, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error) {
+	if len(queues) == 0 {
+		queues = []string{backend.DefaultWorkflowQueue}
+	}
+
+	return notify.Poll(ctx, sb.workflowNotifier, sb.pollOptions(), func(ctx context.Context) (*task.Workflow, error) {
+		return sb.getWorkflowTask(ctx, capabilities, queues, knownSequenceID)
+	})
+}
+
+func (sb *sqliteBackend) GetActivityTaskForCapabilities(ctx context.Context, capabilities backend.WorkerCapabilities, queues []string) (*task.Activity, error) {
+	if len(queues) == 0 {
+		queues = []string{backend.DefaultActivityQueue}
+	}
+
+	return notify.Poll(ctx, sb.activityNotifier, sb.pollOptions(), func(ctx context.Context) (*task.Activity, error) {
+		return sb.getActivityTask(ctx, capabilities, queues)
+	})
+}
+
+// nameFilter returns a condition restricting the given column to one of names. Without names, the condition
+// is empty.
+func nameFilter(column string, names []string) (string, []interface{}) {
+	if len(names) == 0 {
+		return "", nil
+	}
+
+	args := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		args = append(args, name)
+	}
+
+	return "AND " + column + " IN (?" + strings.Repeat(",?", len(names)-1) + ")", args
+}
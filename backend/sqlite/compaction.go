@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+var _ backend.HistoryCompactor = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) CompactWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance) (int, error) {
+	tx, err := sb.beginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	h, err := getHistory(ctx, tx, instance.InstanceID, nil)
+	if err != nil {
+		return 0, fmt.Errorf("getting workflow history: %w", err)
+	}
+
+	sort.Slice(h, func(i, j int) bool {
+		return h[i].SequenceID < h[j].SequenceID
+	})
+
+	superseded := history.SupersededEvents(h)
+	for _, event := range superseded {
+		if _, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM `history` WHERE id = ? AND instance_id = ?",
+			event.ID,
+			instance.InstanceID,
+		); err != nil {
+			return 0, fmt.Errorf("removing history event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(superseded), nil
+}
@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// workflowConcurrencyFilter returns a condition skipping tasks of instances of workflows that reached their
+// concurrency limit, see backend.WithWorkflowConcurrencyLimit. nameExpr and instanceIDExpr select the workflow name
+// and the instance ID of the task. An instance is executing while it has a locked workflow or activity task,
+// instances already executing are never skipped.
+func workflowConcurrencyFilter(limits map[string]int, nameExpr, instanceIDExpr string, now time.Time) (string, []interface{}) {
+	names := make([]string, 0, len(limits))
+	for name, limit := range limits {
+		if limit > 0 {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	var filter strings.Builder
+	args := make([]interface{}, 0, len(names)*7)
+
+	for _, name := range names {
+		filter.WriteString(`AND (` + nameExpr + ` != ?
+			OR EXISTS (SELECT 1 FROM instances ci WHERE ci.id = ` + instanceIDExpr + ` AND ci.locked_until >= ?)
+			OR EXISTS (SELECT 1 FROM activities ca WHERE ca.instance_id = ` + instanceIDExpr + ` AND ca.locked_until >= ?)
+			OR (SELECT COUNT(*) FROM instances cl WHERE cl.name = ? AND cl.completed_at IS NULL AND (
+				cl.locked_until >= ?
+				OR EXISTS (SELECT 1 FROM activities cla WHERE cla.instance_id = cl.id AND cla.locked_until >= ?)
+			)) < ?) `)
+
+		args = append(args, name, now, now, name, now, now, limits[name])
+	}
+
+	return filter.String(), args
+}
@@ -14,7 +14,7 @@ var _ diag.Backend = (*sqliteBackend)(nil)
 
 func (sb *sqliteBackend) GetWorkflowInstances(ctx context.Context, afterInstanceID string, count int) ([]*diag.WorkflowInstanceRef, error) {
 	var err error
-	tx, err := sb.db.BeginTx(ctx, nil)
+	tx, err := sb.beginReadTx(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -24,7 +24,7 @@ func (sb *sqliteBackend) GetWorkflowInstances(ctx context.Context, afterInstance
 	if afterInstanceID != "" {
 		rows, err = tx.QueryContext(
 			ctx,
-			`SELECT i.id, i.execution_id, i.created_at, i.completed_at
+			`SELECT i.id, i.execution_id, i.created_at, i.completed_at, i.last_error
 			FROM instances i
 			INNER JOIN (SELECT id, created_at FROM instances WHERE id = ?) ii
 				ON i.created_at < ii.created_at OR (i.created_at = ii.created_at AND i.id < ii.id)
@@ -36,7 +36,7 @@ func (sb *sqliteBackend) GetWorkflowInstances(ctx context.Context, afterInstance
 	} else {
 		rows, err = tx.QueryContext(
 			ctx,
-			`SELECT i.id, i.execution_id, i.created_at, i.completed_at
+			`SELECT i.id, i.execution_id, i.created_at, i.completed_at, i.last_error
 			FROM instances i
 			ORDER BY i.created_at DESC, i.id DESC
 			LIMIT ?`,
@@ -53,7 +53,13 @@ func (sb *sqliteBackend) GetWorkflowInstances(ctx context.Context, afterInstance
 		var id, executionID string
 		var createdAt time.Time
 		var completedAt *time.Time
-		err = rows.Scan(&id, &executionID, &createdAt, &completedAt)
+		var lastError sql.NullString
+		err = rows.Scan(&id, &executionID, &createdAt, &completedAt, &lastError)
+		if err != nil {
+			return nil, err
+		}
+
+		instanceErr, err := unmarshalInstanceError(lastError)
 		if err != nil {
 			return nil, err
 		}
@@ -68,6 +74,7 @@ func (sb *sqliteBackend) GetWorkflowInstances(ctx context.Context, afterInstance
 			CreatedAt:   createdAt,
 			CompletedAt: completedAt,
 			State:       state,
+			LastError:   instanceErr,
 		})
 	}
 
@@ -75,19 +82,20 @@ func (sb *sqliteBackend) GetWorkflowInstances(ctx context.Context, afterInstance
 }
 
 func (sb *sqliteBackend) GetWorkflowInstance(ctx context.Context, instanceID string) (*diag.WorkflowInstanceRef, error) {
-	tx, err := sb.db.BeginTx(ctx, nil)
+	tx, err := sb.beginReadTx(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	res := tx.QueryRowContext(ctx, "SELECT id, execution_id, created_at, completed_at FROM instances WHERE id = ?", instanceID)
+	res := tx.QueryRowContext(ctx, "SELECT id, execution_id, created_at, completed_at, last_error FROM instances WHERE id = ?", instanceID)
 
 	var id, executionID string
 	var createdAt time.Time
 	var completedAt *time.Time
+	var lastError sql.NullString
 
-	err = res.Scan(&id, &executionID, &createdAt, &completedAt)
+	err = res.Scan(&id, &executionID, &createdAt, &completedAt, &lastError)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -96,6 +104,11 @@ func (sb *sqliteBackend) GetWorkflowInstance(ctx context.Context, instanceID str
 		return nil, err
 	}
 
+	instanceErr, err := unmarshalInstanceError(lastError)
+	if err != nil {
+		return nil, err
+	}
+
 	var state backend.WorkflowState
 	if completedAt != nil {
 		state = backend.WorkflowStateFinished
@@ -106,5 +119,6 @@ func (sb *sqliteBackend) GetWorkflowInstance(ctx context.Context, instanceID str
 		CreatedAt:   createdAt,
 		CompletedAt: completedAt,
 		State:       state,
+		LastError:   instanceErr,
 	}, nil
 }
@@ -33,6 +33,28 @@ func getPendingEvents(ctx context.Context, tx *sql.Tx, instanceID string) ([]his
 	return pendingEvents, nil
 }
 
+// getPendingSignals returns the signals pending for the given instance, including signals that are not visible yet
+func getPendingSignals(ctx context.Context, tx *sql.Tx, instanceID string) ([]history.Event, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT * FROM `pending_events` WHERE instance_id = ? AND event_type = ?", instanceID, history.EventType_SignalReceived)
+	if err != nil {
+		return nil, fmt.Errorf("getting pending signals: %w", err)
+	}
+	defer rows.Close()
+
+	signals := make([]history.Event, 0)
+
+	for rows.Next() {
+		signal, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("reading event: %w", err)
+		}
+
+		signals = append(signals, signal)
+	}
+
+	return signals, rows.Err()
+}
+
 func getHistory(ctx context.Context, tx *sql.Tx, instanceID string, lastSequenceID *int64) ([]history.Event, error) {
 	var historyEvents *sql.Rows
 	var err error
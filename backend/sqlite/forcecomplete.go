@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+var _ backend.InstanceForceCompleter = (*sqliteBackend)(nil)
+var _ backend.InstanceTerminator = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) ForceCompleteWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, event history.Event, workflowEvents []history.WorkflowEvent) error {
+	tx, err := sb.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := forceCompleteInstance(ctx, tx, instance, &event, workflowEvents); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sb.workflowNotifier.Notify()
+	sb.completionNotifier.Notify()
+
+	if sb.options.HistoryExporter != nil {
+		sb.options.HistoryExporter.ExportHistory(instance, backend.WorkflowStateFinished, []history.Event{event})
+	}
+
+	return nil
+}
+
+// forceCompleteInstance appends event to the history of the given instance and marks it as finished in the given
+// transaction. The sequence ID of event is set to the next one of the instance.
+func forceCompleteInstance(ctx context.Context, tx *sql.Tx, instance *core.WorkflowInstance, event *history.Event, workflowEvents []history.WorkflowEvent) error {
+	row := tx.QueryRowContext(ctx, "SELECT completed_at FROM `instances` WHERE id = ? AND execution_id = ?", instance.InstanceID, instance.ExecutionID)
+
+	var completedAt sql.NullTime
+	if err := row.Scan(&completedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return backend.ErrInstanceNotFound
+		}
+
+		return fmt.Errorf("reading instance: %w", err)
+	}
+
+	if completedAt.Valid {
+		return backend.ErrInstanceFinished
+	}
+
+	row = tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(sequence_id), 0) FROM `history` WHERE instance_id = ?", instance.InstanceID)
+	if err := row.Scan(&event.SequenceID); err != nil {
+		return fmt.Errorf("reading last sequence id: %w", err)
+	}
+
+	event.SequenceID++
+
+	if err := insertHistoryEvents(ctx, tx, instance.InstanceID, []history.Event{*event}); err != nil {
+		return fmt.Errorf("inserting history event: %w", err)
+	}
+
+	// Release any lock, so that a worker still executing the instance cannot complete its task
+	if _, err := tx.ExecContext(
+		ctx,
+		"UPDATE `instances` SET completed_at = ?, locked_until = NULL, sticky_until = NULL, worker = NULL WHERE id = ? AND execution_id = ?",
+		time.Now(),
+		instance.InstanceID,
+		instance.ExecutionID,
+	); err != nil {
+		return fmt.Errorf("completing instance: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM `pending_events` WHERE instance_id = ?", instance.InstanceID); err != nil {
+		return fmt.Errorf("removing pending events: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM `activities` WHERE instance_id = ?", instance.InstanceID); err != nil {
+		return fmt.Errorf("removing pending activities: %w", err)
+	}
+
+	for _, e := range workflowEvents {
+		if err := insertNewEvents(ctx, tx, e.WorkflowInstance.InstanceID, []history.Event{e.HistoryEvent}); err != nil {
+			return fmt.Errorf("inserting workflow events: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// TerminateWorkflowInstance finishes the given instance like force-completing it, only the recorded event differs
+func (sb *sqliteBackend) TerminateWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance, event history.Event, workflowEvents []history.WorkflowEvent) error {
+	return sb.ForceCompleteWorkflowInstance(ctx, instance, event, workflowEvents)
+}
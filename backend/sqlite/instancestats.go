@@ -0,0 +1,141 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/task"
+)
+
+var _ backend.InstanceStatsProvider = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) GetWorkflowInstanceStats(ctx context.Context, instance *core.WorkflowInstance) (*backend.InstanceStats, error) {
+	tx, err := sb.beginReadTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, "SELECT last_error FROM `instances` WHERE id = ? AND execution_id = ?", instance.InstanceID, instance.ExecutionID)
+
+	var lastError sql.NullString
+	if err := row.Scan(&lastError); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, fmt.Errorf("reading instance: %w", err)
+	}
+
+	stats := &backend.InstanceStats{}
+
+	instanceErr, err := unmarshalInstanceError(lastError)
+	if err != nil {
+		return nil, err
+	}
+
+	if instanceErr != nil {
+		stats.FailedAttempts = instanceErr.Attempt
+	}
+
+	row = tx.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*), COALESCE(SUM(LENGTH(attributes)), 0) FROM `history` WHERE instance_id = ?",
+		instance.InstanceID,
+	)
+	if err := row.Scan(&stats.HistoryEvents, &stats.HistorySize); err != nil {
+		return nil, fmt.Errorf("counting history events: %w", err)
+	}
+
+	row = tx.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*), COALESCE(SUM(CASE WHEN event_type = ? AND (visible_at IS NULL OR visible_at <= ?) THEN 1 ELSE 0 END), 0)
+			FROM pending_events WHERE instance_id = ?`,
+		history.EventType_SignalReceived,
+		time.Now(),
+		instance.InstanceID,
+	)
+	if err := row.Scan(&stats.PendingEvents, &stats.BufferedSignals); err != nil {
+		return nil, fmt.Errorf("counting pending events: %w", err)
+	}
+
+	row = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM `activities` WHERE instance_id = ?", instance.InstanceID)
+	if err := row.Scan(&stats.PendingActivities); err != nil {
+		return nil, fmt.Errorf("counting pending activities: %w", err)
+	}
+
+	now := time.Now()
+
+	stats.WorkflowTaskScheduledAt, err = oldestScheduledAt(
+		ctx, tx,
+		"SELECT timestamp, visible_at FROM `pending_events` WHERE instance_id = ? AND (visible_at IS NULL OR visible_at <= ?)",
+		instance.InstanceID, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading pending events: %w", err)
+	}
+
+	stats.ActivityTaskScheduledAt, err = oldestScheduledAt(
+		ctx, tx,
+		"SELECT timestamp, visible_at FROM `activities` WHERE instance_id = ? AND (locked_until IS NULL OR locked_until < ?)",
+		instance.InstanceID, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reading pending activities: %w", err)
+	}
+
+	// Timers are pending until they have either fired or been canceled
+	row = tx.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM history h
+			WHERE h.instance_id = ? AND h.event_type = ? AND NOT EXISTS (
+				SELECT 1 FROM history
+					WHERE instance_id = h.instance_id AND schedule_event_id = h.schedule_event_id AND event_type IN (?, ?)
+			)`,
+		instance.InstanceID,
+		history.EventType_TimerScheduled,
+		history.EventType_TimerFired,
+		history.EventType_TimerCanceled,
+	)
+	if err := row.Scan(&stats.PendingTimers); err != nil {
+		return nil, fmt.Errorf("counting pending timers: %w", err)
+	}
+
+	return stats, nil
+}
+
+// oldestScheduledAt returns the time the oldest of the rows returned by query became visible. The query has to
+// select the timestamp and visible_at columns. Returns nil if there are no rows.
+func oldestScheduledAt(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (*time.Time, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []history.Event
+	for rows.Next() {
+		var event history.Event
+		if err := rows.Scan(&event.Timestamp, &event.VisibleAt); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	scheduledAt := task.ScheduledAt(events...)
+	return &scheduledAt, nil
+}
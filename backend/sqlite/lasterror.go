@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+var _ backend.InstanceErrorRecorder = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) RecordInstanceError(ctx context.Context, instance *core.WorkflowInstance, instanceErr *backend.InstanceError) error {
+	tx, err := sb.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, "SELECT last_error FROM `instances` WHERE id = ? AND execution_id = ?", instance.InstanceID, instance.ExecutionID)
+
+	var lastError sql.NullString
+	if err := row.Scan(&lastError); err != nil {
+		if err == sql.ErrNoRows {
+			return backend.ErrInstanceNotFound
+		}
+
+		return fmt.Errorf("reading last error: %w", err)
+	}
+
+	prev, err := unmarshalInstanceError(lastError)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(instanceErr.NextAttempt(prev))
+	if err != nil {
+		return fmt.Errorf("marshaling instance error: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		"UPDATE `instances` SET last_error = ? WHERE id = ? AND execution_id = ?",
+		string(data),
+		instance.InstanceID,
+		instance.ExecutionID,
+	); err != nil {
+		return fmt.Errorf("storing last error: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func unmarshalInstanceError(s sql.NullString) (*backend.InstanceError, error) {
+	if !s.Valid || s.String == "" {
+		return nil, nil
+	}
+
+	var instanceErr backend.InstanceError
+	if err := json.Unmarshal([]byte(s.String), &instanceErr); err != nil {
+		return nil, fmt.Errorf("unmarshaling instance error: %w", err)
+	}
+
+	return &instanceErr, nil
+}
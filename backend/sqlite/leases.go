@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+)
+
+var _ backend.LeaseProvider = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	// Take over the lease if it's held by the same holder or has expired
+	res, err := sb.db.ExecContext(
+		ctx,
+		`INSERT INTO leases (name, holder, expires_at) VALUES (?, ?, ?)
+			ON CONFLICT (name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+			WHERE leases.holder = excluded.holder OR leases.expires_at < ?`,
+		name,
+		holder,
+		now.Add(ttl),
+		now,
+	)
+	if err != nil {
+		return false, fmt.Errorf("acquiring lease: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (sb *sqliteBackend) ReleaseLease(ctx context.Context, name, holder string) error {
+	if _, err := sb.db.ExecContext(ctx, "DELETE FROM leases WHERE name = ? AND holder = ?", name, holder); err != nil {
+		return fmt.Errorf("releasing lease: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+var _ backend.PayloadRewriter = (*sqliteBackend)(nil)
+
+// rewriteBatchSize is the number of rows rewritten in a single transaction
+const rewriteBatchSize = 100
+
+// eventTables are the tables storing events with payloads in their attributes
+var eventTables = []string{"pending_events", "history", "activities", "run_history"}
+
+func (sb *sqliteBackend) RewritePayloads(ctx context.Context, rewrite func(payload.Payload) (payload.Payload, error)) (int, error) {
+	total := 0
+
+	for _, table := range eventTables {
+		var after int64
+		for {
+			n, last, err := sb.rewriteEventPayloads(ctx, table, after, rewrite)
+			total += n
+			if err != nil {
+				return total, fmt.Errorf("rewriting payloads in %v: %w", table, err)
+			}
+
+			if last == after {
+				break
+			}
+
+			after = last
+		}
+	}
+
+	for _, column := range payloadColumns {
+		var after int64
+		for {
+			n, last, err := sb.rewriteColumnPayloads(ctx, column, after, rewrite)
+			total += n
+			if err != nil {
+				return total, fmt.Errorf("rewriting payloads in %v.%v: %w", column.table, column.column, err)
+			}
+
+			if last == after {
+				break
+			}
+
+			after = last
+		}
+	}
+
+	return total, nil
+}
+
+// rewriteEventPayloads rewrites the payloads of the next batch of events after the given rowid. It returns the
+// number of rewritten payloads and the rowid of the last event of the batch.
+func (sb *sqliteBackend) rewriteEventPayloads(
+	ctx context.Context, table string, after int64, rewrite func(payload.Payload) (payload.Payload, error),
+) (int, int64, error) {
+	tx, err := sb.beginTx(ctx)
+	if err != nil {
+		return 0, after, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT rowid, event_type, attributes FROM `"+table+"` WHERE rowid > ? ORDER BY rowid LIMIT ?",
+		after,
+		rewriteBatchSize,
+	)
+	if err != nil {
+		return 0, after, err
+	}
+
+	type update struct {
+		rowid      int64
+		attributes []byte
+	}
+
+	var updates []update
+	rewritten := 0
+	last := after
+
+	for rows.Next() {
+		var eventType history.EventType
+		var data []byte
+		if err := rows.Scan(&last, &eventType, &data); err != nil {
+			rows.Close()
+			return 0, after, err
+		}
+
+		attributes, err := history.DeserializeAttributes(eventType, data)
+		if err != nil {
+			rows.Close()
+			return 0, after, fmt.Errorf("deserializing attributes: %w", err)
+		}
+
+		n, err := history.RewritePayloads(attributes, rewrite)
+		if err != nil {
+			rows.Close()
+			return 0, after, err
+		}
+
+		if n == 0 {
+			continue
+		}
+
+		data, err = history.SerializeAttributes(attributes)
+		if err != nil {
+			rows.Close()
+			return 0, after, fmt.Errorf("serializing attributes: %w", err)
+		}
+
+		updates = append(updates, update{last, data})
+		rewritten += n
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, after, err
+	}
+
+	for _, u := range updates {
+		if _, err := tx.ExecContext(ctx, "UPDATE `"+table+"` SET attributes = ? WHERE rowid = ?", u.attributes, u.rowid); err != nil {
+			return 0, after, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, after, err
+	}
+
+	return rewritten, last, nil
+}
+
+// payloadColumn is a column storing payloads outside of event attributes. rewrite rewrites the payloads in a value of
+// the column, and returns the new value and the number of rewritten payloads.
+type payloadColumn struct {
+	table   string
+	column  string
+	rewrite func(data []byte, rewrite func(payload.Payload) (payload.Payload, error)) ([]byte, int, error)
+}
+
+var payloadColumns = []payloadColumn{
+	{table: "activity_results", column: "result", rewrite: rewritePayload},
+	{table: "activities", column: "heartbeat_details", rewrite: rewritePayload},
+	{table: "workflow_state", column: "value", rewrite: rewritePayload},
+	{table: "workflow_queries", column: "inputs", rewrite: rewriteQueryInputs},
+	{table: "workflow_queries", column: "result", rewrite: rewriteQueryResult},
+}
+
+// rewriteColumnPayloads rewrites the payloads of the next batch of rows after the given rowid. It returns the number
+// of rewritten payloads and the rowid of the last row of the batch.
+func (sb *sqliteBackend) rewriteColumnPayloads(
+	ctx context.Context, c payloadColumn, after int64, rewrite func(payload.Payload) (payload.Payload, error),
+) (int, int64, error) {
+	tx, err := sb.beginTx(ctx)
+	if err != nil {
+		return 0, after, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT rowid, `"+c.column+"` FROM `"+c.table+"` WHERE rowid > ? ORDER BY rowid LIMIT ?",
+		after,
+		rewriteBatchSize,
+	)
+	if err != nil {
+		return 0, after, err
+	}
+
+	updates := map[int64][]byte{}
+	rewritten := 0
+	last := after
+
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&last, &data); err != nil {
+			rows.Close()
+			return 0, after, err
+		}
+
+		if len(data) == 0 {
+			continue
+		}
+
+		r, n, err := c.rewrite(data, rewrite)
+		if err != nil {
+			rows.Close()
+			return 0, after, err
+		}
+
+		if n > 0 {
+			updates[last] = r
+			rewritten += n
+		}
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, after, err
+	}
+
+	for rowid, data := range updates {
+		if _, err := tx.ExecContext(ctx, "UPDATE `"+c.table+"` SET `"+c.column+"` = ? WHERE rowid = ?", data, rowid); err != nil {
+			return 0, after, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, after, err
+	}
+
+	return rewritten, last, nil
+}
+
+// rewritePayload rewrites a column storing a single payload
+func rewritePayload(data []byte, rewrite func(payload.Payload) (payload.Payload, error)) ([]byte, int, error) {
+	r, err := rewrite(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if bytes.Equal(r, data) {
+		return data, 0, nil
+	}
+
+	return r, 1, nil
+}
+
+// rewriteQueryInputs rewrites the inputs of a workflow query
+func rewriteQueryInputs(data []byte, rewrite func(payload.Payload) (payload.Payload, error)) ([]byte, int, error) {
+	var inputs []payload.Payload
+	if err := json.Unmarshal(data, &inputs); err != nil {
+		return nil, 0, fmt.Errorf("unmarshaling query inputs: %w", err)
+	}
+
+	changed := 0
+	for i, input := range inputs {
+		r, n, err := rewritePayload(input, rewrite)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		inputs[i] = r
+		changed += n
+	}
+
+	if changed == 0 {
+		return data, 0, nil
+	}
+
+	r, err := json.Marshal(inputs)
+	return r, changed, err
+}
+
+// rewriteQueryResult rewrites the result of a workflow query
+func rewriteQueryResult(data []byte, rewrite func(payload.Payload) (payload.Payload, error)) ([]byte, int, error) {
+	var result backend.WorkflowQueryResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, 0, fmt.Errorf("unmarshaling query result: %w", err)
+	}
+
+	if len(result.Result) == 0 {
+		return data, 0, nil
+	}
+
+	r, n, err := rewritePayload(result.Result, rewrite)
+	if err != nil || n == 0 {
+		return data, 0, err
+	}
+
+	result.Result = r
+
+	data, err = json.Marshal(&result)
+	return data, n, err
+}
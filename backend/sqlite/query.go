@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+var _ backend.InstanceQuerier = (*sqliteBackend)(nil)
+var _ backend.InstanceQuerier = (*visibilityStore)(nil)
+
+// timestampFormat is the format of timestamps set by CURRENT_TIMESTAMP. Times compared to them need to be passed
+// in the same format, since sqlite compares them as strings.
+const timestampFormat = "2006-01-02 15:04:05"
+
+func (sb *sqliteBackend) QueryWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*backend.InstanceQueryResult, error) {
+	cursor, err := backend.DecodePageToken(query.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &backend.InstanceQueryResult{
+		Instances: make([]*backend.WorkflowInstanceSummary, 0),
+	}
+
+	where, args, ok := instanceFilterClause(backend.InstanceFilter{States: query.States})
+	if !ok {
+		return result, nil
+	}
+
+	if query.InstanceIDPrefix != "" {
+		// LIKE is case-insensitive in sqlite, compare the prefix instead
+		where += " AND substr(i.id, 1, ?) = ?"
+		args = append(args, len(query.InstanceIDPrefix), query.InstanceIDPrefix)
+	}
+
+	if !query.CreatedAfter.IsZero() {
+		where += " AND i.created_at >= ?"
+		args = append(args, query.CreatedAfter.UTC().Format(timestampFormat))
+	}
+
+	if !query.CreatedBefore.IsZero() {
+		where += " AND i.created_at < ?"
+		args = append(args, query.CreatedBefore.UTC().Format(timestampFormat))
+	}
+
+	if cursor != nil {
+		createdAt := cursor.CreatedAt.UTC().Format(timestampFormat)
+		where += " AND (i.created_at < ? OR (i.created_at = ? AND i.id < ?))"
+		args = append(args, createdAt, createdAt, cursor.InstanceID)
+	}
+
+	limit := query.Limit()
+	args = append(args, limit+1)
+
+	rows, err := sb.options.ReadDB(ctx, sb.db).QueryContext(
+		ctx,
+		"SELECT i.id, i.execution_id, i.parent_instance_id, i.parent_schedule_event_id, i.name, i.created_at, i.completed_at "+
+			"FROM `instances` i WHERE "+where+" ORDER BY i.created_at DESC, i.id DESC LIMIT ?",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying instances: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var instanceID, executionID, name string
+		var parentInstanceID sql.NullString
+		var parentEventID sql.NullInt64
+		var createdAt time.Time
+		var completedAt *time.Time
+		if err := rows.Scan(&instanceID, &executionID, &parentInstanceID, &parentEventID, &name, &createdAt, &completedAt); err != nil {
+			return nil, fmt.Errorf("scanning instance: %w", err)
+		}
+
+		if len(result.Instances) == limit {
+			last := result.Instances[limit-1]
+			result.NextPageToken = backend.EncodePageToken(last.CreatedAt, last.Instance.InstanceID)
+			break
+		}
+
+		instance := core.NewWorkflowInstance(instanceID, executionID)
+		if parentInstanceID.Valid {
+			instance = core.NewSubWorkflowInstance(instanceID, executionID, parentInstanceID.String, parentEventID.Int64)
+		}
+
+		state := backend.WorkflowStateActive
+		if completedAt != nil {
+			state = backend.WorkflowStateFinished
+		}
+
+		result.Instances = append(result.Instances, &backend.WorkflowInstanceSummary{
+			Instance:    instance,
+			Name:        name,
+			State:       state,
+			CreatedAt:   createdAt,
+			CompletedAt: completedAt,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying instances: %w", err)
+	}
+
+	return result, nil
+}
+
+func (v *visibilityStore) QueryWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*backend.InstanceQueryResult, error) {
+	return v.b.QueryWorkflowInstances(backend.PreferReadReplica(ctx), query)
+}
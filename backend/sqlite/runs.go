@@ -0,0 +1,319 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+var _ backend.RunProvider = (*sqliteBackend)(nil)
+var _ backend.RunReplacer = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) StartWorkflowInstanceRun(ctx context.Context, m history.WorkflowEvent) error {
+	tx, err := sb.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := archiveRun(ctx, tx, m.WorkflowInstance.InstanceID); err != nil {
+		return err
+	}
+
+	if err := sb.createWorkflowInstance(ctx, tx, m); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("starting workflow instance run: %w", err)
+	}
+
+	sb.workflowNotifier.Notify()
+
+	return nil
+}
+
+func (sb *sqliteBackend) ReplaceWorkflowInstanceRun(
+	ctx context.Context, m history.WorkflowEvent, terminate func(run *core.WorkflowInstance) (history.Event, []history.WorkflowEvent),
+) error {
+	tx, err := sb.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
+		ctx,
+		"SELECT execution_id, parent_instance_id, parent_schedule_event_id, created_at, completed_at FROM `instances` WHERE id = ?",
+		m.WorkflowInstance.InstanceID,
+	)
+
+	current, err := scanRun(row, m.WorkflowInstance.InstanceID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	var terminated *core.WorkflowInstance
+	var terminateEvent history.Event
+
+	// A run with the execution ID of the new run has been started by an earlier attempt, creating the
+	// instance reports it as existing
+	if current != nil && current.State == backend.WorkflowStateActive && current.Instance.ExecutionID != m.WorkflowInstance.ExecutionID {
+		var workflowEvents []history.WorkflowEvent
+		terminated = current.Instance
+		terminateEvent, workflowEvents = terminate(terminated)
+
+		if err := forceCompleteInstance(ctx, tx, terminated, &terminateEvent, workflowEvents); err != nil {
+			return fmt.Errorf("terminating current run: %w", err)
+		}
+	}
+
+	if err := archiveRun(ctx, tx, m.WorkflowInstance.InstanceID); err != nil {
+		return err
+	}
+
+	if err := sb.createWorkflowInstance(ctx, tx, m); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("replacing workflow instance run: %w", err)
+	}
+
+	sb.workflowNotifier.Notify()
+
+	if terminated != nil {
+		sb.completionNotifier.Notify()
+	}
+
+	if terminated != nil && sb.options.HistoryExporter != nil {
+		sb.options.HistoryExporter.ExportHistory(terminated, backend.WorkflowStateFinished, []history.Event{terminateEvent})
+	}
+
+	return nil
+}
+
+// archiveRun moves the current run of the given instance to the runs tables, if it has finished. Active runs are
+// left in place, so creating a new run fails with an InstanceAlreadyExistsError.
+func archiveRun(ctx context.Context, tx *sql.Tx, instanceID string) error {
+	res, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO `instance_runs` (instance_id, execution_id, parent_instance_id, parent_schedule_event_id, name, created_at, completed_at) "+
+			"SELECT id, execution_id, parent_instance_id, parent_schedule_event_id, name, created_at, completed_at FROM `instances` WHERE id = ? AND completed_at IS NOT NULL",
+		instanceID,
+	)
+	if err != nil {
+		return fmt.Errorf("archiving run: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		// No instance or the current run is still active
+		return nil
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO `run_history` (id, sequence_id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at) "+
+			"SELECT h.id, h.sequence_id, h.instance_id, i.execution_id, h.event_type, h.timestamp, h.schedule_event_id, h.attributes, h.visible_at "+
+			"FROM `history` h INNER JOIN `instances` i ON i.id = h.instance_id WHERE h.instance_id = ?",
+		instanceID,
+	); err != nil {
+		return fmt.Errorf("archiving run history: %w", err)
+	}
+
+	for _, q := range []string{
+		"DELETE FROM `history` WHERE instance_id = ?",
+		"DELETE FROM `pending_events` WHERE instance_id = ?",
+		"DELETE FROM `activities` WHERE instance_id = ?",
+		"DELETE FROM `instance_tags` WHERE instance_id = ?",
+		"DELETE FROM `instances` WHERE id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, q, instanceID); err != nil {
+			return fmt.Errorf("removing archived run: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (sb *sqliteBackend) ListWorkflowInstanceRuns(ctx context.Context, instanceID string) ([]*backend.WorkflowRun, error) {
+	tx, err := sb.beginReadTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT execution_id, parent_instance_id, parent_schedule_event_id, created_at, completed_at FROM `instance_runs` WHERE instance_id = ? ORDER BY created_at, rowid",
+		instanceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing runs: %w", err)
+	}
+
+	runs := make([]*backend.WorkflowRun, 0)
+	for rows.Next() {
+		run, err := scanRun(rows, instanceID)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		runs = append(runs, run)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing runs: %w", err)
+	}
+
+	row := tx.QueryRowContext(
+		ctx,
+		"SELECT execution_id, parent_instance_id, parent_schedule_event_id, created_at, completed_at FROM `instances` WHERE id = ?",
+		instanceID,
+	)
+
+	current, err := scanRun(row, instanceID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+	} else {
+		runs = append(runs, current)
+	}
+
+	if len(runs) == 0 {
+		return nil, backend.ErrInstanceNotFound
+	}
+
+	return runs, nil
+}
+
+func scanRun(row Scanner, instanceID string) (*backend.WorkflowRun, error) {
+	var executionID string
+	var parentInstanceID sql.NullString
+	var parentEventID sql.NullInt64
+	var createdAt time.Time
+	var completedAt sql.NullTime
+	if err := row.Scan(&executionID, &parentInstanceID, &parentEventID, &createdAt, &completedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("scanning run: %w", err)
+	}
+
+	run := &backend.WorkflowRun{
+		Instance:  core.NewWorkflowInstance(instanceID, executionID),
+		State:     backend.WorkflowStateActive,
+		CreatedAt: createdAt,
+	}
+
+	if parentInstanceID.Valid {
+		run.Instance = core.NewSubWorkflowInstance(instanceID, executionID, parentInstanceID.String, parentEventID.Int64)
+	}
+
+	if completedAt.Valid {
+		run.State = backend.WorkflowStateFinished
+		run.CompletedAt = &completedAt.Time
+	}
+
+	return run, nil
+}
+
+func (sb *sqliteBackend) GetWorkflowInstanceRunHistory(ctx context.Context, instance *core.WorkflowInstance) ([]history.Event, error) {
+	tx, err := sb.beginReadTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := runExists(ctx, tx, "SELECT 1 FROM `instances` WHERE id = ? AND execution_id = ?", instance)
+	if err != nil {
+		return nil, err
+	}
+
+	if current {
+		return getHistory(ctx, tx, instance.InstanceID, nil)
+	}
+
+	if archived, err := runExists(ctx, tx, "SELECT 1 FROM `instance_runs` WHERE instance_id = ? AND execution_id = ?", instance); err != nil {
+		return nil, err
+	} else if !archived {
+		return nil, backend.ErrInstanceNotFound
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM `run_history` WHERE instance_id = ? AND execution_id = ? ORDER BY sequence_id",
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("getting run history: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]history.Event, 0)
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("reading event: %w", err)
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func runExists(ctx context.Context, tx *sql.Tx, query string, instance *core.WorkflowInstance) (bool, error) {
+	row := tx.QueryRowContext(ctx, query, instance.InstanceID, instance.ExecutionID)
+	if err := row.Scan(new(int)); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("reading run: %w", err)
+	}
+
+	return true, nil
+}
+
+// continueAsNew archives the finished run of an instance that continues as new, and creates its next run with the
+// given events. Signals that have not been delivered to the finished run are delivered to the next run.
+func continueAsNew(ctx context.Context, tx *sql.Tx, next *core.WorkflowInstance, events []history.Event) error {
+	signals, err := getPendingSignals(ctx, tx, next.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	if err := archiveRun(ctx, tx, next.InstanceID); err != nil {
+		return err
+	}
+
+	var priority int
+	for _, e := range events {
+		if a, ok := e.Attributes.(*history.ExecutionStartedAttributes); ok {
+			priority = a.Priority
+		}
+	}
+
+	if err := createInstance(ctx, tx, next, history.WorkflowName(events), history.WorkflowQueue(events), priority, false); err != nil {
+		return fmt.Errorf("creating next run: %w", err)
+	}
+
+	if err := insertNewEvents(ctx, tx, next.InstanceID, append(events, signals...)); err != nil {
+		return fmt.Errorf("inserting new events: %w", err)
+	}
+
+	return insertInstanceTags(ctx, tx, next.InstanceID, history.AddedTags(events))
+}
@@ -3,11 +3,15 @@ CREATE TABLE IF NOT EXISTS `instances` (
   `execution_id` TEXT NO NULL,
   `parent_instance_id` TEXT NULL,
   `parent_schedule_event_id` INTEGER NULL,
+  `priority` INTEGER NOT NULL DEFAULT 0,
+  `last_error` TEXT NULL,
   `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `completed_at` DATETIME NULL,
   `locked_until` DATETIME NULL,
   `sticky_until` DATETIME NULL,
-  `worker` TEXT NULL
+  `worker` TEXT NULL,
+  `name` TEXT NOT NULL DEFAULT '',
+  `queue` TEXT NOT NULL DEFAULT ''
 );
 
 CREATE INDEX IF NOT EXISTS `idx_instances_locked_until_completed_at` ON `instances` (`locked_until`, `sticky_until`, `completed_at`, `worker`);
@@ -51,5 +55,77 @@ CREATE TABLE IF NOT EXISTS `activities` (
   `attributes` BLOB NOT NULL,
   `visible_at` DATETIME NULL,
   `locked_until` DATETIME NULL,
-  `worker` TEXT NULL
-);
\ No newline at end of file
+  `worker` TEXT NULL,
+  `priority` INTEGER NOT NULL DEFAULT 0,
+  `queue` TEXT NOT NULL DEFAULT '',
+  `name` TEXT NOT NULL DEFAULT '',
+  `heartbeat_details` BLOB NULL
+);
+CREATE TABLE IF NOT EXISTS `activity_results` (
+  `key` TEXT PRIMARY KEY,
+  `result` BLOB NULL,
+  `expires_at` DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS `workflow_state` (
+  `space` TEXT NOT NULL,
+  `key` TEXT NOT NULL,
+  `value` BLOB NOT NULL,
+  PRIMARY KEY(`space`, `key`)
+);
+
+CREATE TABLE IF NOT EXISTS `instance_tags` (
+  `instance_id` TEXT NOT NULL,
+  `tag` TEXT NOT NULL,
+  PRIMARY KEY(`instance_id`, `tag`)
+);
+
+CREATE INDEX IF NOT EXISTS `idx_instance_tags_tag` ON `instance_tags` (`tag`);
+
+CREATE TABLE IF NOT EXISTS `instance_runs` (
+  `instance_id` TEXT NOT NULL,
+  `execution_id` TEXT NOT NULL,
+  `parent_instance_id` TEXT NULL,
+  `parent_schedule_event_id` INTEGER NULL,
+  `name` TEXT NOT NULL DEFAULT '',
+  `created_at` DATETIME NOT NULL,
+  `completed_at` DATETIME NOT NULL,
+  PRIMARY KEY(`instance_id`, `execution_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `run_history` (
+  `id` TEXT,
+  `sequence_id` INTEGER NOT NULL,
+  `instance_id` TEXT NOT NULL,
+  `execution_id` TEXT NOT NULL,
+  `event_type` INTEGER NOT NULL,
+  `timestamp` DATETIME NOT NULL,
+  `schedule_event_id` INT NOT NULL,
+  `attributes` BLOB NOT NULL,
+  `visible_at` DATETIME NULL,
+  PRIMARY KEY(`id`, `instance_id`, `execution_id`)
+);
+
+CREATE INDEX IF NOT EXISTS `idx_run_history_instance_execution_sequence_id` ON `run_history` (`instance_id`, `execution_id`, `sequence_id`);
+
+CREATE TABLE IF NOT EXISTS `activity_queue_redirects` (
+  `queue` TEXT PRIMARY KEY,
+  `target` TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS `leases` (
+  `name` TEXT PRIMARY KEY,
+  `holder` TEXT NOT NULL,
+  `expires_at` DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS `workflow_queries` (
+  `id` TEXT PRIMARY KEY,
+  `instance_id` TEXT NOT NULL,
+  `execution_id` TEXT NOT NULL,
+  `name` TEXT NOT NULL,
+  `inputs` BLOB NOT NULL,
+  `deadline` DATETIME NOT NULL,
+  `locked_until` DATETIME NULL,
+  `result` BLOB NULL
+);
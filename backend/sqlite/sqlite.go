@@ -10,10 +10,15 @@ import (
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/notify"
+	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/trace"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
 
@@ -31,8 +36,22 @@ func NewInMemoryBackend(opts ...backend.BackendOption) *sqliteBackend {
 	return b
 }
 
+// NewSqliteBackend creates a backend using the database file at path. Several processes can share the same
+// database file. The database uses WAL mode, and transactions wait for the write lock held by other
+// connections.
 func NewSqliteBackend(path string, opts ...backend.BackendOption) backend.Backend {
-	return newSqliteBackend(fmt.Sprintf("file:%v", path), opts...)
+	return newSqliteBackend(fileDSN(path), opts...)
+}
+
+// fileDSN returns the data source name for an on-disk database that supports concurrent access from
+// multiple processes
+func fileDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+
+	return fmt.Sprintf("file:%v%v_journal_mode=WAL&_busy_timeout=%d&_txlock=immediate", path, sep, busyTimeout.Milliseconds())
 }
 
 func newSqliteBackend(dsn string, opts ...backend.BackendOption) *sqliteBackend {
@@ -46,10 +65,17 @@ func newSqliteBackend(dsn string, opts ...backend.BackendOption) *sqliteBackend
 		panic(err)
 	}
 
+	options := backend.ApplyOptions(opts...)
+
 	return &sqliteBackend{
 		db:         db,
 		workerName: fmt.Sprintf("worker-%v", uuid.NewString()),
-		options:    backend.ApplyOptions(opts...),
+		options:    options,
+		throttler:  backend.NewInstanceCreationThrottler(options.InstanceCreationLimits),
+
+		workflowNotifier:   notify.NewNotifier(),
+		activityNotifier:   notify.NewNotifier(),
+		completionNotifier: notify.NewNotifier(),
 	}
 }
 
@@ -57,21 +83,122 @@ type sqliteBackend struct {
 	db         *sql.DB
 	workerName string
 	options    backend.Options
+	throttler  *backend.InstanceCreationThrottler
+
+	// Notifiers wake up pollers in this process waiting for new tasks
+	workflowNotifier *notify.Notifier
+	activityNotifier *notify.Notifier
+
+	// completionNotifier wakes up clients in this process waiting for workflow instances to finish
+	completionNotifier *notify.Notifier
+}
+
+// pollOptions returns the options for waiting for new tasks. Notifications only cover work added in this
+// process, so poll to pick up timers, expired locks, and work added by other processes. The poll interval is
+// bounded by MaxTimerSkew so that due timers are picked up in time.
+func (sb *sqliteBackend) pollOptions() notify.PollOptions {
+	minInterval := 50 * time.Millisecond
+	if minInterval > sb.options.MaxTimerSkew {
+		minInterval = sb.options.MaxTimerSkew
+	}
+
+	return notify.PollOptions{
+		Timeout:     sb.options.TaskPollTimeout,
+		MinInterval: minInterval,
+		MaxInterval: sb.options.MaxTimerSkew,
+	}
+}
+
+var _ backend.LockTimeoutProvider = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) LockTimeouts() (time.Duration, time.Duration) {
+	return sb.options.WorkflowLockTimeout, sb.options.ActivityLockTimeout
 }
 
 func (sb *sqliteBackend) Logger() log.Logger {
 	return sb.options.Logger
 }
 
+func (sb *sqliteBackend) Metrics() metrics.Client {
+	return sb.options.Metrics
+}
+
+func (sb *sqliteBackend) Tracer() trace.Tracer {
+	return sb.options.Tracer
+}
+
+func (sb *sqliteBackend) Converter() converter.Converter {
+	return sb.options.Converter
+}
+
 func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, m history.WorkflowEvent) error {
-	tx, err := sb.db.BeginTx(ctx, nil)
+	defer backend.MeasureOperation(sb.options.Metrics, "sqlite", backend.OperationCreateWorkflowInstance)()
+
+	tx, err := sb.beginTx(ctx)
 	if err != nil {
 		return fmt.Errorf("starting transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	if err := sb.createWorkflowInstance(ctx, tx, m); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("creating workflow instance: %w", err)
+	}
+
+	sb.workflowNotifier.Notify()
+
+	return nil
+}
+
+var _ backend.TransactionalInstanceCreator = (*sqliteBackend)(nil)
+
+// CreateWorkflowInstanceTx creates a new workflow instance as part of the given transaction. This allows
+// starting a workflow atomically with other changes made in the same transaction. The caller is responsible
+// for committing or rolling back the transaction. Workers waiting for tasks only pick up the instance when they
+// check for tasks again, after up to MaxTimerSkew, unless NotifyWorkflowCreated is called after committing.
+func (sb *sqliteBackend) CreateWorkflowInstanceTx(ctx context.Context, tx *sql.Tx, m history.WorkflowEvent) error {
+	return sb.createWorkflowInstance(ctx, tx, m)
+}
+
+// NotifyWorkflowCreated wakes up workers of this process waiting for workflow tasks, see CreateWorkflowInstanceTx
+func (sb *sqliteBackend) NotifyWorkflowCreated() {
+	sb.workflowNotifier.Notify()
+}
+
+func (sb *sqliteBackend) createWorkflowInstance(ctx context.Context, tx *sql.Tx, m history.WorkflowEvent) error {
+	if err := sb.throttler.Allow(m.HistoryEvent); err != nil {
+		return err
+	}
+
+	if err := checkBackpressure(ctx, tx, sb.options.Backpressure); err != nil {
+		return err
+	}
+
+	if sb.options.MaxActiveInstances > 0 {
+		var active int
+		row := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM `instances` WHERE completed_at IS NULL")
+		if err := row.Scan(&active); err != nil {
+			return fmt.Errorf("counting active workflow instances: %w", err)
+		}
+
+		if active >= sb.options.MaxActiveInstances {
+			return backend.ErrMaxActiveInstancesReached
+		}
+	}
+
 	// Create workflow instance
-	if err := createInstance(ctx, tx, m.WorkflowInstance, false); err != nil {
+	var priority int
+	var name, queue string
+	if a, ok := m.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes); ok {
+		priority = a.Priority
+		name = a.Name
+		queue = a.Queue
+	}
+
+	if err := createInstance(ctx, tx, m.WorkflowInstance, name, queue, priority, false); err != nil {
 		return err
 	}
 
@@ -80,14 +207,14 @@ func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, m history.W
 		return fmt.Errorf("inserting new event: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("creating workflow instance: %w", err)
+	if err := insertInstanceTags(ctx, tx, m.WorkflowInstance.InstanceID, history.AddedTags([]history.Event{m.HistoryEvent})); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, ignoreDuplicate bool) error {
+func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, name, queue string, priority int, ignoreDuplicate bool) error {
 	var parentInstanceID *string
 	var parentEventID *int64
 	if wfi.SubWorkflow() {
@@ -100,11 +227,14 @@ func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, ign
 
 	res, err := tx.ExecContext(
 		ctx,
-		"INSERT OR IGNORE INTO `instances` (id, execution_id, parent_instance_id, parent_schedule_event_id) VALUES (?, ?, ?, ?)",
+		"INSERT OR IGNORE INTO `instances` (id, execution_id, parent_instance_id, parent_schedule_event_id, priority, name, queue) VALUES (?, ?, ?, ?, ?, ?, ?)",
 		wfi.InstanceID,
 		wfi.ExecutionID,
 		parentInstanceID,
 		parentEventID,
+		priority,
+		name,
+		queue,
 	)
 	if err != nil {
 		return fmt.Errorf("inserting workflow instance: %w", err)
@@ -117,15 +247,34 @@ func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, ign
 		}
 
 		if rows != 1 {
-			return errors.New("could not insert workflow instance")
+			// Instance already exists, return it to the caller
+			var executionID string
+			row := tx.QueryRowContext(ctx, "SELECT execution_id FROM `instances` WHERE id = ?", wfi.InstanceID)
+			if err := row.Scan(&executionID); err != nil {
+				return fmt.Errorf("reading existing workflow instance: %w", err)
+			}
+
+			return &backend.InstanceAlreadyExistsError{
+				Instance: core.NewWorkflowInstance(wfi.InstanceID, executionID),
+			}
 		}
 	}
 
 	return nil
 }
 
+func instancePriority(ctx context.Context, tx *sql.Tx, instanceID string) (int, error) {
+	var priority int
+	row := tx.QueryRowContext(ctx, "SELECT priority FROM `instances` WHERE id = ?", instanceID)
+	if err := row.Scan(&priority); err != nil {
+		return 0, fmt.Errorf("reading instance priority: %w", err)
+	}
+
+	return priority, nil
+}
+
 func (sb *sqliteBackend) CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
-	tx, err := sb.db.BeginTx(ctx, nil)
+	tx, err := sb.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -147,11 +296,17 @@ func (sb *sqliteBackend) CancelWorkflowInstance(ctx context.Context, instance *w
 		return fmt.Errorf("inserting cancellation event: %w", err)
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sb.workflowNotifier.Notify()
+
+	return nil
 }
 
 func (sb *sqliteBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64) ([]history.Event, error) {
-	tx, err := sb.db.BeginTx(ctx, nil)
+	tx, err := sb.beginReadTx(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -166,7 +321,9 @@ func (sb *sqliteBackend) GetWorkflowInstanceHistory(ctx context.Context, instanc
 }
 
 func (s *sqliteBackend) GetWorkflowInstanceState(ctx context.Context, instance *workflow.Instance) (backend.WorkflowState, error) {
-	row := s.db.QueryRowContext(
+	db := s.options.ReadDB(ctx, s.db)
+
+	row := db.QueryRowContext(
 		ctx,
 		"SELECT completed_at FROM instances WHERE id = ? AND execution_id = ?",
 		instance.InstanceID,
@@ -176,6 +333,12 @@ func (s *sqliteBackend) GetWorkflowInstanceState(ctx context.Context, instance *
 	var completedAt sql.NullTime
 	if err := row.Scan(&completedAt); err != nil {
 		if err == sql.ErrNoRows {
+			// Earlier runs of the instance have finished
+			row := db.QueryRowContext(ctx, "SELECT 1 FROM `instance_runs` WHERE instance_id = ? AND execution_id = ?", instance.InstanceID, instance.ExecutionID)
+			if err := row.Scan(new(int)); err == nil {
+				return backend.WorkflowStateFinished, nil
+			}
+
 			return backend.WorkflowStateActive, backend.ErrInstanceNotFound
 		}
 	}
@@ -187,8 +350,40 @@ func (s *sqliteBackend) GetWorkflowInstanceState(ctx context.Context, instance *
 	return backend.WorkflowStateActive, nil
 }
 
+var _ backend.WorkflowInstanceWaiter = (*sqliteBackend)(nil)
+
+// WaitForWorkflowInstance returns as soon as an instance finishing in this process is committed. Instances finished
+// by other processes sharing the database are picked up by polling their state with a growing interval.
+func (sb *sqliteBackend) WaitForWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	return notify.Wait(ctx, sb.completionNotifier, notify.PollOptions{
+		MinInterval: 50 * time.Millisecond,
+		MaxInterval: time.Second,
+	}, func(ctx context.Context) (bool, error) {
+		state, err := sb.GetWorkflowInstanceState(ctx, instance)
+		if err != nil {
+			return false, err
+		}
+
+		return state == backend.WorkflowStateFinished, nil
+	})
+}
+
 func (sb *sqliteBackend) SignalWorkflow(ctx context.Context, instanceID string, event history.Event) error {
-	tx, err := sb.db.BeginTx(ctx, nil)
+	defer backend.MeasureOperation(sb.options.Metrics, "sqlite", backend.OperationSignalWorkflow)()
+
+	return sb.signalWorkflow(ctx, instanceID, []history.Event{event})
+}
+
+var _ backend.BatchSignaler = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) SignalWorkflowBatch(ctx context.Context, instanceID string, events []history.Event) error {
+	defer backend.MeasureOperation(sb.options.Metrics, "sqlite", backend.OperationSignalWorkflow)()
+
+	return sb.signalWorkflow(ctx, instanceID, events)
+}
+
+func (sb *sqliteBackend) signalWorkflow(ctx context.Context, instanceID string, events []history.Event) error {
+	tx, err := sb.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -200,15 +395,53 @@ func (sb *sqliteBackend) SignalWorkflow(ctx context.Context, instanceID string,
 		return backend.ErrInstanceNotFound
 	}
 
-	if err := insertNewEvents(ctx, tx, instanceID, []history.Event{event}); err != nil {
-		return fmt.Errorf("inserting signal event: %w", err)
+	if err := checkBackpressure(ctx, tx, sb.options.Backpressure); err != nil {
+		return err
 	}
 
-	return tx.Commit()
+	if err := checkPendingEventsBackpressure(ctx, tx, instanceID, sb.options.Backpressure); err != nil {
+		return err
+	}
+
+	if err := insertNewEvents(ctx, tx, instanceID, events); err != nil {
+		return fmt.Errorf("inserting signal events: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sb.workflowNotifier.Notify()
+
+	return nil
 }
 
+var _ backend.IncrementalHistoryTaskProvider = (*sqliteBackend)(nil)
+
 func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
-	tx, err := sb.db.BeginTx(ctx, nil)
+	return sb.GetWorkflowTaskWithHistory(ctx, nil)
+}
+
+func (sb *sqliteBackend) GetWorkflowTaskWithHistory(ctx context.Context, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error) {
+	return sb.GetWorkflowTaskFromQueues(ctx, []string{backend.DefaultWorkflowQueue}, knownSequenceID)
+}
+
+var _ backend.WorkflowQueueProvider = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) GetWorkflowTaskFromQueues(ctx context.Context, queues []string, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error) {
+	if len(queues) == 0 {
+		queues = []string{backend.DefaultWorkflowQueue}
+	}
+
+	return notify.Poll(ctx, sb.workflowNotifier, sb.pollOptions(), func(ctx context.Context) (*task.Workflow, error) {
+		return sb.getWorkflowTask(ctx, backend.WorkerCapabilities{}, queues, knownSequenceID)
+	})
+}
+
+func (sb *sqliteBackend) getWorkflowTask(ctx context.Context, capabilities backend.WorkerCapabilities, queues []string, knownSequenceID func(instance *workflow.Instance) (int64, bool)) (*task.Workflow, error) {
+	defer backend.MeasureOperation(sb.options.Metrics, "sqlite", backend.OperationGetWorkflowTask)()
+
+	tx, err := sb.beginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -217,6 +450,22 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, e
 	// Lock next workflow task by finding an unlocked instance with new events to process
 	// (work around missing LIMIT support in sqlite driver for UPDATE statements by using sub-query)
 	now := time.Now()
+	workflows, workflowArgs := nameFilter("name", capabilities.Workflows)
+	queueFilter, queueArgs := nameFilter("queue", queues)
+	args := []interface{}{
+		now.Add(sb.options.WorkflowLockTimeout), // new locked_until
+		sb.workerName,
+		now,           // locked_until
+		now,           // sticky_until
+		sb.workerName, // worker
+		now,           // event.visible_at
+	}
+	args = append(args, queueArgs...)
+	args = append(args, workflowArgs...)
+
+	concurrency, concurrencyArgs := workflowConcurrencyFilter(sb.options.WorkflowConcurrencyLimits, "i.name", "i.id", now)
+	args = append(args, concurrencyArgs...)
+
 	row := tx.QueryRowContext(
 		ctx,
 		`UPDATE instances
@@ -232,14 +481,13 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, e
 								FROM pending_events
 								WHERE instance_id = i.id AND execution_id = i.execution_id AND (visible_at IS NULL OR visible_at <= ?)
 						)
+						`+queueFilter+`
+						`+workflows+`
+						`+concurrency+`
+					ORDER BY priority DESC
 					LIMIT 1
 			) RETURNING id, execution_id, parent_instance_id, parent_schedule_event_id, sticky_until`,
-		now.Add(sb.options.WorkflowLockTimeout), // new locked_until
-		sb.workerName,
-		now,           // locked_until
-		now,           // sticky_until
-		sb.workerName, // worker
-		now,           // event.visible_at
+		args...,
 	)
 
 	var instanceID, executionID string
@@ -279,6 +527,7 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, e
 	}
 
 	t.NewEvents = pendingEvents
+	t.ScheduledAt = task.ScheduledAt(pendingEvents...)
 
 	// Get only most recent sequence ID
 	// TODO: Denormalize to instances table
@@ -289,6 +538,18 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, e
 		}
 	}
 
+	// Include the history the worker is missing
+	if knownSequenceID != nil {
+		if sequenceID, ok := knownSequenceID(wfi); ok && sequenceID < t.LastSequenceID {
+			t.History, err = getHistory(ctx, tx, instanceID, &sequenceID)
+			if err != nil {
+				return nil, fmt.Errorf("getting workflow history: %w", err)
+			}
+
+			t.HistorySequenceID = sequenceID
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -307,7 +568,9 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 	activityEvents []history.Event,
 	workflowEvents []history.WorkflowEvent,
 ) error {
-	tx, err := sb.db.BeginTx(ctx, nil)
+	defer backend.MeasureOperation(sb.options.Metrics, "sqlite", backend.OperationCompleteWorkflowTask)()
+
+	tx, err := sb.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -353,11 +616,31 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 		}
 	}
 
+	// Remove fired events of timers canceled by the workflow, so they are not delivered anymore
+	for _, e := range executedEvents {
+		if e.Type != history.EventType_TimerCanceled {
+			continue
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM pending_events WHERE instance_id = ? AND schedule_event_id = ? AND event_type = ?",
+			instance.InstanceID, e.ScheduleEventID, history.EventType_TimerFired,
+		); err != nil {
+			return fmt.Errorf("removing canceled timer: %w", err)
+		}
+	}
+
 	// Add events from last execution to history
 	if err := insertHistoryEvents(ctx, tx, instance.InstanceID, executedEvents); err != nil {
 		return fmt.Errorf("inserting new history events: %w", err)
 	}
 
+	// Index tags added by the workflow
+	if err := insertInstanceTags(ctx, tx, instance.InstanceID, history.AddedTags(executedEvents)); err != nil {
+		return err
+	}
+
 	// Schedule activities
 	for _, event := range activityEvents {
 		if err := scheduleActivity(ctx, tx, instance.InstanceID, instance.ExecutionID, event); err != nil {
@@ -376,9 +659,23 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 	}
 
 	for targetInstance, events := range groupedEvents {
+		if targetInstance.InstanceID == instance.InstanceID && targetInstance.ExecutionID != instance.ExecutionID {
+			// The workflow continues as new, replace the finished execution with the new one
+			if err := continueAsNew(ctx, tx, targetInstance, events); err != nil {
+				return err
+			}
+
+			continue
+		}
+
 		if instance.InstanceID != targetInstance.InstanceID {
-			// Create new instance
-			if err := createInstance(ctx, tx, targetInstance, true); err != nil {
+			// Create new instance, sub-workflows inherit the priority of their parent
+			priority, err := instancePriority(ctx, tx, instance.InstanceID)
+			if err != nil {
+				return err
+			}
+
+			if err := createInstance(ctx, tx, targetInstance, history.WorkflowName(events), history.WorkflowQueue(events), priority, true); err != nil {
 				return err
 			}
 		}
@@ -389,11 +686,26 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sb.workflowNotifier.Notify()
+	sb.activityNotifier.Notify()
+
+	if state == backend.WorkflowStateFinished {
+		sb.completionNotifier.Notify()
+	}
+
+	if sb.options.HistoryExporter != nil && len(executedEvents) > 0 {
+		sb.options.HistoryExporter.ExportHistory(instance, state, executedEvents)
+	}
+
+	return nil
 }
 
 func (sb *sqliteBackend) ExtendWorkflowTask(ctx context.Context, taskID string, instance *workflow.Instance) error {
-	tx, err := sb.db.BeginTx(ctx, nil)
+	tx, err := sb.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -421,8 +733,61 @@ func (sb *sqliteBackend) ExtendWorkflowTask(ctx context.Context, taskID string,
 	return tx.Commit()
 }
 
+var _ backend.WorkflowTaskReleaser = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) ReleaseWorkflowTask(ctx context.Context, taskID string, instance *workflow.Instance) error {
+	tx, err := sb.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE instances SET locked_until = NULL, sticky_until = NULL WHERE id = ? AND execution_id = ? AND worker = ?`,
+		instance.InstanceID,
+		instance.ExecutionID,
+		sb.workerName,
+	)
+	if err != nil {
+		return fmt.Errorf("releasing workflow task: %w", err)
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("determining if workflow task was released: %w", err)
+	} else if rowsAffected == 0 {
+		return errors.New("could not release workflow task")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("releasing workflow task: %w", err)
+	}
+
+	sb.workflowNotifier.Notify()
+
+	return nil
+}
+
 func (sb *sqliteBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
-	tx, err := sb.db.BeginTx(ctx, nil)
+	return sb.GetActivityTaskFromQueues(ctx, []string{backend.DefaultActivityQueue})
+}
+
+var _ backend.ActivityQueueProvider = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) GetActivityTaskFromQueues(ctx context.Context, queues []string) (*task.Activity, error) {
+	if len(queues) == 0 {
+		queues = []string{backend.DefaultActivityQueue}
+	}
+
+	return notify.Poll(ctx, sb.activityNotifier, sb.pollOptions(), func(ctx context.Context) (*task.Activity, error) {
+		return sb.getActivityTask(ctx, backend.WorkerCapabilities{}, queues)
+	})
+}
+
+func (sb *sqliteBackend) getActivityTask(ctx context.Context, capabilities backend.WorkerCapabilities, queues []string) (*task.Activity, error) {
+	defer backend.MeasureOperation(sb.options.Metrics, "sqlite", backend.OperationGetActivityTask)()
+
+	tx, err := sb.beginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -431,26 +796,43 @@ func (sb *sqliteBackend) GetActivityTask(ctx context.Context) (*task.Activity, e
 	// Lock next activity
 	// (work around missing LIMIT support in sqlite driver for UPDATE statements by using sub-query)
 	now := time.Now()
+	args := []interface{}{now.Add(sb.options.ActivityLockTimeout), sb.workerName, now}
+	for _, queue := range queues {
+		args = append(args, queue)
+	}
+
+	activities, activityArgs := nameFilter("name", capabilities.Activities)
+	args = append(args, activityArgs...)
+
+	// Skip activities of instances that already have the maximum number of activities in progress
+	var fairness string
+	if sb.options.MaxConcurrentActivitiesPerInstance > 0 {
+		fairness = "AND (SELECT COUNT(*) FROM activities la WHERE la.instance_id = a.instance_id AND la.locked_until >= ?) < ?"
+		args = append(args, now, sb.options.MaxConcurrentActivitiesPerInstance)
+	}
+
+	concurrency, concurrencyArgs := workflowConcurrencyFilter(
+		sb.options.WorkflowConcurrencyLimits, "COALESCE((SELECT name FROM instances WHERE id = a.instance_id), '')", "a.instance_id", now)
+	args = append(args, concurrencyArgs...)
+
 	row := tx.QueryRowContext(
 		ctx,
 		`UPDATE activities
 			SET locked_until = ?, worker = ?
 			WHERE rowid = (
-				SELECT rowid FROM activities WHERE locked_until IS NULL OR locked_until < ? LIMIT 1
-			) RETURNING id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at`,
-		now.Add(sb.options.ActivityLockTimeout),
-		sb.workerName,
-		now,
+				SELECT rowid FROM activities a WHERE (locked_until IS NULL OR locked_until < ?) AND queue IN (?`+strings.Repeat(",?", len(queues)-1)+`) `+activities+` `+fairness+` `+concurrency+` ORDER BY priority DESC LIMIT 1
+			) RETURNING id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at, heartbeat_details`,
+		args...,
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	var instanceID, executionID string
-	var attributes []byte
+	var attributes, heartbeatDetails []byte
 	event := history.Event{}
 
-	if err := row.Scan(&event.ID, &instanceID, &executionID, &event.Type, &event.Timestamp, &event.ScheduleEventID, &attributes, &event.VisibleAt); err != nil {
+	if err := row.Scan(&event.ID, &instanceID, &executionID, &event.Type, &event.Timestamp, &event.ScheduleEventID, &attributes, &event.VisibleAt, &heartbeatDetails); err != nil {
 		if err == sql.ErrNoRows {
 			// No rows locked, just return
 			return nil, nil
@@ -470,6 +852,8 @@ func (sb *sqliteBackend) GetActivityTask(ctx context.Context) (*task.Activity, e
 		ID:               event.ID,
 		WorkflowInstance: core.NewWorkflowInstance(instanceID, executionID),
 		Event:            event,
+		ScheduledAt:      task.ScheduledAt(event),
+		HeartbeatDetails: heartbeatDetails,
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -480,7 +864,9 @@ func (sb *sqliteBackend) GetActivityTask(ctx context.Context) (*task.Activity, e
 }
 
 func (sb *sqliteBackend) CompleteActivityTask(ctx context.Context, instance *workflow.Instance, id string, event history.Event) error {
-	tx, err := sb.db.BeginTx(ctx, nil)
+	defer backend.MeasureOperation(sb.options.Metrics, "sqlite", backend.OperationCompleteActivityTask)()
+
+	tx, err := sb.beginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -506,21 +892,46 @@ func (sb *sqliteBackend) CompleteActivityTask(ctx context.Context, instance *wor
 		return fmt.Errorf("inserting new events for completed activity: %w", err)
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sb.workflowNotifier.Notify()
+	sb.activityNotifier.Notify()
+
+	return nil
 }
 
 func (sb *sqliteBackend) ExtendActivityTask(ctx context.Context, activityID string) error {
-	tx, err := sb.db.BeginTx(ctx, nil)
+	return sb.extendActivityTask(ctx, activityID, nil)
+}
+
+var _ backend.ActivityHeartbeater = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) HeartbeatActivityTask(ctx context.Context, activityID string, details payload.Payload) error {
+	return sb.extendActivityTask(ctx, activityID, details)
+}
+
+// extendActivityTask extends the lock of the activity, and replaces its heartbeat details unless they are nil
+func (sb *sqliteBackend) extendActivityTask(ctx context.Context, activityID string, details payload.Payload) error {
+	tx, err := sb.beginTx(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	// Keep the stored details when there are no new ones
+	var heartbeatDetails interface{}
+	if details != nil {
+		heartbeatDetails = []byte(details)
+	}
+
 	until := time.Now().Add(sb.options.ActivityLockTimeout)
 	res, err := tx.ExecContext(
 		ctx,
-		`UPDATE activities SET locked_until = ? WHERE id = ? AND worker = ?`,
+		`UPDATE activities SET locked_until = ?, heartbeat_details = COALESCE(?, heartbeat_details) WHERE id = ? AND worker = ?`,
 		until,
+		heartbeatDetails,
 		activityID,
 		sb.workerName,
 	)
@@ -1,16 +1,33 @@
 package sqlite
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_SqliteBackend(t *testing.T) {
-	test.BackendTest(t, func() backend.Backend {
-		// Disable sticky workflow behavior for the test execution
-		return NewInMemoryBackend(backend.WithStickyTimeout(0))
+	test.BackendTest(t, func(options ...backend.BackendOption) backend.Backend {
+		// Disable sticky workflow behavior and waiting for tasks for the test execution
+		return NewInMemoryBackend(append([]backend.BackendOption{backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0)}, options...)...)
 	}, nil)
 }
 
@@ -20,3 +37,1575 @@ func Test_EndToEndSqliteBackend(t *testing.T) {
 		return NewInMemoryBackend(backend.WithStickyTimeout(0))
 	}, nil)
 }
+
+func Test_SqliteBackend_CleanupFinishedInstances(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend()
+
+	finished := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	active := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	for _, instance := range []*core.WorkflowInstance{finished, active} {
+		err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+			WorkflowInstance: instance,
+			HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := b.db.ExecContext(ctx, "UPDATE instances SET completed_at = ? WHERE id = ?", time.Now().Add(-time.Hour), finished.InstanceID)
+	require.NoError(t, err)
+
+	require.NoError(t, b.CleanupFinishedInstances(ctx, time.Minute))
+
+	_, err = b.GetWorkflowInstanceState(ctx, finished)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	state, err := b.GetWorkflowInstanceState(ctx, active)
+	require.NoError(t, err)
+	require.Equal(t, backend.WorkflowStateActive, state)
+}
+
+func Test_SqliteBackend_RemoveWorkflowInstance(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend()
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, b.RemoveWorkflowInstance(ctx, instance), backend.ErrInstanceNotFinished)
+
+	_, err = b.db.ExecContext(ctx, "UPDATE instances SET completed_at = ? WHERE id = ?", time.Now(), instance.InstanceID)
+	require.NoError(t, err)
+
+	require.NoError(t, b.RemoveWorkflowInstance(ctx, instance))
+
+	_, err = b.GetWorkflowInstanceState(ctx, instance)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	require.ErrorIs(t, b.RemoveWorkflowInstance(ctx, instance), backend.ErrInstanceNotFound)
+}
+
+func Test_SqliteBackend_Retention(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewInMemoryBackend(backend.WithRetention(time.Minute))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	_, err = b.db.ExecContext(ctx, "UPDATE instances SET completed_at = ? WHERE id = ?", time.Now().Add(-time.Hour), instance.InstanceID)
+	require.NoError(t, err)
+
+	// The janitor of the worker removes the instance once it acquired the leadership
+	w := worker.New(b, &worker.DefaultWorkerOptions)
+	require.NoError(t, w.Start(ctx))
+
+	require.Eventually(t, func() bool {
+		_, err := b.GetWorkflowInstanceState(ctx, instance)
+		return errors.Is(err, backend.ErrInstanceNotFound)
+	}, time.Second*5, time.Millisecond*50)
+
+	cancel()
+	require.NoError(t, w.WaitForCompletion())
+}
+
+func Test_SqliteBackend_CreateWorkflowInstanceTx(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend()
+
+	rolledBack := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	committed := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	for _, instance := range []*core.WorkflowInstance{rolledBack, committed} {
+		tx, err := b.db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+
+		err = b.CreateWorkflowInstanceTx(ctx, tx, history.WorkflowEvent{
+			WorkflowInstance: instance,
+			HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+		})
+		require.NoError(t, err)
+
+		if instance == committed {
+			require.NoError(t, tx.Commit())
+		} else {
+			require.NoError(t, tx.Rollback())
+		}
+	}
+
+	_, err := b.GetWorkflowInstanceState(ctx, rolledBack)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	state, err := b.GetWorkflowInstanceState(ctx, committed)
+	require.NoError(t, err)
+	require.Equal(t, backend.WorkflowStateActive, state)
+}
+
+func Test_SqliteBackend_CreateWorkflowInstanceTx_Notify(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithMaxTimerSkew(time.Minute), backend.WithTaskPollTimeout(time.Minute))
+
+	tasks := make(chan *task.Workflow, 1)
+	go func() {
+		task, _ := b.GetWorkflowTask(ctx)
+		tasks <- task
+	}()
+
+	// Let the poller back off, so it only checks for tasks again after seconds
+	time.Sleep(2 * time.Second)
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstanceTx(ctx, tx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	}))
+	require.NoError(t, tx.Commit())
+
+	b.NotifyWorkflowCreated()
+
+	select {
+	case task := <-tasks:
+		require.NotNil(t, task)
+		require.Equal(t, instance.InstanceID, task.WorkflowInstance.InstanceID)
+	case <-time.After(500 * time.Millisecond):
+		require.Fail(t, "worker did not pick up the instance")
+	}
+}
+
+func Test_SqliteBackend_MaxActiveInstances(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithMaxActiveInstances(1))
+
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	err = b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.ErrorIs(t, err, backend.ErrMaxActiveInstancesReached)
+}
+
+func Test_SqliteBackend_Priority(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend()
+
+	low := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	high := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	for _, i := range []struct {
+		instance *core.WorkflowInstance
+		priority int
+	}{{low, 0}, {high, 10}} {
+		err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+			WorkflowInstance: i.instance,
+			HistoryEvent: history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+				Priority: i.priority,
+			}),
+		})
+		require.NoError(t, err)
+	}
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.Equal(t, high.InstanceID, task.WorkflowInstance.InstanceID)
+
+	task, err = b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.Equal(t, low.InstanceID, task.WorkflowInstance.InstanceID)
+}
+
+func Test_SqliteBackend_MaxConcurrentActivitiesPerInstance(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0), backend.WithMaxConcurrentActivitiesPerInstance(1))
+
+	// Schedule three activities for the first, and one activity for the second instance
+	instances := []*core.WorkflowInstance{
+		core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+		core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+	}
+
+	for i, instance := range instances {
+		err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+			WorkflowInstance: instance,
+			HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+		})
+		require.NoError(t, err)
+
+		task, err := b.GetWorkflowTask(ctx)
+		require.NoError(t, err)
+
+		activityEvents := []history.Event{}
+		for j := 0; j < 3-i*2; j++ {
+			activityEvents = append(activityEvents, history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(int64(j+1))))
+		}
+
+		executedEvents := append(task.NewEvents, activityEvents...)
+		for j := range executedEvents {
+			executedEvents[j].SequenceID = int64(j + 1)
+		}
+
+		err = b.CompleteWorkflowTask(ctx, task.ID, instance, backend.WorkflowStateActive, executedEvents, activityEvents, []history.WorkflowEvent{})
+		require.NoError(t, err)
+	}
+
+	// Only a single activity per instance is handed out
+	seen := map[string]bool{}
+	for range instances {
+		task, err := b.GetActivityTask(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, task)
+		require.False(t, seen[task.WorkflowInstance.InstanceID])
+		seen[task.WorkflowInstance.InstanceID] = true
+	}
+
+	task, err := b.GetActivityTask(ctx)
+	require.NoError(t, err)
+	require.Nil(t, task)
+}
+
+func Test_SqliteBackend_WorkflowConcurrencyLimit(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0), backend.WithWorkflowConcurrencyLimit("limited", 1))
+
+	create := func(name string) *core.WorkflowInstance {
+		instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+		err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+			WorkflowInstance: instance,
+			HistoryEvent: history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+				Name: name,
+			}),
+		})
+		require.NoError(t, err)
+
+		return instance
+	}
+
+	complete := func(wt *task.Workflow, activityEvents []history.Event) {
+		executedEvents := append(wt.NewEvents, activityEvents...)
+		for j := range executedEvents {
+			executedEvents[j].SequenceID = int64(j + 1)
+		}
+
+		err := b.CompleteWorkflowTask(ctx, wt.ID, wt.WorkflowInstance, backend.WorkflowStateActive, executedEvents, activityEvents, []history.WorkflowEvent{})
+		require.NoError(t, err)
+	}
+
+	first := create("limited")
+	second := create("limited")
+	other := create("other")
+
+	// Only one instance of the limited workflow is handed out, other workflows are not affected
+	firstTask, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.Equal(t, first.InstanceID, firstTask.WorkflowInstance.InstanceID)
+
+	otherTask, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.Equal(t, other.InstanceID, otherTask.WorkflowInstance.InstanceID)
+
+	next, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.Nil(t, next)
+
+	// Once the first instance has no locked tasks, the second instance executes
+	complete(firstTask, []history.Event{
+		history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(1)),
+	})
+
+	secondTask, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.Equal(t, second.InstanceID, secondTask.WorkflowInstance.InstanceID)
+
+	// The activity of the first instance waits for the second instance
+	activityTask, err := b.GetActivityTask(ctx)
+	require.NoError(t, err)
+	require.Nil(t, activityTask)
+
+	complete(secondTask, []history.Event{})
+
+	activityTask, err = b.GetActivityTask(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, activityTask)
+	require.Equal(t, first.InstanceID, activityTask.WorkflowInstance.InstanceID)
+}
+
+func Test_SqliteBackend_HeartbeatActivityTask(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithActivityLockTimeout(time.Millisecond * 500))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	activityEvents := []history.Event{
+		history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(1)),
+	}
+	executedEvents := append(task.NewEvents, activityEvents...)
+	for i := range executedEvents {
+		executedEvents[i].SequenceID = int64(i + 1)
+	}
+
+	err = b.CompleteWorkflowTask(ctx, task.ID, instance, backend.WorkflowStateActive, executedEvents, activityEvents, []history.WorkflowEvent{})
+	require.NoError(t, err)
+
+	activityTask, err := b.GetActivityTask(ctx)
+	require.NoError(t, err)
+	require.Nil(t, activityTask.HeartbeatDetails)
+
+	require.NoError(t, b.HeartbeatActivityTask(ctx, activityTask.ID, []byte(`"halfway"`)))
+
+	// Extending the lock without details keeps the stored details
+	require.NoError(t, b.ExtendActivityTask(ctx, activityTask.ID))
+
+	// Abandon the task, the next execution continues from the last heartbeat
+	time.Sleep(time.Second)
+
+	activityTask, err = b.GetActivityTask(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, activityTask)
+	require.Equal(t, `"halfway"`, string(activityTask.HeartbeatDetails))
+}
+
+func Test_SqliteBackend_RecordInstanceError(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend()
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		err = b.RecordInstanceError(ctx, instance, &backend.InstanceError{
+			Message:   "activity failed",
+			Source:    backend.InstanceErrorSourceActivity,
+			Name:      "Activity1",
+			Timestamp: time.Now(),
+		})
+		require.NoError(t, err)
+	}
+
+	ref, err := b.GetWorkflowInstance(ctx, instance.InstanceID)
+	require.NoError(t, err)
+	require.NotNil(t, ref.LastError)
+	require.Equal(t, "activity failed", ref.LastError.Message)
+	require.Equal(t, 2, ref.LastError.Attempt)
+}
+
+func Test_SqliteBackend_MultipleProcesses(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "shared.sqlite")
+
+	// Every backend uses its own connection pool, like separate processes sharing the database file
+	backends := []backend.Backend{
+		NewSqliteBackend(path, backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0)),
+		NewSqliteBackend(path, backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0)),
+		NewSqliteBackend(path, backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0)),
+	}
+
+	const instances = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < instances; i++ {
+		wg.Add(1)
+		go func(b backend.Backend) {
+			defer wg.Done()
+
+			err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+				WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+				HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+			})
+			require.NoError(t, err)
+		}(backends[i%len(backends)])
+	}
+	wg.Wait()
+
+	// Claim tasks concurrently from all backends, every task must be handed out exactly once
+	var mu sync.Mutex
+	claimed := map[string]int{}
+
+	for _, b := range backends {
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func(b backend.Backend) {
+				defer wg.Done()
+
+				for {
+					task, err := b.GetWorkflowTask(ctx)
+					require.NoError(t, err)
+					if task == nil {
+						return
+					}
+
+					mu.Lock()
+					claimed[task.WorkflowInstance.InstanceID]++
+					mu.Unlock()
+				}
+			}(b)
+		}
+	}
+	wg.Wait()
+
+	require.Len(t, claimed, instances)
+	for id, n := range claimed {
+		require.Equal(t, 1, n, "instance %v claimed more than once", id)
+	}
+}
+
+func Test_SqliteBackend_GetWorkflowInstanceStats(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	// Schedule an activity and a timer
+	activityEvents := []history.Event{
+		history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(1)),
+	}
+	fireAt := time.Now().Add(time.Hour)
+	executedEvents := append(task.NewEvents, activityEvents...)
+	executedEvents = append(executedEvents,
+		history.NewPendingEvent(time.Now(), history.EventType_TimerScheduled, &history.TimerScheduledAttributes{At: fireAt}, history.ScheduleEventID(2)))
+	for i := range executedEvents {
+		executedEvents[i].SequenceID = int64(i + 1)
+	}
+
+	workflowEvents := []history.WorkflowEvent{
+		{
+			WorkflowInstance: instance,
+			HistoryEvent: history.NewPendingEvent(
+				time.Now(), history.EventType_TimerFired, &history.TimerFiredAttributes{At: fireAt}, history.ScheduleEventID(2), history.VisibleAt(fireAt)),
+		},
+	}
+
+	err = b.CompleteWorkflowTask(ctx, task.ID, instance, backend.WorkflowStateActive, executedEvents, activityEvents, workflowEvents)
+	require.NoError(t, err)
+
+	err = b.SignalWorkflow(ctx, instance.InstanceID, history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{Name: "signal"}))
+	require.NoError(t, err)
+
+	err = b.RecordInstanceError(ctx, instance, &backend.InstanceError{Message: "failed", Source: backend.InstanceErrorSourceWorkflow})
+	require.NoError(t, err)
+
+	stats, err := b.GetWorkflowInstanceStats(ctx, instance)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(executedEvents)), stats.HistoryEvents)
+	require.Greater(t, stats.HistorySize, int64(0))
+	require.Equal(t, int64(2), stats.PendingEvents)
+	require.Equal(t, int64(1), stats.BufferedSignals)
+	require.Equal(t, int64(1), stats.PendingActivities)
+	require.Equal(t, int64(1), stats.PendingTimers)
+	require.Equal(t, 1, stats.FailedAttempts)
+	require.NotNil(t, stats.WorkflowTaskScheduledAt)
+	require.WithinDuration(t, time.Now(), *stats.WorkflowTaskScheduledAt, time.Second)
+	require.NotNil(t, stats.ActivityTaskScheduledAt)
+	require.WithinDuration(t, time.Now(), *stats.ActivityTaskScheduledAt, time.Second)
+
+	_, err = b.GetWorkflowInstanceStats(ctx, core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()))
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+}
+
+func Test_SqliteBackend_CompactWorkflowInstanceHistory(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	executedEvents := append(task.NewEvents,
+		history.NewPendingEvent(time.Now(), history.EventType_TimerScheduled, &history.TimerScheduledAttributes{}, history.ScheduleEventID(1)),
+		history.NewPendingEvent(time.Now(), history.EventType_TimerCanceled, &history.TimerCanceledAttributes{}, history.ScheduleEventID(1)),
+		history.NewPendingEvent(time.Now(), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+		history.NewPendingEvent(time.Now(), history.EventType_TimerFired, &history.TimerFiredAttributes{}, history.ScheduleEventID(1)),
+		history.NewPendingEvent(time.Now(), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+		history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{}),
+	)
+	for i := range executedEvents {
+		executedEvents[i].SequenceID = int64(i + 1)
+	}
+
+	err = b.CompleteWorkflowTask(ctx, task.ID, instance, backend.WorkflowStateActive, executedEvents, []history.Event{}, []history.WorkflowEvent{})
+	require.NoError(t, err)
+
+	removed, err := b.CompactWorkflowInstanceHistory(ctx, instance)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+
+	h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+	require.NoError(t, err)
+	require.Len(t, h, len(executedEvents)-2)
+
+	// Compacting again does not remove anything
+	removed, err = b.CompactWorkflowInstanceHistory(ctx, instance)
+	require.NoError(t, err)
+	require.Equal(t, 0, removed)
+}
+
+func Test_SqliteBackend_RewritePayloads(t *testing.T) {
+	ctx := context.Background()
+
+	key1 := []byte("0123456789abcdef0123456789abcdef")
+	key2 := []byte("fedcba9876543210fedcba9876543210")
+
+	codec1, err := converter.NewAESCodec(converter.EncryptionKeys{CurrentKeyID: "1", Keys: map[string][]byte{"1": key1}})
+	require.NoError(t, err)
+
+	c1 := converter.NewEnvelopeConverter(converter.DefaultConverter, codec1)
+
+	input, err := c1.To("input")
+	require.NoError(t, err)
+
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0))
+
+	err = b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+		HistoryEvent: history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+			Name:   "wf",
+			Inputs: []payload.Payload{input},
+		}),
+	})
+	require.NoError(t, err)
+
+	result, err := c1.To(42)
+	require.NoError(t, err)
+	require.NoError(t, b.StoreActivityResult(ctx, "key", result, time.Hour))
+
+	state, err := c1.To("state")
+	require.NoError(t, err)
+	require.NoError(t, b.SetState(ctx, "space", "key", state))
+
+	codec2, err := converter.NewAESCodec(converter.EncryptionKeys{CurrentKeyID: "2", Keys: map[string][]byte{"1": key1, "2": key2}})
+	require.NoError(t, err)
+
+	n, err := b.RewritePayloads(ctx, codec2.Reencrypt)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	// Everything has been re-encrypted, nothing left to rewrite
+	n, err = b.RewritePayloads(ctx, codec2.Reencrypt)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// Payloads can be decoded without the previous key
+	codec3, err := converter.NewAESCodec(converter.EncryptionKeys{CurrentKeyID: "2", Keys: map[string][]byte{"2": key2}})
+	require.NoError(t, err)
+
+	c3 := converter.NewEnvelopeConverter(converter.DefaultConverter, codec3)
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	var s string
+	require.NoError(t, c3.From(task.NewEvents[0].Attributes.(*history.ExecutionStartedAttributes).Inputs[0], &s))
+	require.Equal(t, "input", s)
+
+	cached, ok, err := b.GetActivityResult(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var i int
+	require.NoError(t, c3.From(cached, &i))
+	require.Equal(t, 42, i)
+
+	stored, ok, err := b.GetState(ctx, "space", "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, c3.From(stored, &s))
+	require.Equal(t, "state", s)
+}
+
+func Test_SqliteBackend_GetWorkflowTaskWithHistory(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	executedEvents := append(task.NewEvents,
+		history.NewPendingEvent(time.Now(), history.EventType_TimerScheduled, &history.TimerScheduledAttributes{}, history.ScheduleEventID(1)),
+		history.NewPendingEvent(time.Now(), history.EventType_TimerCanceled, &history.TimerCanceledAttributes{}, history.ScheduleEventID(1)),
+	)
+	for i := range executedEvents {
+		executedEvents[i].SequenceID = int64(i + 1)
+	}
+
+	err = b.CompleteWorkflowTask(ctx, task.ID, instance, backend.WorkflowStateActive, executedEvents, []history.Event{}, []history.WorkflowEvent{})
+	require.NoError(t, err)
+
+	err = b.SignalWorkflow(ctx, instance.InstanceID, history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{Name: "signal"}))
+	require.NoError(t, err)
+
+	task, err = b.GetWorkflowTaskWithHistory(ctx, func(i *core.WorkflowInstance) (int64, bool) {
+		require.Equal(t, instance.InstanceID, i.InstanceID)
+		return 1, true
+	})
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	require.Equal(t, int64(3), task.LastSequenceID)
+	require.Equal(t, int64(1), task.HistorySequenceID)
+	require.Len(t, task.History, 2)
+	require.Equal(t, int64(2), task.History[0].SequenceID)
+	require.Equal(t, int64(3), task.History[1].SequenceID)
+}
+
+func Test_SqliteBackend_ForceCompleteWorkflowInstance(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	event := history.NewPendingEvent(time.Now(), history.EventType_WorkflowExecutionForceCompleted, &history.ExecutionForceCompletedAttributes{
+		Result: []byte("42"),
+		Reason: "manual",
+	})
+
+	err = b.ForceCompleteWorkflowInstance(ctx, instance, event, nil)
+	require.NoError(t, err)
+
+	state, err := b.GetWorkflowInstanceState(ctx, instance)
+	require.NoError(t, err)
+	require.Equal(t, backend.WorkflowStateFinished, state)
+
+	h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+	require.NoError(t, err)
+	require.Len(t, h, 1)
+	require.Equal(t, history.EventType_WorkflowExecutionForceCompleted, h[0].Type)
+	require.Equal(t, int64(1), h[0].SequenceID)
+	require.Equal(t, "manual", h[0].Attributes.(*history.ExecutionForceCompletedAttributes).Reason)
+
+	// The pending started event was discarded
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.Nil(t, task)
+
+	err = b.ForceCompleteWorkflowInstance(ctx, instance, event, nil)
+	require.ErrorIs(t, err, backend.ErrInstanceFinished)
+
+	err = b.ForceCompleteWorkflowInstance(ctx, core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()), event, nil)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+}
+
+func Test_SqliteBackend_WaitForWorkflowInstance(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, b.WaitForWorkflowInstance(waitCtx, instance), context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+
+		event := history.NewPendingEvent(time.Now(), history.EventType_WorkflowExecutionForceCompleted, &history.ExecutionForceCompletedAttributes{})
+		_ = b.ForceCompleteWorkflowInstance(ctx, instance, event, nil)
+	}()
+
+	// Completing the instance in this process wakes up the waiter right away
+	start := time.Now()
+	require.NoError(t, b.WaitForWorkflowInstance(ctx, instance))
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func Test_SqliteBackend_GetWorkflowInstancesByTags(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent: history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+			Tags: []string{"release:2024-06", "team:a"},
+		}),
+	})
+	require.NoError(t, err)
+
+	other := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err = b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: other,
+		HistoryEvent: history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+			Tags: []string{"release:2024-06"},
+		}),
+	})
+	require.NoError(t, err)
+
+	instances, err := b.GetWorkflowInstancesByTags(ctx, []string{"release:2024-06"}, true)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []*core.WorkflowInstance{instance, other}, instances)
+
+	instances, err = b.GetWorkflowInstancesByTags(ctx, []string{"release:2024-06", "team:a"}, true)
+	require.NoError(t, err)
+	require.Equal(t, []*core.WorkflowInstance{instance}, instances)
+
+	// Add a tag from the workflow and finish the instance
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	executedEvents := append(task.NewEvents,
+		history.NewPendingEvent(time.Now(), history.EventType_WorkflowTagsAdded, &history.WorkflowTagsAddedAttributes{Tags: []string{"step:done"}}))
+	for i := range executedEvents {
+		executedEvents[i].SequenceID = int64(i + 1)
+	}
+
+	err = b.CompleteWorkflowTask(ctx, task.ID, task.WorkflowInstance, backend.WorkflowStateFinished, executedEvents, nil, nil)
+	require.NoError(t, err)
+
+	instances, err = b.GetWorkflowInstancesByTags(ctx, []string{"step:done"}, false)
+	require.NoError(t, err)
+	require.Equal(t, []*core.WorkflowInstance{task.WorkflowInstance}, instances)
+
+	instances, err = b.GetWorkflowInstancesByTags(ctx, []string{"step:done"}, true)
+	require.NoError(t, err)
+	require.Empty(t, instances)
+}
+
+func Test_SqliteBackend_InstanceCreationLimit(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithInstanceCreationLimit("", 1, 1))
+
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	err = b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.ErrorIs(t, err, backend.ErrThrottled)
+}
+
+func Test_SqliteBackend_Backpressure(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithBackpressure(backend.BackpressureOptions{
+		MaxPendingWorkflowTasks:     2,
+		MaxPendingEventsPerInstance: 2,
+	}))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	// The instance has one pending event, one more is allowed
+	err = b.SignalWorkflow(ctx, instance.InstanceID, history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{Name: "signal"}))
+	require.NoError(t, err)
+
+	err = b.SignalWorkflow(ctx, instance.InstanceID, history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{Name: "signal"}))
+	require.ErrorIs(t, err, backend.ErrBackpressure)
+
+	var bpErr *backend.BackpressureError
+	require.ErrorAs(t, err, &bpErr)
+	require.Equal(t, int64(2), bpErr.PendingEvents)
+
+	err = b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	err = b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.ErrorAs(t, err, &bpErr)
+	require.Equal(t, int64(2), bpErr.Backlog.PendingWorkflowTasks)
+}
+
+func Test_SqliteBackend_ActivityQueues(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+	})
+	require.NoError(t, err)
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	activityEvents := []history.Event{
+		history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "encode", Queue: "video"}, history.ScheduleEventID(1)),
+		history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "notify"}, history.ScheduleEventID(2)),
+	}
+
+	executedEvents := append(task.NewEvents, activityEvents...)
+	for i := range executedEvents {
+		executedEvents[i].SequenceID = int64(i + 1)
+	}
+
+	err = b.CompleteWorkflowTask(ctx, task.ID, instance, backend.WorkflowStateActive, executedEvents, activityEvents, []history.WorkflowEvent{})
+	require.NoError(t, err)
+
+	// Only activities of the default queue are returned by GetActivityTask
+	activityTask, err := b.GetActivityTask(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "notify", activityTask.Event.Attributes.(*history.ActivityScheduledAttributes).Name)
+
+	activityTask, err = b.GetActivityTask(ctx)
+	require.NoError(t, err)
+	require.Nil(t, activityTask)
+
+	activityTask, err = b.GetActivityTaskFromQueues(ctx, []string{"video"})
+	require.NoError(t, err)
+	require.Equal(t, "encode", activityTask.Event.Attributes.(*history.ActivityScheduledAttributes).Name)
+}
+
+func Test_SqliteBackend_WorkflowQueues(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Queue: "heavy"}),
+	})
+	require.NoError(t, err)
+
+	// Only instances of the default queue are returned by GetWorkflowTask
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.Nil(t, task)
+
+	task, err = b.GetWorkflowTaskFromQueues(ctx, []string{backend.DefaultWorkflowQueue, "heavy"}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	require.Equal(t, instance.InstanceID, task.WorkflowInstance.InstanceID)
+
+	// Sub-workflows are placed on the queue of their started event
+	subInstance := core.NewSubWorkflowInstance(uuid.NewString(), uuid.NewString(), instance.InstanceID, 1)
+	workflowEvents := []history.WorkflowEvent{{
+		WorkflowInstance: subInstance,
+		HistoryEvent:     history.NewPendingEvent(time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Queue: "heavy"}),
+	}}
+
+	executedEvents := task.NewEvents
+	for i := range executedEvents {
+		executedEvents[i].SequenceID = int64(i + 1)
+	}
+
+	err = b.CompleteWorkflowTask(ctx, task.ID, instance, backend.WorkflowStateActive, executedEvents, nil, workflowEvents)
+	require.NoError(t, err)
+
+	task, err = b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.Nil(t, task)
+
+	task, err = b.GetWorkflowTaskFromQueues(ctx, []string{"heavy"}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	require.Equal(t, subInstance.InstanceID, task.WorkflowInstance.InstanceID)
+}
+
+func Test_SqliteBackend_MoveActivityQueue(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0))
+
+	scheduleActivities := func(names ...string) {
+		instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+		err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+			WorkflowInstance: instance,
+			HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+		})
+		require.NoError(t, err)
+
+		task, err := b.GetWorkflowTask(ctx)
+		require.NoError(t, err)
+
+		activityEvents := []history.Event{}
+		for i, name := range names {
+			activityEvents = append(activityEvents, history.NewPendingEvent(
+				time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: name, Queue: "video"}, history.ScheduleEventID(int64(i+1))))
+		}
+
+		executedEvents := append(task.NewEvents, activityEvents...)
+		for i := range executedEvents {
+			executedEvents[i].SequenceID = int64(i + 1)
+		}
+
+		err = b.CompleteWorkflowTask(ctx, task.ID, instance, backend.WorkflowStateActive, executedEvents, activityEvents, []history.WorkflowEvent{})
+		require.NoError(t, err)
+	}
+
+	scheduleActivities("encode", "thumbnail")
+
+	// Move only the tasks of one activity
+	moved, err := b.MoveActivityQueue(ctx, "video", "gpu", []string{"encode"}, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, moved)
+
+	activityTask, err := b.GetActivityTaskFromQueues(ctx, []string{"gpu"})
+	require.NoError(t, err)
+	require.Equal(t, "encode", activityTask.Event.Attributes.(*history.ActivityScheduledAttributes).Name)
+
+	// Move the remaining tasks and redirect activities scheduled later
+	moved, err = b.MoveActivityQueue(ctx, "video", "gpu", nil, true)
+	require.NoError(t, err)
+	require.Equal(t, 1, moved)
+
+	scheduleActivities("encode")
+
+	activityTask, err = b.GetActivityTaskFromQueues(ctx, []string{"video"})
+	require.NoError(t, err)
+	require.Nil(t, activityTask)
+
+	for i := 0; i < 2; i++ {
+		activityTask, err = b.GetActivityTaskFromQueues(ctx, []string{"gpu"})
+		require.NoError(t, err)
+		require.NotNil(t, activityTask)
+	}
+
+	redirects, err := b.ActivityQueueRedirects(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"video": "gpu"}, redirects)
+
+	// Moving back to a redirected queue would create a cycle
+	_, err = b.MoveActivityQueue(ctx, "gpu", "video", nil, false)
+	require.Error(t, err)
+
+	require.NoError(t, b.RemoveActivityQueueRedirect(ctx, "video"))
+
+	scheduleActivities("encode")
+
+	activityTask, err = b.GetActivityTaskFromQueues(ctx, []string{"video"})
+	require.NoError(t, err)
+	require.NotNil(t, activityTask)
+}
+
+func Test_SqliteBackend_Capabilities(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"}),
+	})
+	require.NoError(t, err)
+
+	task, err := b.GetWorkflowTaskForCapabilities(ctx, backend.WorkerCapabilities{Workflows: []string{"other"}}, nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, task)
+
+	task, err = b.GetWorkflowTaskForCapabilities(ctx, backend.WorkerCapabilities{Workflows: []string{"other", "wf"}}, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	require.Equal(t, instance.InstanceID, task.WorkflowInstance.InstanceID)
+
+	activityEvents := []history.Event{
+		history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "encode"}, history.ScheduleEventID(1)),
+		history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "notify"}, history.ScheduleEventID(2)),
+	}
+
+	executedEvents := append(task.NewEvents, activityEvents...)
+	for i := range executedEvents {
+		executedEvents[i].SequenceID = int64(i + 1)
+	}
+
+	err = b.CompleteWorkflowTask(ctx, task.ID, instance, backend.WorkflowStateActive, executedEvents, activityEvents, []history.WorkflowEvent{})
+	require.NoError(t, err)
+
+	activityTask, err := b.GetActivityTaskForCapabilities(ctx, backend.WorkerCapabilities{Activities: []string{"notify"}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "notify", activityTask.Event.Attributes.(*history.ActivityScheduledAttributes).Name)
+
+	activityTask, err = b.GetActivityTaskForCapabilities(ctx, backend.WorkerCapabilities{Activities: []string{"notify"}}, nil)
+	require.NoError(t, err)
+	require.Nil(t, activityTask)
+
+	activityTask, err = b.GetActivityTask(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "encode", activityTask.Event.Attributes.(*history.ActivityScheduledAttributes).Name)
+}
+
+func Test_SqliteBackend_LockTimeouts(t *testing.T) {
+	b := NewInMemoryBackend(backend.WithWorkflowLockTimeout(time.Minute), backend.WithActivityLockTimeout(10*time.Second))
+
+	workflowLockTimeout, activityLockTimeout := b.LockTimeouts()
+	require.Equal(t, time.Minute, workflowLockTimeout)
+	require.Equal(t, 10*time.Second, activityLockTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The default heartbeat interval is derived from the lock timeouts
+	w := worker.New(b, nil)
+	require.NoError(t, w.Start(ctx))
+
+	// Activity locks would expire before the configured heartbeat interval
+	options := worker.DefaultWorkerOptions
+	options.ActivityHeartbeatInterval = 10 * time.Second
+	w = worker.New(b, &options)
+	require.Error(t, w.Start(ctx))
+
+	options.ActivityHeartbeatInterval = 5 * time.Second
+	w = worker.New(b, &options)
+	require.NoError(t, w.Start(ctx))
+}
+
+func Test_SqliteBackend_WorkflowTaskHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Two workers with their own backend share the database, either can pick up the task once its lock expires
+	path := filepath.Join(t.TempDir(), "heartbeat.sqlite")
+	backends := []backend.Backend{
+		NewSqliteBackend(path, backend.WithStickyTimeout(0), backend.WithWorkflowLockTimeout(300*time.Millisecond), backend.WithMaxTimerSkew(50*time.Millisecond)),
+		NewSqliteBackend(path, backend.WithStickyTimeout(0), backend.WithWorkflowLockTimeout(300*time.Millisecond), backend.WithMaxTimerSkew(50*time.Millisecond)),
+	}
+
+	var executions int32
+	wf := func(ctx workflow.Context) error {
+		atomic.AddInt32(&executions, 1)
+
+		// Takes longer than the lock timeout, the lock has to be extended
+		time.Sleep(time.Second)
+		return nil
+	}
+
+	// The heartbeat interval is derived from the lock timeout
+	options := worker.DefaultWorkerOptions
+	options.HeartbeatWorkflowTasks = true
+
+	for _, b := range backends {
+		w := worker.New(b, &options)
+		require.NoError(t, w.RegisterWorkflow(wf))
+		require.NoError(t, w.Start(ctx))
+	}
+
+	c := client.New(backends[0])
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, wf)
+	require.NoError(t, err)
+
+	_, err = client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+	require.NoError(t, err)
+
+	// The other worker didn't pick up the task while it was executed
+	require.Equal(t, int32(1), atomic.LoadInt32(&executions))
+}
+
+func Test_SqliteBackend_ActivityPrefetch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithActivityLockTimeout(time.Second))
+	c := client.New(b)
+
+	var executions int32
+	a := func(ctx context.Context) error {
+		atomic.AddInt32(&executions, 1)
+		time.Sleep(400 * time.Millisecond)
+		return nil
+	}
+
+	wf := func(ctx workflow.Context) error {
+		fs := []workflow.Future[any]{}
+		for i := 0; i < 4; i++ {
+			fs = append(fs, workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, a))
+		}
+
+		for _, f := range fs {
+			if _, err := f.Get(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	// Prefetched tasks wait longer than the activity lock timeout for the single slot
+	options := worker.DefaultWorkerOptions
+	options.MaxParallelActivityTasks = 1
+	options.ActivityDispatchQueueSize = 2
+	options.ActivityHeartbeatInterval = 200 * time.Millisecond
+
+	w := worker.New(b, &options)
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.RegisterActivity(a))
+	require.NoError(t, w.Start(ctx))
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, wf)
+	require.NoError(t, err)
+
+	_, err = client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+	require.NoError(t, err)
+
+	// The locks of waiting tasks were extended, no task was handed out twice
+	require.Equal(t, int32(4), atomic.LoadInt32(&executions))
+}
+
+func Test_SqliteBackend_MaxParallelTasksPerTaskType(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0))
+	c := client.New(b)
+
+	// track records the maximum number of callers running at the same time
+	track := func(running, maxRunning *int32, d time.Duration) {
+		n := atomic.AddInt32(running, 1)
+		for {
+			m := atomic.LoadInt32(maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(maxRunning, m, n) {
+				break
+			}
+		}
+
+		time.Sleep(d)
+		atomic.AddInt32(running, -1)
+	}
+
+	var runningWorkflows, maxWorkflows, runningActivities, maxActivities int32
+
+	a := func(ctx context.Context) error {
+		track(&runningActivities, &maxActivities, 50*time.Millisecond)
+		return nil
+	}
+
+	wf := func(ctx workflow.Context) error {
+		// Runs for every workflow task of the instance
+		track(&runningWorkflows, &maxWorkflows, 20*time.Millisecond)
+
+		fs := []workflow.Future[any]{}
+		for i := 0; i < 3; i++ {
+			fs = append(fs, workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, a))
+		}
+
+		for _, f := range fs {
+			if _, err := f.Get(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	options := worker.DefaultWorkerOptions
+	options.MaxParallelWorkflowTasks = 1
+	options.MaxParallelActivityTasks = 2
+
+	w := worker.New(b, &options)
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.RegisterActivity(a))
+
+	instances := make([]*workflow.Instance, 4)
+	for i := range instances {
+		var err error
+		instances[i], err = c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, wf)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Start(ctx))
+
+	for _, instance := range instances {
+		_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+		require.NoError(t, err)
+	}
+
+	// Each task type is limited by its own setting
+	require.Equal(t, int32(1), atomic.LoadInt32(&maxWorkflows))
+	require.Equal(t, int32(2), atomic.LoadInt32(&maxActivities))
+}
+
+func Test_SqliteBackend_ListWorkflowInstances(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent: history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+			Name: "wf",
+			Tags: []string{"team:a"},
+		}),
+	})
+	require.NoError(t, err)
+
+	other := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err = b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: other,
+		HistoryEvent: history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+			Name: "other",
+			Tags: []string{"team:a"},
+		}),
+	})
+	require.NoError(t, err)
+
+	instances, err := b.ListWorkflowInstances(ctx, backend.InstanceFilter{Tags: []string{"team:a"}})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []*core.WorkflowInstance{instance, other}, instances)
+
+	instances, err = b.ListWorkflowInstances(ctx, backend.InstanceFilter{Name: "wf", Tags: []string{"team:a"}})
+	require.NoError(t, err)
+	require.Equal(t, []*core.WorkflowInstance{instance}, instances)
+
+	// Finish the first instance
+	task, err := b.GetWorkflowTaskForCapabilities(ctx, backend.WorkerCapabilities{Workflows: []string{"wf"}}, nil, nil)
+	require.NoError(t, err)
+
+	executedEvents := task.NewEvents
+	for i := range executedEvents {
+		executedEvents[i].SequenceID = int64(i + 1)
+	}
+
+	err = b.CompleteWorkflowTask(ctx, task.ID, task.WorkflowInstance, backend.WorkflowStateFinished, executedEvents, nil, nil)
+	require.NoError(t, err)
+
+	instances, err = b.ListWorkflowInstances(ctx, backend.InstanceFilter{States: []backend.WorkflowState{backend.WorkflowStateActive}})
+	require.NoError(t, err)
+	require.Equal(t, []*core.WorkflowInstance{other}, instances)
+
+	instances, err = b.ListWorkflowInstances(ctx, backend.InstanceFilter{States: []backend.WorkflowState{backend.WorkflowStateFinished}})
+	require.NoError(t, err)
+	require.Equal(t, []*core.WorkflowInstance{instance}, instances)
+}
+
+func Test_SqliteBackend_QueryWorkflowInstances(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend()
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, instanceID := range []string{"order-1", "order-2", "order-3", "order_4", "user-1"} {
+		err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+			WorkflowInstance: core.NewWorkflowInstance(instanceID, uuid.NewString()),
+			HistoryEvent: history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+				Name: "wf",
+			}),
+		})
+		require.NoError(t, err)
+
+		// order-2 and order-3 are created at the same time
+		createdAt := start.Add(time.Duration(i) * time.Minute)
+		if i == 2 {
+			createdAt = start.Add(time.Minute)
+		}
+
+		_, err = b.db.ExecContext(ctx, "UPDATE instances SET created_at = ? WHERE id = ?", createdAt.Format(timestampFormat), instanceID)
+		require.NoError(t, err)
+	}
+
+	_, err := b.db.ExecContext(ctx, "UPDATE instances SET completed_at = ? WHERE id = ?", time.Now(), "order-1")
+	require.NoError(t, err)
+
+	ids := func(r *backend.InstanceQueryResult) []string {
+		ids := make([]string, 0, len(r.Instances))
+		for _, i := range r.Instances {
+			ids = append(ids, i.Instance.InstanceID)
+		}
+
+		return ids
+	}
+
+	r, err := b.QueryWorkflowInstances(ctx, backend.InstanceQuery{InstanceIDPrefix: "order-", PageSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"order-3", "order-2"}, ids(r))
+	require.Equal(t, "wf", r.Instances[0].Name)
+	require.Equal(t, start.Add(time.Minute), r.Instances[0].CreatedAt.UTC())
+	require.NotEmpty(t, r.NextPageToken)
+
+	r, err = b.QueryWorkflowInstances(ctx, backend.InstanceQuery{InstanceIDPrefix: "order-", PageSize: 2, PageToken: r.NextPageToken})
+	require.NoError(t, err)
+	require.Equal(t, []string{"order-1"}, ids(r))
+	require.Equal(t, backend.WorkflowStateFinished, r.Instances[0].State)
+	require.NotNil(t, r.Instances[0].CompletedAt)
+	require.Empty(t, r.NextPageToken)
+
+	r, err = b.QueryWorkflowInstances(ctx, backend.InstanceQuery{States: []backend.WorkflowState{backend.WorkflowStateActive}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"user-1", "order_4", "order-3", "order-2"}, ids(r))
+
+	r, err = b.QueryWorkflowInstances(ctx, backend.InstanceQuery{CreatedAfter: start.Add(time.Minute), CreatedBefore: start.Add(4 * time.Minute)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"order_4", "order-3", "order-2"}, ids(r))
+
+	_, err = b.QueryWorkflowInstances(ctx, backend.InstanceQuery{PageToken: "invalid"})
+	require.ErrorIs(t, err, backend.ErrInvalidPageToken)
+}
+
+func Test_SqliteBackend_VisibilityStore(t *testing.T) {
+	ctx := context.Background()
+
+	replica := NewInMemoryBackend()
+	b := NewInMemoryBackend(backend.WithReadReplica(replica.db))
+
+	for _, tags := range [][]string{{"team:a"}, {"team:a", "team:b"}, {"team:b"}} {
+		err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+			WorkflowInstance: core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()),
+			HistoryEvent: history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+				Name: "wf",
+				Tags: tags,
+			}),
+		})
+		require.NoError(t, err)
+	}
+
+	// The visibility store is served from the replica, which has not caught up yet
+	v := b.VisibilityStore()
+
+	n, err := v.CountWorkflowInstances(ctx, backend.InstanceFilter{Name: "wf"})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+
+	// Without a replica, the store queries the primary
+	b.options.ReadReplica = nil
+
+	n, err = v.CountWorkflowInstances(ctx, backend.InstanceFilter{Name: "wf"})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+
+	n, err = v.CountWorkflowInstances(ctx, backend.InstanceFilter{Tags: []string{"team:a"}})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	n, err = v.CountWorkflowInstances(ctx, backend.InstanceFilter{States: []backend.WorkflowState{backend.WorkflowStateFinished}})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+
+	instances, err := v.ListWorkflowInstances(ctx, backend.InstanceFilter{Tags: []string{"team:b"}})
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+}
+
+func Test_SqliteBackend_ReadReplica(t *testing.T) {
+	ctx := context.Background()
+
+	// An empty database stands in for a replica that has not caught up yet
+	replica := NewInMemoryBackend()
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0), backend.WithReadReplica(replica.db))
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: instance,
+		HistoryEvent: history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+			Name: "wf",
+		}),
+	})
+	require.NoError(t, err)
+
+	// Reads are served from the primary by default
+	state, err := b.GetWorkflowInstanceState(ctx, instance)
+	require.NoError(t, err)
+	require.Equal(t, backend.WorkflowStateActive, state)
+
+	instances, err := b.ListWorkflowInstances(ctx, backend.InstanceFilter{Name: "wf"})
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+
+	// and from the replica if the context prefers it
+	replicaCtx := backend.PreferReadReplica(ctx)
+
+	_, err = b.GetWorkflowInstanceState(replicaCtx, instance)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	instances, err = b.ListWorkflowInstances(replicaCtx, backend.InstanceFilter{Name: "wf"})
+	require.NoError(t, err)
+	require.Empty(t, instances)
+
+	h, err := b.GetWorkflowInstanceHistory(replicaCtx, instance, nil)
+	require.NoError(t, err)
+	require.Empty(t, h)
+
+	// Writes always go to the primary
+	err = b.SignalWorkflow(replicaCtx, instance.InstanceID, history.NewHistoryEvent(1, time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{
+		Name: "signal",
+	}))
+	require.NoError(t, err)
+}
+
+type sessionHostActivities struct {
+	host string
+}
+
+func (a *sessionHostActivities) Host(ctx context.Context) (string, error) {
+	return a.host, nil
+}
+
+func Test_SqliteBackend_Sessions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0))
+	c := client.New(b)
+
+	wf := func(ctx workflow.Context) ([]string, error) {
+		s, err := workflow.CreateSession(ctx).Get(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var a *sessionHostActivities
+
+		hosts := []string{}
+		for i := 0; i < 5; i++ {
+			h, err := workflow.ExecuteActivity[string](ctx, workflow.ActivityOptions{Session: s}, a.Host).Get(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			hosts = append(hosts, h)
+		}
+
+		if _, err := workflow.CompleteSession(ctx, s).Get(ctx); err != nil {
+			return nil, err
+		}
+
+		_, err = workflow.ExecuteActivity[string](ctx, workflow.ActivityOptions{Session: s}, a.Host).Get(ctx)
+		if !errors.Is(err, workflow.ErrSessionFailed) {
+			return nil, fmt.Errorf("expected session to be completed, got %v", err)
+		}
+
+		return hosts, nil
+	}
+
+	options := worker.DefaultWorkerOptions
+	options.EnableSessions = true
+
+	for _, host := range []string{"a", "b", "c"} {
+		w := worker.New(b, &options)
+		require.NoError(t, w.RegisterWorkflow(wf))
+		require.NoError(t, w.RegisterActivity(&sessionHostActivities{host: host}))
+		require.NoError(t, w.Start(ctx))
+	}
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, wf)
+	require.NoError(t, err)
+
+	hosts, err := client.GetWorkflowResult[[]string](ctx, c, instance, time.Second*10)
+	require.NoError(t, err)
+	require.Len(t, hosts, 5)
+
+	// All activities ran on the worker hosting the session
+	for _, h := range hosts {
+		require.Equal(t, hosts[0], h)
+	}
+}
+
+func Test_SqliteBackend_SessionFailsWhenHostStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewInMemoryBackend(backend.WithStickyTimeout(0))
+	c := client.New(b)
+
+	wf := func(ctx workflow.Context) error {
+		s, err := workflow.CreateSession(ctx).Get(ctx)
+		if err != nil {
+			return err
+		}
+
+		var a *sessionHostActivities
+		if _, err := workflow.ExecuteActivity[string](ctx, workflow.ActivityOptions{Session: s}, a.Host).Get(ctx); err != nil {
+			return err
+		}
+
+		workflow.NewSignalChannel[string](ctx, "continue").Receive(ctx)
+
+		_, err = workflow.ExecuteActivity[string](ctx, workflow.ActivityOptions{Session: s}, a.Host).Get(ctx)
+		return err
+	}
+
+	// Only the first worker hosts sessions, the second worker keeps executing the workflow after it stopped
+	hostCtx, stopHost := context.WithCancel(ctx)
+
+	options := worker.DefaultWorkerOptions
+	options.EnableSessions = true
+	options.ActivityQueues = []string{"unused"}
+	host := worker.New(b, &options)
+	require.NoError(t, host.RegisterWorkflow(wf))
+	require.NoError(t, host.RegisterActivity(&sessionHostActivities{host: "session"}))
+	require.NoError(t, host.Start(hostCtx))
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.Start(ctx))
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, wf)
+	require.NoError(t, err)
+
+	// Wait for the first activity of the session
+	require.Eventually(t, func() bool {
+		h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+		require.NoError(t, err)
+
+		for _, e := range h {
+			if e.Type == history.EventType_ActivityCompleted {
+				return true
+			}
+		}
+
+		return false
+	}, time.Second*10, time.Millisecond*50)
+
+	stopHost()
+	require.NoError(t, host.WaitForCompletion())
+
+	require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "continue", ""))
+
+	_, err = client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+	require.ErrorContains(t, err, workflow.ErrSessionFailed.Error())
+}
@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+var _ backend.StateStore = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) GetState(ctx context.Context, space, key string) (payload.Payload, bool, error) {
+	row := sb.db.QueryRowContext(ctx, "SELECT value FROM `workflow_state` WHERE space = ? AND `key` = ?", space, key)
+
+	var value []byte
+	if err := row.Scan(&value); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("reading state: %w", err)
+	}
+
+	return value, true, nil
+}
+
+func (sb *sqliteBackend) SetState(ctx context.Context, space, key string, value payload.Payload) error {
+	if _, err := sb.db.ExecContext(
+		ctx,
+		"INSERT OR REPLACE INTO `workflow_state` (space, `key`, value) VALUES (?, ?, ?)",
+		space,
+		key,
+		[]byte(value),
+	); err != nil {
+		return fmt.Errorf("storing state: %w", err)
+	}
+
+	return nil
+}
+
+func (sb *sqliteBackend) DeleteState(ctx context.Context, space, key string) error {
+	if _, err := sb.db.ExecContext(ctx, "DELETE FROM `workflow_state` WHERE space = ? AND `key` = ?", space, key); err != nil {
+		return fmt.Errorf("deleting state: %w", err)
+	}
+
+	return nil
+}
+
+func (sb *sqliteBackend) IncrementState(ctx context.Context, space, key string, delta int64) (int64, error) {
+	tx, err := sb.beginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var n int64
+
+	row := tx.QueryRowContext(ctx, "SELECT value FROM `workflow_state` WHERE space = ? AND `key` = ?", space, key)
+
+	var value []byte
+	if err := row.Scan(&value); err != nil {
+		if err != sql.ErrNoRows {
+			return 0, fmt.Errorf("reading state: %w", err)
+		}
+	} else if n, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+		return 0, fmt.Errorf("state %s/%s is not a counter: %w", space, key, err)
+	}
+
+	n += delta
+
+	if _, err := tx.ExecContext(
+		ctx,
+		"INSERT OR REPLACE INTO `workflow_state` (space, `key`, value) VALUES (?, ?, ?)",
+		space,
+		key,
+		[]byte(strconv.FormatInt(n, 10)),
+	); err != nil {
+		return 0, fmt.Errorf("storing state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("incrementing state: %w", err)
+	}
+
+	return n, nil
+}
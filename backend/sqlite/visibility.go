@@ -0,0 +1,42 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+var _ backend.VisibilityProvider = (*sqliteBackend)(nil)
+
+// VisibilityStore returns a visibility store querying the instances of this backend. Queries are served from the
+// read replica if one is configured with backend.WithReadReplica, so listing and counting instances doesn't load
+// the primary database.
+func (sb *sqliteBackend) VisibilityStore() backend.VisibilityStore {
+	return &visibilityStore{b: sb}
+}
+
+type visibilityStore struct {
+	b *sqliteBackend
+}
+
+func (v *visibilityStore) ListWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) ([]*core.WorkflowInstance, error) {
+	return v.b.ListWorkflowInstances(backend.PreferReadReplica(ctx), filter)
+}
+
+func (v *visibilityStore) CountWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) (int64, error) {
+	where, args, ok := instanceFilterClause(filter)
+	if !ok {
+		return 0, nil
+	}
+
+	ctx = backend.PreferReadReplica(ctx)
+
+	var n int64
+	if err := v.b.options.ReadDB(ctx, v.b.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM `instances` i WHERE "+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting instances: %w", err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+var _ backend.WorkflowQuerier = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) AddWorkflowQuery(ctx context.Context, query *backend.WorkflowQuery) error {
+	inputs, err := json.Marshal(query.Inputs)
+	if err != nil {
+		return fmt.Errorf("marshaling query inputs: %w", err)
+	}
+
+	if _, err := sb.db.ExecContext(
+		ctx,
+		"INSERT INTO `workflow_queries` (id, instance_id, execution_id, name, inputs, deadline) VALUES (?, ?, ?, ?, ?, ?)",
+		query.ID,
+		query.Instance.InstanceID,
+		query.Instance.ExecutionID,
+		query.Name,
+		inputs,
+		query.Deadline,
+	); err != nil {
+		return fmt.Errorf("inserting query: %w", err)
+	}
+
+	return nil
+}
+
+func (sb *sqliteBackend) GetWorkflowQuery(ctx context.Context) (*backend.WorkflowQuery, error) {
+	// Avoid starting a write transaction on every poll while there are no queries
+	var n int
+	if err := sb.db.QueryRowContext(ctx, "SELECT 1 FROM `workflow_queries` LIMIT 1").Scan(&n); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("checking for queries: %w", err)
+	}
+
+	tx, err := sb.beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	// Nobody waits for the results of expired queries anymore
+	if _, err := tx.ExecContext(ctx, "DELETE FROM `workflow_queries` WHERE deadline < ?", now); err != nil {
+		return nil, fmt.Errorf("deleting expired queries: %w", err)
+	}
+
+	row := tx.QueryRowContext(
+		ctx,
+		`SELECT id, instance_id, execution_id, name, inputs, deadline FROM workflow_queries
+			WHERE result IS NULL AND (locked_until IS NULL OR locked_until < ?)
+			ORDER BY deadline LIMIT 1`,
+		now,
+	)
+
+	query := &backend.WorkflowQuery{Instance: &core.WorkflowInstance{}}
+	var inputs []byte
+	if err := row.Scan(&query.ID, &query.Instance.InstanceID, &query.Instance.ExecutionID, &query.Name, &inputs, &query.Deadline); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("reading query: %w", err)
+	}
+
+	if err := json.Unmarshal(inputs, &query.Inputs); err != nil {
+		return nil, fmt.Errorf("unmarshaling query inputs: %w", err)
+	}
+
+	// Lock the query, if the worker doesn't answer it in time, another worker can pick it up
+	if _, err := tx.ExecContext(
+		ctx, "UPDATE `workflow_queries` SET locked_until = ? WHERE id = ?", now.Add(sb.options.WorkflowLockTimeout), query.ID,
+	); err != nil {
+		return nil, fmt.Errorf("locking query: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+func (sb *sqliteBackend) CompleteWorkflowQuery(ctx context.Context, queryID string, result *backend.WorkflowQueryResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("marshaling query result: %w", err)
+	}
+
+	if _, err := sb.db.ExecContext(
+		ctx, "UPDATE `workflow_queries` SET result = ?, locked_until = NULL WHERE id = ?", data, queryID,
+	); err != nil {
+		return fmt.Errorf("storing query result: %w", err)
+	}
+
+	return nil
+}
+
+func (sb *sqliteBackend) GetWorkflowQueryResult(ctx context.Context, queryID string) (*backend.WorkflowQueryResult, error) {
+	var data []byte
+	if err := sb.db.QueryRowContext(
+		ctx, "SELECT result FROM `workflow_queries` WHERE id = ? AND result IS NOT NULL", queryID,
+	).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("reading query result: %w", err)
+	}
+
+	var result backend.WorkflowQueryResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("unmarshaling query result: %w", err)
+	}
+
+	if _, err := sb.db.ExecContext(ctx, "DELETE FROM `workflow_queries` WHERE id = ?", queryID); err != nil {
+		return nil, fmt.Errorf("deleting query: %w", err)
+	}
+
+	return &result, nil
+}
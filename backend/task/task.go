@@ -0,0 +1,12 @@
+// Package task exposes the workflow and activity tasks backends hand out to workers, for implementing
+// backends outside of this module.
+package task
+
+import (
+	"github.com/cschleiden/go-workflows/internal/task"
+)
+
+type (
+	Workflow = task.Workflow
+	Activity = task.Activity
+)
@@ -2,6 +2,8 @@ package test
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -13,10 +15,13 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func BackendTest(t *testing.T, setup func() backend.Backend, teardown func(b backend.Backend)) {
+// BackendTest runs the backend conformance tests against the backend returned by setup. Some tests require
+// specific options, e.g., short lock timeouts, so setup needs to apply any options passed to it.
+func BackendTest(t *testing.T, setup func(options ...backend.BackendOption) backend.Backend, teardown func(b backend.Backend)) {
 	tests := []struct {
-		name string
-		f    func(t *testing.T, ctx context.Context, b backend.Backend)
+		name    string
+		options []backend.BackendOption
+		f       func(t *testing.T, ctx context.Context, b backend.Backend)
 	}{
 		{
 			name: "GetWorkflowTask_ReturnsNilWhenTimeout",
@@ -69,6 +74,54 @@ func BackendTest(t *testing.T, setup func() backend.Backend, teardown func(b bac
 				require.Error(t, err)
 			},
 		},
+		{
+			name: "CreateWorkflowInstance_SameInstanceIDReturnsExistingInstance",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+				err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+					WorkflowInstance: instance,
+					HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+				})
+				require.NoError(t, err)
+
+				err = b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+					WorkflowInstance: core.NewWorkflowInstance(instance.InstanceID, uuid.NewString()),
+					HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+				})
+				require.ErrorIs(t, err, backend.ErrInstanceAlreadyExists)
+
+				var existsErr *backend.InstanceAlreadyExistsError
+				require.ErrorAs(t, err, &existsErr)
+				require.Equal(t, instance.InstanceID, existsErr.Instance.InstanceID)
+				require.Equal(t, instance.ExecutionID, existsErr.Instance.ExecutionID)
+			},
+		},
+		{
+			name: "GetWorkflowInstance_ResolvesCurrentExecution",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				r, ok := b.(backend.InstanceResolver)
+				if !ok {
+					t.Skip("backend does not resolve instances")
+				}
+
+				instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+				err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+					WorkflowInstance: instance,
+					HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+				})
+				require.NoError(t, err)
+
+				resolved, err := r.ResolveWorkflowInstance(ctx, instance.InstanceID)
+				require.NoError(t, err)
+				require.Equal(t, instance.InstanceID, resolved.InstanceID)
+				require.Equal(t, instance.ExecutionID, resolved.ExecutionID)
+
+				_, err = r.ResolveWorkflowInstance(ctx, uuid.NewString())
+				require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+			},
+		},
 		{
 			name: "GetWorkflowTask_ReturnsTask",
 			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
@@ -176,6 +229,138 @@ func BackendTest(t *testing.T, setup func() backend.Backend, teardown func(b bac
 				}
 			},
 		},
+		{
+			name: "GetWorkflowTask_ReportsScheduledAt",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				createdAt := time.Now()
+
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+					WorkflowInstance: wfi,
+					HistoryEvent:     history.NewHistoryEvent(1, createdAt, history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+				})
+				require.NoError(t, err)
+
+				task, err := b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.WithinDuration(t, createdAt, task.ScheduledAt, time.Second)
+			},
+		},
+		{
+			name:    "GetWorkflowTaskWithHistory_ReturnsMissingHistory",
+			options: []backend.BackendOption{backend.WithStickyTimeout(0)},
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				ib, ok := b.(backend.IncrementalHistoryTaskProvider)
+				if !ok {
+					t.Skip("backend does not deliver history with tasks")
+				}
+
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+					WorkflowInstance: wfi,
+					HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+				})
+				require.NoError(t, err)
+
+				task, err := b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				executedEvents := append(task.NewEvents,
+					history.NewPendingEvent(time.Now(), history.EventType_TimerScheduled, &history.TimerScheduledAttributes{}, history.ScheduleEventID(1)),
+					history.NewPendingEvent(time.Now(), history.EventType_TimerCanceled, &history.TimerCanceledAttributes{}, history.ScheduleEventID(1)),
+				)
+				for i := range executedEvents {
+					executedEvents[i].SequenceID = int64(i + 1)
+				}
+
+				err = b.CompleteWorkflowTask(ctx, task.ID, wfi, backend.WorkflowStateActive, executedEvents, []history.Event{}, []history.WorkflowEvent{})
+				require.NoError(t, err)
+
+				// Only the events after the given sequence ID are returned
+				lastSequenceID := int64(1)
+				h, err := b.GetWorkflowInstanceHistory(ctx, wfi, &lastSequenceID)
+				require.NoError(t, err)
+				require.Len(t, h, 2)
+				require.Equal(t, int64(2), h[0].SequenceID)
+				require.Equal(t, int64(3), h[1].SequenceID)
+
+				err = b.SignalWorkflow(ctx, wfi.InstanceID, history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{Name: "signal"}))
+				require.NoError(t, err)
+
+				// The worker only knows the started event, the task includes the rest of the history
+				task, err = ib.GetWorkflowTaskWithHistory(ctx, func(instance *core.WorkflowInstance) (int64, bool) {
+					return 1, instance.InstanceID == wfi.InstanceID
+				})
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, int64(3), task.LastSequenceID)
+				require.Equal(t, int64(1), task.HistorySequenceID)
+				require.Len(t, task.History, 2)
+				require.Equal(t, int64(2), task.History[0].SequenceID)
+				require.Equal(t, int64(3), task.History[1].SequenceID)
+			},
+		},
+		{
+			name: "CompleteWorkflowTask_TimerFiresOnceWhenDue",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi, sequenceID := startWorkflowWithTimer(t, ctx, b, time.Now().Add(500*time.Millisecond))
+
+				// Timer is not delivered before it's due
+				task, err := b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.Nil(t, task)
+
+				time.Sleep(time.Second)
+
+				task, err = b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Len(t, task.NewEvents, 1)
+				require.Equal(t, history.EventType_TimerFired, task.NewEvents[0].Type)
+
+				firedEvent := task.NewEvents[0]
+				firedEvent.SequenceID = sequenceID + 1
+				err = b.CompleteWorkflowTask(ctx, task.ID, wfi, backend.WorkflowStateActive, []history.Event{firedEvent}, []history.Event{}, []history.WorkflowEvent{})
+				require.NoError(t, err)
+
+				// Timer is delivered only once
+				task, err = b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.Nil(t, task)
+			},
+		},
+		{
+			name: "CompleteWorkflowTask_CanceledTimerDoesNotFire",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi, sequenceID := startWorkflowWithTimer(t, ctx, b, time.Now().Add(500*time.Millisecond))
+
+				// Signal the workflow to get a task in which the timer is canceled
+				err := b.SignalWorkflow(ctx, wfi.InstanceID, history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{Name: "cancel"}))
+				require.NoError(t, err)
+
+				task, err := b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				canceledEvent := history.NewPendingEvent(time.Now(), history.EventType_TimerCanceled, &history.TimerCanceledAttributes{}, history.ScheduleEventID(1))
+				events := append(task.NewEvents, canceledEvent)
+				for i := range events {
+					sequenceID++
+					events[i].SequenceID = sequenceID
+				}
+
+				err = b.CompleteWorkflowTask(ctx, task.ID, wfi, backend.WorkflowStateActive, events, []history.Event{}, []history.WorkflowEvent{})
+				require.NoError(t, err)
+
+				time.Sleep(time.Second)
+
+				task, err = b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.Nil(t, task)
+			},
+		},
 		{
 			name: "SignalWorkflow_ErrorWhenInstanceDoesNotExist",
 			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
@@ -185,6 +370,40 @@ func BackendTest(t *testing.T, setup func() backend.Backend, teardown func(b bac
 				require.Equal(t, backend.ErrInstanceNotFound, err)
 			},
 		},
+		{
+			name: "SignalWorkflowBatch_DeliversSignalsInOneTask",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				if _, ok := b.(backend.BatchSignaler); !ok {
+					t.Skip("backend does not support batch signals")
+				}
+
+				c := client.New(b)
+				instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				startWorkflow(t, ctx, b, c, instance)
+
+				err := c.SignalWorkflowBatch(ctx, instance.InstanceID, []client.Signal{
+					{Name: "a", Arg: 1},
+					{Name: "b", Arg: 2},
+					{Name: "c", Arg: 3},
+				})
+				require.NoError(t, err)
+
+				task, err := b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				names := []string{}
+				for _, event := range task.NewEvents {
+					if event.Type == history.EventType_SignalReceived {
+						names = append(names, event.Attributes.(*history.SignalReceivedAttributes).Name)
+					}
+				}
+				require.Equal(t, []string{"a", "b", "c"}, names)
+
+				err = c.SignalWorkflowBatch(ctx, "does-not-exist", []client.Signal{{Name: "a"}})
+				require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+			},
+		},
 		{
 			name: "CancelWorkflow_ErrorWhenInstanceDoesNotExist",
 			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
@@ -245,11 +464,254 @@ func BackendTest(t *testing.T, setup func() backend.Backend, teardown func(b bac
 				require.Equal(t, history.EventType_WorkflowExecutionCanceled, task.NewEvents[len(task.NewEvents)-1].Type)
 			},
 		},
+		{
+			name: "GetBacklogStats_CountsPendingTasks",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				br, ok := b.(backend.BacklogReporter)
+				if !ok {
+					t.Skip("backend does not report backlog")
+				}
+
+				stats, err := br.GetBacklogStats(ctx)
+				require.NoError(t, err)
+				require.Equal(t, &backend.BacklogStats{}, stats)
+
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err = b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+					WorkflowInstance: wfi,
+					HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+				})
+				require.NoError(t, err)
+
+				stats, err = br.GetBacklogStats(ctx)
+				require.NoError(t, err)
+				require.Equal(t, int64(1), stats.PendingWorkflowTasks)
+
+				task, err := b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+
+				activityScheduledEvent := history.NewPendingEvent(time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{}, history.ScheduleEventID(1))
+				executedEvents := append(task.NewEvents, activityScheduledEvent)
+				for i := range executedEvents {
+					executedEvents[i].SequenceID = int64(i + 1)
+				}
+
+				err = b.CompleteWorkflowTask(ctx, task.ID, wfi, backend.WorkflowStateActive, executedEvents, []history.Event{activityScheduledEvent}, []history.WorkflowEvent{})
+				require.NoError(t, err)
+
+				stats, err = br.GetBacklogStats(ctx)
+				require.NoError(t, err)
+				require.Equal(t, int64(0), stats.PendingWorkflowTasks)
+				require.Equal(t, int64(1), stats.PendingActivityTasks)
+			},
+		},
+		{
+			name: "StateStore_SetGetDelete",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				ss, ok := b.(backend.StateStore)
+				if !ok {
+					t.Skip("backend does not store state")
+				}
+
+				space := uuid.NewString()
+
+				_, found, err := ss.GetState(ctx, space, "key")
+				require.NoError(t, err)
+				require.False(t, found)
+
+				require.NoError(t, ss.SetState(ctx, space, "key", []byte(`"value"`)))
+				require.NoError(t, ss.SetState(ctx, space, "key", []byte(`"value2"`)))
+
+				value, found, err := ss.GetState(ctx, space, "key")
+				require.NoError(t, err)
+				require.True(t, found)
+				require.Equal(t, `"value2"`, string(value))
+
+				_, found, err = ss.GetState(ctx, uuid.NewString(), "key")
+				require.NoError(t, err)
+				require.False(t, found, "keys are scoped to their key space")
+
+				require.NoError(t, ss.DeleteState(ctx, space, "key"))
+				require.NoError(t, ss.DeleteState(ctx, space, "key"))
+
+				_, found, err = ss.GetState(ctx, space, "key")
+				require.NoError(t, err)
+				require.False(t, found)
+			},
+		},
+		{
+			name: "StateStore_IncrementState",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				ss, ok := b.(backend.StateStore)
+				if !ok {
+					t.Skip("backend does not store state")
+				}
+
+				space := uuid.NewString()
+
+				n, err := ss.IncrementState(ctx, space, "counter", 2)
+				require.NoError(t, err)
+				require.Equal(t, int64(2), n)
+
+				n, err = ss.IncrementState(ctx, space, "counter", -3)
+				require.NoError(t, err)
+				require.Equal(t, int64(-1), n)
+
+				value, found, err := ss.GetState(ctx, space, "counter")
+				require.NoError(t, err)
+				require.True(t, found)
+				require.Equal(t, "-1", string(value))
+			},
+		},
+		{
+			name: "GetWorkflowTask_ConcurrentCallsReturnTaskOnlyOnce",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+					WorkflowInstance: wfi,
+					HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+				})
+				require.NoError(t, err)
+
+				const pollers = 10
+
+				var wg sync.WaitGroup
+				var delivered int32
+
+				for i := 0; i < pollers; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+
+						ctx, cancel := context.WithTimeout(ctx, time.Millisecond*500)
+						defer cancel()
+
+						task, err := b.GetWorkflowTask(ctx)
+						if err == nil && task != nil {
+							atomic.AddInt32(&delivered, 1)
+						}
+					}()
+				}
+
+				wg.Wait()
+
+				require.Equal(t, int32(1), atomic.LoadInt32(&delivered), "task should be delivered to exactly one poller")
+			},
+		},
+		{
+			name: "GetWorkflowTask_ReclaimsTaskAfterLockExpires",
+			options: []backend.BackendOption{
+				backend.WithWorkflowLockTimeout(time.Second),
+			},
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+					WorkflowInstance: wfi,
+					HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+				})
+				require.NoError(t, err)
+
+				task, err := b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				// Abandon the task and wait for the lock to expire
+				time.Sleep(time.Second * 2)
+
+				task, err = b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, wfi.InstanceID, task.WorkflowInstance.InstanceID)
+			},
+		},
+		{
+			name: "ExtendWorkflowTask_KeepsTaskLocked",
+			options: []backend.BackendOption{
+				backend.WithWorkflowLockTimeout(time.Second * 3),
+			},
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+					WorkflowInstance: wfi,
+					HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+				})
+				require.NoError(t, err)
+
+				task, err := b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				time.Sleep(time.Second * 2)
+
+				err = b.ExtendWorkflowTask(ctx, task.ID, task.WorkflowInstance)
+				require.NoError(t, err)
+
+				// Original lock would have expired by now, extended lock is still valid
+				time.Sleep(time.Millisecond * 1800)
+
+				ctx, cancel := context.WithTimeout(ctx, time.Millisecond*100)
+				defer cancel()
+
+				task, err = b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.Nil(t, task)
+			},
+		},
+		{
+			name: "GetWorkflowTask_FutureEventsOnlyVisibleAfterVisibleAt",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+					WorkflowInstance: wfi,
+					HistoryEvent:     history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+				})
+				require.NoError(t, err)
+
+				task, err := b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+
+				// Schedule a timer for the instance itself
+				visibleAt := time.Now().Add(time.Second * 2)
+				timerFiredEvent := history.NewPendingEvent(
+					time.Now(),
+					history.EventType_TimerFired,
+					&history.TimerFiredAttributes{At: visibleAt},
+					history.ScheduleEventID(1),
+					history.VisibleAt(visibleAt),
+				)
+
+				executedEvents := task.NewEvents
+				for i := range executedEvents {
+					executedEvents[i].SequenceID = task.LastSequenceID + int64(i) + 1
+				}
+
+				err = b.CompleteWorkflowTask(ctx, task.ID, wfi, backend.WorkflowStateActive, executedEvents, []history.Event{}, []history.WorkflowEvent{
+					{WorkflowInstance: wfi, HistoryEvent: timerFiredEvent},
+				})
+				require.NoError(t, err)
+
+				// Timer has not fired yet
+				ctxTimeout, cancel := context.WithTimeout(ctx, time.Millisecond*100)
+				defer cancel()
+				task, err = b.GetWorkflowTask(ctxTimeout)
+				require.NoError(t, err)
+				require.Nil(t, task)
+
+				time.Sleep(time.Until(visibleAt) + time.Second)
+
+				task, err = b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Len(t, task.NewEvents, 1)
+				require.Equal(t, timerFiredEvent.ID, task.NewEvents[0].ID)
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			b := setup()
+			b := setup(tt.options...)
 			ctx := context.Background()
 			tt.f(t, ctx, b)
 			if teardown != nil {
@@ -273,3 +735,50 @@ func startWorkflow(t *testing.T, ctx context.Context, b backend.Backend, c clien
 	err = b.CompleteWorkflowTask(ctx, task.ID, instance, backend.WorkflowStateActive, task.NewEvents, []history.Event{}, []history.WorkflowEvent{})
 	require.NoError(t, err)
 }
+
+// startWorkflowWithTimer creates a workflow instance and completes its first task scheduling a timer firing at
+// the given time. Returns the instance and the last sequence id of its history.
+func startWorkflowWithTimer(t *testing.T, ctx context.Context, b backend.Backend, at time.Time) (*core.WorkflowInstance, int64) {
+	startedEvent := history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{})
+
+	wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	err := b.CreateWorkflowInstance(ctx, history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     startedEvent,
+	})
+	require.NoError(t, err)
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+
+	events := []history.Event{
+		history.NewPendingEvent(time.Now(), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+		startedEvent,
+		history.NewPendingEvent(time.Now(), history.EventType_TimerScheduled, &history.TimerScheduledAttributes{At: at}, history.ScheduleEventID(1)),
+	}
+
+	sequenceID := int64(0)
+	for i := range events {
+		sequenceID++
+		events[i].SequenceID = sequenceID
+	}
+
+	workflowEvents := []history.WorkflowEvent{
+		{
+			WorkflowInstance: wfi,
+			HistoryEvent: history.NewPendingEvent(
+				time.Now(),
+				history.EventType_TimerFired,
+				&history.TimerFiredAttributes{At: at},
+				history.ScheduleEventID(1),
+				history.VisibleAt(at),
+			),
+		},
+	}
+
+	err = b.CompleteWorkflowTask(ctx, task.ID, wfi, backend.WorkflowStateActive, events, []history.Event{}, workflowEvents)
+	require.NoError(t, err)
+
+	return wfi, sequenceID
+}
@@ -0,0 +1,124 @@
+// Package containers starts MySQL and Redis in Docker containers and runs the backend test suites against
+// them. It requires the docker CLI to be available, tests are skipped otherwise.
+package containers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTimeout is how long to wait for a container to accept connections
+const startTimeout = 2 * time.Minute
+
+// container is a running Docker container
+type container struct {
+	id string
+}
+
+// runContainer starts a container from the given image in the background, publishing the given port on a
+// random host port. Returns the container and the host port.
+func runContainer(ctx context.Context, image string, port int, env map[string]string, cmd ...string) (*container, int, error) {
+	args := []string{"run", "-d", "--rm", "-p", fmt.Sprintf("127.0.0.1::%d", port)}
+	for k, v := range env {
+		args = append(args, "-e", k+"="+v)
+	}
+
+	args = append(args, image)
+	args = append(args, cmd...)
+
+	out, err := docker(ctx, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("starting %v container: %w", image, err)
+	}
+
+	c := &container{id: strings.TrimSpace(out)}
+
+	hostPort, err := c.hostPort(ctx, port)
+	if err != nil {
+		c.stop(context.Background())
+		return nil, 0, err
+	}
+
+	return c, hostPort, nil
+}
+
+// hostPort returns the host port the given container port is published on
+func (c *container) hostPort(ctx context.Context, port int) (int, error) {
+	out, err := docker(ctx, "port", c.id, fmt.Sprintf("%d/tcp", port))
+	if err != nil {
+		return 0, fmt.Errorf("getting published port: %w", err)
+	}
+
+	// Output is one or more lines of host:port
+	line := strings.SplitN(strings.TrimSpace(out), "\n", 2)[0]
+	i := strings.LastIndex(line, ":")
+	if i < 0 {
+		return 0, fmt.Errorf("unexpected port mapping %q", line)
+	}
+
+	return strconv.Atoi(line[i+1:])
+}
+
+func (c *container) stop(ctx context.Context) error {
+	if _, err := docker(ctx, "rm", "-f", c.id); err != nil {
+		return fmt.Errorf("removing container: %w", err)
+	}
+
+	return nil
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %v: %w: %v", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// waitReady calls ready until it succeeds or the start timeout expires
+func waitReady(ctx context.Context, ready func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+
+	for {
+		err := ready(ctx)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for container: %w", err)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// skipWithoutDocker skips the test if the docker CLI is not available or tests run in short mode
+func skipWithoutDocker(t *testing.T) {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip()
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not available")
+	}
+
+	if _, err := docker(context.Background(), "info"); err != nil {
+		t.Skip("docker daemon is not available: " + err.Error())
+	}
+}
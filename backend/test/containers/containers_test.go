@@ -0,0 +1,11 @@
+package containers
+
+import "testing"
+
+func Test_MySQL(t *testing.T) {
+	RunMySQLTests(t)
+}
+
+func Test_Redis(t *testing.T) {
+	RunRedisTests(t)
+}
@@ -0,0 +1,136 @@
+package containers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/mysql"
+	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/google/uuid"
+)
+
+const (
+	mysqlImage    = "mysql:8"
+	mysqlUser     = "root"
+	mysqlPassword = "root"
+)
+
+// MySQL is a MySQL server running in a Docker container
+type MySQL struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+
+	c *container
+}
+
+// StartMySQL starts a MySQL server in a Docker container and waits until it accepts connections
+func StartMySQL(ctx context.Context) (*MySQL, error) {
+	c, port, err := runContainer(ctx, mysqlImage, 3306, map[string]string{
+		"MYSQL_ROOT_PASSWORD": mysqlPassword,
+	}, "--default-authentication-plugin=mysql_native_password")
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MySQL{
+		Host:     "127.0.0.1",
+		Port:     port,
+		User:     mysqlUser,
+		Password: mysqlPassword,
+		c:        c,
+	}
+
+	if err := waitReady(ctx, func(ctx context.Context) error {
+		return m.exec(ctx, "SELECT 1")
+	}); err != nil {
+		c.stop(context.Background())
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Stop removes the container
+func (m *MySQL) Stop(ctx context.Context) error {
+	return m.c.stop(ctx)
+}
+
+// NewBackend creates a new database and returns a backend using it. Drop the database with Teardown.
+func (m *MySQL) NewBackend(options ...backend.BackendOption) (backend.Backend, string, error) {
+	dbName := "test_" + strings.Replace(uuid.NewString(), "-", "", -1)
+	if err := m.exec(context.Background(), "CREATE DATABASE "+dbName); err != nil {
+		return nil, "", fmt.Errorf("creating database: %w", err)
+	}
+
+	return mysql.NewMysqlBackend(m.Host, m.Port, m.User, m.Password, dbName, options...), dbName, nil
+}
+
+// DropDatabase drops a database created by NewBackend
+func (m *MySQL) DropDatabase(dbName string) error {
+	if err := m.exec(context.Background(), "DROP DATABASE IF EXISTS "+dbName); err != nil {
+		return fmt.Errorf("dropping database: %w", err)
+	}
+
+	return nil
+}
+
+func (m *MySQL) exec(ctx context.Context, query string) error {
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/?parseTime=true", m.User, m.Password, m.Host, m.Port))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, query)
+	return err
+}
+
+// RunMySQLTests starts a MySQL container and runs the backend and end-to-end test suites against the MySQL
+// backend. Each test uses a fresh database. Skipped if Docker is not available.
+func RunMySQLTests(t *testing.T) {
+	skipWithoutDocker(t)
+
+	m, err := StartMySQL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := m.Stop(context.Background()); err != nil {
+			t.Log(err)
+		}
+	})
+
+	var dbName string
+
+	setup := func(options ...backend.BackendOption) backend.Backend {
+		var b backend.Backend
+		var err error
+		b, dbName, err = m.NewBackend(append([]backend.BackendOption{backend.WithStickyTimeout(0), backend.WithTaskPollTimeout(0)}, options...)...)
+		if err != nil {
+			panic(err)
+		}
+
+		return b
+	}
+
+	teardown := func(b backend.Backend) {
+		if err := m.DropDatabase(dbName); err != nil {
+			panic(err)
+		}
+	}
+
+	t.Run("Backend", func(t *testing.T) {
+		test.BackendTest(t, setup, teardown)
+	})
+
+	t.Run("EndToEnd", func(t *testing.T) {
+		test.EndToEndBackendTest(t, func() backend.Backend { return setup() }, teardown)
+	})
+}
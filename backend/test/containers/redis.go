@@ -0,0 +1,110 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/redis"
+	"github.com/cschleiden/go-workflows/backend/test"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+const (
+	redisImage    = "redis:6.2-alpine"
+	redisPassword = "RedisPassw0rd"
+)
+
+// Redis is a Redis server running in a Docker container
+type Redis struct {
+	Address  string
+	Password string
+
+	c *container
+}
+
+// StartRedis starts a Redis server in a Docker container and waits until it accepts connections
+func StartRedis(ctx context.Context) (*Redis, error) {
+	c, port, err := runContainer(ctx, redisImage, 6379, nil, "redis-server", "--requirepass", redisPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Redis{
+		Address:  fmt.Sprintf("127.0.0.1:%d", port),
+		Password: redisPassword,
+		c:        c,
+	}
+
+	if err := waitReady(ctx, func(ctx context.Context) error {
+		client := r.client()
+		defer client.Close()
+
+		return client.Ping(ctx).Err()
+	}); err != nil {
+		c.stop(context.Background())
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Stop removes the container
+func (r *Redis) Stop(ctx context.Context) error {
+	return r.c.stop(ctx)
+}
+
+// NewBackend flushes the database and returns a backend using it
+func (r *Redis) NewBackend(options ...redis.RedisBackendOption) (backend.Backend, error) {
+	client := r.client()
+	defer client.Close()
+
+	if err := client.FlushDB(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("flushing database: %w", err)
+	}
+
+	return redis.NewRedisBackend(r.Address, "", r.Password, 0, options...)
+}
+
+func (r *Redis) client() goredis.UniversalClient {
+	return goredis.NewUniversalClient(&goredis.UniversalOptions{
+		Addrs:    []string{r.Address},
+		Password: r.Password,
+	})
+}
+
+// RunRedisTests starts a Redis container and runs the backend and end-to-end test suites against the Redis
+// backend. The database is flushed before each test. Skipped if Docker is not available.
+func RunRedisTests(t *testing.T) {
+	skipWithoutDocker(t)
+
+	r, err := StartRedis(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := r.Stop(context.Background()); err != nil {
+			t.Log(err)
+		}
+	})
+
+	setup := func(options ...backend.BackendOption) backend.Backend {
+		b, err := r.NewBackend(redis.WithBlockTimeout(time.Millisecond*2), redis.WithBackendOptions(options...))
+		if err != nil {
+			panic(err)
+		}
+
+		return b
+	}
+
+	t.Run("Backend", func(t *testing.T) {
+		test.BackendTest(t, setup, nil)
+	})
+
+	t.Run("EndToEnd", func(t *testing.T) {
+		test.EndToEndBackendTest(t, func() backend.Backend { return setup() }, nil)
+	})
+}
@@ -1,15 +1,22 @@
 package test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/cschleiden/go-workflows/activity"
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/worker"
 	"github.com/cschleiden/go-workflows/workflow"
@@ -36,6 +43,312 @@ func EndToEndBackendTest(t *testing.T, setup func() backend.Backend, teardown fu
 				require.NoError(t, err)
 			},
 		},
+		{
+			name: "RestartWorkflowInstance_StartsNewRun",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.RunProvider); !ok {
+					t.Skip("backend does not support multiple runs")
+				}
+
+				wf := func(ctx workflow.Context, msg string) (string, error) {
+					return msg + " world", nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				first := runWorkflow(t, ctx, c, wf, "hello")
+				_, err := client.GetWorkflowResult[string](ctx, c, first, time.Second*10)
+				require.NoError(t, err)
+
+				second, err := c.RestartWorkflowInstance(ctx, first)
+				require.NoError(t, err)
+				require.Equal(t, first.InstanceID, second.InstanceID)
+				require.NotEqual(t, first.ExecutionID, second.ExecutionID)
+
+				output, err := client.GetWorkflowResult[string](ctx, c, second, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, "hello world", output)
+
+				runs, err := c.ListWorkflowInstanceRuns(ctx, first.InstanceID)
+				require.NoError(t, err)
+				require.Len(t, runs, 2)
+				require.Equal(t, first.ExecutionID, runs[0].Instance.ExecutionID)
+				require.Equal(t, backend.WorkflowStateFinished, runs[0].State)
+				require.Equal(t, second.ExecutionID, runs[1].Instance.ExecutionID)
+
+				// The earlier run keeps its history and result
+				h, err := c.GetWorkflowRunHistory(ctx, first)
+				require.NoError(t, err)
+				require.Equal(t, history.EventType_WorkflowExecutionFinished, h[len(h)-1].Type)
+
+				output, err = client.GetWorkflowResult[string](ctx, c, first, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, "hello world", output)
+
+				current, err := c.GetWorkflowInstance(ctx, first.InstanceID)
+				require.NoError(t, err)
+				require.Equal(t, second.ExecutionID, current.ExecutionID)
+			},
+		},
+		{
+			name: "CreateWorkflowInstance_IDReusePolicy",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.RunReplacer); !ok {
+					t.Skip("backend does not support replacing runs")
+				}
+
+				wf := func(ctx workflow.Context) (string, error) {
+					msg, _ := workflow.NewSignalChannel[string](ctx, "msg").Receive(ctx)
+					return msg, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				options := client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}
+
+				first, err := c.CreateWorkflowInstance(ctx, options, wf)
+				require.NoError(t, err)
+
+				// The first run is still active
+				options.IDReusePolicy = client.IDReusePolicyAllowIfFinished
+				_, err = c.CreateWorkflowInstance(ctx, options, wf)
+				require.ErrorIs(t, err, backend.ErrInstanceAlreadyExists)
+
+				options.IDReusePolicy = client.IDReusePolicyTerminateExisting
+				second, err := c.CreateWorkflowInstance(ctx, options, wf)
+				require.NoError(t, err)
+				require.Equal(t, first.InstanceID, second.InstanceID)
+				require.NotEqual(t, first.ExecutionID, second.ExecutionID)
+
+				_, err = client.GetWorkflowResult[string](ctx, c, first, time.Second*10)
+				require.ErrorIs(t, err, client.ErrWorkflowTerminated)
+
+				require.NoError(t, c.SignalWorkflow(ctx, second.InstanceID, "msg", "done"))
+				r, err := client.GetWorkflowResult[string](ctx, c, second, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, "done", r)
+
+				options.IDReusePolicy = client.IDReusePolicyRejectDuplicate
+				_, err = c.CreateWorkflowInstance(ctx, options, wf)
+				require.ErrorIs(t, err, backend.ErrInstanceAlreadyExists)
+
+				options.IDReusePolicy = client.IDReusePolicyAllowIfFinished
+				third, err := c.CreateWorkflowInstance(ctx, options, wf)
+				require.NoError(t, err)
+
+				runs, err := c.ListWorkflowInstanceRuns(ctx, first.InstanceID)
+				require.NoError(t, err)
+				require.Len(t, runs, 3)
+				require.Equal(t, third.ExecutionID, runs[2].Instance.ExecutionID)
+			},
+		},
+		{
+			name: "Query_AnswersFromWorkflowState",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.WorkflowQuerier); !ok {
+					t.Skip("backend does not support workflow queries")
+				}
+
+				wf := func(ctx workflow.Context) (int, error) {
+					status := "waiting"
+					if err := workflow.HandleQuery(ctx, "status", func(prefix string) (string, error) {
+						return prefix + status, nil
+					}); err != nil {
+						return 0, err
+					}
+
+					workflow.NewSignalChannel[int](ctx, "signal").Receive(ctx)
+					status = "done"
+
+					return 42, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				// The query fails until the first workflow task has been executed
+				var status string
+				require.Eventually(t, func() bool {
+					var err error
+					status, err = client.QueryWorkflow[string](ctx, c, instance.InstanceID, "status", "status: ")
+					return err == nil
+				}, time.Second*10, time.Millisecond*100)
+				require.Equal(t, "status: waiting", status)
+
+				_, err := client.QueryWorkflow[string](ctx, c, instance.InstanceID, "unknown")
+				require.ErrorContains(t, err, "unknown query")
+
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "signal", 1))
+
+				output, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, 42, output)
+
+				before, err := c.GetWorkflowRunHistory(ctx, instance)
+				require.NoError(t, err)
+
+				// Finished instances can still be queried
+				status, err = client.QueryWorkflow[string](ctx, c, instance.InstanceID, "status", "status: ")
+				require.NoError(t, err)
+				require.Equal(t, "status: done", status)
+
+				// Queries are not recorded in the history
+				after, err := c.GetWorkflowRunHistory(ctx, instance)
+				require.NoError(t, err)
+				require.Equal(t, len(before), len(after))
+			},
+		},
+		{
+			name: "Query_StrongConsistencyWaitsForPendingEvents",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.WorkflowQuerier); !ok {
+					t.Skip("backend does not support workflow queries")
+				}
+				if _, ok := b.(backend.InstanceStatsProvider); !ok {
+					t.Skip("backend does not support instance stats")
+				}
+
+				wf := func(ctx workflow.Context) (int, error) {
+					received := 0
+					if err := workflow.HandleQuery(ctx, "received", func() (int, error) {
+						return received, nil
+					}); err != nil {
+						return 0, err
+					}
+
+					s := workflow.NewSignalChannel[int](ctx, "signal")
+					for received < 3 {
+						s.Receive(ctx)
+						received++
+					}
+
+					return received, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				strong := client.QueryOptions{Consistency: client.QueryConsistencyStrong}
+
+				for i := 1; i <= 2; i++ {
+					require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "signal", i))
+
+					// The signal is processed before the query is answered
+					received, err := client.QueryWorkflowWithOptions[int](ctx, c, instance.InstanceID, "received", strong)
+					require.NoError(t, err)
+					require.Equal(t, i, received)
+				}
+			},
+		},
+		{
+			name: "ContinueAsNew_StartsNewRun",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.RunProvider); !ok {
+					t.Skip("backend does not support multiple runs")
+				}
+
+				wf := func(ctx workflow.Context, n int) (int, error) {
+					if n < 3 {
+						return 0, workflow.ContinueAsNew(ctx, n+1)
+					}
+
+					return n, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf, 0)
+
+				// The result of the instance is the result of its last run
+				output, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, 3, output)
+
+				runs, err := c.ListWorkflowInstanceRuns(ctx, instance.InstanceID)
+				require.NoError(t, err)
+				require.Len(t, runs, 4)
+				require.Equal(t, instance.ExecutionID, runs[0].Instance.ExecutionID)
+
+				for i, run := range runs[:3] {
+					require.Equal(t, backend.WorkflowStateFinished, run.State)
+
+					// Every run has its own history, which ends by continuing as the next run
+					h, err := c.GetWorkflowRunHistory(ctx, run.Instance)
+					require.NoError(t, err)
+					require.Equal(t, fn.Name(wf), history.WorkflowName(h))
+					require.Equal(t, history.EventType_WorkflowExecutionContinuedAsNew, h[len(h)-1].Type)
+					a := h[len(h)-1].Attributes.(*history.ExecutionContinuedAsNewAttributes)
+					require.Equal(t, runs[i+1].Instance.ExecutionID, a.ExecutionID)
+				}
+
+				current, err := c.GetWorkflowInstance(ctx, instance.InstanceID)
+				require.NoError(t, err)
+				require.Equal(t, runs[3].Instance.ExecutionID, current.ExecutionID)
+			},
+		},
+		{
+			name: "ContinueAsNew_KeepsPendingSignals",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.RunProvider); !ok {
+					t.Skip("backend does not support multiple runs")
+				}
+
+				started := make(chan struct{})
+				signaled := make(chan struct{})
+				var once sync.Once
+
+				wf := func(ctx workflow.Context, n int) (int, error) {
+					if n == 0 {
+						// Keep the first task running until the signal has been sent, so the first run doesn't
+						// receive it anymore
+						once.Do(func() { close(started) })
+						<-signaled
+
+						return 0, workflow.ContinueAsNew(ctx, n+1)
+					}
+
+					v, _ := workflow.NewSignalChannel[int](ctx, "signal").Receive(ctx)
+					return v, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf, 0)
+
+				select {
+				case <-started:
+				case <-time.After(time.Second * 10):
+					t.Fatal("workflow did not start")
+				}
+
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "signal", 42))
+				close(signaled)
+
+				output, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, 42, output)
+			},
+		},
+		{
+			name: "ContinueAsNew_SubWorkflow",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.RunProvider); !ok {
+					t.Skip("backend does not support multiple runs")
+				}
+
+				swf := func(ctx workflow.Context, n int) (int, error) {
+					if n < 2 {
+						return 0, workflow.ContinueAsNew(ctx, n+1)
+					}
+
+					return n * 10, nil
+				}
+				wf := func(ctx workflow.Context) (int, error) {
+					return workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, swf, 0).Get(ctx)
+				}
+				register(t, ctx, w, []interface{}{wf, swf}, nil)
+
+				output, err := runWorkflowWithResult[int](t, ctx, c, wf)
+				require.NoError(t, err)
+				require.Equal(t, 20, output)
+			},
+		},
 		{
 			name: "UnregisteredWorkflow_Errors",
 			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
@@ -50,6 +363,33 @@ func EndToEndBackendTest(t *testing.T, setup func() backend.Backend, teardown fu
 				require.ErrorContains(t, err, "workflow 1 not found")
 			},
 		},
+		{
+			name: "UnregisteredWorkflow_DynamicWorkflow",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				wf := func(ctx workflow.Context, msg string) (string, error) {
+					return "", errors.New("not executed")
+				}
+
+				var name string
+				require.NoError(t, w.RegisterDynamicWorkflow(func(ctx workflow.Context, n string, inputs []converter.Payload) (converter.Payload, error) {
+					name = n
+
+					var msg string
+					if err := converter.DefaultConverter.From(inputs[0], &msg); err != nil {
+						return nil, err
+					}
+
+					return converter.DefaultConverter.To(msg + " world")
+				}))
+				register(t, ctx, w, nil, nil)
+
+				output, err := runWorkflowWithResult[string](t, ctx, c, wf, "hello")
+
+				require.NoError(t, err)
+				require.Equal(t, "hello world", output)
+				require.Equal(t, "1", name)
+			},
+		},
 		{
 			name: "WorkflowArgumentMismatch",
 			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
@@ -79,6 +419,34 @@ func EndToEndBackendTest(t *testing.T, setup func() backend.Backend, teardown fu
 				require.ErrorContains(t, err, "activity not found")
 			},
 		},
+		{
+			name: "UnregisteredActivity_DynamicActivity",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				a := func(context.Context, int) (int, error) { return 0, nil }
+				wf := func(ctx workflow.Context) (int, error) {
+					return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a, 21).Get(ctx)
+				}
+
+				var name string
+				require.NoError(t, w.RegisterDynamicActivity(func(ctx context.Context, n string, inputs []converter.Payload) (converter.Payload, error) {
+					name = n
+
+					var i int
+					if err := converter.DefaultConverter.From(inputs[0], &i); err != nil {
+						return nil, err
+					}
+
+					return converter.DefaultConverter.To(i * 2)
+				}))
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				output, err := runWorkflowWithResult[int](t, ctx, c, wf)
+
+				require.NoError(t, err)
+				require.Equal(t, 42, output)
+				require.NotEmpty(t, name)
+			},
+		},
 		{
 			name: "ActivityArgumentMismatch",
 			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
@@ -94,6 +462,230 @@ func EndToEndBackendTest(t *testing.T, setup func() backend.Backend, teardown fu
 				require.ErrorContains(t, err, "converting activity inputs: mismatched argument count: expected 2, got 1")
 			},
 		},
+		{
+			name: "ActivityFailure_Metadata",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				a := func(context.Context) (int, error) { return 0, errors.New("activity failed") }
+				wf := func(ctx workflow.Context) (int, error) {
+					r, err := workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+					if err != nil {
+						return 0, fmt.Errorf("running activity: %w", err)
+					}
+
+					return r, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+				_, err := runWorkflowWithResult[int](t, ctx, c, wf)
+				require.EqualError(t, err, "running activity: activity failed")
+
+				var f *workflow.Failure
+				require.ErrorAs(t, err, &f)
+				require.NotNil(t, f.Cause)
+				require.NotEmpty(t, f.Cause.ActivityName)
+				require.Equal(t, workflow.DefaultRetryOptions.MaxAttempts, f.Cause.Attempt)
+				require.NotNil(t, f.Cause.FirstAttemptAt)
+			},
+		},
+		{
+			name: "LocalActivity_RecordsMarker",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				executions := int32(0)
+
+				a := func(ctx context.Context, n int) (int, error) {
+					atomic.AddInt32(&executions, 1)
+					return n * 2, nil
+				}
+				wf := func(ctx workflow.Context) (int, error) {
+					r, err := workflow.ExecuteLocalActivity[int](ctx, workflow.DefaultLocalActivityOptions, a, 21).Get(ctx)
+					if err != nil {
+						return 0, err
+					}
+
+					// Continue in another workflow task
+					if _, err := workflow.ScheduleTimer(ctx, time.Millisecond).Get(ctx); err != nil {
+						return 0, err
+					}
+
+					return r, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, 42, r)
+				require.Equal(t, int32(1), atomic.LoadInt32(&executions))
+
+				h, err := c.GetWorkflowRunHistory(ctx, instance)
+				require.NoError(t, err)
+
+				markers := 0
+				for _, e := range h {
+					require.NotEqual(t, history.EventType_ActivityScheduled, e.Type)
+
+					if e.Type == history.EventType_MarkerRecorded {
+						markers++
+					}
+				}
+				require.Equal(t, 1, markers)
+			},
+		},
+		{
+			name: "ActivityFailure_TypedError",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				executions := int32(0)
+
+				a := func(context.Context) (int, error) {
+					atomic.AddInt32(&executions, 1)
+					return 0, workflow.NewError("InsufficientFunds", "insufficient funds", 12)
+				}
+				wf := func(ctx workflow.Context) (int, error) {
+					_, err := workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{
+						RetryOptions: workflow.RetryOptions{
+							MaxAttempts:            3,
+							NonRetryableErrorTypes: []string{"InsufficientFunds"},
+						},
+					}, a).Get(ctx)
+
+					var werr *workflow.Error
+					if !errors.As(err, &werr) || werr.Type != "InsufficientFunds" {
+						return 0, fmt.Errorf("unexpected error: %w", err)
+					}
+
+					var available int
+					if err := werr.Details(&available); err != nil {
+						return 0, err
+					}
+
+					return 0, workflow.NewError("PaymentFailed", "payment failed", available, "retry later")
+				}
+				register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+				_, err := runWorkflowWithResult[int](t, ctx, c, wf)
+				require.EqualError(t, err, "payment failed")
+				require.Equal(t, int32(1), atomic.LoadInt32(&executions))
+
+				var werr *workflow.Error
+				require.ErrorAs(t, err, &werr)
+				require.Equal(t, "PaymentFailed", werr.Type)
+				require.NotEmpty(t, werr.StackTrace)
+
+				var available int
+				var hint string
+				require.NoError(t, werr.Details(&available, &hint))
+				require.Equal(t, 12, available)
+				require.Equal(t, "retry later", hint)
+			},
+		},
+		{
+			name: "MemoizedActivity_ExecutesOnce",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.ActivityResultCache); !ok {
+					t.Skip("backend does not support caching activity results")
+				}
+
+				executions := int32(0)
+
+				a := func(ctx context.Context, i int) (int, error) {
+					atomic.AddInt32(&executions, 1)
+					return i * 2, nil
+				}
+				wf := func(ctx workflow.Context) (int, error) {
+					options := workflow.DefaultActivityOptions
+					options.MemoizeFor = time.Minute
+
+					r1, err := workflow.ExecuteActivity[int](ctx, options, a, 21).Get(ctx)
+					if err != nil {
+						return 0, err
+					}
+
+					r2, err := workflow.ExecuteActivity[int](ctx, options, a, 21).Get(ctx)
+					if err != nil {
+						return 0, err
+					}
+
+					return r1 + r2, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+				output, err := runWorkflowWithResult[int](t, ctx, c, wf)
+
+				require.NoError(t, err)
+				require.Equal(t, 84, output)
+				require.Equal(t, int32(1), atomic.LoadInt32(&executions))
+			},
+		},
+		{
+			name: "WorkflowQueues_RouteToDedicatedWorkers",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.WorkflowQueueProvider); !ok {
+					t.Skip("backend does not support workflow queues")
+				}
+
+				swf := func(ctx workflow.Context, i int) (int, error) {
+					return i * 2, nil
+				}
+				wf := func(ctx workflow.Context) (int, error) {
+					// Sub-workflows are placed on the queue of their parent
+					return workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, swf, 21).Get(ctx)
+				}
+
+				// The default worker polls the default queue only, it has nothing registered and would fail the tasks
+				register(t, ctx, w, nil, nil)
+
+				options := worker.DefaultWorkerOptions
+				options.WorkflowQueues = []string{"heavy"}
+				hw := worker.New(b, &options)
+				register(t, ctx, hw, []interface{}{wf, swf}, nil)
+
+				instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+					InstanceID: uuid.NewString(),
+					Queue:      "heavy",
+				}, wf)
+				require.NoError(t, err)
+
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, 42, r)
+			},
+		},
+		{
+			name: "Worker_Shutdown_DrainsActivities",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				started := make(chan struct{})
+				finished := int32(0)
+
+				a := func(ctx context.Context) (int, error) {
+					close(started)
+					time.Sleep(time.Millisecond * 200)
+					atomic.StoreInt32(&finished, 1)
+
+					return 42, nil
+				}
+				wf := func(ctx workflow.Context) (int, error) {
+					return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+				}
+				register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				<-started
+
+				sctx, cancel := context.WithTimeout(ctx, time.Second*5)
+				defer cancel()
+				require.NoError(t, w.Shutdown(sctx))
+				require.Equal(t, int32(1), atomic.LoadInt32(&finished))
+
+				// Another worker picks up the workflow right away, the activity result is not lost
+				w2 := worker.New(b, &worker.DefaultWorkerOptions)
+				register(t, ctx, w2, []interface{}{wf}, []interface{}{a})
+
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*5)
+				require.NoError(t, err)
+				require.Equal(t, 42, r)
+			},
+		},
 		{
 			name: "SubWorkflow_PropagateCancellation",
 			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
@@ -191,6 +783,400 @@ func EndToEndBackendTest(t *testing.T, setup func() backend.Backend, teardown fu
 				require.Equal(t, backend.ErrInstanceNotFound, err)
 			},
 		},
+		{
+			name: "SubWorkflow_ParentClosePolicy_Abandon",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				finished := int32(0)
+
+				swf := func(ctx workflow.Context) error {
+					workflow.NewSignalChannel[int](ctx, "finish").Receive(ctx)
+					atomic.StoreInt32(&finished, 1)
+
+					return nil
+				}
+				wf := func(ctx workflow.Context) error {
+					workflow.CreateSubWorkflowInstance[any](ctx, workflow.SubWorkflowOptions{
+						InstanceID:        "abandoned",
+						ParentClosePolicy: workflow.ParentClosePolicyAbandon,
+					}, swf)
+
+					return nil
+				}
+				register(t, ctx, w, []interface{}{wf, swf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+				_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+
+				// The sub-workflow keeps running after the parent finished
+				require.Eventually(t, func() bool {
+					return c.SignalWorkflow(ctx, "abandoned", "finish", 1) == nil
+				}, time.Second*10, time.Millisecond*50)
+
+				require.Eventually(t, func() bool {
+					return atomic.LoadInt32(&finished) == 1
+				}, time.Second*10, time.Millisecond*50)
+			},
+		},
+		{
+			name: "SubWorkflow_ParentClosePolicy_RequestCancel",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				canceled := int32(0)
+
+				swf := func(ctx workflow.Context) error {
+					if err := workflow.Sleep(ctx, time.Hour); err == workflow.Canceled {
+						atomic.StoreInt32(&canceled, 1)
+					}
+
+					return nil
+				}
+				wf := func(ctx workflow.Context) error {
+					workflow.CreateSubWorkflowInstance[any](ctx, workflow.SubWorkflowOptions{
+						InstanceID:        "canceled",
+						ParentClosePolicy: workflow.ParentClosePolicyRequestCancel,
+					}, swf)
+
+					return nil
+				}
+				register(t, ctx, w, []interface{}{wf, swf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+				_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+
+				// The sub-workflow is canceled and can clean up
+				require.Eventually(t, func() bool {
+					return atomic.LoadInt32(&canceled) == 1
+				}, time.Second*10, time.Millisecond*50)
+			},
+		},
+		{
+			name: "SubWorkflow_ParentClosePolicy_Terminate",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				var subInstance atomic.Value
+				resumed := int32(0)
+
+				swf := func(ctx workflow.Context) error {
+					subInstance.Store(workflow.WorkflowInstance(ctx))
+
+					workflow.Sleep(ctx, time.Hour)
+					atomic.StoreInt32(&resumed, 1)
+
+					return nil
+				}
+				wf := func(ctx workflow.Context) error {
+					workflow.CreateSubWorkflowInstance[any](ctx, workflow.SubWorkflowOptions{
+						InstanceID:        "terminated",
+						ParentClosePolicy: workflow.ParentClosePolicyTerminate,
+					}, swf)
+
+					return nil
+				}
+				register(t, ctx, w, []interface{}{wf, swf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+				_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+
+				// The sub-workflow is finished without running any more of its code
+				require.Eventually(t, func() bool {
+					i, ok := subInstance.Load().(*workflow.Instance)
+					if !ok {
+						return false
+					}
+
+					state, err := b.GetWorkflowInstanceState(ctx, i)
+					return err == nil && state == backend.WorkflowStateFinished
+				}, time.Second*10, time.Millisecond*50)
+
+				h, err := b.GetWorkflowInstanceHistory(ctx, subInstance.Load().(*workflow.Instance), nil)
+				require.NoError(t, err)
+				require.Equal(t, history.EventType_WorkflowExecutionTerminated, h[len(h)-1].Type)
+				require.Equal(t, int32(0), atomic.LoadInt32(&resumed))
+			},
+		},
+		{
+			name: "RequestResponse_BetweenInstances",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				server := func(ctx workflow.Context) error {
+					req, _ := workflow.NewRequestChannel[int](ctx, "double").Receive(ctx)
+
+					_, err := workflow.Reply(ctx, req, req.Arg*2, nil).Get(ctx)
+					return err
+				}
+				requester := func(ctx workflow.Context, serverInstanceID string) (int, error) {
+					return workflow.SendRequest[int](ctx, serverInstanceID, "double", 21, workflow.RequestOptions{
+						Timeout: time.Second * 10,
+					}).Get(ctx)
+				}
+				register(t, ctx, w, []interface{}{server, requester}, nil)
+
+				serverInstance := runWorkflow(t, ctx, c, server)
+
+				output, err := runWorkflowWithResult[int](t, ctx, c, requester, serverInstance.InstanceID)
+				require.NoError(t, err)
+				require.Equal(t, 42, output)
+
+				_, err = client.GetWorkflowResult[any](ctx, c, serverInstance, time.Second*5)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "SignalWorkflow_UnknownInstance_Errors",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				wf := func(ctx workflow.Context) error {
+					_, err := workflow.SignalWorkflow(ctx, "unknown-instance", "signal", 42).Get(ctx)
+					return err
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				_, err := runWorkflowWithResult[any](t, ctx, c, wf)
+				require.ErrorContains(t, err, backend.ErrInstanceNotFound.Error())
+			},
+		},
+		{
+			name: "CancelAfter_CancelsWorkflow",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				wf := func(ctx workflow.Context) (string, error) {
+					_, err := workflow.ScheduleTimer(ctx, time.Second*30).Get(ctx)
+					if err != nil && err != workflow.Canceled {
+						return "", err
+					}
+
+					if ctx.Err() == workflow.Canceled {
+						return "canceled", nil
+					}
+
+					return "timer fired", nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+					InstanceID:  uuid.NewString(),
+					CancelAfter: time.Millisecond * 200,
+				}, wf)
+				require.NoError(t, err)
+
+				r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, "canceled", r)
+			},
+		},
+		{
+			name: "TerminateWorkflowInstance_FailsParent",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.InstanceTerminator); !ok {
+					t.Skip("backend does not support terminating instances")
+				}
+
+				childID := uuid.NewString()
+
+				swf := func(ctx workflow.Context) (int, error) {
+					_, err := workflow.ScheduleTimer(ctx, time.Second*30).Get(ctx)
+					return 42, err
+				}
+				wf := func(ctx workflow.Context) (string, error) {
+					_, err := workflow.CreateSubWorkflowInstance[int](ctx, workflow.SubWorkflowOptions{
+						InstanceID:   childID,
+						RetryOptions: workflow.RetryOptions{MaxAttempts: 1},
+					}, swf).Get(ctx)
+					if err != nil {
+						return err.Error(), nil
+					}
+
+					return "completed", nil
+				}
+				register(t, ctx, w, []interface{}{wf, swf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				var child *workflow.Instance
+				require.Eventually(t, func() bool {
+					var err error
+					child, err = c.GetWorkflowInstance(ctx, childID)
+					return err == nil
+				}, time.Second*10, time.Millisecond*10)
+
+				require.NoError(t, c.TerminateWorkflowInstance(ctx, child, "stuck"))
+
+				r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, "workflow terminated: stuck", r)
+
+				_, err = client.GetWorkflowResult[int](ctx, c, child, time.Second*10)
+				require.ErrorIs(t, err, client.ErrWorkflowTerminated)
+
+				err = c.TerminateWorkflowInstance(ctx, child, "again")
+				require.ErrorIs(t, err, backend.ErrInstanceFinished)
+			},
+		},
+		{
+			name: "CronSchedule_PauseResumeDelete",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				var runs int32
+
+				wf := func(ctx workflow.Context, step int32) error {
+					atomic.AddInt32(&runs, step)
+					return nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instanceID := uuid.NewString()
+				instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+					InstanceID:   instanceID,
+					CronSchedule: "@every 1s",
+				}, wf, int32(1))
+				require.NoError(t, err)
+
+				require.Eventually(t, func() bool {
+					return atomic.LoadInt32(&runs) >= 2
+				}, time.Second*10, time.Millisecond*10)
+
+				require.NoError(t, c.PauseSchedule(ctx, instanceID))
+
+				// Wait for the pause to be processed and a run in progress to finish
+				time.Sleep(time.Second)
+				paused := atomic.LoadInt32(&runs)
+				time.Sleep(time.Second * 2)
+				require.Equal(t, paused, atomic.LoadInt32(&runs))
+
+				require.NoError(t, c.ResumeSchedule(ctx, instanceID))
+				require.Eventually(t, func() bool {
+					return atomic.LoadInt32(&runs) > paused
+				}, time.Second*10, time.Millisecond*10)
+
+				require.NoError(t, c.DeleteSchedule(ctx, instanceID))
+				require.NoError(t, c.WaitForWorkflowInstance(ctx, instance, time.Second*10))
+			},
+		},
+		{
+			name: "State_SharedBetweenInstances",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.StateStore); !ok {
+					t.Skip("backend does not store state")
+				}
+
+				space := uuid.NewString()
+
+				wf := func(ctx workflow.Context, msg string) (string, error) {
+					if _, err := workflow.IncrementState(ctx, space, "runs", 1).Get(ctx); err != nil {
+						return "", err
+					}
+
+					last, err := workflow.GetState[string](ctx, space, "last").Get(ctx)
+					if err != nil {
+						return "", err
+					}
+
+					if _, err := workflow.SetState(ctx, space, "last", msg).Get(ctx); err != nil {
+						return "", err
+					}
+
+					return last, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				output, err := runWorkflowWithResult[string](t, ctx, c, wf, "hello")
+				require.NoError(t, err)
+				require.Equal(t, "", output)
+
+				output, err = runWorkflowWithResult[string](t, ctx, c, wf, "world")
+				require.NoError(t, err)
+				require.Equal(t, "hello", output)
+
+				runs, found, err := b.(backend.StateStore).GetState(ctx, space, "runs")
+				require.NoError(t, err)
+				require.True(t, found)
+				require.Equal(t, "2", string(runs))
+			},
+		},
+		{
+			name: "Stream_LargeActivityResult",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				if _, ok := b.(backend.StateStore); !ok {
+					t.Skip("backend does not store state")
+				}
+
+				data := bytes.Repeat([]byte("0123456789"), 100_000)
+
+				a := func(ctx context.Context) (workflow.Stream, error) {
+					s, err := activity.NewStream(ctx)
+					if err != nil {
+						return workflow.Stream{}, err
+					}
+
+					if _, err := s.Write(data); err != nil {
+						return workflow.Stream{}, err
+					}
+
+					if err := s.Close(); err != nil {
+						return workflow.Stream{}, err
+					}
+
+					return s.Stream(), nil
+				}
+				wf := func(ctx workflow.Context) (workflow.Stream, error) {
+					s, err := workflow.ExecuteActivity[workflow.Stream](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+					if err != nil {
+						return workflow.Stream{}, err
+					}
+
+					chunk, err := workflow.ReadStreamChunk(ctx, s, 0).Get(ctx)
+					if err != nil {
+						return workflow.Stream{}, err
+					}
+
+					if !bytes.HasPrefix(data, chunk) {
+						return workflow.Stream{}, errors.New("unexpected chunk")
+					}
+
+					return s, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+				s, err := runWorkflowWithResult[workflow.Stream](t, ctx, c, wf)
+				require.NoError(t, err)
+				require.Equal(t, int64(len(data)), s.Size)
+				require.Greater(t, s.Chunks, 1)
+
+				r, err := c.OpenStream(ctx, s)
+				require.NoError(t, err)
+
+				read, err := io.ReadAll(r)
+				require.NoError(t, err)
+				require.Equal(t, data, read)
+			},
+		},
+		{
+			name: "PauseActivities_StopsActivityPolling",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
+				var executed int32
+				a := func(ctx context.Context) (int, error) {
+					atomic.AddInt32(&executed, 1)
+					return 42, nil
+				}
+				wf := func(ctx workflow.Context) (int, error) {
+					return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+				}
+				register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+				pauseCtx, cancel := context.WithTimeout(ctx, time.Second*5)
+				defer cancel()
+				require.NoError(t, w.PauseActivities(pauseCtx))
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				_, err := client.GetWorkflowResult[int](ctx, c, instance, time.Millisecond*500)
+				require.ErrorIs(t, err, client.ErrTimeout)
+				require.Equal(t, int32(0), atomic.LoadInt32(&executed))
+
+				w.ResumeActivities()
+
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, 42, r)
+			},
+		},
 		{
 			name: "Timer_CancelBeforeStarting",
 			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b backend.Backend) {
@@ -0,0 +1,136 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+var ErrThrottled = errors.New("workflow instance creation throttled")
+
+// ThrottledError is returned by CreateWorkflowInstance when an InstanceCreationLimit is exceeded. It matches
+// ErrThrottled when using errors.Is.
+type ThrottledError struct {
+	// WorkflowPrefix is the prefix of the limit that has been exceeded
+	WorkflowPrefix string
+
+	// RetryAfter is the time after which creating the instance is allowed again
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", ErrThrottled.Error(), e.RetryAfter)
+}
+
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrThrottled
+}
+
+// InstanceCreationLimit limits the rate at which workflow instances are created
+type InstanceCreationLimit struct {
+	// WorkflowPrefix restricts the limit to workflows with a name starting with the prefix. An empty prefix
+	// applies the limit to all workflow instances.
+	WorkflowPrefix string
+
+	// Rate is the number of instances that can be created per second
+	Rate float64
+
+	// Burst is the number of instances that can be created at once, before Rate applies. Defaults to 1.
+	Burst int
+}
+
+// InstanceCreationThrottler enforces InstanceCreationLimits for a backend. Limits are tracked in memory, so they
+// apply per backend instance.
+type InstanceCreationThrottler struct {
+	mu      sync.Mutex
+	buckets []*tokenBucket
+	now     func() time.Time
+}
+
+type tokenBucket struct {
+	limit  InstanceCreationLimit
+	tokens float64
+	last   time.Time
+}
+
+func NewInstanceCreationThrottler(limits []InstanceCreationLimit) *InstanceCreationThrottler {
+	return newInstanceCreationThrottler(limits, time.Now)
+}
+
+func newInstanceCreationThrottler(limits []InstanceCreationLimit, now func() time.Time) *InstanceCreationThrottler {
+	t := &InstanceCreationThrottler{
+		now: now,
+	}
+
+	for _, limit := range limits {
+		if limit.Burst <= 0 {
+			limit.Burst = 1
+		}
+
+		t.buckets = append(t.buckets, &tokenBucket{
+			limit:  limit,
+			tokens: float64(limit.Burst),
+			last:   now(),
+		})
+	}
+
+	return t
+}
+
+// Allow returns a ThrottledError if creating the workflow instance started by event exceeds any of the
+// configured limits. Otherwise the creation is counted against all matching limits.
+func (t *InstanceCreationThrottler) Allow(event history.Event) error {
+	if t == nil || len(t.buckets) == 0 {
+		return nil
+	}
+
+	var name string
+	if a, ok := event.Attributes.(*history.ExecutionStartedAttributes); ok {
+		name = a.Name
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+
+	matching := make([]*tokenBucket, 0, len(t.buckets))
+	for _, b := range t.buckets {
+		if !strings.HasPrefix(name, b.limit.WorkflowPrefix) {
+			continue
+		}
+
+		b.refill(now)
+		if b.tokens < 1 {
+			return &ThrottledError{
+				WorkflowPrefix: b.limit.WorkflowPrefix,
+				RetryAfter:     time.Duration(math.Ceil((1 - b.tokens) / b.limit.Rate * float64(time.Second))),
+			}
+		}
+
+		matching = append(matching, b)
+	}
+
+	// Only take tokens once all limits allow the creation
+	for _, b := range matching {
+		b.tokens--
+	}
+
+	return nil
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last)
+	b.last = now
+
+	if elapsed <= 0 {
+		return
+	}
+
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed.Seconds()*b.limit.Rate)
+}
@@ -0,0 +1,38 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_InstanceCreationThrottler(t *testing.T) {
+	now := time.Now()
+	th := newInstanceCreationThrottler([]InstanceCreationLimit{
+		{WorkflowPrefix: "scale", Rate: 2, Burst: 2},
+	}, func() time.Time { return now })
+
+	started := func(name string) history.Event {
+		return history.NewPendingEvent(now, history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: name})
+	}
+
+	require.NoError(t, th.Allow(started("scaleStarter")))
+	require.NoError(t, th.Allow(started("scaleStarter")))
+
+	err := th.Allow(started("scaleStarter"))
+	require.ErrorIs(t, err, ErrThrottled)
+
+	var throttledErr *ThrottledError
+	require.True(t, errors.As(err, &throttledErr))
+	require.Equal(t, "scale", throttledErr.WorkflowPrefix)
+	require.Equal(t, 500*time.Millisecond, throttledErr.RetryAfter)
+
+	// Other workflows are not limited
+	require.NoError(t, th.Allow(started("otherWorkflow")))
+
+	now = now.Add(500 * time.Millisecond)
+	require.NoError(t, th.Allow(started("scaleStarter")))
+}
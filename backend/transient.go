@@ -0,0 +1,25 @@
+package backend
+
+import "errors"
+
+// ErrTransient is matched by errors of backend operations that failed without effect and can be retried as is,
+// for example, because of a deadlock or a database that stayed locked
+var ErrTransient = errors.New("transient backend error")
+
+// TransientError marks an error as transient. It matches ErrTransient when using errors.Is, and unwraps to the
+// underlying error.
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TransientError) Is(target error) bool {
+	return target == ErrTransient
+}
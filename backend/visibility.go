@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+// VisibilityStore lists, searches, and counts workflow instances. It's separate from the Backend executing
+// workflows, so heavy list and search traffic can be served by a dedicated store, for example, a read replica or
+// a search index fed by a HistoryExporter, and never competes with task dispatch. Visibility stores may lag
+// behind the backend.
+type VisibilityStore interface {
+	// ListWorkflowInstances returns the workflow instances matching the given filter, oldest first
+	ListWorkflowInstances(ctx context.Context, filter InstanceFilter) ([]*core.WorkflowInstance, error)
+
+	// CountWorkflowInstances returns the number of workflow instances matching the given filter
+	CountWorkflowInstances(ctx context.Context, filter InstanceFilter) (int64, error)
+}
+
+// VisibilityProvider is an optional interface a backend can implement to provide a visibility store for its
+// workflow instances
+type VisibilityProvider interface {
+	VisibilityStore() VisibilityStore
+}
+
+// DefaultVisibilityStore returns the visibility store of the given backend if it implements VisibilityProvider.
+// Otherwise, if the backend implements InstanceLister, it returns a store listing instances with the backend and
+// counting the listed instances. Returns nil if the backend supports neither.
+func DefaultVisibilityStore(b Backend) VisibilityStore {
+	if vp, ok := b.(VisibilityProvider); ok {
+		return vp.VisibilityStore()
+	}
+
+	if l, ok := b.(InstanceLister); ok {
+		return &listerVisibilityStore{l: l}
+	}
+
+	return nil
+}
+
+type listerVisibilityStore struct {
+	l InstanceLister
+}
+
+func (s *listerVisibilityStore) ListWorkflowInstances(ctx context.Context, filter InstanceFilter) ([]*core.WorkflowInstance, error) {
+	return s.l.ListWorkflowInstances(ctx, filter)
+}
+
+func (s *listerVisibilityStore) CountWorkflowInstances(ctx context.Context, filter InstanceFilter) (int64, error) {
+	instances, err := s.l.ListWorkflowInstances(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(instances)), nil
+}
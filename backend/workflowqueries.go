@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"context"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// WorkflowQuery asks a workflow instance for its current state, see workflow.HandleQuery
+type WorkflowQuery struct {
+	ID string `json:"id"`
+
+	// Instance is the execution of the workflow instance to query
+	Instance *core.WorkflowInstance `json:"instance"`
+
+	// Name is the name of the query handler
+	Name string `json:"name"`
+
+	Inputs []payload.Payload `json:"inputs,omitempty"`
+
+	// Deadline is when the client stops waiting for the result. Queries not answered by then are dropped.
+	Deadline time.Time `json:"deadline"`
+}
+
+// WorkflowQueryResult is the answer of a worker to a query
+type WorkflowQueryResult struct {
+	Result payload.Payload `json:"result,omitempty"`
+
+	// Error is set if the query could not be answered, for example because the workflow has no handler for it
+	Error string `json:"error,omitempty"`
+}
+
+// WorkflowQuerier is an optional interface a backend can implement to pass queries from clients to workers.
+// Queries are answered by workers from the state of the workflow, they are never recorded in the history.
+type WorkflowQuerier interface {
+	// AddWorkflowQuery stores a query to be answered by a worker
+	AddWorkflowQuery(ctx context.Context, query *WorkflowQuery) error
+
+	// GetWorkflowQuery returns a pending query for a worker to answer, or nil if there is none. Queries past
+	// their deadline are not returned.
+	GetWorkflowQuery(ctx context.Context) (*WorkflowQuery, error)
+
+	// CompleteWorkflowQuery stores the result of the query with the given ID
+	CompleteWorkflowQuery(ctx context.Context, queryID string, result *WorkflowQueryResult) error
+
+	// GetWorkflowQueryResult returns the result of the query with the given ID and removes the query, or nil if it
+	// has not been answered yet
+	GetWorkflowQueryResult(ctx context.Context, queryID string) (*WorkflowQueryResult, error)
+}
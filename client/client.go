@@ -2,8 +2,13 @@ package client
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/benbjohnson/clock"
@@ -11,111 +16,1191 @@ import (
 	a "github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/cron"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/stream"
+	"github.com/cschleiden/go-workflows/internal/tracing"
+	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/trace"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
 )
 
 var ErrWorkflowCanceled = errors.New("workflow canceled")
 var ErrWorkflowTerminated = errors.New("workflow terminated")
+var ErrTransactionsNotSupported = errors.New("backend does not support creating workflow instances in a transaction")
+var ErrStatsNotSupported = errors.New("backend does not support workflow instance statistics")
+var ErrForceCompleteNotSupported = errors.New("backend does not support force-completing workflow instances")
+var ErrTerminateNotSupported = errors.New("backend does not support terminating workflow instances")
+var ErrRemoveNotSupported = errors.New("backend does not support removing workflow instances")
+var ErrWorkflowNotFinished = errors.New("workflow instance has not finished")
+var ErrTagsNotSupported = errors.New("backend does not support looking up workflow instances by tags")
+var ErrListingNotSupported = errors.New("backend does not support listing workflow instances")
+var ErrQueryNotSupported = errors.New("backend does not support querying workflow instances")
+var ErrLookupNotSupported = errors.New("backend does not support looking up workflow instances by instance ID")
+var ErrRunsNotSupported = errors.New("backend does not support multiple runs of workflow instances")
+var ErrStreamsNotSupported = errors.New("backend does not support streams")
+var ErrWorkflowQueriesNotSupported = errors.New("backend does not support workflow queries")
+var ErrQueryConsistencyNotSupported = errors.New("backend does not support strongly consistent workflow queries")
+var ErrWorkflowQueuesNotSupported = errors.New("backend does not support workflow queues")
+var ErrIDReusePolicyNotSupported = errors.New("backend does not support the instance ID reuse policy")
+
+// ErrTimeout is returned when a workflow instance did not finish within the timeout while waiting for it
+var ErrTimeout = errors.New("workflow did not finish in specified timeout")
+
+// ErrQueryTimeout is returned when no worker answered a workflow query in time, or, for queries with
+// QueryConsistencyStrong, the events of the instance were not processed in time
+var ErrQueryTimeout = errors.New("workflow query was not answered in time")
+
+const (
+	// defaultQueryTimeout is how long QueryWorkflowPayload waits for an answer if the context has no deadline
+	defaultQueryTimeout = 10 * time.Second
+
+	// queryPollInterval is how often QueryWorkflowPayload checks for an answer, and for pending events of the
+	// instance with QueryConsistencyStrong
+	queryPollInterval = 50 * time.Millisecond
+)
 
 type WorkflowInstanceOptions struct {
 	InstanceID string
+
+	// ReturnExisting makes creating a workflow instance idempotent. If an instance with the same InstanceID
+	// already exists, the existing instance is returned instead of an error. This allows safely retrying
+	// requests to start a workflow.
+	ReturnExisting bool
+
+	// IDReusePolicy determines whether an instance can be created with the InstanceID of an existing instance.
+	// Reusing the ID starts a new run of the existing instance, which requires a backend supporting multiple runs.
+	// Defaults to IDReusePolicyRejectDuplicate.
+	IDReusePolicy IDReusePolicy
+
+	// Priority of the workflow instance. When there is a backlog, workflow and activity tasks of instances with
+	// a higher priority are dispatched first. Sub-workflows inherit the priority of their parent. Defaults to 0.
+	Priority int
+
+	// Tags of the workflow instance, e.g., "release:2024-06". Instances can be looked up, canceled, and signaled
+	// by their tags if the backend supports it. Workflows can add more tags using workflow.AddTags.
+	Tags []string
+
+	// CancelAfter cancels the workflow instance automatically if it's still running after the given duration,
+	// like calling CancelWorkflowInstance. 0 disables the automatic cancellation.
+	CancelAfter time.Duration
+
+	// Queue is the workflow queue to place the instance on. Only workers polling that queue execute its workflow
+	// tasks, see the WorkflowQueues worker option. Sub-workflows are placed on the same queue unless their
+	// options say otherwise. Defaults to the default queue.
+	Queue string
+
+	// CronSchedule runs the workflow repeatedly on a cron schedule, like "0 * * * *" or "@daily", see
+	// workflow.CronOptions. The instance runs the schedule, every run of the workflow is a sub-workflow of it
+	// started with the same arguments. Use PauseSchedule, ResumeSchedule, and DeleteSchedule to manage the
+	// schedule. The instance only finishes once the schedule is deleted.
+	CronSchedule string
+}
+
+// IDReusePolicy determines what happens when creating a workflow instance with the instance ID of an existing
+// instance
+type IDReusePolicy int
+
+const (
+	// IDReusePolicyRejectDuplicate fails with an InstanceAlreadyExistsError if an instance with the same instance
+	// ID exists, whether it's still running or has finished
+	IDReusePolicyRejectDuplicate IDReusePolicy = iota
+
+	// IDReusePolicyAllowIfFinished starts a new run if the existing instance has finished. It fails with an
+	// InstanceAlreadyExistsError if the existing instance is still running.
+	IDReusePolicyAllowIfFinished
+
+	// IDReusePolicyTerminateExisting terminates the existing instance if it's still running and starts a new run
+	IDReusePolicyTerminateExisting
+)
+
+// ForceCompleteOptions describe how a workflow instance is force-completed
+type ForceCompleteOptions struct {
+	// Result is recorded as the result of the workflow instance
+	Result interface{}
+
+	// Error, if set, is recorded as the error the workflow instance failed with. Result is ignored.
+	Error error
+
+	// Reason is stored with the history event for auditing
+	Reason string
+}
+
+// QueryConsistency determines which state of a workflow instance a query is answered from
+type QueryConsistency int
+
+const (
+	// QueryConsistencyCached answers the query right away from the state of the instance after its last completed
+	// workflow task. Events waiting for a workflow task, like signals sent just before the query, are not
+	// reflected yet. This is the default.
+	QueryConsistencyCached QueryConsistency = iota
+
+	// QueryConsistencyStrong waits until workflow tasks have processed the pending events of the instance, like
+	// signals sent before the query, and then answers the query. Events that only become visible in the future,
+	// like timers that haven't fired, are not waited for. Queries for instances that keep receiving events may
+	// time out waiting.
+	QueryConsistencyStrong
+)
+
+// QueryOptions configure a single workflow query
+type QueryOptions struct {
+	// Consistency determines which state of the instance the query is answered from. Defaults to
+	// QueryConsistencyCached.
+	Consistency QueryConsistency
+}
+
+// SignalReport describes the outcome of signaling multiple workflow instances
+type SignalReport struct {
+	// Delivered are the instances the signal was delivered to
+	Delivered []*workflow.Instance
+
+	// Failed are the instances the signal could not be delivered to
+	Failed []SignalFailure
+}
+
+// Signal is a signal delivered with SignalWorkflowBatch
+type Signal struct {
+	Name string
+	Arg  interface{}
+}
+
+// CancelReport describes the outcome of canceling multiple workflow instances
+type CancelReport struct {
+	// Canceled are the instances that were canceled
+	Canceled []*workflow.Instance
+
+	// Failed are the instances that could not be canceled
+	Failed []InstanceFailure
+}
+
+// InstanceFailure is an instance an operation on multiple workflow instances failed for
+type InstanceFailure struct {
+	Instance *workflow.Instance
+	Err      error
+}
+
+// SignalFailure is an instance a signal could not be delivered to
+type SignalFailure = InstanceFailure
+
+type Client interface {
+	CreateWorkflowInstance(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (*workflow.Instance, error)
+
+	// CreateWorkflowInstanceTx creates a new workflow instance as part of the given transaction. The instance is
+	// only started once the transaction is committed. Only supported by the SQL backends, returns
+	// ErrTransactionsNotSupported otherwise.
+	CreateWorkflowInstanceTx(ctx context.Context, tx *sql.Tx, options WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (*workflow.Instance, error)
+
+	// NotifyWorkflowCreated wakes up workers of this process waiting for workflow tasks. Call it after committing
+	// a transaction used with CreateWorkflowInstanceTx, otherwise workers only pick up the new instances when they
+	// check for tasks again, after up to the MaxTimerSkew of the backend.
+	NotifyWorkflowCreated()
+
+	CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance) error
+
+	// ListWorkflowInstancesByTags returns all workflow instances, active and finished, that have all of the given
+	// tags. Returns ErrTagsNotSupported if the backend does not support it.
+	ListWorkflowInstancesByTags(ctx context.Context, tags ...string) ([]*workflow.Instance, error)
+
+	// CancelWorkflowInstancesByTags cancels all active workflow instances that have all of the given tags and
+	// returns the number of canceled instances. Returns ErrTagsNotSupported if the backend does not support it.
+	CancelWorkflowInstancesByTags(ctx context.Context, tags ...string) (int, error)
+
+	// RestartWorkflowInstance starts a new run of the given finished instance with the same workflow, inputs, and
+	// priority. The new run has the same instance ID and a new execution ID. For backends that store a single
+	// execution per instance ID, a new instance with a new instance ID is started instead. Returns
+	// ErrWorkflowNotFinished if the given instance is still running.
+	RestartWorkflowInstance(ctx context.Context, instance *workflow.Instance) (*workflow.Instance, error)
+
+	// ListWorkflowInstanceRuns returns all runs of the workflow instance with the given ID, oldest first. The last
+	// run is the current one. Returns ErrRunsNotSupported if the backend does not support multiple runs.
+	ListWorkflowInstanceRuns(ctx context.Context, instanceID string) ([]*backend.WorkflowRun, error)
+
+	// GetWorkflowRunHistory returns the history of the run identified by the instance and execution ID of the given
+	// instance. Earlier runs are only available if the backend supports multiple runs.
+	GetWorkflowRunHistory(ctx context.Context, instance *workflow.Instance) ([]history.Event, error)
+
+	// WaitForWorkflowInstance waits for the given workflow instance to finish. Without a timeout, the WaitTimeout
+	// client option applies. Returns ErrTimeout if the instance did not finish in time.
+	WaitForWorkflowInstance(ctx context.Context, instance *workflow.Instance, timeout time.Duration) error
+
+	// GetWorkflowResultPayload waits for the given workflow instance to finish and returns its serialized result,
+	// or the error the workflow failed with. Use GetWorkflowResult to retrieve a typed result.
+	GetWorkflowResultPayload(ctx context.Context, instance *workflow.Instance, timeout time.Duration) ([]byte, error)
+
+	SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}) error
+
+	// SignalWorkflowBatch delivers multiple signals to the given workflow instance in order. Backends implementing
+	// backend.BatchSignaler append all signals atomically and wake up the instance once. For other backends, the
+	// signals are delivered one by one, and delivery stops at the first failure.
+	SignalWorkflowBatch(ctx context.Context, instanceID string, signals []Signal) error
+
+	// SignalWorkflowsByTags signals all active workflow instances that have all of the given tags and returns the
+	// number of signaled instances. Returns ErrTagsNotSupported if the backend does not support it.
+	SignalWorkflowsByTags(ctx context.Context, tags []string, name string, arg interface{}) (int, error)
+
+	// ListWorkflowInstances returns the workflow instances matching the given filter, oldest first, from the
+	// visibility store. Returns ErrListingNotSupported if there is no visibility store.
+	ListWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) ([]*workflow.Instance, error)
+
+	// CountWorkflowInstances returns the number of workflow instances matching the given filter, from the
+	// visibility store. Returns ErrListingNotSupported if there is no visibility store.
+	CountWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) (int64, error)
+
+	// GetWorkflowInstances returns a page of the workflow instances matching the query, newest first. Pass the
+	// NextPageToken of the result in the query to get the next page. Queries the visibility store if it supports
+	// it, the backend otherwise. Returns ErrQueryNotSupported if neither does.
+	GetWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*backend.InstanceQueryResult, error)
+
+	// SignalWorkflows signals all workflow instances matching the given query. If the query does not restrict the
+	// state, only active instances are signaled. The query has to select an instance ID prefix or a creation time
+	// range, to guard against signaling every instance by accident. Matching instances are queried page by page,
+	// starting at the page token of the query, and each page is signaled before the next one is queried. Failing
+	// to signal an instance does not stop signaling the remaining ones, the returned report lists the instances the
+	// signal was delivered to and the ones it failed for. Instances are signaled in parallel, see
+	// WithBatchConcurrency. Returns ErrQueryNotSupported if the backend does not support querying instances.
+	SignalWorkflows(ctx context.Context, query backend.InstanceQuery, name string, arg interface{}) (*SignalReport, error)
+
+	// CancelWorkflowInstances cancels all workflow instances matching the given query, like SignalWorkflows
+	// signals them. If the query does not restrict the state, only active instances are canceled. The query has
+	// to select an instance ID prefix or a creation time range. The returned report lists the instances that were
+	// canceled and the ones canceling failed for. Returns ErrQueryNotSupported if the backend does not support
+	// querying instances.
+	CancelWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*CancelReport, error)
+
+	// QueryWorkflowPayload asks the current execution of the workflow instance with the given ID for its state,
+	// using the query handler with the given name, see workflow.HandleQuery. The query is answered by a worker
+	// and never changes the history of the instance. Without a context deadline, it waits 10s for an answer
+	// before returning ErrQueryTimeout. Use QueryWorkflow to retrieve a typed result. Returns
+	// ErrWorkflowQueriesNotSupported if the backend does not support it.
+	QueryWorkflowPayload(ctx context.Context, instanceID, name string, args ...interface{}) ([]byte, error)
+
+	// QueryWorkflowPayloadWithOptions works like QueryWorkflowPayload, with the given options for the query, for
+	// example to wait for pending events with QueryConsistencyStrong. Use QueryWorkflowWithOptions to retrieve a
+	// typed result. Returns ErrQueryConsistencyNotSupported for QueryConsistencyStrong if the backend does not
+	// implement backend.InstanceStatsProvider.
+	QueryWorkflowPayloadWithOptions(ctx context.Context, instanceID, name string, options QueryOptions, args ...interface{}) ([]byte, error)
+
+	// GetWorkflowInstance returns the current execution of the workflow instance with the given ID. The returned
+	// instance can be used to wait for, cancel, or inspect the instance when only its instance ID is known.
+	// Returns an error matching backend.ErrInstanceNotFound if there is no such instance, and
+	// ErrLookupNotSupported if the backend does not support it.
+	GetWorkflowInstance(ctx context.Context, instanceID string) (*workflow.Instance, error)
+
+	// GetWorkflowInstanceState returns whether the given workflow instance is active or finished, without waiting
+	// for it. Returns an error matching backend.ErrInstanceNotFound if the instance does not exist.
+	GetWorkflowInstanceState(ctx context.Context, instance *workflow.Instance) (backend.WorkflowState, error)
+
+	// GetWorkflowInstanceStats returns statistics about the history and outstanding work of the given workflow
+	// instance. Returns ErrStatsNotSupported if the backend does not support it.
+	GetWorkflowInstanceStats(ctx context.Context, instance *workflow.Instance) (*backend.InstanceStats, error)
+
+	// ForceCompleteWorkflowInstance marks a stuck workflow instance as finished with the result or error given
+	// in options, without executing it. Use this when the work of the instance already happened and replaying
+	// it isn't possible. Returns ErrForceCompleteNotSupported if the backend does not support it.
+	ForceCompleteWorkflowInstance(ctx context.Context, instance *workflow.Instance, options ForceCompleteOptions) error
+
+	// TerminateWorkflowInstance forcefully stops a workflow instance without giving the workflow a chance to
+	// clean up, unlike CancelWorkflowInstance. Pending activities and events of the instance are discarded, and
+	// waiting for its result returns ErrWorkflowTerminated. The parent of a terminated sub-workflow sees the
+	// sub-workflow fail. Returns ErrTerminateNotSupported if the backend does not support it.
+	TerminateWorkflowInstance(ctx context.Context, instance *workflow.Instance, reason string) error
+
+	// RemoveWorkflowInstance removes a finished workflow instance together with its history and earlier runs.
+	// Returns an error matching backend.ErrInstanceNotFinished if the instance is still active, or
+	// ErrRemoveNotSupported if the backend does not support it. See backend.WithRetention to remove finished
+	// instances automatically.
+	RemoveWorkflowInstance(ctx context.Context, instance *workflow.Instance) error
+
+	// PauseSchedule pauses the cron schedule of the instance with the given ID, created with a CronSchedule. A run
+	// in progress is not affected, but no further runs are started until the schedule is resumed.
+	PauseSchedule(ctx context.Context, instanceID string) error
+
+	// ResumeSchedule resumes a paused cron schedule. Runs that were due while it was paused are skipped.
+	ResumeSchedule(ctx context.Context, instanceID string) error
+
+	// DeleteSchedule stops the cron schedule of the instance with the given ID by canceling the instance. A run
+	// in progress is canceled as well.
+	DeleteSchedule(ctx context.Context, instanceID string) error
+
+	// OpenStream returns a reader for a stream written by an activity, see activity.NewStream. Chunks are read from
+	// the backend as the reader consumes them. Returns ErrStreamsNotSupported if the backend does not support it.
+	OpenStream(ctx context.Context, s workflow.Stream) (io.Reader, error)
+
+	// Converter returns the converter used to serialize workflow inputs and results
+	Converter() converter.Converter
+}
+
+type client struct {
+	backend   backend.Backend
+	converter converter.Converter
+	clock     clock.Clock
+	options   Options
+
+	// tracer records spans for created workflow instances, nil to discard them
+	tracer trace.Tracer
+}
+
+func New(backend backend.Backend, opts ...Option) Client {
+	options := DefaultOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	cv := options.Converter
+	if cv == nil {
+		cv = backend.Converter()
+	}
+
+	if cv == nil {
+		cv = converter.DefaultConverter
+	}
+
+	return &client{
+		backend:   backend,
+		converter: cv,
+		clock:     clock.New(),
+		options:   options,
+		tracer:    backend.Tracer(),
+	}
+}
+
+// startCreateSpan starts the span for creating the workflow instance of the given start message, and stores its
+// trace context with the instance, so workflow tasks and activities become children of the span
+func (c *client) startCreateSpan(ctx context.Context, name string, startMessage *history.WorkflowEvent) (context.Context, trace.Span) {
+	tracer := c.tracer
+	if tracer == nil {
+		tracer = tracing.NewNoopTracer()
+	}
+
+	attrs := startMessage.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+
+	ctx, span := tracer.Start(ctx, name, map[string]string{
+		"workflow":     attrs.Name,
+		"instance_id":  startMessage.WorkflowInstance.InstanceID,
+		"execution_id": startMessage.WorkflowInstance.ExecutionID,
+	})
+
+	attrs.TraceContext = tracing.Inject(tracer, ctx)
+
+	return ctx, span
+}
+
+// logger returns the logger for messages logged by the client
+func (c *client) logger() log.Logger {
+	logger := c.options.Logger
+	if logger == nil {
+		logger = c.backend.Logger()
+	}
+
+	if c.options.LogLevel > log.LevelDebug {
+		return log.WithLevel(logger, c.options.LogLevel)
+	}
+
+	return logger
+}
+
+func (c *client) Converter() converter.Converter {
+	return c.converter
+}
+
+func (c *client) CreateWorkflowInstance(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (*workflow.Instance, error) {
+	startMessage, err := c.newStartMessage(options, wf, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := c.startCreateSpan(ctx, "CreateWorkflowInstance", startMessage)
+	defer span.End()
+
+	err = c.retry(ctx, "CreateWorkflowInstance", func() error {
+		err := c.createInstance(ctx, options.IDReusePolicy, startMessage)
+
+		// A retried create finding the instance of an earlier attempt, whose result got lost, succeeded
+		var existsErr *backend.InstanceAlreadyExistsError
+		if errors.As(err, &existsErr) && existsErr.Instance != nil &&
+			existsErr.Instance.ExecutionID == startMessage.WorkflowInstance.ExecutionID {
+			return nil
+		}
+
+		return err
+	})
+	if err != nil {
+		if existing, ok := existingInstance(options, err); ok {
+			return existing, nil
+		}
+
+		span.RecordError(err)
+		return nil, fmt.Errorf("creating workflow instance: %w", err)
+	}
+
+	wfi := startMessage.WorkflowInstance
+
+	c.logger().Debug("Created workflow instance", "instance_id", wfi.InstanceID, "execution_id", wfi.ExecutionID)
+
+	return wfi, nil
+}
+
+// createInstance creates the instance of the given start message, reusing the instance ID of an existing
+// instance as the policy allows
+func (c *client) createInstance(ctx context.Context, policy IDReusePolicy, startMessage *history.WorkflowEvent) error {
+	switch policy {
+	case IDReusePolicyRejectDuplicate:
+		return c.backend.CreateWorkflowInstance(ctx, *startMessage)
+
+	case IDReusePolicyAllowIfFinished:
+		rp, ok := c.backend.(backend.RunProvider)
+		if !ok {
+			return ErrIDReusePolicyNotSupported
+		}
+
+		return rp.StartWorkflowInstanceRun(ctx, *startMessage)
+
+	case IDReusePolicyTerminateExisting:
+		rr, ok := c.backend.(backend.RunReplacer)
+		if !ok {
+			return ErrIDReusePolicyNotSupported
+		}
+
+		return rr.ReplaceWorkflowInstanceRun(ctx, *startMessage, func(run *workflow.Instance) (history.Event, []history.WorkflowEvent) {
+			return c.terminationEvents(run, "replaced by a new run")
+		})
+
+	default:
+		return fmt.Errorf("unknown instance ID reuse policy %d", policy)
+	}
+}
+
+func (c *client) CreateWorkflowInstanceTx(ctx context.Context, tx *sql.Tx, options WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (*workflow.Instance, error) {
+	tb, ok := c.backend.(backend.TransactionalInstanceCreator)
+	if !ok {
+		return nil, ErrTransactionsNotSupported
+	}
+
+	// Runs are only started outside of transactions
+	if options.IDReusePolicy != IDReusePolicyRejectDuplicate {
+		return nil, ErrIDReusePolicyNotSupported
+	}
+
+	startMessage, err := c.newStartMessage(options, wf, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := c.startCreateSpan(ctx, "CreateWorkflowInstanceTx", startMessage)
+	defer span.End()
+
+	if err := tb.CreateWorkflowInstanceTx(ctx, tx, *startMessage); err != nil {
+		if existing, ok := existingInstance(options, err); ok {
+			return existing, nil
+		}
+
+		span.RecordError(err)
+		return nil, fmt.Errorf("creating workflow instance: %w", err)
+	}
+
+	wfi := startMessage.WorkflowInstance
+
+	c.logger().Debug("Created workflow instance in transaction", "instance_id", wfi.InstanceID, "execution_id", wfi.ExecutionID)
+
+	return wfi, nil
+}
+
+func (c *client) NotifyWorkflowCreated() {
+	if tb, ok := c.backend.(backend.TransactionalInstanceCreator); ok {
+		tb.NotifyWorkflowCreated()
+	}
+}
+
+// existingInstance returns the already existing instance if creating an instance failed because of a
+// duplicate instance ID and the options allow returning it.
+func existingInstance(options WorkflowInstanceOptions, err error) (*workflow.Instance, bool) {
+	if !options.ReturnExisting {
+		return nil, false
+	}
+
+	var existsErr *backend.InstanceAlreadyExistsError
+	if errors.As(err, &existsErr) && existsErr.Instance != nil {
+		return existsErr.Instance, true
+	}
+
+	return nil, false
+}
+
+func (c *client) newStartMessage(options WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (*history.WorkflowEvent, error) {
+	if options.Queue != backend.DefaultWorkflowQueue {
+		if _, ok := c.backend.(backend.WorkflowQueueProvider); !ok {
+			return nil, ErrWorkflowQueuesNotSupported
+		}
+	}
+
+	inputs, err := a.ArgsToInputs(c.converter, args...)
+	if err != nil {
+		return nil, fmt.Errorf("converting arguments: %w", err)
+	}
+
+	if options.CronSchedule != "" {
+		if _, err := cron.Parse(options.CronSchedule); err != nil {
+			return nil, err
+		}
+
+		// Start an instance running the schedule instead
+		schedule, err := a.ArgsToInputs(c.converter, workflow.CronSchedule{
+			Schedule: options.CronSchedule,
+			Workflow: fn.Name(wf),
+			Inputs:   inputs,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("converting cron schedule: %w", err)
+		}
+
+		wf, inputs = workflow.RunCronSchedule, schedule
+	}
+
+	return c.newStartMessageFromAttributes(options.InstanceID, &history.ExecutionStartedAttributes{
+		Name:        fn.Name(wf),
+		Inputs:      inputs,
+		Priority:    options.Priority,
+		Queue:       options.Queue,
+		Tags:        options.Tags,
+		CancelAfter: options.CancelAfter,
+	}), nil
+}
+
+func (c *client) newStartMessageFromAttributes(instanceID string, attributes *history.ExecutionStartedAttributes) *history.WorkflowEvent {
+	startedEvent := history.NewPendingEvent(
+		c.clock.Now(),
+		history.EventType_WorkflowExecutionStarted,
+		attributes)
+
+	wfi := core.NewWorkflowInstance(instanceID, uuid.NewString())
+
+	return &history.WorkflowEvent{
+		WorkflowInstance: wfi,
+		HistoryEvent:     startedEvent,
+	}
+}
+
+func (c *client) CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	cancellationEvent := history.NewWorkflowCancellationEvent(time.Now())
+	return c.backend.CancelWorkflowInstance(ctx, instance, &cancellationEvent)
+}
+
+func (c *client) RestartWorkflowInstance(ctx context.Context, instance *workflow.Instance) (*workflow.Instance, error) {
+	state, err := c.backend.GetWorkflowInstanceState(ctx, instance)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow state: %w", err)
+	}
+
+	if state != backend.WorkflowStateFinished {
+		return nil, ErrWorkflowNotFinished
+	}
+
+	h, err := c.GetWorkflowRunHistory(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	var startedAttributes *history.ExecutionStartedAttributes
+	for _, event := range h {
+		if event.Type == history.EventType_WorkflowExecutionStarted {
+			startedAttributes = event.Attributes.(*history.ExecutionStartedAttributes)
+			break
+		}
+	}
+
+	if startedAttributes == nil {
+		return nil, errors.New("could not find workflow started event")
+	}
+
+	attributes := &history.ExecutionStartedAttributes{
+		Name:        startedAttributes.Name,
+		Inputs:      startedAttributes.Inputs,
+		Priority:    startedAttributes.Priority,
+		Queue:       startedAttributes.Queue,
+		Tags:        startedAttributes.Tags,
+		CancelAfter: startedAttributes.CancelAfter,
+	}
+
+	if rp, ok := c.backend.(backend.RunProvider); ok {
+		startMessage := c.newStartMessageFromAttributes(instance.InstanceID, attributes)
+		if err := rp.StartWorkflowInstanceRun(ctx, *startMessage); err != nil {
+			return nil, fmt.Errorf("starting workflow instance run: %w", err)
+		}
+
+		wfi := startMessage.WorkflowInstance
+
+		c.logger().Debug("Restarted workflow instance", "instance_id", wfi.InstanceID, "execution_id", wfi.ExecutionID,
+			"restarted_execution_id", instance.ExecutionID)
+
+		return wfi, nil
+	}
+
+	startMessage := c.newStartMessageFromAttributes(uuid.NewString(), attributes)
+
+	if err := c.backend.CreateWorkflowInstance(ctx, *startMessage); err != nil {
+		return nil, fmt.Errorf("creating workflow instance: %w", err)
+	}
+
+	wfi := startMessage.WorkflowInstance
+
+	c.logger().Debug("Restarted workflow instance", "instance_id", wfi.InstanceID, "execution_id", wfi.ExecutionID,
+		"restarted_instance_id", instance.InstanceID)
+
+	return wfi, nil
+}
+
+func (c *client) ListWorkflowInstanceRuns(ctx context.Context, instanceID string) ([]*backend.WorkflowRun, error) {
+	rp, ok := c.backend.(backend.RunProvider)
+	if !ok {
+		return nil, ErrRunsNotSupported
+	}
+
+	runs, err := rp.ListWorkflowInstanceRuns(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("listing workflow instance runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+func (c *client) GetWorkflowRunHistory(ctx context.Context, instance *workflow.Instance) ([]history.Event, error) {
+	var h []history.Event
+	var err error
+	if rp, ok := c.backend.(backend.RunProvider); ok {
+		h, err = rp.GetWorkflowInstanceRunHistory(ctx, instance)
+	} else {
+		h, err = c.backend.GetWorkflowInstanceHistory(ctx, instance, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow history: %w", err)
+	}
+
+	return h, nil
+}
+
+func (c *client) ListWorkflowInstancesByTags(ctx context.Context, tags ...string) ([]*workflow.Instance, error) {
+	return c.workflowInstancesByTags(ctx, tags, false)
+}
+
+func (c *client) CancelWorkflowInstancesByTags(ctx context.Context, tags ...string) (int, error) {
+	instances, err := c.workflowInstancesByTags(ctx, tags, true)
+	if err != nil {
+		return 0, err
+	}
+
+	canceled := 0
+	for _, instance := range instances {
+		if err := c.CancelWorkflowInstance(ctx, instance); err != nil {
+			return canceled, fmt.Errorf("canceling workflow instance %v: %w", instance.InstanceID, err)
+		}
+
+		canceled++
+	}
+
+	return canceled, nil
+}
+
+func (c *client) workflowInstancesByTags(ctx context.Context, tags []string, activeOnly bool) ([]*workflow.Instance, error) {
+	// Guard against accidentally selecting every instance
+	if len(tags) == 0 {
+		return nil, errors.New("at least one tag is required")
+	}
+
+	// Search a dedicated visibility store, if configured, instead of the backend
+	if v := c.options.VisibilityStore; v != nil {
+		filter := backend.InstanceFilter{Tags: tags}
+		if activeOnly {
+			filter.States = []backend.WorkflowState{backend.WorkflowStateActive}
+		}
+
+		instances, err := v.ListWorkflowInstances(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("getting workflow instances by tags: %w", err)
+		}
+
+		return instances, nil
+	}
+
+	ti, ok := c.backend.(backend.InstanceTagIndex)
+	if !ok {
+		return nil, ErrTagsNotSupported
+	}
+
+	instances, err := ti.GetWorkflowInstancesByTags(ctx, tags, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow instances by tags: %w", err)
+	}
+
+	return instances, nil
+}
+
+func (c *client) SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}) error {
+	input, err := c.converter.To(arg)
+	if err != nil {
+		return fmt.Errorf("converting arguments: %w", err)
+	}
+
+	signalEvent := history.NewPendingEvent(
+		c.clock.Now(),
+		history.EventType_SignalReceived,
+		&history.SignalReceivedAttributes{
+			Name: name,
+			Arg:  input,
+		},
+	)
+
+	err = c.retry(ctx, "SignalWorkflow", func() error {
+		return c.backend.SignalWorkflow(ctx, instanceID, signalEvent)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.logger().Debug("Signaled workflow instance", "instance_id", instanceID)
+
+	return nil
+}
+
+func (c *client) SignalWorkflowBatch(ctx context.Context, instanceID string, signals []Signal) error {
+	events := make([]history.Event, 0, len(signals))
+	for _, signal := range signals {
+		input, err := c.converter.To(signal.Arg)
+		if err != nil {
+			return fmt.Errorf("converting arguments of signal %v: %w", signal.Name, err)
+		}
+
+		events = append(events, history.NewPendingEvent(
+			c.clock.Now(),
+			history.EventType_SignalReceived,
+			&history.SignalReceivedAttributes{
+				Name: signal.Name,
+				Arg:  input,
+			},
+		))
+	}
+
+	if bs, ok := c.backend.(backend.BatchSignaler); ok {
+		if err := bs.SignalWorkflowBatch(ctx, instanceID, events); err != nil {
+			return err
+		}
+	} else {
+		for _, event := range events {
+			if err := c.backend.SignalWorkflow(ctx, instanceID, event); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.logger().Debug("Signaled workflow instance", "instance_id", instanceID, "signals", len(signals))
+
+	return nil
+}
+
+func (c *client) SignalWorkflowsByTags(ctx context.Context, tags []string, name string, arg interface{}) (int, error) {
+	instances, err := c.workflowInstancesByTags(ctx, tags, true)
+	if err != nil {
+		return 0, err
+	}
+
+	signaled := 0
+	for _, instance := range instances {
+		if err := c.SignalWorkflow(ctx, instance.InstanceID, name, arg); err != nil {
+			return signaled, fmt.Errorf("signaling workflow instance %v: %w", instance.InstanceID, err)
+		}
+
+		signaled++
+	}
+
+	return signaled, nil
+}
+
+// visibility returns the visibility store configured for the client, or the default store of the backend
+func (c *client) visibility() backend.VisibilityStore {
+	if c.options.VisibilityStore != nil {
+		return c.options.VisibilityStore
+	}
+
+	return backend.DefaultVisibilityStore(c.backend)
+}
+
+func (c *client) ListWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) ([]*workflow.Instance, error) {
+	v := c.visibility()
+	if v == nil {
+		return nil, ErrListingNotSupported
+	}
+
+	instances, err := v.ListWorkflowInstances(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("listing workflow instances: %w", err)
+	}
+
+	return instances, nil
+}
+
+func (c *client) CountWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) (int64, error) {
+	v := c.visibility()
+	if v == nil {
+		return 0, ErrListingNotSupported
+	}
+
+	n, err := v.CountWorkflowInstances(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("counting workflow instances: %w", err)
+	}
+
+	return n, nil
+}
+
+func (c *client) GetWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*backend.InstanceQueryResult, error) {
+	querier, ok := c.visibility().(backend.InstanceQuerier)
+	if !ok {
+		if querier, ok = c.backend.(backend.InstanceQuerier); !ok {
+			return nil, ErrQueryNotSupported
+		}
+	}
+
+	result, err := querier.QueryWorkflowInstances(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying workflow instances: %w", err)
+	}
+
+	return result, nil
+}
+
+func (c *client) SignalWorkflows(ctx context.Context, query backend.InstanceQuery, name string, arg interface{}) (*SignalReport, error) {
+	report := &SignalReport{}
+
+	err := c.forEachPage(ctx, query, func(instances []*workflow.Instance) error {
+		delivered, failed, err := c.forEachInstance(ctx, instances, func(instance *workflow.Instance) error {
+			return c.SignalWorkflow(ctx, instance.InstanceID, name, arg)
+		})
+
+		report.Delivered = append(report.Delivered, delivered...)
+		report.Failed = append(report.Failed, failed...)
+
+		return err
+	})
+	if err != nil && report.Delivered == nil && report.Failed == nil {
+		return nil, err
+	}
+
+	return report, err
 }
 
-type Client interface {
-	CreateWorkflowInstance(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (*workflow.Instance, error)
+func (c *client) CancelWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*CancelReport, error) {
+	report := &CancelReport{}
 
-	CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance) error
+	err := c.forEachPage(ctx, query, func(instances []*workflow.Instance) error {
+		canceled, failed, err := c.forEachInstance(ctx, instances, func(instance *workflow.Instance) error {
+			return c.CancelWorkflowInstance(ctx, instance)
+		})
 
-	WaitForWorkflowInstance(ctx context.Context, instance *workflow.Instance, timeout time.Duration) error
+		report.Canceled = append(report.Canceled, canceled...)
+		report.Failed = append(report.Failed, failed...)
 
-	SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}) error
+		return err
+	})
+	if err != nil && report.Canceled == nil && report.Failed == nil {
+		return nil, err
+	}
+
+	return report, err
 }
 
-type client struct {
-	backend backend.Backend
-	clock   clock.Clock
+// forEachPage queries the instances an operation on multiple instances selected by the query applies to, and calls
+// f with the instances of each page until there are no more pages or f returns an error. Only active instances are
+// selected if the query does not restrict the state.
+func (c *client) forEachPage(ctx context.Context, query backend.InstanceQuery, f func([]*workflow.Instance) error) error {
+	// Guard against accidentally selecting every instance
+	if query.InstanceIDPrefix == "" && query.CreatedAfter.IsZero() && query.CreatedBefore.IsZero() {
+		return errors.New("query has to select an instance ID prefix or a creation time range")
+	}
+
+	if len(query.States) == 0 {
+		query.States = []backend.WorkflowState{backend.WorkflowStateActive}
+	}
+
+	for {
+		result, err := c.GetWorkflowInstances(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		instances := make([]*workflow.Instance, 0, len(result.Instances))
+		for _, summary := range result.Instances {
+			instances = append(instances, summary.Instance)
+		}
+
+		if err := f(instances); err != nil {
+			return err
+		}
+
+		if result.NextPageToken == "" {
+			return nil
+		}
+
+		query.PageToken = result.NextPageToken
+	}
 }
 
-func New(backend backend.Backend) Client {
-	return &client{
-		backend: backend,
-		clock:   clock.New(),
+// forEachInstance calls f for the given instances, up to the batch concurrency of the client in parallel. It returns
+// the instances f succeeded for and the ones it failed for, both in the order of instances. Once ctx is canceled, no
+// further calls are started and the context error is returned.
+func (c *client) forEachInstance(ctx context.Context, instances []*workflow.Instance, f func(*workflow.Instance) error) ([]*workflow.Instance, []InstanceFailure, error) {
+	errs := make([]error, len(instances))
+	sem := make(chan struct{}, c.options.batchConcurrency())
+
+	var wg sync.WaitGroup
+	started := 0
+
+	for i, instance := range instances {
+		if ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		started++
+		wg.Add(1)
+		go func(i int, instance *workflow.Instance) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			errs[i] = f(instance)
+		}(i, instance)
+	}
+
+	wg.Wait()
+
+	succeeded := make([]*workflow.Instance, 0, started)
+	failed := make([]InstanceFailure, 0)
+	for i, instance := range instances[:started] {
+		if errs[i] != nil {
+			failed = append(failed, InstanceFailure{Instance: instance, Err: errs[i]})
+			continue
+		}
+
+		succeeded = append(succeeded, instance)
+	}
+
+	if started < len(instances) {
+		return succeeded, failed, ctx.Err()
 	}
+
+	return succeeded, failed, nil
 }
 
-func (c *client) CreateWorkflowInstance(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (*workflow.Instance, error) {
-	inputs, err := a.ArgsToInputs(converter.DefaultConverter, args...)
+func (c *client) GetWorkflowInstance(ctx context.Context, instanceID string) (*workflow.Instance, error) {
+	r, ok := c.backend.(backend.InstanceResolver)
+	if !ok {
+		return nil, ErrLookupNotSupported
+	}
+
+	instance, err := r.ResolveWorkflowInstance(ctx, instanceID)
 	if err != nil {
-		return nil, fmt.Errorf("converting arguments: %w", err)
+		return nil, fmt.Errorf("getting workflow instance: %w", err)
 	}
 
-	startedEvent := history.NewPendingEvent(
-		c.clock.Now(),
-		history.EventType_WorkflowExecutionStarted,
-		&history.ExecutionStartedAttributes{
-			Name:   fn.Name(wf),
-			Inputs: inputs,
-		})
+	return instance, nil
+}
+
+func (c *client) GetWorkflowInstanceState(ctx context.Context, instance *workflow.Instance) (backend.WorkflowState, error) {
+	state, err := c.backend.GetWorkflowInstanceState(ctx, instance)
+	if err != nil {
+		return state, fmt.Errorf("getting workflow state: %w", err)
+	}
 
-	wfi := core.NewWorkflowInstance(options.InstanceID, uuid.NewString())
+	return state, nil
+}
 
-	startMessage := &history.WorkflowEvent{
-		WorkflowInstance: wfi,
-		HistoryEvent:     startedEvent,
+func (c *client) GetWorkflowInstanceStats(ctx context.Context, instance *workflow.Instance) (*backend.InstanceStats, error) {
+	sp, ok := c.backend.(backend.InstanceStatsProvider)
+	if !ok {
+		return nil, ErrStatsNotSupported
 	}
 
-	if err := c.backend.CreateWorkflowInstance(ctx, *startMessage); err != nil {
-		return nil, fmt.Errorf("creating workflow instance: %w", err)
+	stats, err := sp.GetWorkflowInstanceStats(ctx, instance)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow instance stats: %w", err)
 	}
 
-	c.backend.Logger().Debug("Created workflow instance", "instance_id", wfi.InstanceID, "execution_id", wfi.ExecutionID)
+	return stats, nil
+}
+
+func (c *client) OpenStream(ctx context.Context, s workflow.Stream) (io.Reader, error) {
+	store, ok := c.backend.(backend.StateStore)
+	if !ok {
+		return nil, ErrStreamsNotSupported
+	}
 
-	return wfi, nil
+	return stream.NewReader(ctx, store, s), nil
 }
 
-func (c *client) CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
-	cancellationEvent := history.NewWorkflowCancellationEvent(time.Now())
-	return c.backend.CancelWorkflowInstance(ctx, instance, &cancellationEvent)
+func (c *client) ForceCompleteWorkflowInstance(ctx context.Context, instance *workflow.Instance, options ForceCompleteOptions) error {
+	fc, ok := c.backend.(backend.InstanceForceCompleter)
+	if !ok {
+		return ErrForceCompleteNotSupported
+	}
+
+	attrs := &history.ExecutionForceCompletedAttributes{
+		Reason: options.Reason,
+	}
+
+	if options.Error != nil {
+		attrs.Error = options.Error.Error()
+	} else {
+		result, err := c.converter.To(options.Result)
+		if err != nil {
+			return fmt.Errorf("converting result: %w", err)
+		}
+
+		attrs.Result = result
+	}
+
+	now := c.clock.Now()
+	event := history.NewPendingEvent(now, history.EventType_WorkflowExecutionForceCompleted, attrs)
+
+	workflowEvents := []history.WorkflowEvent{}
+	if instance.SubWorkflow() {
+		// Notify the parent instance as if the sub-workflow had finished on its own
+		var parentEvent history.Event
+		if attrs.Error != "" {
+			parentEvent = history.NewPendingEvent(now, history.EventType_SubWorkflowFailed, &history.SubWorkflowFailedAttributes{
+				Error: attrs.Error,
+			}, history.ScheduleEventID(instance.ParentEventID))
+		} else {
+			parentEvent = history.NewPendingEvent(now, history.EventType_SubWorkflowCompleted, &history.SubWorkflowCompletedAttributes{
+				Result: attrs.Result,
+			}, history.ScheduleEventID(instance.ParentEventID))
+		}
+
+		workflowEvents = append(workflowEvents, history.WorkflowEvent{
+			WorkflowInstance: core.NewWorkflowInstance(instance.ParentInstanceID, ""),
+			HistoryEvent:     parentEvent,
+		})
+	}
+
+	if err := fc.ForceCompleteWorkflowInstance(ctx, instance, event, workflowEvents); err != nil {
+		return fmt.Errorf("force-completing workflow instance: %w", err)
+	}
+
+	c.logger().Debug("Force-completed workflow instance", "instance_id", instance.InstanceID, "execution_id", instance.ExecutionID)
+
+	return nil
 }
 
-func (c *client) SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}) error {
-	input, err := converter.DefaultConverter.To(arg)
-	if err != nil {
-		return fmt.Errorf("converting arguments: %w", err)
+func (c *client) TerminateWorkflowInstance(ctx context.Context, instance *workflow.Instance, reason string) error {
+	t, ok := c.backend.(backend.InstanceTerminator)
+	if !ok {
+		return ErrTerminateNotSupported
 	}
 
-	signalEvent := history.NewPendingEvent(
-		c.clock.Now(),
-		history.EventType_SignalReceived,
-		&history.SignalReceivedAttributes{
-			Name: name,
-			Arg:  input,
-		},
-	)
+	event, workflowEvents := c.terminationEvents(instance, reason)
+
+	if err := t.TerminateWorkflowInstance(ctx, instance, event, workflowEvents); err != nil {
+		return fmt.Errorf("terminating workflow instance: %w", err)
+	}
+
+	c.logger().Debug("Terminated workflow instance", "instance_id", instance.InstanceID, "execution_id", instance.ExecutionID, "reason", reason)
+
+	return nil
+}
+
+func (c *client) RemoveWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	r, ok := c.backend.(backend.WorkflowInstanceRemover)
+	if !ok {
+		return ErrRemoveNotSupported
+	}
+
+	if err := r.RemoveWorkflowInstance(ctx, instance); err != nil {
+		return fmt.Errorf("removing workflow instance: %w", err)
+	}
+
+	c.logger().Debug("Removed workflow instance", "instance_id", instance.InstanceID, "execution_id", instance.ExecutionID)
+
+	return nil
+}
+
+func (c *client) PauseSchedule(ctx context.Context, instanceID string) error {
+	return c.SignalWorkflow(ctx, instanceID, workflow.CronPauseSignal, nil)
+}
 
-	err = c.backend.SignalWorkflow(ctx, instanceID, signalEvent)
+func (c *client) ResumeSchedule(ctx context.Context, instanceID string) error {
+	return c.SignalWorkflow(ctx, instanceID, workflow.CronResumeSignal, nil)
+}
+
+func (c *client) DeleteSchedule(ctx context.Context, instanceID string) error {
+	instance, err := c.GetWorkflowInstance(ctx, instanceID)
 	if err != nil {
 		return err
 	}
 
-	c.backend.Logger().Debug("Signaled workflow instance", "instance_id", instanceID)
+	return c.CancelWorkflowInstance(ctx, instance)
+}
 
-	return nil
+// terminatedError returns the error reported for an instance terminated with the given reason
+// terminationEvents returns the event terminating the given instance, and the events for other instances
+func (c *client) terminationEvents(instance *workflow.Instance, reason string) (history.Event, []history.WorkflowEvent) {
+	now := c.clock.Now()
+	event := history.NewPendingEvent(now, history.EventType_WorkflowExecutionTerminated, &history.ExecutionTerminatedAttributes{
+		Reason: reason,
+	})
+
+	workflowEvents := []history.WorkflowEvent{}
+	if instance.SubWorkflow() {
+		// Notify the parent instance, which would otherwise wait for the sub-workflow forever
+		workflowEvents = append(workflowEvents, history.WorkflowEvent{
+			WorkflowInstance: core.NewWorkflowInstance(instance.ParentInstanceID, ""),
+			HistoryEvent: history.NewPendingEvent(now, history.EventType_SubWorkflowFailed, &history.SubWorkflowFailedAttributes{
+				Error: terminatedError(reason).Error(),
+			}, history.ScheduleEventID(instance.ParentEventID)),
+		})
+	}
+
+	return event, workflowEvents
+}
+
+func terminatedError(reason string) error {
+	if reason == "" {
+		return ErrWorkflowTerminated
+	}
+
+	return fmt.Errorf("%w: %s", ErrWorkflowTerminated, reason)
 }
 
 func (c *client) WaitForWorkflowInstance(ctx context.Context, instance *workflow.Instance, timeout time.Duration) error {
-	if timeout == 0 {
-		timeout = time.Second * 20
+	if timeout = c.options.waitTimeout(timeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = c.clock.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	ticker := c.clock.Ticker(time.Second)
-	defer ticker.Stop()
+	// Prefer notifications if the backend supports them
+	if w, ok := c.backend.(backend.WorkflowInstanceWaiter); ok {
+		if err := w.WaitForWorkflowInstance(ctx, instance); err != nil {
+			if ctx.Err() != nil {
+				return ErrTimeout
+			}
+
+			return fmt.Errorf("waiting for workflow instance: %w", err)
+		}
 
-	ctx, cancel := c.clock.WithTimeout(ctx, timeout)
-	defer cancel()
+		return nil
+	}
+
+	interval := c.options.nextPollInterval(0)
+
+	ticker := c.clock.Ticker(interval)
+	defer ticker.Stop()
 
 	for {
 		s, err := c.backend.GetWorkflowInstanceState(ctx, instance)
@@ -127,28 +1212,229 @@ func (c *client) WaitForWorkflowInstance(ctx context.Context, instance *workflow
 			return nil
 		}
 
-		ticker.Reset(time.Second)
+		ticker.Reset(interval)
 		select {
 		case <-ticker.C:
+			interval = c.options.nextPollInterval(interval)
 			continue
 
 		case <-ctx.Done():
-			return errors.New("workflow did not finish in specified timeout")
+			return ErrTimeout
 		}
 	}
 }
 
 func GetWorkflowResult[T any](ctx context.Context, c Client, instance *workflow.Instance, timeout time.Duration) (T, error) {
-	if err := c.WaitForWorkflowInstance(ctx, instance, timeout); err != nil {
-		return *new(T), fmt.Errorf("workflow did not finish in time: %w", err)
+	p, err := c.GetWorkflowResultPayload(ctx, instance, timeout)
+	if err != nil {
+		return *new(T), err
+	}
+
+	var r T
+	if err := converter.Decode(c.Converter(), p, &r); err != nil {
+		return *new(T), fmt.Errorf("converting result: %w", err)
+	}
+
+	return r, nil
+}
+
+// ExecuteWorkflow creates a new workflow instance, waits for it to finish, and returns its result. Use the
+// context to bound how long to wait, without a deadline the default timeout of WaitForWorkflowInstance applies.
+func ExecuteWorkflow[T any](ctx context.Context, c Client, options WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (T, error) {
+	instance, err := c.CreateWorkflowInstance(ctx, options, wf, args...)
+	if err != nil {
+		return *new(T), fmt.Errorf("creating workflow instance: %w", err)
+	}
+
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	return GetWorkflowResult[T](ctx, c, instance, timeout)
+}
+
+// GetWorkflowResultInto waits for the given workflow instance to finish and decodes its result into out, which
+// needs to be a non-nil pointer. Unlike GetWorkflowResult, it does not require instantiating a generic function,
+// for example, when the result type is only known at runtime.
+func GetWorkflowResultInto(ctx context.Context, c Client, instance *workflow.Instance, timeout time.Duration, out interface{}) error {
+	if v := reflect.ValueOf(out); v.Kind() != reflect.Pointer || v.IsNil() {
+		return errors.New("out needs to be a non-nil pointer")
+	}
+
+	p, err := c.GetWorkflowResultPayload(ctx, instance, timeout)
+	if err != nil {
+		return err
+	}
+
+	if p == nil {
+		// Workflow without a result
+		return nil
+	}
+
+	if err := converter.Decode(c.Converter(), p, out); err != nil {
+		return fmt.Errorf("converting result: %w", err)
+	}
+
+	return nil
+}
+
+// ExportWorkflowHistory writes the history of the run identified by the given instance as JSON to w. Exported
+// histories can be replayed against workflow code with the replay package, for example to debug non-determinism
+// errors locally.
+func ExportWorkflowHistory(ctx context.Context, c Client, instance *workflow.Instance, w io.Writer) error {
+	h, err := c.GetWorkflowRunHistory(ctx, instance)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(w).Encode(h); err != nil {
+		return fmt.Errorf("encoding history: %w", err)
+	}
+
+	return nil
+}
+
+// QueryWorkflow asks the workflow instance with the given ID for its state and decodes the answer, see
+// Client.QueryWorkflowPayload
+func QueryWorkflow[T any](ctx context.Context, c Client, instanceID, name string, args ...interface{}) (T, error) {
+	return QueryWorkflowWithOptions[T](ctx, c, instanceID, name, QueryOptions{}, args...)
+}
+
+// QueryWorkflowWithOptions works like QueryWorkflow, with the given options for the query, see
+// Client.QueryWorkflowPayloadWithOptions
+func QueryWorkflowWithOptions[T any](ctx context.Context, c Client, instanceID, name string, options QueryOptions, args ...interface{}) (T, error) {
+	p, err := c.QueryWorkflowPayloadWithOptions(ctx, instanceID, name, options, args...)
+	if err != nil {
+		return *new(T), err
+	}
+
+	var r T
+	if err := converter.Decode(c.Converter(), p, &r); err != nil {
+		return *new(T), fmt.Errorf("converting query result: %w", err)
+	}
+
+	return r, nil
+}
+
+func (c *client) QueryWorkflowPayload(ctx context.Context, instanceID, name string, args ...interface{}) ([]byte, error) {
+	return c.QueryWorkflowPayloadWithOptions(ctx, instanceID, name, QueryOptions{}, args...)
+}
+
+func (c *client) QueryWorkflowPayloadWithOptions(ctx context.Context, instanceID, name string, options QueryOptions, args ...interface{}) ([]byte, error) {
+	q, ok := c.backend.(backend.WorkflowQuerier)
+	if !ok {
+		return nil, ErrWorkflowQueriesNotSupported
 	}
 
-	ic := c.(*client)
-	b := ic.backend
+	instance, err := c.GetWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := a.ArgsToInputs(c.converter, args...)
+	if err != nil {
+		return nil, fmt.Errorf("converting query arguments: %w", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = c.clock.Now().Add(defaultQueryTimeout)
+
+		var cancel context.CancelFunc
+		ctx, cancel = c.clock.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	if options.Consistency == QueryConsistencyStrong {
+		if err := c.waitForPendingEvents(ctx, instance); err != nil {
+			return nil, err
+		}
+	}
+
+	query := &backend.WorkflowQuery{
+		ID:       uuid.NewString(),
+		Instance: instance,
+		Name:     name,
+		Inputs:   inputs,
+		Deadline: deadline,
+	}
+
+	if err := q.AddWorkflowQuery(ctx, query); err != nil {
+		return nil, fmt.Errorf("adding workflow query: %w", err)
+	}
+
+	ticker := c.clock.Ticker(queryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		result, err := q.GetWorkflowQueryResult(ctx, query.ID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ErrQueryTimeout
+			}
+
+			return nil, fmt.Errorf("getting workflow query result: %w", err)
+		}
+
+		if result != nil {
+			if result.Error != "" {
+				return nil, errors.New(result.Error)
+			}
+
+			return result.Result, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ErrQueryTimeout
+		}
+	}
+}
+
+// waitForPendingEvents waits until workflow tasks have processed all events of the instance that are visible to
+// workers. Workers answer queries after catching up with the history, so the answer then reflects these events.
+func (c *client) waitForPendingEvents(ctx context.Context, instance *workflow.Instance) error {
+	sp, ok := c.backend.(backend.InstanceStatsProvider)
+	if !ok {
+		return ErrQueryConsistencyNotSupported
+	}
+
+	ticker := c.clock.Ticker(queryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		stats, err := sp.GetWorkflowInstanceStats(ctx, instance)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ErrQueryTimeout
+			}
+
+			return fmt.Errorf("getting workflow instance stats: %w", err)
+		}
+
+		// No workflow task is waiting for the instance
+		if stats.WorkflowTaskScheduledAt == nil {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ErrQueryTimeout
+		}
+	}
+}
+
+func (c *client) GetWorkflowResultPayload(ctx context.Context, instance *workflow.Instance, timeout time.Duration) ([]byte, error) {
+	if err := c.WaitForWorkflowInstance(ctx, instance, timeout); err != nil {
+		return nil, fmt.Errorf("workflow did not finish in time: %w", err)
+	}
 
-	h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+	h, err := c.GetWorkflowRunHistory(ctx, instance)
 	if err != nil {
-		return *new(T), fmt.Errorf("getting workflow history: %w", err)
+		return nil, err
 	}
 
 	// Iterate over history backwards
@@ -157,24 +1443,42 @@ func GetWorkflowResult[T any](ctx context.Context, c Client, instance *workflow.
 		switch event.Type {
 		case history.EventType_WorkflowExecutionFinished:
 			a := event.Attributes.(*history.ExecutionCompletedAttributes)
+			if a.Failure != nil {
+				return nil, history.FailureWithConverter(a.Failure, c.converter)
+			}
+
 			if a.Error != "" {
-				return *new(T), errors.New(a.Error)
+				return nil, errors.New(a.Error)
 			}
 
-			var r T
-			if err := converter.DefaultConverter.From(a.Result, &r); err != nil {
-				return *new(T), fmt.Errorf("converting result: %w", err)
+			return a.Result, nil
+
+		case history.EventType_WorkflowExecutionForceCompleted:
+			a := event.Attributes.(*history.ExecutionForceCompletedAttributes)
+			if a.Error != "" {
+				return nil, errors.New(a.Error)
 			}
 
-			return r, nil
+			return a.Result, nil
 
 		case history.EventType_WorkflowExecutionCanceled:
-			return *new(T), ErrWorkflowCanceled
+			return nil, ErrWorkflowCanceled
 
 		case history.EventType_WorkflowExecutionTerminated:
-			return *new(T), ErrWorkflowTerminated
+			a := event.Attributes.(*history.ExecutionTerminatedAttributes)
+			return nil, terminatedError(a.Reason)
+
+		case history.EventType_WorkflowExecutionContinuedAsNew:
+			// The result is the result of the last execution of the instance
+			a := event.Attributes.(*history.ExecutionContinuedAsNewAttributes)
+			next := core.NewWorkflowInstance(instance.InstanceID, a.ExecutionID)
+			if instance.SubWorkflow() {
+				next = core.NewSubWorkflowInstance(instance.InstanceID, a.ExecutionID, instance.ParentInstanceID, instance.ParentEventID)
+			}
+
+			return c.GetWorkflowResultPayload(ctx, next, timeout)
 		}
 	}
 
-	return *new(T), errors.New("workflow finished, but could not find result event")
+	return nil, errors.New("workflow finished, but could not find result event")
 }
@@ -3,6 +3,13 @@ package client
 import (
 	"bytes"
 	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -10,8 +17,14 @@ import (
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/logger"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/tracing"
+	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/trace"
+	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -26,16 +39,44 @@ func Test_Client_GetWorkflowResultTimeout(t *testing.T) {
 	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(backend.WorkflowStateActive, nil)
 
 	c := &client{
-		backend: b,
-		clock:   clock.New(),
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
 	}
 
 	result, err := GetWorkflowResult[int](ctx, c, instance, time.Microsecond*1)
 	require.Zero(t, result)
 	require.EqualError(t, err, "workflow did not finish in time: workflow did not finish in specified timeout")
+	require.ErrorIs(t, err, ErrTimeout)
 	b.AssertExpectations(t)
 }
 
+func Test_Options_NextPollInterval(t *testing.T) {
+	o := Options{WaitPollInterval: time.Second, WaitMaxPollInterval: time.Second * 5, WaitBackoffCoefficient: 2}
+
+	require.Equal(t, time.Second, o.nextPollInterval(0))
+	require.Equal(t, time.Second*2, o.nextPollInterval(time.Second))
+	require.Equal(t, time.Second*4, o.nextPollInterval(time.Second*2))
+	require.Equal(t, time.Second*5, o.nextPollInterval(time.Second*4))
+
+	fixed := Options{}
+	require.Equal(t, time.Second, fixed.nextPollInterval(0))
+	require.Equal(t, time.Second, fixed.nextPollInterval(time.Second))
+}
+
+func Test_Client_New_Converter(t *testing.T) {
+	b := &backend.MockBackend{}
+	b.On("Converter").Return(converter.DefaultConverter)
+	b.On("Tracer").Return(nil)
+
+	c := New(b)
+	require.Equal(t, converter.DefaultConverter, c.Converter())
+
+	cv := converter.NewEnvelopeConverter(converter.DefaultConverter, converter.NewGzipCodec(1024))
+	c = New(b, WithConverter(cv))
+	require.Equal(t, cv, c.Converter())
+}
+
 func Test_Client_GetWorkflowResultSuccess(t *testing.T) {
 	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
 
@@ -60,8 +101,9 @@ func Test_Client_GetWorkflowResultSuccess(t *testing.T) {
 	}, nil)
 
 	c := &client{
-		backend: b,
-		clock:   mockClock,
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     mockClock,
 	}
 
 	result, err := GetWorkflowResult[int](ctx, c, instance, 0)
@@ -70,6 +112,149 @@ func Test_Client_GetWorkflowResultSuccess(t *testing.T) {
 	b.AssertExpectations(t)
 }
 
+func Test_Client_GetWorkflowResultInto(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	ctx := context.Background()
+
+	r, _ := converter.DefaultConverter.To(42)
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(backend.WorkflowStateFinished, nil)
+	b.On("GetWorkflowInstanceHistory", mock.Anything, instance).Return([]history.Event{
+		history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+		history.NewHistoryEvent(2, time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{
+			Result: r,
+		}),
+	}, nil)
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	var result int
+	err := GetWorkflowResultInto(ctx, c, instance, 0, &result)
+	require.NoError(t, err)
+	require.Equal(t, 42, result)
+
+	err = GetWorkflowResultInto(ctx, c, instance, 0, result)
+	require.Error(t, err)
+}
+
+func Test_Client_ExecuteWorkflow(t *testing.T) {
+	instanceID := uuid.NewString()
+
+	ctx := context.Background()
+
+	r, _ := converter.DefaultConverter.To(42)
+
+	b := &backend.MockBackend{}
+	b.On("Logger").Return(logger.NewDefaultLogger())
+	b.On("CreateWorkflowInstance", mock.Anything, mock.Anything).Return(nil)
+	b.On("GetWorkflowInstanceState", mock.Anything, mock.MatchedBy(func(instance *core.WorkflowInstance) bool {
+		return instance.InstanceID == instanceID
+	})).Return(backend.WorkflowStateFinished, nil)
+	b.On("GetWorkflowInstanceHistory", mock.Anything, mock.Anything).Return([]history.Event{
+		history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+		history.NewHistoryEvent(2, time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{
+			Result: r,
+		}),
+	}, nil)
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	wf := func(ctx workflow.Context) (int, error) { return 42, nil }
+
+	result, err := ExecuteWorkflow[int](ctx, c, WorkflowInstanceOptions{InstanceID: instanceID}, wf)
+	require.NoError(t, err)
+	require.Equal(t, 42, result)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_GetWorkflowResultFailure(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	ctx := context.Background()
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(backend.WorkflowStateFinished, nil)
+	b.On("GetWorkflowInstanceHistory", mock.Anything, instance).Return([]history.Event{
+		history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+		history.NewHistoryEvent(2, time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{
+			Error: "activity failed",
+			Failure: &history.Failure{
+				Message:      "activity failed",
+				ActivityName: "Activity1",
+				Attempt:      3,
+			},
+		}),
+	}, nil)
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	_, err := GetWorkflowResult[int](ctx, c, instance, 0)
+	require.EqualError(t, err, "activity failed")
+
+	var f *workflow.Failure
+	require.ErrorAs(t, err, &f)
+	require.Equal(t, "Activity1", f.ActivityName)
+	require.Equal(t, 3, f.Attempt)
+	b.AssertExpectations(t)
+}
+
+type decoratedClient struct {
+	Client
+
+	calls int
+}
+
+func (c *decoratedClient) GetWorkflowResultPayload(ctx context.Context, instance *workflow.Instance, timeout time.Duration) ([]byte, error) {
+	c.calls++
+
+	return c.Client.GetWorkflowResultPayload(ctx, instance, timeout)
+}
+
+func Test_Client_GetWorkflowResult_DecoratedClient(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	ctx := context.Background()
+
+	r, _ := converter.DefaultConverter.To(42)
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(backend.WorkflowStateFinished, nil)
+	b.On("GetWorkflowInstanceHistory", mock.Anything, instance).Return([]history.Event{
+		history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+		history.NewHistoryEvent(2, time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{
+			Result: r,
+		}),
+	}, nil)
+
+	c := &decoratedClient{
+		Client: &client{
+			backend:   b,
+			converter: converter.DefaultConverter,
+			clock:     clock.New(),
+		},
+	}
+
+	result, err := GetWorkflowResult[int](ctx, c, instance, 0)
+	require.NoError(t, err)
+	require.Equal(t, 42, result)
+	require.Equal(t, 1, c.calls)
+	b.AssertExpectations(t)
+}
+
 func Test_Client_SignalWorkflow(t *testing.T) {
 	instanceID := uuid.NewString()
 
@@ -83,8 +268,9 @@ func Test_Client_SignalWorkflow(t *testing.T) {
 	})).Return(nil)
 
 	c := &client{
-		backend: b,
-		clock:   clock.New(),
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
 	}
 
 	err := c.SignalWorkflow(ctx, instanceID, "test", "signal")
@@ -93,6 +279,82 @@ func Test_Client_SignalWorkflow(t *testing.T) {
 	b.AssertExpectations(t)
 }
 
+func Test_Client_SignalWorkflowBatch_SignalsOneByOne(t *testing.T) {
+	instanceID := uuid.NewString()
+
+	ctx := context.Background()
+
+	names := []string{}
+
+	b := &backend.MockBackend{}
+	b.On("Logger").Return(logger.NewDefaultLogger())
+	b.On("SignalWorkflow", ctx, instanceID, mock.Anything).Run(func(args mock.Arguments) {
+		names = append(names, args.Get(2).(history.Event).Attributes.(*history.SignalReceivedAttributes).Name)
+	}).Return(nil)
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	err := c.SignalWorkflowBatch(ctx, instanceID, []Signal{{Name: "a", Arg: 1}, {Name: "b", Arg: 2}})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, names)
+}
+
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Debug(msg string, fields ...interface{}) {
+	l.messages = append(l.messages, msg)
+}
+
+func (l *recordingLogger) Warn(msg string, fields ...interface{}) {
+	l.messages = append(l.messages, msg)
+}
+
+func (l *recordingLogger) Error(msg string, fields ...interface{}) {
+	l.messages = append(l.messages, msg)
+}
+
+func (l *recordingLogger) Panic(msg string, fields ...interface{}) {
+	panic(msg)
+}
+
+func (l *recordingLogger) With(fields ...interface{}) log.Logger {
+	return l
+}
+
+func Test_Client_SignalWorkflow_UsesClientLogger(t *testing.T) {
+	instanceID := uuid.NewString()
+
+	ctx := context.Background()
+
+	// The backend's logger is not used, the mock would fail when calling it
+	b := &backend.MockBackend{}
+	b.On("SignalWorkflow", ctx, instanceID, mock.Anything).Return(nil)
+
+	l := &recordingLogger{}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+		options:   Options{Logger: l},
+	}
+
+	require.NoError(t, c.SignalWorkflow(ctx, instanceID, "test", "signal"))
+	require.Equal(t, []string{"Signaled workflow instance"}, l.messages)
+
+	c.options.LogLevel = log.LevelWarn
+
+	require.NoError(t, c.SignalWorkflow(ctx, instanceID, "test", "signal"))
+	require.Len(t, l.messages, 1)
+}
+
 func Test_Client_SignalWorkflow_WithArgs(t *testing.T) {
 	instanceID := uuid.NewString()
 
@@ -111,8 +373,9 @@ func Test_Client_SignalWorkflow_WithArgs(t *testing.T) {
 	})).Return(nil)
 
 	c := &client{
-		backend: b,
-		clock:   clock.New(),
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
 	}
 
 	err := c.SignalWorkflow(ctx, instanceID, "test", arg)
@@ -120,3 +383,826 @@ func Test_Client_SignalWorkflow_WithArgs(t *testing.T) {
 	require.Nil(t, err)
 	b.AssertExpectations(t)
 }
+
+type waitingBackend struct {
+	*backend.MockBackend
+}
+
+func (b *waitingBackend) WaitForWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance) error {
+	args := b.Called(ctx, instance)
+	return args.Error(0)
+}
+
+func Test_Client_WaitForWorkflowInstance_UsesWaiter(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	ctx := context.Background()
+
+	b := &waitingBackend{&backend.MockBackend{}}
+	b.On("WaitForWorkflowInstance", mock.Anything, instance).Return(nil)
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	err := c.WaitForWorkflowInstance(ctx, instance, time.Second)
+	require.NoError(t, err)
+	b.AssertNotCalled(t, "GetWorkflowInstanceState", mock.Anything, instance)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_CreateWorkflowInstanceTx_NotSupported(t *testing.T) {
+	b := &backend.MockBackend{}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	_, err := c.CreateWorkflowInstanceTx(context.Background(), nil, WorkflowInstanceOptions{InstanceID: uuid.NewString()}, func(ctx workflow.Context) error { return nil })
+	require.ErrorIs(t, err, ErrTransactionsNotSupported)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_CreateWorkflowInstance_ReturnExisting(t *testing.T) {
+	existing := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	b := &backend.MockBackend{}
+	b.On("CreateWorkflowInstance", mock.Anything, mock.Anything).Return(&backend.InstanceAlreadyExistsError{Instance: existing})
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	wf := func(ctx workflow.Context) error { return nil }
+
+	_, err := c.CreateWorkflowInstance(context.Background(), WorkflowInstanceOptions{InstanceID: existing.InstanceID}, wf)
+	require.ErrorIs(t, err, backend.ErrInstanceAlreadyExists)
+
+	instance, err := c.CreateWorkflowInstance(context.Background(), WorkflowInstanceOptions{InstanceID: existing.InstanceID, ReturnExisting: true}, wf)
+	require.NoError(t, err)
+	require.Equal(t, existing, instance)
+	b.AssertExpectations(t)
+}
+
+type propagatingTracer struct {
+	trace.Tracer
+}
+
+func (t *propagatingTracer) Inject(ctx context.Context) map[string]string {
+	return map[string]string{"traceparent": "00-trace-span-01"}
+}
+
+func (t *propagatingTracer) Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return ctx
+}
+
+func Test_Client_CreateWorkflowInstance_StoresTraceContext(t *testing.T) {
+	var started *history.ExecutionStartedAttributes
+
+	b := &backend.MockBackend{}
+	b.On("Logger").Return(logger.NewDefaultLogger())
+	b.On("CreateWorkflowInstance", mock.Anything, mock.Anything).Return(func(ctx context.Context, event history.WorkflowEvent) error {
+		started = event.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+		return nil
+	})
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+		tracer:    &propagatingTracer{Tracer: tracing.NewNoopTracer()},
+	}
+
+	_, err := c.CreateWorkflowInstance(context.Background(), WorkflowInstanceOptions{InstanceID: uuid.NewString()}, func(ctx workflow.Context) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"traceparent": "00-trace-span-01"}, started.TraceContext)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_CreateWorkflowInstance_CronSchedule(t *testing.T) {
+	var started *history.ExecutionStartedAttributes
+
+	b := &backend.MockBackend{}
+	b.On("Logger").Return(logger.NewDefaultLogger())
+	b.On("CreateWorkflowInstance", mock.Anything, mock.Anything).Return(func(ctx context.Context, event history.WorkflowEvent) error {
+		started = event.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+		return nil
+	}).Once()
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	wf := func(ctx workflow.Context, report string) error { return nil }
+
+	_, err := c.CreateWorkflowInstance(context.Background(), WorkflowInstanceOptions{InstanceID: uuid.NewString(), CronSchedule: "61 * * * *"}, wf, "daily")
+	require.Error(t, err)
+
+	_, err = c.CreateWorkflowInstance(context.Background(), WorkflowInstanceOptions{InstanceID: uuid.NewString(), CronSchedule: "@daily"}, wf, "daily")
+	require.NoError(t, err)
+	b.AssertExpectations(t)
+
+	require.Equal(t, "RunCronSchedule", started.Name)
+	require.Len(t, started.Inputs, 1)
+
+	var schedule workflow.CronSchedule
+	require.NoError(t, converter.DefaultConverter.From(started.Inputs[0], &schedule))
+	require.Equal(t, "@daily", schedule.Schedule)
+	require.Equal(t, fn.Name(wf), schedule.Workflow)
+	require.Equal(t, []payload.Payload{payload.Payload(`"daily"`)}, schedule.Inputs)
+}
+
+func Test_Client_CreateWorkflowInstance_RetriesTransientErrors(t *testing.T) {
+	instanceID := uuid.NewString()
+
+	b := &backend.MockBackend{}
+	b.On("Logger").Return(logger.NewDefaultLogger())
+
+	// The first attempt created the instance, but reported a transient error
+	b.On("CreateWorkflowInstance", mock.Anything, mock.Anything).Return(&backend.TransientError{Err: errors.New("connection lost")}).Once()
+	b.On("CreateWorkflowInstance", mock.Anything, mock.Anything).Return(func(ctx context.Context, event history.WorkflowEvent) error {
+		return &backend.InstanceAlreadyExistsError{Instance: core.NewWorkflowInstance(instanceID, event.WorkflowInstance.ExecutionID)}
+	}).Once()
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+		options:   Options{RetryPolicy: &RetryPolicy{MaxAttempts: 3, FirstRetryInterval: time.Millisecond}},
+	}
+
+	instance, err := c.CreateWorkflowInstance(context.Background(), WorkflowInstanceOptions{InstanceID: instanceID}, func(ctx workflow.Context) error { return nil })
+	require.NoError(t, err)
+	require.Equal(t, instanceID, instance.InstanceID)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_CreateWorkflowInstance_DoesNotReturnOtherInstances(t *testing.T) {
+	existing := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	b := &backend.MockBackend{}
+	b.On("Logger").Return(logger.NewDefaultLogger())
+	b.On("CreateWorkflowInstance", mock.Anything, mock.Anything).Return(&backend.TransientError{Err: errors.New("deadlock")}).Once()
+	b.On("CreateWorkflowInstance", mock.Anything, mock.Anything).Return(&backend.InstanceAlreadyExistsError{Instance: existing}).Once()
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+		options:   Options{RetryPolicy: &RetryPolicy{MaxAttempts: 3, FirstRetryInterval: time.Millisecond}},
+	}
+
+	_, err := c.CreateWorkflowInstance(context.Background(), WorkflowInstanceOptions{InstanceID: existing.InstanceID}, func(ctx workflow.Context) error { return nil })
+	require.ErrorIs(t, err, backend.ErrInstanceAlreadyExists)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_SignalWorkflow_RetriesTransientErrors(t *testing.T) {
+	instanceID := uuid.NewString()
+
+	b := &backend.MockBackend{}
+	b.On("Logger").Return(logger.NewDefaultLogger())
+	b.On("SignalWorkflow", mock.Anything, instanceID, mock.Anything).Return(&backend.ThrottledError{RetryAfter: time.Millisecond}).Times(2)
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+		options:   Options{RetryPolicy: &RetryPolicy{MaxAttempts: 2, FirstRetryInterval: time.Millisecond}},
+	}
+
+	err := c.SignalWorkflow(context.Background(), instanceID, "test", "signal")
+	require.ErrorIs(t, err, backend.ErrThrottled)
+
+	// Errors that aren't transient are not retried
+	b.On("SignalWorkflow", mock.Anything, instanceID, mock.Anything).Return(backend.ErrInstanceNotFound).Once()
+
+	err = c.SignalWorkflow(context.Background(), instanceID, "test", "signal")
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+	b.AssertExpectations(t)
+}
+
+func Test_IsTransientError(t *testing.T) {
+	require.True(t, IsTransientError(&backend.TransientError{Err: errors.New("deadlock")}))
+	require.True(t, IsTransientError(fmt.Errorf("creating workflow instance: %w", backend.ErrBackpressure)))
+	require.True(t, IsTransientError(driver.ErrBadConn))
+	require.True(t, IsTransientError(&net.OpError{Op: "read", Err: syscall.ECONNRESET}))
+	require.False(t, IsTransientError(context.DeadlineExceeded))
+	require.False(t, IsTransientError(backend.ErrInstanceNotFound))
+}
+
+func Test_Client_GetWorkflowInstanceStats_NotSupported(t *testing.T) {
+	b := &backend.MockBackend{}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	_, err := c.GetWorkflowInstanceStats(context.Background(), core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()))
+	require.ErrorIs(t, err, ErrStatsNotSupported)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_ForceCompleteWorkflowInstance_NotSupported(t *testing.T) {
+	b := &backend.MockBackend{}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	err := c.ForceCompleteWorkflowInstance(context.Background(), core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()), ForceCompleteOptions{Result: 42})
+	require.ErrorIs(t, err, ErrForceCompleteNotSupported)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_TerminateWorkflowInstance_NotSupported(t *testing.T) {
+	b := &backend.MockBackend{}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	err := c.TerminateWorkflowInstance(context.Background(), core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()), "stuck")
+	require.ErrorIs(t, err, ErrTerminateNotSupported)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_RemoveWorkflowInstance_NotSupported(t *testing.T) {
+	b := &backend.MockBackend{}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	err := c.RemoveWorkflowInstance(context.Background(), core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()))
+	require.ErrorIs(t, err, ErrRemoveNotSupported)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_RestartWorkflowInstance(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	inputs := []payload.Payload{[]byte("42")}
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(backend.WorkflowStateFinished, nil)
+	b.On("GetWorkflowInstanceHistory", mock.Anything, instance).Return([]history.Event{
+		history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+			Name:     "wf",
+			Inputs:   inputs,
+			Priority: 2,
+		}),
+		history.NewHistoryEvent(2, time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{
+			Error: "failed",
+		}),
+	}, nil)
+	b.On("CreateWorkflowInstance", mock.Anything, mock.MatchedBy(func(event history.WorkflowEvent) bool {
+		a := event.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+		return event.WorkflowInstance.InstanceID != instance.InstanceID && a.Name == "wf" && a.Priority == 2 && bytes.Equal(a.Inputs[0], inputs[0])
+	})).Return(nil)
+	b.On("Logger").Return(logger.NewDefaultLogger())
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	restarted, err := c.RestartWorkflowInstance(context.Background(), instance)
+	require.NoError(t, err)
+	require.NotEqual(t, instance.InstanceID, restarted.InstanceID)
+	require.NotEqual(t, instance.ExecutionID, restarted.ExecutionID)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_RestartWorkflowInstance_NotFinished(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(backend.WorkflowStateActive, nil)
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	_, err := c.RestartWorkflowInstance(context.Background(), instance)
+	require.ErrorIs(t, err, ErrWorkflowNotFinished)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_CancelWorkflowInstancesByTags_NotSupported(t *testing.T) {
+	b := &backend.MockBackend{}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	_, err := c.CancelWorkflowInstancesByTags(context.Background(), "release:2024-06")
+	require.ErrorIs(t, err, ErrTagsNotSupported)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_SignalWorkflows(t *testing.T) {
+	delivered := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	failed := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	b := &pagingBackend{
+		MockBackend: &backend.MockBackend{},
+		instances:   []*core.WorkflowInstance{delivered, failed},
+	}
+	b.On("SignalWorkflow", mock.Anything, delivered.InstanceID, mock.Anything).Return(nil)
+	b.On("SignalWorkflow", mock.Anything, failed.InstanceID, mock.Anything).Return(backend.ErrInstanceNotFound)
+	b.On("Logger").Return(logger.NewDefaultLogger())
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	report, err := c.SignalWorkflows(context.Background(), backend.InstanceQuery{InstanceIDPrefix: "order-"}, "signal", 42)
+	require.NoError(t, err)
+	require.Equal(t, []*core.WorkflowInstance{delivered}, report.Delivered)
+	require.Len(t, report.Failed, 1)
+	require.Equal(t, failed, report.Failed[0].Instance)
+	require.ErrorIs(t, report.Failed[0].Err, backend.ErrInstanceNotFound)
+
+	// Only active instances are signaled by default
+	require.Len(t, b.queries, 1)
+	require.Equal(t, "order-", b.queries[0].InstanceIDPrefix)
+	require.Equal(t, []backend.WorkflowState{backend.WorkflowStateActive}, b.queries[0].States)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_SignalWorkflows_Pages(t *testing.T) {
+	instances := make([]*core.WorkflowInstance, 5)
+	for i := range instances {
+		instances[i] = core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	}
+
+	b := &pagingBackend{
+		MockBackend: &backend.MockBackend{},
+		instances:   instances,
+	}
+	b.On("SignalWorkflow", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	b.On("Logger").Return(logger.NewDefaultLogger())
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	report, err := c.SignalWorkflows(context.Background(), backend.InstanceQuery{
+		CreatedAfter: time.Now().Add(-time.Hour),
+		PageSize:     2,
+	}, "signal", 42)
+	require.NoError(t, err)
+	require.Equal(t, instances, report.Delivered)
+	require.Empty(t, report.Failed)
+
+	// Each page continues the query with the token of the previous one
+	require.Len(t, b.queries, 3)
+	require.Equal(t, "", b.queries[0].PageToken)
+	require.Equal(t, "2", b.queries[1].PageToken)
+	require.Equal(t, "4", b.queries[2].PageToken)
+	b.AssertNumberOfCalls(t, "SignalWorkflow", 5)
+}
+
+func Test_Client_SignalWorkflows_RequiresCriteria(t *testing.T) {
+	b := &pagingBackend{MockBackend: &backend.MockBackend{}}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	_, err := c.SignalWorkflows(context.Background(), backend.InstanceQuery{}, "signal", 42)
+	require.Error(t, err)
+
+	// Restricting the state alone still selects too many instances
+	_, err = c.SignalWorkflows(context.Background(), backend.InstanceQuery{
+		States: []backend.WorkflowState{backend.WorkflowStateActive},
+	}, "signal", 42)
+	require.Error(t, err)
+	require.Empty(t, b.queries)
+}
+
+func Test_Client_SignalWorkflows_NotSupported(t *testing.T) {
+	b := &backend.MockBackend{}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	_, err := c.SignalWorkflows(context.Background(), backend.InstanceQuery{InstanceIDPrefix: "order-"}, "signal", 42)
+	require.ErrorIs(t, err, ErrQueryNotSupported)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_SignalWorkflows_BoundedParallelism(t *testing.T) {
+	instances := make([]*core.WorkflowInstance, 10)
+	for i := range instances {
+		instances[i] = core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	}
+
+	var running, maxRunning int32
+	b := &pagingBackend{
+		MockBackend: &backend.MockBackend{},
+		instances:   instances,
+	}
+	b.On("SignalWorkflow", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}).Return(nil)
+	b.On("Logger").Return(logger.NewDefaultLogger())
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+		options:   Options{BatchConcurrency: 3},
+	}
+
+	report, err := c.SignalWorkflows(context.Background(), backend.InstanceQuery{InstanceIDPrefix: "order-"}, "signal", 42)
+	require.NoError(t, err)
+
+	// Delivered instances keep the order of the matching instances
+	require.Equal(t, instances, report.Delivered)
+	require.Empty(t, report.Failed)
+	require.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(3))
+	require.Greater(t, atomic.LoadInt32(&maxRunning), int32(1))
+}
+
+func Test_Client_CancelWorkflowInstances(t *testing.T) {
+	canceled := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	failed := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	b := &pagingBackend{
+		MockBackend: &backend.MockBackend{},
+		instances:   []*core.WorkflowInstance{canceled, failed},
+	}
+	b.On("CancelWorkflowInstance", mock.Anything, canceled, mock.Anything).Return(nil)
+	b.On("CancelWorkflowInstance", mock.Anything, failed, mock.Anything).Return(backend.ErrInstanceNotFound)
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	report, err := c.CancelWorkflowInstances(context.Background(), backend.InstanceQuery{InstanceIDPrefix: "order-", PageSize: 1})
+	require.NoError(t, err)
+	require.Equal(t, []*core.WorkflowInstance{canceled}, report.Canceled)
+	require.Len(t, report.Failed, 1)
+	require.Equal(t, failed, report.Failed[0].Instance)
+	require.ErrorIs(t, report.Failed[0].Err, backend.ErrInstanceNotFound)
+
+	require.Len(t, b.queries, 2)
+	require.Equal(t, []backend.WorkflowState{backend.WorkflowStateActive}, b.queries[1].States)
+	b.AssertExpectations(t)
+
+	_, err = c.CancelWorkflowInstances(context.Background(), backend.InstanceQuery{})
+	require.Error(t, err)
+}
+
+// pagingBackend returns its instances for every query, a page of the query's page size at a time. Page tokens are
+// the index of the first instance of the page.
+type pagingBackend struct {
+	*backend.MockBackend
+
+	instances []*core.WorkflowInstance
+	queries   []backend.InstanceQuery
+}
+
+func (b *pagingBackend) QueryWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*backend.InstanceQueryResult, error) {
+	b.queries = append(b.queries, query)
+
+	start := 0
+	if query.PageToken != "" {
+		start, _ = strconv.Atoi(query.PageToken)
+	}
+
+	end := start + query.Limit()
+	if end > len(b.instances) {
+		end = len(b.instances)
+	}
+
+	result := &backend.InstanceQueryResult{}
+	for _, instance := range b.instances[start:end] {
+		result.Instances = append(result.Instances, &backend.WorkflowInstanceSummary{
+			Instance: instance,
+			State:    backend.WorkflowStateActive,
+		})
+	}
+
+	if end < len(b.instances) {
+		result.NextPageToken = strconv.Itoa(end)
+	}
+
+	return result, nil
+}
+
+type listingBackend struct {
+	*backend.MockBackend
+
+	instances []*core.WorkflowInstance
+	filter    backend.InstanceFilter
+}
+
+func (b *listingBackend) ListWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) ([]*core.WorkflowInstance, error) {
+	b.filter = filter
+	return b.instances, nil
+}
+
+type testVisibilityStore struct {
+	instances []*core.WorkflowInstance
+	filters   []backend.InstanceFilter
+}
+
+func (s *testVisibilityStore) ListWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) ([]*core.WorkflowInstance, error) {
+	s.filters = append(s.filters, filter)
+	return s.instances, nil
+}
+
+func (s *testVisibilityStore) CountWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) (int64, error) {
+	s.filters = append(s.filters, filter)
+	return int64(len(s.instances)), nil
+}
+
+func Test_Client_VisibilityStore(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	// The backend neither lists nor indexes tags, all queries are served by the visibility store
+	b := &backend.MockBackend{}
+	v := &testVisibilityStore{instances: []*core.WorkflowInstance{instance}}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+		options:   Options{VisibilityStore: v},
+	}
+
+	ctx := context.Background()
+
+	instances, err := c.ListWorkflowInstances(ctx, backend.InstanceFilter{Name: "wf"})
+	require.NoError(t, err)
+	require.Equal(t, []*core.WorkflowInstance{instance}, instances)
+
+	n, err := c.CountWorkflowInstances(ctx, backend.InstanceFilter{Name: "wf"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	instances, err = c.ListWorkflowInstancesByTags(ctx, "team:a")
+	require.NoError(t, err)
+	require.Equal(t, []*core.WorkflowInstance{instance}, instances)
+
+	require.Equal(t, []backend.InstanceFilter{
+		{Name: "wf"},
+		{Name: "wf"},
+		{Tags: []string{"team:a"}},
+	}, v.filters)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_CountWorkflowInstances_DefaultsToBackend(t *testing.T) {
+	b := &listingBackend{
+		MockBackend: &backend.MockBackend{},
+		instances:   []*core.WorkflowInstance{core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())},
+	}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	n, err := c.CountWorkflowInstances(context.Background(), backend.InstanceFilter{Name: "wf"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	require.Equal(t, "wf", b.filter.Name)
+
+	_, err = (&client{backend: &backend.MockBackend{}}).CountWorkflowInstances(context.Background(), backend.InstanceFilter{})
+	require.ErrorIs(t, err, ErrListingNotSupported)
+}
+
+type queryingBackend struct {
+	*backend.MockBackend
+
+	query backend.InstanceQuery
+}
+
+func (b *queryingBackend) QueryWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*backend.InstanceQueryResult, error) {
+	b.query = query
+	return &backend.InstanceQueryResult{NextPageToken: "next"}, nil
+}
+
+func Test_Client_GetWorkflowInstances(t *testing.T) {
+	b := &queryingBackend{MockBackend: &backend.MockBackend{}}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	query := backend.InstanceQuery{InstanceIDPrefix: "order-", PageSize: 10}
+
+	result, err := c.GetWorkflowInstances(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, "next", result.NextPageToken)
+	require.Equal(t, query, b.query)
+
+	_, err = (&client{backend: &backend.MockBackend{}}).GetWorkflowInstances(context.Background(), query)
+	require.ErrorIs(t, err, ErrQueryNotSupported)
+}
+
+func Test_Client_GetWorkflowInstanceState(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(backend.WorkflowStateFinished, nil)
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	state, err := c.GetWorkflowInstanceState(context.Background(), instance)
+	require.NoError(t, err)
+	require.Equal(t, backend.WorkflowStateFinished, state)
+	require.Equal(t, "finished", state.String())
+	b.AssertExpectations(t)
+}
+
+func Test_Client_GetWorkflowInstanceState_NotFound(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(backend.WorkflowStateActive, backend.ErrInstanceNotFound)
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	_, err := c.GetWorkflowInstanceState(context.Background(), instance)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+}
+
+type resolvingBackend struct {
+	*backend.MockBackend
+
+	instance *core.WorkflowInstance
+}
+
+func (b *resolvingBackend) ResolveWorkflowInstance(ctx context.Context, instanceID string) (*core.WorkflowInstance, error) {
+	if b.instance == nil || b.instance.InstanceID != instanceID {
+		return nil, backend.ErrInstanceNotFound
+	}
+
+	return b.instance, nil
+}
+
+func Test_Client_GetWorkflowInstance(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	c := &client{
+		backend:   &resolvingBackend{MockBackend: &backend.MockBackend{}, instance: instance},
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	resolved, err := c.GetWorkflowInstance(context.Background(), instance.InstanceID)
+	require.NoError(t, err)
+	require.Equal(t, instance, resolved)
+
+	_, err = c.GetWorkflowInstance(context.Background(), uuid.NewString())
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+}
+
+func Test_Client_GetWorkflowInstance_NotSupported(t *testing.T) {
+	c := &client{
+		backend:   &backend.MockBackend{},
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	_, err := c.GetWorkflowInstance(context.Background(), uuid.NewString())
+	require.ErrorIs(t, err, ErrLookupNotSupported)
+}
+
+type runsBackend struct {
+	*backend.MockBackend
+
+	history []history.Event
+	started *history.WorkflowEvent
+}
+
+func (b *runsBackend) StartWorkflowInstanceRun(ctx context.Context, event history.WorkflowEvent) error {
+	b.started = &event
+	return nil
+}
+
+func (b *runsBackend) ListWorkflowInstanceRuns(ctx context.Context, instanceID string) ([]*backend.WorkflowRun, error) {
+	return nil, backend.ErrInstanceNotFound
+}
+
+func (b *runsBackend) GetWorkflowInstanceRunHistory(ctx context.Context, instance *core.WorkflowInstance) ([]history.Event, error) {
+	return b.history, nil
+}
+
+func Test_Client_RestartWorkflowInstance_StartsNewRun(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	mb := &backend.MockBackend{}
+	mb.On("GetWorkflowInstanceState", mock.Anything, instance).Return(backend.WorkflowStateFinished, nil)
+	mb.On("Logger").Return(logger.NewDefaultLogger())
+
+	b := &runsBackend{
+		MockBackend: mb,
+		history: []history.Event{
+			history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+				Name: "wf",
+			}),
+		},
+	}
+
+	c := &client{
+		backend:   b,
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	restarted, err := c.RestartWorkflowInstance(context.Background(), instance)
+	require.NoError(t, err)
+	require.Equal(t, instance.InstanceID, restarted.InstanceID)
+	require.NotEqual(t, instance.ExecutionID, restarted.ExecutionID)
+	require.Equal(t, restarted, b.started.WorkflowInstance)
+	require.Equal(t, "wf", b.started.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes).Name)
+	mb.AssertExpectations(t)
+}
+
+func Test_Client_ListWorkflowInstanceRuns_NotSupported(t *testing.T) {
+	c := &client{
+		backend:   &backend.MockBackend{},
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	_, err := c.ListWorkflowInstanceRuns(context.Background(), uuid.NewString())
+	require.ErrorIs(t, err, ErrRunsNotSupported)
+}
+
+func Test_Client_CreateWorkflowInstance_IDReusePolicyNotSupported(t *testing.T) {
+	c := &client{
+		backend:   &backend.MockBackend{},
+		converter: converter.DefaultConverter,
+		clock:     clock.New(),
+	}
+
+	wf := func(ctx workflow.Context) error {
+		return nil
+	}
+
+	for _, policy := range []IDReusePolicy{IDReusePolicyAllowIfFinished, IDReusePolicyTerminateExisting} {
+		_, err := c.CreateWorkflowInstance(context.Background(), WorkflowInstanceOptions{
+			InstanceID:    uuid.NewString(),
+			IDReusePolicy: policy,
+		}, wf)
+		require.ErrorIs(t, err, ErrIDReusePolicyNotSupported)
+	}
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/log"
+)
+
+// Options configure a client
+type Options struct {
+	// WaitTimeout is how long WaitForWorkflowInstance waits when it's called without a timeout. A negative value
+	// waits until the instance finishes or the context is canceled. Defaults to 20s.
+	WaitTimeout time.Duration
+
+	// WaitPollInterval is the initial interval at which WaitForWorkflowInstance polls the state of the instance,
+	// for backends that cannot notify about finished instances. Defaults to 1s.
+	WaitPollInterval time.Duration
+
+	// WaitMaxPollInterval caps the poll interval when it grows with WaitBackoffCoefficient. 0 does not cap the
+	// poll interval.
+	WaitMaxPollInterval time.Duration
+
+	// WaitBackoffCoefficient is multiplied with the poll interval after every poll. Defaults to 1, which polls
+	// at a fixed interval.
+	WaitBackoffCoefficient float64
+
+	// Logger is used for messages logged by the client. Defaults to the logger of the backend.
+	Logger log.Logger
+
+	// LogLevel is the minimum level of messages logged by the client. Defaults to log.LevelDebug.
+	LogLevel log.Level
+
+	// RetryPolicy, if set, retries CreateWorkflowInstance and SignalWorkflow after transient backend errors
+	RetryPolicy *RetryPolicy
+
+	// VisibilityStore serves ListWorkflowInstances, CountWorkflowInstances, and the tag-based methods. Defaults to
+	// the visibility store of the backend, see backend.DefaultVisibilityStore.
+	VisibilityStore backend.VisibilityStore
+
+	// Converter encodes workflow inputs and signal arguments, and decodes results. It has to match the converter
+	// of the workers, for example when both encrypt payloads. Defaults to the converter of the backend.
+	Converter converter.Converter
+
+	// BatchConcurrency is the number of instances SignalWorkflows and CancelWorkflowInstances operate on in
+	// parallel. Defaults to 10.
+	BatchConcurrency int
+}
+
+var DefaultOptions = Options{
+	WaitTimeout:            time.Second * 20,
+	WaitPollInterval:       time.Second,
+	WaitBackoffCoefficient: 1,
+	BatchConcurrency:       10,
+}
+
+type Option func(*Options)
+
+// WithWaitTimeout sets the timeout WaitForWorkflowInstance uses when it's called without a timeout. A negative
+// timeout waits until the instance finishes or the context is canceled.
+func WithWaitTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.WaitTimeout = timeout
+	}
+}
+
+// WithWaitPolling sets how WaitForWorkflowInstance polls backends that cannot notify about finished instances.
+// The poll interval starts at interval and is multiplied with backoffCoefficient after every poll, up to
+// maxInterval.
+func WithWaitPolling(interval, maxInterval time.Duration, backoffCoefficient float64) Option {
+	return func(o *Options) {
+		o.WaitPollInterval = interval
+		o.WaitMaxPollInterval = maxInterval
+		o.WaitBackoffCoefficient = backoffCoefficient
+	}
+}
+
+// WithLogger sets the logger for messages logged by the client, independent of the logger of the backend
+func WithLogger(logger log.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithLogLevel sets the minimum level of messages logged by the client
+func WithLogLevel(level log.Level) Option {
+	return func(o *Options) {
+		o.LogLevel = level
+	}
+}
+
+// WithRetryPolicy retries CreateWorkflowInstance and SignalWorkflow after transient backend errors according to
+// the given policy. Retried creates are idempotent: if an earlier attempt created the instance but its result
+// got lost, the instance is returned. A retried signal might be delivered twice in that case.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *Options) {
+		o.RetryPolicy = &policy
+	}
+}
+
+// WithVisibilityStore lists, counts, and searches workflow instances by tags in the given store instead of the
+// backend, so that traffic doesn't compete with task dispatch
+func WithVisibilityStore(v backend.VisibilityStore) Option {
+	return func(o *Options) {
+		o.VisibilityStore = v
+	}
+}
+
+// WithBatchConcurrency sets the number of instances SignalWorkflows and CancelWorkflowInstances operate on in
+// parallel
+func WithBatchConcurrency(n int) Option {
+	return func(o *Options) {
+		o.BatchConcurrency = n
+	}
+}
+
+func (o *Options) waitTimeout(timeout time.Duration) time.Duration {
+	if timeout != 0 {
+		return timeout
+	}
+
+	if o.WaitTimeout != 0 {
+		return o.WaitTimeout
+	}
+
+	return DefaultOptions.WaitTimeout
+}
+
+// nextPollInterval returns the interval to wait after polling with the given interval. An interval of 0 returns
+// the initial poll interval.
+func (o *Options) nextPollInterval(interval time.Duration) time.Duration {
+	if interval == 0 {
+		if o.WaitPollInterval > 0 {
+			return o.WaitPollInterval
+		}
+
+		return DefaultOptions.WaitPollInterval
+	}
+
+	if o.WaitBackoffCoefficient <= 1 {
+		return interval
+	}
+
+	next := time.Duration(float64(interval) * o.WaitBackoffCoefficient)
+
+	if o.WaitMaxPollInterval > 0 && next > o.WaitMaxPollInterval {
+		next = o.WaitMaxPollInterval
+	}
+
+	return next
+}
+
+// WithConverter sets the converter used for workflow inputs, signal arguments, and results instead of the
+// converter of the backend
+func WithConverter(c converter.Converter) Option {
+	return func(o *Options) {
+		o.Converter = c
+	}
+}
+
+func (o *Options) batchConcurrency() int {
+	if o.BatchConcurrency > 0 {
+		return o.BatchConcurrency
+	}
+
+	return DefaultOptions.BatchConcurrency
+}
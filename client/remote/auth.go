@@ -0,0 +1,293 @@
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+var (
+	// ErrUnauthenticated is returned if a request has no valid credentials
+	ErrUnauthenticated = errors.New("unauthenticated")
+
+	// ErrPermissionDenied is returned if the authenticated caller is not allowed to perform an operation
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Subject identifies the caller, for example, the name of an API key, the common name of a client
+	// certificate, or the subject of an OIDC token
+	Subject string
+
+	// Method is the authentication method, "api_key", "mtls", or "oidc"
+	Method string
+
+	// Claims are the claims of an OIDC token, nil for other methods
+	Claims map[string]interface{}
+}
+
+// Authenticator authenticates requests. If a request doesn't carry credentials of its kind, an Authenticator
+// returns nil and no error, so the next authenticator can try. Invalid credentials are returned as an error.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// AuthenticatorFunc adapts a function to an Authenticator
+type AuthenticatorFunc func(r *http.Request) (*Principal, error)
+
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (*Principal, error) {
+	return f(r)
+}
+
+// APIKeyHeader is the header API keys are read from
+const APIKeyHeader = "X-Api-Key"
+
+// NewAPIKeyAuthenticator returns an authenticator accepting the given API keys, sent in the X-Api-Key header.
+// keys maps each key to the subject of the principal it authenticates.
+func NewAPIKeyAuthenticator(keys map[string]string) Authenticator {
+	type apiKey struct {
+		hash    [sha256.Size]byte
+		subject string
+	}
+
+	hashed := make([]apiKey, 0, len(keys))
+	for key, subject := range keys {
+		hashed = append(hashed, apiKey{hash: sha256.Sum256([]byte(key)), subject: subject})
+	}
+
+	return AuthenticatorFunc(func(r *http.Request) (*Principal, error) {
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" {
+			return nil, nil
+		}
+
+		// Compare hashes in constant time, so neither the keys nor their length can be guessed from timing
+		h := sha256.Sum256([]byte(key))
+
+		var subject string
+		for _, k := range hashed {
+			if subtle.ConstantTimeCompare(h[:], k.hash[:]) == 1 {
+				subject = k.subject
+			}
+		}
+
+		if subject == "" {
+			return nil, errors.New("invalid API key")
+		}
+
+		return &Principal{Subject: subject, Method: "api_key"}, nil
+	})
+}
+
+// NewClientCertAuthenticator returns an authenticator accepting TLS client certificates. The server's TLS config
+// needs to verify client certificates, with ClientAuth set to tls.VerifyClientCertIfGiven or
+// tls.RequireAndVerifyClientCert and ClientCAs set to the trusted CAs. The subject of the principal is the common
+// name of the certificate.
+func NewClientCertAuthenticator() Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) (*Principal, error) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return nil, nil
+		}
+
+		cert := r.TLS.VerifiedChains[0][0]
+
+		return &Principal{Subject: cert.Subject.CommonName, Method: "mtls"}, nil
+	})
+}
+
+// Operation describes a request to authorize
+type Operation struct {
+	// Method is the called client method, e.g. "CreateWorkflowInstance" or "SignalWorkflow"
+	Method string
+
+	// Workflow is the name of the workflow to start, the workflow of the instance the operation targets, or the
+	// workflow name filter of ListWorkflowInstances and CountWorkflowInstances. It's empty if the targeted instance
+	// doesn't exist, the backend can't look up instances without their execution, or the operation selects
+	// instances by a query, like SignalWorkflows and CancelWorkflowInstances.
+	Workflow string
+
+	// InstanceID is the workflow instance the operation targets, if any
+	InstanceID string
+
+	// Tags are the tags of the instance to start, or the tags selecting the instances to operate on
+	Tags []string
+
+	// Signals are the names of the signals to send
+	Signals []string
+}
+
+// Authorizer decides whether the principal may perform the given operation. Returning an error rejects the
+// request, errors not wrapping ErrPermissionDenied are wrapped with it.
+type Authorizer func(ctx context.Context, p *Principal, op *Operation) error
+
+type principalKey struct{}
+
+// PrincipalFromContext returns the authenticated principal of the request the context belongs to, or nil
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+func authenticate(r *http.Request, authenticators []Authenticator) (*Principal, error) {
+	for _, a := range authenticators {
+		p, err := a.Authenticate(r)
+		if err != nil {
+			return nil, &authError{err: err, known: ErrUnauthenticated}
+		}
+
+		if p != nil {
+			return p, nil
+		}
+	}
+
+	return nil, &authError{err: errors.New("missing credentials"), known: ErrUnauthenticated}
+}
+
+func authorize(ctx context.Context, authorizer Authorizer, p *Principal, op *Operation) error {
+	if err := authorizer(ctx, p, op); err != nil {
+		if errors.Is(err, ErrPermissionDenied) {
+			return err
+		}
+
+		return &authError{err: err, known: ErrPermissionDenied}
+	}
+
+	return nil
+}
+
+type authError struct {
+	err   error
+	known error
+}
+
+func (e *authError) Error() string {
+	return e.known.Error() + ": " + e.err.Error()
+}
+
+func (e *authError) Unwrap() error {
+	return e.known
+}
+
+// operationRequest holds the fields of all requests needed to authorize them
+type operationRequest struct {
+	Options struct {
+		InstanceID string
+		Tags       []string
+	} `json:"options"`
+	Workflow   string   `json:"workflow"`
+	InstanceID string   `json:"instance_id"`
+	Tags       []string `json:"tags"`
+	Instance   *struct {
+		InstanceID  string `json:"instance_id"`
+		ExecutionID string `json:"execution_id"`
+	} `json:"instance"`
+	Filter struct {
+		Name string
+		Tags []string
+	} `json:"filter"`
+	Signals []signal `json:"signals"`
+	Signal  *signal  `json:"signal"`
+}
+
+func (r *operationRequest) operation(method string) *Operation {
+	op := &Operation{
+		Method:     method,
+		Workflow:   r.Workflow,
+		InstanceID: r.InstanceID,
+		Tags:       r.Tags,
+	}
+
+	if op.InstanceID == "" {
+		op.InstanceID = r.Options.InstanceID
+	}
+
+	if r.Instance != nil {
+		op.InstanceID = r.Instance.InstanceID
+	}
+
+	if len(op.Tags) == 0 {
+		op.Tags = r.Options.Tags
+	}
+
+	if op.Workflow == "" {
+		op.Workflow = r.Filter.Name
+	}
+
+	if len(op.Tags) == 0 {
+		op.Tags = r.Filter.Tags
+	}
+
+	for _, s := range r.Signals {
+		op.Signals = append(op.Signals, s.Name)
+	}
+
+	if r.Signal != nil {
+		op.Signals = append(op.Signals, r.Signal.Name)
+	}
+
+	return op
+}
+
+// executionID returns the execution of the targeted instance, if the request addresses a specific execution
+func (r *operationRequest) executionID() string {
+	if r.Instance != nil {
+		return r.Instance.ExecutionID
+	}
+
+	return ""
+}
+
+// resolveWorkflow sets the workflow of an operation targeting an existing instance to the name of the workflow of
+// the instance, so authorizers can restrict operations on instances by workflow
+func resolveWorkflow(ctx context.Context, c client.Client, op *Operation, executionID string) error {
+	if op.InstanceID == "" || op.Workflow != "" {
+		return nil
+	}
+
+	instance := core.NewWorkflowInstance(op.InstanceID, executionID)
+	if executionID == "" {
+		var err error
+		instance, err = c.GetWorkflowInstance(ctx, op.InstanceID)
+		if err != nil {
+			// The operation fails for instances that don't exist, leave it to the authorizer whether the caller
+			// may learn that. Backends that can't look up instances leave the workflow unresolved.
+			if errors.Is(err, backend.ErrInstanceNotFound) || errors.Is(err, client.ErrLookupNotSupported) {
+				return nil
+			}
+
+			return fmt.Errorf("resolving workflow instance: %w", err)
+		}
+	}
+
+	h, err := c.GetWorkflowRunHistory(ctx, instance)
+	if err != nil {
+		if errors.Is(err, backend.ErrInstanceNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("resolving workflow of instance: %w", err)
+	}
+
+	op.Workflow = history.WorkflowName(h)
+
+	return nil
+}
+
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+
+	return ""
+}
@@ -0,0 +1,212 @@
+package remote
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Remote_APIKeys(t *testing.T) {
+	url, stop := newTestServer(t, WithAuthenticators(NewAPIKeyAuthenticator(map[string]string{"key1": "service-a"})))
+	defer stop()
+
+	ctx := context.Background()
+	options := client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}
+
+	_, err := New(url).CreateWorkflowInstance(ctx, options, Workflow1, "hello")
+	require.ErrorIs(t, err, ErrUnauthenticated)
+
+	_, err = New(url, WithHeader(APIKeyHeader, "wrong")).CreateWorkflowInstance(ctx, options, Workflow1, "hello")
+	require.ErrorIs(t, err, ErrUnauthenticated)
+
+	_, err = New(url, WithHeader(APIKeyHeader, "key1")).CreateWorkflowInstance(ctx, options, Workflow1, "hello")
+	require.NoError(t, err)
+}
+
+func Test_Remote_Authorizer(t *testing.T) {
+	var ops []*Operation
+
+	url, stop := newTestServer(t,
+		WithAuthenticators(NewAPIKeyAuthenticator(map[string]string{"key1": "service-a", "key2": "service-b"})),
+		WithAuthorizer(func(ctx context.Context, p *Principal, op *Operation) error {
+			require.Equal(t, p, PrincipalFromContext(ctx))
+
+			ops = append(ops, op)
+
+			if p.Subject == "service-b" && op.Method == "SignalWorkflow" {
+				return errors.New("service-b may not send signals")
+			}
+
+			return nil
+		}),
+	)
+	defer stop()
+
+	ctx := context.Background()
+	a := New(url, WithHeader(APIKeyHeader, "key1"))
+	b := New(url, WithHeader(APIKeyHeader, "key2"))
+
+	instance, err := b.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+		Tags:       []string{"namespace:billing"},
+	}, "Workflow1", "hello")
+	require.NoError(t, err)
+
+	require.Equal(t, &Operation{
+		Method:     "CreateWorkflowInstance",
+		Workflow:   "Workflow1",
+		InstanceID: instance.InstanceID,
+		Tags:       []string{"namespace:billing"},
+	}, ops[0])
+
+	err = b.SignalWorkflow(ctx, instance.InstanceID, "signal", "world")
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	require.ErrorContains(t, err, "service-b may not send signals")
+
+	require.NoError(t, a.SignalWorkflow(ctx, instance.InstanceID, "signal", "world"))
+	require.Equal(t, &Operation{
+		Method:     "SignalWorkflow",
+		Workflow:   "Workflow1",
+		InstanceID: instance.InstanceID,
+		Signals:    []string{"signal"},
+	}, ops[2])
+
+	r, err := client.GetWorkflowResult[string](ctx, a, instance, time.Second*10)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", r)
+}
+
+func Test_Remote_RejectsLargeRequests(t *testing.T) {
+	url, stop := newTestServer(t,
+		WithMaxRequestSize(1024),
+		WithAuthorizer(func(ctx context.Context, p *Principal, op *Operation) error {
+			return nil
+		}),
+	)
+	defer stop()
+
+	ctx := context.Background()
+	c := New(url)
+
+	_, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, "Workflow1", strings.Repeat("a", 2048))
+	require.ErrorContains(t, err, "request body too large")
+
+	_, err = c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, "Workflow1", "hello")
+	require.NoError(t, err)
+}
+
+func Test_Remote_OIDC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var issuer string
+
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/keys"})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys": []map[string]string{{
+					"kid": "1",
+					"kty": "RSA",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer provider.Close()
+
+	issuer = provider.URL
+
+	ctx := context.Background()
+
+	a, err := NewOIDCAuthenticator(ctx, OIDCOptions{Issuer: issuer, Audience: "workflows"})
+	require.NoError(t, err)
+
+	sign := func(kid string, claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+		payload, _ := json.Marshal(claims)
+
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(signed))
+
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+
+		return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+
+	authenticate := func(token string) (*Principal, error) {
+		r := httptest.NewRequest(http.MethodPost, "/v1/GetWorkflowInstance", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return a.Authenticate(r)
+	}
+
+	valid := map[string]interface{}{
+		"iss": issuer,
+		"aud": []string{"workflows"},
+		"sub": "user-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	p, err := authenticate(sign("1", valid))
+	require.NoError(t, err)
+	require.Equal(t, "user-1", p.Subject)
+	require.Equal(t, "oidc", p.Method)
+
+	claims := func(key string, value interface{}) map[string]interface{} {
+		c := make(map[string]interface{})
+		for k, v := range valid {
+			c[k] = v
+		}
+
+		c[key] = value
+		return c
+	}
+
+	_, err = authenticate(sign("1", claims("aud", "other")))
+	require.ErrorContains(t, err, "audience")
+
+	_, err = authenticate(sign("1", claims("iss", "https://other.example.com")))
+	require.ErrorContains(t, err, "issuer")
+
+	_, err = authenticate(sign("1", claims("exp", time.Now().Add(-time.Hour).Unix())))
+	require.ErrorContains(t, err, "expired")
+
+	_, err = authenticate(sign("2", valid))
+	require.ErrorContains(t, err, "unknown signing key")
+
+	token := sign("1", valid)
+	parts := strings.Split(token, ".")
+	tampered, _ := json.Marshal(claims("sub", "admin"))
+	_, err = authenticate(parts[0] + "." + base64.RawURLEncoding.EncodeToString(tampered) + "." + parts[2])
+	require.ErrorContains(t, err, "invalid signature")
+
+	p, err = a.Authenticate(httptest.NewRequest(http.MethodPost, "/v1/GetWorkflowInstance", nil))
+	require.NoError(t, err)
+	require.Nil(t, p)
+}
@@ -0,0 +1,415 @@
+package remote
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type OIDCOptions struct {
+	// Issuer is the URL of the OpenID Connect provider. Its discovery document is read from
+	// <Issuer>/.well-known/openid-configuration.
+	Issuer string
+
+	// Audience is the audience tokens need to be issued for, usually the client ID registered with the provider
+	Audience string
+
+	// HTTPClient is used to fetch the discovery document and the signing keys. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// ClockSkew is tolerated when checking the expiry of tokens. Defaults to one minute.
+	ClockSkew time.Duration
+}
+
+// keyRefreshInterval is the minimum time between fetching the signing keys of the provider. Keys are fetched
+// again when a token is signed with an unknown key, to pick up rotated keys.
+const keyRefreshInterval = time.Minute
+
+type oidcAuthenticator struct {
+	options OIDCOptions
+	jwksURI string
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+
+	now func() time.Time
+}
+
+// NewOIDCAuthenticator returns an authenticator accepting JWTs issued by an OpenID Connect provider, sent as
+// bearer token in the Authorization header. Tokens need to be signed with one of the provider's keys, issued by
+// the provider for the configured audience, and not be expired. The subject of the principal is the sub claim,
+// all claims are available in Claims.
+func NewOIDCAuthenticator(ctx context.Context, options OIDCOptions) (Authenticator, error) {
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+
+	if options.ClockSkew == 0 {
+		options.ClockSkew = time.Minute
+	}
+
+	options.Issuer = strings.TrimSuffix(options.Issuer, "/")
+
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+
+	if err := getJSON(ctx, options.HTTPClient, options.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("fetching OIDC discovery document: %w", err)
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != options.Issuer {
+		return nil, fmt.Errorf("OIDC discovery document is for issuer %q, expected %q", discovery.Issuer, options.Issuer)
+	}
+
+	a := &oidcAuthenticator{
+		options: options,
+		jwksURI: discovery.JWKSURI,
+		now:     time.Now,
+	}
+
+	if err := a.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *oidcAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+
+	claims, err := a.verify(r.Context(), token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, errors.New("invalid token: missing sub claim")
+	}
+
+	return &Principal{Subject: sub, Method: "oidc", Claims: claims}, nil
+}
+
+func (a *oidcAuthenticator) verify(ctx context.Context, token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+
+	key, err := a.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != a.options.Issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", iss)
+	}
+
+	if !hasAudience(claims["aud"], a.options.Audience) {
+		return nil, errors.New("token not issued for this audience")
+	}
+
+	now := a.now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("missing exp claim")
+	}
+
+	if now.Add(-a.options.ClockSkew).After(time.Unix(int64(exp), 0)) {
+		return nil, errors.New("token expired")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(a.options.ClockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token not valid yet")
+	}
+
+	return claims, nil
+}
+
+// key returns the signing key with the given ID, fetching the keys again if it's unknown
+func (a *oidcAuthenticator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	a.mu.Lock()
+	key, ok := a.keys[kid]
+	refresh := !ok && a.now().Sub(a.fetchedAt) >= keyRefreshInterval
+	a.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if refresh {
+		if err := a.fetchKeys(ctx); err != nil {
+			return nil, err
+		}
+
+		a.mu.Lock()
+		key, ok = a.keys[kid]
+		a.mu.Unlock()
+
+		if ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (a *oidcAuthenticator) fetchKeys(ctx context.Context) error {
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := getJSON(ctx, a.options.HTTPClient, a.jwksURI, &jwks); err != nil {
+		return fmt.Errorf("fetching OIDC signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			// Skip keys of unsupported types, tokens signed with them are rejected
+			continue
+		}
+
+		keys[k.Kid] = key
+	}
+
+	a.mu.Lock()
+	a.keys = keys
+	a.fetchedAt = a.now()
+	a.mu.Unlock()
+
+	return nil
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+
+	// RSA keys
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC keys
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+
+	if hash == 0 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	invalid := errors.New("invalid signature")
+
+	switch alg[:2] {
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return invalid
+		}
+
+		if alg[:2] == "PS" {
+			if rsa.VerifyPSS(pub, hash, digest, sig, nil) != nil {
+				return invalid
+			}
+
+			return nil
+		}
+
+		if rsa.VerifyPKCS1v15(pub, hash, digest, sig) != nil {
+			return invalid
+		}
+
+		return nil
+
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return invalid
+		}
+
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return invalid
+		}
+
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return invalid
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func decodeSegment(s string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.New("malformed token")
+	}
+
+	return nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("malformed key")
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+func getJSON(ctx context.Context, c *http.Client, url string, v interface{}) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %v: %v", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,225 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// Every method of the client is exposed as POST <base>/v1/<method>, taking and returning JSON. Arguments, signal
+// payloads, and results are transferred as JSON and converted by the server's converter.
+
+type createRequest struct {
+	Options  client.WorkflowInstanceOptions `json:"options"`
+	Workflow string                         `json:"workflow"`
+	Args     []json.RawMessage              `json:"args,omitempty"`
+}
+
+type instanceRequest struct {
+	Instance *workflow.Instance `json:"instance"`
+}
+
+type instanceIDRequest struct {
+	InstanceID string `json:"instance_id"`
+}
+
+type tagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type waitRequest struct {
+	Instance *workflow.Instance `json:"instance"`
+	Timeout  time.Duration      `json:"timeout"`
+}
+
+type signal struct {
+	Name string          `json:"name"`
+	Arg  json.RawMessage `json:"arg,omitempty"`
+}
+
+type signalRequest struct {
+	InstanceID string   `json:"instance_id,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Signals    []signal `json:"signals"`
+}
+
+type signalWorkflowsRequest struct {
+	Query  backend.InstanceQuery `json:"query"`
+	Signal signal                `json:"signal"`
+}
+
+type workflowQueryRequest struct {
+	InstanceID  string                  `json:"instance_id"`
+	Name        string                  `json:"name"`
+	Args        []json.RawMessage       `json:"args,omitempty"`
+	Consistency client.QueryConsistency `json:"consistency,omitempty"`
+
+	// Timeout is the time left until the deadline of the caller's context, 0 if it has none
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+type listRequest struct {
+	Filter backend.InstanceFilter `json:"filter"`
+}
+
+type queryRequest struct {
+	Query backend.InstanceQuery `json:"query"`
+}
+
+type terminateRequest struct {
+	Instance *workflow.Instance `json:"instance"`
+	Reason   string             `json:"reason,omitempty"`
+}
+
+type forceCompleteRequest struct {
+	Instance *workflow.Instance `json:"instance"`
+	Result   json.RawMessage    `json:"result,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	Reason   string             `json:"reason,omitempty"`
+}
+
+type streamRequest struct {
+	Stream workflow.Stream `json:"stream"`
+}
+
+type instanceFailure struct {
+	Instance *workflow.Instance `json:"instance"`
+	Error    string             `json:"error"`
+}
+
+type signalReport struct {
+	Delivered []*workflow.Instance `json:"delivered"`
+	Failed    []instanceFailure    `json:"failed"`
+}
+
+type cancelReport struct {
+	Canceled []*workflow.Instance `json:"canceled"`
+	Failed   []instanceFailure    `json:"failed"`
+}
+
+type countResponse struct {
+	Count int `json:"count"`
+}
+
+type instanceCountResponse struct {
+	Count int64 `json:"count"`
+}
+
+type stateResponse struct {
+	State backend.WorkflowState `json:"state"`
+}
+
+type resultResponse struct {
+	Result []byte `json:"result"`
+}
+
+// errorResponse is returned with a non-2xx status code
+type errorResponse struct {
+	Message string `json:"message"`
+
+	// Code identifies well-known errors, so the client can return errors matching them
+	Code string `json:"code,omitempty"`
+
+	// Failure is set if the workflow failed
+	Failure *history.Failure `json:"failure,omitempty"`
+}
+
+// knownErrors are the errors clients can match with errors.Is, by their code
+var knownErrors = map[string]error{
+	"instance_not_found":           backend.ErrInstanceNotFound,
+	"instance_already_exists":      backend.ErrInstanceAlreadyExists,
+	"instance_finished":            backend.ErrInstanceFinished,
+	"instance_not_finished":        backend.ErrInstanceNotFinished,
+	"max_active_instances":         backend.ErrMaxActiveInstancesReached,
+	"backpressure":                 backend.ErrBackpressure,
+	"throttled":                    backend.ErrThrottled,
+	"transient":                    backend.ErrTransient,
+	"timeout":                      client.ErrTimeout,
+	"workflow_canceled":            client.ErrWorkflowCanceled,
+	"workflow_terminated":          client.ErrWorkflowTerminated,
+	"workflow_not_finished":        client.ErrWorkflowNotFinished,
+	"transactions_not_supported":   client.ErrTransactionsNotSupported,
+	"stats_not_supported":          client.ErrStatsNotSupported,
+	"force_complete_not_supported": client.ErrForceCompleteNotSupported,
+	"terminate_not_supported":      client.ErrTerminateNotSupported,
+	"remove_not_supported":         client.ErrRemoveNotSupported,
+	"tags_not_supported":           client.ErrTagsNotSupported,
+	"listing_not_supported":        client.ErrListingNotSupported,
+	"lookup_not_supported":         client.ErrLookupNotSupported,
+	"query_not_supported":          client.ErrQueryNotSupported,
+	"invalid_page_token":           backend.ErrInvalidPageToken,
+	"runs_not_supported":           client.ErrRunsNotSupported,
+	"streams_not_supported":        client.ErrStreamsNotSupported,
+	"queries_not_supported":        client.ErrWorkflowQueriesNotSupported,
+	"queues_not_supported":         client.ErrWorkflowQueuesNotSupported,
+	"id_reuse_not_supported":       client.ErrIDReusePolicyNotSupported,
+	"query_timeout":                client.ErrQueryTimeout,
+	"consistency_not_supported":    client.ErrQueryConsistencyNotSupported,
+	"unauthenticated":              ErrUnauthenticated,
+	"permission_denied":            ErrPermissionDenied,
+}
+
+func newErrorResponse(err error) *errorResponse {
+	r := &errorResponse{Message: err.Error()}
+
+	for code, known := range knownErrors {
+		if errors.Is(err, known) {
+			r.Code = code
+			break
+		}
+	}
+
+	var f *history.Failure
+	if errors.As(err, &f) {
+		r.Failure = f
+	}
+
+	return r
+}
+
+// remoteError is an error returned by the server. It matches the well-known error it was created from.
+type remoteError struct {
+	message string
+	known   error
+}
+
+func (e *remoteError) Error() string {
+	return e.message
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.known
+}
+
+func (r *errorResponse) err() error {
+	if r.Failure != nil {
+		return r.Failure
+	}
+
+	return &remoteError{message: r.Message, known: knownErrors[r.Code]}
+}
+
+// instanceFailures returns the wire format of the failures of an operation on multiple instances
+func instanceFailures(failures []client.InstanceFailure) []instanceFailure {
+	res := make([]instanceFailure, 0, len(failures))
+	for _, f := range failures {
+		res = append(res, instanceFailure{Instance: f.Instance, Error: f.Err.Error()})
+	}
+
+	return res
+}
+
+// remoteFailures returns the failures of an operation on multiple instances returned by the server
+func remoteFailures(failures []instanceFailure) []client.InstanceFailure {
+	res := make([]client.InstanceFailure, 0, len(failures))
+	for _, f := range failures {
+		res = append(res, client.InstanceFailure{Instance: f.Instance, Err: &remoteError{message: f.Error}})
+	}
+
+	return res
+}
@@ -0,0 +1,447 @@
+// Package remote provides a client talking to a workflow server over HTTP instead of to the backend directly. It
+// lets applications start and manage workflows without database or Redis credentials. Serve the API with
+// NewHandler next to a regular client.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/fn"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+type Options struct {
+	// HTTPClient sends the requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Header is added to every request, for example, to authenticate with the server
+	Header http.Header
+}
+
+type Option func(*Options)
+
+// WithHTTPClient sets the HTTP client used to send requests
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = c
+	}
+}
+
+// WithHeader adds a header to every request, for example, an Authorization header
+func WithHeader(key, value string) Option {
+	return func(o *Options) {
+		if o.Header == nil {
+			o.Header = make(http.Header)
+		}
+
+		o.Header.Add(key, value)
+	}
+}
+
+type remoteClient struct {
+	baseURL string
+	options Options
+}
+
+var _ client.Client = (*remoteClient)(nil)
+
+// New creates a client for the server at baseURL, which serves a handler created with NewHandler. Workflows are
+// started by name, the workflow passed to CreateWorkflowInstance can be a workflow function or its name.
+func New(baseURL string, opts ...Option) client.Client {
+	options := Options{
+		HTTPClient: http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &remoteClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		options: options,
+	}
+}
+
+// do calls the given method on the server. If res is non-nil, the response is decoded into it.
+func (c *remoteClient) do(ctx context.Context, method string, req, res interface{}) error {
+	body, err := c.send(ctx, method, req)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if res == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(body).Decode(res); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
+
+func (c *remoteClient) send(ctx context.Context, method string, req interface{}) (io.ReadCloser, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/"+method, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range c.options.Header {
+		for _, v := range values {
+			r.Header.Add(key, v)
+		}
+	}
+
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.options.HTTPClient.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("calling %v: %w", method, err)
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+
+		var e errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+			return nil, fmt.Errorf("calling %v: %v", method, resp.Status)
+		}
+
+		return nil, e.err()
+	}
+
+	return resp.Body, nil
+}
+
+func (c *remoteClient) Converter() converter.Converter {
+	return converter.DefaultConverter
+}
+
+func (c *remoteClient) CreateWorkflowInstance(ctx context.Context, options client.WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (*workflow.Instance, error) {
+	req := &createRequest{
+		Options:  options,
+		Workflow: fn.Name(wf),
+		Args:     make([]json.RawMessage, len(args)),
+	}
+
+	for i, arg := range args {
+		data, err := json.Marshal(arg)
+		if err != nil {
+			return nil, fmt.Errorf("converting arguments: %w", err)
+		}
+
+		req.Args[i] = data
+	}
+
+	var instance *workflow.Instance
+	if err := c.do(ctx, "CreateWorkflowInstance", req, &instance); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// CreateWorkflowInstanceTx always returns client.ErrTransactionsNotSupported, transactions cannot span the server
+func (c *remoteClient) CreateWorkflowInstanceTx(ctx context.Context, tx *sql.Tx, options client.WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (*workflow.Instance, error) {
+	return nil, client.ErrTransactionsNotSupported
+}
+
+// NotifyWorkflowCreated does nothing, transactions are not supported
+func (c *remoteClient) NotifyWorkflowCreated() {}
+
+func (c *remoteClient) CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	return c.do(ctx, "CancelWorkflowInstance", &instanceRequest{Instance: instance}, nil)
+}
+
+func (c *remoteClient) ListWorkflowInstancesByTags(ctx context.Context, tags ...string) ([]*workflow.Instance, error) {
+	var instances []*workflow.Instance
+	if err := c.do(ctx, "ListWorkflowInstancesByTags", &tagsRequest{Tags: tags}, &instances); err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+func (c *remoteClient) CancelWorkflowInstancesByTags(ctx context.Context, tags ...string) (int, error) {
+	var res countResponse
+	err := c.do(ctx, "CancelWorkflowInstancesByTags", &tagsRequest{Tags: tags}, &res)
+	return res.Count, err
+}
+
+func (c *remoteClient) RestartWorkflowInstance(ctx context.Context, instance *workflow.Instance) (*workflow.Instance, error) {
+	var restarted *workflow.Instance
+	if err := c.do(ctx, "RestartWorkflowInstance", &instanceRequest{Instance: instance}, &restarted); err != nil {
+		return nil, err
+	}
+
+	return restarted, nil
+}
+
+func (c *remoteClient) ListWorkflowInstanceRuns(ctx context.Context, instanceID string) ([]*backend.WorkflowRun, error) {
+	var runs []*backend.WorkflowRun
+	if err := c.do(ctx, "ListWorkflowInstanceRuns", &instanceIDRequest{InstanceID: instanceID}, &runs); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+func (c *remoteClient) GetWorkflowRunHistory(ctx context.Context, instance *workflow.Instance) ([]history.Event, error) {
+	var h []history.Event
+	if err := c.do(ctx, "GetWorkflowRunHistory", &instanceRequest{Instance: instance}, &h); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (c *remoteClient) WaitForWorkflowInstance(ctx context.Context, instance *workflow.Instance, timeout time.Duration) error {
+	return c.do(ctx, "WaitForWorkflowInstance", &waitRequest{Instance: instance, Timeout: timeout}, nil)
+}
+
+func (c *remoteClient) GetWorkflowResultPayload(ctx context.Context, instance *workflow.Instance, timeout time.Duration) ([]byte, error) {
+	var res resultResponse
+	if err := c.do(ctx, "GetWorkflowResultPayload", &waitRequest{Instance: instance, Timeout: timeout}, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Result, nil
+}
+
+func (c *remoteClient) SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}) error {
+	s, err := newSignal(name, arg)
+	if err != nil {
+		return err
+	}
+
+	return c.do(ctx, "SignalWorkflow", &signalRequest{InstanceID: instanceID, Signals: []signal{s}}, nil)
+}
+
+func (c *remoteClient) SignalWorkflowBatch(ctx context.Context, instanceID string, signals []client.Signal) error {
+	req := &signalRequest{InstanceID: instanceID, Signals: make([]signal, len(signals))}
+
+	for i, s := range signals {
+		var err error
+		if req.Signals[i], err = newSignal(s.Name, s.Arg); err != nil {
+			return err
+		}
+	}
+
+	return c.do(ctx, "SignalWorkflowBatch", req, nil)
+}
+
+func (c *remoteClient) QueryWorkflowPayload(ctx context.Context, instanceID, name string, args ...interface{}) ([]byte, error) {
+	return c.QueryWorkflowPayloadWithOptions(ctx, instanceID, name, client.QueryOptions{}, args...)
+}
+
+func (c *remoteClient) QueryWorkflowPayloadWithOptions(ctx context.Context, instanceID, name string, options client.QueryOptions, args ...interface{}) ([]byte, error) {
+	req := &workflowQueryRequest{
+		InstanceID:  instanceID,
+		Name:        name,
+		Args:        make([]json.RawMessage, len(args)),
+		Consistency: options.Consistency,
+	}
+
+	for i, arg := range args {
+		data, err := json.Marshal(arg)
+		if err != nil {
+			return nil, fmt.Errorf("converting arguments: %w", err)
+		}
+
+		req.Args[i] = data
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Timeout = time.Until(deadline)
+	}
+
+	var res resultResponse
+	if err := c.do(ctx, "QueryWorkflowPayload", req, &res); err != nil {
+		return nil, err
+	}
+
+	return res.Result, nil
+}
+
+func (c *remoteClient) SignalWorkflowsByTags(ctx context.Context, tags []string, name string, arg interface{}) (int, error) {
+	s, err := newSignal(name, arg)
+	if err != nil {
+		return 0, err
+	}
+
+	var res countResponse
+	err = c.do(ctx, "SignalWorkflowsByTags", &signalRequest{Tags: tags, Signals: []signal{s}}, &res)
+	return res.Count, err
+}
+
+func (c *remoteClient) ListWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) ([]*workflow.Instance, error) {
+	var instances []*workflow.Instance
+	if err := c.do(ctx, "ListWorkflowInstances", &listRequest{Filter: filter}, &instances); err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+func (c *remoteClient) CountWorkflowInstances(ctx context.Context, filter backend.InstanceFilter) (int64, error) {
+	var res instanceCountResponse
+	if err := c.do(ctx, "CountWorkflowInstances", &listRequest{Filter: filter}, &res); err != nil {
+		return 0, err
+	}
+
+	return res.Count, nil
+}
+
+func (c *remoteClient) GetWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*backend.InstanceQueryResult, error) {
+	var result backend.InstanceQueryResult
+	if err := c.do(ctx, "GetWorkflowInstances", &queryRequest{Query: query}, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *remoteClient) SignalWorkflows(ctx context.Context, query backend.InstanceQuery, name string, arg interface{}) (*client.SignalReport, error) {
+	s, err := newSignal(name, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	var res signalReport
+	if err := c.do(ctx, "SignalWorkflows", &signalWorkflowsRequest{Query: query, Signal: s}, &res); err != nil {
+		return nil, err
+	}
+
+	return &client.SignalReport{Delivered: res.Delivered, Failed: remoteFailures(res.Failed)}, nil
+}
+
+func (c *remoteClient) CancelWorkflowInstances(ctx context.Context, query backend.InstanceQuery) (*client.CancelReport, error) {
+	var res cancelReport
+	if err := c.do(ctx, "CancelWorkflowInstances", &queryRequest{Query: query}, &res); err != nil {
+		return nil, err
+	}
+
+	return &client.CancelReport{Canceled: res.Canceled, Failed: remoteFailures(res.Failed)}, nil
+}
+
+func (c *remoteClient) GetWorkflowInstance(ctx context.Context, instanceID string) (*workflow.Instance, error) {
+	var instance *workflow.Instance
+	if err := c.do(ctx, "GetWorkflowInstance", &instanceIDRequest{InstanceID: instanceID}, &instance); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+func (c *remoteClient) GetWorkflowInstanceState(ctx context.Context, instance *workflow.Instance) (backend.WorkflowState, error) {
+	var res stateResponse
+	err := c.do(ctx, "GetWorkflowInstanceState", &instanceRequest{Instance: instance}, &res)
+	return res.State, err
+}
+
+func (c *remoteClient) GetWorkflowInstanceStats(ctx context.Context, instance *workflow.Instance) (*backend.InstanceStats, error) {
+	var stats *backend.InstanceStats
+	if err := c.do(ctx, "GetWorkflowInstanceStats", &instanceRequest{Instance: instance}, &stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (c *remoteClient) TerminateWorkflowInstance(ctx context.Context, instance *workflow.Instance, reason string) error {
+	return c.do(ctx, "TerminateWorkflowInstance", &terminateRequest{Instance: instance, Reason: reason}, nil)
+}
+
+func (c *remoteClient) RemoveWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	return c.do(ctx, "RemoveWorkflowInstance", &instanceRequest{Instance: instance}, nil)
+}
+
+func (c *remoteClient) PauseSchedule(ctx context.Context, instanceID string) error {
+	return c.do(ctx, "PauseSchedule", &instanceIDRequest{InstanceID: instanceID}, nil)
+}
+
+func (c *remoteClient) ResumeSchedule(ctx context.Context, instanceID string) error {
+	return c.do(ctx, "ResumeSchedule", &instanceIDRequest{InstanceID: instanceID}, nil)
+}
+
+func (c *remoteClient) DeleteSchedule(ctx context.Context, instanceID string) error {
+	return c.do(ctx, "DeleteSchedule", &instanceIDRequest{InstanceID: instanceID}, nil)
+}
+
+func (c *remoteClient) ForceCompleteWorkflowInstance(ctx context.Context, instance *workflow.Instance, options client.ForceCompleteOptions) error {
+	req := &forceCompleteRequest{Instance: instance, Reason: options.Reason}
+
+	if options.Error != nil {
+		req.Error = options.Error.Error()
+	} else if options.Result != nil {
+		data, err := json.Marshal(options.Result)
+		if err != nil {
+			return fmt.Errorf("converting result: %w", err)
+		}
+
+		req.Result = data
+	}
+
+	return c.do(ctx, "ForceCompleteWorkflowInstance", req, nil)
+}
+
+// OpenStream returns a reader for the response streaming the data. The response is closed once the reader is read
+// to the end.
+func (c *remoteClient) OpenStream(ctx context.Context, s workflow.Stream) (io.Reader, error) {
+	body, err := c.send(ctx, "OpenStream", &streamRequest{Stream: s})
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamReader{rc: body, size: s.Size}, nil
+}
+
+func newSignal(name string, arg interface{}) (signal, error) {
+	data, err := json.Marshal(arg)
+	if err != nil {
+		return signal{}, fmt.Errorf("converting signal argument: %w", err)
+	}
+
+	return signal{Name: name, Arg: data}, nil
+}
+
+// streamReader closes the response when reaching its end or failing. The server cannot report errors once it
+// started streaming, a truncated stream is detected by its size.
+type streamReader struct {
+	rc   io.ReadCloser
+	size int64
+	read int64
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	r.read += int64(n)
+
+	if err != nil {
+		r.rc.Close()
+
+		if err == io.EOF && r.read != r.size {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+
+	return n, err
+}
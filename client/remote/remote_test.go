@@ -0,0 +1,153 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Workflow1(ctx workflow.Context, msg string) (string, error) {
+	c := workflow.NewSignalChannel[string](ctx, "signal")
+	s, _ := c.Receive(ctx)
+
+	return msg + " " + s, nil
+}
+
+func Workflow2(ctx workflow.Context) error {
+	return errors.New("workflow failed")
+}
+
+func newTestClient(t *testing.T, opts ...Option) (client.Client, func()) {
+	url, stop := newTestServer(t)
+
+	return New(url, opts...), stop
+}
+
+func newTestServer(t *testing.T, opts ...HandlerOption) (string, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := sqlite.NewInMemoryBackend()
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(Workflow1))
+	require.NoError(t, w.RegisterWorkflow(Workflow2))
+	require.NoError(t, w.Start(ctx))
+
+	srv := httptest.NewServer(NewHandler(client.New(b), opts...))
+
+	return srv.URL, func() {
+		srv.Close()
+
+		// Let in-flight tasks finish, canceling them makes the worker panic
+		require.NoError(t, w.Shutdown(context.Background()))
+		cancel()
+	}
+}
+
+func Test_Remote_RunsWorkflow(t *testing.T) {
+	c, stop := newTestClient(t)
+	defer stop()
+
+	ctx := context.Background()
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, Workflow1, "hello")
+	require.NoError(t, err)
+
+	require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "signal", "world"))
+
+	r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", r)
+
+	h, err := c.GetWorkflowRunHistory(ctx, instance)
+	require.NoError(t, err)
+	require.NotEmpty(t, h)
+}
+
+func Test_Remote_StartsWorkflowByName(t *testing.T) {
+	c, stop := newTestClient(t)
+	defer stop()
+
+	ctx := context.Background()
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, "Workflow1", "hello")
+	require.NoError(t, err)
+
+	require.NoError(t, c.SignalWorkflowBatch(ctx, instance.InstanceID, []client.Signal{{Name: "signal", Arg: "by name"}}))
+
+	r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+	require.NoError(t, err)
+	require.Equal(t, "hello by name", r)
+}
+
+func Test_Remote_CancelsWorkflowInstances(t *testing.T) {
+	c, stop := newTestClient(t)
+	defer stop()
+
+	ctx := context.Background()
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, Workflow1, "hello")
+	require.NoError(t, err)
+
+	report, err := c.CancelWorkflowInstances(ctx, backend.InstanceQuery{InstanceIDPrefix: instance.InstanceID})
+	require.NoError(t, err)
+	require.Len(t, report.Canceled, 1)
+	require.Equal(t, instance.InstanceID, report.Canceled[0].InstanceID)
+	require.Empty(t, report.Failed)
+}
+
+func Test_Remote_Errors(t *testing.T) {
+	c, stop := newTestClient(t)
+	defer stop()
+
+	ctx := context.Background()
+
+	_, err := c.GetWorkflowInstanceState(ctx, &workflow.Instance{InstanceID: "missing", ExecutionID: "missing"})
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	options := client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}
+
+	instance, err := c.CreateWorkflowInstance(ctx, options, Workflow2)
+	require.NoError(t, err)
+
+	_, err = c.CreateWorkflowInstance(ctx, options, Workflow2)
+	require.ErrorIs(t, err, backend.ErrInstanceAlreadyExists)
+
+	_, err = client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+	require.ErrorContains(t, err, "workflow failed")
+
+	_, err = c.CreateWorkflowInstanceTx(ctx, nil, options, Workflow2)
+	require.ErrorIs(t, err, client.ErrTransactionsNotSupported)
+}
+
+func Test_Remote_SendsHeaders(t *testing.T) {
+	var header string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithHeader("Authorization", "Bearer token"))
+
+	require.NoError(t, c.CancelWorkflowInstance(context.Background(), &workflow.Instance{InstanceID: "instance"}))
+	require.Equal(t, "Bearer token", header)
+}
@@ -0,0 +1,513 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/converter"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+type handlerFunc func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error)
+
+var handlers = map[string]handlerFunc{
+	"CreateWorkflowInstance": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r createRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		args := make([]interface{}, len(r.Args))
+		for i, arg := range r.Args {
+			args[i] = arg
+		}
+
+		return c.CreateWorkflowInstance(ctx, r.Options, r.Workflow, args...)
+	},
+	"CancelWorkflowInstance": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r instanceRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return nil, c.CancelWorkflowInstance(ctx, r.Instance)
+	},
+	"ListWorkflowInstancesByTags": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r tagsRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return c.ListWorkflowInstancesByTags(ctx, r.Tags...)
+	},
+	"CancelWorkflowInstancesByTags": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r tagsRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		n, err := c.CancelWorkflowInstancesByTags(ctx, r.Tags...)
+		return &countResponse{Count: n}, err
+	},
+	"RestartWorkflowInstance": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r instanceRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return c.RestartWorkflowInstance(ctx, r.Instance)
+	},
+	"ListWorkflowInstanceRuns": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r instanceIDRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return c.ListWorkflowInstanceRuns(ctx, r.InstanceID)
+	},
+	"GetWorkflowRunHistory": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r instanceRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return c.GetWorkflowRunHistory(ctx, r.Instance)
+	},
+	"WaitForWorkflowInstance": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r waitRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return nil, c.WaitForWorkflowInstance(ctx, r.Instance, r.Timeout)
+	},
+	"GetWorkflowResultPayload": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r waitRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		p, err := c.GetWorkflowResultPayload(ctx, r.Instance, r.Timeout)
+		return &resultResponse{Result: p}, err
+	},
+	"SignalWorkflow": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r signalRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		if len(r.Signals) != 1 {
+			return nil, &badRequestError{err: errors.New("expected exactly one signal")}
+		}
+
+		return nil, c.SignalWorkflow(ctx, r.InstanceID, r.Signals[0].Name, r.Signals[0].arg())
+	},
+	"SignalWorkflowBatch": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r signalRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		signals := make([]client.Signal, len(r.Signals))
+		for i, s := range r.Signals {
+			signals[i] = client.Signal{Name: s.Name, Arg: s.arg()}
+		}
+
+		return nil, c.SignalWorkflowBatch(ctx, r.InstanceID, signals)
+	},
+	"QueryWorkflowPayload": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r workflowQueryRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		if r.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+			defer cancel()
+		}
+
+		args := make([]interface{}, len(r.Args))
+		for i, arg := range r.Args {
+			args[i] = arg
+		}
+
+		p, err := c.QueryWorkflowPayloadWithOptions(ctx, r.InstanceID, r.Name, client.QueryOptions{Consistency: r.Consistency}, args...)
+		return &resultResponse{Result: p}, err
+	},
+	"SignalWorkflowsByTags": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r signalRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		if len(r.Signals) != 1 {
+			return nil, &badRequestError{err: errors.New("expected exactly one signal")}
+		}
+
+		n, err := c.SignalWorkflowsByTags(ctx, r.Tags, r.Signals[0].Name, r.Signals[0].arg())
+		return &countResponse{Count: n}, err
+	},
+	"ListWorkflowInstances": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r listRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return c.ListWorkflowInstances(ctx, r.Filter)
+	},
+	"CountWorkflowInstances": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r listRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		n, err := c.CountWorkflowInstances(ctx, r.Filter)
+		return &instanceCountResponse{Count: n}, err
+	},
+	"GetWorkflowInstances": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r queryRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return c.GetWorkflowInstances(ctx, r.Query)
+	},
+	"SignalWorkflows": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r signalWorkflowsRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		report, err := c.SignalWorkflows(ctx, r.Query, r.Signal.Name, r.Signal.arg())
+		if err != nil {
+			return nil, err
+		}
+
+		return &signalReport{Delivered: report.Delivered, Failed: instanceFailures(report.Failed)}, nil
+	},
+	"CancelWorkflowInstances": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r queryRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		report, err := c.CancelWorkflowInstances(ctx, r.Query)
+		if err != nil {
+			return nil, err
+		}
+
+		return &cancelReport{Canceled: report.Canceled, Failed: instanceFailures(report.Failed)}, nil
+	},
+	"GetWorkflowInstance": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r instanceIDRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return c.GetWorkflowInstance(ctx, r.InstanceID)
+	},
+	"GetWorkflowInstanceState": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r instanceRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		s, err := c.GetWorkflowInstanceState(ctx, r.Instance)
+		return &stateResponse{State: s}, err
+	},
+	"GetWorkflowInstanceStats": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r instanceRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return c.GetWorkflowInstanceStats(ctx, r.Instance)
+	},
+	"TerminateWorkflowInstance": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r terminateRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return nil, c.TerminateWorkflowInstance(ctx, r.Instance, r.Reason)
+	},
+	"RemoveWorkflowInstance": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r instanceRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return nil, c.RemoveWorkflowInstance(ctx, r.Instance)
+	},
+	"PauseSchedule": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r instanceIDRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return nil, c.PauseSchedule(ctx, r.InstanceID)
+	},
+	"ResumeSchedule": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r instanceIDRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return nil, c.ResumeSchedule(ctx, r.InstanceID)
+	},
+	"DeleteSchedule": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r instanceIDRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		return nil, c.DeleteSchedule(ctx, r.InstanceID)
+	},
+	"ForceCompleteWorkflowInstance": func(ctx context.Context, c client.Client, body io.Reader) (interface{}, error) {
+		var r forceCompleteRequest
+		if err := decode(body, &r); err != nil {
+			return nil, err
+		}
+
+		options := client.ForceCompleteOptions{Reason: r.Reason}
+		if len(r.Result) > 0 {
+			options.Result = r.Result
+		}
+
+		if r.Error != "" {
+			options.Error = errors.New(r.Error)
+		}
+
+		return nil, c.ForceCompleteWorkflowInstance(ctx, r.Instance, options)
+	},
+}
+
+func (s *signal) arg() interface{} {
+	if len(s.Arg) == 0 {
+		return nil
+	}
+
+	return s.Arg
+}
+
+func decode(body io.Reader, v interface{}) error {
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return &badRequestError{err: fmt.Errorf("decoding request: %w", err)}
+	}
+
+	return nil
+}
+
+type badRequestError struct {
+	err error
+}
+
+func (e *badRequestError) Error() string {
+	return e.err.Error()
+}
+
+type handlerOptions struct {
+	redactor       converter.PayloadRedactor
+	authenticators []Authenticator
+	authorizer     Authorizer
+	maxRequestSize int64
+}
+
+// DefaultMaxRequestSize is the default limit for the size of request bodies, see WithMaxRequestSize
+const DefaultMaxRequestSize = 10 << 20
+
+// HandlerOption configures a handler created with NewHandler
+type HandlerOption func(*handlerOptions)
+
+// WithHistoryRedactor applies the given redactor to all payloads of the histories returned by
+// GetWorkflowRunHistory, so sensitive data doesn't leave the server when histories are exported
+func WithHistoryRedactor(r converter.PayloadRedactor) HandlerOption {
+	return func(o *handlerOptions) {
+		o.redactor = r
+	}
+}
+
+// WithAuthenticators requires every request to be authenticated by one of the given authenticators, tried in
+// order. Requests without valid credentials are rejected with ErrUnauthenticated.
+func WithAuthenticators(authenticators ...Authenticator) HandlerOption {
+	return func(o *handlerOptions) {
+		o.authenticators = append(o.authenticators, authenticators...)
+	}
+}
+
+// WithAuthorizer checks every request with the given authorizer before it is executed. Rejected requests fail
+// with ErrPermissionDenied.
+func WithAuthorizer(a Authorizer) HandlerOption {
+	return func(o *handlerOptions) {
+		o.authorizer = a
+	}
+}
+
+// WithMaxRequestSize limits the size of request bodies to n bytes. Larger requests are rejected. Defaults to
+// DefaultMaxRequestSize.
+func WithMaxRequestSize(n int64) HandlerOption {
+	return func(o *handlerOptions) {
+		o.maxRequestSize = n
+	}
+}
+
+// NewHandler returns an HTTP handler exposing the given client, for remote clients created with New. Arguments,
+// signal payloads, and results are encoded as JSON, so the client needs to use the default converter.
+//
+// Without WithAuthenticators, the handler does not authenticate requests and should only be reachable from a
+// trusted network. Remote clients send credentials with WithHeader, or with a client certificate configured in
+// the HTTP client passed to WithHTTPClient.
+func NewHandler(c client.Client, opts ...HandlerOption) http.Handler {
+	options := handlerOptions{
+		maxRequestSize: DefaultMaxRequestSize,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	m := http.NewServeMux()
+
+	m.HandleFunc("/v1/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+
+		method := strings.TrimPrefix(r.URL.Path, "/v1/")
+
+		r.Body = http.MaxBytesReader(w, r.Body, options.maxRequestSize)
+
+		if len(options.authenticators) > 0 {
+			p, err := authenticate(r, options.authenticators)
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, err)
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+		}
+
+		if options.authorizer != nil {
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+
+			var req operationRequest
+			if err := decode(bytes.NewReader(data), &req); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+
+			op := req.operation(method)
+			if err := resolveWorkflow(r.Context(), c, op, req.executionID()); err != nil {
+				writeError(w, statusCode(err), err)
+				return
+			}
+
+			if err := authorize(r.Context(), options.authorizer, PrincipalFromContext(r.Context()), op); err != nil {
+				writeError(w, http.StatusForbidden, err)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(data))
+		}
+
+		if method == "OpenStream" {
+			serveStream(w, r, c)
+			return
+		}
+
+		h, ok := handlers[method]
+		if !ok {
+			writeError(w, http.StatusNotFound, errors.New("unknown method "+method))
+			return
+		}
+
+		res, err := h(r.Context(), c, r.Body)
+		if err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+
+		if events, ok := res.([]history.Event); ok && options.redactor != nil {
+			res = history.RedactEvents(events, options.redactor)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if res == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		json.NewEncoder(w).Encode(res)
+	})
+
+	return m
+}
+
+func serveStream(w http.ResponseWriter, r *http.Request, c client.Client) {
+	var req streamRequest
+	if err := decode(r.Body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sr, err := c.OpenStream(r.Context(), req.Stream)
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	// Errors while streaming cannot be reported anymore, the client sees a truncated body
+	io.Copy(w, sr)
+}
+
+func statusCode(err error) int {
+	var badRequest *badRequestError
+	if errors.As(err, &badRequest) {
+		return http.StatusBadRequest
+	}
+
+	r := newErrorResponse(err)
+	if r.Failure != nil {
+		return http.StatusUnprocessableEntity
+	}
+
+	switch r.Code {
+	case "instance_not_found":
+		return http.StatusNotFound
+	case "instance_already_exists", "instance_finished", "workflow_not_finished":
+		return http.StatusConflict
+	case "max_active_instances", "backpressure", "throttled":
+		return http.StatusTooManyRequests
+	case "transient":
+		return http.StatusServiceUnavailable
+	case "unauthenticated":
+		return http.StatusUnauthorized
+	case "permission_denied":
+		return http.StatusForbidden
+	case "":
+		return http.StatusInternalServerError
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(newErrorResponse(err))
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+)
+
+// RetryPolicy configures how the client retries creating and signaling workflow instances after transient
+// backend errors
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first one
+	MaxAttempts int
+
+	// FirstRetryInterval is the delay before the first retry. Delays are jittered by +/- 50%. Defaults to 100ms.
+	FirstRetryInterval time.Duration
+
+	// BackoffCoefficient is multiplied with the delay after every retry. Defaults to 2.
+	BackoffCoefficient float64
+
+	// MaxRetryInterval caps the delay between retries. 0 does not cap the delay.
+	MaxRetryInterval time.Duration
+
+	// IsRetryable decides whether an error is transient. Defaults to IsTransientError.
+	IsRetryable func(error) bool
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:        5,
+	FirstRetryInterval: 100 * time.Millisecond,
+	BackoffCoefficient: 2,
+	MaxRetryInterval:   5 * time.Second,
+}
+
+// IsTransientError returns whether err is a transient error that is worth retrying: errors matching
+// backend.ErrTransient, backend.ErrThrottled, or backend.ErrBackpressure, broken or refused connections, and
+// network timeouts.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, backend.ErrTransient) ||
+		errors.Is(err, backend.ErrThrottled) ||
+		errors.Is(err, backend.ErrBackpressure) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (p *RetryPolicy) isRetryable(err error) bool {
+	if p.IsRetryable != nil {
+		return p.IsRetryable(err)
+	}
+
+	return IsTransientError(err)
+}
+
+// delay returns the delay before the given retry, starting at 1. Throttled operations wait at least as long as
+// the backend asks for.
+func (p *RetryPolicy) delay(retry int, err error) time.Duration {
+	interval := p.FirstRetryInterval
+	if interval <= 0 {
+		interval = DefaultRetryPolicy.FirstRetryInterval
+	}
+
+	coefficient := p.BackoffCoefficient
+	if coefficient < 1 {
+		coefficient = DefaultRetryPolicy.BackoffCoefficient
+	}
+
+	for i := 1; i < retry; i++ {
+		interval = time.Duration(float64(interval) * coefficient)
+
+		if p.MaxRetryInterval > 0 && interval > p.MaxRetryInterval {
+			interval = p.MaxRetryInterval
+			break
+		}
+	}
+
+	interval = interval/2 + time.Duration(rand.Int63n(int64(interval)+1))
+
+	var throttled *backend.ThrottledError
+	if errors.As(err, &throttled) && throttled.RetryAfter > interval {
+		interval = throttled.RetryAfter
+	}
+
+	return interval
+}
+
+// retry calls fn until it succeeds, fails with an error that is not transient, or the attempts of the retry
+// policy are exhausted. Without a retry policy, fn is called once.
+func (c *client) retry(ctx context.Context, operation string, fn func() error) error {
+	p := c.options.RetryPolicy
+	if p == nil {
+		return fn()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !p.isRetryable(err) {
+			return err
+		}
+
+		delay := p.delay(attempt, err)
+
+		c.logger().Debug("Retrying after transient error", "operation", operation, "attempt", attempt, "delay", delay, "error", err)
+
+		t := c.clock.Timer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Command dev-server runs a single process development server with a SQLite backend, a worker, and the
+// diagnostics web UI. Pass -demo to run a sample workflow so there is something to look at.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/devserver"
+	"github.com/google/uuid"
+)
+
+func main() {
+	addr := flag.String("addr", ":3000", "address to listen on")
+	db := flag.String("db", "", "path of the SQLite database file, in-memory if empty")
+	demo := flag.Bool("demo", false, "start a demo workflow instance")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	s, err := devserver.New(devserver.Options{
+		Addr:         *addr,
+		DatabasePath: *db,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := s.Worker()
+	if err := w.RegisterWorkflow(Greeting); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := w.RegisterActivity(Greet); err != nil {
+		log.Fatal(err)
+	}
+
+	if *demo {
+		go func() {
+			wf, err := s.Client().CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+				InstanceID: uuid.NewString(),
+			}, Greeting, "world")
+			if err != nil {
+				log.Println("could not start demo workflow:", err)
+				return
+			}
+
+			log.Println("Started demo workflow instance", wf.InstanceID)
+		}()
+	}
+
+	log.Printf("Diagnostics UI available at http://localhost%v/diag/\n", *addr)
+
+	if err := s.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
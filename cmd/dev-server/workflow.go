@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// Greeting is a demo workflow that waits for a bit and then greets the given name
+func Greeting(ctx workflow.Context, name string) (string, error) {
+	if _, err := workflow.ScheduleTimer(ctx, time.Second*2).Get(ctx); err != nil {
+		return "", err
+	}
+
+	return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, Greet, name).Get(ctx)
+}
+
+func Greet(ctx context.Context, name string) (string, error) {
+	return fmt.Sprintf("Hello %v!", name), nil
+}
@@ -0,0 +1,92 @@
+// Package activities provides ready-made activities for common integrations. Register them with a worker
+// using Register and call them from workflows using the methods of Activities:
+//
+//	var a *activities.Activities
+//	r, err := workflow.ExecuteActivity[*activities.HTTPResponse](ctx, workflow.DefaultActivityOptions, a.HTTP, activities.HTTPRequest{
+//		Method: http.MethodGet,
+//		URL:    "https://example.com",
+//	}).Get(ctx)
+package activities
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cschleiden/go-workflows/worker"
+)
+
+// DefaultMaxCaptureSize is the default number of bytes captured of HTTP response bodies and command output
+const DefaultMaxCaptureSize = 1024 * 1024
+
+type Activities struct {
+	httpClient *http.Client
+
+	maxCaptureSize int64
+
+	allowedCommands map[string]bool
+}
+
+type Option func(*Activities)
+
+// WithHTTPClient sets the client used for HTTP requests. Defaults to http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(a *Activities) {
+		a.httpClient = c
+	}
+}
+
+// WithMaxCaptureSize sets the number of bytes captured of HTTP response bodies and command output.
+// Anything beyond that is discarded and the result is marked as truncated.
+func WithMaxCaptureSize(n int64) Option {
+	return func(a *Activities) {
+		a.maxCaptureSize = n
+	}
+}
+
+// WithAllowedCommands sets the commands the Exec activity may run. Exec refuses to run any command
+// unless it's allowed explicitly.
+func WithAllowedCommands(commands ...string) Option {
+	return func(a *Activities) {
+		for _, c := range commands {
+			a.allowedCommands[c] = true
+		}
+	}
+}
+
+func New(opts ...Option) *Activities {
+	a := &Activities{
+		httpClient:      http.DefaultClient,
+		maxCaptureSize:  DefaultMaxCaptureSize,
+		allowedCommands: map[string]bool{},
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Register registers all activities of this package with the given worker
+func Register(r worker.ActivityRegistry, opts ...Option) error {
+	return r.RegisterActivity(New(opts...))
+}
+
+// Sleep waits for the given duration, or until the activity is canceled. Useful for testing.
+func (a *Activities) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Noop does nothing. Useful for testing.
+func (a *Activities) Noop(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,130 @@
+package activities
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Register(t *testing.T) {
+	w := worker.New(sqlite.NewInMemoryBackend(), nil)
+
+	require.NoError(t, Register(w))
+}
+
+func Test_HTTP_RetriesServerErrors(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("X-Test", "value")
+		w.Write([]byte("hello"))
+	}))
+	defer s.Close()
+
+	a := New()
+
+	resp, err := a.HTTP(context.Background(), HTTPRequest{
+		URL:                s.URL,
+		FirstRetryInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "hello", string(resp.Body))
+	require.Equal(t, "value", resp.Headers.Get("X-Test"))
+	require.Equal(t, 3, resp.Attempts)
+}
+
+func Test_HTTP_DoesNotRetryNonIdempotentOrClientErrors(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	a := New()
+
+	resp, err := a.HTTP(context.Background(), HTTPRequest{Method: http.MethodPost, URL: s.URL, FirstRetryInterval: time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.Equal(t, 1, resp.Attempts)
+
+	resp, err = a.HTTP(context.Background(), HTTPRequest{URL: s.URL, FirstRetryInterval: time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, 1, resp.Attempts)
+
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func Test_HTTP_TruncatesBody(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}))
+	defer s.Close()
+
+	a := New(WithMaxCaptureSize(5))
+
+	resp, err := a.HTTP(context.Background(), HTTPRequest{URL: s.URL})
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(resp.Body))
+	require.True(t, resp.Truncated)
+}
+
+func Test_Exec(t *testing.T) {
+	a := New(WithAllowedCommands("sh"))
+
+	r, err := a.Exec(context.Background(), ExecRequest{
+		Command: "sh",
+		Args:    []string{"-c", "echo $GREETING; echo oops >&2; exit 3"},
+		Env:     []string{"GREETING=hello"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, r.ExitCode)
+	require.Equal(t, "hello\n", string(r.Stdout))
+	require.Equal(t, "oops\n", string(r.Stderr))
+}
+
+func Test_Exec_NotAllowed(t *testing.T) {
+	a := New()
+
+	_, err := a.Exec(context.Background(), ExecRequest{Command: "sh"})
+	require.ErrorContains(t, err, "not allowed")
+}
+
+func Test_Exec_Timeout(t *testing.T) {
+	a := New(WithAllowedCommands("sleep"))
+
+	_, err := a.Exec(context.Background(), ExecRequest{
+		Command: "sleep",
+		Args:    []string{"10"},
+		Timeout: 50 * time.Millisecond,
+	})
+	require.ErrorContains(t, err, "timed out")
+}
+
+func Test_Sleep_Canceled(t *testing.T) {
+	a := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(t, a.Sleep(ctx, time.Minute), context.Canceled)
+}
@@ -0,0 +1,108 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+type ExecRequest struct {
+	// Command is the name or path of the command, it has to be allowed using WithAllowedCommands
+	Command string
+	Args    []string
+
+	// Dir is the working directory of the command. Defaults to the working directory of the worker.
+	Dir string
+
+	// Env are additional environment variables in the form key=value
+	Env []string
+
+	Stdin []byte
+
+	// Timeout after which the command is killed. Defaults to 1 minute.
+	Timeout time.Duration
+}
+
+type ExecResult struct {
+	ExitCode int
+
+	// Stdout and Stderr contain the output of the command, up to the configured capture size
+	Stdout []byte
+	Stderr []byte
+
+	// Truncated is set if any output was larger than the configured capture size
+	Truncated bool
+}
+
+// Exec runs a command and captures its output. A non-zero exit code is not an error, check the exit code of
+// the result. Commands that time out or cannot be started return an error.
+func (a *Activities) Exec(ctx context.Context, req ExecRequest) (*ExecResult, error) {
+	if !a.allowedCommands[req.Command] {
+		return nil, fmt.Errorf("command %q is not allowed", req.Command)
+	}
+
+	if req.Timeout <= 0 {
+		req.Timeout = time.Minute
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, req.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, req.Command, req.Args...)
+	cmd.Dir = req.Dir
+	if len(req.Env) > 0 {
+		cmd.Env = append(os.Environ(), req.Env...)
+	}
+	cmd.Stdin = bytes.NewReader(req.Stdin)
+
+	stdout := &cappedBuffer{max: a.maxCaptureSize}
+	stderr := &cappedBuffer{max: a.maxCaptureSize}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("command %q timed out after %v", req.Command, req.Timeout)
+	}
+
+	result := &ExecResult{
+		Stdout:    stdout.Bytes(),
+		Stderr:    stderr.Bytes(),
+		Truncated: stdout.truncated || stderr.truncated,
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		return nil, fmt.Errorf("running command %q: %w", req.Command, err)
+	}
+
+	return result, nil
+}
+
+// cappedBuffer keeps up to max bytes written to it and discards the rest
+type cappedBuffer struct {
+	bytes.Buffer
+
+	max       int64
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+
+	if remaining := b.max - int64(b.Len()); int64(len(p)) > remaining {
+		p = p[:remaining]
+		b.truncated = true
+	}
+
+	b.Buffer.Write(p)
+
+	// Report everything as written, otherwise the command fails with a short write
+	return n, nil
+}
@@ -0,0 +1,197 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type HTTPRequest struct {
+	Method string
+	URL    string
+
+	Headers http.Header
+	Body    []byte
+
+	// Timeout for every attempt. Defaults to 30 seconds.
+	Timeout time.Duration
+
+	// MaxAttempts is the number of attempts for connection errors and responses with status 429 or 5xx.
+	// Defaults to 3 for idempotent methods and 1 otherwise.
+	MaxAttempts int
+
+	// FirstRetryInterval is the time to wait before the first retry. Defaults to 1 second.
+	FirstRetryInterval time.Duration
+
+	// BackoffCoefficient is applied to the retry interval after every attempt. Defaults to 2.
+	BackoffCoefficient float64
+
+	// MaxRetryInterval caps the time between attempts, including waiting for a Retry-After header.
+	// Defaults to 30 seconds.
+	MaxRetryInterval time.Duration
+}
+
+type HTTPResponse struct {
+	StatusCode int
+
+	Headers http.Header
+
+	// Body contains the response body, up to the configured capture size
+	Body []byte
+
+	// Truncated is set if the body was larger than the configured capture size
+	Truncated bool
+
+	// Attempts is the number of requests sent
+	Attempts int
+}
+
+// HTTP sends an HTTP request and captures the response. Connection errors and responses with status 429 or
+// 5xx are retried with exponential backoff. Other responses, including client errors, are returned without
+// an error, check the status code of the response.
+func (a *Activities) HTTP(ctx context.Context, req HTTPRequest) (*HTTPResponse, error) {
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+
+	if req.Timeout <= 0 {
+		req.Timeout = 30 * time.Second
+	}
+
+	if req.MaxAttempts <= 0 {
+		req.MaxAttempts = 1
+		if idempotent(req.Method) {
+			req.MaxAttempts = 3
+		}
+	}
+
+	if req.FirstRetryInterval <= 0 {
+		req.FirstRetryInterval = time.Second
+	}
+
+	if req.BackoffCoefficient < 1 {
+		req.BackoffCoefficient = 2
+	}
+
+	if req.MaxRetryInterval <= 0 {
+		req.MaxRetryInterval = 30 * time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, retryAfter, err := a.sendHTTPRequest(ctx, req)
+		if resp != nil {
+			resp.Attempts = attempt
+		}
+
+		if !retryable(resp, err) || attempt >= req.MaxAttempts {
+			return resp, err
+		}
+
+		backoff := time.Duration(float64(req.FirstRetryInterval) * math.Pow(req.BackoffCoefficient, float64(attempt-1)))
+		if retryAfter > backoff {
+			backoff = retryAfter
+		}
+
+		if backoff > req.MaxRetryInterval {
+			backoff = req.MaxRetryInterval
+		}
+
+		if err := a.Sleep(ctx, backoff); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (a *Activities) sendHTTPRequest(ctx context.Context, req HTTPRequest) (*HTTPResponse, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, req.Timeout)
+	defer cancel()
+
+	r, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("creating request: %w", err)
+	}
+
+	for k, v := range req.Headers {
+		r.Header[k] = v
+	}
+
+	resp, err := a.httpClient.Do(r)
+	if err != nil {
+		return nil, 0, &retryableError{fmt.Errorf("sending request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	body, truncated, err := readCapped(resp.Body, a.maxCaptureSize)
+	if err != nil {
+		return nil, 0, &retryableError{fmt.Errorf("reading response body: %w", err)}
+	}
+
+	return &HTTPResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header,
+		Body:       body,
+		Truncated:  truncated,
+	}, retryAfter(resp), nil
+}
+
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+func retryable(resp *HTTPResponse, err error) bool {
+	if err != nil {
+		var re *retryableError
+		return errors.As(err, &re)
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the response, if it's given in seconds
+func retryAfter(resp *http.Response) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+
+	return 0
+}
+
+// readCapped reads up to max bytes from r and discards the rest
+func readCapped(r io.Reader, max int64) ([]byte, bool, error) {
+	b, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, false, err
+	}
+
+	if int64(len(b)) > max {
+		// Drain the rest to allow reusing the connection
+		io.Copy(io.Discard, r)
+
+		return b[:max], true, nil
+	}
+
+	return b, false, nil
+}
@@ -0,0 +1,274 @@
+// Package export streams the events appended to the history of workflow instances to external systems like
+// Kafka, a webhook, or files, so analytics and data pipelines can consume workflow activity without polling the
+// backend. Pass an Exporter to the backend with backend.WithHistoryExporter and run it next to the worker.
+package export
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/logger"
+	"github.com/cschleiden/go-workflows/log"
+)
+
+// Record is a history event of a workflow instance
+type Record struct {
+	InstanceID       string `json:"instance_id"`
+	ExecutionID      string `json:"execution_id"`
+	ParentInstanceID string `json:"parent_instance_id,omitempty"`
+
+	// State is the state of the instance after the event was appended, "active" or "finished"
+	State string `json:"state"`
+
+	EventID         string      `json:"event_id"`
+	SequenceID      int64       `json:"sequence_id"`
+	Type            string      `json:"type"`
+	Timestamp       time.Time   `json:"timestamp"`
+	ScheduleEventID int64       `json:"schedule_event_id,omitempty"`
+	Attributes      interface{} `json:"attributes,omitempty"`
+}
+
+// Sink delivers records to an external system. Records of an instance are passed in the order they were
+// appended. If Write returns an error, the same records are passed again later, so a sink needs to deliver
+// a batch atomically or consumers need to ignore duplicates by EventID.
+type Sink interface {
+	Write(ctx context.Context, records []*Record) error
+}
+
+type Options struct {
+	Logger log.Logger
+
+	// BufferSize is the number of records buffered while the sink is slow or unavailable. When the buffer is
+	// full, new records are dropped, so exporting never blocks workflow execution.
+	BufferSize int
+
+	// BatchSize is the maximum number of records passed to the sink at once
+	BatchSize int
+
+	// FlushInterval is the maximum time records are buffered before they are passed to the sink
+	FlushInterval time.Duration
+
+	// MaxRetryInterval is the maximum time between attempts to write a failed batch again
+	MaxRetryInterval time.Duration
+
+	// ShutdownTimeout is the time Run keeps writing buffered records after its context has been canceled
+	ShutdownTimeout time.Duration
+}
+
+var DefaultOptions = Options{
+	BufferSize:       10_000,
+	BatchSize:        100,
+	FlushInterval:    time.Second,
+	MaxRetryInterval: time.Second * 30,
+	ShutdownTimeout:  time.Second * 5,
+}
+
+type Option func(*Options)
+
+func WithLogger(logger log.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithBufferSize(size int) Option {
+	return func(o *Options) {
+		o.BufferSize = size
+	}
+}
+
+func WithBatchSize(size int) Option {
+	return func(o *Options) {
+		o.BatchSize = size
+	}
+}
+
+func WithFlushInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.FlushInterval = interval
+	}
+}
+
+func WithMaxRetryInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.MaxRetryInterval = interval
+	}
+}
+
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.ShutdownTimeout = timeout
+	}
+}
+
+// Exporter buffers the history events passed by a backend and writes them to a sink in batches. Exporting is
+// best effort: records still buffered when the process exits are lost, and records are dropped while the
+// buffer is full.
+type Exporter struct {
+	sink    Sink
+	options Options
+
+	records chan *Record
+
+	dropped     int64
+	droppedOnce sync.Once
+}
+
+var _ backend.HistoryExporter = (*Exporter)(nil)
+
+func New(sink Sink, opts ...Option) *Exporter {
+	options := DefaultOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Logger == nil {
+		options.Logger = logger.NewDefaultLogger()
+	}
+
+	if options.BatchSize <= 0 {
+		options.BatchSize = 1
+	}
+
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = DefaultOptions.FlushInterval
+	}
+
+	return &Exporter{
+		sink:    sink,
+		options: options,
+		records: make(chan *Record, options.BufferSize),
+	}
+}
+
+// ExportHistory buffers the given events for writing to the sink. It never blocks.
+func (e *Exporter) ExportHistory(instance *core.WorkflowInstance, state backend.WorkflowState, events []history.Event) {
+	for _, event := range events {
+		r := &Record{
+			InstanceID:       instance.InstanceID,
+			ExecutionID:      instance.ExecutionID,
+			ParentInstanceID: instance.ParentInstanceID,
+			State:            state.String(),
+			EventID:          event.ID,
+			SequenceID:       event.SequenceID,
+			Type:             event.Type.String(),
+			Timestamp:        event.Timestamp,
+			ScheduleEventID:  event.ScheduleEventID,
+			Attributes:       event.Attributes,
+		}
+
+		select {
+		case e.records <- r:
+		default:
+			atomic.AddInt64(&e.dropped, 1)
+
+			// Only warn once, the number of dropped records is available from Dropped
+			e.droppedOnce.Do(func() {
+				e.options.Logger.Warn("export buffer full, dropping history events", "instance_id", instance.InstanceID)
+			})
+		}
+	}
+}
+
+// Dropped returns the number of records dropped because the buffer was full
+func (e *Exporter) Dropped() int64 {
+	return atomic.LoadInt64(&e.dropped)
+}
+
+// Run writes buffered records to the sink until the context is canceled. It then keeps writing the remaining
+// buffered records for up to ShutdownTimeout.
+func (e *Exporter) Run(ctx context.Context) error {
+	batch := make([]*Record, 0, e.options.BatchSize)
+
+	t := time.NewTicker(e.options.FlushInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return e.shutdown(batch)
+
+		case r := <-e.records:
+			batch = append(batch, r)
+			if len(batch) < e.options.BatchSize {
+				continue
+			}
+
+		case <-t.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		if err := e.write(ctx, batch); err != nil {
+			return e.shutdown(batch)
+		}
+
+		batch = batch[:0]
+	}
+}
+
+func (e *Exporter) shutdown(batch []*Record) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.options.ShutdownTimeout)
+	defer cancel()
+
+	for {
+	drain:
+		for len(batch) < e.options.BatchSize {
+			select {
+			case r := <-e.records:
+				batch = append(batch, r)
+			default:
+				break drain
+			}
+		}
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := e.write(ctx, batch); err != nil {
+			return fmt.Errorf("writing buffered history events: %w", err)
+		}
+
+		batch = batch[:0]
+	}
+}
+
+// write writes the batch to the sink, retrying with exponential backoff until it succeeds or the context is
+// canceled
+func (e *Exporter) write(ctx context.Context, batch []*Record) error {
+	backoff := 100 * time.Millisecond
+
+	for {
+		err := e.sink.Write(ctx, batch)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		e.options.Logger.Error("could not export history events, retrying", "records", len(batch), "retry_in", backoff, "error", err)
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+
+		backoff *= 2
+		if backoff > e.options.MaxRetryInterval {
+			backoff = e.options.MaxRetryInterval
+		}
+	}
+}
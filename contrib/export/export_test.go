@@ -0,0 +1,196 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	mu      sync.Mutex
+	records []*Record
+	fail    int
+}
+
+func (s *recordingSink) Write(ctx context.Context, records []*Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail > 0 {
+		s.fail--
+		return errors.New("sink unavailable")
+	}
+
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *recordingSink) types() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	types := make([]string, len(s.records))
+	for i, r := range s.records {
+		types[i] = r.Type
+	}
+
+	return types
+}
+
+func activity1(ctx context.Context) (int, error) {
+	return 42, nil
+}
+
+func workflow1(ctx workflow.Context) (int, error) {
+	return workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity1).Get(ctx)
+}
+
+func Test_Exporter_ExportsHistory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sink := &recordingSink{}
+	e := New(sink, WithFlushInterval(time.Millisecond*10))
+
+	b := sqlite.NewInMemoryBackend(backend.WithHistoryExporter(e))
+	c := client.New(b)
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(workflow1))
+	require.NoError(t, w.RegisterActivity(activity1))
+	require.NoError(t, w.Start(ctx))
+
+	done := make(chan error)
+	go func() {
+		done <- e.Run(ctx)
+	}()
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, workflow1)
+	require.NoError(t, err)
+
+	r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+	require.NoError(t, err)
+	require.Equal(t, 42, r)
+
+	require.Eventually(t, func() bool {
+		types := sink.types()
+		return len(types) > 0 && types[len(types)-1] == "WorkflowExecutionFinished"
+	}, time.Second*5, time.Millisecond*10)
+
+	h, err := c.GetWorkflowRunHistory(ctx, instance)
+	require.NoError(t, err)
+
+	sink.mu.Lock()
+	require.Len(t, sink.records, len(h))
+	for i, r := range sink.records {
+		require.Equal(t, instance.InstanceID, r.InstanceID)
+		require.Equal(t, instance.ExecutionID, r.ExecutionID)
+		require.Equal(t, h[i].ID, r.EventID)
+		require.Equal(t, h[i].SequenceID, r.SequenceID)
+		require.Equal(t, h[i].Type.String(), r.Type)
+	}
+	require.Equal(t, "finished", sink.records[len(sink.records)-1].State)
+	sink.mu.Unlock()
+
+	cancel()
+	require.NoError(t, <-done)
+	w.WaitForCompletion()
+}
+
+func events(n int) []history.Event {
+	events := make([]history.Event, n)
+	for i := range events {
+		events[i] = history.NewHistoryEvent(int64(i+1), time.Now(), history.EventType_TimerFired, &history.TimerFiredAttributes{})
+	}
+
+	return events
+}
+
+func Test_Exporter_RetriesAndFlushesOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sink := &recordingSink{fail: 2}
+	e := New(sink, WithBatchSize(2), WithFlushInterval(time.Hour), WithMaxRetryInterval(time.Millisecond))
+
+	e.ExportHistory(&core.WorkflowInstance{InstanceID: "i", ExecutionID: "e"}, backend.WorkflowStateActive, events(3))
+
+	done := make(chan error)
+	go func() {
+		done <- e.Run(ctx)
+	}()
+
+	// The first batch is written once the sink recovers, the last record only when shutting down
+	require.Eventually(t, func() bool {
+		return len(sink.types()) == 2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.Len(t, sink.types(), 3)
+}
+
+func Test_Exporter_DropsWhenBufferFull(t *testing.T) {
+	e := New(&recordingSink{}, WithBufferSize(2))
+
+	e.ExportHistory(&core.WorkflowInstance{InstanceID: "i", ExecutionID: "e"}, backend.WorkflowStateActive, events(5))
+
+	require.Equal(t, int64(3), e.Dropped())
+}
+
+func Test_WriterSink(t *testing.T) {
+	var buf bytes.Buffer
+
+	s := NewWriterSink(&buf)
+	require.NoError(t, s.Write(context.Background(), []*Record{{InstanceID: "a", Type: "TimerFired"}, {InstanceID: "b"}}))
+
+	dec := json.NewDecoder(&buf)
+
+	var r Record
+	require.NoError(t, dec.Decode(&r))
+	require.Equal(t, "a", r.InstanceID)
+	require.Equal(t, "TimerFired", r.Type)
+
+	require.NoError(t, dec.Decode(&r))
+	require.Equal(t, "b", r.InstanceID)
+}
+
+func Test_WebhookSink(t *testing.T) {
+	var (
+		received []*Record
+		header   string
+		status   = http.StatusOK
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	s := NewWebhookSink(srv.URL, WithWebhookHeader("Authorization", "Bearer token"))
+
+	require.NoError(t, s.Write(context.Background(), []*Record{{InstanceID: "a"}}))
+	require.Equal(t, "Bearer token", header)
+	require.Len(t, received, 1)
+	require.Equal(t, "a", received[0].InstanceID)
+
+	status = http.StatusInternalServerError
+	require.Error(t, s.Write(context.Background(), []*Record{{InstanceID: "a"}}))
+}
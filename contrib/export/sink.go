@@ -0,0 +1,126 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// SinkFunc adapts a function to a Sink. Adapters for message brokers like Kafka can be written as a SinkFunc on
+// top of the respective client library, using the InstanceID as message key to keep the records of an instance
+// in order.
+type SinkFunc func(ctx context.Context, records []*Record) error
+
+func (f SinkFunc) Write(ctx context.Context, records []*Record) error {
+	return f(ctx, records)
+}
+
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a sink writing records as JSON lines to w, for example, a file
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+func (s *writerSink) Write(ctx context.Context, records []*Record) error {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("encoding record: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+type WebhookOptions struct {
+	// HTTPClient sends the requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Header is added to every request, for example, to authenticate with the receiver
+	Header http.Header
+}
+
+type WebhookOption func(*WebhookOptions)
+
+// WithWebhookHTTPClient sets the HTTP client used to send requests
+func WithWebhookHTTPClient(c *http.Client) WebhookOption {
+	return func(o *WebhookOptions) {
+		o.HTTPClient = c
+	}
+}
+
+// WithWebhookHeader adds a header to every request
+func WithWebhookHeader(key, value string) WebhookOption {
+	return func(o *WebhookOptions) {
+		if o.Header == nil {
+			o.Header = make(http.Header)
+		}
+
+		o.Header.Add(key, value)
+	}
+}
+
+type webhookSink struct {
+	url     string
+	options WebhookOptions
+}
+
+// NewWebhookSink returns a sink posting every batch of records as a JSON array to the given URL. Any response
+// status other than 2xx fails the batch, which is then retried.
+func NewWebhookSink(url string, opts ...WebhookOption) Sink {
+	options := WebhookOptions{
+		HTTPClient: http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &webhookSink{url: url, options: options}
+}
+
+func (s *webhookSink) Write(ctx context.Context, records []*Record) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("encoding records: %w", err)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	for key, values := range s.options.Header {
+		for _, v := range values {
+			r.Header.Add(key, v)
+		}
+	}
+
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.options.HTTPClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %v", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+// Package ingest delivers messages from external systems like Kafka, SQS, or NATS to workflow instances. A
+// router maps every message to a signal for a workflow instance, optionally starting the instance first.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/internal/logger"
+	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// ErrSourceClosed is returned by a Source when no more messages will be received
+var ErrSourceClosed = errors.New("source closed")
+
+// Route describes how a message is delivered to a workflow instance
+type Route struct {
+	InstanceID string
+
+	SignalName string
+
+	Arg interface{}
+
+	// Start, if set, starts the workflow instance if it does not exist before delivering the signal
+	Start *StartOptions
+}
+
+type StartOptions struct {
+	// Options for creating the workflow instance. The instance ID is taken from the route.
+	Options client.WorkflowInstanceOptions
+
+	Workflow workflow.Workflow
+
+	Args []interface{}
+}
+
+// Router maps a message to a route. A nil route skips the message. If an error is returned, the message
+// is negatively acknowledged.
+type Router func(ctx context.Context, m *Message) (*Route, error)
+
+type Options struct {
+	Logger log.Logger
+
+	// DropUnknownInstances acknowledges messages routed to instances that do not exist and are not started
+	// by the route. Otherwise they are negatively acknowledged.
+	DropUnknownInstances bool
+}
+
+var DefaultOptions = Options{
+	DropUnknownInstances: true,
+}
+
+type Option func(*Options)
+
+func WithLogger(logger log.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithDropUnknownInstances(drop bool) Option {
+	return func(o *Options) {
+		o.DropUnknownInstances = drop
+	}
+}
+
+type Ingester struct {
+	client client.Client
+	source Source
+	router Router
+
+	options Options
+}
+
+func New(c client.Client, source Source, router Router, opts ...Option) *Ingester {
+	options := DefaultOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Logger == nil {
+		options.Logger = logger.NewDefaultLogger()
+	}
+
+	return &Ingester{
+		client:  c,
+		source:  source,
+		router:  router,
+		options: options,
+	}
+}
+
+// Run consumes messages from the source until the context is canceled or the source is closed
+func (i *Ingester) Run(ctx context.Context) error {
+	for {
+		d, err := i.source.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrSourceClosed) {
+				return nil
+			}
+
+			return fmt.Errorf("receiving message: %w", err)
+		}
+
+		if err := i.handle(ctx, d); err != nil {
+			i.options.Logger.Error("could not ingest message", "key", d.Message().Key, "error", err)
+
+			if err := d.Nack(ctx, err); err != nil {
+				i.options.Logger.Error("could not nack message", "key", d.Message().Key, "error", err)
+			}
+
+			continue
+		}
+
+		if err := d.Ack(ctx); err != nil {
+			i.options.Logger.Error("could not ack message", "key", d.Message().Key, "error", err)
+		}
+	}
+}
+
+func (i *Ingester) handle(ctx context.Context, d Delivery) error {
+	m := d.Message()
+
+	route, err := i.router(ctx, m)
+	if err != nil {
+		return fmt.Errorf("routing message: %w", err)
+	}
+
+	if route == nil {
+		i.options.Logger.Debug("Skipping message without route", "key", m.Key)
+		return nil
+	}
+
+	err = i.signal(ctx, route)
+	if errors.Is(err, backend.ErrInstanceNotFound) && i.options.DropUnknownInstances {
+		i.options.Logger.Warn("Dropping message for unknown workflow instance", "key", m.Key, "instance_id", route.InstanceID)
+		return nil
+	}
+
+	return err
+}
+
+// signal delivers the signal of the route, starting the workflow instance first if it does not exist and
+// the route asks for it
+func (i *Ingester) signal(ctx context.Context, route *Route) error {
+	err := i.client.SignalWorkflow(ctx, route.InstanceID, route.SignalName, route.Arg)
+	if !errors.Is(err, backend.ErrInstanceNotFound) || route.Start == nil {
+		return err
+	}
+
+	options := route.Start.Options
+	options.InstanceID = route.InstanceID
+
+	if _, err := i.client.CreateWorkflowInstance(ctx, options, route.Start.Workflow, route.Start.Args...); err != nil &&
+		!errors.Is(err, backend.ErrInstanceAlreadyExists) {
+		return fmt.Errorf("starting workflow instance: %w", err)
+	}
+
+	return i.client.SignalWorkflow(ctx, route.InstanceID, route.SignalName, route.Arg)
+}
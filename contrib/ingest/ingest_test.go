@@ -0,0 +1,153 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+func sum(ctx workflow.Context) (int, error) {
+	c := workflow.NewSignalChannel[int](ctx, "add")
+
+	total := 0
+	for i := 0; i < 2; i++ {
+		v, _ := c.Receive(ctx)
+		total += v
+	}
+
+	return total, nil
+}
+
+func Test_Ingester_SignalWithStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := sqlite.NewInMemoryBackend()
+	c := client.New(b)
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(sum))
+	require.NoError(t, w.Start(ctx))
+
+	messages := make(chan *Message, 2)
+	messages <- &Message{Key: "order-1", Data: []byte("1")}
+	messages <- &Message{Key: "order-1", Data: []byte("41")}
+	close(messages)
+
+	i := New(c, NewChannelSource(messages), func(ctx context.Context, m *Message) (*Route, error) {
+		v, err := strconv.Atoi(string(m.Data))
+		if err != nil {
+			return nil, err
+		}
+
+		return &Route{
+			InstanceID: m.Key,
+			SignalName: "add",
+			Arg:        v,
+			Start: &StartOptions{
+				Workflow: sum,
+			},
+		}, nil
+	})
+	require.NoError(t, i.Run(ctx))
+
+	instances, err := c.ListWorkflowInstances(ctx, backend.InstanceFilter{Name: "sum"})
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	require.Equal(t, "order-1", instances[0].InstanceID)
+
+	r, err := client.GetWorkflowResult[int](ctx, c, instances[0], time.Second*10)
+	require.NoError(t, err)
+	require.Equal(t, 42, r)
+}
+
+func Test_Ingester_AcknowledgesMessages(t *testing.T) {
+	ctx := context.Background()
+
+	b := sqlite.NewInMemoryBackend()
+	c := client.New(b)
+
+	s := &recordingSource{
+		messages: []*Message{
+			{Key: "unknown"},
+			{Key: "invalid"},
+			{Key: "skipped"},
+		},
+	}
+
+	routingErr := errors.New("invalid message")
+
+	i := New(c, s, func(ctx context.Context, m *Message) (*Route, error) {
+		switch m.Key {
+		case "invalid":
+			return nil, routingErr
+		case "skipped":
+			return nil, nil
+		}
+
+		return &Route{InstanceID: m.Key, SignalName: "signal"}, nil
+	})
+	require.NoError(t, i.Run(ctx))
+
+	require.Equal(t, []string{"unknown", "skipped"}, s.acked)
+	require.Equal(t, []string{"invalid"}, s.nacked)
+
+	// Without dropping, messages for unknown instances are redelivered
+	s = &recordingSource{messages: []*Message{{Key: "unknown"}}}
+	i = New(c, s, func(ctx context.Context, m *Message) (*Route, error) {
+		return &Route{InstanceID: m.Key, SignalName: "signal"}, nil
+	}, WithDropUnknownInstances(false))
+	require.NoError(t, i.Run(ctx))
+
+	require.Empty(t, s.acked)
+	require.Equal(t, []string{"unknown"}, s.nacked)
+	require.ErrorIs(t, s.nackErrs[0], backend.ErrInstanceNotFound)
+}
+
+type recordingSource struct {
+	messages []*Message
+
+	acked    []string
+	nacked   []string
+	nackErrs []error
+}
+
+func (s *recordingSource) Receive(ctx context.Context) (Delivery, error) {
+	if len(s.messages) == 0 {
+		return nil, ErrSourceClosed
+	}
+
+	m := s.messages[0]
+	s.messages = s.messages[1:]
+
+	return &recordingDelivery{s: s, m: m}, nil
+}
+
+type recordingDelivery struct {
+	s *recordingSource
+	m *Message
+}
+
+func (d *recordingDelivery) Message() *Message {
+	return d.m
+}
+
+func (d *recordingDelivery) Ack(ctx context.Context) error {
+	d.s.acked = append(d.s.acked, d.m.Key)
+	return nil
+}
+
+func (d *recordingDelivery) Nack(ctx context.Context, err error) error {
+	d.s.nacked = append(d.s.nacked, d.m.Key)
+	d.s.nackErrs = append(d.s.nackErrs, err)
+	return nil
+}
@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"context"
+)
+
+// Message is a message consumed from an external system
+type Message struct {
+	// Key identifies the message in the external system, e.g. the Kafka message key or the SQS message ID
+	Key string
+
+	Data []byte
+
+	Headers map[string]string
+}
+
+// Delivery is a message received from a Source. Every delivery is either acknowledged, after it has been
+// delivered to a workflow instance or it was skipped, or negatively acknowledged to have the source
+// redeliver it later.
+type Delivery interface {
+	Message() *Message
+
+	Ack(ctx context.Context) error
+
+	Nack(ctx context.Context, err error) error
+}
+
+// Source consumes messages from an external system, e.g. a Kafka topic, an SQS queue, or a NATS subject.
+// Adapters for message brokers implement this interface on top of the respective client library.
+type Source interface {
+	// Receive blocks until the next message is available or the context is canceled
+	Receive(ctx context.Context) (Delivery, error)
+}
+
+type channelSource struct {
+	c <-chan *Message
+}
+
+// NewChannelSource returns a source consuming messages from the given channel. Acknowledgements are no-ops,
+// which makes this source useful for in-process producers and tests.
+func NewChannelSource(c <-chan *Message) Source {
+	return &channelSource{c: c}
+}
+
+func (s *channelSource) Receive(ctx context.Context) (Delivery, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case m, ok := <-s.c:
+		if !ok {
+			return nil, ErrSourceClosed
+		}
+
+		return &channelDelivery{m: m}, nil
+	}
+}
+
+type channelDelivery struct {
+	m *Message
+}
+
+func (d *channelDelivery) Message() *Message {
+	return d.m
+}
+
+func (d *channelDelivery) Ack(ctx context.Context) error {
+	return nil
+}
+
+func (d *channelDelivery) Nack(ctx context.Context, err error) error {
+	return nil
+}
@@ -0,0 +1,106 @@
+package converter
+
+import (
+	"reflect"
+
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// Converter converts workflow and activity inputs and results to and from payloads
+type Converter = converter.Converter
+
+// Codec encodes and decodes values of a single type
+type Codec = converter.Codec
+
+// CodecRegistry holds custom codecs by type, use RegisterCodec to add codecs
+type CodecRegistry = converter.CodecRegistry
+
+// DefaultConverter encodes values as JSON
+var DefaultConverter = converter.DefaultConverter
+
+func NewCodecRegistry() *CodecRegistry {
+	return converter.NewCodecRegistry()
+}
+
+// RegisterCodec sets the codec used for values of type T
+func RegisterCodec[T any](r *CodecRegistry, codec Codec) {
+	r.Register(reflect.TypeOf((*T)(nil)).Elem(), codec)
+}
+
+// JSONOption configures a converter returned by NewJSONConverter
+type JSONOption = converter.JSONOption
+
+// WithUseNumber decodes numbers in interface{} values as json.Number instead of float64, so that integers
+// that float64 cannot represent exactly keep their value
+func WithUseNumber() JSONOption {
+	return converter.WithUseNumber()
+}
+
+// NewJSONConverter returns a converter that encodes values as JSON, except for values of types with a
+// codec in the given registry. Codecs are used for inputs and results themselves, not for values nested
+// in other types.
+func NewJSONConverter(codecs *CodecRegistry, opts ...JSONOption) Converter {
+	return converter.NewJSONConverter(codecs, opts...)
+}
+
+// Payload is a value encoded by a converter
+type Payload = payload.Payload
+
+// Metadata describes how the data of a payload is encoded
+type Metadata = payload.Metadata
+
+const (
+	MetadataEncoding        = payload.MetadataEncoding
+	MetadataContentType     = payload.MetadataContentType
+	MetadataCompression     = payload.MetadataCompression
+	MetadataEncryptionKeyID = payload.MetadataEncryptionKeyID
+)
+
+// PayloadCodec transforms encoded payload data, for example to compress or encrypt it
+type PayloadCodec = converter.PayloadCodec
+
+// NewEnvelopeConverter returns a converter that stores the payloads produced by c together with metadata
+// describing their encoding. Codecs are applied in order when encoding, and in reverse order when decoding.
+func NewEnvelopeConverter(c Converter, codecs ...PayloadCodec) Converter {
+	return converter.NewEnvelopeConverter(c, codecs...)
+}
+
+// NewGzipCodec returns a payload codec that compresses data of at least minSize bytes using gzip
+func NewGzipCodec(minSize int) PayloadCodec {
+	return converter.NewGzipCodec(minSize)
+}
+
+// ErrUnknownEncryptionKey is returned when decoding a payload encrypted with a key that is not available
+var ErrUnknownEncryptionKey = converter.ErrUnknownEncryptionKey
+
+// EncryptionKeys are the keys of an AES codec
+type EncryptionKeys = converter.EncryptionKeys
+
+// AESCodec is a payload codec encrypting data with AES-GCM, see NewAESCodec
+type AESCodec = converter.AESCodec
+
+// NewAESCodec returns a payload codec encrypting data with the current key using AES-GCM, and decrypting data
+// with any of the given keys. The ID of the key is recorded in the payload metadata, so keys can be rotated.
+func NewAESCodec(keys EncryptionKeys) (*AESCodec, error) {
+	return converter.NewAESCodec(keys)
+}
+
+// PayloadRedactor returns a copy of the given payload with sensitive data masked. Redactors are applied to
+// payloads shown to people, for example in the diagnostics UI, and never to the payloads used for execution.
+type PayloadRedactor = converter.PayloadRedactor
+
+// RedactedValue replaces the values masked by a redactor
+const RedactedValue = converter.RedactedValue
+
+// NewJSONFieldRedactor returns a redactor that decodes payloads with c, and replaces the values of all object
+// fields with one of the given names, at any depth, with RedactedValue. Names are matched case-insensitively.
+func NewJSONFieldRedactor(c Converter, fields ...string) PayloadRedactor {
+	return converter.NewJSONFieldRedactor(c, fields...)
+}
+
+// NewTemporalConverter returns a converter that stores payloads in the proto-JSON form of Temporal's
+// Payload and Payloads messages, using c to encode values as JSON.
+func NewTemporalConverter(c Converter) Converter {
+	return converter.NewTemporalConverter(c)
+}
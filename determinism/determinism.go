@@ -0,0 +1,69 @@
+// Package determinism provides shims for non-deterministic standard library calls. Outside of workflow code
+// they behave exactly like the functions they replace. Called from workflow code while the worker's
+// DeterminismGuard is enabled, they are reported as violations, pointing to the deterministic alternative.
+//
+// Use them in code shared between workflows and other parts of an application, to find calls that would make
+// workflows non-deterministic.
+package determinism
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/guard"
+)
+
+type Mode = guard.Mode
+
+const (
+	// Off disables the runtime determinism checks
+	Off = guard.ModeOff
+
+	// Warn logs a warning and records a metric for every violation
+	Warn = guard.ModeWarn
+
+	// Strict fails the workflow task on every violation
+	Strict = guard.ModeStrict
+)
+
+// Now replaces time.Now
+func Now() time.Time {
+	guard.Check("time.Now", "workflow.Now")
+	return time.Now()
+}
+
+// Since replaces time.Since
+func Since(t time.Time) time.Duration {
+	guard.Check("time.Since", "workflow.Now")
+	return time.Since(t)
+}
+
+// Sleep replaces time.Sleep
+func Sleep(d time.Duration) {
+	guard.Check("time.Sleep", "workflow.Sleep")
+	time.Sleep(d)
+}
+
+// After replaces time.After
+func After(d time.Duration) <-chan time.Time {
+	guard.Check("time.After", "workflow.ScheduleTimer")
+	return time.After(d)
+}
+
+// Int63 replaces rand.Int63
+func Int63() int64 {
+	guard.Check("rand.Int63", "workflow.SideEffect")
+	return rand.Int63()
+}
+
+// Intn replaces rand.Intn
+func Intn(n int) int {
+	guard.Check("rand.Intn", "workflow.SideEffect")
+	return rand.Intn(n)
+}
+
+// Float64 replaces rand.Float64
+func Float64() float64 {
+	guard.Check("rand.Float64", "workflow.SideEffect")
+	return rand.Float64()
+}
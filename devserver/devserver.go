@@ -0,0 +1,162 @@
+// Package devserver bundles a backend, a worker, and the diagnostics web UI into a single server. It is meant
+// for local development, samples, and demos, not for production use.
+package devserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/client/remote"
+	"github.com/cschleiden/go-workflows/diag"
+	"github.com/cschleiden/go-workflows/worker"
+)
+
+type Options struct {
+	// Addr is the address the HTTP server listens on. Defaults to ":3000".
+	Addr string
+
+	// DatabasePath is the path of the SQLite database file. If empty, an in-memory database is used and all
+	// state is lost when the server stops.
+	DatabasePath string
+
+	// BackendOptions are passed to the backend
+	BackendOptions []backend.BackendOption
+
+	// WorkerOptions configure the hosted worker. If nil, worker.DefaultWorkerOptions are used.
+	WorkerOptions *worker.Options
+
+	// DiagOptions are passed to the diagnostics web UI, for example diag.WithWorkflows to replay histories
+	DiagOptions []diag.Option
+}
+
+// Server hosts a backend, a worker, and the diagnostics web UI. Register workflows and activities with the
+// worker returned by Worker before calling Run.
+type Server struct {
+	options Options
+
+	backend backend.Backend
+	worker  worker.Worker
+	client  client.Client
+	diag    http.Handler
+}
+
+// New creates a new dev server. The diagnostics UI is served under /diag/. Returns an error if the diagnostics UI
+// cannot be set up with the given DiagOptions.
+func New(options Options) (*Server, error) {
+	if options.Addr == "" {
+		options.Addr = ":3000"
+	}
+
+	var b backend.Backend
+	if options.DatabasePath == "" {
+		b = sqlite.NewInMemoryBackend(options.BackendOptions...)
+	} else {
+		b = sqlite.NewSqliteBackend(options.DatabasePath, options.BackendOptions...)
+	}
+
+	// As the client API is served anyway, the diagnostics UI can cancel and signal instances
+	diagOptions := append([]diag.Option{diag.WithActions()}, options.DiagOptions...)
+
+	diagMux, err := diag.NewServeMux(b, diagOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("creating diagnostics UI: %w", err)
+	}
+
+	return &Server{
+		options: options,
+		backend: b,
+		worker:  worker.New(b, options.WorkerOptions),
+		client:  client.New(b),
+		diag:    diagMux,
+	}, nil
+}
+
+// Backend returns the backend of the server
+func (s *Server) Backend() backend.Backend {
+	return s.backend
+}
+
+// Worker returns the hosted worker, use it to register workflows and activities
+func (s *Server) Worker() worker.Worker {
+	return s.worker
+}
+
+// Client returns a client for the server's backend
+func (s *Server) Client() client.Client {
+	return s.client
+}
+
+// Handler returns the HTTP handler serving the diagnostics UI under /diag/ and the API for remote clients under
+// /client/, see remote.New. Requests to / are redirected to the UI. As the client API is served anyway, the
+// diagnostics UI can cancel and signal instances, see diag.WithActions.
+func (s *Server) Handler() http.Handler {
+	m := http.NewServeMux()
+	m.Handle("/diag/", http.StripPrefix("/diag", s.diag))
+	m.Handle("/client/", http.StripPrefix("/client", remote.NewHandler(s.client)))
+	m.Handle("/", http.RedirectHandler("/diag/", http.StatusFound))
+
+	return m
+}
+
+// Run starts the worker and the HTTP server and blocks until the context is canceled. It then stops the HTTP
+// server and waits for the worker to finish active tasks.
+func (s *Server) Run(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.options.Addr)
+	if err != nil {
+		return fmt.Errorf("listening on %v: %w", s.options.Addr, err)
+	}
+
+	return s.Serve(ctx, l)
+}
+
+// Serve works like Run, but accepts HTTP connections on the given listener
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := s.worker.Start(ctx); err != nil {
+		l.Close()
+		return fmt.Errorf("starting worker: %w", err)
+	}
+
+	srv := &http.Server{Handler: s.Handler()}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(l)
+	}()
+
+	s.backend.Logger().Debug("Dev server started", "addr", l.Addr().String())
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+		// Server stopped unexpectedly, stop the worker as well
+		cancel()
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+
+	if serr := srv.Shutdown(shutdownCtx); serr != nil && err == nil {
+		err = serr
+	}
+
+	if werr := s.worker.WaitForCompletion(); werr != nil && err == nil {
+		err = werr
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	return err
+}
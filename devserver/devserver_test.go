@@ -0,0 +1,74 @@
+package devserver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/client/remote"
+	"github.com/cschleiden/go-workflows/diag"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Server(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	wf := func(ctx workflow.Context, msg string) (string, error) {
+		return msg, nil
+	}
+	require.NoError(t, s.Worker().RegisterWorkflow(wf))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Serve(ctx, l)
+	}()
+
+	instance, err := s.Client().CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, wf, "hello")
+	require.NoError(t, err)
+
+	r, err := client.GetWorkflowResult[string](ctx, s.Client(), instance, time.Second*10)
+	require.NoError(t, err)
+	require.Equal(t, "hello", r)
+
+	res, err := http.Get("http://" + l.Addr().String() + "/diag/api/")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var instances []*diag.WorkflowInstanceRef
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&instances))
+	require.Len(t, instances, 1)
+	require.Equal(t, instance.InstanceID, instances[0].Instance.InstanceID)
+
+	rc := remote.New("http://" + l.Addr().String() + "/client")
+	r, err = client.ExecuteWorkflow[string](ctx, rc, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, wf, "remote")
+	require.NoError(t, err)
+	require.Equal(t, "remote", r)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func Test_Server_InvalidDiagWorkflow(t *testing.T) {
+	_, err := New(Options{
+		DiagOptions: []diag.Option{diag.WithWorkflows(func() {})},
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package diag
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/client"
+)
+
+// json: serialization in this file needs to be kept in sync with client.ts in the web app
+
+type SignalRequest struct {
+	Name string          `json:"name"`
+	Arg  json.RawMessage `json:"arg,omitempty"`
+}
+
+// writeCancel requests cancellation of the current execution of the given instance
+func writeCancel(w http.ResponseWriter, r *http.Request, b backend.Backend, instanceID string) {
+	instance, err := getWorkflowInstance(r.Context(), b, instanceID)
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		return
+	}
+
+	if err := client.New(b).CancelWorkflowInstance(r.Context(), instance.Instance); err != nil {
+		w.WriteHeader(actionErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeSignal sends the signal in the request body to the given instance. The argument of the signal is passed on
+// as JSON.
+func writeSignal(w http.ResponseWriter, r *http.Request, b backend.Backend, instanceID string) {
+	var req SignalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var arg interface{}
+	if len(req.Arg) > 0 {
+		arg = req.Arg
+	}
+
+	if err := client.New(b).SignalWorkflow(r.Context(), instanceID, req.Name, arg); err != nil {
+		w.WriteHeader(actionErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func actionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, backend.ErrInstanceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, backend.ErrInstanceFinished):
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
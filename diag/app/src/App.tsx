@@ -4,6 +4,7 @@ import Home from "./Home";
 import Instance from "./Instance";
 import Layout from "./Layout";
 import React from "react";
+import Replay from "./Replay";
 
 function App() {
   return (
@@ -12,6 +13,7 @@ function App() {
         <Route index element={<Home />} />
 
         <Route path=":instanceId" element={<Instance />} />
+        <Route path=":instanceId/replay" element={<Replay />} />
       </Route>
     </Routes>
   );
@@ -16,10 +16,12 @@ export function decodePayloads(payload: { [key: string]: any }): any {
   for (const key of Object.keys(payload)) {
     switch (key) {
       case "inputs":
+      case "Inputs":
         r[key] = payload[key].map((p: any) => decodePayload(p));
         break;
 
       case "result":
+      case "Result":
         r[key] = decodePayload(payload[key]);
         break;
 
@@ -83,6 +85,12 @@ function eventColor(event: string): [Color, string] {
     case "SideEffectResult":
       return ["dark", "secondary"];
 
+    case "MarkerRecorded":
+      return ["light", "secondary"];
+
+    case "HistoryLimitWarning":
+      return ["light", "danger"];
+
     case "WorkflowTaskStarted":
       return ["dark", "light"];
 
@@ -1,4 +1,4 @@
-import { Accordion, Alert, Badge, Card } from "react-bootstrap";
+import { Accordion, Alert, Badge, Button, Card, Form, Table } from "react-bootstrap";
 import {
   EventType,
   Payload,
@@ -8,13 +8,17 @@ import {
 import {
   ExecutionCompletedAttributes,
   ExecutionStartedAttributes,
+  Failure,
   HistoryEvent,
+  PendingWork,
   WorkflowInstanceInfo,
+  cancelInstance,
+  signalInstance,
 } from "./client";
 
-import React from "react";
+import React, { useState } from "react";
 import useFetch from "react-fetch-hook";
-import { useParams } from "react-router-dom";
+import { Link, useParams } from "react-router-dom";
 
 function Instance() {
   let params = useParams();
@@ -29,6 +33,22 @@ function Instance() {
     document.location.pathname + "api/" + instanceId
   );
 
+  const { data: pending } = useFetch<PendingWork>(
+    document.location.pathname + "api/" + instanceId + "/pending"
+  );
+
+  const [signalName, setSignalName] = useState("");
+  const [signalArg, setSignalArg] = useState("");
+  const [actionResult, setActionResult] = useState<string | undefined>();
+
+  // Actions are only served if enabled with diag.WithActions
+  const runAction = async (action: () => Promise<Response>) => {
+    const res = await action();
+    setActionResult(
+      res.ok ? "Done, reload to see the changes" : `Failed: ${res.status} ${res.statusText}`
+    );
+  };
+
   if (isLoading) {
     return <div>Loading...</div>;
   }
@@ -52,12 +72,16 @@ function Instance() {
 
   let wfResult: string | undefined;
   let wfError: string | undefined;
+  let wfFailure: Failure | undefined;
   const finishedEvent = instance.history.find(
-    (e) => e.type === "WorkflowExecutionFinished"
+    (e) =>
+      e.type === "WorkflowExecutionFinished" ||
+      e.type === "WorkflowExecutionForceCompleted"
   ) as HistoryEvent<ExecutionCompletedAttributes>;
   if (finishedEvent) {
     wfResult = finishedEvent.attributes.result;
     wfError = finishedEvent.attributes.error;
+    wfFailure = finishedEvent.attributes.failure;
   }
 
   return (
@@ -91,6 +115,17 @@ function Instance() {
         <dd className="col-sm-8">
           {!instance.completed_at ? <i>pending</i> : instance.completed_at}
         </dd>
+
+        {instance.last_error && (
+          <>
+            <dt className="col-sm-4">Last error</dt>
+            <dd className="col-sm-8">
+              <code>{instance.last_error.message}</code> ({instance.last_error.source}
+              {instance.last_error.name && `: ${instance.last_error.name}`}, attempt{" "}
+              {instance.last_error.attempt}, {instance.last_error.timestamp})
+            </dd>
+          </>
+        )}
       </dl>
 
       <Card>
@@ -104,11 +139,115 @@ function Instance() {
         <Card.Header as="h5">Result</Card.Header>
         <Card.Body>
           {wfResult && <Payload payloads={[decodePayload(wfResult)]} />}
-          {wfError && <Payload payloads={[wfError]} />}
+          {wfFailure ? (
+            <Payload payloads={[JSON.stringify(wfFailure, undefined, 2)]} />
+          ) : (
+            wfError && <Payload payloads={[wfError]} />
+          )}
         </Card.Body>
       </Card>
 
-      <h2 className="mt-3">History</h2>
+      {pending &&
+        (pending.activities.length > 0 || pending.timers.length > 0) && (
+          <Card className="mt-3">
+            <Card.Header as="h5">Pending</Card.Header>
+            <Card.Body>
+              <Table size="sm">
+                <thead>
+                  <tr>
+                    <th>Schedule Event ID</th>
+                    <th>Type</th>
+                    <th>Details</th>
+                    <th>Scheduled at</th>
+                  </tr>
+                </thead>
+                <tbody>
+                  {pending.activities.map((a) => (
+                    <tr key={`a${a.schedule_event_id}`}>
+                      <td>{a.schedule_event_id}</td>
+                      <td>Activity</td>
+                      <td>
+                        <code>{a.name}</code>
+                        {a.queue && ` on queue ${a.queue}`}
+                        {a.attempt && `, attempt ${a.attempt}`}
+                      </td>
+                      <td>{a.scheduled_at}</td>
+                    </tr>
+                  ))}
+                  {pending.timers.map((t) => (
+                    <tr key={`t${t.schedule_event_id}`}>
+                      <td>{t.schedule_event_id}</td>
+                      <td>Timer</td>
+                      <td>fires at {t.at}</td>
+                      <td>{t.scheduled_at}</td>
+                    </tr>
+                  ))}
+                </tbody>
+              </Table>
+            </Card.Body>
+          </Card>
+        )}
+
+      {instance.state === 0 && (
+        <Card className="mt-3">
+          <Card.Header as="h5">Actions</Card.Header>
+          <Card.Body>
+            <Form
+              className="d-flex align-items-center"
+              onSubmit={(e) => {
+                e.preventDefault();
+                runAction(() =>
+                  signalInstance(
+                    instanceId!,
+                    signalName,
+                    signalArg ? JSON.parse(signalArg) : undefined
+                  )
+                );
+              }}
+            >
+              <Form.Control
+                className="me-2"
+                placeholder="Signal name"
+                value={signalName}
+                onChange={(e) => setSignalName(e.target.value)}
+              />
+              <Form.Control
+                className="me-2"
+                placeholder="Argument (JSON)"
+                value={signalArg}
+                onChange={(e) => setSignalArg(e.target.value)}
+              />
+              <Button className="me-2" type="submit" disabled={!signalName}>
+                Signal
+              </Button>
+              <Button
+                variant="danger"
+                onClick={() => runAction(() => cancelInstance(instanceId!))}
+              >
+                Cancel
+              </Button>
+            </Form>
+            {actionResult && <div className="mt-2">{actionResult}</div>}
+          </Card.Body>
+        </Card>
+      )}
+
+      <div className="d-flex align-items-center mt-3">
+        <h2 className="flex-grow-1">History</h2>
+        <a
+          className="me-3"
+          href={`${document.location.pathname}api/${instanceId}/graph?format=mermaid`}
+        >
+          Graph (Mermaid)
+        </a>
+        <a
+          className="me-3"
+          href={`${document.location.pathname}api/${instanceId}/graph?format=dot`}
+        >
+          Graph (DOT)
+        </a>
+        <Link to={`/${instanceId}/replay`}>Replay step by step</Link>
+      </div>
       <Accordion alwaysOpen>
         {instance.history.map((event, idx) => (
           <Accordion.Item eventKey={`${idx}`} key={event.id}>
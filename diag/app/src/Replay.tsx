@@ -0,0 +1,137 @@
+import { Alert, Badge, Button, Card, ListGroup } from "react-bootstrap";
+import { EventType, Payload, decodePayloads } from "./Components";
+import { Link, useParams } from "react-router-dom";
+import React, { useState } from "react";
+
+import { ReplayStep } from "./client";
+import useFetch from "react-fetch-hook";
+
+function Replay() {
+  let params = useParams();
+
+  const instanceId = params.instanceId;
+
+  const [step, setStep] = useState(0);
+
+  const {
+    isLoading,
+    data: steps,
+    error,
+  } = useFetch<ReplayStep[]>(
+    document.location.pathname + "api/" + instanceId + "/replay"
+  );
+
+  if (isLoading) {
+    return <div>Loading...</div>;
+  }
+
+  if (error || !steps) {
+    return (
+      <div>
+        <Alert variant="danger">
+          {error && error.status === 501 ? (
+            <>
+              Replaying requires the workflow to be registered with the
+              diagnostics UI, see <code>diag.WithWorkflows</code>
+            </>
+          ) : (
+            <>
+              Workflow instance with id <code>{instanceId}</code> not found
+            </>
+          )}
+        </Alert>
+      </div>
+    );
+  }
+
+  const current = steps[step];
+
+  return (
+    <div>
+      <div className="d-flex align-items-center">
+        <h2 className="flex-grow-1">
+          Replay: <Link to={`/${instanceId}`}>{instanceId}</Link>
+        </h2>
+        <Button
+          variant="secondary"
+          disabled={step === 0}
+          onClick={() => setStep(step - 1)}
+        >
+          Previous
+        </Button>
+        <div className="px-3">
+          Event {step + 1} of {steps.length}
+        </div>
+        <Button
+          variant="secondary"
+          disabled={step >= steps.length - 1}
+          onClick={() => setStep(step + 1)}
+        >
+          Next
+        </Button>
+      </div>
+
+      {current && (
+        <>
+          <Card className="mt-3">
+            <Card.Header as="h5" className="d-flex align-items-center">
+              <div className="text-secondary" style={{ width: "50px" }}>
+                #{current.event.sequence_id}
+              </div>
+              <div className="flex-grow-1">
+                <EventType type={current.event.type} />
+              </div>
+              {current.completed && <Badge bg="success">Completed</Badge>}
+            </Card.Header>
+            <Card.Body>
+              <Payload
+                payloads={[
+                  JSON.stringify(
+                    decodePayloads(current.event.attributes || {}),
+                    undefined,
+                    2
+                  ),
+                ]}
+              />
+            </Card.Body>
+          </Card>
+
+          {current.error && (
+            <Alert variant="danger" className="mt-3">
+              Replay failed: <code>{current.error}</code>
+            </Alert>
+          )}
+
+          <h3 className="mt-3">Pending commands</h3>
+          {current.commands.length === 0 ? (
+            <i>none</i>
+          ) : (
+            <ListGroup>
+              {current.commands.map((c) => (
+                <ListGroup.Item key={c.id}>
+                  <div className="d-flex align-items-center mb-2">
+                    <code className="flex-grow-1">{c.type}</code>
+                    <Badge bg="secondary">{c.state}</Badge>
+                  </div>
+                  {c.attributes && (
+                    <Payload
+                      payloads={[
+                        JSON.stringify(
+                          decodePayloads(c.attributes),
+                          undefined,
+                          2
+                        ),
+                      ]}
+                    />
+                  )}
+                </ListGroup.Item>
+              ))}
+            </ListGroup>
+          )}
+        </>
+      )}
+    </div>
+  );
+}
+
+export default Replay;
@@ -10,6 +10,16 @@ export interface WorkflowInstanceRef {
   completed_at?: string;
 
   state: number;
+
+  last_error?: InstanceError;
+}
+
+export interface InstanceError {
+  message: string;
+  source: string;
+  name?: string;
+  attempt: number;
+  timestamp: string;
 }
 
 export type WorkflowInstanceInfo = WorkflowInstanceRef & {
@@ -26,6 +36,20 @@ export interface HistoryEvent<TAttributes> {
   visible_at?: string;
 }
 
+export interface ReplayCommand {
+  id: number;
+  type: string;
+  state: string;
+  attributes?: any;
+}
+
+export interface ReplayStep {
+  event: HistoryEvent<any>;
+  commands: ReplayCommand[];
+  completed?: boolean;
+  error?: string;
+}
+
 export interface ExecutionStartedAttributes {
   name: string;
   inputs: string[];
@@ -34,4 +58,52 @@ export interface ExecutionStartedAttributes {
 export interface ExecutionCompletedAttributes {
   result: string;
   error: string;
+  failure?: Failure;
+}
+
+export interface Failure {
+  message: string;
+  type?: string;
+  activity_name?: string;
+  attempt?: number;
+  first_attempt_at?: string;
+  timestamp?: string;
+  cause?: Failure;
+}
+
+export interface PendingActivity {
+  schedule_event_id: number;
+  name: string;
+  queue?: string;
+  attempt?: number;
+  scheduled_at: string;
+}
+
+export interface PendingTimer {
+  schedule_event_id: number;
+  scheduled_at: string;
+  at: string;
+}
+
+export interface PendingWork {
+  activities: PendingActivity[];
+  timers: PendingTimer[];
+}
+
+export async function cancelInstance(instanceId: string): Promise<Response> {
+  return fetch(document.location.pathname + "api/" + instanceId + "/cancel", {
+    method: "POST",
+  });
+}
+
+export async function signalInstance(
+  instanceId: string,
+  name: string,
+  arg?: any
+): Promise<Response> {
+  return fetch(document.location.pathname + "api/" + instanceId + "/signal", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ name, arg }),
+  });
 }
@@ -2,6 +2,7 @@ package diag
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
@@ -15,6 +16,7 @@ type WorkflowInstanceRef struct {
 	CreatedAt   time.Time              `json:"created_at,omitempty"`
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
 	State       backend.WorkflowState  `json:"state,omitempty"`
+	LastError   *backend.InstanceError `json:"last_error,omitempty"`
 }
 
 type Event struct {
@@ -33,9 +35,51 @@ type WorkflowInstanceInfo struct {
 	History []*Event `json:"history,omitempty"`
 }
 
+// Backend is an optional interface a backend can implement to list workflow instances in the diagnostics UI.
+// Without it, instances can only be looked up by their ID, if the backend implements backend.InstanceResolver.
 type Backend interface {
 	backend.Backend
 
 	GetWorkflowInstance(ctx context.Context, instanceID string) (*WorkflowInstanceRef, error)
 	GetWorkflowInstances(ctx context.Context, afterInstanceID string, count int) ([]*WorkflowInstanceRef, error)
 }
+
+// getWorkflowInstance looks up the current execution of the instance with the given ID. It returns errNotFound if
+// there is no such instance, and errNotSupported if the backend cannot look up instances by their ID.
+func getWorkflowInstance(ctx context.Context, b backend.Backend, instanceID string) (*WorkflowInstanceRef, error) {
+	if db, ok := b.(Backend); ok {
+		instance, err := db.GetWorkflowInstance(ctx, instanceID)
+		if err != nil || instance == nil {
+			return nil, errNotFound
+		}
+
+		return instance, nil
+	}
+
+	r, ok := b.(backend.InstanceResolver)
+	if !ok {
+		return nil, errNotSupported
+	}
+
+	instance, err := r.ResolveWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		if errors.Is(err, backend.ErrInstanceNotFound) {
+			return nil, errNotFound
+		}
+
+		return nil, err
+	}
+
+	state, err := b.GetWorkflowInstanceState(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WorkflowInstanceRef{Instance: instance, State: state}, nil
+}
+
+// preferReadReplica marks the context to serve reads from the read replica of the backend, see
+// backend.PreferReadReplica
+func preferReadReplica(ctx context.Context) context.Context {
+	return backend.PreferReadReplica(ctx)
+}
@@ -3,33 +3,82 @@ package diag
 import (
 	"embed"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/fs"
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/workflow"
 )
 
 //go:embed app/build
 var embeddedFiles embed.FS
 
+var (
+	errNotFound     = errors.New("workflow instance not found")
+	errNotSupported = errors.New("backend does not support diagnostics")
+)
+
 // NewServeMux returns an *http.ServeMux that serves the diagnostics web app at / and the diagnostics API at /api which is
-// used by the web app.
-func NewServeMux(backend Backend) *http.ServeMux {
+// used by the web app. Listing instances requires the backend to implement Backend, other endpoints only need to look
+// up instances by their ID, see backend.InstanceResolver. Cancel and signal actions are only served when enabled with
+// WithActions. Returns an error if one of the workflows passed with WithWorkflows cannot be registered.
+func NewServeMux(b backend.Backend, opts ...Option) (*http.ServeMux, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var registry *workflow.Registry
+	if len(o.workflows) > 0 {
+		registry = workflow.NewRegistry()
+		for _, wf := range o.workflows {
+			if err := registry.RegisterWorkflow(wf); err != nil {
+				return nil, fmt.Errorf("registering workflow for replay: %w", err)
+			}
+		}
+	}
+
 	mux := http.NewServeMux()
 
 	// API
 	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
-		// Only support GET requests
+		relativeURL := strings.TrimPrefix(r.URL.Path, "/api/")
+		segments := strings.Split(relativeURL, "/")
+
+		// POST /api/{instanceID}/cancel and /api/{instanceID}/signal
+		if r.Method == http.MethodPost && o.actions && len(segments) == 2 {
+			switch segments[1] {
+			case "cancel":
+				writeCancel(w, r, b, segments[0])
+				return
+			case "signal":
+				writeSignal(w, r, b, segments[0])
+				return
+			}
+		}
+
+		// Only support GET requests otherwise
 		if r.Method != http.MethodGet {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 
-		relativeURL := strings.TrimPrefix(r.URL.Path, "/api/")
+		// Diagnostics tolerate replication lag, serve them from a read replica if the backend has one
+		r = r.WithContext(preferReadReplica(r.Context()))
 
 		// /api/
 		if relativeURL == "" {
 			// Index
+			db, ok := b.(Backend)
+			if !ok {
+				w.WriteHeader(http.StatusNotImplemented)
+				return
+			}
+
 			query := r.URL.Query()
 
 			count := 25
@@ -43,7 +92,7 @@ func NewServeMux(backend Backend) *http.ServeMux {
 				}
 			}
 
-			instances, err := backend.GetWorkflowInstances(r.Context(), query.Get("after"), count)
+			instances, err := db.GetWorkflowInstances(r.Context(), query.Get("after"), count)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				return
@@ -58,24 +107,15 @@ func NewServeMux(backend Backend) *http.ServeMux {
 			return
 		}
 
-		segments := strings.Split(relativeURL, "/")
-
 		// /api/{instanceID}
 		if len(segments) == 1 {
-			instanceID := segments[0]
-
-			instance, err := backend.GetWorkflowInstance(r.Context(), instanceID)
+			instance, err := getWorkflowInstance(r.Context(), b, segments[0])
 			if err != nil {
-				w.WriteHeader(http.StatusNotFound)
-				return
-			}
-
-			if instance == nil {
-				w.WriteHeader(http.StatusNotFound)
+				w.WriteHeader(errorStatus(err))
 				return
 			}
 
-			history, err := backend.GetWorkflowInstanceHistory(r.Context(), instance.Instance, nil)
+			history, err := b.GetWorkflowInstanceHistory(r.Context(), instance.Instance, nil)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				return
@@ -89,7 +129,7 @@ func NewServeMux(backend Backend) *http.ServeMux {
 					Type:            event.Type.String(),
 					Timestamp:       event.Timestamp,
 					ScheduleEventID: event.ScheduleEventID,
-					Attributes:      event.Attributes,
+					Attributes:      redactEventAttributes(o.redactor, event.Type, event.Attributes),
 					VisibleAt:       event.VisibleAt,
 				})
 			}
@@ -107,12 +147,44 @@ func NewServeMux(backend Backend) *http.ServeMux {
 
 			return
 		}
+
+		// /api/{instanceID}/graph
+		if len(segments) == 2 && segments[1] == "graph" {
+			writeGraph(w, r, b, segments[0])
+			return
+		}
+
+		// /api/{instanceID}/pending
+		if len(segments) == 2 && segments[1] == "pending" {
+			writePending(w, r, b, segments[0])
+			return
+		}
+
+		// /api/{instanceID}/replay
+		if len(segments) == 2 && segments[1] == "replay" {
+			writeReplay(w, r, b, registry, o.redactor, segments[0])
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
 	})
 
 	// App
 	mux.Handle("/", http.FileServer(getFileSystem()))
 
-	return mux
+	return mux, nil
+}
+
+// errorStatus maps errors from looking up instances to HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errNotSupported):
+		return http.StatusNotImplemented
+	}
+
+	return http.StatusInternalServerError
 }
 
 func getFileSystem() http.FileSystem {
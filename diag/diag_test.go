@@ -0,0 +1,102 @@
+package diag_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/diag"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PendingAndSignal(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := sqlite.NewInMemoryBackend()
+	c := client.New(b)
+
+	wf := func(ctx workflow.Context) (string, error) {
+		workflow.ScheduleTimer(ctx, time.Hour)
+
+		msg, _ := workflow.NewSignalChannel[string](ctx, "msg").Receive(ctx)
+		return msg, nil
+	}
+
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.Start(ctx))
+
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+		InstanceID: uuid.NewString(),
+	}, wf)
+	require.NoError(t, err)
+
+	mux, err := diag.NewServeMux(b, diag.WithActions())
+	require.NoError(t, err)
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	require.Eventually(t, func() bool {
+		res, err := http.Get(s.URL + "/api/" + instance.InstanceID + "/pending")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var pending diag.PendingWork
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&pending))
+
+		return len(pending.Timers) == 1 && len(pending.Activities) == 0
+	}, time.Second*10, time.Millisecond*50)
+
+	res, err := http.Get(s.URL + "/api/" + uuid.NewString() + "/pending")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// Actions need to be enabled explicitly
+	readOnlyMux, err := diag.NewServeMux(b)
+	require.NoError(t, err)
+
+	readOnly := httptest.NewServer(readOnlyMux)
+	defer readOnly.Close()
+
+	res, err = http.Post(readOnly.URL+"/api/"+instance.InstanceID+"/signal", "application/json", strings.NewReader(`{"name":"msg","arg":"hello"}`))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	res, err = http.Post(s.URL+"/api/"+instance.InstanceID+"/signal", "application/json", strings.NewReader(`{"name":"msg","arg":"hello"}`))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*10)
+	require.NoError(t, err)
+	require.Equal(t, "hello", r)
+
+	cancel()
+	require.NoError(t, w.WaitForCompletion())
+}
+
+func Test_NewServeMux_InvalidWorkflow(t *testing.T) {
+	b := sqlite.NewInMemoryBackend()
+
+	// Workflows have to take a workflow.Context as their first parameter
+	invalid := func(s string) (string, error) { return s, nil }
+
+	_, err := diag.NewServeMux(b, diag.WithWorkflows(invalid))
+
+	var invalidErr *worker.ErrInvalidWorkflow
+	require.ErrorAs(t, err, &invalidErr)
+}
@@ -0,0 +1,40 @@
+package diag
+
+import (
+	"net/http"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/graph"
+)
+
+// writeGraph writes the execution graph of the given instance. The format query parameter selects between "mermaid"
+// (default) and "dot".
+func writeGraph(w http.ResponseWriter, r *http.Request, b backend.Backend, instanceID string) {
+	instance, err := getWorkflowInstance(r.Context(), b, instanceID)
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		return
+	}
+
+	history, err := b.GetWorkflowInstanceHistory(r.Context(), instance.Instance, nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	g := graph.FromHistory(history)
+
+	var out string
+	switch r.URL.Query().Get("format") {
+	case "", "mermaid":
+		out = g.Mermaid()
+	case "dot":
+		out = g.DOT()
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(out))
+}
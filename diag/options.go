@@ -0,0 +1,38 @@
+package diag
+
+import (
+	"github.com/cschleiden/go-workflows/converter"
+	"github.com/cschleiden/go-workflows/internal/workflow"
+)
+
+type options struct {
+	workflows []workflow.Workflow
+	redactor  converter.PayloadRedactor
+	actions   bool
+}
+
+type Option func(*options)
+
+// WithWorkflows registers workflows with the diagnostics UI. Histories of instances of these workflows can be replayed
+// step by step in the UI.
+func WithWorkflows(workflows ...workflow.Workflow) Option {
+	return func(o *options) {
+		o.workflows = append(o.workflows, workflows...)
+	}
+}
+
+// WithRedactor applies the given redactor to all payloads shown in the diagnostics UI, in histories and in the
+// commands of replayed workflows
+func WithRedactor(r converter.PayloadRedactor) Option {
+	return func(o *options) {
+		o.redactor = r
+	}
+}
+
+// WithActions enables the endpoints to cancel and signal workflow instances from the diagnostics UI. They are
+// disabled by default, as the diagnostics UI does not authenticate its users.
+func WithActions() Option {
+	return func(o *options) {
+		o.actions = true
+	}
+}
@@ -0,0 +1,111 @@
+package diag
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+// json: serialization in this file needs to be kept in sync with client.ts in the web app
+
+type PendingActivity struct {
+	ScheduleEventID int64     `json:"schedule_event_id"`
+	Name            string    `json:"name"`
+	Queue           string    `json:"queue,omitempty"`
+	Attempt         int       `json:"attempt,omitempty"`
+	ScheduledAt     time.Time `json:"scheduled_at"`
+}
+
+type PendingTimer struct {
+	ScheduleEventID int64     `json:"schedule_event_id"`
+	ScheduledAt     time.Time `json:"scheduled_at"`
+	At              time.Time `json:"at"`
+}
+
+type PendingWork struct {
+	Activities []*PendingActivity `json:"activities"`
+	Timers     []*PendingTimer    `json:"timers"`
+}
+
+// pendingWork returns the activities and timers scheduled in the given history that have not completed, failed,
+// fired, or been canceled yet
+func pendingWork(h []history.Event) *PendingWork {
+	activities := make(map[int64]*PendingActivity)
+	timers := make(map[int64]*PendingTimer)
+
+	// Keep the order in which the work was scheduled
+	var order []int64
+
+	for _, event := range h {
+		switch event.Type {
+		case history.EventType_ActivityScheduled:
+			a := event.Attributes.(*history.ActivityScheduledAttributes)
+			activities[event.ScheduleEventID] = &PendingActivity{
+				ScheduleEventID: event.ScheduleEventID,
+				Name:            a.Name,
+				Queue:           a.Queue,
+				Attempt:         a.Attempt,
+				ScheduledAt:     event.Timestamp,
+			}
+			order = append(order, event.ScheduleEventID)
+
+		case history.EventType_ActivityCompleted, history.EventType_ActivityFailed:
+			delete(activities, event.ScheduleEventID)
+
+		case history.EventType_TimerScheduled:
+			a := event.Attributes.(*history.TimerScheduledAttributes)
+			timers[event.ScheduleEventID] = &PendingTimer{
+				ScheduleEventID: event.ScheduleEventID,
+				ScheduledAt:     event.Timestamp,
+				At:              a.At,
+			}
+			order = append(order, event.ScheduleEventID)
+
+		case history.EventType_TimerFired, history.EventType_TimerCanceled:
+			delete(timers, event.ScheduleEventID)
+		}
+	}
+
+	result := &PendingWork{
+		Activities: make([]*PendingActivity, 0, len(activities)),
+		Timers:     make([]*PendingTimer, 0, len(timers)),
+	}
+
+	for _, id := range order {
+		if a, ok := activities[id]; ok {
+			result.Activities = append(result.Activities, a)
+			delete(activities, id)
+		}
+
+		if t, ok := timers[id]; ok {
+			result.Timers = append(result.Timers, t)
+			delete(timers, id)
+		}
+	}
+
+	return result
+}
+
+// writePending writes the activities and timers the given instance is currently waiting for
+func writePending(w http.ResponseWriter, r *http.Request, b backend.Backend, instanceID string) {
+	instance, err := getWorkflowInstance(r.Context(), b, instanceID)
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		return
+	}
+
+	h, err := b.GetWorkflowInstanceHistory(r.Context(), instance.Instance, nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(pendingWork(h)); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
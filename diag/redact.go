@@ -0,0 +1,62 @@
+package diag
+
+import (
+	"github.com/cschleiden/go-workflows/converter"
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+func redactEventAttributes(r converter.PayloadRedactor, eventType history.EventType, attributes interface{}) interface{} {
+	if r == nil {
+		return attributes
+	}
+
+	return history.RedactAttributes(eventType, attributes, r)
+}
+
+// redactCommandAttributes returns a copy of the given command attributes with all payloads redacted. Commands
+// are only shown during replay, their attributes are copied here instead of being serialized.
+func redactCommandAttributes(r converter.PayloadRedactor, attributes interface{}) interface{} {
+	if r == nil {
+		return attributes
+	}
+
+	redactAll := func(ps []payload.Payload) []payload.Payload {
+		redacted := make([]payload.Payload, len(ps))
+		for i, p := range ps {
+			redacted[i] = r(p)
+		}
+
+		return redacted
+	}
+
+	switch a := attributes.(type) {
+	case *command.ScheduleActivityTaskCommandAttr:
+		c := *a
+		c.Inputs = redactAll(a.Inputs)
+		return &c
+
+	case *command.ScheduleSubWorkflowCommandAttr:
+		c := *a
+		c.Inputs = redactAll(a.Inputs)
+		return &c
+
+	case *command.SideEffectCommandAttr:
+		c := *a
+		c.Result = r(a.Result)
+		return &c
+
+	case *command.RecordMarkerCommandAttr:
+		c := *a
+		c.Data = r(a.Data)
+		return &c
+
+	case *command.CompleteWorkflowCommandAttr:
+		c := *a
+		c.Result = r(a.Result)
+		return &c
+	}
+
+	return attributes
+}
@@ -0,0 +1,109 @@
+package diag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/converter"
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/workflow"
+)
+
+// json: serialization in this file needs to be kept in sync with client.ts in the web app
+
+type ReplayCommand struct {
+	ID         int64       `json:"id"`
+	Type       string      `json:"type,omitempty"`
+	State      string      `json:"state,omitempty"`
+	Attributes interface{} `json:"attributes,omitempty"`
+}
+
+type ReplayStep struct {
+	Event     *Event           `json:"event,omitempty"`
+	Commands  []*ReplayCommand `json:"commands"`
+	Completed bool             `json:"completed,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+func commandState(s command.CommandState) string {
+	switch s {
+	case command.CommandState_Pending:
+		return "Pending"
+	case command.CommandState_Committed:
+		return "Committed"
+	case command.CommandState_Done:
+		return "Done"
+	}
+
+	return ""
+}
+
+func replay(ctx context.Context, b backend.Backend, registry *workflow.Registry, redactor converter.PayloadRedactor, instanceID string) ([]*ReplayStep, error) {
+	instance, err := getWorkflowInstance(ctx, b, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := b.GetWorkflowInstanceHistory(ctx, instance.Instance, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := workflow.ReplaySteps(b.Logger(), b.Converter(), registry, instance.Instance, history)
+
+	result := make([]*ReplayStep, 0, len(steps))
+	for _, step := range steps {
+		s := &ReplayStep{
+			Event: &Event{
+				ID:              step.Event.ID,
+				SequenceID:      step.Event.SequenceID,
+				Type:            step.Event.Type.String(),
+				Timestamp:       step.Event.Timestamp,
+				ScheduleEventID: step.Event.ScheduleEventID,
+				Attributes:      redactEventAttributes(redactor, step.Event.Type, step.Event.Attributes),
+				VisibleAt:       step.Event.VisibleAt,
+			},
+			Commands:  make([]*ReplayCommand, 0, len(step.Commands)),
+			Completed: step.Completed,
+		}
+
+		for _, c := range step.Commands {
+			s.Commands = append(s.Commands, &ReplayCommand{
+				ID:         c.ID,
+				Type:       c.Type.String(),
+				State:      commandState(c.State),
+				Attributes: redactCommandAttributes(redactor, c.Attr),
+			})
+		}
+
+		if step.Err != nil {
+			s.Error = step.Err.Error()
+		}
+
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+func writeReplay(w http.ResponseWriter, r *http.Request, b backend.Backend, registry *workflow.Registry, redactor converter.PayloadRedactor, instanceID string) {
+	if registry == nil {
+		// Replaying requires the workflows, see WithWorkflows
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	steps, err := replay(r.Context(), b, registry, redactor, instanceID)
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(steps); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
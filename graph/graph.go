@@ -0,0 +1,268 @@
+// Package graph converts the history of a workflow instance into a graph of the activities, timers, signals, and
+// sub-workflows it executed. Graphs can be rendered as DOT for Graphviz or as Mermaid flowcharts, for example for
+// post-mortems or documentation.
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+type NodeKind string
+
+const (
+	NodeKindWorkflowStarted  NodeKind = "WorkflowStarted"
+	NodeKindWorkflowFinished NodeKind = "WorkflowFinished"
+	NodeKindWorkflowCanceled NodeKind = "WorkflowCanceled"
+	NodeKindActivity         NodeKind = "Activity"
+	NodeKindTimer            NodeKind = "Timer"
+	NodeKindSubWorkflow      NodeKind = "SubWorkflow"
+	NodeKindSignal           NodeKind = "Signal"
+)
+
+type Node struct {
+	// ID identifies the node within the graph. It's the sequence ID of the event that created the node.
+	ID int64
+
+	Kind NodeKind
+
+	// Name is the name of the workflow, activity, sub-workflow, or signal
+	Name string
+
+	// Start is when the node was scheduled or received
+	Start time.Time
+
+	// End is when the node finished, nil if it is still pending or finishes immediately
+	End *time.Time
+
+	// Outcome describes how the node finished, for example "completed" or the error of a failed activity
+	Outcome string
+}
+
+// Duration returns how long the node took, or 0 if it has not finished
+func (n *Node) Duration() time.Duration {
+	if n.End == nil {
+		return 0
+	}
+
+	return n.End.Sub(n.Start)
+}
+
+// Edge connects a node to a node the workflow scheduled in reaction to it
+type Edge struct {
+	From int64
+	To   int64
+}
+
+type Graph struct {
+	Nodes []*Node
+	Edges []Edge
+}
+
+// FromHistory builds the graph for the given history of a workflow instance. Work the workflow schedules has an edge
+// from every node that finished or was received since it last scheduled work, so work scheduled concurrently appears
+// side by side.
+func FromHistory(events []history.Event) *Graph {
+	g := &Graph{}
+
+	// Nodes for scheduled work, by their schedule event ID
+	scheduled := make(map[int64]*Node)
+
+	// Nodes that finished or were received since the workflow last scheduled work, and the nodes that triggered the
+	// work scheduled last
+	var causes, taskCauses []int64
+
+	// consume makes the pending causes the causes of the work the workflow schedules next
+	consume := func() {
+		if len(causes) > 0 {
+			taskCauses = causes
+			causes = nil
+		}
+	}
+
+	addNode := func(event history.Event, kind NodeKind, name string) *Node {
+		n := &Node{
+			ID:    event.SequenceID,
+			Kind:  kind,
+			Name:  name,
+			Start: event.Timestamp,
+		}
+		g.Nodes = append(g.Nodes, n)
+
+		return n
+	}
+
+	schedule := func(event history.Event, kind NodeKind, name string) {
+		consume()
+
+		n := addNode(event, kind, name)
+		scheduled[event.ScheduleEventID] = n
+
+		for _, c := range taskCauses {
+			g.Edges = append(g.Edges, Edge{From: c, To: n.ID})
+		}
+	}
+
+	finish := func(event history.Event, outcome string) {
+		n, ok := scheduled[event.ScheduleEventID]
+		if !ok {
+			return
+		}
+
+		end := event.Timestamp
+		n.End = &end
+		n.Outcome = outcome
+
+		causes = append(causes, n.ID)
+	}
+
+	for _, event := range events {
+		switch event.Type {
+		case history.EventType_WorkflowExecutionStarted:
+			a := event.Attributes.(*history.ExecutionStartedAttributes)
+			n := addNode(event, NodeKindWorkflowStarted, a.Name)
+			causes = append(causes, n.ID)
+
+		case history.EventType_WorkflowExecutionCanceled:
+			n := addNode(event, NodeKindWorkflowCanceled, "")
+			causes = append(causes, n.ID)
+
+		case history.EventType_SignalReceived:
+			a := event.Attributes.(*history.SignalReceivedAttributes)
+			n := addNode(event, NodeKindSignal, a.Name)
+			causes = append(causes, n.ID)
+
+		case history.EventType_ActivityScheduled:
+			a := event.Attributes.(*history.ActivityScheduledAttributes)
+			schedule(event, NodeKindActivity, a.Name)
+
+		case history.EventType_ActivityCompleted:
+			finish(event, "completed")
+
+		case history.EventType_ActivityFailed:
+			a := event.Attributes.(*history.ActivityFailedAttributes)
+			finish(event, "failed: "+a.Reason)
+
+		case history.EventType_TimerScheduled:
+			a := event.Attributes.(*history.TimerScheduledAttributes)
+			schedule(event, NodeKindTimer, a.At.Sub(event.Timestamp).Round(time.Millisecond).String())
+
+		case history.EventType_TimerFired:
+			finish(event, "fired")
+
+		case history.EventType_TimerCanceled:
+			finish(event, "canceled")
+
+		case history.EventType_SubWorkflowScheduled:
+			a := event.Attributes.(*history.SubWorkflowScheduledAttributes)
+			schedule(event, NodeKindSubWorkflow, a.Name)
+
+		case history.EventType_SubWorkflowCompleted:
+			finish(event, "completed")
+
+		case history.EventType_SubWorkflowFailed:
+			a := event.Attributes.(*history.SubWorkflowFailedAttributes)
+			finish(event, "failed: "+a.Error)
+
+		case history.EventType_WorkflowExecutionFinished, history.EventType_WorkflowExecutionForceCompleted:
+			a := event.Attributes.(*history.ExecutionCompletedAttributes)
+			n := addNode(event, NodeKindWorkflowFinished, "")
+			n.Outcome = "completed"
+			if a.Error != "" {
+				n.Outcome = "failed: " + a.Error
+			}
+
+			consume()
+
+			for _, c := range taskCauses {
+				g.Edges = append(g.Edges, Edge{From: c, To: n.ID})
+			}
+
+		case history.EventType_WorkflowExecutionContinuedAsNew:
+			n := addNode(event, NodeKindWorkflowFinished, "")
+			n.Outcome = "continued as new"
+
+			consume()
+
+			for _, c := range taskCauses {
+				g.Edges = append(g.Edges, Edge{From: c, To: n.ID})
+			}
+
+		case history.EventType_WorkflowExecutionTerminated:
+			a := event.Attributes.(*history.ExecutionTerminatedAttributes)
+			n := addNode(event, NodeKindWorkflowFinished, "")
+			n.Outcome = "terminated"
+			if a.Reason != "" {
+				n.Outcome = "terminated: " + a.Reason
+			}
+
+			consume()
+
+			for _, c := range taskCauses {
+				g.Edges = append(g.Edges, Edge{From: c, To: n.ID})
+			}
+		}
+	}
+
+	return g
+}
+
+func (n *Node) label() string {
+	var sb strings.Builder
+	sb.WriteString(string(n.Kind))
+	if n.Name != "" {
+		sb.WriteString(": ")
+		sb.WriteString(n.Name)
+	}
+
+	if n.End != nil {
+		fmt.Fprintf(&sb, "\n%v", n.Duration().Round(time.Millisecond))
+	}
+
+	if n.Outcome != "" {
+		sb.WriteString("\n")
+		sb.WriteString(n.Outcome)
+	}
+
+	return sb.String()
+}
+
+// DOT renders the graph in the Graphviz DOT language
+func (g *Graph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph workflow {\n")
+	sb.WriteString("  node [shape=box];\n")
+
+	for _, n := range g.Nodes {
+		label := strings.ReplaceAll(strings.ReplaceAll(n.label(), `"`, `\"`), "\n", `\n`)
+		fmt.Fprintf(&sb, "  n%d [label=\"%s\"];\n", n.ID, label)
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "  n%d -> n%d;\n", e.From, e.To)
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+func (g *Graph) Mermaid() string {
+	var sb strings.Builder
+	sb.WriteString("flowchart TD\n")
+
+	for _, n := range g.Nodes {
+		label := strings.ReplaceAll(strings.ReplaceAll(n.label(), `"`, "#quot;"), "\n", "<br/>")
+		fmt.Fprintf(&sb, "  n%d[\"%s\"]\n", n.ID, label)
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "  n%d --> n%d\n", e.From, e.To)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func event(seq int64, ts time.Time, eventType history.EventType, attributes interface{}, scheduleEventID int64) history.Event {
+	return history.NewHistoryEvent(seq, ts, eventType, attributes, history.ScheduleEventID(scheduleEventID))
+}
+
+func Test_FromHistory(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events := []history.Event{
+		event(1, start, history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}, 0),
+		event(2, start, history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "Workflow1"}, 0),
+		// Two activities scheduled concurrently
+		event(3, start, history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "A"}, 1),
+		event(4, start, history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "B"}, 2),
+		event(5, start.Add(time.Second), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}, 0),
+		event(6, start.Add(time.Second), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{}, 1),
+		event(7, start.Add(2*time.Second), history.EventType_ActivityFailed, &history.ActivityFailedAttributes{Reason: "boom"}, 2),
+		event(8, start.Add(2*time.Second), history.EventType_TimerScheduled, &history.TimerScheduledAttributes{At: start.Add(7 * time.Second)}, 3),
+		event(9, start.Add(7*time.Second), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}, 0),
+		event(10, start.Add(7*time.Second), history.EventType_TimerFired, &history.TimerFiredAttributes{}, 3),
+		event(11, start.Add(7*time.Second), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{}, 0),
+	}
+
+	g := FromHistory(events)
+
+	require.Len(t, g.Nodes, 5)
+
+	require.Equal(t, NodeKindWorkflowStarted, g.Nodes[0].Kind)
+	require.Equal(t, "Workflow1", g.Nodes[0].Name)
+
+	require.Equal(t, NodeKindActivity, g.Nodes[1].Kind)
+	require.Equal(t, "A", g.Nodes[1].Name)
+	require.Equal(t, "completed", g.Nodes[1].Outcome)
+	require.Equal(t, time.Second, g.Nodes[1].Duration())
+
+	require.Equal(t, "failed: boom", g.Nodes[2].Outcome)
+	require.Equal(t, 2*time.Second, g.Nodes[2].Duration())
+
+	require.Equal(t, NodeKindTimer, g.Nodes[3].Kind)
+	require.Equal(t, "5s", g.Nodes[3].Name)
+	require.Equal(t, "fired", g.Nodes[3].Outcome)
+
+	require.Equal(t, NodeKindWorkflowFinished, g.Nodes[4].Kind)
+	require.Equal(t, "completed", g.Nodes[4].Outcome)
+
+	require.Equal(t, []Edge{
+		{From: 2, To: 3},
+		{From: 2, To: 4},
+		{From: 3, To: 8},
+		{From: 4, To: 8},
+		{From: 8, To: 11},
+	}, g.Edges)
+}
+
+func Test_Render(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	g := FromHistory([]history.Event{
+		event(1, start, history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "Workflow1"}, 0),
+		event(2, start, history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "A"}, 1),
+		event(3, start.Add(time.Second), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{}, 1),
+	})
+
+	require.Equal(t, `digraph workflow {
+  node [shape=box];
+  n1 [label="WorkflowStarted: Workflow1"];
+  n2 [label="Activity: A\n1s\ncompleted"];
+  n1 -> n2;
+}
+`, g.DOT())
+
+	require.Equal(t, `flowchart TD
+  n1["WorkflowStarted: Workflow1"]
+  n2["Activity: A<br/>1s<br/>completed"]
+  n1 --> n2
+`, g.Mermaid())
+}
@@ -2,8 +2,15 @@ package activity
 
 import (
 	"context"
+	"sync"
+	"time"
 
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/trace"
 	"github.com/cschleiden/go-workflows/workflow"
 )
 
@@ -11,22 +18,75 @@ type ActivityState struct {
 	ActivityID string
 	Instance   *workflow.Instance
 	Logger     log.Logger
+	Metrics    metrics.Client
+	Tracer     trace.Tracer
+	Converter  converter.Converter
+
+	// HeartbeatDetails are the details of the last heartbeat of an earlier execution of the task
+	HeartbeatDetails payload.Payload
+
+	mu            sync.Mutex
+	lastHeartbeat time.Time
+	onHeartbeat   HeartbeatFunc
 }
 
-func NewActivityState(activityID string, instance *workflow.Instance, logger log.Logger) *ActivityState {
+// HeartbeatFunc is called for every heartbeat of an activity, with nil details for heartbeats without details
+type HeartbeatFunc func(details payload.Payload)
+
+func NewActivityState(activityID, name string, instance *workflow.Instance, logger log.Logger, mc metrics.Client, tracer trace.Tracer) *ActivityState {
+	tags := map[string]string{
+		"activity_id":   activityID,
+		"activity_name": name,
+		"instance_id":   instance.InstanceID,
+		"execution_id":  instance.ExecutionID,
+	}
+
 	return &ActivityState{
-		activityID,
-		instance,
-		logger.With(
+		ActivityID: activityID,
+		Instance:   instance,
+		Logger: logger.With(
 			"activity_id", activityID,
+			"activity_name", name,
 			"instance_id", instance.InstanceID,
 			"execution_id", instance.ExecutionID,
-		)}
+		),
+		Metrics:       mc.WithTags(tags),
+		Tracer:        tracing.WithAttributes(tracer, tags),
+		lastHeartbeat: time.Now(),
+	}
+}
+
+// RecordHeartbeat marks the activity as alive, and passes the details of the heartbeat on to the worker
+func (as *ActivityState) RecordHeartbeat(details payload.Payload) {
+	as.mu.Lock()
+	as.lastHeartbeat = time.Now()
+	onHeartbeat := as.onHeartbeat
+	as.mu.Unlock()
+
+	if onHeartbeat != nil {
+		onHeartbeat(details)
+	}
+}
+
+// sinceHeartbeat returns the time since the last heartbeat, or since the activity started without one
+func (as *ActivityState) sinceHeartbeat() time.Duration {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	return time.Since(as.lastHeartbeat)
 }
 
 type key int
 
-var activityCtxKey key
+const (
+	activityCtxKey key = iota
+	heartbeatFuncKey
+)
+
+// WithHeartbeatFunc returns a context for executing an activity, which calls f for every heartbeat of the activity
+func WithHeartbeatFunc(ctx context.Context, f HeartbeatFunc) context.Context {
+	return context.WithValue(ctx, heartbeatFuncKey, f)
+}
 
 func WithActivityState(ctx context.Context, as *ActivityState) context.Context {
 	return context.WithValue(ctx, activityCtxKey, as)
@@ -5,33 +5,78 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflow"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/trace"
+	"github.com/google/uuid"
 )
 
+// ErrHeartbeatTimeout fails activities that do not record a heartbeat within their heartbeat timeout
+var ErrHeartbeatTimeout = errors.New("activity heartbeat timed out")
+
 type Executor struct {
-	logger log.Logger
-	r      *workflow.Registry
+	logger    log.Logger
+	converter converter.Converter
+	metrics   metrics.Client
+	tracer    trace.Tracer
+	r         *workflow.Registry
 }
 
-func NewExecutor(logger log.Logger, r *workflow.Registry) Executor {
+func NewExecutor(logger log.Logger, converter converter.Converter, metrics metrics.Client, tracer trace.Tracer, r *workflow.Registry) Executor {
 	return Executor{
-		logger: logger,
-		r:      r,
+		logger:    logger,
+		converter: converter,
+		metrics:   metrics,
+		tracer:    tracer,
+		r:         r,
 	}
 }
 func (e *Executor) ExecuteActivity(ctx context.Context, task *task.Activity) (payload.Payload, error) {
 	a := task.Event.Attributes.(*history.ActivityScheduledAttributes)
 
+	// Continue the trace of the workflow that scheduled the activity
+	ctx = tracing.Extract(e.tracer, ctx, a.TraceContext)
+	ctx, span := e.tracer.Start(ctx, "Activity", map[string]string{
+		"activity_name": a.Name,
+		"activity_id":   task.Event.ID,
+		"instance_id":   task.WorkflowInstance.InstanceID,
+		"execution_id":  task.WorkflowInstance.ExecutionID,
+	})
+	defer span.End()
+
+	result, err := e.executeActivity(ctx, task, a)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return result, err
+}
+
+func (e *Executor) executeActivity(ctx context.Context, task *task.Activity, a *history.ActivityScheduledAttributes) (payload.Payload, error) {
 	activity, err := e.r.GetActivity(a.Name)
 	if err != nil {
-		return nil, err
+		dynamic, options, ok := e.r.GetDynamicActivity()
+		if !ok {
+			return nil, err
+		}
+
+		activityCtx, cancel := e.activityContext(ctx, task, a, options.StartToCloseTimeout)
+		defer cancel()
+
+		return runWithHeartbeatTimeout(activityCtx, heartbeatTimeout(a, options), func(ctx context.Context) (payload.Payload, error) {
+			return dynamic(ctx, a.Name, a.Inputs)
+		})
 	}
 
 	activityFn := reflect.ValueOf(activity)
@@ -39,37 +84,93 @@ func (e *Executor) ExecuteActivity(ctx context.Context, task *task.Activity) (pa
 		return nil, errors.New("activity not a function")
 	}
 
-	args, addContext, err := args.InputsToArgs(converter.DefaultConverter, activityFn, a.Inputs)
+	args, addContext, err := args.InputsToArgs(e.converter, activityFn, a.Inputs)
 	if err != nil {
 		return nil, fmt.Errorf("converting activity inputs: %w", err)
 	}
 
-	as := NewActivityState(
-		task.Event.ID,
-		task.WorkflowInstance,
-		e.logger)
-	activityCtx := WithActivityState(ctx, as)
+	options, _ := e.r.GetActivityOptions(a.Name)
+
+	if options.Validator != nil {
+		inputs := argValues(args, addContext)
+		if err := options.Validator.ValidateInputs(inputs); err != nil {
+			return nil, fmt.Errorf("validating activity inputs: %w", err)
+		}
+	}
+
+	activityCtx, cancel := e.activityContext(ctx, task, a, options.StartToCloseTimeout)
+	defer cancel()
+
+	return runWithHeartbeatTimeout(activityCtx, heartbeatTimeout(a, options), func(ctx context.Context) (payload.Payload, error) {
+		if addContext {
+			args[0] = reflect.ValueOf(ctx)
+		}
+
+		return e.call(activityFn, args, options.Validator)
+	})
+}
+
+// ExecuteLocalActivity executes the given activity function for the given workflow instance, without scheduling
+// it. The activity does not have to be registered, if it is, its registration options apply.
+func (e *Executor) ExecuteLocalActivity(ctx context.Context, instance *core.WorkflowInstance, activity interface{}, inputs []payload.Payload, timeout time.Duration) (payload.Payload, error) {
+	activityFn := reflect.ValueOf(activity)
+	if activityFn.Kind() != reflect.Func {
+		return nil, errors.New("activity not a function")
+	}
+
+	args, addContext, err := args.InputsToArgs(e.converter, activityFn, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("converting activity inputs: %w", err)
+	}
+
+	name := fn.Name(activity)
+	options, _ := e.r.GetActivityOptions(name)
+
+	if options.Validator != nil {
+		if err := options.Validator.ValidateInputs(argValues(args, addContext)); err != nil {
+			return nil, fmt.Errorf("validating activity inputs: %w", err)
+		}
+	}
+
+	as := NewActivityState(uuid.NewString(), name, instance, e.logger, e.metrics, e.tracer)
+	as.Converter = e.converter
+	ctx = WithActivityState(ctx, as)
+
+	if timeout == 0 {
+		timeout = options.StartToCloseTimeout
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
 	if addContext {
-		args[0] = reflect.ValueOf(activityCtx)
+		args[0] = reflect.ValueOf(ctx)
 	}
 
+	return e.call(activityFn, args, options.Validator)
+}
+
+func (e *Executor) call(activityFn reflect.Value, args []reflect.Value, validator core.Validator) (payload.Payload, error) {
 	r := activityFn.Call(args)
 
-	if len(r) < 1 || len(r) > 2 {
-		return nil, errors.New("activity has to return either (error) or (<result>, error)")
+	if len(r) < 1 {
+		return nil, errors.New("activity has to return either (error) or (<result>..., error)")
 	}
 
-	var result payload.Payload
-
-	if len(r) > 1 {
-		var err error
-		result, err = converter.DefaultConverter.To(r[0].Interface())
-		if err != nil {
-			return nil, fmt.Errorf("converting activity result: %w", err)
+	if validator != nil && r[len(r)-1].IsNil() {
+		if err := validator.ValidateResults(resultValues(r)); err != nil {
+			return nil, fmt.Errorf("validating activity result: %w", err)
 		}
 	}
 
+	result, err := converter.EncodeResults(e.converter, resultValues(r))
+	if err != nil {
+		return nil, fmt.Errorf("converting activity result: %w", err)
+	}
+
 	errResult := r[len(r)-1]
 	if errResult.IsNil() {
 		return result, nil
@@ -82,3 +183,127 @@ func (e *Executor) ExecuteActivity(ctx context.Context, task *task.Activity) (pa
 
 	return result, errInterface
 }
+
+// activityContext returns the context an activity is executed with. The timeout of the scheduled activity takes
+// precedence over the timeout the activity has been registered with.
+func (e *Executor) activityContext(ctx context.Context, task *task.Activity, a *history.ActivityScheduledAttributes, registeredTimeout time.Duration) (context.Context, context.CancelFunc) {
+	as := NewActivityState(
+		task.Event.ID,
+		a.Name,
+		task.WorkflowInstance,
+		e.logger,
+		e.metrics,
+		e.tracer)
+	as.Converter = e.converter
+	as.HeartbeatDetails = task.HeartbeatDetails
+	as.onHeartbeat, _ = ctx.Value(heartbeatFuncKey).(HeartbeatFunc)
+	activityCtx := WithActivityState(ctx, as)
+
+	timeout := a.StartToCloseTimeout
+	if timeout == 0 {
+		timeout = registeredTimeout
+	}
+
+	if timeout > 0 {
+		return context.WithTimeout(activityCtx, timeout)
+	}
+
+	return activityCtx, func() {}
+}
+
+// HeartbeatTimeout returns the heartbeat timeout the given scheduled activity is executed with, or zero if it
+// does not need to record heartbeats
+func (e *Executor) HeartbeatTimeout(a *history.ActivityScheduledAttributes) time.Duration {
+	if _, err := e.r.GetActivity(a.Name); err != nil {
+		_, options, _ := e.r.GetDynamicActivity()
+		return heartbeatTimeout(a, options)
+	}
+
+	options, _ := e.r.GetActivityOptions(a.Name)
+	return heartbeatTimeout(a, options)
+}
+
+// heartbeatTimeout returns the heartbeat timeout of the scheduled activity, or the one it has been registered with
+func heartbeatTimeout(a *history.ActivityScheduledAttributes, options core.ActivityRegistrationOptions) time.Duration {
+	if a.HeartbeatTimeout != 0 {
+		return a.HeartbeatTimeout
+	}
+
+	return options.HeartbeatTimeout
+}
+
+// runWithHeartbeatTimeout runs f and fails with ErrHeartbeatTimeout if the activity does not record a heartbeat
+// within the timeout. The context passed to f is canceled then, but f is not waited for, so a stuck activity is
+// failed and can be retried without waiting for it.
+func runWithHeartbeatTimeout(ctx context.Context, timeout time.Duration, f func(context.Context) (payload.Payload, error)) (payload.Payload, error) {
+	if timeout <= 0 {
+		return f(ctx)
+	}
+
+	as := GetActivityState(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		p   payload.Payload
+		err error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		p, err := f(ctx)
+		done <- result{p, err}
+	}()
+
+	t := time.NewTicker(heartbeatCheckInterval(timeout))
+	defer t.Stop()
+
+	for {
+		select {
+		case r := <-done:
+			return r.p, r.err
+		case <-t.C:
+			if as.sinceHeartbeat() >= timeout {
+				as.Logger.Warn("Activity did not record a heartbeat in time", "heartbeat_timeout", timeout)
+
+				return nil, ErrHeartbeatTimeout
+			}
+		}
+	}
+}
+
+// heartbeatCheckInterval returns how often heartbeats are checked, so a missed heartbeat is detected at most a
+// tenth of the timeout late
+func heartbeatCheckInterval(timeout time.Duration) time.Duration {
+	interval := timeout / 10
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+
+	return interval
+}
+
+// resultValues returns the non-error return values of a function call
+func resultValues(r []reflect.Value) []interface{} {
+	vs := make([]interface{}, len(r)-1)
+	for i := range vs {
+		vs[i] = r[i].Interface()
+	}
+
+	return vs
+}
+
+// argValues returns the decoded inputs of an activity call, not including the context
+func argValues(args []reflect.Value, addContext bool) []interface{} {
+	if addContext {
+		args = args[1:]
+	}
+
+	vs := make([]interface{}, len(args))
+	for i, arg := range args {
+		vs[i] = arg.Interface()
+	}
+
+	return vs
+}
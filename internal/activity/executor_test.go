@@ -2,16 +2,22 @@ package activity
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/logger"
+	mi "github.com/cschleiden/go-workflows/internal/metrics"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/trace"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
@@ -35,6 +41,23 @@ func TestExecutor_ExecuteActivity(t *testing.T) {
 				require.EqualError(t, err, "activity not found")
 			},
 		},
+		{
+			name: "unknown activity with dynamic activity",
+			setup: func(t *testing.T, r *workflow.Registry) *history.ActivityScheduledAttributes {
+				require.NoError(t, r.RegisterDynamicActivity(func(ctx context.Context, name string, inputs []payload.Payload) (payload.Payload, error) {
+					return payload.Payload(name + ":" + string(inputs[0])), nil
+				}))
+
+				return &history.ActivityScheduledAttributes{
+					Name:   "unknown",
+					Inputs: []payload.Payload{payload.Payload("42")},
+				}
+			},
+			result: func(t *testing.T, result payload.Payload, err error) {
+				require.NoError(t, err)
+				require.Equal(t, payload.Payload("unknown:42"), result)
+			},
+		},
 		{
 			name: "mismatched argument count",
 			setup: func(t *testing.T, r *workflow.Registry) *history.ActivityScheduledAttributes {
@@ -51,6 +74,44 @@ func TestExecutor_ExecuteActivity(t *testing.T) {
 				require.EqualError(t, err, "converting activity inputs: mismatched argument count: expected 2, got 0")
 			},
 		},
+		{
+			name: "invalid inputs",
+			setup: func(t *testing.T, r *workflow.Registry) *history.ActivityScheduledAttributes {
+				a := func(context.Context, int) error {
+					require.Fail(t, "activity executed with invalid inputs")
+					return nil
+				}
+				require.NoError(t, r.RegisterActivity(a, func(o *core.ActivityRegistrationOptions) {
+					o.Validator = &testValidator{}
+				}))
+
+				return &history.ActivityScheduledAttributes{
+					Name:   fn.Name(a),
+					Inputs: []payload.Payload{payload.Payload("-1")},
+				}
+			},
+			result: func(t *testing.T, result payload.Payload, err error) {
+				require.Nil(t, result)
+				require.EqualError(t, err, "validating activity inputs: must be positive")
+			},
+		},
+		{
+			name: "invalid result",
+			setup: func(t *testing.T, r *workflow.Registry) *history.ActivityScheduledAttributes {
+				a := func(context.Context) (int, error) { return -1, nil }
+				require.NoError(t, r.RegisterActivity(a, func(o *core.ActivityRegistrationOptions) {
+					o.Validator = &testValidator{}
+				}))
+
+				return &history.ActivityScheduledAttributes{
+					Name: fn.Name(a),
+				}
+			},
+			result: func(t *testing.T, result payload.Payload, err error) {
+				require.Nil(t, result)
+				require.EqualError(t, err, "validating activity result: must be positive")
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -58,8 +119,11 @@ func TestExecutor_ExecuteActivity(t *testing.T) {
 			attr := tt.setup(t, r)
 
 			e := &Executor{
-				logger: logger.NewDefaultLogger(),
-				r:      r,
+				logger:    logger.NewDefaultLogger(),
+				converter: converter.DefaultConverter,
+				metrics:   mi.NewNoopMetricsClient(),
+				tracer:    tracing.NewNoopTracer(),
+				r:         r,
 			}
 			got, err := e.ExecuteActivity(context.Background(), &task.Activity{
 				ID:               uuid.NewString(),
@@ -70,3 +134,235 @@ func TestExecutor_ExecuteActivity(t *testing.T) {
 		})
 	}
 }
+
+type taggedMetricsClient struct {
+	metrics.Client
+
+	tags map[string]string
+}
+
+func (c *taggedMetricsClient) WithTags(tags map[string]string) metrics.Client {
+	c.tags = tags
+	return c
+}
+
+func TestExecutor_ActivityState(t *testing.T) {
+	r := workflow.NewRegistry()
+
+	var as *ActivityState
+	a := func(ctx context.Context) error {
+		as = GetActivityState(ctx)
+		return nil
+	}
+	require.NoError(t, r.RegisterActivity(a))
+
+	mc := &taggedMetricsClient{Client: mi.NewNoopMetricsClient()}
+	e := NewExecutor(logger.NewDefaultLogger(), nil, mc, tracing.NewNoopTracer(), r)
+
+	_, err := e.ExecuteActivity(context.Background(), &task.Activity{
+		ID:               uuid.NewString(),
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		Event: history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+			Name: fn.Name(a),
+		}),
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, as)
+	require.Equal(t, mc, as.Metrics)
+	require.Equal(t, map[string]string{
+		"activity_id":   as.ActivityID,
+		"activity_name": fn.Name(a),
+		"instance_id":   "instanceID",
+		"execution_id":  "executionID",
+	}, mc.tags)
+}
+
+func TestExecutor_StartToCloseTimeout(t *testing.T) {
+	r := workflow.NewRegistry()
+
+	var deadline time.Time
+	a := func(ctx context.Context) error {
+		deadline, _ = ctx.Deadline()
+		return nil
+	}
+	require.NoError(t, r.RegisterActivity(a, func(o *core.ActivityRegistrationOptions) {
+		o.StartToCloseTimeout = time.Hour
+	}))
+
+	e := NewExecutor(logger.NewDefaultLogger(), nil, mi.NewNoopMetricsClient(), tracing.NewNoopTracer(), r)
+
+	execute := func(timeout time.Duration) {
+		_, err := e.ExecuteActivity(context.Background(), &task.Activity{
+			ID:               uuid.NewString(),
+			WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+			Event: history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+				Name:                fn.Name(a),
+				StartToCloseTimeout: timeout,
+			}),
+		})
+		require.NoError(t, err)
+	}
+
+	// Registered timeout
+	execute(0)
+	require.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Minute)
+
+	// Timeout passed when scheduling the activity
+	execute(time.Minute)
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second*10)
+}
+
+func TestExecutor_HeartbeatTimeout(t *testing.T) {
+	r := workflow.NewRegistry()
+
+	stuck := func(ctx context.Context) error {
+		// Ignores cancellation, like an activity blocked on I/O
+		time.Sleep(time.Second)
+		return nil
+	}
+	require.NoError(t, r.RegisterActivity(stuck, func(o *core.ActivityRegistrationOptions) {
+		o.HeartbeatTimeout = time.Hour
+	}))
+
+	heartbeating := func(ctx context.Context) (int, error) {
+		for i := 0; i < 5; i++ {
+			time.Sleep(20 * time.Millisecond)
+			GetActivityState(ctx).RecordHeartbeat(nil)
+		}
+
+		return 42, nil
+	}
+	require.NoError(t, r.RegisterActivity(heartbeating))
+
+	e := NewExecutor(logger.NewDefaultLogger(), converter.DefaultConverter, mi.NewNoopMetricsClient(), tracing.NewNoopTracer(), r)
+
+	execute := func(a interface{}, timeout time.Duration) (payload.Payload, error) {
+		return e.ExecuteActivity(context.Background(), &task.Activity{
+			ID:               uuid.NewString(),
+			WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+			Event: history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+				Name:             fn.Name(a),
+				HeartbeatTimeout: timeout,
+			}),
+		})
+	}
+
+	// Timeout passed when scheduling the activity overrides the registered one
+	start := time.Now()
+	_, err := execute(stuck, 50*time.Millisecond)
+	require.ErrorIs(t, err, ErrHeartbeatTimeout)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// Runs longer than the heartbeat timeout, but records heartbeats
+	result, err := execute(heartbeating, 50*time.Millisecond)
+	require.NoError(t, err)
+	require.Equal(t, payload.Payload("42"), result)
+}
+
+func TestExecutor_HeartbeatDetails(t *testing.T) {
+	r := workflow.NewRegistry()
+
+	a := func(ctx context.Context) (int, error) {
+		as := GetActivityState(ctx)
+
+		var progress int
+		require.NoError(t, as.Converter.From(as.HeartbeatDetails, &progress))
+
+		p, err := as.Converter.To(progress + 1)
+		require.NoError(t, err)
+		as.RecordHeartbeat(p)
+
+		return progress, nil
+	}
+	require.NoError(t, r.RegisterActivity(a))
+
+	e := NewExecutor(logger.NewDefaultLogger(), converter.DefaultConverter, mi.NewNoopMetricsClient(), tracing.NewNoopTracer(), r)
+
+	var recorded []payload.Payload
+	ctx := WithHeartbeatFunc(context.Background(), func(details payload.Payload) {
+		recorded = append(recorded, details)
+	})
+
+	result, err := e.ExecuteActivity(ctx, &task.Activity{
+		ID:               uuid.NewString(),
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		Event: history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+			Name: fn.Name(a),
+		}),
+		HeartbeatDetails: payload.Payload("41"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, payload.Payload("41"), result)
+	require.Equal(t, []payload.Payload{payload.Payload("42")}, recorded)
+}
+
+// testValidator rejects integers that are not positive
+type testValidator struct{}
+
+func (v *testValidator) ValidateInputs(inputs []interface{}) error {
+	return v.validate(inputs)
+}
+
+func (v *testValidator) ValidateResults(results []interface{}) error {
+	return v.validate(results)
+}
+
+func (*testValidator) validate(values []interface{}) error {
+	for _, value := range values {
+		if n, ok := value.(int); ok && n <= 0 {
+			return errors.New("must be positive")
+		}
+	}
+
+	return nil
+}
+
+type traceKey struct{}
+
+type propagatingTracer struct {
+	trace.Tracer
+
+	parent     string
+	attributes map[string]string
+}
+
+func (t *propagatingTracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, trace.Span) {
+	t.parent, _ = ctx.Value(traceKey{}).(string)
+	t.attributes = attributes
+	return t.Tracer.Start(ctx, name, attributes)
+}
+
+func (t *propagatingTracer) Inject(ctx context.Context) map[string]string {
+	return map[string]string{"trace": ctx.Value(traceKey{}).(string)}
+}
+
+func (t *propagatingTracer) Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return context.WithValue(ctx, traceKey{}, carrier["trace"])
+}
+
+func TestExecutor_TraceContext(t *testing.T) {
+	r := workflow.NewRegistry()
+
+	a := func(ctx context.Context) error {
+		return nil
+	}
+	require.NoError(t, r.RegisterActivity(a))
+
+	tracer := &propagatingTracer{Tracer: tracing.NewNoopTracer()}
+	e := NewExecutor(logger.NewDefaultLogger(), nil, mi.NewNoopMetricsClient(), tracer, r)
+
+	_, err := e.ExecuteActivity(context.Background(), &task.Activity{
+		ID:               uuid.NewString(),
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		Event: history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{
+			Name:         fn.Name(a),
+			TraceContext: map[string]string{"trace": "workflow-span"},
+		}),
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "workflow-span", tracer.parent)
+	require.Equal(t, fn.Name(a), tracer.attributes["activity_name"])
+	require.Equal(t, "instanceID", tracer.attributes["instance_id"])
+}
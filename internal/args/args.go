@@ -13,7 +13,15 @@ import (
 func ArgsToInputs(c converter.Converter, args ...interface{}) ([]payload.Payload, error) {
 	inputs := make([]payload.Payload, 0)
 
-	for _, arg := range args {
+	for i, arg := range args {
+		// Contexts cannot be serialized, workflows and activities receive their context automatically
+		switch arg.(type) {
+		case context.Context:
+			return nil, fmt.Errorf("argument %d is a context.Context, contexts are passed to workflows and activities automatically and cannot be passed as argument", i)
+		case sync.Context:
+			return nil, fmt.Errorf("argument %d is a workflow.Context, contexts are passed to workflows and activities automatically and cannot be passed as argument", i)
+		}
+
 		input, err := c.To(arg)
 		if err != nil {
 			return nil, fmt.Errorf("converting args to inputs: %w", err)
@@ -37,7 +45,7 @@ func InputsToArgs(c converter.Converter, fn reflect.Value, inputs []payload.Payl
 		argT := activityFnT.In(i)
 
 		// Insert context if requested
-		if i == 0 && (IsOwnContext(argT) || isContext(argT)) {
+		if i == 0 && (IsOwnContext(argT) || IsContext(argT)) {
 			addContext = true
 			continue
 		}
@@ -71,7 +79,7 @@ func IsOwnContext(inType reflect.Type) bool {
 	return inType != nil && inType.Implements(contextElem)
 }
 
-func isContext(inType reflect.Type) bool {
+func IsContext(inType reflect.Type) bool {
 	contextElem := reflect.TypeOf((*context.Context)(nil)).Elem()
 	return inType != nil && inType.Implements(contextElem)
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/stretchr/testify/require"
 )
 
@@ -89,3 +90,13 @@ func TestInputsToArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestArgsToInputs_RejectsContexts(t *testing.T) {
+	c := converter.DefaultConverter
+
+	_, err := ArgsToInputs(c, 42, context.Background())
+	require.ErrorContains(t, err, "argument 1 is a context.Context")
+
+	_, err = ArgsToInputs(c, sync.Background())
+	require.ErrorContains(t, err, "argument 0 is a workflow.Context")
+}
@@ -3,7 +3,9 @@ package command
 import (
 	"time"
 
+	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/google/uuid"
 )
@@ -23,7 +25,12 @@ const (
 
 	CommandType_SideEffect
 
+	CommandType_AddTags
+
+	CommandType_RecordMarker
+
 	CommandType_CompleteWorkflow
+	CommandType_ContinueAsNew
 )
 
 func (ct CommandType) String() string {
@@ -44,8 +51,16 @@ func (ct CommandType) String() string {
 	case CommandType_SideEffect:
 		return "SideEffect"
 
+	case CommandType_AddTags:
+		return "AddTags"
+
+	case CommandType_RecordMarker:
+		return "RecordMarker"
+
 	case CommandType_CompleteWorkflow:
 		return "CompleteWorkflow"
+	case CommandType_ContinueAsNew:
+		return "ContinueAsNew"
 	}
 
 	return ""
@@ -70,28 +85,40 @@ type Command struct {
 }
 
 type ScheduleActivityTaskCommandAttr struct {
-	Name   string
-	Inputs []payload.Payload
+	Name                string
+	Inputs              []payload.Payload
+	MemoizeFor          time.Duration
+	StartToCloseTimeout time.Duration
+	HeartbeatTimeout    time.Duration
+	Queue               string
+	Attempt             int
 }
 
-func NewScheduleActivityTaskCommand(id int64, name string, inputs []payload.Payload) Command {
+func NewScheduleActivityTaskCommand(id int64, name string, inputs []payload.Payload, memoizeFor, startToCloseTimeout, heartbeatTimeout time.Duration, queue string, attempt int) Command {
 	return Command{
 		ID:   id,
 		Type: CommandType_ScheduleActivity,
 		Attr: &ScheduleActivityTaskCommandAttr{
-			Name:   name,
-			Inputs: inputs,
+			Name:                name,
+			Inputs:              inputs,
+			MemoizeFor:          memoizeFor,
+			StartToCloseTimeout: startToCloseTimeout,
+			HeartbeatTimeout:    heartbeatTimeout,
+			Queue:               queue,
+			Attempt:             attempt,
 		},
 	}
 }
 
 type ScheduleSubWorkflowCommandAttr struct {
-	Instance *core.WorkflowInstance
-	Name     string
-	Inputs   []payload.Payload
+	Instance          *core.WorkflowInstance
+	Name              string
+	Inputs            []payload.Payload
+	ParentClosePolicy core.ParentClosePolicy
+	Queue             string
 }
 
-func NewScheduleSubWorkflowCommand(id int64, parentInstance *core.WorkflowInstance, subWorkflowInstanceID, name string, inputs []payload.Payload) Command {
+func NewScheduleSubWorkflowCommand(id int64, parentInstance *core.WorkflowInstance, subWorkflowInstanceID, name string, inputs []payload.Payload, parentClosePolicy core.ParentClosePolicy, queue string) Command {
 	if subWorkflowInstanceID == "" {
 		subWorkflowInstanceID = uuid.New().String()
 	}
@@ -100,9 +127,11 @@ func NewScheduleSubWorkflowCommand(id int64, parentInstance *core.WorkflowInstan
 		ID:   id,
 		Type: CommandType_ScheduleSubWorkflow,
 		Attr: &ScheduleSubWorkflowCommandAttr{
-			Instance: core.NewSubWorkflowInstance(subWorkflowInstanceID, uuid.NewString(), parentInstance.InstanceID, id),
-			Name:     name,
-			Inputs:   inputs,
+			Instance:          core.NewSubWorkflowInstance(subWorkflowInstanceID, uuid.NewString(), parentInstance.InstanceID, id),
+			Name:              name,
+			Inputs:            inputs,
+			ParentClosePolicy: parentClosePolicy,
+			Queue:             queue,
 		},
 	}
 }
@@ -163,12 +192,43 @@ func NewSideEffectCommand(id int64, result payload.Payload) Command {
 	}
 }
 
+type AddTagsCommandAttr struct {
+	Tags []string
+}
+
+func NewAddTagsCommand(id int64, tags []string) Command {
+	return Command{
+		ID:   id,
+		Type: CommandType_AddTags,
+		Attr: &AddTagsCommandAttr{
+			Tags: tags,
+		},
+	}
+}
+
+type RecordMarkerCommandAttr struct {
+	Name string
+	Data payload.Payload
+}
+
+func NewRecordMarkerCommand(id int64, name string, data payload.Payload) Command {
+	return Command{
+		ID:   id,
+		Type: CommandType_RecordMarker,
+		Attr: &RecordMarkerCommandAttr{
+			Name: name,
+			Data: data,
+		},
+	}
+}
+
 type CompleteWorkflowCommandAttr struct {
-	Result payload.Payload
-	Error  string
+	Result  payload.Payload
+	Error   string
+	Failure *history.Failure
 }
 
-func NewCompleteWorkflowCommand(id int64, result payload.Payload, err error) Command {
+func NewCompleteWorkflowCommand(id int64, c converter.Converter, result payload.Payload, err error, completedAt time.Time) Command {
 	var error string
 	if err != nil {
 		error = err.Error()
@@ -178,8 +238,23 @@ func NewCompleteWorkflowCommand(id int64, result payload.Payload, err error) Com
 		ID:   id,
 		Type: CommandType_CompleteWorkflow,
 		Attr: &CompleteWorkflowCommandAttr{
-			Result: result,
-			Error:  error,
+			Result:  result,
+			Error:   error,
+			Failure: history.NewFailure(c, err, completedAt),
+		},
+	}
+}
+
+type ContinueAsNewCommandAttr struct {
+	Inputs []payload.Payload
+}
+
+func NewContinueAsNewCommand(id int64, inputs []payload.Payload) Command {
+	return Command{
+		ID:   id,
+		Type: CommandType_ContinueAsNew,
+		Attr: &ContinueAsNewCommandAttr{
+			Inputs: inputs,
 		},
 	}
 }
@@ -0,0 +1,128 @@
+package converter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// ErrUnknownEncryptionKey is returned when decoding a payload encrypted with a key that is not available
+var ErrUnknownEncryptionKey = errors.New("unknown encryption key")
+
+// EncryptionKeys are the keys of an AES codec
+type EncryptionKeys struct {
+	// CurrentKeyID is the ID of the key new payloads are encrypted with
+	CurrentKeyID string
+
+	// Keys are the keys available for decryption, by their ID. They have to include the current key. Keys are
+	// 16, 24, or 32 bytes long, to use AES-128, AES-192, or AES-256.
+	Keys map[string][]byte
+}
+
+// AESCodec is a payload codec encrypting data with AES-GCM. The ID of the key is recorded in the payload
+// metadata, so keys can be rotated while payloads encrypted with earlier keys are still stored.
+type AESCodec struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+var _ PayloadCodec = (*AESCodec)(nil)
+
+// NewAESCodec returns a payload codec encrypting data with the current key, and decrypting data with any of
+// the given keys
+func NewAESCodec(keys EncryptionKeys) (*AESCodec, error) {
+	c := &AESCodec{
+		current: keys.CurrentKeyID,
+		aeads:   make(map[string]cipher.AEAD, len(keys.Keys)),
+	}
+
+	for id, key := range keys.Keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("creating cipher for key %q: %w", id, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("creating cipher for key %q: %w", id, err)
+		}
+
+		c.aeads[id] = aead
+	}
+
+	if _, ok := c.aeads[keys.CurrentKeyID]; !ok {
+		return nil, fmt.Errorf("current key %q: %w", keys.CurrentKeyID, ErrUnknownEncryptionKey)
+	}
+
+	return c, nil
+}
+
+func (c *AESCodec) Encode(data []byte, metadata payload.Metadata) ([]byte, error) {
+	aead := c.aeads[c.current]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	metadata[payload.MetadataEncryptionKeyID] = c.current
+
+	// The nonce is stored in front of the encrypted data
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func (c *AESCodec) Decode(data []byte, metadata payload.Metadata) ([]byte, error) {
+	id, ok := metadata[payload.MetadataEncryptionKeyID]
+	if !ok {
+		// Not encrypted
+		return data, nil
+	}
+
+	aead, ok := c.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("key %q: %w", id, ErrUnknownEncryptionKey)
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted data too short")
+	}
+
+	data, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting with key %q: %w", id, err)
+	}
+
+	delete(metadata, payload.MetadataEncryptionKeyID)
+
+	return data, nil
+}
+
+// Reencrypt returns the given payload encrypted with the current key. Payloads that are already encrypted with
+// the current key, or not encrypted at all, are returned unchanged. The codec has to be the last codec of the
+// envelope converter, so the encryption is the outermost encoding of the data.
+func (c *AESCodec) Reencrypt(p payload.Payload) (payload.Payload, error) {
+	data, metadata, err := payload.Unwrap(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if id, ok := metadata[payload.MetadataEncryptionKeyID]; !ok || id == c.current {
+		return p, nil
+	}
+
+	data, err = c.Decode(data, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = c.Encode(data, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload.Wrap(data, metadata)
+}
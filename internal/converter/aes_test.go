@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testKey1 = []byte("0123456789abcdef0123456789abcdef")
+	testKey2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestAESCodec(t *testing.T) {
+	codec, err := NewAESCodec(EncryptionKeys{CurrentKeyID: "1", Keys: map[string][]byte{"1": testKey1}})
+	require.NoError(t, err)
+
+	c := NewEnvelopeConverter(DefaultConverter, codec)
+
+	p, err := c.To("secret")
+	require.NoError(t, err)
+	require.NotContains(t, string(p), "secret")
+
+	_, metadata, err := payload.Unwrap(p)
+	require.NoError(t, err)
+	require.Equal(t, "1", metadata[payload.MetadataEncryptionKeyID])
+
+	var r string
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, "secret", r)
+
+	// Unencrypted payloads can still be decoded
+	p, err = NewEnvelopeConverter(DefaultConverter).To("plain")
+	require.NoError(t, err)
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, "plain", r)
+}
+
+func TestAESCodec_KeyRotation(t *testing.T) {
+	codec1, err := NewAESCodec(EncryptionKeys{CurrentKeyID: "1", Keys: map[string][]byte{"1": testKey1}})
+	require.NoError(t, err)
+
+	p, err := NewEnvelopeConverter(DefaultConverter, codec1).To("secret")
+	require.NoError(t, err)
+
+	codec2, err := NewAESCodec(EncryptionKeys{CurrentKeyID: "2", Keys: map[string][]byte{"1": testKey1, "2": testKey2}})
+	require.NoError(t, err)
+
+	// Payloads encrypted with the previous key can still be decoded
+	var r string
+	require.NoError(t, NewEnvelopeConverter(DefaultConverter, codec2).From(p, &r))
+	require.Equal(t, "secret", r)
+
+	p2, err := codec2.Reencrypt(p)
+	require.NoError(t, err)
+
+	_, metadata, err := payload.Unwrap(p2)
+	require.NoError(t, err)
+	require.Equal(t, "2", metadata[payload.MetadataEncryptionKeyID])
+
+	// Payloads encrypted with the current key are unchanged
+	p3, err := codec2.Reencrypt(p2)
+	require.NoError(t, err)
+	require.Equal(t, p2, p3)
+
+	// Once re-encrypted, the previous key is not needed anymore
+	codec3, err := NewAESCodec(EncryptionKeys{CurrentKeyID: "2", Keys: map[string][]byte{"2": testKey2}})
+	require.NoError(t, err)
+
+	c3 := NewEnvelopeConverter(DefaultConverter, codec3)
+	require.NoError(t, c3.From(p2, &r))
+	require.Equal(t, "secret", r)
+
+	require.ErrorIs(t, c3.From(p, &r), ErrUnknownEncryptionKey)
+}
+
+func TestAESCodec_InvalidKeys(t *testing.T) {
+	_, err := NewAESCodec(EncryptionKeys{CurrentKeyID: "1", Keys: map[string][]byte{"1": []byte("short")}})
+	require.Error(t, err)
+
+	_, err = NewAESCodec(EncryptionKeys{CurrentKeyID: "2", Keys: map[string][]byte{"1": testKey1}})
+	require.ErrorIs(t, err, ErrUnknownEncryptionKey)
+}
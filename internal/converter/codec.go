@@ -0,0 +1,44 @@
+package converter
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Codec encodes and decodes values of a single type
+type Codec interface {
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte, vptr interface{}) error
+}
+
+// CodecRegistry holds custom codecs by type
+type CodecRegistry struct {
+	mu     sync.RWMutex
+	codecs map[reflect.Type]Codec
+}
+
+func NewCodecRegistry() *CodecRegistry {
+	return &CodecRegistry{
+		codecs: make(map[reflect.Type]Codec),
+	}
+}
+
+// Register sets the codec used for values of type t
+func (r *CodecRegistry) Register(t reflect.Type, codec Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.codecs[t] = codec
+}
+
+func (r *CodecRegistry) codec(t reflect.Type) (Codec, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.codecs[t]
+	return c, ok
+}
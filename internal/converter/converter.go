@@ -12,7 +12,7 @@ type Converter interface {
 	From(data payload.Payload, v interface{}) error
 }
 
-var DefaultConverter Converter = &jsonConverter{}
+var DefaultConverter Converter = NewJSONConverter(nil)
 
 func AssignValue(c Converter, v interface{}, vptr interface{}) error {
 	vvptr := reflect.ValueOf(vptr)
@@ -39,7 +39,7 @@ func AssignValue(c Converter, v interface{}, vptr interface{}) error {
 			return nil
 		}
 
-		return c.From(vp, vptr)
+		return Decode(c, vp, vptr)
 	} else {
 		// TODO: Assert that values can be assigned
 		vvptr.Elem().Set(reflect.ValueOf(v))
@@ -1,6 +1,10 @@
 package converter
 
 import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -49,3 +53,99 @@ func TestAssignValue_Time(t *testing.T) {
 
 	require.True(t, i.Equal(r))
 }
+
+func TestJSONConverter_RawMessage(t *testing.T) {
+	raw := json.RawMessage(`{"a":  1}`)
+
+	p, err := DefaultConverter.To(raw)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":  1}`, string(p))
+
+	var r json.RawMessage
+	require.NoError(t, DefaultConverter.From(p, &r))
+	require.Equal(t, raw, r)
+}
+
+func TestJSONConverter_Numbers(t *testing.T) {
+	p, err := DefaultConverter.To(map[string]interface{}{
+		"small": 42,
+		"list":  []interface{}{1.5},
+	})
+	require.NoError(t, err)
+
+	var r interface{}
+	require.NoError(t, DefaultConverter.From(p, &r))
+	require.Equal(t, map[string]interface{}{
+		"small": float64(42),
+		"list":  []interface{}{1.5},
+	}, r)
+
+	var i int64
+	require.NoError(t, DefaultConverter.From([]byte("9223372036854775807"), &i))
+	require.Equal(t, int64(math.MaxInt64), i)
+}
+
+func TestJSONConverter_UseNumber(t *testing.T) {
+	c := NewJSONConverter(nil, WithUseNumber())
+
+	p, err := c.To(map[string]interface{}{
+		"large": int64(math.MaxInt64),
+		"small": 42,
+		"list":  []interface{}{int64(1 << 60), 1.5},
+	})
+	require.NoError(t, err)
+
+	var r interface{}
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, map[string]interface{}{
+		"large": json.Number("9223372036854775807"),
+		"small": json.Number("42"),
+		"list":  []interface{}{json.Number("1152921504606846976"), json.Number("1.5")},
+	}, r)
+
+	// Typed values are decoded as usual
+	var s struct {
+		Large int64
+		Small float64
+	}
+	require.NoError(t, c.From([]byte(`{"Large":9223372036854775807,"Small":42}`), &s))
+	require.Equal(t, int64(math.MaxInt64), s.Large)
+	require.Equal(t, float64(42), s.Small)
+}
+
+type unixCodec struct{}
+
+func (unixCodec) Encode(v interface{}) ([]byte, error) {
+	return []byte(strconv.FormatInt(v.(time.Time).Unix(), 10)), nil
+}
+
+func (unixCodec) Decode(data []byte, vptr interface{}) error {
+	s, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*vptr.(*time.Time) = time.Unix(s, 0)
+	return nil
+}
+
+func TestJSONConverter_Codec(t *testing.T) {
+	r := NewCodecRegistry()
+	r.Register(reflect.TypeOf(time.Time{}), unixCodec{})
+
+	c := NewJSONConverter(r)
+
+	now := time.Unix(1650000000, 0)
+	p, err := c.To(now)
+	require.NoError(t, err)
+	require.Equal(t, "1650000000", string(p))
+
+	var result time.Time
+	require.NoError(t, c.From(p, &result))
+	require.True(t, now.Equal(result))
+
+	// Other types are still encoded as JSON
+	p, err = c.To("test")
+	require.NoError(t, err)
+	require.Equal(t, `"test"`, string(p))
+}
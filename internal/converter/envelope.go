@@ -0,0 +1,78 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// PayloadCodec transforms encoded payload data, for example to compress or encrypt it. Encode records
+// what it did in the metadata, Decode uses the metadata to determine whether and how to reverse it.
+type PayloadCodec interface {
+	Encode(data []byte, metadata payload.Metadata) ([]byte, error)
+	Decode(data []byte, metadata payload.Metadata) ([]byte, error)
+}
+
+// Encoder is implemented by converters that can describe their encoding in payload metadata
+type Encoder interface {
+	Metadata() payload.Metadata
+}
+
+type envelopeConverter struct {
+	c      Converter
+	codecs []PayloadCodec
+}
+
+// NewEnvelopeConverter returns a converter that wraps the payloads produced by c together with their
+// metadata. Codecs are applied in order when encoding, and in reverse order when decoding. Payloads
+// without an envelope are passed to c directly, so existing payloads can still be decoded.
+func NewEnvelopeConverter(c Converter, codecs ...PayloadCodec) Converter {
+	return &envelopeConverter{c, codecs}
+}
+
+func (ec *envelopeConverter) To(v interface{}) (payload.Payload, error) {
+	data, err := ec.c.To(v)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := payload.Metadata{}
+	if e, ok := ec.c.(Encoder); ok {
+		for k, v := range e.Metadata() {
+			metadata[k] = v
+		}
+	}
+
+	for _, codec := range ec.codecs {
+		data, err = codec.Encode(data, metadata)
+		if err != nil {
+			return nil, fmt.Errorf("encoding payload: %w", err)
+		}
+	}
+
+	return payload.Wrap(data, metadata)
+}
+
+func (ec *envelopeConverter) From(p payload.Payload, vptr interface{}) error {
+	if !payload.IsWrapped(p) {
+		return ec.c.From(p, vptr)
+	}
+
+	data, metadata, err := payload.Unwrap(p)
+	if err != nil {
+		return err
+	}
+
+	if metadata == nil {
+		metadata = payload.Metadata{}
+	}
+
+	for i := len(ec.codecs) - 1; i >= 0; i-- {
+		data, err = ec.codecs[i].Decode(data, metadata)
+		if err != nil {
+			return fmt.Errorf("decoding payload: %w", err)
+		}
+	}
+
+	return ec.c.From(data, vptr)
+}
@@ -0,0 +1,63 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelopeConverter(t *testing.T) {
+	c := NewEnvelopeConverter(DefaultConverter)
+
+	p, err := c.To(42)
+	require.NoError(t, err)
+	require.True(t, payload.IsWrapped(p))
+
+	data, metadata, err := payload.Unwrap(p)
+	require.NoError(t, err)
+	require.Equal(t, "42", string(data))
+	require.Equal(t, "json/plain", metadata[payload.MetadataEncoding])
+	require.Equal(t, "application/json", metadata[payload.MetadataContentType])
+
+	var r int
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, 42, r)
+}
+
+func TestEnvelopeConverter_DecodesBarePayloads(t *testing.T) {
+	c := NewEnvelopeConverter(DefaultConverter)
+
+	p, err := DefaultConverter.To("test")
+	require.NoError(t, err)
+
+	var r string
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, "test", r)
+}
+
+func TestEnvelopeConverter_Gzip(t *testing.T) {
+	c := NewEnvelopeConverter(DefaultConverter, NewGzipCodec(100))
+
+	long := strings.Repeat("a", 1000)
+	p, err := c.To(long)
+	require.NoError(t, err)
+	require.Less(t, len(p), 200)
+
+	_, metadata, err := payload.Unwrap(p)
+	require.NoError(t, err)
+	require.Equal(t, "gzip", metadata[payload.MetadataCompression])
+
+	var r string
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, long, r)
+
+	// Short payloads are not compressed
+	p, err = c.To("a")
+	require.NoError(t, err)
+
+	_, metadata, err = payload.Unwrap(p)
+	require.NoError(t, err)
+	require.NotContains(t, metadata, payload.MetadataCompression)
+}
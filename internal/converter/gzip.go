@@ -0,0 +1,59 @@
+package converter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+type gzipCodec struct {
+	minSize int
+}
+
+// NewGzipCodec returns a payload codec that compresses data of at least minSize bytes using gzip
+func NewGzipCodec(minSize int) PayloadCodec {
+	return &gzipCodec{minSize}
+}
+
+func (c *gzipCodec) Encode(data []byte, metadata payload.Metadata) ([]byte, error) {
+	if len(data) < c.minSize {
+		return data, nil
+	}
+
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	metadata[payload.MetadataCompression] = "gzip"
+
+	return b.Bytes(), nil
+}
+
+func (c *gzipCodec) Decode(data []byte, metadata payload.Metadata) ([]byte, error) {
+	if metadata[payload.MetadataCompression] != "gzip" {
+		return data, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err = io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(metadata, payload.MetadataCompression)
+
+	return data, nil
+}
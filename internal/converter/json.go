@@ -1,17 +1,90 @@
 package converter
 
 import (
+	"bytes"
 	"encoding/json"
+	"reflect"
 
 	"github.com/cschleiden/go-workflows/internal/payload"
 )
 
-type jsonConverter struct{}
+type jsonConverter struct {
+	codecs    *CodecRegistry
+	useNumber bool
+}
+
+// JSONOption configures a converter returned by NewJSONConverter
+type JSONOption func(jc *jsonConverter)
+
+// WithUseNumber decodes numbers in interface{} values as json.Number instead of float64, so that integers
+// that float64 cannot represent exactly keep their value
+func WithUseNumber() JSONOption {
+	return func(jc *jsonConverter) {
+		jc.useNumber = true
+	}
+}
+
+// NewJSONConverter returns a converter encoding values as JSON. Values of types with a codec in the given
+// registry are encoded using that codec instead. codecs may be nil.
+func NewJSONConverter(codecs *CodecRegistry, opts ...JSONOption) Converter {
+	jc := &jsonConverter{codecs: codecs}
+	for _, opt := range opts {
+		opt(jc)
+	}
+
+	return jc
+}
+
+// Metadata implements Encoder
+func (jc *jsonConverter) Metadata() payload.Metadata {
+	return payload.Metadata{
+		payload.MetadataEncoding:    "json/plain",
+		payload.MetadataContentType: "application/json",
+	}
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
 
 func (jc *jsonConverter) To(v interface{}) (payload.Payload, error) {
+	if v != nil {
+		if c, ok := jc.codecs.codec(reflect.TypeOf(v)); ok {
+			return c.Encode(v)
+		}
+	}
+
+	// Pass through values that are already encoded
+	switch rv := v.(type) {
+	case json.RawMessage:
+		if rv != nil {
+			return payload.Payload(rv), nil
+		}
+	case *json.RawMessage:
+		if rv != nil && *rv != nil {
+			return payload.Payload(*rv), nil
+		}
+	}
+
 	return json.Marshal(v)
 }
 
 func (jc *jsonConverter) From(data payload.Payload, vptr interface{}) error {
-	return json.Unmarshal(data, vptr)
+	if t := reflect.TypeOf(vptr); t != nil && t.Kind() == reflect.Ptr {
+		if c, ok := jc.codecs.codec(t.Elem()); ok {
+			return c.Decode(data, vptr)
+		}
+
+		if t.Elem() == rawMessageType {
+			*vptr.(*json.RawMessage) = append(json.RawMessage(nil), data...)
+			return nil
+		}
+	}
+
+	if !jc.useNumber {
+		return json.Unmarshal(data, vptr)
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	return d.Decode(vptr)
 }
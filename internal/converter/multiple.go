@@ -0,0 +1,73 @@
+package converter
+
+import (
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// MultiValue is implemented by types holding multiple values, like the results of activities or workflows
+// returning more than one value. ValuePointers returns pointers to the individual values in order.
+type MultiValue interface {
+	ValuePointers() []interface{}
+}
+
+// ToMultiple converts the given values into a single payload containing the list of individually converted
+// values.
+func ToMultiple(c Converter, vs ...interface{}) (payload.Payload, error) {
+	ps := make([]payload.Payload, len(vs))
+
+	for i, v := range vs {
+		p, err := c.To(v)
+		if err != nil {
+			return nil, fmt.Errorf("converting value %d: %w", i, err)
+		}
+
+		ps[i] = p
+	}
+
+	return c.To(ps)
+}
+
+// FromMultiple converts a payload created by ToMultiple into the given pointers.
+func FromMultiple(c Converter, data payload.Payload, vptrs ...interface{}) error {
+	var ps []payload.Payload
+	if err := c.From(data, &ps); err != nil {
+		return fmt.Errorf("converting values: %w", err)
+	}
+
+	if len(ps) != len(vptrs) {
+		return fmt.Errorf("mismatched value count: expected %d, got %d", len(vptrs), len(ps))
+	}
+
+	for i, p := range ps {
+		if err := c.From(p, vptrs[i]); err != nil {
+			return fmt.Errorf("converting value %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Decode converts the given payload into vptr. If vptr implements MultiValue, the payload is expected to
+// have been created by ToMultiple.
+func Decode(c Converter, data payload.Payload, vptr interface{}) error {
+	if mv, ok := vptr.(MultiValue); ok {
+		return FromMultiple(c, data, mv.ValuePointers()...)
+	}
+
+	return c.From(data, vptr)
+}
+
+// EncodeResults converts the non-error return values of an activity or workflow into a payload. A single
+// value is converted directly, multiple values are converted using ToMultiple.
+func EncodeResults(c Converter, vs []interface{}) (payload.Payload, error) {
+	switch len(vs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return c.To(vs[0])
+	default:
+		return ToMultiple(c, vs...)
+	}
+}
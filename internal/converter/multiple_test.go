@@ -0,0 +1,43 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type pair struct {
+	a int
+	b string
+}
+
+func (p *pair) ValuePointers() []interface{} {
+	return []interface{}{&p.a, &p.b}
+}
+
+func TestMultiple_Roundtrip(t *testing.T) {
+	payload, err := EncodeResults(DefaultConverter, []interface{}{42, "hello"})
+	require.NoError(t, err)
+
+	var p pair
+	require.NoError(t, Decode(DefaultConverter, payload, &p))
+	require.Equal(t, 42, p.a)
+	require.Equal(t, "hello", p.b)
+}
+
+func TestMultiple_MismatchedCount(t *testing.T) {
+	payload, err := ToMultiple(DefaultConverter, 42, "hello", true)
+	require.NoError(t, err)
+
+	var p pair
+	require.EqualError(t, Decode(DefaultConverter, payload, &p), "mismatched value count: expected 2, got 3")
+}
+
+func TestEncodeResults_Single(t *testing.T) {
+	payload, err := EncodeResults(DefaultConverter, []interface{}{42})
+	require.NoError(t, err)
+
+	var r int
+	require.NoError(t, Decode(DefaultConverter, payload, &r))
+	require.Equal(t, 42, r)
+}
@@ -0,0 +1,64 @@
+package converter
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// PayloadRedactor returns a copy of the given payload with sensitive data masked. Redactors are applied to
+// payloads shown to people, never to the payloads used for execution, so they must not modify p.
+type PayloadRedactor func(p payload.Payload) payload.Payload
+
+// RedactedValue replaces the values masked by a redactor
+const RedactedValue = "[REDACTED]"
+
+// NewJSONFieldRedactor returns a redactor that decodes payloads with c, and replaces the values of all object
+// fields with one of the given names, at any depth, with RedactedValue. Names are matched case-insensitively.
+// The redacted value is returned as plain JSON, payloads that cannot be decoded are replaced entirely.
+func NewJSONFieldRedactor(c Converter, fields ...string) PayloadRedactor {
+	names := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		names[strings.ToLower(f)] = true
+	}
+
+	return func(p payload.Payload) payload.Payload {
+		var v interface{}
+		if err := c.From(p, &v); err != nil {
+			return redacted()
+		}
+
+		data, err := json.Marshal(redactFields(v, names))
+		if err != nil {
+			return redacted()
+		}
+
+		return data
+	}
+}
+
+func redacted() payload.Payload {
+	data, _ := json.Marshal(RedactedValue)
+	return data
+}
+
+func redactFields(v interface{}, names map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, fv := range v {
+			if names[strings.ToLower(k)] {
+				v[k] = RedactedValue
+			} else {
+				v[k] = redactFields(fv, names)
+			}
+		}
+
+	case []interface{}:
+		for i, iv := range v {
+			v[i] = redactFields(iv, names)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,53 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONFieldRedactor(t *testing.T) {
+	type address struct {
+		Street string
+		City   string
+	}
+
+	type customer struct {
+		Name    string
+		Email   string
+		Address address
+		Tags    []map[string]string
+	}
+
+	p, err := DefaultConverter.To(customer{
+		Name:    "Jane",
+		Email:   "jane@example.com",
+		Address: address{Street: "Main St 1", City: "Springfield"},
+		Tags:    []map[string]string{{"email": "other@example.com"}},
+	})
+	require.NoError(t, err)
+
+	original := string(p)
+
+	redact := NewJSONFieldRedactor(DefaultConverter, "email", "Street")
+	r := redact(p)
+
+	var v map[string]interface{}
+	require.NoError(t, DefaultConverter.From(r, &v))
+	require.Equal(t, "Jane", v["Name"])
+	require.Equal(t, RedactedValue, v["Email"])
+	require.Equal(t, map[string]interface{}{"Street": RedactedValue, "City": "Springfield"}, v["Address"])
+	require.Equal(t, []interface{}{map[string]interface{}{"email": RedactedValue}}, v["Tags"])
+
+	require.Equal(t, original, string(p))
+}
+
+func TestJSONFieldRedactor_InvalidPayload(t *testing.T) {
+	redact := NewJSONFieldRedactor(DefaultConverter, "email")
+
+	r := redact([]byte("not json"))
+
+	var v string
+	require.NoError(t, DefaultConverter.From(r, &v))
+	require.Equal(t, RedactedValue, v)
+}
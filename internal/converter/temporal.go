@@ -0,0 +1,113 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// Encodings used by Temporal's default data converter
+const (
+	temporalEncodingJSON   = "json/plain"
+	temporalEncodingNull   = "binary/null"
+	temporalEncodingBinary = "binary/plain"
+)
+
+// temporalPayload is the proto-JSON form of Temporal's Payload message. Metadata values and data are bytes
+// in the proto definition, so they are base64 encoded.
+type temporalPayload struct {
+	Metadata map[string][]byte `json:"metadata,omitempty"`
+	Data     []byte            `json:"data,omitempty"`
+}
+
+// temporalPayloads is the proto-JSON form of Temporal's Payloads message
+type temporalPayloads struct {
+	Payloads []json.RawMessage `json:"payloads"`
+}
+
+type temporalConverter struct {
+	c Converter
+}
+
+// NewTemporalConverter returns a converter that stores payloads in the proto-JSON form of Temporal's
+// Payload message, using c to encode values. Multiple values, for example the results of workflows
+// returning more than one value, are stored as Temporal Payloads message. c needs to encode values as
+// JSON, like the default converter.
+func NewTemporalConverter(c Converter) Converter {
+	return &temporalConverter{c}
+}
+
+func (tc *temporalConverter) To(v interface{}) (payload.Payload, error) {
+	switch vv := v.(type) {
+	case nil:
+		return json.Marshal(&temporalPayload{
+			Metadata: map[string][]byte{payload.MetadataEncoding: []byte(temporalEncodingNull)},
+		})
+
+	case []byte:
+		return json.Marshal(&temporalPayload{
+			Metadata: map[string][]byte{payload.MetadataEncoding: []byte(temporalEncodingBinary)},
+			Data:     vv,
+		})
+
+	case []payload.Payload:
+		ps := temporalPayloads{Payloads: make([]json.RawMessage, len(vv))}
+		for i, p := range vv {
+			ps.Payloads[i] = json.RawMessage(p)
+		}
+
+		return json.Marshal(&ps)
+	}
+
+	data, err := tc.c.To(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&temporalPayload{
+		Metadata: map[string][]byte{payload.MetadataEncoding: []byte(temporalEncodingJSON)},
+		Data:     data,
+	})
+}
+
+func (tc *temporalConverter) From(data payload.Payload, vptr interface{}) error {
+	if ps, ok := vptr.(*[]payload.Payload); ok {
+		var tps temporalPayloads
+		if err := json.Unmarshal(data, &tps); err != nil {
+			return fmt.Errorf("unmarshaling payloads: %w", err)
+		}
+
+		*ps = make([]payload.Payload, len(tps.Payloads))
+		for i, p := range tps.Payloads {
+			(*ps)[i] = payload.Payload(p)
+		}
+
+		return nil
+	}
+
+	var tp temporalPayload
+	if err := json.Unmarshal(data, &tp); err != nil {
+		return fmt.Errorf("unmarshaling payload: %w", err)
+	}
+
+	switch encoding := string(tp.Metadata[payload.MetadataEncoding]); encoding {
+	case temporalEncodingNull:
+		return tc.c.From(payload.Payload("null"), vptr)
+
+	case temporalEncodingBinary:
+		b, ok := vptr.(*[]byte)
+		if !ok {
+			return fmt.Errorf("cannot decode %s payload into %T", encoding, vptr)
+		}
+
+		*b = tp.Data
+		return nil
+
+	case temporalEncodingJSON:
+		return tc.c.From(tp.Data, vptr)
+
+	default:
+		return fmt.Errorf("unsupported payload encoding: %q", encoding)
+	}
+}
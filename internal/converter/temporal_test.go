@@ -0,0 +1,57 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemporalConverter(t *testing.T) {
+	c := NewTemporalConverter(DefaultConverter)
+
+	p, err := c.To("hello")
+	require.NoError(t, err)
+	// "json/plain" and "\"hello\"" in base64
+	require.JSONEq(t, `{"metadata":{"encoding":"anNvbi9wbGFpbg=="},"data":"ImhlbGxvIg=="}`, string(p))
+
+	var r string
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, "hello", r)
+}
+
+func TestTemporalConverter_NullAndBinary(t *testing.T) {
+	c := NewTemporalConverter(DefaultConverter)
+
+	p, err := c.To(nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"metadata":{"encoding":"YmluYXJ5L251bGw="}}`, string(p))
+
+	r := &struct{}{}
+	require.NoError(t, c.From(p, &r))
+	require.Nil(t, r)
+
+	p, err = c.To([]byte{1, 2, 3})
+	require.NoError(t, err)
+
+	var b []byte
+	require.NoError(t, c.From(p, &b))
+	require.Equal(t, []byte{1, 2, 3}, b)
+}
+
+func TestTemporalConverter_Multiple(t *testing.T) {
+	c := NewTemporalConverter(DefaultConverter)
+
+	p, err := ToMultiple(c, 42, "hello")
+	require.NoError(t, err)
+
+	var ps []payload.Payload
+	require.NoError(t, c.From(p, &ps))
+	require.Len(t, ps, 2)
+
+	var i int
+	var s string
+	require.NoError(t, FromMultiple(c, p, &i, &s))
+	require.Equal(t, 42, i)
+	require.Equal(t, "hello", s)
+}
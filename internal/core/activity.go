@@ -0,0 +1,23 @@
+package core
+
+import "time"
+
+// ActivityRegistrationOptions are the defaults an activity has been registered with
+type ActivityRegistrationOptions struct {
+	// RetryOptions are used when an activity is scheduled without retry options
+	RetryOptions *RetryOptions
+
+	// StartToCloseTimeout limits how long a single execution of the activity may take
+	StartToCloseTimeout time.Duration
+
+	// HeartbeatTimeout fails an execution of the activity if it does not record a heartbeat within the timeout
+	HeartbeatTimeout time.Duration
+
+	// Queue is the activity queue the activity is scheduled on, unless overridden when scheduling the activity
+	Queue string
+
+	// Validator validates the inputs and the result of the activity, if set
+	Validator Validator
+}
+
+type ActivityRegistrationOption func(*ActivityRegistrationOptions)
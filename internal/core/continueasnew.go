@@ -0,0 +1,13 @@
+package core
+
+import "github.com/cschleiden/go-workflows/internal/payload"
+
+// ContinueAsNewError is returned by a workflow to finish its current execution and continue as a new execution of
+// the same instance with the given inputs
+type ContinueAsNewError struct {
+	Inputs []payload.Payload
+}
+
+func (e *ContinueAsNewError) Error() string {
+	return "workflow continued as new"
+}
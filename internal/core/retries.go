@@ -0,0 +1,30 @@
+package core
+
+import "time"
+
+type RetryOptions struct {
+	// Maximum number of times to retry
+	MaxAttempts int
+
+	// Time to wait before first retry
+	FirstRetryInterval time.Duration
+
+	// Maximum delay for any individual retry attempt
+	MaxRetryInterval time.Duration
+
+	// Coeffecient for calculation the next retry delay
+	BackoffCoefficient float64
+
+	// Timeout after which retries are aborted
+	RetryTimeout time.Duration
+
+	// NonRetryableErrorTypes are the Go types of errors that are not retried, as formatted by %T, e.g.
+	// "*mypkg.ValidationError". Errors wrapping an error of one of these types are not retried either.
+	NonRetryableErrorTypes []string
+}
+
+// IsZero returns whether no retry options are set
+func (o RetryOptions) IsZero() bool {
+	return o.MaxAttempts == 0 && o.FirstRetryInterval == 0 && o.MaxRetryInterval == 0 && o.BackoffCoefficient == 0 &&
+		o.RetryTimeout == 0 && len(o.NonRetryableErrorTypes) == 0
+}
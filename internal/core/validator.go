@@ -0,0 +1,12 @@
+package core
+
+// Validator validates the inputs and results of a workflow or activity
+type Validator interface {
+	// ValidateInputs is called with the decoded inputs, not including the context, before the workflow or
+	// activity is executed
+	ValidateInputs(inputs []interface{}) error
+
+	// ValidateResults is called with the results, not including the error, after the workflow or activity
+	// succeeded
+	ValidateResults(results []interface{}) error
+}
@@ -0,0 +1,28 @@
+package core
+
+import "time"
+
+// WorkflowRegistrationOptions are the options a workflow has been registered with
+type WorkflowRegistrationOptions struct {
+	// TaskTimeout limits how long a single workflow task of the workflow may take, including replaying its history
+	TaskTimeout time.Duration
+
+	// Validator validates the inputs and the result of the workflow, if set
+	Validator Validator
+}
+
+type WorkflowRegistrationOption func(*WorkflowRegistrationOptions)
+
+// ParentClosePolicy determines what happens to a running sub-workflow when its parent finishes
+type ParentClosePolicy int
+
+const (
+	// ParentClosePolicyAbandon leaves the sub-workflow running
+	ParentClosePolicyAbandon ParentClosePolicy = iota
+
+	// ParentClosePolicyRequestCancel cancels the sub-workflow, which can still clean up before it finishes
+	ParentClosePolicyRequestCancel
+
+	// ParentClosePolicyTerminate terminates the sub-workflow right away
+	ParentClosePolicyTerminate
+)
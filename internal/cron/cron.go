@@ -0,0 +1,244 @@
+// Package cron parses cron expressions and computes when they fire next.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar are set when the day of month or day of week field is *. If both fields are
+	// restricted, a day matches if it matches either of them.
+	domStar, dowStar bool
+
+	// every is the interval of an @every schedule
+	every time.Duration
+}
+
+// maxSearch limits how far Next looks ahead, schedules like "0 0 30 2 *" never fire
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Sunday is 0 or 7
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Parse parses a cron expression with the five fields minute, hour, day of month, month, and day of week.
+// Fields can be *, a value, a range like 1-5, a step like */15 or 0-30/10, or a comma separated list of them.
+// Months and days of week can be given by their three letter English names. The descriptors @yearly,
+// @annually, @monthly, @weekly, @daily, @midnight, and @hourly are supported, as well as @every <duration>
+// for a fixed interval, e.g., @every 90m.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron schedule %q: %w", spec, err)
+		}
+
+		if d < time.Second {
+			return nil, fmt.Errorf("invalid cron schedule %q: interval must be at least one second", spec)
+		}
+
+		return &Schedule{every: d}, nil
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		expr, ok := descriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("invalid cron schedule %q: unknown descriptor", spec)
+		}
+
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	s := &Schedule{
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}
+
+	for i, f := range []struct {
+		field field
+		bits  *uint64
+	}{
+		{minuteField, &s.minute},
+		{hourField, &s.hour},
+		{domField, &s.dom},
+		{monthField, &s.month},
+		{dowField, &s.dow},
+	} {
+		bits, err := parseField(fields[i], f.field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron schedule %q: %w", spec, err)
+		}
+
+		*f.bits = bits
+	}
+
+	// Sunday can be given as 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	if s.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, fmt.Errorf("invalid cron schedule %q: schedule never fires", spec)
+	}
+
+	return s, nil
+}
+
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepExpr)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepExpr, f.name)
+			}
+		}
+
+		var from, to int
+		switch {
+		case rangeExpr == "*":
+			from, to = f.min, f.max
+
+		case strings.Contains(rangeExpr, "-"):
+			fromExpr, toExpr, _ := strings.Cut(rangeExpr, "-")
+
+			var err error
+			if from, err = f.value(fromExpr); err != nil {
+				return 0, err
+			}
+
+			if to, err = f.value(toExpr); err != nil {
+				return 0, err
+			}
+
+			if from > to {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangeExpr, f.name)
+			}
+
+		default:
+			var err error
+			if from, err = f.value(rangeExpr); err != nil {
+				return 0, err
+			}
+
+			to = from
+			if hasStep {
+				// 5/15 is short for 5-max/15
+				to = f.max
+			}
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func (f field) value(expr string) (int, error) {
+	if v, ok := f.names[strings.ToLower(expr)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(expr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", expr, f.name)
+	}
+
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d in %s field", v, f.min, f.max, f.name)
+	}
+
+	return v, nil
+}
+
+// Next returns the first time after t the schedule fires, in UTC. Returns the zero time if the schedule does
+// not fire within the next five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.UTC()
+
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	// Cron expressions have a resolution of one minute
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.Add(maxSearch)
+
+	for t.Before(end) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
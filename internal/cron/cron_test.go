@@ -0,0 +1,73 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Next(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2024, 1, 10, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 10, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 10, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17 * * *", time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC)},
+		{"5,10 8 * * *", time.Date(2024, 1, 11, 8, 5, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * mon", time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 1, 14, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Day of month or day of week, if both are restricted
+		{"0 0 20 * fri", time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{"30/10 * * * *", time.Date(2024, 1, 10, 10, 40, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", time.Date(2024, 1, 10, 12, 0, 15, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			require.NoError(t, err)
+			require.Equal(t, tt.next, s.Next(now))
+		})
+	}
+}
+
+func Test_Next_ConvertsToUTC(t *testing.T) {
+	s, err := Parse("0 12 * * *")
+	require.NoError(t, err)
+
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	require.Equal(t, time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC), s.Next(time.Date(2024, 1, 10, 13, 0, 0, 0, loc)))
+}
+
+func Test_Parse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"0 0 30 2 *",
+		"@weird",
+		"@every 1ms",
+		"@every soon",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			_, err := Parse(spec)
+			require.Error(t, err)
+		})
+	}
+}
@@ -6,7 +6,13 @@ import (
 	"strings"
 )
 
+// Name returns the name of the given function. Strings are returned as they are, so workflows and activities can
+// be referred to by name.
 func Name(i interface{}) string {
+	if name, ok := i.(string); ok {
+		return name
+	}
+
 	// Adapted from https://stackoverflow.com/a/7053871
 	fnName := runtime.FuncForPC(reflect.ValueOf(i).Pointer()).Name()
 
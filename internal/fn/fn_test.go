@@ -44,6 +44,11 @@ func Test_GetFunctionName(t *testing.T) {
 			i:    f.DoSomething,
 			want: "DoSomething",
 		},
+		{
+			name: "name",
+			i:    "Workflow1",
+			want: "Workflow1",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
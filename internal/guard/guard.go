@@ -0,0 +1,108 @@
+package guard
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+)
+
+// Mode controls how calls that break determinism are handled when they are made from workflow code
+type Mode int
+
+const (
+	// ModeOff disables the runtime determinism checks
+	ModeOff Mode = iota
+
+	// ModeWarn logs a warning and records a metric for every violation
+	ModeWarn
+
+	// ModeStrict panics on every violation, which fails the workflow task
+	ModeStrict
+)
+
+// Guard checks calls made from the goroutines executing the code of a workflow instance
+type Guard struct {
+	Mode Mode
+
+	Logger  log.Logger
+	Metrics metrics.Client
+
+	// Workflow is the name of the guarded workflow, used for reporting
+	Workflow string
+}
+
+// Violation reports a call that breaks determinism. In strict mode it panics with the given message.
+func (g *Guard) Violation(msg string) {
+	switch g.Mode {
+	case ModeWarn:
+		g.Warn(msg)
+
+	case ModeStrict:
+		panic(fmt.Sprintf("non-deterministic call from workflow code: %s", msg))
+	}
+}
+
+// Warn reports a violation without panicking, regardless of the mode
+func (g *Guard) Warn(msg string) {
+	if g.Logger != nil {
+		g.Logger.Warn("Non-deterministic call from workflow code", "workflow", g.Workflow, "violation", msg)
+	}
+
+	if g.Metrics != nil {
+		g.Metrics.Counter(metrics.DeterminismViolations, map[string]string{"workflow": g.Workflow}, 1)
+	}
+}
+
+var goroutines sync.Map // goroutine ID -> *Guard
+
+// Enter guards the current goroutine until the returned func is called
+func Enter(g *Guard) (exit func()) {
+	id := GoroutineID()
+	goroutines.Store(id, g)
+
+	return func() {
+		goroutines.Delete(id)
+	}
+}
+
+// Current returns the guard of the current goroutine, if it executes workflow code
+func Current() (*Guard, bool) {
+	g, ok := goroutines.Load(GoroutineID())
+	if !ok {
+		return nil, false
+	}
+
+	return g.(*Guard), true
+}
+
+// Check reports a violation if called from workflow code. call is the forbidden call, alternative the
+// deterministic replacement to suggest.
+func Check(call, alternative string) {
+	g, ok := Current()
+	if !ok || g.Mode == ModeOff {
+		return
+	}
+
+	g.Violation(fmt.Sprintf("%s must not be called from workflow code, use %s instead", call, alternative))
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// GoroutineID returns the ID of the current goroutine, parsed from its stack trace
+func GoroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
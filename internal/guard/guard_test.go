@@ -0,0 +1,47 @@
+package guard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Check_OutsideWorkflow(t *testing.T) {
+	require.NotPanics(t, func() {
+		Check("time.Now", "workflow.Now")
+	})
+}
+
+func Test_Check_Strict(t *testing.T) {
+	done := make(chan interface{})
+
+	go func() {
+		defer func() {
+			done <- recover()
+		}()
+
+		exit := Enter(&Guard{Mode: ModeStrict})
+		defer exit()
+
+		Check("time.Now", "workflow.Now")
+	}()
+
+	require.Equal(t, "non-deterministic call from workflow code: time.Now must not be called from workflow code, use workflow.Now instead", <-done)
+
+	// Guard is removed when the goroutine exits the guarded section
+	_, ok := Current()
+	require.False(t, ok)
+}
+
+func Test_GoroutineID(t *testing.T) {
+	id := GoroutineID()
+	require.NotZero(t, id)
+	require.Equal(t, id, GoroutineID())
+
+	other := make(chan uint64)
+	go func() {
+		other <- GoroutineID()
+	}()
+
+	require.NotEqual(t, id, <-other)
+}
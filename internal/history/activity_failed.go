@@ -2,4 +2,6 @@ package history
 
 type ActivityFailedAttributes struct {
 	Reason string `json:"reason,omitempty"`
+
+	Failure *Failure `json:"failure,omitempty"`
 }
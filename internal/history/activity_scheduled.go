@@ -1,9 +1,33 @@
 package history
 
-import "github.com/cschleiden/go-workflows/internal/payload"
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
 
 type ActivityScheduledAttributes struct {
 	Name string `json:"name,omitempty"`
 
 	Inputs []payload.Payload `json:"inputs,omitempty"`
+
+	// MemoizeFor is the duration for which the result of the activity is cached. 0 disables caching.
+	MemoizeFor time.Duration `json:"memoize_for,omitempty"`
+
+	// StartToCloseTimeout limits how long a single execution of the activity may take. 0 uses the timeout
+	// the activity has been registered with, if any.
+	StartToCloseTimeout time.Duration `json:"start_to_close_timeout,omitempty"`
+
+	// HeartbeatTimeout fails an execution of the activity if it does not record a heartbeat within the timeout.
+	// 0 uses the timeout the activity has been registered with, if any.
+	HeartbeatTimeout time.Duration `json:"heartbeat_timeout,omitempty"`
+
+	// Queue is the activity queue the activity is scheduled on. Empty for the default queue.
+	Queue string `json:"queue,omitempty"`
+
+	// Attempt is the attempt number, starting at 1, if the activity is retried by the workflow. 0 if unknown.
+	Attempt int `json:"attempt,omitempty"`
+
+	// TraceContext is the trace context of the workflow instance scheduling the activity, see trace.Propagator
+	TraceContext map[string]string `json:"trace_context,omitempty"`
 }
@@ -0,0 +1,54 @@
+package history
+
+// SupersededEvents returns the events of the given history that can be removed without changing the
+// outcome of replaying it:
+//
+//   - TimerFired events for timers that were canceled before. Replaying them has no effect.
+//   - WorkflowTaskStarted events directly followed by another WorkflowTaskStarted event. They only set the
+//     workflow time, which is overwritten before any workflow code runs.
+//
+// The events need to be ordered by sequence ID. The last event of the history is never returned, since it
+// determines the sequence ID of the history.
+func SupersededEvents(events []Event) []Event {
+	superseded := make([]Event, 0)
+	removed := make(map[string]bool)
+	canceledTimers := make(map[int64]bool)
+
+	for i, event := range events {
+		if i == len(events)-1 {
+			break
+		}
+
+		switch event.Type {
+		case EventType_TimerCanceled:
+			canceledTimers[event.ScheduleEventID] = true
+
+		case EventType_TimerFired:
+			if canceledTimers[event.ScheduleEventID] {
+				superseded = append(superseded, event)
+				removed[event.ID] = true
+			}
+		}
+	}
+
+	// Look for consecutive task started events after removing fired timers
+	var lastTaskStarted *Event
+	for i := range events {
+		event := events[i]
+		if removed[event.ID] {
+			continue
+		}
+
+		if event.Type == EventType_WorkflowTaskStarted {
+			if lastTaskStarted != nil {
+				superseded = append(superseded, *lastTaskStarted)
+			}
+
+			lastTaskStarted = &events[i]
+		} else {
+			lastTaskStarted = nil
+		}
+	}
+
+	return superseded
+}
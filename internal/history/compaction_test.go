@@ -0,0 +1,42 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupersededEvents(t *testing.T) {
+	now := time.Now()
+
+	events := []Event{
+		NewHistoryEvent(1, now, EventType_WorkflowTaskStarted, &WorkflowTaskStartedAttributes{}),
+		NewHistoryEvent(2, now, EventType_WorkflowExecutionStarted, &ExecutionStartedAttributes{}),
+		NewHistoryEvent(3, now, EventType_TimerScheduled, &TimerScheduledAttributes{}, ScheduleEventID(1)),
+		NewHistoryEvent(4, now, EventType_WorkflowTaskStarted, &WorkflowTaskStartedAttributes{}),
+		NewHistoryEvent(5, now, EventType_SignalReceived, &SignalReceivedAttributes{}),
+		NewHistoryEvent(6, now, EventType_TimerCanceled, &TimerCanceledAttributes{}, ScheduleEventID(1)),
+		NewHistoryEvent(7, now, EventType_WorkflowTaskStarted, &WorkflowTaskStartedAttributes{}),
+		NewHistoryEvent(8, now, EventType_TimerFired, &TimerFiredAttributes{}, ScheduleEventID(1)),
+		NewHistoryEvent(9, now, EventType_WorkflowTaskStarted, &WorkflowTaskStartedAttributes{}),
+		NewHistoryEvent(10, now, EventType_SignalReceived, &SignalReceivedAttributes{}),
+	}
+
+	superseded := SupersededEvents(events)
+	require.Len(t, superseded, 2)
+	require.Equal(t, int64(8), superseded[0].SequenceID)
+	require.Equal(t, int64(7), superseded[1].SequenceID)
+}
+
+func TestSupersededEvents_KeepsLastEvent(t *testing.T) {
+	now := time.Now()
+
+	events := []Event{
+		NewHistoryEvent(1, now, EventType_TimerCanceled, &TimerCanceledAttributes{}, ScheduleEventID(1)),
+		NewHistoryEvent(2, now, EventType_WorkflowTaskStarted, &WorkflowTaskStartedAttributes{}),
+		NewHistoryEvent(3, now, EventType_TimerFired, &TimerFiredAttributes{}, ScheduleEventID(1)),
+	}
+
+	require.Empty(t, SupersededEvents(events))
+}
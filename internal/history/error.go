@@ -0,0 +1,113 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// ApplicationError is an error with an application defined type and optional details. When an activity or
+// workflow fails with it, it's recorded with its type and details, and restored for the code handling the failure.
+type ApplicationError struct {
+	// Type identifies the kind of error, e.g., "InsufficientFunds"
+	Type string
+
+	Message string
+
+	// StackTrace is the stack of the code that created the error
+	StackTrace string
+
+	// details holds the details before the error is recorded
+	details []interface{}
+
+	// encodedDetails holds the details of a restored error, they are decoded with converter
+	encodedDetails []payload.Payload
+	converter      converter.Converter
+}
+
+var _ error = (*ApplicationError)(nil)
+
+// NewApplicationError creates an ApplicationError, recording the stack of the caller skip frames up
+func NewApplicationError(skip int, errType, message string, details ...interface{}) *ApplicationError {
+	return &ApplicationError{
+		Type:       errType,
+		Message:    message,
+		StackTrace: stackTrace(skip + 1),
+		details:    details,
+	}
+}
+
+func (e *ApplicationError) Error() string {
+	return e.Message
+}
+
+// Details decodes the details of the error into the given pointers, in the order they were passed when creating
+// the error
+func (e *ApplicationError) Details(vptrs ...interface{}) error {
+	if e.details != nil {
+		// Not recorded yet, round-trip the values to get the same behavior as for restored errors
+		p, err := encodeDetails(converter.DefaultConverter, e.details)
+		if err != nil {
+			return err
+		}
+
+		return decodeDetails(converter.DefaultConverter, p, vptrs)
+	}
+
+	c := e.converter
+	if c == nil {
+		c = converter.DefaultConverter
+	}
+
+	return decodeDetails(c, e.encodedDetails, vptrs)
+}
+
+func encodeDetails(c converter.Converter, details []interface{}) ([]payload.Payload, error) {
+	payloads := make([]payload.Payload, 0, len(details))
+	for _, d := range details {
+		p, err := c.To(d)
+		if err != nil {
+			return nil, fmt.Errorf("encoding error details: %w", err)
+		}
+
+		payloads = append(payloads, p)
+	}
+
+	return payloads, nil
+}
+
+func decodeDetails(c converter.Converter, payloads []payload.Payload, vptrs []interface{}) error {
+	if len(vptrs) > len(payloads) {
+		return errors.New("error has fewer details than requested")
+	}
+
+	for i, vptr := range vptrs {
+		if err := converter.Decode(c, payloads[i], vptr); err != nil {
+			return fmt.Errorf("decoding error details: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func stackTrace(skip int) string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var sb strings.Builder
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+
+		if !more {
+			break
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,144 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// Failure is the structured representation of an error that caused an activity, sub-workflow, or
+// workflow to fail. Wrapped errors are preserved as a chain of causes.
+type Failure struct {
+	// Message is the full error message, including the messages of all causes
+	Message string `json:"message,omitempty"`
+
+	// Type is the Go type of the original error, or the type given to an application error
+	Type string `json:"type,omitempty"`
+
+	// Application is true if the original error was an application error, see workflow.Error
+	Application bool `json:"application,omitempty"`
+
+	// Details are the encoded details of an application error
+	Details []payload.Payload `json:"details,omitempty"`
+
+	// StackTrace is the stack of the code that created an application error
+	StackTrace string `json:"stack_trace,omitempty"`
+
+	// ActivityName is the name of the activity the failure originated from, if any
+	ActivityName string `json:"activity_name,omitempty"`
+
+	// Attempt is the number of attempts made before giving up, if the failed operation was retried
+	Attempt int `json:"attempt,omitempty"`
+
+	// FirstAttemptAt is the time of the first attempt, if the failed operation was retried
+	FirstAttemptAt *time.Time `json:"first_attempt_at,omitempty"`
+
+	// Timestamp is the time the failure occurred
+	Timestamp time.Time `json:"timestamp,omitempty"`
+
+	Cause *Failure `json:"cause,omitempty"`
+
+	// converter decodes the details of a restored application error
+	converter converter.Converter
+}
+
+var _ error = (*Failure)(nil)
+
+func (f *Failure) Error() string {
+	return f.Message
+}
+
+func (f *Failure) Unwrap() error {
+	if f.Cause == nil {
+		return nil
+	}
+
+	return f.Cause
+}
+
+// As restores the application error a failure was recorded for, so code handling the failure can use errors.As
+// with a *workflow.Error target
+func (f *Failure) As(target interface{}) bool {
+	t, ok := target.(**ApplicationError)
+	if !ok || !f.Application {
+		return false
+	}
+
+	*t = &ApplicationError{
+		Type:           f.Type,
+		Message:        f.Message,
+		StackTrace:     f.StackTrace,
+		encodedDetails: f.Details,
+		converter:      f.converter,
+	}
+
+	return true
+}
+
+// FailureWithConverter returns a copy of the given failure, which decodes the details of application errors with
+// the given converter
+func FailureWithConverter(f *Failure, c converter.Converter) *Failure {
+	if f == nil {
+		return nil
+	}
+
+	r := *f
+	r.converter = c
+	r.Cause = FailureWithConverter(f.Cause, c)
+
+	return &r
+}
+
+// NewFailure converts the given error into a Failure. If err is or wraps a Failure, the existing
+// metadata is preserved. The details of application errors are encoded with the given converter.
+func NewFailure(c converter.Converter, err error, timestamp time.Time) *Failure {
+	if err == nil {
+		return nil
+	}
+
+	if f, ok := err.(*Failure); ok {
+		r := *f
+		if r.Timestamp.IsZero() {
+			r.Timestamp = timestamp
+		}
+
+		return &r
+	}
+
+	if ae, ok := err.(*ApplicationError); ok {
+		return applicationFailure(c, ae, timestamp)
+	}
+
+	return &Failure{
+		Message:   err.Error(),
+		Type:      fmt.Sprintf("%T", err),
+		Timestamp: timestamp,
+		Cause:     NewFailure(c, errors.Unwrap(err), timestamp),
+	}
+}
+
+func applicationFailure(c converter.Converter, ae *ApplicationError, timestamp time.Time) *Failure {
+	f := &Failure{
+		Message:     ae.Message,
+		Type:        ae.Type,
+		Application: true,
+		Details:     ae.encodedDetails,
+		StackTrace:  ae.StackTrace,
+		Timestamp:   timestamp,
+	}
+
+	if ae.details != nil {
+		details, err := encodeDetails(c, ae.details)
+		if err != nil {
+			// Don't lose the error itself because of its details
+			f.Message = fmt.Sprintf("%v (%v)", f.Message, err)
+		} else {
+			f.Details = details
+		}
+	}
+
+	return f
+}
@@ -0,0 +1,59 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFailure(t *testing.T) {
+	now := time.Now()
+
+	inner := &Failure{Message: "activity failed", ActivityName: "Activity1", Timestamp: now.Add(-time.Second)}
+	err := fmt.Errorf("executing activity: %w", inner)
+
+	f := NewFailure(converter.DefaultConverter, err, now)
+	require.Equal(t, "executing activity: activity failed", f.Message)
+	require.Equal(t, "*fmt.wrapError", f.Type)
+	require.Equal(t, now, f.Timestamp)
+
+	require.NotNil(t, f.Cause)
+	require.Equal(t, "Activity1", f.Cause.ActivityName)
+	require.Equal(t, now.Add(-time.Second), f.Cause.Timestamp)
+
+	var target *Failure
+	require.True(t, errors.As(f.Unwrap(), &target))
+	require.Equal(t, "activity failed", target.Message)
+}
+
+func TestNewFailure_Nil(t *testing.T) {
+	require.Nil(t, NewFailure(converter.DefaultConverter, nil, time.Now()))
+}
+
+func TestNewFailure_ApplicationError(t *testing.T) {
+	err := fmt.Errorf("charging card: %w", NewApplicationError(0, "InsufficientFunds", "insufficient funds", 12))
+
+	f := NewFailure(converter.DefaultConverter, err, time.Now())
+	require.NotNil(t, f.Cause)
+	require.True(t, f.Cause.Application)
+	require.Equal(t, "InsufficientFunds", f.Cause.Type)
+	require.Len(t, f.Cause.Details, 1)
+	require.Contains(t, f.Cause.StackTrace, "TestNewFailure_ApplicationError")
+
+	var ae *ApplicationError
+	require.True(t, errors.As(FailureWithConverter(f, converter.DefaultConverter), &ae))
+	require.Equal(t, "InsufficientFunds", ae.Type)
+	require.Equal(t, "insufficient funds", ae.Error())
+
+	var available int
+	require.NoError(t, ae.Details(&available))
+	require.Equal(t, 12, available)
+
+	// Recording a restored error keeps its details
+	f = NewFailure(converter.DefaultConverter, ae, time.Now())
+	require.Len(t, f.Details, 1)
+}
@@ -35,6 +35,16 @@ const (
 	EventType_SignalReceived
 
 	EventType_SideEffectResult
+
+	EventType_HistoryLimitWarning
+
+	EventType_WorkflowExecutionForceCompleted
+
+	EventType_WorkflowTagsAdded
+
+	EventType_MarkerRecorded
+
+	EventType_WorkflowExecutionContinuedAsNew
 )
 
 func (et EventType) String() string {
@@ -79,6 +89,22 @@ func (et EventType) String() string {
 
 	case EventType_SideEffectResult:
 		return "SideEffectResult"
+
+	case EventType_HistoryLimitWarning:
+		return "HistoryLimitWarning"
+
+	case EventType_WorkflowExecutionForceCompleted:
+		return "WorkflowExecutionForceCompleted"
+
+	case EventType_WorkflowTagsAdded:
+		return "WorkflowTagsAdded"
+
+	case EventType_MarkerRecorded:
+		return "MarkerRecorded"
+
+	case EventType_WorkflowExecutionContinuedAsNew:
+		return "WorkflowExecutionContinuedAsNew"
+
 	default:
 		return "Unknown"
 	}
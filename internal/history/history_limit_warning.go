@@ -0,0 +1,11 @@
+package history
+
+// HistoryLimitWarningAttributes are recorded once the history of a workflow instance grows beyond the
+// configured warning threshold
+type HistoryLimitWarningAttributes struct {
+	// Length is the number of events in the history when the warning was recorded
+	Length int64 `json:"length,omitempty"`
+
+	// Size is the size of the history in bytes when the warning was recorded
+	Size int64 `json:"size,omitempty"`
+}
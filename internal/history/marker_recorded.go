@@ -0,0 +1,10 @@
+package history
+
+import "github.com/cschleiden/go-workflows/internal/payload"
+
+// MarkerRecordedAttributes are recorded when workflow code records a marker. Markers do not affect the
+// execution of the workflow, they only annotate its history.
+type MarkerRecordedAttributes struct {
+	Name string          `json:"name,omitempty"`
+	Data payload.Payload `json:"data,omitempty"`
+}
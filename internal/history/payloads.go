@@ -0,0 +1,81 @@
+package history
+
+import (
+	"bytes"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// RewritePayloads replaces every payload in the given event attributes with the payload returned by rewrite,
+// and returns the number of payloads that changed
+func RewritePayloads(attributes interface{}, rewrite func(payload.Payload) (payload.Payload, error)) (int, error) {
+	var payloads []*payload.Payload
+
+	switch a := attributes.(type) {
+	case *ExecutionStartedAttributes:
+		payloads = inputPayloads(a.Inputs)
+	case *ExecutionCompletedAttributes:
+		payloads = append([]*payload.Payload{&a.Result}, failurePayloads(a.Failure)...)
+	case *ExecutionContinuedAsNewAttributes:
+		payloads = inputPayloads(a.Inputs)
+	case *ExecutionForceCompletedAttributes:
+		payloads = []*payload.Payload{&a.Result}
+	case *ActivityScheduledAttributes:
+		payloads = inputPayloads(a.Inputs)
+	case *ActivityCompletedAttributes:
+		payloads = []*payload.Payload{&a.Result}
+	case *ActivityFailedAttributes:
+		payloads = failurePayloads(a.Failure)
+	case *SubWorkflowScheduledAttributes:
+		payloads = inputPayloads(a.Inputs)
+	case *SubWorkflowCompletedAttributes:
+		payloads = []*payload.Payload{&a.Result}
+	case *SubWorkflowFailedAttributes:
+		payloads = failurePayloads(a.Failure)
+	case *SignalReceivedAttributes:
+		payloads = []*payload.Payload{&a.Arg}
+	case *SideEffectResultAttributes:
+		payloads = []*payload.Payload{&a.Result}
+	case *MarkerRecordedAttributes:
+		payloads = []*payload.Payload{&a.Data}
+	}
+
+	changed := 0
+
+	for _, p := range payloads {
+		if len(*p) == 0 {
+			continue
+		}
+
+		r, err := rewrite(*p)
+		if err != nil {
+			return changed, err
+		}
+
+		if !bytes.Equal(r, *p) {
+			*p = r
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+func inputPayloads(inputs []payload.Payload) []*payload.Payload {
+	r := make([]*payload.Payload, len(inputs))
+	for i := range inputs {
+		r[i] = &inputs[i]
+	}
+
+	return r
+}
+
+// failurePayloads returns the details of the given failure and of all its causes
+func failurePayloads(f *Failure) []*payload.Payload {
+	var r []*payload.Payload
+	for ; f != nil; f = f.Cause {
+		r = append(r, inputPayloads(f.Details)...)
+	}
+
+	return r
+}
@@ -0,0 +1,37 @@
+package history
+
+import (
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// RedactEvents returns copies of the given events, with every payload in their attributes replaced by the
+// payload returned by redact. The given events are not modified.
+func RedactEvents(events []Event, redact func(payload.Payload) payload.Payload) []Event {
+	r := make([]Event, len(events))
+	for i, e := range events {
+		e.Attributes = RedactAttributes(e.Type, e.Attributes, redact)
+		r[i] = e
+	}
+
+	return r
+}
+
+// RedactAttributes returns a copy of the given event attributes, with every payload replaced by the payload
+// returned by redact. If the attributes cannot be copied, nil is returned, so no payload is shown unredacted.
+func RedactAttributes(eventType EventType, attributes interface{}, redact func(payload.Payload) payload.Payload) interface{} {
+	data, err := SerializeAttributes(attributes)
+	if err != nil {
+		return nil
+	}
+
+	a, err := DeserializeAttributes(eventType, data)
+	if err != nil {
+		return nil
+	}
+
+	RewritePayloads(a, func(p payload.Payload) (payload.Payload, error) {
+		return redact(p), nil
+	})
+
+	return a
+}
@@ -0,0 +1,81 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactEvents(t *testing.T) {
+	events := []Event{
+		NewHistoryEvent(1, time.Now(), EventType_ActivityScheduled, &ActivityScheduledAttributes{
+			Name:   "a",
+			Inputs: []payload.Payload{[]byte(`"secret"`)},
+		}),
+		NewHistoryEvent(2, time.Now(), EventType_TimerScheduled, &TimerScheduledAttributes{}),
+		NewHistoryEvent(3, time.Now(), EventType_WorkflowExecutionContinuedAsNew, &ExecutionContinuedAsNewAttributes{
+			Inputs: []payload.Payload{[]byte(`"next"`)},
+		}),
+	}
+
+	redacted := RedactEvents(events, func(p payload.Payload) payload.Payload {
+		return []byte(`"x"`)
+	})
+
+	require.Len(t, redacted, 3)
+
+	a := redacted[0].Attributes.(*ActivityScheduledAttributes)
+	require.Equal(t, "a", a.Name)
+	require.Equal(t, []payload.Payload{[]byte(`"x"`)}, a.Inputs)
+
+	// The original events are not modified
+	require.Equal(t, []payload.Payload{[]byte(`"secret"`)}, events[0].Attributes.(*ActivityScheduledAttributes).Inputs)
+	require.IsType(t, &TimerScheduledAttributes{}, redacted[1].Attributes)
+	require.Equal(t, []payload.Payload{[]byte(`"x"`)}, redacted[2].Attributes.(*ExecutionContinuedAsNewAttributes).Inputs)
+}
+
+func TestRedactEvents_FailureDetails(t *testing.T) {
+	failure := &Failure{
+		Message:     "failed",
+		Application: true,
+		Details:     []payload.Payload{[]byte(`"secret"`)},
+		Cause: &Failure{
+			Message:     "cause",
+			Application: true,
+			Details:     []payload.Payload{[]byte(`"secret cause"`)},
+		},
+	}
+
+	events := []Event{
+		NewHistoryEvent(1, time.Now(), EventType_ActivityFailed, &ActivityFailedAttributes{Failure: failure}),
+		NewHistoryEvent(2, time.Now(), EventType_SubWorkflowFailed, &SubWorkflowFailedAttributes{Failure: failure}),
+		NewHistoryEvent(3, time.Now(), EventType_WorkflowExecutionFinished, &ExecutionCompletedAttributes{
+			Result:  []byte(`"result"`),
+			Failure: failure,
+		}),
+	}
+
+	redacted := RedactEvents(events, func(p payload.Payload) payload.Payload {
+		return []byte(`"x"`)
+	})
+
+	failures := []*Failure{
+		redacted[0].Attributes.(*ActivityFailedAttributes).Failure,
+		redacted[1].Attributes.(*SubWorkflowFailedAttributes).Failure,
+		redacted[2].Attributes.(*ExecutionCompletedAttributes).Failure,
+	}
+
+	for _, f := range failures {
+		require.Equal(t, "failed", f.Message)
+		require.Equal(t, []payload.Payload{[]byte(`"x"`)}, f.Details)
+		require.Equal(t, []payload.Payload{[]byte(`"x"`)}, f.Cause.Details)
+	}
+
+	require.Equal(t, payload.Payload(`"x"`), redacted[2].Attributes.(*ExecutionCompletedAttributes).Result)
+
+	// The original events are not modified
+	require.Equal(t, []payload.Payload{[]byte(`"secret"`)}, failure.Details)
+	require.Equal(t, []payload.Payload{[]byte(`"secret cause"`)}, failure.Cause.Details)
+}
@@ -32,6 +32,17 @@ func SerializeAttributes(attributes interface{}) ([]byte, error) {
 	return json.Marshal(attributes)
 }
 
+// AttributesSize returns the size of the serialized attributes of the event, which is what backends store
+// for every event in the history
+func AttributesSize(e Event) int64 {
+	a, err := SerializeAttributes(e.Attributes)
+	if err != nil {
+		return 0
+	}
+
+	return int64(len(a))
+}
+
 func DeserializeAttributes(eventType EventType, attributes []byte) (attr interface{}, err error) {
 	switch eventType {
 	case EventType_WorkflowExecutionStarted:
@@ -40,6 +51,12 @@ func DeserializeAttributes(eventType EventType, attributes []byte) (attr interfa
 		attr = &ExecutionCompletedAttributes{}
 	case EventType_WorkflowExecutionCanceled:
 		attr = &ExecutionCanceledAttributes{}
+	case EventType_WorkflowExecutionForceCompleted:
+		attr = &ExecutionForceCompletedAttributes{}
+	case EventType_WorkflowExecutionTerminated:
+		attr = &ExecutionTerminatedAttributes{}
+	case EventType_WorkflowExecutionContinuedAsNew:
+		attr = &ExecutionContinuedAsNewAttributes{}
 
 	case EventType_WorkflowTaskStarted:
 		attr = &WorkflowTaskStartedAttributes{}
@@ -57,6 +74,15 @@ func DeserializeAttributes(eventType EventType, attributes []byte) (attr interfa
 	case EventType_SideEffectResult:
 		attr = &SideEffectResultAttributes{}
 
+	case EventType_HistoryLimitWarning:
+		attr = &HistoryLimitWarningAttributes{}
+
+	case EventType_WorkflowTagsAdded:
+		attr = &WorkflowTagsAddedAttributes{}
+
+	case EventType_MarkerRecorded:
+		attr = &MarkerRecordedAttributes{}
+
 	case EventType_TimerScheduled:
 		attr = &TimerScheduledAttributes{}
 	case EventType_TimerFired:
@@ -2,4 +2,6 @@ package history
 
 type SubWorkflowFailedAttributes struct {
 	Error string `json:"error,omitempty"`
+
+	Failure *Failure `json:"failure,omitempty"`
 }
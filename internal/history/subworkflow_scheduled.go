@@ -11,4 +11,7 @@ type SubWorkflowScheduledAttributes struct {
 	Name string `json:"name,omitempty"`
 
 	Inputs []payload.Payload `json:"inputs,omitempty"`
+
+	// ParentClosePolicy determines what happens to the sub-workflow if it's still running when the parent finishes
+	ParentClosePolicy core.ParentClosePolicy `json:"parent_close_policy,omitempty"`
 }
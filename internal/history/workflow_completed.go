@@ -5,4 +5,7 @@ import "github.com/cschleiden/go-workflows/internal/payload"
 type ExecutionCompletedAttributes struct {
 	Result payload.Payload `json:"result,omitempty"`
 	Error  string          `json:"error,omitempty"`
+
+	// Failure holds structured information about the error the workflow failed with
+	Failure *Failure `json:"failure,omitempty"`
 }
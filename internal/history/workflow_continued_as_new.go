@@ -0,0 +1,13 @@
+package history
+
+import "github.com/cschleiden/go-workflows/internal/payload"
+
+// ExecutionContinuedAsNewAttributes are recorded when a workflow execution has finished by continuing as a new
+// execution of the same instance, with an empty history
+type ExecutionContinuedAsNewAttributes struct {
+	// Inputs are the inputs the new execution is started with
+	Inputs []payload.Payload `json:"inputs,omitempty"`
+
+	// ExecutionID is the execution ID of the new execution
+	ExecutionID string `json:"execution_id,omitempty"`
+}
@@ -0,0 +1,13 @@
+package history
+
+import "github.com/cschleiden/go-workflows/internal/payload"
+
+// ExecutionForceCompletedAttributes are recorded when a workflow instance has been finished by an
+// administrator instead of by executing the workflow
+type ExecutionForceCompletedAttributes struct {
+	Result payload.Payload `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+
+	// Reason is the explanation given for force-completing the instance
+	Reason string `json:"reason,omitempty"`
+}
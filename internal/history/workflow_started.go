@@ -1,9 +1,53 @@
 package history
 
-import "github.com/cschleiden/go-workflows/internal/payload"
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
 
 type ExecutionStartedAttributes struct {
 	Name string `json:"name,omitempty"`
 
 	Inputs []payload.Payload `json:"inputs,omitempty"`
+
+	// Priority of the workflow instance, tasks of instances with a higher priority are dispatched first
+	Priority int `json:"priority,omitempty"`
+
+	// Queue is the workflow queue the tasks of the workflow instance are placed on. Empty for the default queue.
+	Queue string `json:"queue,omitempty"`
+
+	// Tags of the workflow instance, backends implementing InstanceTagIndex allow looking up instances by tag
+	Tags []string `json:"tags,omitempty"`
+
+	// CancelAfter is the time after the start of the workflow instance after which it's canceled automatically.
+	// 0 disables the automatic cancellation.
+	CancelAfter time.Duration `json:"cancel_after,omitempty"`
+
+	// TraceContext is the trace context of the span that created the workflow instance, see trace.Propagator
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+}
+
+// WorkflowName returns the name of the workflow started by the given events, or an empty string if none of
+// the events starts a workflow execution
+func WorkflowName(events []Event) string {
+	for _, e := range events {
+		if a, ok := e.Attributes.(*ExecutionStartedAttributes); ok {
+			return a.Name
+		}
+	}
+
+	return ""
+}
+
+// WorkflowQueue returns the workflow queue of the execution started by the given events, or the default queue
+// if none of the events starts a workflow execution
+func WorkflowQueue(events []Event) string {
+	for _, e := range events {
+		if a, ok := e.Attributes.(*ExecutionStartedAttributes); ok {
+			return a.Queue
+		}
+	}
+
+	return ""
 }
@@ -0,0 +1,23 @@
+package history
+
+// WorkflowTagsAddedAttributes are recorded when workflow code adds tags to its instance
+type WorkflowTagsAddedAttributes struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
+// AddedTags returns the tags added by the given events, either when starting a workflow instance or from
+// workflow code
+func AddedTags(events []Event) []string {
+	var tags []string
+
+	for _, e := range events {
+		switch a := e.Attributes.(type) {
+		case *ExecutionStartedAttributes:
+			tags = append(tags, a.Tags...)
+		case *WorkflowTagsAddedAttributes:
+			tags = append(tags, a.Tags...)
+		}
+	}
+
+	return tags
+}
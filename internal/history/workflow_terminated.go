@@ -0,0 +1,8 @@
+package history
+
+// ExecutionTerminatedAttributes are recorded when a workflow instance has been stopped by an administrator
+// without executing the workflow any further
+type ExecutionTerminatedAttributes struct {
+	// Reason is the explanation given for terminating the instance
+	Reason string `json:"reason,omitempty"`
+}
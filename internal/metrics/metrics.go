@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"github.com/cschleiden/go-workflows/metrics"
+)
+
+type noopClient struct{}
+
+var _ metrics.Client = (*noopClient)(nil)
+
+// NewNoopMetricsClient returns a client that discards all metrics
+func NewNoopMetricsClient() metrics.Client {
+	return &noopClient{}
+}
+
+func (*noopClient) Counter(name string, tags map[string]string, value int64) {}
+
+func (*noopClient) Gauge(name string, tags map[string]string, value int64) {}
+
+func (*noopClient) Distribution(name string, tags map[string]string, value float64) {}
+
+func (c *noopClient) WithTags(tags map[string]string) metrics.Client {
+	return c
+}
@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Notifier wakes up goroutines waiting for new work, for example, backend pollers waiting for tasks
+type Notifier struct {
+	mu sync.Mutex
+	c  chan struct{}
+}
+
+func NewNotifier() *Notifier {
+	return &Notifier{
+		c: make(chan struct{}),
+	}
+}
+
+// Notify wakes up all current waiters
+func (n *Notifier) Notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	close(n.c)
+	n.c = make(chan struct{})
+}
+
+// C returns a channel that is closed on the next call to Notify
+func (n *Notifier) C() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.c
+}
+
+type PollOptions struct {
+	// Timeout is the maximum time to wait for a result. If 0, fn is called only once.
+	Timeout time.Duration
+
+	// MinInterval is the initial time to wait between attempts while no result is found
+	MinInterval time.Duration
+
+	// MaxInterval is the maximum time to wait between attempts. The interval doubles after every attempt
+	// without a result.
+	MaxInterval time.Duration
+}
+
+// Poll calls fn until it returns a result or an error, the timeout elapses, or the context is canceled. Between
+// attempts it waits for the given notifier or the current poll interval, whichever comes first. Returns nil if
+// no result was found in time.
+func Poll[T any](ctx context.Context, n *Notifier, options PollOptions, fn func(ctx context.Context) (*T, error)) (*T, error) {
+	var deadline <-chan time.Time
+	if options.Timeout > 0 {
+		timer := time.NewTimer(options.Timeout)
+		defer timer.Stop()
+
+		deadline = timer.C
+	}
+
+	interval := options.MinInterval
+
+	for {
+		// Get the channel before checking for work, to not miss notifications sent in-between
+		notified := n.C()
+
+		r, err := fn(ctx)
+		if err != nil || r != nil || options.Timeout <= 0 {
+			return r, err
+		}
+
+		wait := time.NewTimer(interval)
+
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return nil, nil
+
+		case <-deadline:
+			wait.Stop()
+			return nil, nil
+
+		case <-notified:
+			wait.Stop()
+			interval = options.MinInterval
+
+		case <-wait.C:
+			interval *= 2
+			if interval > options.MaxInterval {
+				interval = options.MaxInterval
+			}
+		}
+	}
+}
+
+// Wait calls done until it reports true or returns an error, or the context is canceled. Between attempts it waits
+// for the given notifier or the current poll interval like Poll, the timeout of the options is ignored. Returns
+// the error of the context if it's canceled first.
+func Wait(ctx context.Context, n *Notifier, options PollOptions, done func(ctx context.Context) (bool, error)) error {
+	interval := options.MinInterval
+
+	for {
+		// Get the channel before checking, to not miss notifications sent in-between
+		notified := n.C()
+
+		ok, err := done(ctx)
+		if err != nil || ok {
+			return err
+		}
+
+		wait := time.NewTimer(interval)
+
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return ctx.Err()
+
+		case <-notified:
+			wait.Stop()
+			interval = options.MinInterval
+
+		case <-wait.C:
+			interval *= 2
+			if interval > options.MaxInterval {
+				interval = options.MaxInterval
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Poll_ReturnsResultAfterNotify(t *testing.T) {
+	n := NewNotifier()
+
+	var ready int32
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		atomic.StoreInt32(&ready, 1)
+		n.Notify()
+	}()
+
+	start := time.Now()
+
+	r, err := Poll(context.Background(), n, PollOptions{Timeout: 5 * time.Second, MinInterval: time.Minute, MaxInterval: time.Minute},
+		func(ctx context.Context) (*int, error) {
+			if atomic.LoadInt32(&ready) == 0 {
+				return nil, nil
+			}
+
+			v := 42
+			return &v, nil
+		})
+
+	require.NoError(t, err)
+	require.Equal(t, 42, *r)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func Test_Poll_Timeout(t *testing.T) {
+	attempts := 0
+
+	r, err := Poll(context.Background(), NewNotifier(), PollOptions{Timeout: 20 * time.Millisecond, MinInterval: time.Millisecond, MaxInterval: 4 * time.Millisecond},
+		func(ctx context.Context) (*int, error) {
+			attempts++
+			return nil, nil
+		})
+
+	require.NoError(t, err)
+	require.Nil(t, r)
+	require.Greater(t, attempts, 1)
+}
+
+func Test_Poll_NoTimeout_CallsOnce(t *testing.T) {
+	attempts := 0
+
+	r, err := Poll(context.Background(), NewNotifier(), PollOptions{}, func(ctx context.Context) (*int, error) {
+		attempts++
+		return nil, nil
+	})
+
+	require.NoError(t, err)
+	require.Nil(t, r)
+	require.Equal(t, 1, attempts)
+}
+
+func Test_Wait_ReturnsAfterNotify(t *testing.T) {
+	n := NewNotifier()
+
+	var ready int32
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		atomic.StoreInt32(&ready, 1)
+		n.Notify()
+	}()
+
+	start := time.Now()
+
+	err := Wait(context.Background(), n, PollOptions{MinInterval: time.Minute, MaxInterval: time.Minute}, func(ctx context.Context) (bool, error) {
+		return atomic.LoadInt32(&ready) == 1, nil
+	})
+
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func Test_Wait_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := Wait(ctx, NewNotifier(), PollOptions{MinInterval: time.Millisecond, MaxInterval: 4 * time.Millisecond}, func(ctx context.Context) (bool, error) {
+		return false, nil
+	})
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
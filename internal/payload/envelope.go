@@ -0,0 +1,64 @@
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Metadata describes how the data of a payload is encoded
+type Metadata map[string]string
+
+const (
+	// MetadataEncoding is the encoding used by the converter, e.g. "json/plain"
+	MetadataEncoding = "encoding"
+
+	// MetadataContentType is the MIME type of the encoded data, e.g. "application/json"
+	MetadataContentType = "content-type"
+
+	// MetadataCompression is the compression applied to the data, e.g. "gzip"
+	MetadataCompression = "compression"
+
+	// MetadataEncryptionKeyID identifies the key the data has been encrypted with
+	MetadataEncryptionKeyID = "encryption-key-id"
+)
+
+// envelopePrefix marks payloads wrapped in an envelope. It's not valid at the start of JSON documents,
+// so it cannot be confused with payloads written without an envelope.
+var envelopePrefix = []byte("\x00gwe")
+
+type envelope struct {
+	Metadata Metadata `json:"metadata,omitempty"`
+	Data     []byte   `json:"data"`
+}
+
+// Wrap returns a payload containing the given data together with its metadata. The payload is the prefix
+// "\x00gwe" followed by a JSON object with "metadata" and base64 encoded "data" properties.
+func Wrap(data []byte, metadata Metadata) (Payload, error) {
+	e, err := json.Marshal(&envelope{metadata, data})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling payload envelope: %w", err)
+	}
+
+	return append(append(Payload{}, envelopePrefix...), e...), nil
+}
+
+// IsWrapped returns whether the payload has been created by Wrap
+func IsWrapped(p Payload) bool {
+	return bytes.HasPrefix(p, envelopePrefix)
+}
+
+// Unwrap returns the data and metadata of a payload created by Wrap. Other payloads are returned as-is,
+// without metadata.
+func Unwrap(p Payload) ([]byte, Metadata, error) {
+	if !IsWrapped(p) {
+		return p, nil, nil
+	}
+
+	var e envelope
+	if err := json.Unmarshal(p[len(envelopePrefix):], &e); err != nil {
+		return nil, nil, fmt.Errorf("unmarshaling payload envelope: %w", err)
+	}
+
+	return e.Data, e.Metadata, nil
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/signals"
+)
+
+// Queue is the activity queue polled by every worker with sessions enabled. Sessions are started on it.
+const Queue = "_sessions"
+
+// ErrNotEnabled is returned when a session is started on a worker without sessions enabled
+var ErrNotEnabled = errors.New("sessions are not enabled on this worker")
+
+// ErrHostStopped is returned by KeepWorkflowSession if the worker hosting the session stops
+var ErrHostStopped = errors.New("session host stopped")
+
+// SignalName returns the name of the signal telling the workflow which queue the session's activities are
+// scheduled on
+func SignalName(id string) string {
+	return "__session:" + id
+}
+
+// Host tracks the sessions hosted by a worker
+type Host struct {
+	// Queue is the activity queue only polled by this worker
+	Queue string
+
+	mu       sync.Mutex
+	sessions map[string]chan struct{}
+	stopped  chan struct{}
+}
+
+func NewHost(queue string) *Host {
+	return &Host{
+		Queue:    queue,
+		sessions: make(map[string]chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Stop fails all sessions hosted by the worker
+func (h *Host) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	select {
+	case <-h.stopped:
+	default:
+		close(h.stopped)
+	}
+}
+
+// Activities host sessions on a worker. They are registered with every worker, Host is nil if sessions are not
+// enabled.
+type Activities struct {
+	Host      *Host
+	Signaler  signals.Signaler
+	Converter converter.Converter
+}
+
+// KeepWorkflowSession runs on the worker hosting a session until the session is completed. It tells the workflow
+// which queue to schedule the session's activities on. If the worker dies while the session is open, the activity is
+// delivered to another worker, which tells the workflow about the new host and thereby fails the session.
+func (a *Activities) KeepWorkflowSession(ctx context.Context, instanceID, id string) error {
+	h := a.Host
+	if h == nil {
+		return ErrNotEnabled
+	}
+
+	done := make(chan struct{})
+
+	h.mu.Lock()
+	h.sessions[id] = done
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, id)
+		h.mu.Unlock()
+	}()
+
+	arg, err := a.Converter.To(h.Queue)
+	if err != nil {
+		return err
+	}
+
+	if err := a.Signaler.SignalWorkflow(ctx, instanceID, SignalName(id), arg); err != nil {
+		return err
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-h.stopped:
+		return ErrHostStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// EndWorkflowSession completes the session with the given id, if this worker hosts it
+func (a *Activities) EndWorkflowSession(ctx context.Context, id string) error {
+	h := a.Host
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if done, ok := h.sessions[id]; ok {
+		close(done)
+		delete(h.sessions, id)
+	}
+
+	return nil
+}
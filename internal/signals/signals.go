@@ -0,0 +1,21 @@
+package signals
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// Signaler delivers signals to workflow instances
+type Signaler interface {
+	SignalWorkflow(ctx context.Context, instanceID string, name string, arg payload.Payload) error
+}
+
+// Activities deliver signals sent from workflow code. They are registered with every worker.
+type Activities struct {
+	Signaler Signaler
+}
+
+func (a *Activities) DeliverWorkflowSignal(ctx context.Context, instanceID, name string, arg payload.Payload) error {
+	return a.Signaler.SignalWorkflow(ctx, instanceID, name, arg)
+}
@@ -0,0 +1,68 @@
+package state
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// ErrNotSupported is returned by the state activities if the backend does not store state
+var ErrNotSupported = errors.New("backend does not support workflow state")
+
+// Store persists key-value state shared between workflow instances
+type Store interface {
+	GetState(ctx context.Context, space, key string) (payload.Payload, bool, error)
+	SetState(ctx context.Context, space, key string, value payload.Payload) error
+	DeleteState(ctx context.Context, space, key string) error
+	IncrementState(ctx context.Context, space, key string, delta int64) (int64, error)
+}
+
+// Activities read and write state from workflow code. They are registered with every worker, Store is nil if
+// the backend does not support state.
+type Activities struct {
+	Store Store
+}
+
+// Value is the result of GetWorkflowState. Value is nil if the key does not exist.
+type Value struct {
+	Value payload.Payload `json:"value,omitempty"`
+	Found bool            `json:"found,omitempty"`
+}
+
+func (a *Activities) GetWorkflowState(ctx context.Context, space, key string) (Value, error) {
+	if a.Store == nil {
+		return Value{}, ErrNotSupported
+	}
+
+	v, ok, err := a.Store.GetState(ctx, space, key)
+	if err != nil {
+		return Value{}, err
+	}
+
+	return Value{Value: v, Found: ok}, nil
+}
+
+func (a *Activities) SetWorkflowState(ctx context.Context, space, key string, value payload.Payload) error {
+	if a.Store == nil {
+		return ErrNotSupported
+	}
+
+	return a.Store.SetState(ctx, space, key, value)
+}
+
+func (a *Activities) DeleteWorkflowState(ctx context.Context, space, key string) error {
+	if a.Store == nil {
+		return ErrNotSupported
+	}
+
+	return a.Store.DeleteState(ctx, space, key)
+}
+
+func (a *Activities) IncrementWorkflowState(ctx context.Context, space, key string, delta int64) (int64, error) {
+	if a.Store == nil {
+		return 0, ErrNotSupported
+	}
+
+	return a.Store.IncrementState(ctx, space, key, delta)
+}
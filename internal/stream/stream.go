@@ -0,0 +1,216 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/cschleiden/go-workflows/internal/state"
+	"github.com/google/uuid"
+)
+
+// ChunkSize is the size of the chunks a stream is stored in
+const ChunkSize = 256 * 1024
+
+// ErrNotSupported is returned when streaming without a backend that stores state
+var ErrNotSupported = errors.New("backend does not support streams")
+
+// Stream references a large result stored in chunks. It's small enough to be passed through the history.
+type Stream struct {
+	ID     string `json:"id"`
+	Chunks int    `json:"chunks"`
+	Size   int64  `json:"size"`
+}
+
+// space is the state key space the chunks of the given stream are stored in
+func space(id string) string {
+	return "__stream:" + id
+}
+
+type key int
+
+var storeCtxKey key
+
+// WithStore makes the store available to activities executed with the returned context
+func WithStore(ctx context.Context, store state.Store) context.Context {
+	return context.WithValue(ctx, storeCtxKey, store)
+}
+
+func storeFromContext(ctx context.Context) (state.Store, bool) {
+	s, ok := ctx.Value(storeCtxKey).(state.Store)
+	return s, ok && s != nil
+}
+
+// Writer writes a stream in chunks of ChunkSize
+type Writer struct {
+	ctx   context.Context
+	store state.Store
+
+	stream Stream
+	buf    []byte
+	closed bool
+}
+
+// NewWriter creates a writer for a new stream. Returns ErrNotSupported if the context does not carry a store.
+func NewWriter(ctx context.Context) (*Writer, error) {
+	store, ok := storeFromContext(ctx)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return &Writer{
+		ctx:   ctx,
+		store: store,
+		stream: Stream{
+			ID: uuid.NewString(),
+		},
+	}, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("stream is closed")
+	}
+
+	n := len(p)
+	w.buf = append(w.buf, p...)
+
+	for len(w.buf) >= ChunkSize {
+		if err := w.flush(w.buf[:ChunkSize]); err != nil {
+			return 0, err
+		}
+
+		w.buf = w.buf[ChunkSize:]
+	}
+
+	return n, nil
+}
+
+// Close writes the remaining data. The stream is complete once it's closed.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	err := w.flush(w.buf)
+	w.buf = nil
+
+	return err
+}
+
+// Stream returns the reference to the stream
+func (w *Writer) Stream() Stream {
+	return w.stream
+}
+
+func (w *Writer) flush(chunk []byte) error {
+	data := make([]byte, len(chunk))
+	copy(data, chunk)
+
+	if err := w.store.SetState(w.ctx, space(w.stream.ID), strconv.Itoa(w.stream.Chunks), data); err != nil {
+		return fmt.Errorf("writing stream chunk: %w", err)
+	}
+
+	w.stream.Chunks++
+	w.stream.Size += int64(len(chunk))
+
+	return nil
+}
+
+// ReadChunk returns the chunk of the given stream with the given index
+func ReadChunk(ctx context.Context, store state.Store, s Stream, index int) ([]byte, error) {
+	if index < 0 || index >= s.Chunks {
+		return nil, fmt.Errorf("chunk %v out of range, stream has %v chunks", index, s.Chunks)
+	}
+
+	data, ok, err := store.GetState(ctx, space(s.ID), strconv.Itoa(index))
+	if err != nil {
+		return nil, fmt.Errorf("reading stream chunk: %w", err)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("chunk %v of stream %v not found", index, s.ID)
+	}
+
+	return data, nil
+}
+
+// Delete removes all chunks of the given stream
+func Delete(ctx context.Context, store state.Store, s Stream) error {
+	for i := 0; i < s.Chunks; i++ {
+		if err := store.DeleteState(ctx, space(s.ID), strconv.Itoa(i)); err != nil {
+			return fmt.Errorf("deleting stream chunk: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Reader reads a stream lazily, one chunk at a time
+type Reader struct {
+	ctx   context.Context
+	store state.Store
+
+	stream Stream
+	next   int
+	buf    []byte
+}
+
+func NewReader(ctx context.Context, store state.Store, s Stream) *Reader {
+	return &Reader{
+		ctx:    ctx,
+		store:  store,
+		stream: s,
+	}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.next >= r.stream.Chunks {
+			return 0, io.EOF
+		}
+
+		chunk, err := ReadChunk(r.ctx, r.store, r.stream, r.next)
+		if err != nil {
+			return 0, err
+		}
+
+		r.buf = chunk
+		r.next++
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
+
+// Activities read and delete streams from workflow code. They are registered with every worker, Store is nil if
+// the backend does not support streams.
+type Activities struct {
+	Store state.Store
+}
+
+func (a *Activities) ReadWorkflowStreamChunk(ctx context.Context, s Stream, index int) ([]byte, error) {
+	if a.Store == nil {
+		return nil, ErrNotSupported
+	}
+
+	return ReadChunk(ctx, a.Store, s, index)
+}
+
+func (a *Activities) DeleteWorkflowStream(ctx context.Context, s Stream) error {
+	if a.Store == nil {
+		return ErrNotSupported
+	}
+
+	return Delete(ctx, a.Store, s)
+}
@@ -1,19 +1,20 @@
 package sync
 
-import (
-	"github.com/cschleiden/go-workflows/internal/converter"
-)
-
 type Channel[T any] interface {
+	ReceiveChannel[T]
+
 	Send(ctx Context, v T)
 
 	SendNonblocking(ctx Context, v T) (ok bool)
 
+	Close()
+}
+
+// ReceiveChannel is the receiving side of a channel
+type ReceiveChannel[T any] interface {
 	Receive(ctx Context) (v T, ok bool)
 
 	ReceiveNonBlocking(ctx Context) (v T, ok bool)
-
-	Close()
 }
 
 type ChannelInternal[T any] interface {
@@ -31,16 +32,14 @@ var _ ChannelInternal[struct{}] = (*channel[struct{}])(nil)
 
 func NewChannel[T any]() Channel[T] {
 	return &channel[T]{
-		c:         make([]T, 0),
-		converter: converter.DefaultConverter,
+		c: make([]T, 0),
 	}
 }
 
 func NewBufferedChannel[T any](size int) Channel[T] {
 	return &channel[T]{
-		c:         make([]T, 0, size),
-		size:      size,
-		converter: converter.DefaultConverter,
+		c:    make([]T, 0, size),
+		size: size,
 	}
 }
 
@@ -50,7 +49,6 @@ type channel[T any] struct {
 	senders   []func() T
 	closed    bool
 	size      int
-	converter converter.Converter
 }
 
 func (c *channel[T]) Close() {
@@ -7,6 +7,8 @@ import (
 	"runtime"
 	"sync/atomic"
 	"time"
+
+	"github.com/cschleiden/go-workflows/internal/guard"
 )
 
 const DeadlockDetection = 40 * time.Second
@@ -53,14 +55,34 @@ type coState struct {
 
 	deadlockDetection time.Duration
 
+	// deadlock is set if the coroutine did not yield within the deadlock detection timeout. Only accessed from
+	// the goroutine executing the coroutine.
+	deadlock *DeadlockError
+
 	scheduler Scheduler
+
+	// guard checks the coroutine for non-deterministic calls, if set
+	guard *guard.Guard
+
+	goroutineID uint64
 }
 
 func NewCoroutine(ctx Context, fn func(ctx Context) error) Coroutine {
 	s := newState()
 	ctx = withCoState(ctx, s)
 
+	if d, ok := ctx.Value(deadlockCtxKey).(time.Duration); ok && d > 0 {
+		s.deadlockDetection = d
+	}
+
 	go func() {
+		s.goroutineID = guard.GoroutineID()
+
+		if g, ok := ctx.Value(guardCtxKey).(*guard.Guard); ok && g.Mode != guard.ModeOff {
+			s.guard = g
+			defer guard.Enter(g)()
+		}
+
 		defer s.finish() // Ensure we always mark the coroutine as finished
 		defer func() {
 			if r := recover(); r != nil {
@@ -104,6 +126,11 @@ func (s *coState) SetScheduler(scheduler Scheduler) {
 }
 
 func (s *coState) Finished() bool {
+	if s.deadlock != nil {
+		// The goroutine might still be running, but the coroutine cannot be continued
+		return true
+	}
+
 	v, ok := s.finished.Load().(bool)
 	return ok && v
 }
@@ -175,7 +202,11 @@ func (s *coState) Execute() {
 	case <-s.blocking:
 		s.logger.Println("execute: blocked")
 	case <-t.C:
-		panic("coroutine timed out")
+		s.logger.Println("execute: deadlocked")
+		s.deadlock = &DeadlockError{
+			Timeout: s.deadlockDetection,
+			Stack:   goroutineStack(s.goroutineID),
+		}
 	}
 }
 
@@ -191,6 +222,10 @@ func (s *coState) Exit() {
 }
 
 func (s *coState) Error() error {
+	if s.deadlock != nil {
+		return s.deadlock
+	}
+
 	return s.err
 }
 
@@ -204,5 +239,10 @@ func getCoState(ctx Context) *coState {
 		panic("could not find coroutine state")
 	}
 
+	if s.guard != nil && guard.GoroutineID() != s.goroutineID {
+		// Panicking here would crash the worker, the goroutine is not managed by the workflow
+		s.guard.Warn("workflow context used from a goroutine not started with workflow.Go")
+	}
+
 	return s
 }
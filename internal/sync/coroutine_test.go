@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cschleiden/go-workflows/internal/guard"
+	"github.com/cschleiden/go-workflows/metrics"
 	"github.com/stretchr/testify/require"
 )
 
@@ -136,22 +138,34 @@ func Test_Coroutine_ExitIfAlreadyFinished(t *testing.T) {
 	require.True(t, c.Finished())
 }
 
-func Test_Coroutine_PanicsWhenDeadlocked(t *testing.T) {
-	c := NewCoroutine(Background(), func(ctx Context) error {
+func Test_Coroutine_ErrorsWhenDeadlocked(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	c := NewCoroutine(WithDeadlockDetection(Background(), time.Millisecond*10), func(ctx Context) error {
 		s := getCoState(ctx)
-		s.deadlockDetection = time.Millisecond
 		s.Yield()
 
-		time.Sleep(10 * time.Second)
+		<-block
 
 		return nil
 	})
 
 	c.Execute()
 
-	require.Panics(t, func() {
+	require.NotPanics(t, func() {
 		c.Execute()
 	})
+
+	require.True(t, c.Finished())
+
+	var deadlockErr *DeadlockError
+	require.ErrorAs(t, c.Error(), &deadlockErr)
+	require.Equal(t, time.Millisecond*10, deadlockErr.Timeout)
+	require.Contains(t, deadlockErr.Stack, "Test_Coroutine_ErrorsWhenDeadlocked")
+
+	// Exiting a deadlocked coroutine does not block
+	c.Exit()
 }
 
 func Test_Coroutine_Error(t *testing.T) {
@@ -177,3 +191,39 @@ func Test_Coroutine_Panic(t *testing.T) {
 	require.Error(t, c.Error())
 	require.Equal(t, c.Error().Error(), "panic: test panic")
 }
+
+func Test_Coroutine_Guard_WarnsForForeignGoroutines(t *testing.T) {
+	mc := &countingMetrics{}
+	g := &guard.Guard{Mode: guard.ModeStrict, Metrics: mc}
+
+	c := NewCoroutine(WithGuard(Background(), g), func(ctx Context) error {
+		// Using the context from the coroutine is fine
+		getCoState(ctx)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			getCoState(ctx)
+		}()
+		<-done
+
+		return nil
+	})
+
+	c.Execute()
+
+	require.True(t, c.Finished())
+	require.NoError(t, c.Error())
+	require.Equal(t, int64(1), mc.count)
+}
+
+type countingMetrics struct {
+	metrics.Client
+
+	count int64
+}
+
+func (m *countingMetrics) Counter(name string, tags map[string]string, value int64) {
+	m.count += value
+}
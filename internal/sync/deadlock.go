@@ -0,0 +1,56 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// DeadlockError is returned when workflow code does not yield within the deadlock detection timeout, for
+// example because it's blocked on a native Go channel or stuck in a loop
+type DeadlockError struct {
+	Timeout time.Duration
+
+	// Stack is the stack trace of the goroutine running the blocked workflow code
+	Stack string
+}
+
+func (e *DeadlockError) Error() string {
+	return fmt.Sprintf("workflow deadlock detected: workflow code did not yield within %v, "+
+		"it might be blocked on a native Go channel, mutex, or I/O, or loop without yielding\n\n%s", e.Timeout, e.Stack)
+}
+
+type deadlockKey int
+
+var deadlockCtxKey deadlockKey
+
+// WithDeadlockDetection returns a context whose coroutines abort with a DeadlockError if they do not yield within
+// the given timeout
+func WithDeadlockDetection(ctx Context, timeout time.Duration) Context {
+	return WithValue(ctx, deadlockCtxKey, timeout)
+}
+
+// goroutineStack returns the stack trace of the goroutine with the given ID
+func goroutineStack(id uint64) string {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+
+		buf = make([]byte, 2*len(buf))
+	}
+
+	prefix := []byte("goroutine " + strconv.FormatUint(id, 10) + " ")
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(stack, prefix) {
+			return string(stack)
+		}
+	}
+
+	return ""
+}
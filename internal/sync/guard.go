@@ -0,0 +1,12 @@
+package sync
+
+import "github.com/cschleiden/go-workflows/internal/guard"
+
+type guardKey int
+
+var guardCtxKey guardKey
+
+// WithGuard returns a context whose coroutines are checked for non-deterministic calls by the given guard
+func WithGuard(ctx Context, g *guard.Guard) Context {
+	return WithValue(ctx, guardCtxKey, g)
+}
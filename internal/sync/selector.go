@@ -12,9 +12,9 @@ func Await[T any](f Future[T], handler func(ctx Context, f Future[T])) SelectCas
 	}
 }
 
-func Receive[T any](c Channel[T], handler func(ctx Context, v T, ok bool)) SelectCase {
+func Receive[T any, C ReceiveChannel[T]](c C, handler func(ctx Context, v T, ok bool)) SelectCase {
 	return &channelCase[T]{
-		c:  c.(*channel[T]),
+		c:  any(c).(*channel[T]),
 		fn: handler,
 	}
 }
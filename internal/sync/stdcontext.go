@@ -0,0 +1,86 @@
+package sync
+
+import (
+	"context"
+	gosync "sync"
+	"time"
+)
+
+// NewStdContext returns a context.Context that is canceled when ctx is canceled or the returned cancel
+// function is called, and that returns the values of ctx. Cancellation happens synchronously while ctx is
+// canceled, so code checking the returned context's Err sees the same result on every replay.
+func NewStdContext(ctx Context) (context.Context, CancelFunc) {
+	if ctx == nil {
+		panic("cannot create context from nil parent")
+	}
+
+	c := &stdContext{
+		parent: ctx,
+		done:   make(chan struct{}),
+	}
+	c.canceler = &stdCanceler{c}
+
+	propagateCancel(ctx, c.canceler)
+
+	return c, func() {
+		c.cancel()
+		removeChild(ctx, c.canceler)
+	}
+}
+
+type stdContext struct {
+	parent   Context
+	canceler *stdCanceler
+
+	mu   gosync.Mutex
+	done chan struct{}
+	err  error
+}
+
+var _ context.Context = (*stdContext)(nil)
+
+func (c *stdContext) Deadline() (deadline time.Time, ok bool) {
+	// Workflow contexts don't have deadlines, timers need to be used instead
+	return
+}
+
+func (c *stdContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *stdContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+func (c *stdContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+func (c *stdContext) cancel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+
+	c.err = context.Canceled
+	close(c.done)
+}
+
+// stdCanceler registers a stdContext as child of a workflow context. The workflow Done channel of a
+// stdContext is never used, only its standard Done channel.
+type stdCanceler struct {
+	c *stdContext
+}
+
+func (s *stdCanceler) cancel(_ bool, _ error) {
+	s.c.cancel()
+}
+
+func (s *stdCanceler) Done() Channel[struct{}] {
+	return nil
+}
@@ -0,0 +1,61 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStdContext(t *testing.T) {
+	c, cancel := WithCancel(WithValue(Background(), ctxKey(42), "foo"))
+
+	var stdCtx context.Context
+
+	cr := NewCoroutine(c, func(ctx Context) error {
+		stdCtx, _ = NewStdContext(ctx)
+
+		Select(
+			ctx,
+			Receive(ctx.Done(), func(ctx Context, _ struct{}, _ bool) {}),
+		)
+
+		return nil
+	})
+
+	cr.Execute()
+	require.False(t, cr.Finished())
+
+	require.Equal(t, "foo", stdCtx.Value(ctxKey(42)))
+	require.NoError(t, stdCtx.Err())
+
+	cancel()
+
+	select {
+	case <-stdCtx.Done():
+	default:
+		require.Fail(t, "std context not canceled")
+	}
+
+	require.ErrorIs(t, stdCtx.Err(), context.Canceled)
+
+	cr.Execute()
+	require.True(t, cr.Finished())
+}
+
+func TestNewStdContext_Cancel(t *testing.T) {
+	c, _ := WithCancel(Background())
+
+	cr := NewCoroutine(c, func(ctx Context) error {
+		stdCtx, cancel := NewStdContext(ctx)
+		cancel()
+
+		require.ErrorIs(t, stdCtx.Err(), context.Canceled)
+		require.NoError(t, ctx.Err())
+
+		return nil
+	})
+
+	cr.Execute()
+	require.True(t, cr.Finished())
+}
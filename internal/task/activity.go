@@ -1,8 +1,11 @@
 package task
 
 import (
+	"time"
+
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
 )
 
 type Activity struct {
@@ -11,4 +14,12 @@ type Activity struct {
 	WorkflowInstance *core.WorkflowInstance
 
 	Event history.Event
+
+	// ScheduledAt is the time the task was enqueued. The time between ScheduledAt and a worker picking up the
+	// task is the schedule-to-start latency.
+	ScheduledAt time.Time
+
+	// HeartbeatDetails are the details of the last heartbeat of an earlier execution of the task, if the backend
+	// stores them, see backend.ActivityHeartbeater
+	HeartbeatDetails payload.Payload
 }
@@ -0,0 +1,26 @@
+package task
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+// ScheduledAt returns the time the oldest of the given events became visible. Events without a visibility time
+// become visible when they are created. Returns the zero time if no events are given.
+func ScheduledAt(events ...history.Event) time.Time {
+	var scheduledAt time.Time
+
+	for _, event := range events {
+		visibleAt := event.Timestamp
+		if event.VisibleAt != nil && event.VisibleAt.After(visibleAt) {
+			visibleAt = *event.VisibleAt
+		}
+
+		if scheduledAt.IsZero() || visibleAt.Before(scheduledAt) {
+			scheduledAt = visibleAt
+		}
+	}
+
+	return scheduledAt
+}
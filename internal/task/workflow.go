@@ -1,6 +1,8 @@
 package task
 
 import (
+	"time"
+
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
 )
@@ -17,4 +19,15 @@ type Workflow struct {
 
 	// NewEvents are new events since the last task execution
 	NewEvents []history.Event
+
+	// History contains the history events after HistorySequenceID, if the backend delivered them with the task.
+	// Executors that already know the history up to HistorySequenceID can continue without fetching the history.
+	History []history.Event
+
+	// HistorySequenceID is the sequence ID of the history known to the worker when History was included
+	HistorySequenceID int64
+
+	// ScheduledAt is the time the task was enqueued, i.e., when the oldest of the new events became visible. The
+	// time between ScheduledAt and a worker picking up the task is the schedule-to-start latency.
+	ScheduledAt time.Time
 }
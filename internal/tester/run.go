@@ -0,0 +1,28 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RunWorkflow executes the workflow under test to completion in-process and returns its result, without a
+// backend or worker. Activities registered with the tester's registry run inline, mocked activities and
+// sub-workflows return their mocked results, and timers fire by advancing the tester's clock. An error
+// returned by the workflow, or a failure to execute it, is returned as error.
+func RunWorkflow[TResult any](wt WorkflowTester, args ...interface{}) (result TResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("executing workflow: %v", r)
+		}
+	}()
+
+	wt.Execute(args...)
+
+	var workflowErr string
+	wt.WorkflowResult(&result, &workflowErr)
+	if workflowErr != "" {
+		return result, errors.New(workflowErr)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package tester
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// testStateStore keeps the state written by workflows under test in memory
+type testStateStore struct {
+	mu     sync.Mutex
+	values map[string]payload.Payload
+}
+
+func newTestStateStore() *testStateStore {
+	return &testStateStore{
+		values: make(map[string]payload.Payload),
+	}
+}
+
+func stateKey(space, key string) string {
+	return space + "/" + key
+}
+
+func (s *testStateStore) GetState(ctx context.Context, space, key string) (payload.Payload, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.values[stateKey(space, key)]
+	return v, ok, nil
+}
+
+func (s *testStateStore) SetState(ctx context.Context, space, key string, value payload.Payload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[stateKey(space, key)] = value
+	return nil
+}
+
+func (s *testStateStore) DeleteState(ctx context.Context, space, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, stateKey(space, key))
+	return nil
+}
+
+func (s *testStateStore) IncrementState(ctx context.Context, space, key string, delta int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	if v, ok := s.values[stateKey(space, key)]; ok {
+		var err error
+		if n, err = strconv.ParseInt(string(v), 10, 64); err != nil {
+			return 0, err
+		}
+	}
+
+	n += delta
+	s.values[stateKey(space, key)] = payload.Payload(strconv.FormatInt(n, 10))
+
+	return n, nil
+}
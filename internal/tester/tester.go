@@ -5,23 +5,33 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/activity"
 	margs "github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/fn"
+	"github.com/cschleiden/go-workflows/internal/guard"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/logger"
+	mi "github.com/cschleiden/go-workflows/internal/metrics"
 	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/session"
+	"github.com/cschleiden/go-workflows/internal/signals"
+	"github.com/cschleiden/go-workflows/internal/state"
+	"github.com/cschleiden/go-workflows/internal/stream"
 	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflow"
 	"github.com/cschleiden/go-workflows/log"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -53,9 +63,23 @@ type WorkflowTester interface {
 
 	WorkflowResult(vtpr interface{}, err *string)
 
+	// QueryWorkflow answers the query with the given name from the current state of the workflow under test, see
+	// workflow.HandleQuery, and decodes the result into vtpr. Call it from a callback to query the workflow while
+	// it's running, see ScheduleCallback.
+	QueryWorkflow(name string, vtpr interface{}, args ...interface{}) error
+
 	// AssertExpectations asserts any assertions set up for mock activities and sub-workflow
 	AssertExpectations(t *testing.T)
 
+	// AssertWorkflowResult asserts that the workflow under test has finished with the expected result, or, if
+	// expectedErr is not empty, failed with the expected error message
+	AssertWorkflowResult(t *testing.T, expected interface{}, expectedErr string)
+
+	// AssertSignalSent asserts that workflow code has sent the signal with the given name and argument to the
+	// instance with the given ID. Signals to instances not executed by the tester are recorded, too, even
+	// though delivering them fails. A nil arg matches any argument.
+	AssertSignalSent(t *testing.T, instanceID string, name string, arg interface{})
+
 	// ScheduleCallback schedules the given callback after the given delay in workflow time (not wall clock).
 	ScheduleCallback(delay time.Duration, callback func())
 
@@ -77,11 +101,17 @@ type testWorkflow struct {
 	instance      *core.WorkflowInstance
 	history       []history.Event
 	pendingEvents []history.Event
+
+	// finished is set once the workflow has finished. Events still sent to it, e.g., timers that were canceled,
+	// are dropped like a backend would.
+	finished bool
 }
 
 type options struct {
-	TestTimeout time.Duration
-	Logger      log.Logger
+	TestTimeout      time.Duration
+	Logger           log.Logger
+	Converter        converter.Converter
+	DeterminismGuard guard.Mode
 }
 
 type workflowTester struct {
@@ -116,7 +146,16 @@ type workflowTester struct {
 
 	runningActivities int32
 
+	// sentSignals are the signals sent from workflow code
+	sentSignals  []sentSignal
+	sentSignalsM sync.Mutex
+
+	// stateStore keeps state and streams in memory
+	stateStore *testStateStore
+
 	logger log.Logger
+
+	converter converter.Converter
 }
 
 type WorkflowTesterOption func(*options)
@@ -127,6 +166,20 @@ func WithLogger(logger log.Logger) WorkflowTesterOption {
 	}
 }
 
+// WithConverter sets the converter used for workflow and activity inputs and results
+func WithConverter(c converter.Converter) WorkflowTesterOption {
+	return func(o *options) {
+		o.Converter = c
+	}
+}
+
+// WithDeterminismGuard checks the workflow code for non-deterministic calls while testing
+func WithDeterminismGuard(mode guard.Mode) WorkflowTesterOption {
+	return func(o *options) {
+		o.DeterminismGuard = mode
+	}
+}
+
 func NewWorkflowTester(wf interface{}, opts ...WorkflowTesterOption) WorkflowTester {
 	// Start with the current wall-clock tiem
 	clock := clock.NewMock()
@@ -147,6 +200,10 @@ func NewWorkflowTester(wf interface{}, opts ...WorkflowTesterOption) WorkflowTes
 		options.Logger = logger.NewDefaultLogger()
 	}
 
+	if options.Converter == nil {
+		options.Converter = converter.DefaultConverter
+	}
+
 	wt := &workflowTester{
 		options: options,
 
@@ -165,15 +222,35 @@ func NewWorkflowTester(wf interface{}, opts ...WorkflowTesterOption) WorkflowTes
 		workflowHistory: make([]history.Event, 0),
 		clock:           clock,
 
+		stateStore: newTestStateStore(),
+
 		timers:    make([]*testTimer, 0),
 		callbacks: make(chan func() *history.WorkflowEvent, 1024),
 
 		logger: options.Logger,
+
+		converter: options.Converter,
 	}
 
 	// Always register the workflow under test
 	wt.registry.RegisterWorkflow(wf)
 
+	// Deliver signals sent from workflow code to the test workflows
+	wt.registry.RegisterActivity(&signals.Activities{Signaler: &testSignaler{wt: wt}})
+
+	// Keep state written from workflow code in memory
+	wt.registry.RegisterActivity(&state.Activities{Store: wt.stateStore})
+
+	// Read and delete streams written by activities
+	wt.registry.RegisterActivity(&stream.Activities{Store: wt.stateStore})
+
+	// Host sessions in the tester, activities always run in-process
+	wt.registry.RegisterActivity(&session.Activities{
+		Host:      session.NewHost("tester"),
+		Signaler:  &testSignaler{wt: wt},
+		Converter: wt.converter,
+	})
+
 	return wt
 }
 
@@ -232,12 +309,20 @@ func (wt *workflowTester) Execute(args ...interface{}) {
 				continue
 			}
 
+			if tw.finished {
+				tw.pendingEvents = tw.pendingEvents[:0]
+				continue
+			}
+
 			// Get task
 			t := getNextWorkflowTask(tw.instance, tw.history, tw.pendingEvents)
 			tw.pendingEvents = tw.pendingEvents[:0]
 
 			// Execute task
-			e, err := workflow.NewExecutor(wt.logger, wt.registry, &testHistoryProvider{tw.history}, tw.instance, wt.clock)
+			e, err := workflow.NewExecutor(wt.logger, mi.NewNoopMetricsClient(), wt.converter, wt.registry, &testHistoryProvider{tw.history}, tw.instance, wt.clock, workflow.ExecutorOptions{
+				DeterminismGuard:    wt.options.DeterminismGuard,
+				LocalActivityRunner: wt.runLocalActivity,
+			})
 			if err != nil {
 				panic("could not create workflow executor" + err.Error())
 			}
@@ -257,6 +342,8 @@ func (wt *workflowTester) Execute(args ...interface{}) {
 
 				switch event.Type {
 				case history.EventType_WorkflowExecutionFinished:
+					tw.finished = true
+
 					a := event.Attributes.(*history.ExecutionCompletedAttributes)
 
 					if !tw.instance.SubWorkflow() {
@@ -265,6 +352,14 @@ func (wt *workflowTester) Execute(args ...interface{}) {
 						wt.workflowErr = a.Error
 					}
 
+				case history.EventType_WorkflowExecutionContinuedAsNew:
+					// The next execution is started with the workflow events below
+					tw.finished = true
+
+				case history.EventType_WorkflowExecutionTerminated:
+					// Sub-workflow terminated by its parent, see workflow.ParentClosePolicyTerminate
+					tw.finished = true
+
 				case history.EventType_ActivityScheduled:
 					wt.scheduleActivity(tw.instance, event)
 				}
@@ -276,6 +371,15 @@ func (wt *workflowTester) Execute(args ...interface{}) {
 
 				switch workflowEvent.HistoryEvent.Type {
 				case history.EventType_WorkflowExecutionStarted:
+					if workflowEvent.WorkflowInstance.InstanceID == tw.instance.InstanceID {
+						// Workflow continues as new, start the next execution with an empty history
+						tw.instance = workflowEvent.WorkflowInstance
+						tw.history = []history.Event{}
+						tw.pendingEvents = []history.Event{workflowEvent.HistoryEvent}
+						tw.finished = false
+						continue
+					}
+
 					wt.scheduleSubWorkflow(workflowEvent)
 
 				case history.EventType_TimerFired:
@@ -360,7 +464,7 @@ func (wt *workflowTester) SignalWorkflow(name string, value interface{}) {
 }
 
 func (wt *workflowTester) SignalWorkflowInstance(wfi *core.WorkflowInstance, name string, value interface{}) {
-	arg, err := converter.DefaultConverter.To(value)
+	arg, err := wt.converter.To(value)
 	if err != nil {
 		panic("Could not convert signal value to string" + err.Error())
 	}
@@ -382,13 +486,58 @@ func (wt *workflowTester) SignalWorkflowInstance(wfi *core.WorkflowInstance, nam
 	}
 }
 
+type sentSignal struct {
+	instanceID string
+	name       string
+	arg        payload.Payload
+}
+
+type testSignaler struct {
+	wt *workflowTester
+}
+
+func (s *testSignaler) SignalWorkflow(ctx context.Context, instanceID string, name string, arg payload.Payload) error {
+	s.wt.sentSignalsM.Lock()
+	s.wt.sentSignals = append(s.wt.sentSignals, sentSignal{instanceID: instanceID, name: name, arg: arg})
+	s.wt.sentSignalsM.Unlock()
+
+	errc := make(chan error, 1)
+
+	s.wt.callbacks <- func() *history.WorkflowEvent {
+		for _, tw := range s.wt.testWorkflows {
+			if tw.instance.InstanceID != instanceID {
+				continue
+			}
+
+			errc <- nil
+
+			return &history.WorkflowEvent{
+				WorkflowInstance: tw.instance,
+				HistoryEvent: history.NewPendingEvent(
+					s.wt.clock.Now(),
+					history.EventType_SignalReceived,
+					&history.SignalReceivedAttributes{
+						Name: name,
+						Arg:  arg,
+					},
+				),
+			}
+		}
+
+		errc <- backend.ErrInstanceNotFound
+		return nil
+	}
+
+	return <-errc
+}
+
 func (wt *workflowTester) WorkflowFinished() bool {
 	return wt.workflowFinished
 }
 
 func (wt *workflowTester) WorkflowResult(vtpr interface{}, err *string) {
 	if wt.workflowErr == "" {
-		if err := converter.AssignValue(converter.DefaultConverter, wt.workflowResult, vtpr); err != nil {
+		if err := converter.AssignValue(wt.converter, wt.workflowResult, vtpr); err != nil {
 			panic("Could not convert result to provided type" + err.Error())
 		}
 	}
@@ -398,10 +547,143 @@ func (wt *workflowTester) WorkflowResult(vtpr interface{}, err *string) {
 	}
 }
 
+func (wt *workflowTester) QueryWorkflow(name string, vtpr interface{}, args ...interface{}) error {
+	var tw *testWorkflow
+	for _, w := range wt.testWorkflows {
+		if w.instance.InstanceID == wt.wfi.InstanceID {
+			tw = w
+		}
+	}
+
+	if tw == nil {
+		return fmt.Errorf("workflow has not been started yet")
+	}
+
+	inputs, err := margs.ArgsToInputs(wt.converter, args...)
+	if err != nil {
+		return fmt.Errorf("converting query arguments: %w", err)
+	}
+
+	// Replay the history in a new executor, like a worker without a cached executor would
+	e, err := workflow.NewExecutor(wt.logger, mi.NewNoopMetricsClient(), wt.converter, wt.registry, &testHistoryProvider{tw.history}, tw.instance, wt.clock, workflow.ExecutorOptions{})
+	if err != nil {
+		return fmt.Errorf("creating workflow executor: %w", err)
+	}
+	defer e.Close()
+
+	result, err := e.Query(context.Background(), name, inputs)
+	if err != nil {
+		return err
+	}
+
+	if err := converter.AssignValue(wt.converter, result, vtpr); err != nil {
+		return fmt.Errorf("converting query result: %w", err)
+	}
+
+	return nil
+}
+
 func (wt *workflowTester) AssertExpectations(t *testing.T) {
 	wt.ma.AssertExpectations(t)
 }
 
+func (wt *workflowTester) AssertWorkflowResult(t *testing.T, expected interface{}, expectedErr string) {
+	t.Helper()
+
+	if !assert.True(t, wt.workflowFinished, "workflow has not finished") {
+		return
+	}
+
+	if !assert.Equal(t, expectedErr, wt.workflowErr, "workflow error") || expectedErr != "" || expected == nil {
+		return
+	}
+
+	actual, err := wt.decode(wt.workflowResult, reflect.TypeOf(expected))
+	if assert.NoError(t, err, "decoding workflow result") {
+		assert.Equal(t, expected, actual, "workflow result")
+	}
+}
+
+func (wt *workflowTester) AssertSignalSent(t *testing.T, instanceID string, name string, arg interface{}) {
+	t.Helper()
+
+	wt.sentSignalsM.Lock()
+	defer wt.sentSignalsM.Unlock()
+
+	for _, s := range wt.sentSignals {
+		if s.instanceID != instanceID || s.name != name {
+			continue
+		}
+
+		if arg == nil {
+			return
+		}
+
+		if actual, err := wt.decode(s.arg, reflect.TypeOf(arg)); err == nil && reflect.DeepEqual(arg, actual) {
+			return
+		}
+	}
+
+	t.Errorf("signal %q with argument %v was not sent to instance %v", name, arg, instanceID)
+}
+
+// decode decodes the given payload into a value of the given type
+func (wt *workflowTester) decode(p payload.Payload, t reflect.Type) (interface{}, error) {
+	v := reflect.New(t)
+	if err := converter.AssignValue(wt.converter, p, v.Interface()); err != nil {
+		return nil, err
+	}
+
+	return v.Elem().Interface(), nil
+}
+
+// callMockedActivity calls the mock of the given activity with the decoded inputs
+func (wt *workflowTester) callMockedActivity(name string, afn interface{}, inputs []payload.Payload) (payload.Payload, error) {
+	argValues, addContext, err := margs.InputsToArgs(wt.converter, reflect.ValueOf(afn), inputs)
+	if err != nil {
+		panic("Could not convert activity inputs to args: " + err.Error())
+	}
+
+	args := make([]interface{}, len(argValues))
+	for i, arg := range argValues {
+		if i == 0 && addContext {
+			args[i] = context.Background()
+			continue
+		}
+
+		args[i] = arg.Interface()
+	}
+
+	results := wt.ma.MethodCalled(name, args...)
+
+	if len(results) < 1 {
+		panic(
+			fmt.Sprintf(
+				"Unexpected number of results returned for mocked activity %v, expected at least 1, got %v",
+				name,
+				len(results),
+			),
+		)
+	}
+
+	result, err := converter.EncodeResults(wt.converter, results[:len(results)-1])
+	if err != nil {
+		panic("Could not convert result for activity " + name + ": " + err.Error())
+	}
+
+	return result, results.Error(len(results) - 1)
+}
+
+// runLocalActivity executes a local activity inline, or calls its mock if the activity has been mocked
+func (wt *workflowTester) runLocalActivity(ctx context.Context, instance *core.WorkflowInstance, activityFn interface{}, inputs []payload.Payload, timeout time.Duration) (payload.Payload, error) {
+	if name := fn.Name(activityFn); wt.mockedActivities[name] {
+		return wt.callMockedActivity(name, activityFn, inputs)
+	}
+
+	executor := activity.NewExecutor(wt.logger, wt.converter, mi.NewNoopMetricsClient(), tracing.NewNoopTracer(), wt.registry)
+	return executor.ExecuteLocalActivity(stream.WithStore(ctx, wt.stateStore), instance, activityFn, inputs, timeout)
+}
+
 func (wt *workflowTester) scheduleActivity(wfi *core.WorkflowInstance, event history.Event) {
 	e := event.Attributes.(*history.ActivityScheduledAttributes)
 
@@ -419,49 +701,10 @@ func (wt *workflowTester) scheduleActivity(wfi *core.WorkflowInstance, event his
 				panic("Could not find activity " + e.Name + " in registry")
 			}
 
-			argValues, addContext, err := margs.InputsToArgs(converter.DefaultConverter, reflect.ValueOf(afn), e.Inputs)
-			if err != nil {
-				panic("Could not convert activity inputs to args: " + err.Error())
-			}
-
-			args := make([]interface{}, len(argValues))
-			for i, arg := range argValues {
-				if i == 0 && addContext {
-					args[i] = context.Background()
-					continue
-				}
-
-				args[i] = arg.Interface()
-			}
-
-			results := wt.ma.MethodCalled(e.Name, args...)
-
-			switch len(results) {
-			case 1:
-				// Expect only error
-				activityErr = results.Error(0)
-				activityResult = nil
-			case 2:
-				result := results.Get(0)
-				activityResult, err = converter.DefaultConverter.To(result)
-				if err != nil {
-					panic("Could not convert result for activity " + e.Name + ": " + err.Error())
-				}
-
-				activityErr = results.Error(1)
-			default:
-				panic(
-					fmt.Sprintf(
-						"Unexpected number of results returned for mocked activity %v, expected 1 or 2, got %v",
-						e.Name,
-						len(results),
-					),
-				)
-			}
-
+			activityResult, activityErr = wt.callMockedActivity(e.Name, afn, e.Inputs)
 		} else {
-			executor := activity.NewExecutor(wt.logger, wt.registry)
-			activityResult, activityErr = executor.ExecuteActivity(context.Background(), &task.Activity{
+			executor := activity.NewExecutor(wt.logger, wt.converter, mi.NewNoopMetricsClient(), tracing.NewNoopTracer(), wt.registry)
+			activityResult, activityErr = executor.ExecuteActivity(stream.WithStore(context.Background(), wt.stateStore), &task.Activity{
 				ID:               uuid.NewString(),
 				WorkflowInstance: wfi,
 				Event:            event,
@@ -472,11 +715,15 @@ func (wt *workflowTester) scheduleActivity(wfi *core.WorkflowInstance, event his
 			var ne history.Event
 
 			if activityErr != nil {
+				failure := history.NewFailure(wt.converter, activityErr, wt.clock.Now())
+				failure.ActivityName = e.Name
+
 				ne = history.NewPendingEvent(
 					wt.clock.Now(),
 					history.EventType_ActivityFailed,
 					&history.ActivityFailedAttributes{
-						Reason: activityErr.Error(),
+						Reason:  activityErr.Error(),
+						Failure: failure,
 					},
 					history.ScheduleEventID(event.ScheduleEventID),
 				)
@@ -506,12 +753,28 @@ func (wt *workflowTester) scheduleTimer(event history.WorkflowEvent) {
 		At: e.At,
 		Callback: func() {
 			wt.callbacks <- func() *history.WorkflowEvent {
+				if !wt.isCurrentExecution(event.WorkflowInstance) {
+					// Timers of an execution that continued as new don't fire
+					return nil
+				}
+
 				return &event
 			}
 		},
 	})
 }
 
+// isCurrentExecution returns whether the given instance is the current execution of its workflow instance
+func (wt *workflowTester) isCurrentExecution(wfi *core.WorkflowInstance) bool {
+	for _, tw := range wt.testWorkflows {
+		if tw.instance.InstanceID == wfi.InstanceID {
+			return tw.instance.ExecutionID == wfi.ExecutionID
+		}
+	}
+
+	return true
+}
+
 func (wt *workflowTester) scheduleSubWorkflow(event history.WorkflowEvent) {
 	a := event.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
 
@@ -525,7 +788,7 @@ func (wt *workflowTester) scheduleSubWorkflow(event history.WorkflowEvent) {
 		panic("Could not find workflow " + a.Name + " in registry")
 	}
 
-	argValues, addContext, err := margs.InputsToArgs(converter.DefaultConverter, reflect.ValueOf(wfn), a.Inputs)
+	argValues, addContext, err := margs.InputsToArgs(wt.converter, reflect.ValueOf(wfn), a.Inputs)
 	if err != nil {
 		panic("Could not convert workflow inputs to args: " + err.Error())
 	}
@@ -551,29 +814,23 @@ func (wt *workflowTester) scheduleSubWorkflow(event history.WorkflowEvent) {
 
 	results := wt.mw.MethodCalled(a.Name, args...)
 
-	switch len(results) {
-	case 1:
-		// Expect only error
-		workflowErr = results.Error(0)
-		workflowResult = nil
-	case 2:
-		result := results.Get(0)
-		workflowResult, err = converter.DefaultConverter.To(result)
-		if err != nil {
-			panic("Could not convert result for mocked workflow " + a.Name + ": " + err.Error())
-		}
-
-		workflowErr = results.Error(1)
-	default:
+	if len(results) < 1 {
 		panic(
 			fmt.Sprintf(
-				"Unexpected number of results returned for mocked workflow %v, expected 1 or 2, got %v",
+				"Unexpected number of results returned for mocked workflow %v, expected at least 1, got %v",
 				a.Name,
 				len(results),
 			),
 		)
 	}
 
+	workflowResult, err = converter.EncodeResults(wt.converter, results[:len(results)-1])
+	if err != nil {
+		panic("Could not convert result for mocked workflow " + a.Name + ": " + err.Error())
+	}
+
+	workflowErr = results.Error(len(results) - 1)
+
 	wt.callbacks <- func() *history.WorkflowEvent {
 		// Ideally we'd execute the same command here, but for now duplicate the code
 		var he history.Event
@@ -608,7 +865,7 @@ func (wt *workflowTester) scheduleSubWorkflow(event history.WorkflowEvent) {
 func (wt *workflowTester) getInitialEvent(wf interface{}, args []interface{}) history.Event {
 	name := fn.Name(wf)
 
-	inputs, err := margs.ArgsToInputs(converter.DefaultConverter, args...)
+	inputs, err := margs.ArgsToInputs(wt.converter, args...)
 	if err != nil {
 		panic(err)
 	}
@@ -1,7 +1,9 @@
 package tester
 
 import (
+	"context"
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 
@@ -43,6 +45,38 @@ func Test_SubWorkflow(t *testing.T) {
 	tester.AssertExpectations(t)
 }
 
+func Test_SubWorkflow_Typed(t *testing.T) {
+	activity := func(ctx context.Context, i int) (int, error) {
+		return i * 2, nil
+	}
+
+	subWorkflow := func(ctx workflow.Context, i int) (string, error) {
+		r, err := workflow.ExecuteActivityFn(ctx, workflow.DefaultActivityOptions, activity, i).Get(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.Itoa(r), nil
+	}
+
+	workflowWithSub := func(ctx workflow.Context, i int) (string, error) {
+		return workflow.CreateSubWorkflowInstanceFn(ctx, workflow.DefaultSubWorkflowOptions, subWorkflow, i).Get(ctx)
+	}
+
+	tester := NewWorkflowTester(workflowWithSub)
+	tester.Registry().RegisterWorkflow(subWorkflow)
+	tester.Registry().RegisterActivity(activity)
+
+	tester.Execute(21)
+
+	require.True(t, tester.WorkflowFinished())
+
+	var wfR string
+	tester.WorkflowResult(&wfR, nil)
+	require.Equal(t, "42", wfR)
+	tester.AssertExpectations(t)
+}
+
 func Test_SubWorkflow_Mocked(t *testing.T) {
 	subWorkflow := func(ctx workflow.Context, input string) (string, error) {
 		panic("should not call this")
@@ -156,3 +190,65 @@ func Test_SubWorkflow_Signals(t *testing.T) {
 	require.Equal(t, "hello42", wfR)
 	tester.AssertExpectations(t)
 }
+
+func Test_SubWorkflow_RequestResponse(t *testing.T) {
+	subWorkflow := func(ctx workflow.Context) (int, error) {
+		parentID := workflow.WorkflowInstance(ctx).ParentInstanceID
+
+		return workflow.SendRequest[int](ctx, parentID, "double", 21, workflow.RequestOptions{}).Get(ctx)
+	}
+
+	workflowWithSub := func(ctx workflow.Context) (int, error) {
+		f := workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, subWorkflow)
+
+		req, _ := workflow.NewRequestChannel[int](ctx, "double").Receive(ctx)
+		if _, err := workflow.Reply(ctx, req, req.Arg*2, nil).Get(ctx); err != nil {
+			return 0, err
+		}
+
+		return f.Get(ctx)
+	}
+
+	tester := NewWorkflowTester(workflowWithSub)
+	tester.Registry().RegisterWorkflow(subWorkflow)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	var wfR int
+	var wfE string
+	tester.WorkflowResult(&wfR, &wfE)
+	require.Empty(t, wfE)
+	require.Equal(t, 42, wfR)
+	tester.AssertExpectations(t)
+}
+
+func Test_SubWorkflow_RequestTimeout(t *testing.T) {
+	subWorkflow := func(ctx workflow.Context) (bool, error) {
+		parentID := workflow.WorkflowInstance(ctx).ParentInstanceID
+
+		_, err := workflow.SendRequest[int](ctx, parentID, "double", 21, workflow.RequestOptions{
+			Timeout: time.Minute,
+		}).Get(ctx)
+
+		return errors.Is(err, workflow.ErrRequestTimeout), nil
+	}
+
+	workflowWithSub := func(ctx workflow.Context) (bool, error) {
+		// Never reply to the request
+		return workflow.CreateSubWorkflowInstance[bool](ctx, workflow.DefaultSubWorkflowOptions, subWorkflow).Get(ctx)
+	}
+
+	tester := NewWorkflowTester(workflowWithSub)
+	tester.Registry().RegisterWorkflow(subWorkflow)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	var timedOut bool
+	tester.WorkflowResult(&timedOut, nil)
+	require.True(t, timedOut)
+	tester.AssertExpectations(t)
+}
@@ -1,13 +1,20 @@
 package tester
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"testing"
 	"time"
 
+	"github.com/cschleiden/go-workflows/activity"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/stream"
 	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/validation"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -134,6 +141,110 @@ func activity1(ctx context.Context) (int, error) {
 	return 23, nil
 }
 
+func Test_LocalActivity(t *testing.T) {
+	tester := NewWorkflowTester(workflowWithLocalActivity)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var r int
+	var errStr string
+	tester.WorkflowResult(&r, &errStr)
+	require.Zero(t, errStr)
+	require.Equal(t, 23, r)
+}
+
+func Test_LocalActivity_Mock(t *testing.T) {
+	tester := NewWorkflowTester(workflowWithLocalActivity)
+
+	tester.OnActivity(activity1, mock.Anything).Return(0, errors.New("error")).Once()
+	tester.OnActivity(activity1, mock.Anything).Return(42, nil)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var r int
+	tester.WorkflowResult(&r, nil)
+	require.Equal(t, 42, r)
+	tester.AssertExpectations(t)
+}
+
+func workflowWithLocalActivity(ctx workflow.Context) (int, error) {
+	return workflow.ExecuteLocalActivity[int](ctx, workflow.LocalActivityOptions{
+		RetryOptions: workflow.RetryOptions{
+			MaxAttempts: 2,
+		},
+	}, activity1).Get(ctx)
+}
+
+func Test_Activity_RegisteredRetryPolicy(t *testing.T) {
+	attempts := 0
+	failingActivity := func(ctx context.Context) (int, error) {
+		attempts++
+		return 0, errors.New("activity failed")
+	}
+
+	wf := func(ctx workflow.Context) (int, error) {
+		return workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{}, failingActivity).Get(ctx)
+	}
+
+	tester := NewWorkflowTester(wf)
+	tester.Registry().RegisterActivity(failingActivity, activity.WithDefaultRetryPolicy(workflow.RetryOptions{
+		MaxAttempts: 3,
+	}))
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var errStr string
+	tester.WorkflowResult(nil, &errStr)
+	require.Equal(t, "activity failed", errStr)
+	require.Equal(t, 3, attempts)
+}
+
+type permanentError struct{}
+
+func (permanentError) Error() string {
+	return "permanent"
+}
+
+func Test_Activity_NonRetryableErrorTypes(t *testing.T) {
+	attempts := 0
+	failingActivity := func(ctx context.Context) (int, error) {
+		attempts++
+		return 0, fmt.Errorf("activity failed: %w", &permanentError{})
+	}
+
+	wf := func(ctx workflow.Context) (int, error) {
+		_, err := workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{
+			RetryOptions: workflow.RetryOptions{
+				MaxAttempts:            3,
+				NonRetryableErrorTypes: []string{"*tester.permanentError"},
+			},
+		}, failingActivity).Get(ctx)
+
+		var f *workflow.Failure
+		if errors.As(err, &f) {
+			return f.Attempt, nil
+		}
+
+		return 0, err
+	}
+
+	tester := NewWorkflowTester(wf)
+	tester.Registry().RegisterActivity(failingActivity)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var attempt int
+	var errStr string
+	tester.WorkflowResult(&attempt, &errStr)
+	require.Empty(t, errStr)
+	require.Equal(t, 1, attempt)
+	require.Equal(t, 1, attempts)
+}
+
 func Test_Activity_LongRunning(t *testing.T) {
 	tester := NewWorkflowTester(workflowLongRunningActivity)
 	tester.Registry().RegisterActivity(activityLongRunning)
@@ -170,6 +281,38 @@ func Test_Timer(t *testing.T) {
 	require.True(t, e.Equal(wr.T2), "expected %v, got %v", e, wr.T2)
 }
 
+func Test_ContinueAsNew(t *testing.T) {
+	tester := NewWorkflowTester(workflowContinueAsNew)
+	start := tester.Now()
+
+	tester.Execute(0)
+
+	require.True(t, tester.WorkflowFinished())
+	var wr int
+	var werr string
+	tester.WorkflowResult(&wr, &werr)
+	require.Empty(t, werr)
+	require.Equal(t, 3, wr)
+
+	// Timers of previous executions did not fire in the later ones
+	require.Equal(t, start.Add(3*time.Minute), tester.Now())
+}
+
+func workflowContinueAsNew(ctx workflow.Context, n int) (int, error) {
+	if n > 0 {
+		workflow.ScheduleTimer(ctx, time.Minute).Get(ctx)
+	}
+
+	if n < 3 {
+		// Not waited for, and dropped when continuing as new
+		workflow.ScheduleTimer(ctx, 30*time.Second)
+
+		return 0, workflow.ContinueAsNew(ctx, n+1)
+	}
+
+	return n, nil
+}
+
 type timerResult struct {
 	T1 time.Time
 	T2 time.Time
@@ -249,3 +392,699 @@ func workflowSignal(ctx workflow.Context) (string, error) {
 
 	return val, nil
 }
+
+func Test_SelectSignalsAndTimer(t *testing.T) {
+	wf := func(ctx workflow.Context) ([]string, error) {
+		approve := workflow.NewSignalChannel[string](ctx, "approve")
+		reject := workflow.NewSignalChannel[string](ctx, "reject")
+
+		tctx, cancel := workflow.WithCancel(ctx)
+		defer cancel()
+		timeout := workflow.ScheduleTimer(tctx, time.Minute)
+
+		var received []string
+		for done := false; !done; {
+			workflow.Select(
+				ctx,
+				workflow.Receive(approve, func(ctx workflow.Context, v string, ok bool) {
+					received = append(received, "approve:"+v)
+				}),
+				workflow.Receive(reject, func(ctx workflow.Context, v string, ok bool) {
+					received = append(received, "reject:"+v)
+				}),
+				workflow.Await(timeout, func(ctx workflow.Context, f workflow.Future[struct{}]) {
+					received = append(received, "timeout")
+					done = true
+				}),
+			)
+		}
+
+		return received, nil
+	}
+
+	tester := NewWorkflowTester(wf)
+	tester.ScheduleCallback(5*time.Second, func() {
+		tester.SignalWorkflow("reject", "a")
+	})
+	tester.ScheduleCallback(10*time.Second, func() {
+		tester.SignalWorkflow("approve", "b")
+	})
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	var wr []string
+	var werr string
+	tester.WorkflowResult(&wr, &werr)
+	require.Empty(t, werr)
+	require.Equal(t, []string{"reject:a", "approve:b", "timeout"}, wr)
+}
+
+func Test_State(t *testing.T) {
+	wf := func(ctx workflow.Context) (int64, error) {
+		if _, err := workflow.SetState(ctx, "space", "key", "value").Get(ctx); err != nil {
+			return 0, err
+		}
+
+		v, err := workflow.GetState[string](ctx, "space", "key").Get(ctx)
+		if err != nil || v != "value" {
+			return 0, errors.New("unexpected state value")
+		}
+
+		workflow.IncrementState(ctx, "space", "counter", 40).Get(ctx)
+		workflow.IncrementState(ctx, "space", "counter", 2).Get(ctx)
+
+		return workflow.GetState[int64](ctx, "space", "counter").Get(ctx)
+	}
+
+	tester := NewWorkflowTester(wf)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var wr int64
+	var werr string
+	tester.WorkflowResult(&wr, &werr)
+	require.Empty(t, werr)
+	require.Equal(t, int64(42), wr)
+}
+
+func Test_Session(t *testing.T) {
+	wf := func(ctx workflow.Context) (int, error) {
+		s, err := workflow.CreateSession(ctx).Get(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		r, err := workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{Session: s}, activity1).Get(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = workflow.CompleteSession(ctx, s).Get(ctx)
+		return r, err
+	}
+
+	tester := NewWorkflowTester(wf)
+	tester.Registry().RegisterActivity(activity1)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var wr int
+	var werr string
+	tester.WorkflowResult(&wr, &werr)
+	require.Empty(t, werr)
+	require.Equal(t, 23, wr)
+}
+
+func Test_Stream(t *testing.T) {
+	size := stream.ChunkSize + 42
+
+	a := func(ctx context.Context) (workflow.Stream, error) {
+		w, err := activity.NewStream(ctx)
+		if err != nil {
+			return workflow.Stream{}, err
+		}
+
+		if _, err := w.Write(bytes.Repeat([]byte("a"), size)); err != nil {
+			return workflow.Stream{}, err
+		}
+
+		if err := w.Close(); err != nil {
+			return workflow.Stream{}, err
+		}
+
+		return w.Stream(), nil
+	}
+
+	wf := func(ctx workflow.Context) (int, error) {
+		s, err := workflow.ExecuteActivity[workflow.Stream](ctx, workflow.DefaultActivityOptions, a).Get(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		if s.Chunks != 2 || s.Size != int64(size) {
+			return 0, errors.New("unexpected stream")
+		}
+
+		chunk, err := workflow.ReadStreamChunk(ctx, s, 1).Get(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := workflow.DeleteStream(ctx, s).Get(ctx); err != nil {
+			return 0, err
+		}
+
+		if _, err := workflow.ReadStreamChunk(ctx, s, 0).Get(ctx); err == nil {
+			return 0, errors.New("stream not deleted")
+		}
+
+		return len(chunk), nil
+	}
+
+	tester := NewWorkflowTester(wf)
+	tester.Registry().RegisterActivity(a)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var wr int
+	var werr string
+	tester.WorkflowResult(&wr, &werr)
+	require.Empty(t, werr)
+	require.Equal(t, 42, wr)
+}
+
+func Test_Tick(t *testing.T) {
+	tester := NewWorkflowTester(workflowTick)
+	start := tester.Now()
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	var wr []time.Time
+	tester.WorkflowResult(&wr, nil)
+	require.Len(t, wr, 3)
+	for i, tick := range wr {
+		e := start.Add(time.Duration(i+1) * time.Minute)
+		require.True(t, e.Equal(tick), "expected %v, got %v", e, tick)
+	}
+}
+
+func workflowTick(ctx workflow.Context) ([]time.Time, error) {
+	tctx, cancel := workflow.WithCancel(ctx)
+	defer cancel()
+
+	ticks := workflow.Tick(tctx, time.Minute)
+
+	r := make([]time.Time, 0)
+	for len(r) < 3 {
+		t, ok := ticks.Receive(ctx)
+		if !ok {
+			break
+		}
+
+		r = append(r, t)
+	}
+
+	return r, nil
+}
+
+func Test_RunRecurring(t *testing.T) {
+	tests := []struct {
+		name    string
+		overlap workflow.OverlapPolicy
+		runs    int
+	}{
+		// Runs take 90s and are due every minute, the workflow stops after 4m15s
+		{"skip", workflow.OverlapSkip, 2},
+		{"buffer one", workflow.OverlapBufferOne, 3},
+		{"cancel previous", workflow.OverlapCancelPrevious, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tester := NewWorkflowTester(workflowRecurring)
+			require.NoError(t, tester.Registry().RegisterWorkflow(workflowRecurringRun))
+
+			runs := 0
+			tester.ListenSubWorkflow(func(instance *workflow.Instance, name string) {
+				runs++
+			})
+
+			tester.Execute(tt.overlap)
+
+			require.True(t, tester.WorkflowFinished())
+			require.Equal(t, tt.runs, runs)
+		})
+	}
+}
+
+func workflowRecurring(ctx workflow.Context, overlap workflow.OverlapPolicy) error {
+	rctx, cancel := workflow.WithCancel(ctx)
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		workflow.Sleep(ctx, 4*time.Minute+15*time.Second)
+		cancel()
+	})
+
+	err := workflow.RunRecurring(rctx, workflow.RecurringOptions{
+		Interval:           time.Minute,
+		Overlap:            overlap,
+		SubWorkflowOptions: workflow.DefaultSubWorkflowOptions,
+	}, workflowRecurringRun)
+	if err != nil && rctx.Err() == nil {
+		return err
+	}
+
+	return nil
+}
+
+func workflowRecurringRun(ctx workflow.Context) error {
+	return workflow.Sleep(ctx, 90*time.Second)
+}
+
+func Test_RunCron(t *testing.T) {
+	tester := NewWorkflowTester(workflowCron)
+	require.NoError(t, tester.Registry().RegisterWorkflow(workflowRecurringRun))
+
+	var started []time.Time
+	tester.ListenSubWorkflow(func(instance *workflow.Instance, name string) {
+		started = append(started, tester.Now())
+	})
+
+	// Runs take 90s and are due two minutes after the previous run finished, the workflow stops after 12m
+	start := tester.Now()
+	tester.ScheduleCallback(5*time.Minute, func() {
+		tester.SignalWorkflow(workflow.CronPauseSignal, nil)
+	})
+	tester.ScheduleCallback(8*time.Minute, func() {
+		tester.SignalWorkflow(workflow.CronResumeSignal, nil)
+	})
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	require.Equal(t, []time.Duration{
+		2 * time.Minute,
+		// The run due at 5m30s is skipped, the schedule is paused at 5m and resumed at 8m
+		10 * time.Minute,
+	}, durationsSince(start, started))
+}
+
+func workflowCron(ctx workflow.Context) error {
+	cctx, cancel := workflow.WithCancel(ctx)
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		workflow.Sleep(ctx, 12*time.Minute)
+		cancel()
+	})
+
+	err := workflow.RunCron(cctx, workflow.CronOptions{
+		Schedule:           "@every 2m",
+		SubWorkflowOptions: workflow.DefaultSubWorkflowOptions,
+	}, workflowRecurringRun)
+	if err != nil && cctx.Err() == nil {
+		return err
+	}
+
+	return nil
+}
+
+func durationsSince(start time.Time, times []time.Time) []time.Duration {
+	r := make([]time.Duration, 0, len(times))
+	for _, t := range times {
+		r = append(r, t.Sub(start))
+	}
+
+	return r
+}
+
+func Test_ForEach(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxFailures int
+		results     []int
+		aborted     bool
+	}{
+		{"tolerate all", -1, []int{1, 0, 9, 0, 25}, false},
+		{"tolerate two", 2, []int{1, 0, 9, 0, 25}, false},
+		{"abort", 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tester := NewWorkflowTester(workflowForEach)
+			tester.Registry().RegisterActivity(activitySquare)
+
+			tester.Execute(tt.maxFailures)
+
+			require.True(t, tester.WorkflowFinished())
+
+			var r forEachResult
+			var errStr string
+			tester.WorkflowResult(&r, &errStr)
+			require.Zero(t, errStr)
+			require.Equal(t, tt.aborted, r.Aborted)
+			require.Contains(t, r.Failed, 1)
+
+			if tt.aborted {
+				// Activities for later items are not executed
+				require.Zero(t, r.Results[4])
+			} else {
+				require.Equal(t, tt.results, r.Results)
+				require.Equal(t, []int{1, 3}, r.Failed)
+			}
+		})
+	}
+}
+
+type forEachResult struct {
+	Results []int
+	Failed  []int
+	Aborted bool
+}
+
+func workflowForEach(ctx workflow.Context, maxFailures int) (forEachResult, error) {
+	results, err := workflow.ForEach(ctx, []int{1, -2, 3, -4, 5}, workflow.ForEachOptions{
+		ActivityOptions: workflow.ActivityOptions{
+			RetryOptions: workflow.RetryOptions{
+				MaxAttempts: 1,
+			},
+		},
+		MaxParallelism: 2,
+		MaxFailures:    maxFailures,
+	}, activitySquare)
+
+	r := forEachResult{Results: results}
+
+	var ferr *workflow.ForEachError
+	if errors.As(err, &ferr) {
+		for i := range results {
+			if _, ok := ferr.Errors[i]; ok {
+				r.Failed = append(r.Failed, i)
+			}
+		}
+
+		r.Aborted = ferr.Aborted
+	} else if err != nil {
+		return r, err
+	}
+
+	return r, nil
+}
+
+func activitySquare(ctx context.Context, i int) (int, error) {
+	if i < 0 {
+		return 0, errors.New("negative item")
+	}
+
+	return i * i, nil
+}
+
+func Test_SleepUntil(t *testing.T) {
+	tester := NewWorkflowTester(workflowSleepUntil)
+	start := tester.Now()
+
+	tester.Execute(start.Add(time.Hour))
+
+	require.True(t, tester.WorkflowFinished())
+
+	var wr time.Time
+	tester.WorkflowResult(&wr, nil)
+	e := start.Add(time.Hour)
+	require.True(t, e.Equal(wr), "expected %v, got %v", e, wr)
+}
+
+func workflowSleepUntil(ctx workflow.Context, until time.Time) (time.Time, error) {
+	if err := workflow.SleepUntil(ctx, until); err != nil {
+		return time.Time{}, err
+	}
+
+	return workflow.Now(ctx), nil
+}
+
+func Test_TimerJitter(t *testing.T) {
+	tester := NewWorkflowTester(workflowTimerJitter)
+	start := tester.Now()
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	var wr time.Time
+	tester.WorkflowResult(&wr, nil)
+	require.False(t, wr.Before(start.Add(time.Minute)))
+	require.True(t, wr.Before(start.Add(2*time.Minute)))
+}
+
+func workflowTimerJitter(ctx workflow.Context) (time.Time, error) {
+	if err := workflow.Sleep(ctx, time.Minute, workflow.WithJitter(time.Minute)); err != nil {
+		return time.Time{}, err
+	}
+
+	return workflow.Now(ctx), nil
+}
+
+type countingConverter struct {
+	to   int
+	from int
+}
+
+func (c *countingConverter) To(v interface{}) (payload.Payload, error) {
+	c.to++
+	return converter.DefaultConverter.To(v)
+}
+
+func (c *countingConverter) From(data payload.Payload, v interface{}) error {
+	c.from++
+	return converter.DefaultConverter.From(data, v)
+}
+
+func Test_Converter(t *testing.T) {
+	c := &countingConverter{}
+	tester := NewWorkflowTester(workflowWithActivity, WithConverter(c))
+
+	tester.Registry().RegisterActivity(activity1)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var wr int
+	tester.WorkflowResult(&wr, nil)
+	require.Equal(t, 23, wr)
+	require.Greater(t, c.to, 0)
+	require.Greater(t, c.from, 0)
+}
+
+func Test_EnvelopeConverter(t *testing.T) {
+	tester := NewWorkflowTester(workflowWithActivity, WithConverter(converter.NewEnvelopeConverter(converter.DefaultConverter, converter.NewGzipCodec(0))))
+
+	tester.Registry().RegisterActivity(activity1)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var wr int
+	tester.WorkflowResult(&wr, nil)
+	require.Equal(t, 23, wr)
+}
+
+func Test_TemporalConverter(t *testing.T) {
+	tester := NewWorkflowTester(workflowWithActivity, WithConverter(converter.NewTemporalConverter(converter.DefaultConverter)))
+
+	tester.Registry().RegisterActivity(activity1)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var wr int
+	tester.WorkflowResult(&wr, nil)
+	require.Equal(t, 23, wr)
+}
+
+func activityMultipleResults(ctx context.Context) (int, string, error) {
+	return 42, "hello", nil
+}
+
+func Test_Activity_MultipleResults(t *testing.T) {
+	wf := func(ctx workflow.Context) (int, string, error) {
+		r, err := workflow.ExecuteActivity[workflow.Tuple2[int, string]](ctx, workflow.DefaultActivityOptions, activityMultipleResults).Get(ctx)
+		if err != nil {
+			return 0, "", err
+		}
+
+		return r.V1, r.V2, nil
+	}
+
+	tester := NewWorkflowTester(wf)
+	tester.Registry().RegisterActivity(activityMultipleResults)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var wr workflow.Tuple2[int, string]
+	tester.WorkflowResult(&wr, nil)
+	require.Equal(t, 42, wr.V1)
+	require.Equal(t, "hello", wr.V2)
+}
+
+func Test_Activity_MultipleResults_Mocked(t *testing.T) {
+	wf := func(ctx workflow.Context) (string, error) {
+		r, err := workflow.ExecuteActivity[workflow.Tuple2[int, string]](ctx, workflow.DefaultActivityOptions, activityMultipleResults).Get(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		return r.V2, nil
+	}
+
+	tester := NewWorkflowTester(wf)
+	tester.OnActivity(activityMultipleResults, mock.Anything).Return(23, "mocked", nil)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var wr string
+	tester.WorkflowResult(&wr, nil)
+	require.Equal(t, "mocked", wr)
+	tester.AssertExpectations(t)
+}
+
+func Test_RunWorkflow(t *testing.T) {
+	tester := NewWorkflowTester(workflowWithActivity)
+	require.NoError(t, tester.Registry().RegisterActivity(activity1))
+
+	r, err := RunWorkflow[int](tester)
+	require.NoError(t, err)
+	require.Equal(t, 23, r)
+}
+
+func Test_RunWorkflow_Error(t *testing.T) {
+	wf := func(ctx workflow.Context) (int, error) {
+		return 0, errors.New("workflow failed")
+	}
+
+	_, err := RunWorkflow[int](NewWorkflowTester(wf))
+	require.EqualError(t, err, "workflow failed")
+}
+
+func Test_RunWorkflow_Blocked(t *testing.T) {
+	tester := NewWorkflowTester(workflowBlocked, func(o *options) {
+		o.TestTimeout = time.Millisecond * 10
+	})
+
+	_, err := RunWorkflow[int](tester)
+	require.Error(t, err)
+}
+
+type validatedOrder struct {
+	Item     string `validate:"required"`
+	Quantity int    `validate:"min=1"`
+}
+
+func workflowWithValidatedInput(ctx workflow.Context, order validatedOrder) (int, error) {
+	return order.Quantity, nil
+}
+
+func Test_Workflow_Validator(t *testing.T) {
+	tester := NewWorkflowTester(workflowWithValidatedInput)
+	require.NoError(t, tester.Registry().RegisterWorkflow(
+		workflowWithValidatedInput, workflow.WithValidator(validation.NewStructTagValidator())))
+
+	tester.Execute(validatedOrder{Item: "book"})
+
+	require.True(t, tester.WorkflowFinished())
+
+	var wr int
+	var werr string
+	tester.WorkflowResult(&wr, &werr)
+	require.Equal(t, "validating workflow inputs: validation failed: input 0: Quantity: must be at least 1", werr)
+}
+
+func Test_AssertWorkflowResultAndSignals(t *testing.T) {
+	wf := func(ctx workflow.Context, target string) (int, error) {
+		if _, err := workflow.SignalWorkflow(ctx, target, "done", 42).Get(ctx); err != nil {
+			return 0, err
+		}
+
+		return 23, nil
+	}
+
+	tester := NewWorkflowTester(wf)
+
+	tester.Execute("other-instance")
+
+	// Signals to instances not executed by the tester fail, but are recorded
+	tester.AssertWorkflowResult(t, 0, "workflow instance not found")
+	tester.AssertSignalSent(t, "other-instance", "done", 42)
+	tester.AssertSignalSent(t, "other-instance", "done", nil)
+
+	mt := &testing.T{}
+	tester.AssertSignalSent(mt, "other-instance", "done", 23)
+	require.True(t, mt.Failed())
+
+	mt = &testing.T{}
+	tester.AssertWorkflowResult(mt, 23, "")
+	require.True(t, mt.Failed())
+
+	tester = NewWorkflowTester(workflowWithActivity)
+	tester.Registry().RegisterActivity(activity1)
+
+	tester.Execute()
+
+	tester.AssertWorkflowResult(t, 23, "")
+}
+
+func Test_QueryWorkflow(t *testing.T) {
+	tester := NewWorkflowTester(workflowQuery)
+
+	var status string
+	tester.ScheduleCallback(30*time.Second, func() {
+		require.NoError(t, tester.QueryWorkflow("status", &status))
+	})
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	require.Equal(t, "waiting", status)
+
+	require.NoError(t, tester.QueryWorkflow("status", &status))
+	require.Equal(t, "done", status)
+
+	require.Error(t, tester.QueryWorkflow("unknown", &status))
+}
+
+func workflowQuery(ctx workflow.Context) error {
+	status := "waiting"
+	if err := workflow.HandleQuery(ctx, "status", func() (string, error) {
+		return status, nil
+	}); err != nil {
+		return err
+	}
+
+	workflow.ScheduleTimer(ctx, time.Minute).Get(ctx)
+	status = "done"
+
+	return nil
+}
+
+func Test_NewGUID(t *testing.T) {
+	tester := NewWorkflowTester(workflowNewGUID)
+
+	var recorded string
+	tester.OnActivity(recordGUID, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		recorded = args.String(1)
+	}).Return(nil)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	var wr string
+	var werr string
+	tester.WorkflowResult(&wr, &werr)
+	require.Empty(t, werr)
+	require.Len(t, wr, 36)
+
+	// The GUID is the same after replaying the workflow for the activity result
+	require.Equal(t, recorded, wr)
+}
+
+func workflowNewGUID(ctx workflow.Context) (string, error) {
+	id, err := workflow.NewGUID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, recordGUID, id).Get(ctx); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func recordGUID(ctx context.Context, id string) error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/trace"
+)
+
+type noopTracer struct{}
+
+var _ trace.Tracer = (*noopTracer)(nil)
+
+// NewNoopTracer returns a tracer that does not record any spans
+func NewNoopTracer() trace.Tracer {
+	return &noopTracer{}
+}
+
+func (*noopTracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, trace.Span) {
+	return ctx, &noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (*noopSpan) SetAttributes(attributes map[string]string) {}
+
+func (*noopSpan) RecordError(err error) {}
+
+func (*noopSpan) End() {}
+
+type attributeTracer struct {
+	tracer     trace.Tracer
+	attributes map[string]string
+}
+
+// WithAttributes returns a tracer that adds the given attributes to every span it starts
+func WithAttributes(tracer trace.Tracer, attributes map[string]string) trace.Tracer {
+	return &attributeTracer{
+		tracer:     tracer,
+		attributes: attributes,
+	}
+}
+
+func (t *attributeTracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, trace.Span) {
+	merged := make(map[string]string, len(t.attributes)+len(attributes))
+	for k, v := range t.attributes {
+		merged[k] = v
+	}
+
+	for k, v := range attributes {
+		merged[k] = v
+	}
+
+	return t.tracer.Start(ctx, name, merged)
+}
+
+func (t *attributeTracer) Inject(ctx context.Context) map[string]string {
+	return Inject(t.tracer, ctx)
+}
+
+func (t *attributeTracer) Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return Extract(t.tracer, ctx, carrier)
+}
+
+// Inject returns the trace context of the span in ctx, or nil if the tracer cannot propagate traces
+func Inject(tracer trace.Tracer, ctx context.Context) map[string]string {
+	p, ok := tracer.(trace.Propagator)
+	if !ok {
+		return nil
+	}
+
+	carrier := p.Inject(ctx)
+	if len(carrier) == 0 {
+		return nil
+	}
+
+	return carrier
+}
+
+// Extract returns ctx with the remote span of the given trace context, if the tracer can propagate traces
+func Extract(tracer trace.Tracer, ctx context.Context, carrier map[string]string) context.Context {
+	p, ok := tracer.(trace.Propagator)
+	if !ok || len(carrier) == 0 {
+		return ctx
+	}
+
+	return p.Extract(ctx, carrier)
+}
@@ -2,6 +2,10 @@ package worker
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"log"
 	"sync"
 	"time"
@@ -10,13 +14,39 @@ import (
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/activity"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/state"
+	"github.com/cschleiden/go-workflows/internal/stream"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/cschleiden/go-workflows/metrics"
 )
 
 type ActivityWorker interface {
 	Start(context.Context) error
 	WaitForCompletion() error
+
+	// Shutdown stops polling and waits until the activities already polled have finished, or the context is
+	// canceled
+	Shutdown(ctx context.Context) error
+
+	// Pause stops polling for new activity tasks and waits until the activities already started have finished,
+	// or the context is canceled. The worker stays paused until Resume is called.
+	Pause(ctx context.Context) error
+
+	// Resume continues polling for activity tasks after Pause
+	Resume()
+
+	// Status returns the activities currently executed and the status of the pollers
+	Status() ActivityWorkerStatus
+}
+
+// queuedActivity is a polled activity task waiting for a free slot
+type queuedActivity struct {
+	task *task.Activity
+
+	// stopWaiting stops extending the lock of the waiting task
+	stopWaiting context.CancelFunc
 }
 
 type activityWorker struct {
@@ -24,13 +54,39 @@ type activityWorker struct {
 
 	options *Options
 
-	activityTaskQueue    chan *task.Activity
+	registry *workflow.Registry
+
+	// capabilities restrict the tasks the worker polls for, if set
+	capabilities *backend.WorkerCapabilities
+
+	activityTaskQueue    *dispatchQueue[*queuedActivity]
 	activityTaskExecutor activity.Executor
 
+	// heartbeatInterval is how often the locks of activity tasks are extended
+	heartbeatInterval time.Duration
+
+	pollers *pollerScaler
+
+	// fixedPollers is the number of pollers started if they are not scaled automatically
+	fixedPollers int
+
+	executing *executingTasks
+
+	// pause stops polling while the worker is paused
+	pause *pauseGate
+
 	logger *log.Logger
 
 	wg *sync.WaitGroup
 
+	// stopPolling stops the pollers without canceling the tasks already polled
+	stopPolling context.CancelFunc
+
+	pollWg sync.WaitGroup
+
+	// dispatcherDone is closed when the dispatcher has handed out all polled tasks
+	dispatcherDone chan struct{}
+
 	clock clock.Clock
 }
 
@@ -40,8 +96,14 @@ func NewActivityWorker(backend backend.Backend, registry *workflow.Registry, clo
 
 		options: options,
 
-		activityTaskQueue:    make(chan *task.Activity),
-		activityTaskExecutor: activity.NewExecutor(backend.Logger(), registry),
+		registry: registry,
+
+		activityTaskQueue:    newDispatchQueue[*queuedActivity](options.ActivityDispatchQueueSize, DispatchOverflowBlock),
+		activityTaskExecutor: activity.NewExecutor(backend.Logger(), options.PayloadConverter(backend), backend.Metrics(), backend.Tracer(), registry),
+
+		pause: newPauseGate(),
+
+		executing: newExecutingTasks(),
 
 		logger: log.Default(),
 
@@ -52,11 +114,73 @@ func NewActivityWorker(backend backend.Backend, registry *workflow.Registry, clo
 }
 
 func (aw *activityWorker) Start(ctx context.Context) error {
-	for i := 0; i <= aw.options.ActivityPollers; i++ {
-		go aw.runPoll(ctx)
+	lp, ok := aw.backend.(backend.LockTimeoutProvider)
+
+	var activityLockTimeout time.Duration
+	if ok {
+		_, activityLockTimeout = lp.LockTimeouts()
 	}
 
-	go aw.runDispatcher(ctx)
+	aw.heartbeatInterval = aw.options.activityHeartbeatInterval(activityLockTimeout)
+
+	if ok {
+		if err := validateHeartbeatInterval("activity", aw.heartbeatInterval, activityLockTimeout); err != nil {
+			return err
+		}
+	}
+
+	if len(aw.options.ActivityQueues) > 0 {
+		if _, ok := aw.backend.(backend.ActivityQueueProvider); !ok {
+			return errors.New("backend does not support activity queues")
+		}
+	}
+
+	if aw.options.RegisteredOnly {
+		if _, ok := aw.backend.(backend.CapabilityTaskProvider); !ok {
+			return errors.New("backend does not support restricting workers to registered activities")
+		}
+
+		// With a dynamic activity the worker is able to execute any activity
+		if _, _, ok := aw.registry.GetDynamicActivity(); !ok {
+			aw.capabilities = &backend.WorkerCapabilities{Activities: aw.registry.ActivityNames()}
+
+			if len(aw.capabilities.Activities) == 0 {
+				// Nothing registered, an empty list would not restrict the tasks at all
+				return nil
+			}
+		}
+	}
+
+	pollCtx, stopPolling := context.WithCancel(ctx)
+	aw.stopPolling = stopPolling
+
+	if aw.options.ActivityPollerAutoScale != nil {
+		aw.pollers = newPollerScaler(*aw.options.ActivityPollerAutoScale, aw.backend.Logger(), func(stop <-chan struct{}) {
+			aw.runPoll(ctx, pollCtx, stop)
+		}, aw.backlog)
+
+		aw.pollWg.Add(1)
+		go func() {
+			defer aw.pollWg.Done()
+			aw.pollers.run(pollCtx, aw.options.ActivityPollers)
+		}()
+	} else {
+		for i := 0; i <= aw.options.ActivityPollers; i++ {
+			aw.pollWg.Add(1)
+			go func() {
+				defer aw.pollWg.Done()
+				aw.runPoll(ctx, pollCtx, nil)
+			}()
+		}
+
+		aw.fixedPollers = aw.options.ActivityPollers + 1
+	}
+
+	aw.dispatcherDone = make(chan struct{})
+	go func() {
+		defer close(aw.dispatcherDone)
+		aw.runDispatcher(ctx)
+	}()
 
 	return nil
 }
@@ -67,84 +191,277 @@ func (aw *activityWorker) WaitForCompletion() error {
 	return nil
 }
 
-func (aw *activityWorker) runPoll(ctx context.Context) {
+func (aw *activityWorker) Shutdown(ctx context.Context) error {
+	if aw.dispatcherDone == nil {
+		// Not started, or nothing to poll for
+		return nil
+	}
+
+	aw.stopPolling()
+
+	return waitContext(ctx, func() {
+		aw.pollWg.Wait()
+
+		// All polled tasks are in the queue now, let the dispatcher hand them out before it stops
+		aw.activityTaskQueue.close()
+		<-aw.dispatcherDone
+
+		aw.wg.Wait()
+	})
+}
+
+func (aw *activityWorker) Pause(ctx context.Context) error {
+	aw.pause.pause()
+
+	return aw.pause.waitIdle(ctx)
+}
+
+func (aw *activityWorker) Resume() {
+	aw.pause.resume()
+}
+
+func (aw *activityWorker) Status() ActivityWorkerStatus {
+	pollers := pollerStatus(aw.pollers, aw.fixedPollers, aw.activityTaskQueue)
+	pollers.Paused = aw.pause.isPaused()
+
+	return ActivityWorkerStatus{
+		Activities: aw.executing.list(),
+		Pollers:    pollers,
+	}
+}
+
+// runPoll polls for tasks until pollCtx is canceled or stop is closed. Polled tasks are handed to the dispatcher
+// as long as ctx is not canceled.
+func (aw *activityWorker) runPoll(ctx, pollCtx context.Context, stop <-chan struct{}) {
 	for {
 		select {
-		case <-ctx.Done():
+		case <-pollCtx.Done():
+			return
+		case <-stop:
 			return
 		default:
-			task, err := aw.poll(ctx, 30*time.Second)
+			gateCtx, cancelPoll, ok := aw.pause.acquire(pollCtx, stop)
+			if !ok {
+				return
+			}
+
+			if !aw.activityTaskQueue.acquire(pollCtx) {
+				cancelPoll()
+				aw.pause.release()
+				return
+			}
+
+			pollStart := time.Now()
+			task, err := aw.poll(gateCtx, aw.options.activityPollTimeout())
+			recordPoll(aw.backend.Metrics(), metrics.ActivityTaskPollDuration, pollStart, task != nil)
+			cancelPoll()
+			if aw.pollers != nil {
+				aw.pollers.record(err == nil && task != nil)
+			}
+
 			if err != nil {
 				log.Println("error while polling for activity task:", err)
 			} else if task != nil {
-				aw.activityTaskQueue <- task
+				// Keep the task locked while it waits for a free slot
+				waitCtx, stopWaiting := context.WithCancel(ctx)
+				go aw.heartbeatTask(waitCtx, task, newActivityHeartbeats(), 0)
+
+				// The dispatcher releases the gate once the task has been handled
+				aw.activityTaskQueue.push(ctx, &queuedActivity{task: task, stopWaiting: stopWaiting})
+				continue
 			}
+
+			aw.activityTaskQueue.cancel()
+			aw.pause.release()
 		}
 	}
 }
 
 func (aw *activityWorker) runDispatcher(ctx context.Context) {
-	var sem chan struct{}
-	if aw.options.MaxParallelActivityTasks > 0 {
-		sem = make(chan struct{}, aw.options.MaxParallelActivityTasks)
+	limiter, tuner := newTaskLimiter(ctx, aw.options.ActivitySlotSupplier, aw.options.MaxParallelActivityTasks, aw.options.AutoTuneActivityTasks, aw.backend.Logger(), aw.backlog)
+	rate := newRateLimiter(aw.options.ActivityTasksPerSecond)
+	names := newNameLimits(aw.options.MaxParallelActivityTasksByName)
+
+	start := func(qa *queuedActivity, nl *nameLimit) bool {
+		if !rate.wait(ctx) || !limiter.ReserveSlot(ctx) {
+			if nl != nil {
+				nl.running.ReleaseSlot()
+			}
+
+			return false
+		}
+
+		aw.startTask(qa, limiter, nl, tuner)
+		return true
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case task := <-aw.activityTaskQueue:
-			if sem != nil {
-				sem <- struct{}{}
+		case qa, ok := <-aw.activityTaskQueue.tasks:
+			if !ok {
+				// Shutting down, all polled tasks have been dispatched
+				return
 			}
 
-			aw.wg.Add(1)
-			go func() {
-				defer aw.wg.Done()
+			aw.activityTaskQueue.taken()
 
-				// Create new context to allow activities to complete when root context is canceled
-				taskCtx := context.Background()
-				aw.handleTask(taskCtx, task)
+			nl := names.get(activityName(qa.task))
+			if nl != nil && !nl.running.tryReserveSlot() {
+				if nl.waiting.tryReserveSlot() {
+					// Wait for the activity's limit without holding up the tasks of other activities
+					aw.wg.Add(1)
+					go func() {
+						defer aw.wg.Done()
 
-				if sem != nil {
-					<-sem
+						ok := nl.running.ReserveSlot(ctx)
+						nl.waiting.ReleaseSlot()
+
+						if !ok || !start(qa, nl) {
+							aw.pause.release()
+						}
+					}()
+
+					continue
 				}
-			}()
+
+				// Too many tasks of this activity are waiting already
+				if !nl.running.ReserveSlot(ctx) {
+					aw.pause.release()
+					return
+				}
+			}
+
+			if !start(qa, nl) {
+				aw.pause.release()
+				return
+			}
+		}
+	}
+}
+
+// startTask processes the given task in the background. The reserved slots are released once it is done.
+func (aw *activityWorker) startTask(qa *queuedActivity, limiter SlotSupplier, nl *nameLimit, tuner *tuner) {
+	task := qa.task
+	qa.stopWaiting()
+
+	aw.wg.Add(1)
+	go func() {
+		defer aw.wg.Done()
+		defer aw.pause.release()
+		defer limiter.ReleaseSlot()
+		if nl != nil {
+			defer nl.running.ReleaseSlot()
+		}
+
+		start := time.Now()
+		recordScheduleToStart(aw.backend.Metrics(), metrics.ActivityTaskScheduleToStart, activityTags(task), task.ScheduledAt, start)
+
+		// Create new context to allow activities to complete when root context is canceled
+		taskCtx := context.Background()
+		if store, ok := aw.backend.(state.Store); ok {
+			// Allow activities to stream large results
+			taskCtx = stream.WithStore(taskCtx, store)
 		}
+
+		aw.handleTask(taskCtx, task)
+
+		if tuner != nil {
+			tuner.observe(time.Since(start))
+		}
+	}()
+}
+
+// backlog returns the number of pending activity tasks, if the backend reports it
+func (aw *activityWorker) backlog(ctx context.Context) (int64, bool) {
+	r, ok := aw.backend.(backend.BacklogReporter)
+	if !ok {
+		return 0, false
 	}
+
+	stats, err := r.GetBacklogStats(ctx)
+	if err != nil {
+		aw.backend.Logger().Error("could not get backlog stats", "error", err)
+		return 0, false
+	}
+
+	return stats.PendingActivityTasks, true
 }
 
 func (aw *activityWorker) handleTask(ctx context.Context, task *task.Activity) {
-	heartbeatCtx, cancelHeartbeat := context.WithCancel(ctx)
+	executing := ExecutingTask{
+		InstanceID:  task.WorkflowInstance.InstanceID,
+		ExecutionID: task.WorkflowInstance.ExecutionID,
+		ActivityID:  task.ID,
+		StartedAt:   time.Now(),
+	}
 
-	go func(ctx context.Context) {
-		t := time.NewTicker(30 * time.Second)
-		defer t.Stop()
+	if a, ok := task.Event.Attributes.(*history.ActivityScheduledAttributes); ok {
+		executing.Name = a.Name
+		executing.Attempt = a.Attempt
+	}
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-t.C:
-				if err := aw.backend.ExtendActivityTask(ctx, task.ID); err != nil {
-					aw.logger.Panic(err)
-				}
-			}
+	defer aw.executing.start(executing, nil)()
+
+	heartbeats := newActivityHeartbeats()
+
+	var heartbeatTimeout time.Duration
+	if a, ok := task.Event.Attributes.(*history.ActivityScheduledAttributes); ok {
+		heartbeatTimeout = aw.activityTaskExecutor.HeartbeatTimeout(a)
+	}
+
+	heartbeatCtx, cancelHeartbeat := context.WithCancel(ctx)
+	go aw.heartbeatTask(heartbeatCtx, task, heartbeats, heartbeatTimeout)
+
+	done := watchSlowTask(aw.options.SlowActivityThreshold, func(elapsed time.Duration) {
+		var name string
+		var attempt int
+		if a, ok := task.Event.Attributes.(*history.ActivityScheduledAttributes); ok {
+			name = a.Name
+			attempt = a.Attempt
 		}
-	}(heartbeatCtx)
 
-	result, err := aw.activityTaskExecutor.ExecuteActivity(ctx, task)
+		aw.backend.Logger().Warn("Activity is running longer than the slow activity threshold",
+			"instance_id", task.WorkflowInstance.InstanceID,
+			"execution_id", task.WorkflowInstance.ExecutionID,
+			"activity", name,
+			"activity_id", task.ID,
+			"attempt", attempt,
+			"elapsed", elapsed,
+			"threshold", aw.options.SlowActivityThreshold,
+		)
+
+		aw.backend.Metrics().Counter(metrics.SlowActivities, activityTags(task), 1)
+	})
+
+	start := time.Now()
+	result, err := aw.executeActivity(activity.WithHeartbeatFunc(ctx, heartbeats.record), task)
 
+	done()
 	cancelHeartbeat()
 
+	recordDuration(aw.backend.Metrics(), metrics.ActivityTaskExecutionDuration, activityTags(task), start)
+	if err != nil {
+		aw.backend.Metrics().Counter(metrics.ActivityTasksFailed, activityTags(task), 1)
+	}
+
 	var event history.Event
 
 	if err != nil {
+		aw.recordError(ctx, task, err)
+
+		failure := history.NewFailure(aw.backend.Converter(), err, aw.clock.Now())
+		if a, ok := task.Event.Attributes.(*history.ActivityScheduledAttributes); ok {
+			failure.ActivityName = a.Name
+		}
+
 		event = history.NewPendingEvent(
 			aw.clock.Now(),
 			history.EventType_ActivityFailed,
 			&history.ActivityFailedAttributes{
-				Reason: err.Error(),
+				Reason:  err.Error(),
+				Failure: failure,
 			},
 			history.ScheduleEventID(task.Event.ScheduleEventID),
 		)
@@ -163,11 +480,147 @@ func (aw *activityWorker) handleTask(ctx context.Context, task *task.Activity) {
 	}
 }
 
-func (aw *activityWorker) poll(ctx context.Context, timeout time.Duration) (*task.Activity, error) {
-	if timeout == 0 {
-		timeout = 30 * time.Second
+// heartbeatTask extends the lock of the given task until the context is canceled. The details of heartbeats
+// recorded by the activity are stored with the next extension. Activities with a heartbeat timeout only keep
+// their lock while they record heartbeats, if the worker stalls, the lock expires and another worker picks up
+// the task.
+func (aw *activityWorker) heartbeatTask(ctx context.Context, task *task.Activity, heartbeats *activityHeartbeats, heartbeatTimeout time.Duration) {
+	t := time.NewTicker(aw.heartbeatInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			last, details := heartbeats.take()
+			if heartbeatTimeout > 0 && time.Since(last) >= heartbeatTimeout {
+				continue
+			}
+
+			if err := aw.extendActivityTask(ctx, task.ID, details); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				aw.logger.Panic(err)
+			}
+		}
+	}
+}
+
+// extendActivityTask extends the lock of the task, and stores the given heartbeat details if there are any and
+// the backend supports it
+func (aw *activityWorker) extendActivityTask(ctx context.Context, activityID string, details payload.Payload) error {
+	if h, ok := aw.backend.(backend.ActivityHeartbeater); ok && details != nil {
+		return h.HeartbeatActivityTask(ctx, activityID, details)
+	}
+
+	return aw.backend.ExtendActivityTask(ctx, activityID)
+}
+
+// activityHeartbeats collects the heartbeats an activity records between extensions of its lock
+type activityHeartbeats struct {
+	mu      sync.Mutex
+	last    time.Time
+	details payload.Payload
+}
+
+func newActivityHeartbeats() *activityHeartbeats {
+	return &activityHeartbeats{last: time.Now()}
+}
+
+func (h *activityHeartbeats) record(details payload.Payload) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.last = time.Now()
+	if details != nil {
+		h.details = details
 	}
+}
+
+// take returns the time of the last heartbeat, and the latest details recorded since the previous call
+func (h *activityHeartbeats) take() (time.Time, payload.Payload) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
+	details := h.details
+	h.details = nil
+
+	return h.last, details
+}
+
+// recordError stores the activity error as the last error of the workflow instance, if supported by the backend
+func (aw *activityWorker) recordError(ctx context.Context, task *task.Activity, err error) {
+	r, ok := aw.backend.(backend.InstanceErrorRecorder)
+	if !ok {
+		return
+	}
+
+	var name string
+	if a, ok := task.Event.Attributes.(*history.ActivityScheduledAttributes); ok {
+		name = a.Name
+	}
+
+	if err := r.RecordInstanceError(ctx, task.WorkflowInstance, &backend.InstanceError{
+		Message:   err.Error(),
+		Source:    backend.InstanceErrorSourceActivity,
+		Name:      name,
+		Timestamp: aw.clock.Now(),
+	}); err != nil {
+		aw.backend.Logger().Error("recording activity error", "error", err)
+	}
+}
+
+// executeActivity executes the activity of the given task, or returns a cached result if the activity
+// is memoized and a result is available.
+func (aw *activityWorker) executeActivity(ctx context.Context, task *task.Activity) (payload.Payload, error) {
+	a := task.Event.Attributes.(*history.ActivityScheduledAttributes)
+
+	cache, ok := aw.backend.(backend.ActivityResultCache)
+	if !ok || a.MemoizeFor <= 0 {
+		return aw.activityTaskExecutor.ExecuteActivity(ctx, task)
+	}
+
+	key := memoizationKey(a)
+
+	result, found, err := cache.GetActivityResult(ctx, key)
+	if err != nil {
+		aw.backend.Logger().Error("getting cached activity result", "activity", a.Name, "error", err)
+	} else if found {
+		aw.backend.Logger().Debug("Using cached activity result", "activity", a.Name, "activity_id", task.ID)
+		return result, nil
+	}
+
+	result, err = aw.activityTaskExecutor.ExecuteActivity(ctx, task)
+	if err != nil {
+		// Only successful executions are cached
+		return result, err
+	}
+
+	if err := cache.StoreActivityResult(ctx, key, result, a.MemoizeFor); err != nil {
+		aw.backend.Logger().Error("caching activity result", "activity", a.Name, "error", err)
+	}
+
+	return result, nil
+}
+
+// memoizationKey identifies an activity execution by the name of the activity and its inputs
+func memoizationKey(a *history.ActivityScheduledAttributes) string {
+	h := sha256.New()
+	h.Write([]byte(a.Name))
+
+	for _, input := range a.Inputs {
+		// Prefix every input with its length, to avoid collisions between different splits of the same bytes
+		binary.Write(h, binary.LittleEndian, int64(len(input)))
+		h.Write(input)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (aw *activityWorker) poll(ctx context.Context, timeout time.Duration) (*task.Activity, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -177,7 +630,13 @@ func (aw *activityWorker) poll(ctx context.Context, timeout time.Duration) (*tas
 	done := make(chan struct{})
 
 	go func() {
-		task, err = aw.backend.GetActivityTask(ctx)
+		if cp, ok := aw.backend.(backend.CapabilityTaskProvider); ok && aw.capabilities != nil {
+			task, err = cp.GetActivityTaskForCapabilities(ctx, *aw.capabilities, aw.options.ActivityQueues)
+		} else if qp, ok := aw.backend.(backend.ActivityQueueProvider); ok && len(aw.options.ActivityQueues) > 0 {
+			task, err = qp.GetActivityTaskFromQueues(ctx, aw.options.ActivityQueues)
+		} else {
+			task, err = aw.backend.GetActivityTask(ctx)
+		}
 		close(done)
 	}()
 
@@ -188,3 +647,27 @@ func (aw *activityWorker) poll(ctx context.Context, timeout time.Duration) (*tas
 		return task, err
 	}
 }
+
+// activityTags returns the metric tags for the given activity task. The queue is omitted for the default queue.
+func activityTags(t *task.Activity) map[string]string {
+	tags := map[string]string{}
+
+	if a, ok := t.Event.Attributes.(*history.ActivityScheduledAttributes); ok {
+		tags["activity"] = a.Name
+
+		if a.Queue != backend.DefaultActivityQueue {
+			tags["queue"] = a.Queue
+		}
+	}
+
+	return tags
+}
+
+// activityName returns the name of the activity the given task executes
+func activityName(t *task.Activity) string {
+	if a, ok := t.Event.Attributes.(*history.ActivityScheduledAttributes); ok {
+		return a.Name
+	}
+
+	return ""
+}
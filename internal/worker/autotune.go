@@ -0,0 +1,186 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/log"
+)
+
+// AutoTuneOptions configure the adaptive adjustment of the number of concurrently processed tasks
+type AutoTuneOptions struct {
+	// MinParallelTasks is the lower bound for the number of concurrently processed tasks. Defaults to 1.
+	MinParallelTasks int
+
+	// MaxParallelTasks is the upper bound for the number of concurrently processed tasks. Required.
+	MaxParallelTasks int
+
+	// Interval determines how often the limit is adjusted. Defaults to 5 seconds.
+	Interval time.Duration
+
+	// MaxCPUUtilization is the process CPU utilization, as a fraction of all available CPUs, above which the
+	// limit is reduced. Defaults to 0.8. Ignored on platforms where the CPU utilization cannot be measured.
+	MaxCPUUtilization float64
+
+	// LatencyTolerance is the factor by which the average task latency can exceed the lowest observed
+	// latency before the limit is reduced. Defaults to 2.
+	LatencyTolerance float64
+}
+
+func (o AutoTuneOptions) withDefaults() AutoTuneOptions {
+	if o.MinParallelTasks <= 0 {
+		o.MinParallelTasks = 1
+	}
+
+	if o.MaxParallelTasks < o.MinParallelTasks {
+		o.MaxParallelTasks = o.MinParallelTasks
+	}
+
+	if o.Interval <= 0 {
+		o.Interval = 5 * time.Second
+	}
+
+	if o.MaxCPUUtilization <= 0 {
+		o.MaxCPUUtilization = 0.8
+	}
+
+	if o.LatencyTolerance <= 1 {
+		o.LatencyTolerance = 2
+	}
+
+	return o
+}
+
+// tuner periodically adjusts the limit of a concurrencyLimiter. The limit is reduced when the CPU is under
+// pressure or task latency degrades, and increased while the limit is reached and the backend reports a
+// backlog of tasks.
+type tuner struct {
+	options AutoTuneOptions
+	limiter *concurrencyLimiter
+	logger  log.Logger
+
+	// backlog returns the number of pending tasks, ok is false if the backlog is unknown
+	backlog func(ctx context.Context) (pending int64, ok bool)
+
+	cpu *cpuSampler
+
+	mu           sync.Mutex
+	tasks        int
+	totalLatency time.Duration
+
+	// baseline is the lowest observed average task latency, slowly decaying towards the current average
+	baseline time.Duration
+}
+
+func newTuner(options AutoTuneOptions, initialLimit int, logger log.Logger, backlog func(context.Context) (int64, bool)) *tuner {
+	options = options.withDefaults()
+
+	if initialLimit < options.MinParallelTasks || initialLimit > options.MaxParallelTasks {
+		initialLimit = options.MinParallelTasks
+	}
+
+	return &tuner{
+		options: options,
+		limiter: newConcurrencyLimiter(initialLimit),
+		logger:  logger,
+		backlog: backlog,
+		cpu:     newCPUSampler(),
+	}
+}
+
+// observe records the latency of a processed task
+func (t *tuner) observe(latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.tasks++
+	t.totalLatency += latency
+}
+
+func (t *tuner) run(ctx context.Context) {
+	ticker := time.NewTicker(t.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cpu, _ := t.cpu.utilization()
+			t.adjust(ctx, cpu)
+		}
+	}
+}
+
+func (t *tuner) adjust(ctx context.Context, cpuUtilization float64) {
+	t.mu.Lock()
+	tasks, totalLatency := t.tasks, t.totalLatency
+	t.tasks, t.totalLatency = 0, 0
+	t.mu.Unlock()
+
+	var avgLatency time.Duration
+	if tasks > 0 {
+		avgLatency = totalLatency / time.Duration(tasks)
+
+		if t.baseline == 0 || avgLatency < t.baseline {
+			t.baseline = avgLatency
+		} else {
+			t.baseline += (avgLatency - t.baseline) / 10
+		}
+	}
+
+	limit, inUse, saturated := t.limiter.stats()
+	newLimit := limit
+
+	switch {
+	case cpuUtilization > t.options.MaxCPUUtilization:
+		newLimit = limit * 3 / 4
+
+	case tasks > 0 && float64(avgLatency) > float64(t.baseline)*t.options.LatencyTolerance:
+		newLimit = limit * 3 / 4
+
+	case saturated:
+		if pending, ok := t.backlog(ctx); !ok || pending > int64(inUse) {
+			step := limit / 10
+			if step < 1 {
+				step = 1
+			}
+
+			newLimit = limit + step
+		}
+	}
+
+	if newLimit < t.options.MinParallelTasks {
+		newLimit = t.options.MinParallelTasks
+	}
+
+	if newLimit > t.options.MaxParallelTasks {
+		newLimit = t.options.MaxParallelTasks
+	}
+
+	if newLimit != limit {
+		t.logger.Debug("Adjusting task concurrency", "limit", newLimit, "previous_limit", limit, "cpu", cpuUtilization, "avg_latency", avgLatency)
+		t.limiter.setLimit(newLimit)
+	}
+}
+
+// newTaskLimiter creates the limiter for concurrently processed tasks. A configured slot supplier takes
+// precedence, otherwise if auto-tuning is enabled, a tuner adjusting the limit is started and returned, it
+// stops when the context is canceled.
+func newTaskLimiter(
+	ctx context.Context, supplier SlotSupplier, maxParallel int, autoTune *AutoTuneOptions, logger log.Logger, backlog func(context.Context) (int64, bool),
+) (SlotSupplier, *tuner) {
+	if supplier != nil {
+		return supplier, nil
+	}
+
+	if autoTune == nil {
+		return newConcurrencyLimiter(maxParallel), nil
+	}
+
+	t := newTuner(*autoTune, maxParallel, logger, backlog)
+	go t.run(ctx)
+
+	return t.limiter, t
+}
@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ConcurrencyLimiter_SetLimitUnblocks(t *testing.T) {
+	l := newConcurrencyLimiter(1)
+	require.True(t, l.ReserveSlot(context.Background()))
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- l.ReserveSlot(context.Background())
+	}()
+
+	select {
+	case <-acquired:
+		require.Fail(t, "should not acquire while limit is reached")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	l.setLimit(2)
+	require.True(t, <-acquired)
+
+	_, inUse, saturated := l.stats()
+	require.Equal(t, 2, inUse)
+	require.True(t, saturated)
+}
+
+func Test_ConcurrencyLimiter_AcquireCanceled(t *testing.T) {
+	l := newConcurrencyLimiter(1)
+	require.True(t, l.ReserveSlot(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.False(t, l.ReserveSlot(ctx))
+}
+
+func Test_Tuner_IncreasesWithBacklog(t *testing.T) {
+	tu := newTuner(AutoTuneOptions{MinParallelTasks: 1, MaxParallelTasks: 3}, 1, logger.NewDefaultLogger(), func(context.Context) (int64, bool) {
+		return 10, true
+	})
+
+	require.True(t, tu.limiter.ReserveSlot(context.Background()))
+
+	for i := 0; i < 5; i++ {
+		tu.observe(time.Millisecond)
+		tu.limiter.saturated = true
+		tu.adjust(context.Background(), 0)
+	}
+
+	limit, _, _ := tu.limiter.stats()
+	require.Equal(t, 3, limit)
+}
+
+func Test_Tuner_KeepsLimitWithoutBacklog(t *testing.T) {
+	tu := newTuner(AutoTuneOptions{MinParallelTasks: 1, MaxParallelTasks: 3}, 1, logger.NewDefaultLogger(), func(context.Context) (int64, bool) {
+		return 1, true
+	})
+
+	require.True(t, tu.limiter.ReserveSlot(context.Background()))
+	tu.adjust(context.Background(), 0)
+
+	limit, _, _ := tu.limiter.stats()
+	require.Equal(t, 1, limit)
+}
+
+func Test_Tuner_DecreasesUnderPressure(t *testing.T) {
+	tu := newTuner(AutoTuneOptions{MinParallelTasks: 2, MaxParallelTasks: 10}, 8, logger.NewDefaultLogger(), func(context.Context) (int64, bool) {
+		return 0, false
+	})
+
+	// CPU pressure
+	tu.adjust(context.Background(), 0.95)
+	limit, _, _ := tu.limiter.stats()
+	require.Equal(t, 6, limit)
+
+	// Degraded latency
+	tu.observe(time.Millisecond)
+	tu.adjust(context.Background(), 0)
+	tu.observe(10 * time.Millisecond)
+	tu.adjust(context.Background(), 0)
+	limit, _, _ = tu.limiter.stats()
+	require.Equal(t, 4, limit)
+
+	// Never below the lower bound
+	for i := 0; i < 5; i++ {
+		tu.adjust(context.Background(), 0.95)
+	}
+	limit, _, _ = tu.limiter.stats()
+	require.Equal(t, 2, limit)
+}
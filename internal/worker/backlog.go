@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/metrics"
+)
+
+// reportBacklog reports the backlog of the backend in gauges every interval, until the context is canceled
+func reportBacklog(ctx context.Context, b backend.Backend, r backend.BacklogReporter, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		stats, err := r.GetBacklogStats(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			b.Logger().Error("could not get backlog stats", "error", err)
+		} else {
+			m := b.Metrics()
+			m.Gauge(metrics.PendingWorkflowTasks, nil, stats.PendingWorkflowTasks)
+			m.Gauge(metrics.PendingActivityTasks, nil, stats.PendingActivityTasks)
+			m.Gauge(metrics.FutureEvents, nil, stats.FutureEvents)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	mi "github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+type gaugeRecorder struct {
+	metrics.Client
+
+	mu     sync.Mutex
+	gauges map[string]int64
+}
+
+func (r *gaugeRecorder) Gauge(name string, tags map[string]string, value int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gauges[name] = value
+}
+
+func (r *gaugeRecorder) get(name string) (int64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.gauges[name]
+	return v, ok
+}
+
+type backlogReporterFunc func(ctx context.Context) (*backend.BacklogStats, error)
+
+func (f backlogReporterFunc) GetBacklogStats(ctx context.Context) (*backend.BacklogStats, error) {
+	return f(ctx)
+}
+
+func Test_ReportBacklog(t *testing.T) {
+	m := &gaugeRecorder{Client: mi.NewNoopMetricsClient(), gauges: map[string]int64{}}
+
+	b := &backend.MockBackend{}
+	b.On("Metrics").Return(m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		reportBacklog(ctx, b, backlogReporterFunc(func(ctx context.Context) (*backend.BacklogStats, error) {
+			return &backend.BacklogStats{PendingWorkflowTasks: 3, PendingActivityTasks: 5, FutureEvents: 7}, nil
+		}), time.Hour)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		_, ok := m.get(metrics.FutureEvents)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+
+	workflowTasks, _ := m.get(metrics.PendingWorkflowTasks)
+	activityTasks, _ := m.get(metrics.PendingActivityTasks)
+	futureEvents, _ := m.get(metrics.FutureEvents)
+	require.Equal(t, []int64{3, 5, 7}, []int64{workflowTasks, activityTasks, futureEvents})
+}
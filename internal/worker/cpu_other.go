@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package worker
+
+// cpuSampler is not supported on this platform, CPU utilization is not taken into account
+type cpuSampler struct{}
+
+func newCPUSampler() *cpuSampler {
+	return &cpuSampler{}
+}
+
+func (s *cpuSampler) utilization() (float64, bool) {
+	return 0, false
+}
@@ -0,0 +1,57 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package worker
+
+import (
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// cpuSampler measures the CPU utilization of the current process between calls
+type cpuSampler struct {
+	mu       sync.Mutex
+	lastCPU  time.Duration
+	lastWall time.Time
+}
+
+func newCPUSampler() *cpuSampler {
+	s := &cpuSampler{}
+	s.lastCPU, _ = processCPUTime()
+	s.lastWall = time.Now()
+
+	return s
+}
+
+// utilization returns the CPU utilization since the last call as a fraction of all available CPUs
+func (s *cpuSampler) utilization() (float64, bool) {
+	cpu, ok := processCPUTime()
+	if !ok {
+		return 0, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	wall := now.Sub(s.lastWall)
+	used := cpu - s.lastCPU
+
+	s.lastCPU, s.lastWall = cpu, now
+
+	if wall <= 0 {
+		return 0, false
+	}
+
+	return float64(used) / float64(wall) / float64(runtime.NumCPU()), true
+}
+
+func processCPUTime() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, false
+	}
+
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// DispatchOverflowPolicy determines what happens to a polled task when the dispatch queue is full
+type DispatchOverflowPolicy int
+
+const (
+	// DispatchOverflowBlock stops polling for new tasks until there is room in the dispatch queue
+	DispatchOverflowBlock DispatchOverflowPolicy = iota
+
+	// DispatchOverflowRelease releases a polled task back to the backend if the dispatch queue is full, so it can
+	// be picked up again right away instead of waiting with an aging lock
+	DispatchOverflowRelease
+)
+
+// dispatchQueue holds polled tasks until the dispatcher hands them to a handler
+type dispatchQueue[T any] struct {
+	tasks chan T
+
+	// slots limit the number of tasks being polled or waiting in the queue, if polling stops while the
+	// queue is full
+	slots chan struct{}
+
+	policy DispatchOverflowPolicy
+
+	closeOnce sync.Once
+}
+
+func newDispatchQueue[T any](size int, policy DispatchOverflowPolicy) *dispatchQueue[T] {
+	if size < 0 {
+		size = 0
+	}
+
+	q := &dispatchQueue[T]{
+		tasks:  make(chan T, size),
+		policy: policy,
+	}
+
+	if size > 0 && policy == DispatchOverflowBlock {
+		q.slots = make(chan struct{}, size)
+	}
+
+	return q
+}
+
+// acquire blocks until there is room for another task. Returns false if the context is canceled.
+func (q *dispatchQueue[T]) acquire(ctx context.Context) bool {
+	if q.slots == nil {
+		return true
+	}
+
+	select {
+	case q.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// cancel gives up room acquired for a poll that did not return a task
+func (q *dispatchQueue[T]) cancel() {
+	if q.slots != nil {
+		<-q.slots
+	}
+}
+
+// push adds a polled task to the queue. Returns false if the queue is full and the task has to be released.
+func (q *dispatchQueue[T]) push(ctx context.Context, t T) bool {
+	if q.policy == DispatchOverflowRelease {
+		select {
+		case q.tasks <- t:
+			return true
+		default:
+			return false
+		}
+	}
+
+	select {
+	case q.tasks <- t:
+	case <-ctx.Done():
+	}
+
+	return true
+}
+
+// taken has to be called after the dispatcher has taken a task from the queue
+func (q *dispatchQueue[T]) taken() {
+	if q.slots != nil {
+		<-q.slots
+	}
+}
+
+// close stops the queue once no more tasks are pushed. The dispatcher takes the tasks left in the queue before
+// it sees the queue closed.
+func (q *dispatchQueue[T]) close() {
+	q.closeOnce.Do(func() {
+		close(q.tasks)
+	})
+}
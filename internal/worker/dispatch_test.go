@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DispatchQueue_BlockStopsPollingWhileFull(t *testing.T) {
+	q := newDispatchQueue[int](1, DispatchOverflowBlock)
+
+	require.True(t, q.acquire(context.Background()))
+	require.True(t, q.push(context.Background(), 1))
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- q.acquire(context.Background())
+	}()
+
+	select {
+	case <-acquired:
+		require.Fail(t, "should not poll while the queue is full")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	require.Equal(t, 1, <-q.tasks)
+	q.taken()
+
+	require.True(t, <-acquired)
+}
+
+func Test_DispatchQueue_ReleaseWhenFull(t *testing.T) {
+	q := newDispatchQueue[int](1, DispatchOverflowRelease)
+
+	require.True(t, q.acquire(context.Background()))
+	require.True(t, q.push(context.Background(), 1))
+
+	require.True(t, q.acquire(context.Background()))
+	require.False(t, q.push(context.Background(), 2))
+}
+
+func Test_DispatchQueue_CloseKeepsQueuedTasks(t *testing.T) {
+	q := newDispatchQueue[int](2, DispatchOverflowBlock)
+
+	require.True(t, q.acquire(context.Background()))
+	require.True(t, q.push(context.Background(), 1))
+
+	q.close()
+	q.close()
+
+	tk, ok := <-q.tasks
+	require.True(t, ok)
+	require.Equal(t, 1, tk)
+
+	_, ok = <-q.tasks
+	require.False(t, ok)
+}
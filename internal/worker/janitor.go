@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/leader"
+)
+
+// Janitor removes finished workflow instances once they are older than the retention of the backend
+type Janitor struct {
+	backend backend.Backend
+	options *Options
+
+	wg sync.WaitGroup
+}
+
+func NewJanitor(backend backend.Backend, options *Options) *Janitor {
+	return &Janitor{
+		backend: backend,
+		options: options,
+	}
+}
+
+// Start removes finished instances every RetentionInterval until the context is canceled. It does nothing if the
+// backend has no retention or cannot remove finished instances.
+func (j *Janitor) Start(ctx context.Context) error {
+	rp, ok := j.backend.(backend.RetentionProvider)
+	if !ok || rp.Retention() <= 0 {
+		return nil
+	}
+
+	cleaner, ok := j.backend.(backend.FinishedInstanceCleaner)
+	if !ok {
+		return nil
+	}
+
+	retention := rp.Retention()
+	cleanup := func(ctx context.Context) error {
+		return cleaner.CleanupFinishedInstances(ctx, retention)
+	}
+
+	// Let a single worker remove instances, if the backend supports electing one
+	e, err := leader.New(j.backend, "retention", leader.WithLogger(j.backend.Logger()))
+	if err != nil && !errors.Is(err, leader.ErrNotSupported) {
+		return err
+	}
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		if e != nil {
+			e.RunPeriodically(ctx, j.options.retentionInterval(), cleanup)
+			return
+		}
+
+		j.runPeriodically(ctx, cleanup)
+	}()
+
+	return nil
+}
+
+func (j *Janitor) WaitForCompletion() error {
+	j.wg.Wait()
+
+	return nil
+}
+
+func (j *Janitor) runPeriodically(ctx context.Context, cleanup func(ctx context.Context) error) {
+	t := time.NewTicker(j.options.retentionInterval())
+	defer t.Stop()
+
+	for {
+		if err := cleanup(ctx); err != nil && ctx.Err() == nil {
+			j.backend.Logger().Error("could not remove finished workflow instances", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cschleiden/go-workflows/metrics"
+)
+
+// recordScheduleToStart records the time a task waited between being enqueued and being started
+func recordScheduleToStart(m metrics.Client, name string, tags map[string]string, scheduledAt, startedAt time.Time) {
+	if scheduledAt.IsZero() {
+		// Backend did not record when the task was enqueued
+		return
+	}
+
+	latency := startedAt.Sub(scheduledAt)
+	if latency < 0 {
+		// Clocks of backend and worker might be skewed
+		latency = 0
+	}
+
+	m.Distribution(name, tags, float64(latency.Milliseconds()))
+}
+
+// recordDuration records the time since start in milliseconds
+func recordDuration(m metrics.Client, name string, tags map[string]string, start time.Time) {
+	m.Distribution(name, tags, float64(time.Since(start).Milliseconds()))
+}
+
+// recordPoll records the duration of a poll for a task, tagged with whether it returned a task
+func recordPoll(m metrics.Client, name string, start time.Time, found bool) {
+	recordDuration(m, name, map[string]string{"found": strconv.FormatBool(found)}, start)
+}
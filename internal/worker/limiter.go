@@ -0,0 +1,187 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// concurrencyLimiter is a semaphore whose limit can be changed while it is in use. A limit of 0 means
+// no limit.
+type concurrencyLimiter struct {
+	mu sync.Mutex
+
+	limit int
+	inUse int
+
+	// saturated is set when a caller had to wait for a slot since the last call to stats
+	saturated bool
+
+	// released is closed and replaced whenever a slot is released or the limit changes
+	released chan struct{}
+}
+
+func newConcurrencyLimiter(limit int) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		limit:    limit,
+		released: make(chan struct{}),
+	}
+}
+
+var _ SlotSupplier = (*concurrencyLimiter)(nil)
+
+// ReserveSlot blocks until a slot is available or the context is canceled. Returns false if the context
+// was canceled.
+func (l *concurrencyLimiter) ReserveSlot(ctx context.Context) bool {
+	for {
+		l.mu.Lock()
+		if l.limit <= 0 || l.inUse < l.limit {
+			l.inUse++
+			l.mu.Unlock()
+			return true
+		}
+
+		l.saturated = true
+		released := l.released
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-released:
+		}
+	}
+}
+
+// tryReserveSlot reserves a slot if one is available, without waiting
+func (l *concurrencyLimiter) tryReserveSlot() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit <= 0 || l.inUse < l.limit {
+		l.inUse++
+		return true
+	}
+
+	l.saturated = true
+	return false
+}
+
+func (l *concurrencyLimiter) ReleaseSlot() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inUse--
+	l.notify()
+}
+
+func (l *concurrencyLimiter) setLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.notify()
+}
+
+// stats returns the current limit, the number of slots in use, and whether the limit was reached since
+// the last call.
+func (l *concurrencyLimiter) stats() (limit, inUse int, saturated bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	saturated = l.saturated || (l.limit > 0 && l.inUse >= l.limit)
+	l.saturated = false
+
+	return l.limit, l.inUse, saturated
+}
+
+func (l *concurrencyLimiter) notify() {
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// rateLimiter spaces out tasks so that no more than the given number are started per second. A nil
+// rateLimiter does not limit.
+type rateLimiter struct {
+	mu sync.Mutex
+
+	interval time.Duration
+
+	// next is the earliest time the next task may start
+	next time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / perSecond),
+	}
+}
+
+// wait blocks until the next task may start or the context is canceled. Returns false if the context
+// was canceled.
+func (r *rateLimiter) wait(ctx context.Context) bool {
+	if r == nil {
+		return true
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	at := r.next
+	r.next = r.next.Add(r.interval)
+	r.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return true
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// nameLimit limits the number of concurrent tasks with the same name, e.g. of one activity
+type nameLimit struct {
+	// running are the tasks being processed
+	running *concurrencyLimiter
+
+	// waiting are the tasks waiting for a running slot without holding up the tasks of other names
+	waiting *concurrencyLimiter
+}
+
+// nameLimits holds the concurrency limits per name. Names without a limit are not limited.
+type nameLimits map[string]*nameLimit
+
+func newNameLimits(limits map[string]int) nameLimits {
+	l := make(nameLimits, len(limits))
+	for name, limit := range limits {
+		if limit <= 0 {
+			continue
+		}
+
+		l[name] = &nameLimit{
+			running: newConcurrencyLimiter(limit),
+			waiting: newConcurrencyLimiter(limit),
+		}
+	}
+
+	return l
+}
+
+// get returns the limit for the given name, or nil if tasks with that name are not limited
+func (l nameLimits) get(name string) *nameLimit {
+	return l[name]
+}
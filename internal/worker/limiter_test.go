@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RateLimiter_SpacesOutTasks(t *testing.T) {
+	r := newRateLimiter(100)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		require.True(t, r.wait(context.Background()))
+	}
+
+	// The first task starts right away, the others 10ms apart
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func Test_RateLimiter_NoLimit(t *testing.T) {
+	r := newRateLimiter(0)
+	require.Nil(t, r)
+
+	require.True(t, r.wait(context.Background()))
+}
+
+func Test_RateLimiter_WaitCanceled(t *testing.T) {
+	r := newRateLimiter(0.1)
+	require.True(t, r.wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.False(t, r.wait(ctx))
+}
+
+func Test_NameLimits(t *testing.T) {
+	l := newNameLimits(map[string]int{"slow": 1, "ignored": 0})
+
+	require.Nil(t, l.get("other"))
+	require.Nil(t, l.get("ignored"))
+
+	nl := l.get("slow")
+	require.NotNil(t, nl)
+
+	require.True(t, nl.running.tryReserveSlot())
+	require.False(t, nl.running.tryReserveSlot())
+
+	// One task can wait for the activity without blocking the dispatcher
+	require.True(t, nl.waiting.tryReserveSlot())
+	require.False(t, nl.waiting.tryReserveSlot())
+
+	nl.running.ReleaseSlot()
+	require.True(t, nl.running.tryReserveSlot())
+}
@@ -1,29 +1,280 @@
 package worker
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/guard"
+	"github.com/cschleiden/go-workflows/internal/workflow"
+)
+
 type Options struct {
 	// WorkflowsPollers is the number of pollers to start. Defaults to 2.
 	WorkflowPollers int
 
+	// WorkflowPollerAutoScale enables growing and shrinking the number of workflow pollers, within the configured
+	// bounds, based on the poll success rate and the backlog reported by the backend. WorkflowPollers is used as
+	// the initial number of pollers. Disabled by default.
+	WorkflowPollerAutoScale *PollerAutoScaleOptions
+
+	// WorkflowPollTimeout is how long a workflow poller waits for a task before polling again. Defaults to 30s.
+	WorkflowPollTimeout time.Duration
+
 	// MaxParallelWorkflowTasks determines the maximum number of concurrent workflow tasks processed
 	// by the worker. The default is 0 which is no limit.
 	MaxParallelWorkflowTasks int
 
+	// AutoTuneWorkflowTasks enables adjusting the maximum number of concurrent workflow tasks at runtime, within
+	// the configured bounds, based on task latency, CPU utilization, and the backlog reported by the backend.
+	// MaxParallelWorkflowTasks is used as the initial limit. Disabled by default.
+	AutoTuneWorkflowTasks *AutoTuneOptions
+
+	// WorkflowSlotSupplier controls how many workflow tasks are processed concurrently, for example based on
+	// resource usage, see NewResourceSlotSupplier. Takes precedence over MaxParallelWorkflowTasks and
+	// AutoTuneWorkflowTasks.
+	WorkflowSlotSupplier SlotSupplier
+
+	// WorkflowDispatchQueueSize is the number of polled workflow tasks that can wait for a free slot. The default
+	// is 0, pollers hand tasks directly to the dispatcher.
+	WorkflowDispatchQueueSize int
+
+	// WorkflowDispatchOverflow determines what happens to a polled workflow task while the dispatch queue is
+	// full. By default, pollers stop polling until there is room in the queue. DispatchOverflowRelease requires a
+	// backend implementing backend.WorkflowTaskReleaser.
+	WorkflowDispatchOverflow DispatchOverflowPolicy
+
+	// WorkflowQueues are the workflow queues the worker polls for workflow tasks. Requires a backend implementing
+	// backend.WorkflowQueueProvider. Include backend.DefaultWorkflowQueue to also execute workflow instances
+	// started without a queue. Defaults to only the default queue.
+	WorkflowQueues []string
+
 	// ActivityPollers is the number of pollers to start. Defaults to 2.
 	ActivityPollers int
 
+	// ActivityPollerAutoScale enables growing and shrinking the number of activity pollers, within the configured
+	// bounds, based on the poll success rate and the backlog reported by the backend. ActivityPollers is used as
+	// the initial number of pollers. Disabled by default.
+	ActivityPollerAutoScale *PollerAutoScaleOptions
+
+	// ActivityPollTimeout is how long an activity poller waits for a task before polling again. Defaults to 30s.
+	ActivityPollTimeout time.Duration
+
+	// ActivityDispatchQueueSize is the number of activity tasks pollers prefetch while all slots are busy, to hide
+	// the latency of polling the backend for short activities. Pollers stop polling while that many tasks wait for a
+	// free slot. The locks of waiting tasks are extended like the locks of running tasks, so they don't expire. The
+	// default is 0, pollers hand tasks directly to the dispatcher.
+	ActivityDispatchQueueSize int
+
 	// MaxParallelActivityTasks determines the maximum number of concurrent activity tasks processed
 	// by the worker. The default is 0 which is no limit.
 	MaxParallelActivityTasks int
 
+	// AutoTuneActivityTasks enables adjusting the maximum number of concurrent activity tasks at runtime, within
+	// the configured bounds, based on task latency, CPU utilization, and the backlog reported by the backend.
+	// MaxParallelActivityTasks is used as the initial limit. Disabled by default.
+	AutoTuneActivityTasks *AutoTuneOptions
+
+	// MaxParallelActivityTasksByName limits the number of concurrent tasks of individual activities, by activity
+	// name, so a slow activity cannot take up all slots of the worker. Tasks of an activity at its limit wait without
+	// taking up one of the MaxParallelActivityTasks slots. The default is no limit.
+	MaxParallelActivityTasksByName map[string]int
+
+	// ActivityTasksPerSecond limits the number of activity tasks the worker starts per second. The default is 0
+	// which is no limit.
+	ActivityTasksPerSecond float64
+
+	// ActivitySlotSupplier controls how many activity tasks are processed concurrently, for example based on
+	// resource usage, see NewResourceSlotSupplier. Takes precedence over MaxParallelActivityTasks and
+	// AutoTuneActivityTasks.
+	ActivitySlotSupplier SlotSupplier
+
+	// ActivityQueues are the activity queues the worker polls for activity tasks. Requires a backend implementing
+	// backend.ActivityQueueProvider. Include backend.DefaultActivityQueue to also execute activities scheduled
+	// without a queue. Defaults to only the default queue.
+	ActivityQueues []string
+
+	// EnableSessions lets the worker host sessions, see workflow.CreateSession. The worker additionally polls the
+	// queue sessions are started on and a queue only it polls. Requires a backend implementing
+	// backend.ActivityQueueProvider.
+	EnableSessions bool
+
+	// RegisteredOnly restricts the worker to tasks of the workflows and activities registered with it, instead of
+	// failing tasks it cannot execute. This allows running workers with different sets of workflows and
+	// activities against the same backend. Requires a backend implementing backend.CapabilityTaskProvider.
+	RegisteredOnly bool
+
 	// HeartbeatWorkflowTasks determines if the lock on workflow tasks should be periodically
 	// extended while they are being processed. Given that workflow executions should be
 	// very quick, this is usually not necessary.
 	HeartbeatWorkflowTasks bool
+
+	// WorkflowHeartbeatInterval is how often the lock on a workflow task is extended, if HeartbeatWorkflowTasks
+	// is set. Must be shorter than the workflow lock timeout of the backend. Defaults to a third of the workflow
+	// lock timeout of backends implementing backend.LockTimeoutProvider, 25s for other backends.
+	WorkflowHeartbeatInterval time.Duration
+
+	// ActivityHeartbeatInterval is how often the lock on an activity task is extended while the activity is
+	// executed. Must be shorter than the activity lock timeout of the backend. Defaults to a third of the activity
+	// lock timeout of backends implementing backend.LockTimeoutProvider, 30s for other backends.
+	ActivityHeartbeatInterval time.Duration
+
+	// WorkflowExecutorCacheMaxHistoryEvents limits the number of history events retained by all cached workflow
+	// executors. Once exceeded, the least recently used executors are evicted before their cache duration ends.
+	// The default is 0 which is no limit.
+	WorkflowExecutorCacheMaxHistoryEvents int64
+
+	// WorkflowExecutorCacheMaxMemoryBytes is the memory obtained from the OS by the Go runtime above which the
+	// least recently used workflow executors are evicted from the cache. The default is 0 which does not take
+	// memory into account.
+	WorkflowExecutorCacheMaxMemoryBytes uint64
+
+	// SlowWorkflowTaskThreshold is the duration after which a running workflow task is logged as slow and counted
+	// in the metrics.SlowWorkflowTasks metric. Use it to find slow tasks before they exceed the workflow lock
+	// timeout. The default is 0 which disables the warning.
+	SlowWorkflowTaskThreshold time.Duration
+
+	// SlowActivityThreshold is the duration after which a running activity is logged as slow and counted in the
+	// metrics.SlowActivities metric. Use it to find slow activities before they exceed the activity lock timeout.
+	// The default is 0 which disables the warning.
+	SlowActivityThreshold time.Duration
+
+	// BacklogMetricsInterval is how often the worker reports the backlog of the backend in the
+	// metrics.PendingWorkflowTasks, metrics.PendingActivityTasks, and metrics.FutureEvents gauges. Requires a
+	// backend implementing backend.BacklogReporter. The default is 0 which disables reporting.
+	BacklogMetricsInterval time.Duration
+
+	// HistoryLimits configures thresholds for the history of workflow instances. Crossing a warning threshold
+	// records a HistoryLimitWarning event and suggests continuing as new, crossing a maximum fails the workflow
+	// instance. Disabled by default.
+	HistoryLimits workflow.HistoryLimits
+
+	// DeterminismGuard checks workflow code for non-deterministic calls at runtime, like calls to the shims in the
+	// determinism package or using the workflow context from goroutines not started with workflow.Go. In warn
+	// mode violations are logged and counted in the metrics.DeterminismViolations metric, in strict mode they fail
+	// the workflow task. Disabled by default.
+	DeterminismGuard guard.Mode
+
+	// WorkflowDeadlockTimeout is how long workflow code may run without yielding, for example when it's blocked on
+	// a native Go channel or stuck in a loop, before the workflow task is aborted with a deadlock error including
+	// the stack of the blocked workflow code. Defaults to 40s.
+	WorkflowDeadlockTimeout time.Duration
+
+	// WorkflowTaskTimeout limits how long a single workflow task may take, including replaying the history of the
+	// workflow instance. Exceeding it aborts the task and fails the workflow instance with an error, protecting the
+	// worker from pathological replays. Workflows registered with workflow.WithTaskTimeout use their own timeout.
+	// The default is 0 which means no limit.
+	WorkflowTaskTimeout time.Duration
+
+	// QueryPollInterval is how often the worker polls for workflow queries while there are none, see
+	// workflow.HandleQuery. Only used with backends implementing backend.WorkflowQuerier. Defaults to 200ms.
+	QueryPollInterval time.Duration
+
+	// Converter encodes and decodes workflow and activity inputs and results, and signal payloads. It has to match
+	// the converter of the clients, for example when both encrypt payloads. Defaults to the converter of the
+	// backend.
+	Converter converter.Converter
+
+	// RetentionInterval is how often finished workflow instances older than the retention of the backend are
+	// removed, see backend.WithRetention. Only one worker sharing a backend implementing backend.LeaseProvider
+	// removes instances at a time. Defaults to 1h.
+	RetentionInterval time.Duration
 }
 
+const (
+	defaultWorkflowHeartbeatInterval = 25 * time.Second
+	defaultActivityHeartbeatInterval = 30 * time.Second
+	defaultPollTimeout               = 30 * time.Second
+	defaultQueryPollInterval         = 200 * time.Millisecond
+	defaultRetentionInterval         = time.Hour
+
+	// heartbeatsPerLockTimeout is the number of times the lock of a task is extended within the lock timeout by
+	// default, so a single delayed extension doesn't lose the lock
+	heartbeatsPerLockTimeout = 3
+)
+
 var DefaultOptions = Options{
 	WorkflowPollers:          2,
+	WorkflowPollTimeout:      defaultPollTimeout,
 	ActivityPollers:          2,
+	ActivityPollTimeout:      defaultPollTimeout,
 	MaxParallelWorkflowTasks: 0,
 	MaxParallelActivityTasks: 0,
 }
+
+// workflowHeartbeatInterval returns the interval for extending workflow task locks, derived from the given lock
+// timeout of the backend unless it's configured. A lock timeout of 0 means the backend doesn't report it.
+func (o *Options) workflowHeartbeatInterval(lockTimeout time.Duration) time.Duration {
+	return heartbeatInterval(o.WorkflowHeartbeatInterval, lockTimeout, defaultWorkflowHeartbeatInterval)
+}
+
+// activityHeartbeatInterval returns the interval for extending activity task locks, like workflowHeartbeatInterval
+func (o *Options) activityHeartbeatInterval(lockTimeout time.Duration) time.Duration {
+	return heartbeatInterval(o.ActivityHeartbeatInterval, lockTimeout, defaultActivityHeartbeatInterval)
+}
+
+func heartbeatInterval(configured, lockTimeout, fallback time.Duration) time.Duration {
+	switch {
+	case configured > 0:
+		return configured
+	case lockTimeout > 0:
+		return lockTimeout / heartbeatsPerLockTimeout
+	}
+
+	return fallback
+}
+
+func (o *Options) workflowPollTimeout() time.Duration {
+	if o.WorkflowPollTimeout <= 0 {
+		return defaultPollTimeout
+	}
+
+	return o.WorkflowPollTimeout
+}
+
+func (o *Options) activityPollTimeout() time.Duration {
+	if o.ActivityPollTimeout <= 0 {
+		return defaultPollTimeout
+	}
+
+	return o.ActivityPollTimeout
+}
+
+// PayloadConverter returns the converter of the worker, the converter of the given backend if none is set
+func (o *Options) PayloadConverter(b backend.Backend) converter.Converter {
+	if o.Converter != nil {
+		return o.Converter
+	}
+
+	return b.Converter()
+}
+
+func (o *Options) retentionInterval() time.Duration {
+	if o.RetentionInterval <= 0 {
+		return defaultRetentionInterval
+	}
+
+	return o.RetentionInterval
+}
+
+func (o *Options) queryPollInterval() time.Duration {
+	if o.QueryPollInterval <= 0 {
+		return defaultQueryPollInterval
+	}
+
+	return o.QueryPollInterval
+}
+
+// validateHeartbeatInterval returns an error if tasks would lose their lock between two heartbeats
+func validateHeartbeatInterval(kind string, interval, lockTimeout time.Duration) error {
+	if lockTimeout <= 0 {
+		return fmt.Errorf("%v lock timeout of the backend must be positive, got %v", kind, lockTimeout)
+	}
+
+	if interval >= lockTimeout {
+		return fmt.Errorf("%v heartbeat interval %v must be shorter than the %v lock timeout %v of the backend", kind, interval, kind, lockTimeout)
+	}
+
+	return nil
+}
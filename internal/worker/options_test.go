@@ -0,0 +1,192 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/logger"
+	mi "github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ValidateHeartbeatInterval(t *testing.T) {
+	require.NoError(t, validateHeartbeatInterval("activity", 30*time.Second, 2*time.Minute))
+	require.Error(t, validateHeartbeatInterval("activity", 30*time.Second, 30*time.Second))
+	require.Error(t, validateHeartbeatInterval("activity", 30*time.Second, 10*time.Second))
+	require.Error(t, validateHeartbeatInterval("workflow", 25*time.Second, 0))
+}
+
+func Test_Options_HeartbeatIntervalDefaults(t *testing.T) {
+	o := &Options{}
+
+	// Derived from the lock timeouts of the backend
+	require.Equal(t, 20*time.Second, o.workflowHeartbeatInterval(time.Minute))
+	require.Equal(t, 40*time.Second, o.activityHeartbeatInterval(2*time.Minute))
+
+	// Backends not reporting their lock timeouts
+	require.Equal(t, defaultWorkflowHeartbeatInterval, o.workflowHeartbeatInterval(0))
+	require.Equal(t, defaultActivityHeartbeatInterval, o.activityHeartbeatInterval(0))
+
+	o.WorkflowHeartbeatInterval = 2 * time.Second
+	o.ActivityHeartbeatInterval = time.Second
+	require.Equal(t, 2*time.Second, o.workflowHeartbeatInterval(time.Minute))
+	require.Equal(t, time.Second, o.activityHeartbeatInterval(2*time.Minute))
+}
+
+func Test_Options_PollTimeoutDefaults(t *testing.T) {
+	o := &Options{}
+	require.Equal(t, 30*time.Second, o.workflowPollTimeout())
+	require.Equal(t, 30*time.Second, o.activityPollTimeout())
+
+	o.WorkflowPollTimeout = time.Second
+	require.Equal(t, time.Second, o.workflowPollTimeout())
+	require.Equal(t, 30*time.Second, o.activityPollTimeout())
+}
+
+// pollRecorder is a backend that never returns tasks. It records how many polls for each task type are running at
+// the same time, and how long they wait for a task.
+type pollRecorder struct {
+	*backend.MockBackend
+
+	mu         sync.Mutex
+	workflow   polls
+	activities polls
+}
+
+type polls struct {
+	running    int
+	maxRunning int
+	timeouts   []time.Duration
+}
+
+func newPollRecorder() *pollRecorder {
+	b := &pollRecorder{MockBackend: &backend.MockBackend{}}
+	b.On("Logger").Return(logger.NewDefaultLogger())
+	b.On("Metrics").Return(mi.NewNoopMetricsClient())
+	b.On("Tracer").Return(nil)
+	b.On("Converter").Return(converter.DefaultConverter)
+
+	return b
+}
+
+func (b *pollRecorder) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	b.record(ctx, &b.workflow)
+	return nil, nil
+}
+
+func (b *pollRecorder) GetActivityTask(ctx context.Context) (*task.Activity, error) {
+	b.record(ctx, &b.activities)
+	return nil, nil
+}
+
+func (b *pollRecorder) record(ctx context.Context, p *polls) {
+	deadline, _ := ctx.Deadline()
+
+	b.mu.Lock()
+	p.running++
+	if p.running > p.maxRunning {
+		p.maxRunning = p.running
+	}
+	p.timeouts = append(p.timeouts, time.Until(deadline))
+	b.mu.Unlock()
+
+	<-ctx.Done()
+
+	b.mu.Lock()
+	p.running--
+	b.mu.Unlock()
+}
+
+func (b *pollRecorder) get(p *polls) polls {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return polls{running: p.running, maxRunning: p.maxRunning, timeouts: append([]time.Duration{}, p.timeouts...)}
+}
+
+func Test_Options_PollersPerTaskType(t *testing.T) {
+	tests := []struct {
+		name            string
+		options         Options
+		workflowPollers int
+		activityPollers int
+		workflowTimeout time.Duration
+		activityTimeout time.Duration
+	}{
+		{
+			name: "configured",
+			options: Options{
+				WorkflowPollers:     1,
+				WorkflowPollTimeout: time.Minute,
+				ActivityPollers:     4,
+				ActivityPollTimeout: 2 * time.Minute,
+			},
+			workflowPollers: 1,
+			activityPollers: 4,
+			workflowTimeout: time.Minute,
+			activityTimeout: 2 * time.Minute,
+		},
+		{
+			name: "default timeouts",
+			options: Options{
+				WorkflowPollers: 3,
+				ActivityPollers: 1,
+			},
+			workflowPollers: 3,
+			activityPollers: 1,
+			workflowTimeout: 30 * time.Second,
+			activityTimeout: 30 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			b := newPollRecorder()
+			registry := workflow.NewRegistry()
+
+			ww := NewWorkflowWorker(b, registry, &tt.options)
+			aw := NewActivityWorker(b, registry, clock.New(), &tt.options)
+
+			require.NoError(t, ww.Start(ctx))
+			require.NoError(t, aw.Start(ctx))
+
+			// Besides the configured pollers, every worker runs one more poller
+			require.Eventually(t, func() bool {
+				return b.get(&b.workflow).running == tt.workflowPollers+1 && b.get(&b.activities).running == tt.activityPollers+1
+			}, time.Second, time.Millisecond)
+
+			require.Equal(t, tt.workflowPollers+1, ww.Status().Pollers.Pollers)
+			require.Equal(t, tt.activityPollers+1, aw.Status().Pollers.Pollers)
+
+			assertTimeouts := func(timeouts []time.Duration, expected time.Duration) {
+				for _, timeout := range timeouts {
+					require.LessOrEqual(t, timeout, expected)
+					require.Greater(t, timeout, expected-time.Second)
+				}
+			}
+
+			assertTimeouts(b.get(&b.workflow).timeouts, tt.workflowTimeout)
+			assertTimeouts(b.get(&b.activities).timeouts, tt.activityTimeout)
+
+			shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), time.Second)
+			defer cancelShutdown()
+
+			require.NoError(t, ww.Shutdown(shutdownCtx))
+			require.NoError(t, aw.Shutdown(shutdownCtx))
+
+			// Polls never ran for more than the configured number of pollers at once
+			require.Equal(t, tt.workflowPollers+1, b.get(&b.workflow).maxRunning)
+			require.Equal(t, tt.activityPollers+1, b.get(&b.activities).maxRunning)
+		})
+	}
+}
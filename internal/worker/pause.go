@@ -0,0 +1,132 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// pauseGate stops pollers from polling while paused, and tracks polls and the tasks they returned, so pausing
+// can wait for the work started before to finish
+type pauseGate struct {
+	mu sync.Mutex
+
+	paused bool
+
+	// pausing is closed when polling is paused, to cancel polls in progress
+	pausing chan struct{}
+
+	// resumed is closed when polling is resumed
+	resumed chan struct{}
+
+	// active is the number of polls in progress and tasks being processed
+	active int
+
+	// idle is closed when active drops to 0
+	idle chan struct{}
+}
+
+func newPauseGate() *pauseGate {
+	return &pauseGate{
+		pausing: make(chan struct{}),
+	}
+}
+
+func (g *pauseGate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.paused {
+		g.paused = true
+		g.resumed = make(chan struct{})
+		close(g.pausing)
+	}
+}
+
+func (g *pauseGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.paused {
+		g.paused = false
+		g.pausing = make(chan struct{})
+		close(g.resumed)
+	}
+}
+
+// acquire waits until polling is not paused and marks a poll as active. It returns a context for the poll that
+// is canceled when polling is paused, or false if ctx is canceled or stop is closed first. Callers need to call
+// the cancel function once the poll is done, and release once the task it returned, if any, is done.
+func (g *pauseGate) acquire(ctx context.Context, stop <-chan struct{}) (context.Context, context.CancelFunc, bool) {
+	for {
+		g.mu.Lock()
+		if !g.paused {
+			g.active++
+			pausing := g.pausing
+			g.mu.Unlock()
+
+			pollCtx, cancel := context.WithCancel(ctx)
+			go func() {
+				select {
+				case <-pausing:
+					cancel()
+				case <-pollCtx.Done():
+				}
+			}()
+
+			return pollCtx, cancel, true
+		}
+
+		resumed := g.resumed
+		g.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, false
+		case <-stop:
+			return nil, nil, false
+		case <-resumed:
+		}
+	}
+}
+
+func (g *pauseGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.active--
+
+	if g.active == 0 && g.idle != nil {
+		close(g.idle)
+		g.idle = nil
+	}
+}
+
+// waitIdle waits until there are no active polls and tasks, or the context is canceled
+func (g *pauseGate) waitIdle(ctx context.Context) error {
+	g.mu.Lock()
+	if g.active == 0 {
+		g.mu.Unlock()
+		return nil
+	}
+
+	if g.idle == nil {
+		g.idle = make(chan struct{})
+	}
+
+	idle := g.idle
+	g.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-idle:
+		return nil
+	}
+}
+
+func (g *pauseGate) isPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.paused
+}
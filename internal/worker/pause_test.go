@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PauseGate_StopsPollingWhilePaused(t *testing.T) {
+	g := newPauseGate()
+	g.pause()
+
+	acquired := make(chan bool)
+	go func() {
+		_, cancel, ok := g.acquire(context.Background(), nil)
+		if ok {
+			cancel()
+		}
+
+		acquired <- ok
+	}()
+
+	select {
+	case <-acquired:
+		require.Fail(t, "should not poll while paused")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	g.resume()
+
+	require.True(t, <-acquired)
+}
+
+func Test_PauseGate_AcquireReturnsWhenStopped(t *testing.T) {
+	g := newPauseGate()
+	g.pause()
+
+	stop := make(chan struct{})
+	close(stop)
+
+	_, _, ok := g.acquire(context.Background(), stop)
+	require.False(t, ok)
+}
+
+func Test_PauseGate_PauseCancelsPolls(t *testing.T) {
+	g := newPauseGate()
+
+	ctx, cancel, ok := g.acquire(context.Background(), nil)
+	require.True(t, ok)
+	defer cancel()
+
+	require.NoError(t, ctx.Err())
+
+	g.pause()
+
+	<-ctx.Done()
+}
+
+func Test_PauseGate_WaitIdleWaitsForActiveTasks(t *testing.T) {
+	g := newPauseGate()
+
+	_, cancelPoll, ok := g.acquire(context.Background(), nil)
+	require.True(t, ok)
+	cancelPoll()
+	g.pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, g.waitIdle(ctx), context.DeadlineExceeded)
+
+	idle := make(chan error)
+	go func() {
+		idle <- g.waitIdle(context.Background())
+	}()
+
+	g.release()
+
+	require.NoError(t, <-idle)
+	require.NoError(t, g.waitIdle(context.Background()))
+}
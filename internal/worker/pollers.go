@@ -0,0 +1,172 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/log"
+)
+
+// PollerAutoScaleOptions configure the automatic scaling of the number of pollers
+type PollerAutoScaleOptions struct {
+	// MinPollers is the lower bound for the number of pollers. Defaults to 1.
+	MinPollers int
+
+	// MaxPollers is the upper bound for the number of pollers. Required.
+	MaxPollers int
+
+	// Interval determines how often the number of pollers is adjusted. Defaults to 5 seconds.
+	Interval time.Duration
+}
+
+func (o PollerAutoScaleOptions) withDefaults() PollerAutoScaleOptions {
+	if o.MinPollers <= 0 {
+		o.MinPollers = 1
+	}
+
+	if o.MaxPollers < o.MinPollers {
+		o.MaxPollers = o.MinPollers
+	}
+
+	if o.Interval <= 0 {
+		o.Interval = 5 * time.Second
+	}
+
+	return o
+}
+
+const (
+	// Add pollers if at least this fraction of polls return a task
+	pollerScaleUpRate = 0.8
+
+	// Remove pollers if at most this fraction of polls return a task
+	pollerScaleDownRate = 0.2
+)
+
+// pollerScaler grows the number of pollers while most polls return a task and the backend reports a backlog,
+// and shrinks it while most polls come back empty.
+type pollerScaler struct {
+	options PollerAutoScaleOptions
+	logger  log.Logger
+
+	// start runs a poller until stop is closed
+	start func(stop <-chan struct{})
+
+	// backlog returns the number of pending tasks, ok is false if the backlog is unknown
+	backlog func(ctx context.Context) (pending int64, ok bool)
+
+	mu        sync.Mutex
+	polls     int
+	successes int
+
+	stops []chan struct{}
+
+	// wg tracks the pollers started by the scaler
+	wg sync.WaitGroup
+}
+
+func newPollerScaler(
+	options PollerAutoScaleOptions, logger log.Logger, start func(stop <-chan struct{}), backlog func(context.Context) (int64, bool),
+) *pollerScaler {
+	return &pollerScaler{
+		options: options.withDefaults(),
+		logger:  logger,
+		start:   start,
+		backlog: backlog,
+	}
+}
+
+// record records the outcome of a single poll
+func (s *pollerScaler) record(gotTask bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.polls++
+	if gotTask {
+		s.successes++
+	}
+}
+
+func (s *pollerScaler) run(ctx context.Context, initial int) {
+	if initial < s.options.MinPollers {
+		initial = s.options.MinPollers
+	}
+
+	if initial > s.options.MaxPollers {
+		initial = s.options.MaxPollers
+	}
+
+	for i := 0; i < initial; i++ {
+		s.add()
+	}
+
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Pollers stop with the same context
+			s.wg.Wait()
+			return
+		case <-ticker.C:
+			s.adjust(ctx)
+		}
+	}
+}
+
+func (s *pollerScaler) adjust(ctx context.Context) {
+	s.mu.Lock()
+	polls, successes := s.polls, s.successes
+	s.polls, s.successes = 0, 0
+	s.mu.Unlock()
+
+	if polls == 0 {
+		// All pollers are blocked waiting for tasks, keep the current number
+		return
+	}
+
+	rate := float64(successes) / float64(polls)
+	pending, ok := s.backlog(ctx)
+
+	switch {
+	case rate >= pollerScaleUpRate && (!ok || pending > 0) && len(s.stops) < s.options.MaxPollers:
+		s.add()
+		s.logger.Debug("Added poller", "pollers", len(s.stops), "success_rate", rate)
+
+	case (rate <= pollerScaleDownRate || (ok && pending == 0)) && len(s.stops) > s.options.MinPollers:
+		s.remove()
+		s.logger.Debug("Removed poller", "pollers", len(s.stops), "success_rate", rate)
+	}
+}
+
+func (s *pollerScaler) add() {
+	stop := make(chan struct{})
+
+	s.mu.Lock()
+	s.stops = append(s.stops, stop)
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.start(stop)
+	}()
+}
+
+func (s *pollerScaler) remove() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The poller finishes its current poll before stopping
+	close(s.stops[len(s.stops)-1])
+	s.stops = s.stops[:len(s.stops)-1]
+}
+
+func (s *pollerScaler) pollers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.stops)
+}
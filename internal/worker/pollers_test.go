@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPollerScaler(backlog int64) (*pollerScaler, *int32) {
+	running := int32(0)
+
+	s := newPollerScaler(PollerAutoScaleOptions{MinPollers: 1, MaxPollers: 3}, logger.NewDefaultLogger(), func(stop <-chan struct{}) {
+		atomic.AddInt32(&running, 1)
+		<-stop
+		atomic.AddInt32(&running, -1)
+	}, func(context.Context) (int64, bool) {
+		return backlog, true
+	})
+
+	return s, &running
+}
+
+func Test_PollerScaler_ScalesUp(t *testing.T) {
+	s, running := newTestPollerScaler(10)
+	s.add()
+
+	for i := 0; i < 5; i++ {
+		s.record(true)
+		s.adjust(context.Background())
+	}
+
+	require.Equal(t, 3, s.pollers())
+	require.Eventually(t, func() bool { return atomic.LoadInt32(running) == 3 }, time.Second, time.Millisecond)
+}
+
+func Test_PollerScaler_ScalesDown(t *testing.T) {
+	s, running := newTestPollerScaler(0)
+	s.add()
+	s.add()
+	s.add()
+
+	for i := 0; i < 5; i++ {
+		s.record(false)
+		s.adjust(context.Background())
+	}
+
+	require.Equal(t, 1, s.pollers())
+	require.Eventually(t, func() bool { return atomic.LoadInt32(running) == 1 }, time.Second, time.Millisecond)
+}
+
+func Test_PollerScaler_NoPolls(t *testing.T) {
+	s, _ := newTestPollerScaler(10)
+	s.add()
+
+	s.adjust(context.Background())
+
+	require.Equal(t, 1, s.pollers())
+}
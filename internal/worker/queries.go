@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/workflow"
+)
+
+// runQueries answers workflow queries until the context is canceled
+func (ww *workflowWorker) runQueries(ctx context.Context, q backend.WorkflowQuerier) {
+	t := time.NewTicker(ww.options.queryPollInterval())
+	defer t.Stop()
+
+	for {
+		query, err := q.GetWorkflowQuery(ctx)
+		if err != nil && ctx.Err() == nil {
+			ww.logger.Error("error while polling for workflow query", "error", err)
+		}
+
+		if query != nil {
+			if err := q.CompleteWorkflowQuery(ctx, query.ID, ww.answerQuery(ctx, query)); err != nil {
+				ww.logger.Error("could not complete workflow query", "query_id", query.ID, "error", err)
+			}
+
+			// There might be more queries waiting
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// answerQuery runs the query against the cached executor of the instance, or a new executor replaying the history
+// of the instance. New executors are not cached, so queries don't interfere with the executors of workflow tasks.
+func (ww *workflowWorker) answerQuery(ctx context.Context, query *backend.WorkflowQuery) *backend.WorkflowQueryResult {
+	if executor, ok, _ := ww.cache.Get(ctx, query.Instance); ok {
+		result, err := executor.Query(ctx, query.Name, query.Inputs)
+		if !errors.Is(err, workflow.ErrExecutorClosed) {
+			return queryResult(result, err)
+		}
+
+		// The executor has been evicted in the meantime
+	}
+
+	executor, err := ww.newExecutor(query.Instance)
+	if err != nil {
+		return queryResult(nil, err)
+	}
+	defer executor.Close()
+
+	return queryResult(executor.Query(ctx, query.Name, query.Inputs))
+}
+
+func queryResult(result payload.Payload, err error) *backend.WorkflowQueryResult {
+	if err != nil {
+		return &backend.WorkflowQueryResult{Error: err.Error()}
+	}
+
+	return &backend.WorkflowQueryResult{Result: result}
+}
@@ -0,0 +1,20 @@
+package worker
+
+import "context"
+
+// waitContext runs f and waits until it returns, or the context is canceled
+func waitContext(ctx context.Context, f func()) error {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		f()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/signals"
+)
+
+type backendSignaler struct {
+	b backend.Backend
+}
+
+// NewBackendSignaler returns a signaler delivering signals sent from workflow code using the given backend
+func NewBackendSignaler(b backend.Backend) signals.Signaler {
+	return &backendSignaler{b: b}
+}
+
+func (s *backendSignaler) SignalWorkflow(ctx context.Context, instanceID string, name string, arg payload.Payload) error {
+	event := history.NewPendingEvent(
+		time.Now(),
+		history.EventType_SignalReceived,
+		&history.SignalReceivedAttributes{
+			Name: name,
+			Arg:  arg,
+		},
+	)
+
+	return s.b.SignalWorkflow(ctx, instanceID, event)
+}
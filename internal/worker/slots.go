@@ -0,0 +1,188 @@
+package worker
+
+import (
+	"context"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// SlotSupplier controls how many tasks a worker processes concurrently. A slot is reserved before a task
+// is processed and released once the task is done.
+type SlotSupplier interface {
+	// ReserveSlot blocks until a slot is available or the context is canceled. Returns false if the context
+	// was canceled.
+	ReserveSlot(ctx context.Context) bool
+
+	// ReleaseSlot releases a slot previously reserved with ReserveSlot
+	ReleaseSlot()
+}
+
+// ResourceSlotSupplierOptions configure a slot supplier that hands out slots based on the resource usage of
+// the process
+type ResourceSlotSupplierOptions struct {
+	// MinSlots is the number of slots that are always handed out, regardless of resource usage. Defaults to 1.
+	MinSlots int
+
+	// MaxSlots is the upper bound for the number of slots. The default is 0 which is no limit.
+	MaxSlots int
+
+	// MaxMemoryBytes is the memory obtained from the OS by the Go runtime above which no further slots are
+	// handed out. The default is 0 which does not take memory into account.
+	MaxMemoryBytes uint64
+
+	// MaxCPUUtilization is the process CPU utilization, as a fraction of all available CPUs, above which no
+	// further slots are handed out. The default is 0 which does not take CPU utilization into account. Ignored
+	// on platforms where the CPU utilization cannot be measured.
+	MaxCPUUtilization float64
+
+	// SampleInterval determines how often resource usage is measured. Defaults to 100ms.
+	SampleInterval time.Duration
+}
+
+func (o ResourceSlotSupplierOptions) withDefaults() ResourceSlotSupplierOptions {
+	if o.MinSlots <= 0 {
+		o.MinSlots = 1
+	}
+
+	if o.MaxSlots > 0 && o.MaxSlots < o.MinSlots {
+		o.MaxSlots = o.MinSlots
+	}
+
+	if o.SampleInterval <= 0 {
+		o.SampleInterval = 100 * time.Millisecond
+	}
+
+	return o
+}
+
+// resourceUsage is a single measurement of the resources used by the process
+type resourceUsage struct {
+	memoryBytes uint64
+
+	cpuUtilization float64
+	cpuKnown       bool
+}
+
+// resourceSlotSupplier hands out slots while the process stays below the configured resource limits
+type resourceSlotSupplier struct {
+	options ResourceSlotSupplierOptions
+
+	sample func() resourceUsage
+
+	mu    sync.Mutex
+	inUse int
+
+	lastSample time.Time
+	overloaded bool
+
+	// released is closed and replaced whenever a slot is released
+	released chan struct{}
+}
+
+// NewResourceSlotSupplier creates a slot supplier that stops handing out slots while the memory or CPU
+// usage of the process exceeds the configured limits, and resumes once usage drops again.
+func NewResourceSlotSupplier(options ResourceSlotSupplierOptions) SlotSupplier {
+	cpu := newCPUSampler()
+
+	return newResourceSlotSupplier(options, func() resourceUsage {
+		u := resourceUsage{
+			memoryBytes: runtimeMemory(),
+		}
+		u.cpuUtilization, u.cpuKnown = cpu.utilization()
+
+		return u
+	})
+}
+
+func newResourceSlotSupplier(options ResourceSlotSupplierOptions, sample func() resourceUsage) *resourceSlotSupplier {
+	return &resourceSlotSupplier{
+		options:  options.withDefaults(),
+		sample:   sample,
+		released: make(chan struct{}),
+	}
+}
+
+func (s *resourceSlotSupplier) ReserveSlot(ctx context.Context) bool {
+	for {
+		s.mu.Lock()
+		if s.available() {
+			s.inUse++
+			s.mu.Unlock()
+			return true
+		}
+
+		released := s.released
+		s.mu.Unlock()
+
+		// Wait for a slot to be released or for resource usage to be measured again
+		t := time.NewTimer(s.options.SampleInterval)
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return false
+		case <-released:
+		case <-t.C:
+		}
+
+		t.Stop()
+	}
+}
+
+func (s *resourceSlotSupplier) ReleaseSlot() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inUse--
+
+	close(s.released)
+	s.released = make(chan struct{})
+}
+
+// available returns whether another slot can be handed out. Must be called with the lock held.
+func (s *resourceSlotSupplier) available() bool {
+	if s.inUse < s.options.MinSlots {
+		return true
+	}
+
+	if s.options.MaxSlots > 0 && s.inUse >= s.options.MaxSlots {
+		return false
+	}
+
+	if now := time.Now(); now.Sub(s.lastSample) >= s.options.SampleInterval {
+		s.lastSample = now
+		s.overloaded = s.exceedsLimits(s.sample())
+	}
+
+	return !s.overloaded
+}
+
+func (s *resourceSlotSupplier) exceedsLimits(u resourceUsage) bool {
+	if s.options.MaxMemoryBytes > 0 && u.memoryBytes > s.options.MaxMemoryBytes {
+		return true
+	}
+
+	if s.options.MaxCPUUtilization > 0 && u.cpuKnown && u.cpuUtilization > s.options.MaxCPUUtilization {
+		return true
+	}
+
+	return false
+}
+
+// runtimeMemory returns the memory obtained from the OS by the Go runtime, excluding memory returned to the OS
+func runtimeMemory() uint64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+
+	for _, s := range samples {
+		if s.Value.Kind() != metrics.KindUint64 {
+			return 0
+		}
+	}
+
+	return samples[0].Value.Uint64() - samples[1].Value.Uint64()
+}
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResourceSlotSupplier_PausesWhenOverloaded(t *testing.T) {
+	var memory uint64 = 200
+
+	s := newResourceSlotSupplier(ResourceSlotSupplierOptions{
+		MaxMemoryBytes: 100,
+		SampleInterval: time.Millisecond,
+	}, func() resourceUsage {
+		return resourceUsage{memoryBytes: atomic.LoadUint64(&memory)}
+	})
+
+	// The minimum number of slots is always handed out
+	require.True(t, s.ReserveSlot(context.Background()))
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- s.ReserveSlot(context.Background())
+	}()
+
+	select {
+	case <-acquired:
+		require.Fail(t, "should not reserve a slot while memory limit is exceeded")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	atomic.StoreUint64(&memory, 50)
+	require.True(t, <-acquired)
+}
+
+func Test_ResourceSlotSupplier_CPU(t *testing.T) {
+	s := newResourceSlotSupplier(ResourceSlotSupplierOptions{
+		MinSlots:          1,
+		MaxCPUUtilization: 0.5,
+	}, func() resourceUsage {
+		return resourceUsage{cpuUtilization: 0.9, cpuKnown: true}
+	})
+
+	require.True(t, s.ReserveSlot(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.False(t, s.ReserveSlot(ctx))
+}
+
+func Test_ResourceSlotSupplier_MaxSlots(t *testing.T) {
+	s := newResourceSlotSupplier(ResourceSlotSupplierOptions{
+		MaxSlots: 2,
+	}, func() resourceUsage {
+		return resourceUsage{}
+	})
+
+	require.True(t, s.ReserveSlot(context.Background()))
+	require.True(t, s.ReserveSlot(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.False(t, s.ReserveSlot(ctx))
+
+	s.ReleaseSlot()
+	require.True(t, s.ReserveSlot(context.Background()))
+}
@@ -0,0 +1,22 @@
+package worker
+
+import (
+	"time"
+)
+
+// watchSlowTask calls onSlow with the elapsed time if a task is still running after threshold. Call the returned
+// function once the task is done. A threshold of 0 disables watching.
+func watchSlowTask(threshold time.Duration, onSlow func(elapsed time.Duration)) (done func()) {
+	if threshold <= 0 {
+		return func() {}
+	}
+
+	start := time.Now()
+	t := time.AfterFunc(threshold, func() {
+		onSlow(time.Since(start))
+	})
+
+	return func() {
+		t.Stop()
+	}
+}
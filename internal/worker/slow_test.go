@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WatchSlowTask_WarnsAfterThreshold(t *testing.T) {
+	slow := make(chan time.Duration, 1)
+
+	done := watchSlowTask(10*time.Millisecond, func(elapsed time.Duration) {
+		slow <- elapsed
+	})
+	defer done()
+
+	select {
+	case elapsed := <-slow:
+		require.GreaterOrEqual(t, elapsed, 10*time.Millisecond)
+	case <-time.After(time.Second):
+		require.FailNow(t, "slow task not reported")
+	}
+}
+
+func Test_WatchSlowTask_DoesNotWarnForFastTasks(t *testing.T) {
+	slow := make(chan time.Duration, 1)
+
+	done := watchSlowTask(50*time.Millisecond, func(elapsed time.Duration) {
+		slow <- elapsed
+	})
+	done()
+
+	select {
+	case <-slow:
+		require.FailNow(t, "fast task reported as slow")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func Test_WatchSlowTask_Disabled(t *testing.T) {
+	done := watchSlowTask(0, func(elapsed time.Duration) {
+		require.FailNow(t, "disabled watch reported slow task")
+	})
+	done()
+}
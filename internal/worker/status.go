@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/workflow"
+)
+
+// ExecutingTask is a workflow task or activity a worker is currently executing
+type ExecutingTask struct {
+	InstanceID  string `json:"instance_id"`
+	ExecutionID string `json:"execution_id"`
+
+	// Name is the name of the workflow or activity. It's empty for a workflow task that has not started the
+	// workflow yet.
+	Name string `json:"name"`
+
+	// ActivityID identifies the activity, empty for workflow tasks
+	ActivityID string `json:"activity_id,omitempty"`
+
+	// Attempt is the attempt of the activity, starting at 1. It's 0 for workflow tasks.
+	Attempt int `json:"attempt,omitempty"`
+
+	StartedAt time.Time `json:"started_at"`
+}
+
+// PollerStatus describes the pollers of a worker
+type PollerStatus struct {
+	// Pollers is the number of running pollers
+	Pollers int `json:"pollers"`
+
+	// AutoScale is set if the number of pollers is scaled automatically
+	AutoScale bool `json:"auto_scale"`
+
+	// Paused is set while polling for activity tasks is paused
+	Paused bool `json:"paused"`
+
+	// Queued is the number of polled tasks waiting to be executed
+	Queued int `json:"queued"`
+}
+
+type WorkflowWorkerStatus struct {
+	Tasks   []ExecutingTask       `json:"tasks"`
+	Cache   []workflow.CacheEntry `json:"cache"`
+	Pollers PollerStatus          `json:"pollers"`
+}
+
+type ActivityWorkerStatus struct {
+	Activities []ExecutingTask `json:"activities"`
+	Pollers    PollerStatus    `json:"pollers"`
+}
+
+// executingTask is a task tracked while it's executed. The name of a workflow is only known once the executor
+// has started it, so it's resolved when listing the tasks.
+type executingTask struct {
+	task ExecutingTask
+	name func() string
+}
+
+// executingTasks tracks the tasks a worker is executing
+type executingTasks struct {
+	mu    sync.Mutex
+	tasks map[*executingTask]struct{}
+}
+
+func newExecutingTasks() *executingTasks {
+	return &executingTasks{
+		tasks: make(map[*executingTask]struct{}),
+	}
+}
+
+// start tracks the given task until the returned function is called. name, if given, is called to get the
+// name of the task when listing it.
+func (e *executingTasks) start(t ExecutingTask, name func() string) (done func()) {
+	et := &executingTask{task: t, name: name}
+
+	e.mu.Lock()
+	e.tasks[et] = struct{}{}
+	e.mu.Unlock()
+
+	return func() {
+		e.mu.Lock()
+		delete(e.tasks, et)
+		e.mu.Unlock()
+	}
+}
+
+// list returns the executing tasks, longest running first
+func (e *executingTasks) list() []ExecutingTask {
+	e.mu.Lock()
+	tasks := make([]*executingTask, 0, len(e.tasks))
+	for et := range e.tasks {
+		tasks = append(tasks, et)
+	}
+	e.mu.Unlock()
+
+	r := make([]ExecutingTask, len(tasks))
+	for i, et := range tasks {
+		r[i] = et.task
+		if et.name != nil {
+			r[i].Name = et.name()
+		}
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].StartedAt.Before(r[j].StartedAt)
+	})
+
+	return r
+}
+
+// pollerStatus returns the status of the pollers, fixed is the number of pollers started if they are not
+// scaled automatically
+func pollerStatus[T any](scaler *pollerScaler, fixed int, queue *dispatchQueue[T]) PollerStatus {
+	s := PollerStatus{
+		Pollers: fixed,
+		Queued:  len(queue.tasks),
+	}
+
+	if scaler != nil {
+		s.Pollers = scaler.pollers()
+		s.AutoScale = true
+	}
+
+	return s
+}
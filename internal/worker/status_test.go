@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ExecutingTasks(t *testing.T) {
+	e := newExecutingTasks()
+
+	now := time.Now()
+	name := "before start"
+
+	done1 := e.start(ExecutingTask{InstanceID: "1", StartedAt: now.Add(time.Second)}, func() string { return name })
+	done2 := e.start(ExecutingTask{InstanceID: "2", Name: "activity", StartedAt: now}, nil)
+
+	name = "workflow"
+
+	tasks := e.list()
+	require.Len(t, tasks, 2)
+	require.Equal(t, "2", tasks[0].InstanceID)
+	require.Equal(t, "activity", tasks[0].Name)
+	require.Equal(t, "workflow", tasks[1].Name)
+
+	done2()
+	require.Len(t, e.list(), 1)
+
+	done1()
+	require.Empty(t, e.list())
+}
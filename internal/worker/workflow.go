@@ -2,21 +2,32 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/activity"
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/internal/workflow"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
 )
 
 type WorkflowWorker interface {
 	Start(context.Context) error
 
 	WaitForCompletion() error
+
+	// Shutdown stops polling and waits until the tasks already polled have been completed, or the context is
+	// canceled
+	Shutdown(ctx context.Context) error
+
+	// Status returns the tasks currently executed, the cached executors, and the status of the pollers
+	Status() WorkflowWorkerStatus
 }
 
 type workflowWorker struct {
@@ -26,25 +37,58 @@ type workflowWorker struct {
 
 	registry *workflow.Registry
 
+	// capabilities restrict the tasks the worker polls for, if set
+	capabilities *backend.WorkerCapabilities
+
 	cache workflow.WorkflowExecutorCache
 
-	workflowTaskQueue chan *task.Workflow
+	// localActivities executes local activities inline in workflow tasks
+	localActivities activity.Executor
+
+	workflowTaskQueue *dispatchQueue[*task.Workflow]
+
+	// heartbeatInterval is how often the locks of workflow tasks are extended, if HeartbeatWorkflowTasks is set
+	heartbeatInterval time.Duration
+
+	pollers *pollerScaler
+
+	// fixedPollers is the number of pollers started if they are not scaled automatically
+	fixedPollers int
+
+	executing *executingTasks
 
 	logger log.Logger
 
 	wg *sync.WaitGroup
+
+	// stopPolling stops the pollers without canceling the tasks already polled
+	stopPolling context.CancelFunc
+
+	pollWg sync.WaitGroup
+
+	// dispatcherDone is closed when the dispatcher has handed out all polled tasks
+	dispatcherDone chan struct{}
 }
 
 func NewWorkflowWorker(backend backend.Backend, registry *workflow.Registry, options *Options) WorkflowWorker {
+	cacheOptions := workflow.DefaultWorkflowExecutorCacheOptions
+	cacheOptions.MaxHistoryEvents = options.WorkflowExecutorCacheMaxHistoryEvents
+	cacheOptions.MaxMemoryBytes = options.WorkflowExecutorCacheMaxMemoryBytes
+	cacheOptions.MemoryUsage = runtimeMemory
+
 	return &workflowWorker{
 		backend: backend,
 
 		options: options,
 
 		registry:          registry,
-		workflowTaskQueue: make(chan *task.Workflow),
+		workflowTaskQueue: newDispatchQueue[*task.Workflow](options.WorkflowDispatchQueueSize, options.WorkflowDispatchOverflow),
+
+		cache: workflow.NewWorkflowExecutorCache(cacheOptions),
+
+		localActivities: activity.NewExecutor(backend.Logger(), options.PayloadConverter(backend), backend.Metrics(), backend.Tracer(), registry),
 
-		cache: workflow.NewWorkflowExecutorCache(workflow.DefaultWorkflowExecutorCacheOptions),
+		executing: newExecutingTasks(),
 
 		logger: backend.Logger(),
 
@@ -53,13 +97,94 @@ func NewWorkflowWorker(backend backend.Backend, registry *workflow.Registry, opt
 }
 
 func (ww *workflowWorker) Start(ctx context.Context) error {
+	lp, ok := ww.backend.(backend.LockTimeoutProvider)
+
+	var workflowLockTimeout time.Duration
+	if ok {
+		workflowLockTimeout, _ = lp.LockTimeouts()
+	}
+
+	ww.heartbeatInterval = ww.options.workflowHeartbeatInterval(workflowLockTimeout)
+
+	if ok && ww.options.HeartbeatWorkflowTasks {
+		if err := validateHeartbeatInterval("workflow", ww.heartbeatInterval, workflowLockTimeout); err != nil {
+			return err
+		}
+	}
+
+	if ww.options.WorkflowDispatchOverflow == DispatchOverflowRelease {
+		if _, ok := ww.backend.(backend.WorkflowTaskReleaser); !ok {
+			return errors.New("backend does not support releasing workflow tasks")
+		}
+	}
+
+	if len(ww.options.WorkflowQueues) > 0 {
+		if _, ok := ww.backend.(backend.WorkflowQueueProvider); !ok {
+			return errors.New("backend does not support workflow queues")
+		}
+	}
+
+	if ww.options.BacklogMetricsInterval > 0 {
+		r, ok := ww.backend.(backend.BacklogReporter)
+		if !ok {
+			return errors.New("backend does not support reporting its backlog")
+		}
+
+		go reportBacklog(ctx, ww.backend, r, ww.options.BacklogMetricsInterval)
+	}
+
+	if ww.options.RegisteredOnly {
+		if _, ok := ww.backend.(backend.CapabilityTaskProvider); !ok {
+			return errors.New("backend does not support restricting workers to registered workflows")
+		}
+
+		// With a dynamic workflow the worker is able to execute any workflow
+		if _, _, ok := ww.registry.GetDynamicWorkflow(); !ok {
+			ww.capabilities = &backend.WorkerCapabilities{Workflows: ww.registry.WorkflowNames()}
+
+			if len(ww.capabilities.Workflows) == 0 {
+				// Nothing registered, an empty list would not restrict the tasks at all
+				return nil
+			}
+		}
+	}
+
 	go ww.cache.StartEviction(ctx)
 
-	for i := 0; i <= ww.options.WorkflowPollers; i++ {
-		go ww.runPoll(ctx)
+	pollCtx, stopPolling := context.WithCancel(ctx)
+	ww.stopPolling = stopPolling
+
+	if q, ok := ww.backend.(backend.WorkflowQuerier); ok {
+		go ww.runQueries(pollCtx, q)
 	}
 
-	go ww.runDispatcher(ctx)
+	if ww.options.WorkflowPollerAutoScale != nil {
+		ww.pollers = newPollerScaler(*ww.options.WorkflowPollerAutoScale, ww.backend.Logger(), func(stop <-chan struct{}) {
+			ww.runPoll(ctx, pollCtx, stop)
+		}, ww.backlog)
+
+		ww.pollWg.Add(1)
+		go func() {
+			defer ww.pollWg.Done()
+			ww.pollers.run(pollCtx, ww.options.WorkflowPollers)
+		}()
+	} else {
+		for i := 0; i <= ww.options.WorkflowPollers; i++ {
+			ww.pollWg.Add(1)
+			go func() {
+				defer ww.pollWg.Done()
+				ww.runPoll(ctx, pollCtx, nil)
+			}()
+		}
+
+		ww.fixedPollers = ww.options.WorkflowPollers + 1
+	}
+
+	ww.dispatcherDone = make(chan struct{})
+	go func() {
+		defer close(ww.dispatcherDone)
+		ww.runDispatcher(ctx)
+	}()
 
 	return nil
 }
@@ -70,55 +195,151 @@ func (ww *workflowWorker) WaitForCompletion() error {
 	return nil
 }
 
-func (ww *workflowWorker) runPoll(ctx context.Context) {
+func (ww *workflowWorker) Shutdown(ctx context.Context) error {
+	if ww.dispatcherDone == nil {
+		// Not started, or nothing to poll for
+		return nil
+	}
+
+	ww.stopPolling()
+
+	if err := waitContext(ctx, func() {
+		ww.pollWg.Wait()
+
+		// All polled tasks are in the queue now, let the dispatcher hand them out before it stops
+		ww.workflowTaskQueue.close()
+		<-ww.dispatcherDone
+
+		ww.wg.Wait()
+	}); err != nil {
+		return err
+	}
+
+	ww.cache.Flush()
+
+	return nil
+}
+
+func (ww *workflowWorker) Status() WorkflowWorkerStatus {
+	return WorkflowWorkerStatus{
+		Tasks:   ww.executing.list(),
+		Cache:   ww.cache.Entries(),
+		Pollers: pollerStatus(ww.pollers, ww.fixedPollers, ww.workflowTaskQueue),
+	}
+}
+
+// runPoll polls for tasks until pollCtx is canceled or stop is closed. Polled tasks are handed to the dispatcher
+// as long as ctx is not canceled.
+func (ww *workflowWorker) runPoll(ctx, pollCtx context.Context, stop <-chan struct{}) {
 	for {
 		select {
-		case <-ctx.Done():
+		case <-pollCtx.Done():
+			return
+		case <-stop:
 			return
 		default:
-			task, err := ww.poll(ctx, 30*time.Second)
+			if !ww.workflowTaskQueue.acquire(pollCtx) {
+				return
+			}
+
+			pollStart := time.Now()
+			task, err := ww.poll(pollCtx, ww.options.workflowPollTimeout())
+			recordPoll(ww.backend.Metrics(), metrics.WorkflowTaskPollDuration, pollStart, task != nil)
+			if ww.pollers != nil {
+				ww.pollers.record(err == nil && task != nil)
+			}
+
 			if err != nil {
 				ww.logger.Error("error while polling for workflow task", "error", err)
-			} else if task != nil {
-				ww.workflowTaskQueue <- task
+			}
+
+			if task == nil {
+				ww.workflowTaskQueue.cancel()
+			} else if !ww.workflowTaskQueue.push(ctx, task) {
+				ww.releaseTask(ctx, task)
 			}
 		}
 	}
 }
 
 func (ww *workflowWorker) runDispatcher(ctx context.Context) {
-	var sem chan (struct{})
-
-	if ww.options.MaxParallelWorkflowTasks > 0 {
-		sem = make(chan struct{}, ww.options.MaxParallelWorkflowTasks)
-	}
+	limiter, tuner := newTaskLimiter(ctx, ww.options.WorkflowSlotSupplier, ww.options.MaxParallelWorkflowTasks, ww.options.AutoTuneWorkflowTasks, ww.logger, ww.backlog)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case t := <-ww.workflowTaskQueue:
-			if sem != nil {
-				sem <- struct{}{}
+		case t, ok := <-ww.workflowTaskQueue.tasks:
+			if !ok {
+				// Shutting down, all polled tasks have been dispatched
+				return
+			}
+
+			ww.workflowTaskQueue.taken()
+
+			if !limiter.ReserveSlot(ctx) {
+				return
 			}
 
 			ww.wg.Add(1)
 			go func() {
 				defer ww.wg.Done()
+				defer limiter.ReleaseSlot()
+
+				start := time.Now()
+				recordScheduleToStart(ww.backend.Metrics(), metrics.WorkflowTaskScheduleToStart, nil, t.ScheduledAt, start)
 
 				ww.handle(ctx, t)
 
-				if sem != nil {
-					<-sem
+				if tuner != nil {
+					tuner.observe(time.Since(start))
 				}
 			}()
 		}
 	}
 }
 
+// releaseTask gives a task the worker has no room for back to the backend
+func (ww *workflowWorker) releaseTask(ctx context.Context, t *task.Workflow) {
+	if err := ww.backend.(backend.WorkflowTaskReleaser).ReleaseWorkflowTask(ctx, t.ID, t.WorkflowInstance); err != nil {
+		ww.logger.Error("could not release workflow task", "error", err)
+		return
+	}
+
+	ww.backend.Metrics().Counter(metrics.WorkflowTasksReleased, nil, 1)
+}
+
+// backlog returns the number of pending workflow tasks, if the backend reports it
+func (ww *workflowWorker) backlog(ctx context.Context) (int64, bool) {
+	r, ok := ww.backend.(backend.BacklogReporter)
+	if !ok {
+		return 0, false
+	}
+
+	stats, err := r.GetBacklogStats(ctx)
+	if err != nil {
+		ww.logger.Error("could not get backlog stats", "error", err)
+		return 0, false
+	}
+
+	return stats.PendingWorkflowTasks, true
+}
+
 func (ww *workflowWorker) handle(ctx context.Context, t *task.Workflow) {
 	result, err := ww.handleTask(ctx, t)
 	if err != nil {
+		ww.backend.Metrics().Counter(metrics.WorkflowTasksFailed, nil, 1)
+
+		if r, ok := ww.backend.(backend.InstanceErrorRecorder); ok {
+			if rerr := r.RecordInstanceError(ctx, t.WorkflowInstance, &backend.InstanceError{
+				Message:   err.Error(),
+				Source:    backend.InstanceErrorSourceWorkflow,
+				Timestamp: time.Now(),
+			}); rerr != nil {
+				ww.logger.Error("could not record workflow task error", "error", rerr)
+			}
+		}
+
 		ww.logger.Panic("could not handle workflow task", "error", err)
 	}
 
@@ -141,6 +362,13 @@ func (ww *workflowWorker) handleTask(
 	if err != nil {
 		return nil, err
 	}
+	defer ww.cache.Release(ctx, t.WorkflowInstance)
+
+	defer ww.executing.start(ExecutingTask{
+		InstanceID:  t.WorkflowInstance.InstanceID,
+		ExecutionID: t.WorkflowInstance.ExecutionID,
+		StartedAt:   time.Now(),
+	}, executor.WorkflowName)()
 
 	if ww.options.HeartbeatWorkflowTasks {
 		// Start heartbeat while processing workflow task
@@ -149,11 +377,32 @@ func (ww *workflowWorker) handleTask(
 		go ww.heartbeatTask(heartbeatCtx, t)
 	}
 
+	done := watchSlowTask(ww.options.SlowWorkflowTaskThreshold, func(elapsed time.Duration) {
+		name := executor.WorkflowName()
+
+		ww.logger.Warn("Workflow task is running longer than the slow task threshold",
+			"instance_id", t.WorkflowInstance.InstanceID,
+			"execution_id", t.WorkflowInstance.ExecutionID,
+			"workflow", name,
+			"elapsed", elapsed,
+			"threshold", ww.options.SlowWorkflowTaskThreshold,
+		)
+
+		ww.backend.Metrics().Counter(metrics.SlowWorkflowTasks, map[string]string{"workflow": name}, 1)
+	})
+
+	start := time.Now()
 	result, err := executor.ExecuteTask(ctx, t)
+	done()
 	if err != nil {
 		return nil, fmt.Errorf("executing workflow task: %w", err)
 	}
 
+	tags := map[string]string{"workflow": executor.WorkflowName()}
+	recordDuration(ww.backend.Metrics(), metrics.WorkflowTaskExecutionDuration, tags, start)
+	ww.backend.Metrics().Distribution(metrics.WorkflowHistoryEvents, tags, float64(result.HistoryLength))
+	ww.backend.Metrics().Distribution(metrics.WorkflowHistorySize, tags, float64(result.HistorySize))
+
 	return result, nil
 }
 
@@ -164,11 +413,14 @@ func (ww *workflowWorker) getExecutor(ctx context.Context, t *task.Workflow) (wo
 		ww.logger.Error("could not get cached workflow task executor", "error", err)
 	}
 
-	if !ok {
-		executor, err = workflow.NewExecutor(
-			ww.backend.Logger(), ww.registry, ww.backend, t.WorkflowInstance, clock.New())
+	if ok {
+		ww.backend.Metrics().Counter(metrics.WorkflowExecutorCacheHits, nil, 1)
+	} else {
+		ww.backend.Metrics().Counter(metrics.WorkflowExecutorCacheMisses, nil, 1)
+
+		executor, err = ww.newExecutor(t.WorkflowInstance)
 		if err != nil {
-			return nil, fmt.Errorf("creating workflow executor: %w", err)
+			return nil, err
 		}
 	}
 
@@ -180,9 +432,25 @@ func (ww *workflowWorker) getExecutor(ctx context.Context, t *task.Workflow) (wo
 	return executor, nil
 }
 
+func (ww *workflowWorker) newExecutor(instance *core.WorkflowInstance) (workflow.WorkflowExecutor, error) {
+	executor, err := workflow.NewExecutor(
+		ww.backend.Logger(), ww.backend.Metrics(), ww.options.PayloadConverter(ww.backend), ww.registry, ww.backend, instance, clock.New(), workflow.ExecutorOptions{
+			HistoryLimits:       ww.options.HistoryLimits,
+			DeterminismGuard:    ww.options.DeterminismGuard,
+			DeadlockTimeout:     ww.options.WorkflowDeadlockTimeout,
+			TaskTimeout:         ww.options.WorkflowTaskTimeout,
+			Tracer:              ww.backend.Tracer(),
+			LocalActivityRunner: ww.localActivities.ExecuteLocalActivity,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("creating workflow executor: %w", err)
+	}
+
+	return executor, nil
+}
+
 func (ww *workflowWorker) heartbeatTask(ctx context.Context, task *task.Workflow) {
-	// TODO: Make configurable
-	t := time.NewTicker(25 * time.Second)
+	t := time.NewTicker(ww.heartbeatInterval)
 	defer t.Stop()
 
 	for {
@@ -197,11 +465,17 @@ func (ww *workflowWorker) heartbeatTask(ctx context.Context, task *task.Workflow
 	}
 }
 
-func (ww *workflowWorker) poll(ctx context.Context, timeout time.Duration) (*task.Workflow, error) {
-	if timeout == 0 {
-		timeout = 30 * time.Second
+// knownSequenceID returns the sequence ID of the history of the cached executor for the given instance
+func (ww *workflowWorker) knownSequenceID(instance *core.WorkflowInstance) (int64, bool) {
+	e, ok, err := ww.cache.Get(context.Background(), instance)
+	if err != nil || !ok {
+		return 0, false
 	}
 
+	return e.LastSequenceID(), true
+}
+
+func (ww *workflowWorker) poll(ctx context.Context, timeout time.Duration) (*task.Workflow, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -211,7 +485,16 @@ func (ww *workflowWorker) poll(ctx context.Context, timeout time.Duration) (*tas
 	var err error
 
 	go func() {
-		task, err = ww.backend.GetWorkflowTask(ctx)
+		if cp, ok := ww.backend.(backend.CapabilityTaskProvider); ok && ww.capabilities != nil {
+			task, err = cp.GetWorkflowTaskForCapabilities(ctx, *ww.capabilities, ww.options.WorkflowQueues, ww.knownSequenceID)
+		} else if qp, ok := ww.backend.(backend.WorkflowQueueProvider); ok && len(ww.options.WorkflowQueues) > 0 {
+			task, err = qp.GetWorkflowTaskFromQueues(ctx, ww.options.WorkflowQueues, ww.knownSequenceID)
+		} else if ib, ok := ww.backend.(backend.IncrementalHistoryTaskProvider); ok {
+			task, err = ib.GetWorkflowTaskWithHistory(ctx, ww.knownSequenceID)
+		} else {
+			task, err = ww.backend.GetWorkflowTask(ctx)
+		}
+
 		close(done)
 	}()
 
@@ -3,6 +3,7 @@ package workflow
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -10,9 +11,30 @@ import (
 )
 
 type WorkflowExecutorCache interface {
+	// Store caches the executor for the given instance and marks it as in use, until it's released
 	Store(ctx context.Context, instance *core.WorkflowInstance, workflow WorkflowExecutor) error
 	Get(ctx context.Context, instance *core.WorkflowInstance) (WorkflowExecutor, bool, error)
+
+	// Release marks the cached executor for the given instance as idle. Only idle executors are evicted.
+	Release(ctx context.Context, instance *core.WorkflowInstance)
+
 	StartEviction(ctx context.Context)
+
+	// Flush closes and removes all idle executors
+	Flush()
+
+	// Entries returns a snapshot of the cached executors
+	Entries() []CacheEntry
+}
+
+// CacheEntry describes a cached workflow executor
+type CacheEntry struct {
+	InstanceID   string    `json:"instance_id"`
+	ExecutionID  string    `json:"execution_id"`
+	WorkflowName string    `json:"workflow_name"`
+	HistoryLen   int64     `json:"history_len"`
+	LastAccess   time.Time `json:"last_access"`
+	InUse        bool      `json:"in_use"`
 }
 
 type workflowExecutorCache struct {
@@ -20,22 +42,41 @@ type workflowExecutorCache struct {
 	t       *time.Ticker
 	mu      *sync.Mutex
 	cache   map[string]*workflowExecutorCacheEntry
+
+	lastMemorySample time.Time
 }
 
 type workflowExecutorCacheEntry struct {
+	instance   *core.WorkflowInstance
 	executor   WorkflowExecutor
 	lastAccess time.Time
+	inUse      bool
 }
 
 type WorkflowExecutorCacheOptions struct {
 	// CacheDuration is the duration after which a workflow executor is removed from the cache.
 	CacheDuration time.Duration
+
+	// MaxHistoryEvents limits the number of history events retained by all cached executors. Once exceeded,
+	// the least recently used executors are evicted. The default is 0 which is no limit.
+	MaxHistoryEvents int64
+
+	// MaxMemoryBytes is the memory usage, as reported by MemoryUsage, above which the least recently used
+	// executors are evicted. The default is 0 which does not take memory into account.
+	MaxMemoryBytes uint64
+
+	// MemoryUsage returns the memory currently used by the process. Required for MaxMemoryBytes.
+	MemoryUsage func() uint64
 }
 
 var DefaultWorkflowExecutorCacheOptions = WorkflowExecutorCacheOptions{
 	CacheDuration: 30 * time.Second,
 }
 
+// memorySampleInterval is how often memory usage is checked at most. Evicted executors are only freed by the
+// next garbage collection, checking more often would evict more executors than necessary.
+const memorySampleInterval = time.Second
+
 func NewWorkflowExecutorCache(options WorkflowExecutorCacheOptions) WorkflowExecutorCache {
 	c := workflowExecutorCache{
 		options: options,
@@ -57,8 +98,10 @@ func (c *workflowExecutorCache) Store(ctx context.Context, instance *core.Workfl
 	}
 
 	c.cache[getKey(instance)] = &workflowExecutorCacheEntry{
+		instance:   instance,
 		executor:   executor,
 		lastAccess: time.Now(),
+		inUse:      true,
 	}
 
 	return nil
@@ -76,6 +119,18 @@ func (c *workflowExecutorCache) Get(ctx context.Context, instance *core.Workflow
 	return nil, false, nil
 }
 
+func (c *workflowExecutorCache) Release(ctx context.Context, instance *core.WorkflowInstance) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.cache[getKey(instance)]; ok {
+		entry.inUse = false
+		entry.lastAccess = time.Now()
+	}
+
+	c.evictForLimits()
+}
+
 func (c *workflowExecutorCache) StartEviction(ctx context.Context) {
 	for {
 		select {
@@ -86,7 +141,7 @@ func (c *workflowExecutorCache) StartEviction(ctx context.Context) {
 
 			// Check cache entries for eviction
 			for instance, entry := range c.cache {
-				if entry.lastAccess.Before(cutoff) {
+				if !entry.inUse && entry.lastAccess.Before(cutoff) {
 					entry.executor.Close()
 
 					delete(c.cache, instance)
@@ -101,6 +156,107 @@ func (c *workflowExecutorCache) StartEviction(ctx context.Context) {
 	}
 }
 
+func (c *workflowExecutorCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.cache {
+		if !entry.inUse {
+			entry.executor.Close()
+
+			delete(c.cache, key)
+		}
+	}
+}
+
+func (c *workflowExecutorCache) Entries() []CacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]CacheEntry, 0, len(c.cache))
+	for _, entry := range c.cache {
+		entries = append(entries, CacheEntry{
+			InstanceID:   entry.instance.InstanceID,
+			ExecutionID:  entry.instance.ExecutionID,
+			WorkflowName: entry.executor.WorkflowName(),
+			HistoryLen:   entry.executor.LastSequenceID(),
+			LastAccess:   entry.lastAccess,
+			InUse:        entry.inUse,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastAccess.After(entries[j].LastAccess)
+	})
+
+	return entries
+}
+
+// evictForLimits evicts the least recently used idle executors while the cache exceeds the configured history
+// or memory limits. Must be called with the lock held.
+func (c *workflowExecutorCache) evictForLimits() {
+	var events int64
+	if c.options.MaxHistoryEvents > 0 {
+		for _, entry := range c.cache {
+			events += entry.executor.LastSequenceID()
+		}
+	}
+
+	overHistory := c.options.MaxHistoryEvents > 0 && events > c.options.MaxHistoryEvents
+	overMemory := c.exceedsMemory()
+
+	if !overHistory && !overMemory {
+		return
+	}
+
+	idle := make([]string, 0, len(c.cache))
+	for key, entry := range c.cache {
+		if !entry.inUse {
+			idle = append(idle, key)
+		}
+	}
+
+	sort.Slice(idle, func(i, j int) bool {
+		return c.cache[idle[i]].lastAccess.Before(c.cache[idle[j]].lastAccess)
+	})
+
+	// Under memory pressure, evict a quarter of the idle executors, at least one. Memory usage is checked
+	// again after the evicted executors had a chance to be garbage collected.
+	toEvict := 0
+	if overMemory {
+		toEvict = (len(idle) + 3) / 4
+	}
+
+	for i, key := range idle {
+		if i >= toEvict && (!overHistory || events <= c.options.MaxHistoryEvents) {
+			break
+		}
+
+		entry := c.cache[key]
+		events -= entry.executor.LastSequenceID()
+
+		entry.executor.Close()
+		delete(c.cache, key)
+	}
+}
+
+// exceedsMemory returns whether the memory usage exceeds the configured limit. Memory usage is sampled at most
+// once per memorySampleInterval. Must be called with the lock held.
+func (c *workflowExecutorCache) exceedsMemory() bool {
+	if c.options.MaxMemoryBytes == 0 || c.options.MemoryUsage == nil {
+		return false
+	}
+
+	now := time.Now()
+	if now.Sub(c.lastMemorySample) < memorySampleInterval {
+		return false
+	}
+
+	c.lastMemorySample = now
+
+	return c.options.MemoryUsage() > c.options.MaxMemoryBytes
+}
+
 func getKey(instance *core.WorkflowInstance) string {
 	return fmt.Sprintf("%s-%s", instance.InstanceID, instance.ExecutionID)
 }
@@ -7,8 +7,12 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/logger"
+	mi "github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,7 +23,7 @@ func Test_Cache_StoreAndGet(t *testing.T) {
 
 	r := NewRegistry()
 	r.RegisterWorkflow(workflowWithActivity)
-	e, err := NewExecutor(logger.NewDefaultLogger(), r, &testHistoryProvider{}, i, clock.New())
+	e, err := NewExecutor(logger.NewDefaultLogger(), mi.NewNoopMetricsClient(), converter.DefaultConverter, r, &testHistoryProvider{}, i, clock.New(), ExecutorOptions{})
 	require.NoError(t, err)
 
 	err = c.Store(context.Background(), i, e)
@@ -32,6 +36,28 @@ func Test_Cache_StoreAndGet(t *testing.T) {
 	require.Equal(t, e, e2)
 }
 
+func Test_Cache_Entries(t *testing.T) {
+	c := NewWorkflowExecutorCache(DefaultWorkflowExecutorCacheOptions)
+
+	i := core.NewWorkflowInstance("instanceID", "executionID")
+
+	r := NewRegistry()
+	r.RegisterWorkflow(workflowWithActivity)
+	e, err := NewExecutor(logger.NewDefaultLogger(), mi.NewNoopMetricsClient(), converter.DefaultConverter, r, &testHistoryProvider{}, i, clock.New(), ExecutorOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, c.Store(context.Background(), i, e))
+
+	entries := c.Entries()
+	require.Len(t, entries, 1)
+	require.Equal(t, "instanceID", entries[0].InstanceID)
+	require.Equal(t, "executionID", entries[0].ExecutionID)
+	require.True(t, entries[0].InUse)
+
+	c.Release(context.Background(), i)
+	require.False(t, c.Entries()[0].InUse)
+}
+
 func Test_Cache_Evict(t *testing.T) {
 	c := NewWorkflowExecutorCache(WorkflowExecutorCacheOptions{
 		CacheDuration: 1, // Should evict immediately
@@ -40,12 +66,14 @@ func Test_Cache_Evict(t *testing.T) {
 	i := core.NewWorkflowInstance("instanceID", "executionID")
 	r := NewRegistry()
 	r.RegisterWorkflow(workflowWithActivity)
-	e, err := NewExecutor(logger.NewDefaultLogger(), r, &testHistoryProvider{}, i, clock.New())
+	e, err := NewExecutor(logger.NewDefaultLogger(), mi.NewNoopMetricsClient(), converter.DefaultConverter, r, &testHistoryProvider{}, i, clock.New(), ExecutorOptions{})
 	require.NoError(t, err)
 
 	err = c.Store(context.Background(), i, e)
 	require.NoError(t, err)
 
+	c.Release(context.Background(), i)
+
 	go c.StartEviction(context.Background())
 	time.Sleep(1 * time.Millisecond)
 	runtime.Gosched()
@@ -55,3 +83,102 @@ func Test_Cache_Evict(t *testing.T) {
 	require.False(t, ok)
 	require.Nil(t, e2)
 }
+
+type testExecutor struct {
+	sequenceID int64
+	closed     bool
+}
+
+func (e *testExecutor) ExecuteTask(ctx context.Context, t *task.Workflow) (*ExecutionResult, error) {
+	return &ExecutionResult{}, nil
+}
+
+func (e *testExecutor) LastSequenceID() int64 {
+	return e.sequenceID
+}
+
+func (e *testExecutor) WorkflowName() string {
+	return ""
+}
+
+func (e *testExecutor) Query(ctx context.Context, name string, inputs []payload.Payload) (payload.Payload, error) {
+	return nil, nil
+}
+
+func (e *testExecutor) Close() {
+	e.closed = true
+}
+
+func Test_Cache_EvictsLeastRecentlyUsedOverHistoryLimit(t *testing.T) {
+	c := NewWorkflowExecutorCache(WorkflowExecutorCacheOptions{
+		CacheDuration:    time.Hour,
+		MaxHistoryEvents: 15,
+	})
+
+	ctx := context.Background()
+	i1 := core.NewWorkflowInstance("i1", "e1")
+	i2 := core.NewWorkflowInstance("i2", "e2")
+	e1 := &testExecutor{sequenceID: 10}
+	e2 := &testExecutor{sequenceID: 10}
+
+	require.NoError(t, c.Store(ctx, i1, e1))
+	c.Release(ctx, i1)
+
+	require.NoError(t, c.Store(ctx, i2, e2))
+	c.Release(ctx, i2)
+
+	_, ok, _ := c.Get(ctx, i1)
+	require.False(t, ok)
+	require.True(t, e1.closed)
+
+	_, ok, _ = c.Get(ctx, i2)
+	require.True(t, ok)
+	require.False(t, e2.closed)
+}
+
+func Test_Cache_KeepsExecutorsInUse(t *testing.T) {
+	c := NewWorkflowExecutorCache(WorkflowExecutorCacheOptions{
+		CacheDuration:    time.Hour,
+		MaxHistoryEvents: 15,
+	})
+
+	ctx := context.Background()
+	i1 := core.NewWorkflowInstance("i1", "e1")
+	i2 := core.NewWorkflowInstance("i2", "e2")
+	e1 := &testExecutor{sequenceID: 10}
+	e2 := &testExecutor{sequenceID: 10}
+
+	require.NoError(t, c.Store(ctx, i1, e1))
+
+	require.NoError(t, c.Store(ctx, i2, e2))
+	c.Release(ctx, i2)
+
+	// i1 is still executing, evict i2 instead
+	_, ok, _ := c.Get(ctx, i1)
+	require.True(t, ok)
+	require.False(t, e1.closed)
+	require.True(t, e2.closed)
+}
+
+func Test_Cache_EvictsUnderMemoryPressure(t *testing.T) {
+	var memory uint64 = 200
+
+	c := NewWorkflowExecutorCache(WorkflowExecutorCacheOptions{
+		CacheDuration:  time.Hour,
+		MaxMemoryBytes: 100,
+		MemoryUsage: func() uint64 {
+			return memory
+		},
+	})
+
+	ctx := context.Background()
+	i := core.NewWorkflowInstance("i1", "e1")
+	e := &testExecutor{sequenceID: 1}
+
+	require.NoError(t, c.Store(ctx, i, e))
+	c.Release(ctx, i)
+
+	_, ok, _ := c.Get(ctx, i)
+	require.False(t, ok)
+	require.True(t, e.closed)
+}
@@ -5,17 +5,26 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	gosync "sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/guard"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/trace"
+	"github.com/google/uuid"
 )
 
 type ExecutionResult struct {
@@ -23,6 +32,10 @@ type ExecutionResult struct {
 	Executed       []history.Event
 	ActivityEvents []history.Event
 	WorkflowEvents []history.WorkflowEvent
+
+	// HistoryLength and HistorySize are the number of events and the size in bytes of the history after the task
+	HistoryLength int64
+	HistorySize   int64
 }
 
 type WorkflowHistoryProvider interface {
@@ -32,10 +45,57 @@ type WorkflowHistoryProvider interface {
 type WorkflowExecutor interface {
 	ExecuteTask(ctx context.Context, t *task.Workflow) (*ExecutionResult, error)
 
+	// LastSequenceID returns the sequence ID of the history the executor has applied. It's safe to call while a
+	// task is executed.
+	LastSequenceID() int64
+
+	// WorkflowName returns the name of the executed workflow, or an empty string if the workflow has not been
+	// started yet. It's safe to call while a task is executed.
+	WorkflowName() string
+
+	// Query answers the query with the given name from the state of the workflow, after replaying history the
+	// executor has not seen yet. It never adds to the history of the workflow instance.
+	Query(ctx context.Context, name string, inputs []payload.Payload) (payload.Payload, error)
+
 	Close()
 }
 
+// ExecutorOptions configure how a workflow executor runs workflow tasks
+type ExecutorOptions struct {
+	// HistoryLimits configures thresholds for the history of the workflow instance
+	HistoryLimits HistoryLimits
+
+	// DeterminismGuard checks the workflow code for non-deterministic calls
+	DeterminismGuard guard.Mode
+
+	// DeadlockTimeout is how long workflow code may run without yielding. Defaults to sync.DeadlockDetection.
+	DeadlockTimeout time.Duration
+
+	// TaskTimeout limits how long a single workflow task may take, unless the workflow has been registered with
+	// a task timeout. The default is 0 which means no limit.
+	TaskTimeout time.Duration
+
+	// Tracer records a span for every workflow task. Defaults to a tracer discarding spans.
+	Tracer trace.Tracer
+
+	// LocalActivityRunner executes local activities. Without it, local activities fail unless they are replayed.
+	LocalActivityRunner workflowstate.LocalActivityRunner
+}
+
+// ErrWorkflowTaskTimeout is the error a workflow fails with when a workflow task exceeds its task timeout
+var ErrWorkflowTaskTimeout = errors.New("workflow task timed out")
+
+// ErrUnknownQuery is returned when querying a workflow without a handler for the query
+var ErrUnknownQuery = errors.New("unknown query")
+
+// ErrExecutorClosed is returned when querying an executor after it has been closed
+var ErrExecutorClosed = errors.New("executor has been closed")
+
 type executor struct {
+	// mu serializes tasks, queries, and closing the executor
+	mu     gosync.Mutex
+	closed bool
+
 	registry          *Registry
 	historyProvider   WorkflowHistoryProvider
 	workflow          *workflow
@@ -44,12 +104,58 @@ type executor struct {
 	workflowCtxCancel sync.CancelFunc
 	clock             clock.Clock
 	logger            log.Logger
+	converter         converter.Converter
+	limits            HistoryLimits
+	guard             *guard.Guard
+	taskTimeout       time.Duration
+	tracer            trace.Tracer
 	lastSequenceID    int64
+
+	// traceContext is the trace context of the span that created the workflow instance
+	traceContext map[string]string
+
+	// started are the attributes the current execution has been started with
+	started *history.ExecutionStartedAttributes
+
+	// taskStarted is when execution of the current workflow task started
+	taskStarted time.Time
+
+	// subWorkflows are the sub-workflows of the current execution that have not finished yet, by schedule event ID
+	subWorkflows map[int64]*command.ScheduleSubWorkflowCommandAttr
+
+	// terminated is set once the parent of the instance has terminated it, see core.ParentClosePolicyTerminate
+	terminated bool
+
+	// appliedSequenceID is lastSequenceID after the last task, it's accessed atomically
+	appliedSequenceID int64
+
+	// workflowName is the name of the executed workflow once it has been started, it's accessed atomically
+	workflowName atomic.Value
 }
 
-func NewExecutor(logger log.Logger, registry *Registry, historyProvider WorkflowHistoryProvider, instance *core.WorkflowInstance, clock clock.Clock) (WorkflowExecutor, error) {
-	s := workflowstate.NewWorkflowState(instance, logger, clock)
-	wfCtx, cancel := sync.WithCancel(workflowstate.WithWorkflowState(sync.Background(), s))
+func NewExecutor(logger log.Logger, metrics metrics.Client, converter converter.Converter, registry *Registry, historyProvider WorkflowHistoryProvider, instance *core.WorkflowInstance, clock clock.Clock, options ExecutorOptions) (WorkflowExecutor, error) {
+	s := workflowstate.NewWorkflowState(instance, logger, metrics, converter, clock)
+	s.SetActivityOptions(registry.GetActivityOptions)
+	s.SetLocalActivityRunner(options.LocalActivityRunner)
+
+	ctx := workflowstate.WithWorkflowState(sync.Background(), s)
+
+	var g *guard.Guard
+	if options.DeterminismGuard != guard.ModeOff {
+		g = &guard.Guard{Mode: options.DeterminismGuard, Logger: logger, Metrics: metrics}
+		ctx = sync.WithGuard(ctx, g)
+	}
+
+	if options.DeadlockTimeout > 0 {
+		ctx = sync.WithDeadlockDetection(ctx, options.DeadlockTimeout)
+	}
+
+	wfCtx, cancel := sync.WithCancel(ctx)
+
+	tracer := options.Tracer
+	if tracer == nil {
+		tracer = tracing.NewNoopTracer()
+	}
 
 	return &executor{
 		registry:          registry,
@@ -59,22 +165,40 @@ func NewExecutor(logger log.Logger, registry *Registry, historyProvider Workflow
 		workflowCtxCancel: cancel,
 		clock:             clock,
 		logger:            logger,
+		converter:         converter,
+		limits:            options.HistoryLimits,
+		guard:             g,
+		taskTimeout:       options.TaskTimeout,
+		tracer:            tracer,
+		subWorkflows:      make(map[int64]*command.ScheduleSubWorkflowCommandAttr),
 	}, nil
 }
 
 func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) (*ExecutionResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	e.logger.Debug("Executing workflow task", "task_id", t.ID, "instance_id", t.WorkflowInstance.InstanceID)
 
 	e.workflowState.ClearCommands()
 
+	e.taskStarted = time.Now()
+
 	skipNewEvents := false
 
 	if t.LastSequenceID > e.lastSequenceID {
 		e.logger.Debug("Task has newer history than current state, fetching and replaying history", "task_sequence_id", t.LastSequenceID, "sequence_id", e.lastSequenceID)
 
-		h, err := e.historyProvider.GetWorkflowInstanceHistory(ctx, t.WorkflowInstance, &e.lastSequenceID)
-		if err != nil {
-			return nil, fmt.Errorf("getting workflow history: %w", err)
+		var h []history.Event
+		if t.History != nil && t.HistorySequenceID == e.lastSequenceID {
+			// Backend delivered the missing history with the task
+			h = t.History
+		} else {
+			var err error
+			h, err = e.historyProvider.GetWorkflowInstanceHistory(ctx, t.WorkflowInstance, &e.lastSequenceID)
+			if err != nil {
+				return nil, fmt.Errorf("getting workflow history: %w", err)
+			}
 		}
 
 		if err := e.replayHistory(h); err != nil {
@@ -90,6 +214,33 @@ func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) (*Executio
 		return nil, fmt.Errorf("task has older history than current state, cannot execute")
 	}
 
+	if !skipNewEvents && e.limits.exceeded(e.workflowState.HistoryLength(), e.workflowState.HistorySize()) {
+		e.logger.Error("Workflow history limit exceeded, failing workflow",
+			"history_length", e.workflowState.HistoryLength(),
+			"history_size", e.workflowState.HistorySize(),
+		)
+
+		// Fail the workflow instead of producing new commands
+		e.workflowCompleted(nil, fmt.Errorf("%w: %v events, %v bytes",
+			ErrHistoryLimitExceeded, e.workflowState.HistoryLength(), e.workflowState.HistorySize()))
+		skipNewEvents = true
+	}
+
+	// The first task of an instance carries its started event
+	if e.traceContext == nil {
+		for _, event := range t.NewEvents {
+			if a, ok := event.Attributes.(*history.ExecutionStartedAttributes); ok {
+				e.traceContext = a.TraceContext
+			}
+		}
+	}
+
+	ctx, span := e.tracer.Start(tracing.Extract(e.tracer, ctx, e.traceContext), "WorkflowTask", map[string]string{
+		"instance_id":  t.WorkflowInstance.InstanceID,
+		"execution_id": t.WorkflowInstance.ExecutionID,
+	})
+	defer span.End()
+
 	// Always add a WorkflowTaskStarted event before executing new tasks
 	toExecute := []history.Event{e.createNewEvent(history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{})}
 	executedEvents := toExecute
@@ -115,11 +266,36 @@ func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) (*Executio
 
 	executedEvents = append(executedEvents, newCommandEvents...)
 
+	if e.terminated {
+		completed = true
+		workflowEvents = append(workflowEvents, e.closeSubWorkflows()...)
+	}
+
+	e.propagateTraceContext(activityEvents, workflowEvents)
+
+	if !completed && !skipNewEvents {
+		workflowEvents = append(workflowEvents, e.scheduledCancellation(t)...)
+	}
+
+	for _, event := range newCommandEvents {
+		e.workflowState.AddHistoryEvent(history.AttributesSize(event))
+	}
+
+	if !completed {
+		if warning := e.checkHistoryLimits(); warning != nil {
+			executedEvents = append(executedEvents, *warning)
+		}
+	}
+
 	// Set SequenceIDs for all executed events
 	for i := range executedEvents {
 		executedEvents[i].SequenceID = e.nextSequenceID()
 	}
 
+	atomic.StoreInt64(&e.appliedSequenceID, e.lastSequenceID)
+
+	span.SetAttributes(map[string]string{"workflow": e.WorkflowName()})
+
 	e.logger.Debug("Finished workflow task",
 		"task_id", t.ID,
 		"instance_id", t.WorkflowInstance.InstanceID,
@@ -133,10 +309,58 @@ func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) (*Executio
 		Executed:       executedEvents,
 		ActivityEvents: activityEvents,
 		WorkflowEvents: workflowEvents,
+		HistoryLength:  e.workflowState.HistoryLength(),
+		HistorySize:    e.workflowState.HistorySize(),
 	}, nil
 }
 
+// propagateTraceContext passes the trace context of the instance on to the activities and sub-workflows it
+// schedules, so their spans become part of the same trace
+func (e *executor) propagateTraceContext(activityEvents []history.Event, workflowEvents []history.WorkflowEvent) {
+	if e.traceContext == nil {
+		return
+	}
+
+	for _, event := range activityEvents {
+		if a, ok := event.Attributes.(*history.ActivityScheduledAttributes); ok {
+			a.TraceContext = e.traceContext
+		}
+	}
+
+	for _, event := range workflowEvents {
+		if a, ok := event.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes); ok && a.TraceContext == nil {
+			a.TraceContext = e.traceContext
+		}
+	}
+}
+
+// scheduledCancellation returns a cancellation event for the instance that becomes visible after the instance's
+// CancelAfter deadline, if the given task starts the instance with a deadline
+func (e *executor) scheduledCancellation(t *task.Workflow) []history.WorkflowEvent {
+	for _, event := range t.NewEvents {
+		a, ok := event.Attributes.(*history.ExecutionStartedAttributes)
+		if !ok || a.CancelAfter <= 0 {
+			continue
+		}
+
+		return []history.WorkflowEvent{{
+			WorkflowInstance: t.WorkflowInstance,
+			HistoryEvent: e.createNewEvent(
+				history.EventType_WorkflowExecutionCanceled,
+				&history.ExecutionCanceledAttributes{},
+				history.VisibleAt(event.Timestamp.Add(a.CancelAfter)),
+			),
+		}}
+	}
+
+	return nil
+}
+
 func (e *executor) replayHistory(history []history.Event) error {
+	if err := e.recordVersions(history); err != nil {
+		return err
+	}
+
 	e.workflowState.SetReplaying(true)
 	for _, event := range history {
 		if err := e.executeEvent(event); err != nil {
@@ -144,6 +368,34 @@ func (e *executor) replayHistory(history []history.Event) error {
 		}
 
 		e.lastSequenceID = event.SequenceID
+
+		if err := e.checkTaskTimeout(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordVersions makes the versions recorded in the given history available to workflow code before it is
+// replayed. Workflow code asks for a version before the marker recording it is replayed.
+func (e *executor) recordVersions(h []history.Event) error {
+	for _, event := range h {
+		if event.Type != history.EventType_MarkerRecorded {
+			continue
+		}
+
+		a := event.Attributes.(*history.MarkerRecordedAttributes)
+		if a.Name != workflowstate.VersionMarker {
+			continue
+		}
+
+		var data workflowstate.VersionMarkerData
+		if err := e.converter.From(a.Data, &data); err != nil {
+			return fmt.Errorf("decoding version marker: %w", err)
+		}
+
+		e.workflowState.SetRecordedVersion(data.ChangeID, data.Version)
 	}
 
 	return nil
@@ -156,6 +408,15 @@ func (e *executor) executeNewEvents(newEvents []history.Event) ([]history.Event,
 		if err := e.executeEvent(event); err != nil {
 			return newEvents[:i], err
 		}
+
+		if e.terminated {
+			// Events after the termination are not executed anymore
+			return newEvents[:i+1], nil
+		}
+
+		if err := e.checkTaskTimeout(); err != nil {
+			return newEvents[:i+1], err
+		}
 	}
 
 	if e.workflow.Completed() {
@@ -165,7 +426,85 @@ func (e *executor) executeNewEvents(newEvents []history.Event) ([]history.Event,
 	return newEvents, nil
 }
 
+// checkTaskTimeout returns an error if the current workflow task has been running longer than its task timeout
+func (e *executor) checkTaskTimeout() error {
+	timeout := e.taskTimeout
+	options, ok := e.registry.GetWorkflowOptions(e.WorkflowName())
+	if !ok {
+		// Workflows handled by the dynamic workflow use its options
+		_, options, _ = e.registry.GetDynamicWorkflow()
+	}
+
+	if options.TaskTimeout > 0 {
+		timeout = options.TaskTimeout
+	}
+
+	if timeout <= 0 {
+		return nil
+	}
+
+	if elapsed := time.Since(e.taskStarted); elapsed > timeout {
+		return fmt.Errorf("%w: task has been running for %v, longer than the task timeout of %v",
+			ErrWorkflowTaskTimeout, elapsed.Round(time.Millisecond), timeout)
+	}
+
+	return nil
+}
+
+func (e *executor) LastSequenceID() int64 {
+	return atomic.LoadInt64(&e.appliedSequenceID)
+}
+
+func (e *executor) WorkflowName() string {
+	name, _ := e.workflowName.Load().(string)
+	return name
+}
+
+func (e *executor) Query(ctx context.Context, name string, inputs []payload.Payload) (result payload.Payload, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, ErrExecutorClosed
+	}
+
+	e.taskStarted = time.Now()
+
+	h, err := e.historyProvider.GetWorkflowInstanceHistory(ctx, e.workflowState.Instance(), &e.lastSequenceID)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow history: %w", err)
+	}
+
+	if err := e.replayHistory(h); err != nil {
+		return nil, fmt.Errorf("replaying history: %w", err)
+	}
+
+	atomic.StoreInt64(&e.appliedSequenceID, e.lastSequenceID)
+
+	if e.workflow == nil {
+		return nil, errors.New("workflow has not been started yet")
+	}
+
+	handler, ok := e.workflowState.QueryHandler(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownQuery, name)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("query handler panicked: %v", r)
+		}
+	}()
+
+	return handler(inputs)
+}
+
 func (e *executor) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+
 	if e.workflow != nil {
 		// End workflow if running to prevent leaking goroutines
 		e.workflow.Close(e.workflowCtx)
@@ -180,15 +519,21 @@ func (e *executor) executeEvent(event history.Event) error {
 		"event_type", event.Type,
 	)
 
+	e.workflowState.AddHistoryEvent(history.AttributesSize(event))
+
 	var err error
 
 	switch event.Type {
 	case history.EventType_WorkflowExecutionStarted:
 		err = e.handleWorkflowExecutionStarted(event.Attributes.(*history.ExecutionStartedAttributes))
 
-	case history.EventType_WorkflowExecutionFinished:
+	case history.EventType_WorkflowExecutionFinished, history.EventType_WorkflowExecutionForceCompleted,
+		history.EventType_WorkflowExecutionContinuedAsNew:
 	// Ignore
 
+	case history.EventType_WorkflowExecutionTerminated:
+		e.handleWorkflowTerminated()
+
 	case history.EventType_WorkflowExecutionCanceled:
 		err = e.handleWorkflowCanceled()
 
@@ -228,6 +573,15 @@ func (e *executor) executeEvent(event history.Event) error {
 	case history.EventType_SubWorkflowCompleted:
 		err = e.handleSubWorkflowCompleted(event, event.Attributes.(*history.SubWorkflowCompletedAttributes))
 
+	case history.EventType_HistoryLimitWarning:
+		e.workflowState.SetContinueAsNewSuggested(true)
+
+	case history.EventType_WorkflowTagsAdded:
+	// Ignore, tags are only used by the backend
+
+	case history.EventType_MarkerRecorded:
+		err = e.handleMarkerRecorded(event, event.Attributes.(*history.MarkerRecordedAttributes))
+
 	default:
 		return fmt.Errorf("unknown event type: %v", event.Type)
 	}
@@ -236,12 +590,25 @@ func (e *executor) executeEvent(event history.Event) error {
 }
 
 func (e *executor) handleWorkflowExecutionStarted(a *history.ExecutionStartedAttributes) error {
-	wfFn, err := e.registry.GetWorkflow(a.Name)
-	if err != nil {
+	if wfFn, err := e.registry.GetWorkflow(a.Name); err == nil {
+		e.workflow = NewWorkflow(reflect.ValueOf(wfFn), e.converter)
+
+		if options, ok := e.registry.GetWorkflowOptions(a.Name); ok {
+			e.workflow.validator = options.Validator
+		}
+	} else if dynamic, _, ok := e.registry.GetDynamicWorkflow(); ok {
+		e.workflow = NewDynamicWorkflow(a.Name, dynamic, e.converter)
+	} else {
 		return fmt.Errorf("workflow %s not found", a.Name)
 	}
 
-	e.workflow = NewWorkflow(reflect.ValueOf(wfFn))
+	e.workflowName.Store(a.Name)
+	e.traceContext = a.TraceContext
+	e.started = a
+
+	if e.guard != nil {
+		e.guard.Workflow = a.Name
+	}
 
 	return e.workflow.Execute(e.workflowCtx, a.Inputs)
 }
@@ -252,6 +619,19 @@ func (e *executor) handleWorkflowCanceled() error {
 	return e.workflow.Continue(e.workflowCtx)
 }
 
+// handleWorkflowTerminated stops the workflow when its parent has terminated it. Instances terminated by a client
+// are finished by the backend, the event is only replayed for them.
+func (e *executor) handleWorkflowTerminated() {
+	if e.workflowState.Replaying() {
+		return
+	}
+
+	e.terminated = true
+
+	// Discard anything the workflow has done in this task
+	e.workflowState.ClearCommands()
+}
+
 func (e *executor) handleWorkflowTaskStarted(event history.Event, a *history.WorkflowTaskStartedAttributes) error {
 	e.workflowState.SetTime(event.Timestamp)
 
@@ -299,7 +679,7 @@ func (e *executor) handleActivityFailed(event history.Event, a *history.Activity
 		return errors.New("no pending future for activity failed event")
 	}
 
-	if err := f(nil, errors.New(a.Reason)); err != nil {
+	if err := f(nil, failureError(e.converter, a.Failure, a.Reason)); err != nil {
 		return fmt.Errorf("setting result: %w", err)
 	}
 
@@ -322,10 +702,15 @@ func (e *executor) handleTimerScheduled(event history.Event, a *history.TimerSch
 func (e *executor) handleTimerFired(event history.Event, a *history.TimerFiredAttributes) error {
 	f, ok := e.workflowState.FutureByScheduleEventID(event.ScheduleEventID)
 	if !ok {
-		// Timer already canceled ignore
+		// Timer already canceled or fired, ignore. A backend might deliver the fired event even though the timer
+		// was canceled concurrently, or deliver it more than once.
+		e.logger.Debug("Ignoring fired event for canceled or already fired timer", "schedule_event_id", event.ScheduleEventID)
 		return nil
 	}
 
+	// Timers fire only once, ignore any later events for this timer
+	e.workflowState.RemoveFuture(event.ScheduleEventID)
+
 	if err := f(nil, nil); err != nil {
 		return fmt.Errorf("setting result: %w", err)
 	}
@@ -336,10 +721,13 @@ func (e *executor) handleTimerFired(event history.Event, a *history.TimerFiredAt
 func (e *executor) handleTimerCanceled(event history.Event, a *history.TimerCanceledAttributes) error {
 	f, ok := e.workflowState.FutureByScheduleEventID(event.ScheduleEventID)
 	if !ok {
-		// Timer already canceled ignore
+		// Timer already canceled or fired, ignore
 		return nil
 	}
 
+	// A fired event racing the cancellation must not resolve the timer again
+	e.workflowState.RemoveFuture(event.ScheduleEventID)
+
 	if err := f(nil, sync.Canceled); err != nil {
 		return fmt.Errorf("setting result: %w", err)
 	}
@@ -367,6 +755,8 @@ func (e *executor) handleSubWorkflowScheduled(event history.Event, a *history.Su
 	// this message.
 	ca.Instance = a.SubWorkflowInstance
 
+	e.subWorkflows[event.ScheduleEventID] = ca
+
 	return nil
 }
 
@@ -384,19 +774,33 @@ func (e *executor) handleSubWorkflowCancellationRequest(event history.Event, a *
 }
 
 func (e *executor) handleSubWorkflowFailed(event history.Event, a *history.SubWorkflowFailedAttributes) error {
+	delete(e.subWorkflows, event.ScheduleEventID)
+
 	f, ok := e.workflowState.FutureByScheduleEventID(event.ScheduleEventID)
 	if !ok {
 		return errors.New("no pending future found for sub workflow failed event")
 	}
 
-	if err := f(nil, errors.New(a.Error)); err != nil {
+	if err := f(nil, failureError(e.converter, a.Failure, a.Error)); err != nil {
 		return fmt.Errorf("setting result: %w", err)
 	}
 
 	return e.workflow.Continue(e.workflowCtx)
 }
 
+// failureError returns the structured failure if one was recorded, falling back to the plain error message
+// for events written before failures were recorded
+func failureError(c converter.Converter, f *history.Failure, message string) error {
+	if f != nil {
+		return history.FailureWithConverter(f, c)
+	}
+
+	return errors.New(message)
+}
+
 func (e *executor) handleSubWorkflowCompleted(event history.Event, a *history.SubWorkflowCompletedAttributes) error {
+	delete(e.subWorkflows, event.ScheduleEventID)
+
 	f, ok := e.workflowState.FutureByScheduleEventID(event.ScheduleEventID)
 	if !ok {
 		return errors.New("no pending future found for sub workflow completed event")
@@ -427,10 +831,74 @@ func (e *executor) handleSideEffectResult(event history.Event, a *history.SideEf
 	return e.workflow.Continue(e.workflowCtx)
 }
 
+func (e *executor) handleMarkerRecorded(event history.Event, a *history.MarkerRecordedAttributes) error {
+	// Apart from local activity results, markers only annotate the history
+	if a.Name != workflowstate.LocalActivityMarker {
+		return nil
+	}
+
+	f, ok := e.workflowState.FutureByScheduleEventID(event.ScheduleEventID)
+	if !ok {
+		return errors.New("no pending future found for local activity result")
+	}
+
+	var data workflowstate.LocalActivityMarkerData
+	if err := e.converter.From(a.Data, &data); err != nil {
+		return fmt.Errorf("decoding local activity result: %w", err)
+	}
+
+	var err error
+	if data.Failure != nil {
+		err = failureError(e.converter, data.Failure, "")
+	}
+
+	if err := f(data.Result, err); err != nil {
+		return fmt.Errorf("setting result: %w", err)
+	}
+
+	return e.workflow.Continue(e.workflowCtx)
+}
+
+// checkHistoryLimits returns a warning event the first time the history grows beyond the configured
+// warning threshold
+func (e *executor) checkHistoryLimits() *history.Event {
+	if e.workflowState.ContinueAsNewSuggested() {
+		return nil
+	}
+
+	length, size := e.workflowState.HistoryLength(), e.workflowState.HistorySize()
+	if !e.limits.warn(length, size) {
+		return nil
+	}
+
+	e.logger.Warn("Workflow history is growing large, consider continuing as new",
+		"history_length", length,
+		"history_size", size,
+	)
+
+	event := e.createNewEvent(history.EventType_HistoryLimitWarning, &history.HistoryLimitWarningAttributes{
+		Length: length,
+		Size:   size,
+	})
+
+	e.workflowState.AddHistoryEvent(history.AttributesSize(event))
+	e.workflowState.SetContinueAsNewSuggested(true)
+
+	return &event
+}
+
 func (e *executor) workflowCompleted(result payload.Payload, err error) {
 	eventId := e.workflowState.GetNextScheduleEventID()
 
-	cmd := command.NewCompleteWorkflowCommand(eventId, result, err)
+	var cmd command.Command
+
+	var can *core.ContinueAsNewError
+	if errors.As(err, &can) {
+		cmd = command.NewContinueAsNewCommand(eventId, can.Inputs)
+	} else {
+		cmd = command.NewCompleteWorkflowCommand(eventId, e.converter, result, err, e.clock.Now())
+	}
+
 	e.workflowState.AddCommand(&cmd)
 }
 
@@ -455,8 +923,13 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) (bool,
 			scheduleActivityEvent := e.createNewEvent(
 				history.EventType_ActivityScheduled,
 				&history.ActivityScheduledAttributes{
-					Name:   a.Name,
-					Inputs: a.Inputs,
+					Name:                a.Name,
+					Inputs:              a.Inputs,
+					MemoizeFor:          a.MemoizeFor,
+					StartToCloseTimeout: a.StartToCloseTimeout,
+					HeartbeatTimeout:    a.HeartbeatTimeout,
+					Queue:               a.Queue,
+					Attempt:             a.Attempt,
 				},
 				history.ScheduleEventID(c.ID),
 			)
@@ -473,10 +946,13 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) (bool,
 					SubWorkflowInstance: a.Instance,
 					Name:                a.Name,
 					Inputs:              a.Inputs,
+					ParentClosePolicy:   a.ParentClosePolicy,
 				},
 				history.ScheduleEventID(c.ID),
 			))
 
+			e.subWorkflows[c.ID] = a
+
 			// Send message to new workflow instance
 			workflowEvents = append(workflowEvents, history.WorkflowEvent{
 				WorkflowInstance: a.Instance,
@@ -485,6 +961,7 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) (bool,
 					&history.ExecutionStartedAttributes{
 						Name:   a.Name,
 						Inputs: a.Inputs,
+						Queue:  subWorkflowQueue(a.Queue, e.started),
 					},
 					history.ScheduleEventID(c.ID),
 				),
@@ -496,9 +973,10 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) (bool,
 			// Record sub-workflow cancellation request event
 			newEvents = append(newEvents, e.createNewEvent(
 				history.EventType_SubWorkflowCancellationRequested,
-				&history.SubWorkflowScheduledAttributes{
+				&history.SubWorkflowCancellationRequestedAttributes{
 					SubWorkflowInstance: a.SubWorkflowInstance,
 				},
+				history.ScheduleEventID(c.ID),
 			))
 
 			// Send cancellation event to sub-workflow
@@ -517,6 +995,27 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) (bool,
 				history.ScheduleEventID(c.ID),
 			))
 
+		case command.CommandType_AddTags:
+			a := c.Attr.(*command.AddTagsCommandAttr)
+			newEvents = append(newEvents, e.createNewEvent(
+				history.EventType_WorkflowTagsAdded,
+				&history.WorkflowTagsAddedAttributes{
+					Tags: a.Tags,
+				},
+				history.ScheduleEventID(c.ID),
+			))
+
+		case command.CommandType_RecordMarker:
+			a := c.Attr.(*command.RecordMarkerCommandAttr)
+			newEvents = append(newEvents, e.createNewEvent(
+				history.EventType_MarkerRecorded,
+				&history.MarkerRecordedAttributes{
+					Name: a.Name,
+					Data: a.Data,
+				},
+				history.ScheduleEventID(c.ID),
+			))
+
 		case command.CommandType_ScheduleTimer:
 			a := c.Attr.(*command.ScheduleTimerCommandAttr)
 
@@ -558,8 +1057,9 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) (bool,
 			newEvents = append(newEvents, e.createNewEvent(
 				history.EventType_WorkflowExecutionFinished,
 				&history.ExecutionCompletedAttributes{
-					Result: a.Result,
-					Error:  a.Error,
+					Result:  a.Result,
+					Error:   a.Error,
+					Failure: a.Failure,
 				},
 				history.ScheduleEventID(c.ID),
 			))
@@ -573,7 +1073,8 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) (bool,
 					historyEvent = e.createNewEvent(
 						history.EventType_SubWorkflowFailed,
 						&history.SubWorkflowFailedAttributes{
-							Error: a.Error,
+							Error:   a.Error,
+							Failure: a.Failure,
 						},
 						// Ensure the message gets sent back to the parent workflow with the right eventID
 						history.ScheduleEventID(instance.ParentEventID),
@@ -595,6 +1096,54 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) (bool,
 				})
 			}
 
+			workflowEvents = append(workflowEvents, e.closeSubWorkflows()...)
+
+		case command.CommandType_ContinueAsNew:
+			completed = true
+
+			a := c.Attr.(*command.ContinueAsNewCommandAttr)
+
+			// The new execution keeps the instance ID and, for sub-workflows, the parent, which is notified once
+			// the last execution finishes
+			next := core.NewWorkflowInstance(instance.InstanceID, uuid.NewString())
+			if instance.SubWorkflow() {
+				next = core.NewSubWorkflowInstance(instance.InstanceID, next.ExecutionID, instance.ParentInstanceID, instance.ParentEventID)
+			}
+
+			newEvents = append(newEvents, e.createNewEvent(
+				history.EventType_WorkflowExecutionContinuedAsNew,
+				&history.ExecutionContinuedAsNewAttributes{
+					Inputs:      a.Inputs,
+					ExecutionID: next.ExecutionID,
+				},
+				history.ScheduleEventID(c.ID),
+			))
+
+			// Drop timers of the finished execution, they must not fire in the new one
+			pending := workflowEvents[:0]
+			for _, we := range workflowEvents {
+				if we.WorkflowInstance.InstanceID != instance.InstanceID {
+					pending = append(pending, we)
+				}
+			}
+			workflowEvents = pending
+
+			workflowEvents = append(workflowEvents, e.closeSubWorkflows()...)
+
+			workflowEvents = append(workflowEvents, history.WorkflowEvent{
+				WorkflowInstance: next,
+				HistoryEvent: e.createNewEvent(
+					history.EventType_WorkflowExecutionStarted,
+					&history.ExecutionStartedAttributes{
+						Name:     e.started.Name,
+						Inputs:   a.Inputs,
+						Priority: e.started.Priority,
+						Queue:    e.started.Queue,
+						Tags:     e.started.Tags,
+					},
+				),
+			})
+
 		default:
 			return false, nil, nil, nil, fmt.Errorf("unknown command type: %v", c.Type)
 		}
@@ -603,6 +1152,54 @@ func (e *executor) processCommands(ctx context.Context, t *task.Workflow) (bool,
 	return completed, newEvents, activityEvents, workflowEvents, nil
 }
 
+// subWorkflowQueue returns the queue for a sub-workflow, sub-workflows are placed on the queue of their parent
+// unless another queue is given
+func subWorkflowQueue(queue string, parent *history.ExecutionStartedAttributes) string {
+	if queue != "" || parent == nil {
+		return queue
+	}
+
+	return parent.Queue
+}
+
+// closeSubWorkflows returns the events applying the parent close policies of the sub-workflows that are still
+// running when the current execution finishes
+func (e *executor) closeSubWorkflows() []history.WorkflowEvent {
+	ids := make([]int64, 0, len(e.subWorkflows))
+	for id := range e.subWorkflows {
+		ids = append(ids, id)
+	}
+
+	// Keep the order of the events deterministic
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var workflowEvents []history.WorkflowEvent
+	for _, id := range ids {
+		a := e.subWorkflows[id]
+
+		switch a.ParentClosePolicy {
+		case core.ParentClosePolicyRequestCancel:
+			workflowEvents = append(workflowEvents, history.WorkflowEvent{
+				WorkflowInstance: a.Instance,
+				HistoryEvent:     history.NewWorkflowCancellationEvent(e.clock.Now()),
+			})
+
+		case core.ParentClosePolicyTerminate:
+			workflowEvents = append(workflowEvents, history.WorkflowEvent{
+				WorkflowInstance: a.Instance,
+				HistoryEvent: e.createNewEvent(
+					history.EventType_WorkflowExecutionTerminated,
+					&history.ExecutionTerminatedAttributes{
+						Reason: "parent workflow instance finished",
+					},
+				),
+			})
+		}
+	}
+
+	return workflowEvents
+}
+
 func (e *executor) nextSequenceID() int64 {
 	e.lastSequenceID++
 	return e.lastSequenceID
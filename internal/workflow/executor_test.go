@@ -8,16 +8,21 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/determinism"
 	"github.com/cschleiden/go-workflows/internal/command"
 	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/fn"
+	"github.com/cschleiden/go-workflows/internal/guard"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/logger"
+	mi "github.com/cschleiden/go-workflows/internal/metrics"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
+	"github.com/cschleiden/go-workflows/metrics"
 	wf "github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -33,18 +38,21 @@ func (t *testHistoryProvider) GetWorkflowInstanceHistory(ctx context.Context, in
 
 func newExecutor(r *Registry, i *core.WorkflowInstance, workflow interface{}, historyProvider WorkflowHistoryProvider) *executor {
 	logger := logger.NewDefaultLogger()
-	s := workflowstate.NewWorkflowState(i, logger, clock.New())
+	s := workflowstate.NewWorkflowState(i, logger, mi.NewNoopMetricsClient(), converter.DefaultConverter, clock.New())
 	wfCtx, cancel := sync.WithCancel(workflowstate.WithWorkflowState(sync.Background(), s))
 
 	return &executor{
 		registry:          r,
-		workflow:          NewWorkflow(reflect.ValueOf(workflow)),
+		workflow:          NewWorkflow(reflect.ValueOf(workflow), converter.DefaultConverter),
 		historyProvider:   historyProvider,
 		workflowState:     s,
 		workflowCtx:       wfCtx,
 		workflowCtxCancel: cancel,
 		logger:            logger,
+		converter:         converter.DefaultConverter,
 		clock:             clock.New(),
+		tracer:            tracing.NewNoopTracer(),
+		subWorkflows:      make(map[int64]*command.ScheduleSubWorkflowCommandAttr),
 	}
 }
 
@@ -92,6 +100,48 @@ func Test_ExecuteWorkflow(t *testing.T) {
 	require.Equal(t, command.CommandType_CompleteWorkflow, e.workflowState.Commands()[0].Type)
 }
 
+func Test_ExecuteWorkflow_DynamicWorkflow(t *testing.T) {
+	var name string
+	var inputs []payload.Payload
+
+	r := NewRegistry()
+	require.NoError(t, r.RegisterDynamicWorkflow(func(ctx sync.Context, n string, i []payload.Payload) (payload.Payload, error) {
+		name = n
+		inputs = i
+		return payload.Payload("42"), nil
+	}))
+
+	task := &task.Workflow{
+		ID:               "taskID",
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		NewEvents: []history.Event{
+			history.NewHistoryEvent(
+				1,
+				time.Now(),
+				history.EventType_WorkflowExecutionStarted,
+				&history.ExecutionStartedAttributes{
+					Name:   "unregistered",
+					Inputs: []payload.Payload{payload.Payload("21")},
+				},
+			),
+		},
+	}
+
+	e := newExecutor(r, task.WorkflowInstance, nil, &testHistoryProvider{})
+
+	_, err := e.ExecuteTask(context.Background(), task)
+	require.NoError(t, err)
+
+	require.Equal(t, "unregistered", name)
+	require.Equal(t, []payload.Payload{payload.Payload("21")}, inputs)
+	require.True(t, e.workflow.Completed())
+	require.Len(t, e.workflowState.Commands(), 1)
+	require.Equal(t, command.CommandType_CompleteWorkflow, e.workflowState.Commands()[0].Type)
+
+	a := e.workflowState.Commands()[0].Attr.(*command.CompleteWorkflowCommandAttr)
+	require.Equal(t, payload.Payload("42"), a.Result)
+}
+
 var workflowActivityHit int
 
 func workflowWithActivity(ctx sync.Context) error {
@@ -202,8 +252,9 @@ func Test_ExecuteWorkflowWithActivityCommand(t *testing.T) {
 		State: command.CommandState_Committed,
 		Type:  command.CommandType_ScheduleActivity,
 		Attr: &command.ScheduleActivityTaskCommandAttr{
-			Name:   "activity1",
-			Inputs: []payload.Payload{inputs},
+			Name:    "activity1",
+			Inputs:  []payload.Payload{inputs},
+			Attempt: 1,
 		},
 	}, *e.workflowState.Commands()[0])
 }
@@ -256,6 +307,48 @@ func Test_ExecuteWorkflowWithTimer(t *testing.T) {
 	require.Equal(t, command.CommandType_ScheduleTimer, e.workflowState.Commands()[0].Type)
 }
 
+func Test_ExecuteWorkflow_SchedulesCancellation(t *testing.T) {
+	r := NewRegistry()
+
+	r.RegisterWorkflow(workflowWithTimer)
+
+	startedAt := time.Now()
+
+	task := &task.Workflow{
+		ID:               "taskID",
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		NewEvents: []history.Event{
+			history.NewHistoryEvent(
+				1,
+				startedAt,
+				history.EventType_WorkflowExecutionStarted,
+				&history.ExecutionStartedAttributes{
+					Name:        fn.Name(workflowWithTimer),
+					Inputs:      []payload.Payload{},
+					CancelAfter: time.Minute,
+				},
+			),
+		},
+	}
+
+	e := newExecutor(r, task.WorkflowInstance, workflowWithTimer, &testHistoryProvider{})
+
+	result, err := e.ExecuteTask(context.Background(), task)
+	require.NoError(t, err)
+
+	var canceled []history.WorkflowEvent
+	for _, event := range result.WorkflowEvents {
+		if event.HistoryEvent.Type == history.EventType_WorkflowExecutionCanceled {
+			canceled = append(canceled, event)
+		}
+	}
+
+	require.Len(t, canceled, 1)
+	require.Equal(t, task.WorkflowInstance, canceled[0].WorkflowInstance)
+	require.NotNil(t, canceled[0].HistoryEvent.VisibleAt)
+	require.Equal(t, startedAt.Add(time.Minute), *canceled[0].HistoryEvent.VisibleAt)
+}
+
 var workflowWithSelectorHits int
 
 func workflowWithSelector(ctx sync.Context) error {
@@ -472,6 +565,68 @@ func Test_CompletesWorkflowOnError(t *testing.T) {
 	require.True(t, r1.Completed)
 }
 
+func Test_ContinueAsNew(t *testing.T) {
+	r := NewRegistry()
+
+	workflow := func(ctx wf.Context, n int) error {
+		// Timers of the finished execution must not fire in the next one
+		wf.ScheduleTimer(ctx, time.Hour)
+
+		return wf.ContinueAsNew(ctx, n+1)
+	}
+
+	r.RegisterWorkflow(workflow)
+
+	input, _ := converter.DefaultConverter.To(1)
+
+	task1 := &task.Workflow{
+		ID:               "taskid",
+		WorkflowInstance: core.NewSubWorkflowInstance("instanceID", "executionID", "parentID", 3),
+		NewEvents: []history.Event{
+			history.NewPendingEvent(
+				time.Now(),
+				history.EventType_WorkflowExecutionStarted,
+				&history.ExecutionStartedAttributes{
+					Name:     fn.Name(workflow),
+					Inputs:   []payload.Payload{input},
+					Priority: 2,
+					Tags:     []string{"tag"},
+				},
+			),
+		},
+	}
+
+	e := newExecutor(r, task1.WorkflowInstance, workflow, &testHistoryProvider{})
+
+	r1, err := e.ExecuteTask(context.Background(), task1)
+	require.NoError(t, err)
+	require.True(t, r1.Completed)
+
+	last := r1.Executed[len(r1.Executed)-1]
+	require.Equal(t, history.EventType_WorkflowExecutionContinuedAsNew, last.Type)
+	a := last.Attributes.(*history.ExecutionContinuedAsNewAttributes)
+
+	// Only the next execution is started, the parent is not notified and the timer is dropped
+	require.Len(t, r1.WorkflowEvents, 1)
+	next := r1.WorkflowEvents[0]
+	require.Equal(t, "instanceID", next.WorkflowInstance.InstanceID)
+	require.Equal(t, a.ExecutionID, next.WorkflowInstance.ExecutionID)
+	require.NotEqual(t, "executionID", next.WorkflowInstance.ExecutionID)
+	require.Equal(t, "parentID", next.WorkflowInstance.ParentInstanceID)
+	require.Equal(t, int64(3), next.WorkflowInstance.ParentEventID)
+
+	require.Equal(t, history.EventType_WorkflowExecutionStarted, next.HistoryEvent.Type)
+	started := next.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+	require.Equal(t, fn.Name(workflow), started.Name)
+	require.Equal(t, 2, started.Priority)
+	require.Equal(t, []string{"tag"}, started.Tags)
+
+	var n int
+	require.Len(t, started.Inputs, 1)
+	require.NoError(t, converter.DefaultConverter.From(started.Inputs[0], &n))
+	require.Equal(t, 2, n)
+}
+
 func Test_ClearCommandsBetweenTasks(t *testing.T) {
 	r := NewRegistry()
 
@@ -558,6 +713,62 @@ func Test_ScheduleSubWorkflow(t *testing.T) {
 	require.Equal(t, history.EventType_WorkflowExecutionStarted, result.WorkflowEvents[0].HistoryEvent.Type)
 }
 
+func Test_ScheduleSubWorkflow_ParentClosePolicy(t *testing.T) {
+	r := NewRegistry()
+
+	subworkflow := func(ctx wf.Context) error {
+		return nil
+	}
+
+	workflow := func(ctx wf.Context) error {
+		for i, policy := range []wf.ParentClosePolicy{
+			wf.ParentClosePolicyAbandon, wf.ParentClosePolicyRequestCancel, wf.ParentClosePolicyTerminate,
+		} {
+			wf.CreateSubWorkflowInstance[any](ctx, wf.SubWorkflowOptions{
+				InstanceID:        fmt.Sprintf("subworkflow-%d", i),
+				ParentClosePolicy: policy,
+			}, subworkflow)
+		}
+
+		return nil
+	}
+
+	r.RegisterWorkflow(workflow)
+	r.RegisterWorkflow(subworkflow)
+
+	task1 := startWorkflowTask("instanceID", workflow)
+	hp := &testHistoryProvider{}
+	e := newExecutor(r, task1.WorkflowInstance, workflow, hp)
+	result, err := e.ExecuteTask(context.Background(), task1)
+	require.NoError(t, err)
+	require.True(t, result.Completed)
+
+	// Three sub-workflows are started, then the policies are applied when the parent finishes
+	require.Len(t, result.WorkflowEvents, 5)
+	require.Equal(t, "subworkflow-1", result.WorkflowEvents[3].WorkflowInstance.InstanceID)
+	require.Equal(t, history.EventType_WorkflowExecutionCanceled, result.WorkflowEvents[3].HistoryEvent.Type)
+	require.Equal(t, "subworkflow-2", result.WorkflowEvents[4].WorkflowInstance.InstanceID)
+	require.Equal(t, history.EventType_WorkflowExecutionTerminated, result.WorkflowEvents[4].HistoryEvent.Type)
+
+	// The terminated sub-workflow finishes without running its code
+	subInstance := result.WorkflowEvents[2].WorkflowInstance
+	subTask := &task.Workflow{
+		ID:               "subtask",
+		WorkflowInstance: subInstance,
+		NewEvents: []history.Event{
+			result.WorkflowEvents[2].HistoryEvent,
+			result.WorkflowEvents[4].HistoryEvent,
+		},
+	}
+
+	se := newExecutor(r, subInstance, subworkflow, &testHistoryProvider{})
+	subResult, err := se.ExecuteTask(context.Background(), subTask)
+	require.NoError(t, err)
+	require.True(t, subResult.Completed)
+	require.Empty(t, subResult.WorkflowEvents)
+	require.Equal(t, history.EventType_WorkflowExecutionTerminated, subResult.Executed[len(subResult.Executed)-1].Type)
+}
+
 func Test_ScheduleSubWorkflow_Cancel(t *testing.T) {
 	r := NewRegistry()
 
@@ -635,3 +846,722 @@ func continueTask(instanceID string, newEvents []history.Event, lastSequenceID i
 		LastSequenceID:   lastSequenceID,
 	}
 }
+
+func Test_HistoryLimits_Warning(t *testing.T) {
+	r := NewRegistry()
+
+	workflowActivityHit = 0
+
+	r.RegisterWorkflow(workflowWithActivity)
+	r.RegisterActivity(activity1)
+
+	task := &task.Workflow{
+		ID:               "taskID",
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		NewEvents: []history.Event{
+			history.NewHistoryEvent(
+				1,
+				time.Now(),
+				history.EventType_WorkflowExecutionStarted,
+				&history.ExecutionStartedAttributes{
+					Name:   fn.Name(workflowWithActivity),
+					Inputs: []payload.Payload{},
+				},
+			),
+		},
+	}
+
+	e := newExecutor(r, task.WorkflowInstance, workflowWithActivity, &testHistoryProvider{})
+	e.limits = HistoryLimits{WarnLength: 2}
+
+	result, err := e.ExecuteTask(context.Background(), task)
+	require.NoError(t, err)
+	require.False(t, result.Completed)
+
+	// Task started, execution started, activity scheduled, warning
+	require.Len(t, result.Executed, 4)
+	require.Equal(t, history.EventType_HistoryLimitWarning, result.Executed[3].Type)
+	require.Equal(t, int64(4), e.workflowState.HistoryLength())
+	require.True(t, e.workflowState.ContinueAsNewSuggested())
+}
+
+func Test_HistoryLimits_Exceeded(t *testing.T) {
+	r := NewRegistry()
+
+	workflowActivityHit = 0
+
+	r.RegisterWorkflow(workflowWithActivity)
+	r.RegisterActivity(activity1)
+
+	task1 := &task.Workflow{
+		ID:               "taskID",
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		NewEvents: []history.Event{
+			history.NewHistoryEvent(
+				1,
+				time.Now(),
+				history.EventType_WorkflowExecutionStarted,
+				&history.ExecutionStartedAttributes{
+					Name:   fn.Name(workflowWithActivity),
+					Inputs: []payload.Payload{},
+				},
+			),
+		},
+	}
+
+	e := newExecutor(r, task1.WorkflowInstance, workflowWithActivity, &testHistoryProvider{})
+	e.limits = HistoryLimits{MaxLength: 3}
+
+	result, err := e.ExecuteTask(context.Background(), task1)
+	require.NoError(t, err)
+	require.False(t, result.Completed)
+
+	activityResult, _ := converter.DefaultConverter.To(42)
+
+	task2 := &task.Workflow{
+		ID:               "taskID2",
+		WorkflowInstance: task1.WorkflowInstance,
+		LastSequenceID:   e.lastSequenceID,
+		NewEvents: []history.Event{
+			history.NewHistoryEvent(
+				4,
+				time.Now(),
+				history.EventType_ActivityCompleted,
+				&history.ActivityCompletedAttributes{
+					Result: activityResult,
+				},
+				history.ScheduleEventID(1),
+			),
+		},
+	}
+
+	result, err = e.ExecuteTask(context.Background(), task2)
+	require.NoError(t, err)
+	require.True(t, result.Completed)
+
+	// The workflow is failed without continuing execution
+	require.Equal(t, 1, workflowActivityHit)
+
+	finished := result.Executed[len(result.Executed)-1]
+	require.Equal(t, history.EventType_WorkflowExecutionFinished, finished.Type)
+	require.Contains(t, finished.Attributes.(*history.ExecutionCompletedAttributes).Error, ErrHistoryLimitExceeded.Error())
+}
+
+type failingHistoryProvider struct {
+	t *testing.T
+}
+
+func (p *failingHistoryProvider) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64) ([]history.Event, error) {
+	p.t.Fatal("history should not be fetched")
+	return nil, nil
+}
+
+func Test_ExecuteTask_UsesDeliveredHistory(t *testing.T) {
+	r := NewRegistry()
+
+	workflowActivityHit = 0
+
+	r.RegisterWorkflow(workflowWithActivity)
+	r.RegisterActivity(activity1)
+
+	task1 := &task.Workflow{
+		ID:               "taskID",
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		NewEvents: []history.Event{
+			history.NewHistoryEvent(
+				1,
+				time.Now(),
+				history.EventType_WorkflowExecutionStarted,
+				&history.ExecutionStartedAttributes{
+					Name:   fn.Name(workflowWithActivity),
+					Inputs: []payload.Payload{},
+				},
+			),
+		},
+	}
+
+	e := newExecutor(r, task1.WorkflowInstance, workflowWithActivity, &failingHistoryProvider{t})
+
+	_, err := e.ExecuteTask(context.Background(), task1)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), e.LastSequenceID())
+
+	activityResult, _ := converter.DefaultConverter.To(42)
+
+	// Another worker processed the activity result in the meantime
+	task2 := &task.Workflow{
+		ID:               "taskID2",
+		WorkflowInstance: task1.WorkflowInstance,
+		LastSequenceID:   5,
+		NewEvents: []history.Event{
+			history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{Name: "signal"}),
+		},
+		History: []history.Event{
+			history.NewHistoryEvent(4, time.Now(), history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+			history.NewHistoryEvent(
+				5,
+				time.Now(),
+				history.EventType_ActivityCompleted,
+				&history.ActivityCompletedAttributes{
+					Result: activityResult,
+				},
+				history.ScheduleEventID(1),
+			),
+		},
+		HistorySequenceID: 3,
+	}
+
+	_, err = e.ExecuteTask(context.Background(), task2)
+	require.NoError(t, err)
+	require.Equal(t, 2, workflowActivityHit)
+}
+
+type countingMetricsClient struct {
+	metrics.Client
+
+	counters map[string]int64
+}
+
+func (c *countingMetricsClient) Counter(name string, tags map[string]string, value int64) {
+	c.counters[name] += value
+}
+
+func (c *countingMetricsClient) WithTags(tags map[string]string) metrics.Client {
+	return c
+}
+
+func Test_Metrics_SuppressedDuringReplay(t *testing.T) {
+	r := NewRegistry()
+
+	workflowWithMetrics := func(ctx wf.Context) error {
+		wf.Metrics(ctx).Counter("started", nil, 1)
+
+		_, err := wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx)
+
+		wf.Metrics(ctx).Counter("completed", nil, 1)
+
+		return err
+	}
+
+	r.RegisterWorkflow(workflowWithMetrics)
+	r.RegisterActivity(activity1)
+
+	inputs, _ := converter.DefaultConverter.To(42)
+	result, _ := converter.DefaultConverter.To(42)
+
+	instance := core.NewWorkflowInstance("instanceID", "executionID")
+
+	mc := &countingMetricsClient{counters: map[string]int64{}}
+	e, err := NewExecutor(logger.NewDefaultLogger(), mc, converter.DefaultConverter, r, &testHistoryProvider{[]history.Event{
+		history.NewHistoryEvent(
+			1,
+			time.Now(),
+			history.EventType_WorkflowExecutionStarted,
+			&history.ExecutionStartedAttributes{
+				Name:   fn.Name(workflowWithMetrics),
+				Inputs: []payload.Payload{},
+			},
+		),
+		history.NewHistoryEvent(
+			2,
+			time.Now(),
+			history.EventType_ActivityScheduled,
+			&history.ActivityScheduledAttributes{
+				Name:   fn.Name(activity1),
+				Inputs: []payload.Payload{inputs},
+			},
+			history.ScheduleEventID(1),
+		),
+	}}, instance, clock.New(), ExecutorOptions{})
+	require.NoError(t, err)
+
+	_, err = e.ExecuteTask(context.Background(), &task.Workflow{
+		ID:               "taskID",
+		WorkflowInstance: instance,
+		LastSequenceID:   2,
+		NewEvents: []history.Event{
+			history.NewPendingEvent(
+				time.Now(),
+				history.EventType_ActivityCompleted,
+				&history.ActivityCompletedAttributes{
+					Result: result,
+				},
+				history.ScheduleEventID(1),
+			),
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]int64{"completed": 1}, mc.counters)
+}
+
+func Test_AddTags(t *testing.T) {
+	r := NewRegistry()
+
+	workflow := func(ctx wf.Context) error {
+		wf.AddTags(ctx, "release:2024-06")
+
+		return nil
+	}
+
+	r.RegisterWorkflow(workflow)
+
+	task := startWorkflowTask("instanceID", workflow)
+	e := newExecutor(r, task.WorkflowInstance, workflow, &testHistoryProvider{})
+	result, err := e.ExecuteTask(context.Background(), task)
+	require.NoError(t, err)
+	require.True(t, result.Completed)
+
+	var tags []string
+	for _, event := range result.Executed {
+		if event.Type == history.EventType_WorkflowTagsAdded {
+			tags = append(tags, event.Attributes.(*history.WorkflowTagsAddedAttributes).Tags...)
+		}
+	}
+	require.Equal(t, []string{"release:2024-06"}, tags)
+	require.Equal(t, []string{"release:2024-06"}, history.AddedTags(result.Executed))
+}
+
+func Test_RecordMarker(t *testing.T) {
+	r := NewRegistry()
+
+	workflow := func(ctx wf.Context) error {
+		if err := wf.RecordMarker(ctx, "audit", "approved"); err != nil {
+			return err
+		}
+
+		wf.NewSignalChannel[int](ctx, "signal").Receive(ctx)
+
+		return nil
+	}
+
+	r.RegisterWorkflow(workflow)
+
+	task1 := startWorkflowTask("instanceID", workflow)
+	hp := &testHistoryProvider{}
+	e := newExecutor(r, task1.WorkflowInstance, workflow, hp)
+	r1, err := e.ExecuteTask(context.Background(), task1)
+	require.NoError(t, err)
+	require.False(t, r1.Completed)
+
+	var markers []*history.MarkerRecordedAttributes
+	for _, event := range r1.Executed {
+		if event.Type == history.EventType_MarkerRecorded {
+			markers = append(markers, event.Attributes.(*history.MarkerRecordedAttributes))
+		}
+	}
+	require.Len(t, markers, 1)
+	require.Equal(t, "audit", markers[0].Name)
+
+	var data string
+	require.NoError(t, converter.DefaultConverter.From(markers[0].Data, &data))
+	require.Equal(t, "approved", data)
+
+	// Replaying the history on a new executor must not record the marker again
+	hp.history = r1.Executed
+
+	task2 := &task.Workflow{
+		ID:               "taskid2",
+		WorkflowInstance: task1.WorkflowInstance,
+		NewEvents: []history.Event{
+			history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{
+				Name: "signal",
+				Arg:  []byte("42"),
+			}),
+		},
+		LastSequenceID: r1.Executed[len(r1.Executed)-1].SequenceID,
+	}
+
+	e = newExecutor(r, task1.WorkflowInstance, workflow, hp)
+	r2, err := e.ExecuteTask(context.Background(), task2)
+	require.NoError(t, err)
+	require.True(t, r2.Completed)
+
+	for _, event := range r2.Executed {
+		require.NotEqual(t, history.EventType_MarkerRecorded, event.Type)
+	}
+}
+
+func Test_GetVersion(t *testing.T) {
+	r := NewRegistry()
+
+	var versions []wf.Version
+
+	workflow := func(ctx wf.Context) error {
+		v, err := wf.GetVersion(ctx, "change", wf.DefaultVersion, 1)
+		if err != nil {
+			return err
+		}
+
+		versions = append(versions, v)
+
+		if v == wf.DefaultVersion {
+			wf.NewSignalChannel[int](ctx, "signal").Receive(ctx)
+			return nil
+		}
+
+		_, err = wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx)
+		return err
+	}
+
+	r.RegisterWorkflow(workflow)
+	require.NoError(t, r.RegisterActivity(activity1))
+
+	task1 := startWorkflowTask("instanceID", workflow)
+	hp := &testHistoryProvider{}
+	e := newExecutor(r, task1.WorkflowInstance, workflow, hp)
+	r1, err := e.ExecuteTask(context.Background(), task1)
+	require.NoError(t, err)
+	require.False(t, r1.Completed)
+	require.Equal(t, []wf.Version{1}, versions)
+
+	var scheduleEventID int64
+	var oldHistory []history.Event
+	for _, event := range r1.Executed {
+		switch event.Type {
+		case history.EventType_MarkerRecorded:
+			require.Equal(t, wf.VersionMarker, event.Attributes.(*history.MarkerRecordedAttributes).Name)
+		case history.EventType_ActivityScheduled:
+			scheduleEventID = event.ScheduleEventID
+		default:
+			oldHistory = append(oldHistory, event)
+		}
+	}
+	require.NotZero(t, scheduleEventID)
+
+	// Replaying the history returns the recorded version and doesn't record it again
+	versions = nil
+	hp.history = r1.Executed
+
+	result, _ := converter.DefaultConverter.To(42)
+	task2 := &task.Workflow{
+		ID:               "taskid2",
+		WorkflowInstance: task1.WorkflowInstance,
+		NewEvents: []history.Event{
+			history.NewPendingEvent(time.Now(), history.EventType_ActivityCompleted, &history.ActivityCompletedAttributes{
+				Result: result,
+			}, history.ScheduleEventID(scheduleEventID)),
+		},
+		LastSequenceID: r1.Executed[len(r1.Executed)-1].SequenceID,
+	}
+
+	e = newExecutor(r, task1.WorkflowInstance, workflow, hp)
+	r2, err := e.ExecuteTask(context.Background(), task2)
+	require.NoError(t, err)
+	require.True(t, r2.Completed)
+	require.Equal(t, []wf.Version{1}, versions)
+
+	for _, event := range r2.Executed {
+		require.NotEqual(t, history.EventType_MarkerRecorded, event.Type)
+	}
+
+	// Histories recorded before the change use the default version
+	versions = nil
+	hp.history = oldHistory
+
+	task3 := &task.Workflow{
+		ID:               "taskid3",
+		WorkflowInstance: task1.WorkflowInstance,
+		NewEvents: []history.Event{
+			history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{
+				Name: "signal",
+				Arg:  []byte("42"),
+			}),
+		},
+		LastSequenceID: oldHistory[len(oldHistory)-1].SequenceID,
+	}
+
+	e = newExecutor(r, task1.WorkflowInstance, workflow, hp)
+	r3, err := e.ExecuteTask(context.Background(), task3)
+	require.NoError(t, err)
+	require.True(t, r3.Completed)
+	require.Equal(t, []wf.Version{wf.DefaultVersion}, versions)
+
+	for _, event := range r3.Executed {
+		require.NotEqual(t, history.EventType_MarkerRecorded, event.Type)
+	}
+}
+
+func Test_ExecuteActivity_RegisteredQueue(t *testing.T) {
+	r := NewRegistry()
+
+	workflow := func(ctx wf.Context) error {
+		wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42)
+		wf.ExecuteActivity[int](ctx, wf.ActivityOptions{Queue: "other"}, activity1, 42)
+
+		return nil
+	}
+
+	r.RegisterWorkflow(workflow)
+	require.NoError(t, r.RegisterActivity(activity1, func(o *core.ActivityRegistrationOptions) {
+		o.Queue = "video"
+	}))
+
+	task := startWorkflowTask("instanceID", workflow)
+	e := newExecutor(r, task.WorkflowInstance, workflow, &testHistoryProvider{})
+	e.workflowState.SetActivityOptions(r.GetActivityOptions)
+
+	result, err := e.ExecuteTask(context.Background(), task)
+	require.NoError(t, err)
+	require.Len(t, result.ActivityEvents, 2)
+
+	queues := []string{}
+	for _, event := range result.ActivityEvents {
+		queues = append(queues, event.Attributes.(*history.ActivityScheduledAttributes).Queue)
+	}
+	require.ElementsMatch(t, []string{"video", "other"}, queues)
+}
+
+func Test_TimerFiresOnlyOnce(t *testing.T) {
+	r := NewRegistry()
+
+	workflowTimerHits = 0
+
+	r.RegisterWorkflow(workflowWithTimer)
+
+	task := startWorkflowTask("instanceID", workflowWithTimer)
+	e := newExecutor(r, task.WorkflowInstance, workflowWithTimer, &testHistoryProvider{})
+
+	result, err := e.ExecuteTask(context.Background(), task)
+	require.NoError(t, err)
+	require.Equal(t, 1, workflowTimerHits)
+
+	// Backend delivers the timer fired event twice
+	timerFired := func() history.Event {
+		return history.NewPendingEvent(time.Now(), history.EventType_TimerFired, &history.TimerFiredAttributes{}, history.ScheduleEventID(1))
+	}
+
+	result, err = e.ExecuteTask(context.Background(), continueTask("instanceID", []history.Event{timerFired(), timerFired()}, result.Executed[len(result.Executed)-1].SequenceID))
+	require.NoError(t, err)
+	require.Equal(t, 2, workflowTimerHits)
+	require.True(t, e.workflow.Completed())
+}
+
+func Test_TimerFiredAfterCancellation(t *testing.T) {
+	r := NewRegistry()
+
+	var timerErr error
+	var hits int
+
+	workflow := func(ctx wf.Context) error {
+		tctx, cancel := wf.WithCancel(ctx)
+		timer := wf.ScheduleTimer(tctx, time.Hour)
+
+		wf.NewSignalChannel[string](ctx, "cancel").Receive(ctx)
+		cancel()
+
+		_, timerErr = timer.Get(ctx)
+		hits++
+
+		wf.NewSignalChannel[string](ctx, "done").Receive(ctx)
+
+		return nil
+	}
+
+	r.RegisterWorkflow(workflow)
+
+	task := startWorkflowTask("instanceID", workflow)
+	e := newExecutor(r, task.WorkflowInstance, workflow, &testHistoryProvider{})
+
+	result, err := e.ExecuteTask(context.Background(), task)
+	require.NoError(t, err)
+
+	signal := func(name string) history.Event {
+		arg, _ := converter.DefaultConverter.To("")
+		return history.NewPendingEvent(time.Now(), history.EventType_SignalReceived, &history.SignalReceivedAttributes{Name: name, Arg: arg})
+	}
+
+	result, err = e.ExecuteTask(context.Background(), continueTask("instanceID", []history.Event{signal("cancel")}, result.Executed[len(result.Executed)-1].SequenceID))
+	require.NoError(t, err)
+	require.Equal(t, 1, hits)
+	require.ErrorIs(t, timerErr, sync.Canceled)
+
+	// The timer fired concurrently with the cancellation
+	timerFired := history.NewPendingEvent(time.Now(), history.EventType_TimerFired, &history.TimerFiredAttributes{}, history.ScheduleEventID(1))
+
+	_, err = e.ExecuteTask(context.Background(), continueTask("instanceID", []history.Event{timerFired, signal("done")}, result.Executed[len(result.Executed)-1].SequenceID))
+	require.NoError(t, err)
+	require.Equal(t, 1, hits)
+	require.True(t, e.workflow.Completed())
+}
+
+func Test_DeterminismGuard(t *testing.T) {
+	r := NewRegistry()
+
+	workflow := func(ctx wf.Context) error {
+		determinism.Now()
+
+		return nil
+	}
+
+	r.RegisterWorkflow(workflow)
+
+	t.Run("Warn", func(t *testing.T) {
+		task := startWorkflowTask("instanceID", workflow)
+
+		mc := &countingMetricsClient{counters: map[string]int64{}}
+		e, err := NewExecutor(logger.NewDefaultLogger(), mc, converter.DefaultConverter, r, &testHistoryProvider{}, task.WorkflowInstance, clock.New(), ExecutorOptions{DeterminismGuard: guard.ModeWarn})
+		require.NoError(t, err)
+
+		result, err := e.ExecuteTask(context.Background(), task)
+		require.NoError(t, err)
+		require.True(t, result.Completed)
+
+		finished := result.Executed[len(result.Executed)-1]
+		require.Empty(t, finished.Attributes.(*history.ExecutionCompletedAttributes).Error)
+		require.Equal(t, map[string]int64{metrics.DeterminismViolations: 1}, mc.counters)
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		task := startWorkflowTask("instanceID", workflow)
+
+		e, err := NewExecutor(logger.NewDefaultLogger(), mi.NewNoopMetricsClient(), converter.DefaultConverter, r, &testHistoryProvider{}, task.WorkflowInstance, clock.New(), ExecutorOptions{DeterminismGuard: guard.ModeStrict})
+		require.NoError(t, err)
+
+		result, err := e.ExecuteTask(context.Background(), task)
+		require.NoError(t, err)
+		require.True(t, result.Completed)
+
+		finished := result.Executed[len(result.Executed)-1]
+		require.Contains(t, finished.Attributes.(*history.ExecutionCompletedAttributes).Error, "time.Now must not be called from workflow code, use workflow.Now instead")
+	})
+
+	t.Run("OutsideWorkflow", func(t *testing.T) {
+		require.NotPanics(t, func() {
+			determinism.Now()
+		})
+	})
+}
+
+func Test_DeadlockedWorkflow_Fails(t *testing.T) {
+	r := NewRegistry()
+
+	block := make(chan struct{})
+	defer close(block)
+
+	workflowWithDeadlock := func(ctx wf.Context) error {
+		<-block
+
+		return nil
+	}
+
+	r.RegisterWorkflow(workflowWithDeadlock)
+
+	task := startWorkflowTask("instanceID", workflowWithDeadlock)
+
+	e, err := NewExecutor(logger.NewDefaultLogger(), mi.NewNoopMetricsClient(), converter.DefaultConverter, r, &testHistoryProvider{}, task.WorkflowInstance, clock.New(), ExecutorOptions{DeadlockTimeout: time.Millisecond * 10})
+	require.NoError(t, err)
+
+	result, err := e.ExecuteTask(context.Background(), task)
+	require.NoError(t, err)
+	require.True(t, result.Completed)
+
+	finished := result.Executed[len(result.Executed)-1]
+	require.Equal(t, history.EventType_WorkflowExecutionFinished, finished.Type)
+
+	errMsg := finished.Attributes.(*history.ExecutionCompletedAttributes).Error
+	require.Contains(t, errMsg, "workflow deadlock detected")
+	require.Contains(t, errMsg, "Test_DeadlockedWorkflow_Fails")
+}
+
+func Test_WorkflowTaskTimeout_Fails(t *testing.T) {
+	slowWorkflow := func(ctx wf.Context) error {
+		time.Sleep(time.Millisecond * 20)
+
+		wf.ScheduleTimer(ctx, time.Second).Get(ctx)
+
+		return nil
+	}
+
+	t.Run("WorkerTimeout", func(t *testing.T) {
+		r := NewRegistry()
+		r.RegisterWorkflow(slowWorkflow)
+
+		task := startWorkflowTask("instanceID", slowWorkflow)
+
+		e, err := NewExecutor(logger.NewDefaultLogger(), mi.NewNoopMetricsClient(), converter.DefaultConverter, r, &testHistoryProvider{}, task.WorkflowInstance, clock.New(), ExecutorOptions{TaskTimeout: time.Millisecond})
+		require.NoError(t, err)
+
+		result, err := e.ExecuteTask(context.Background(), task)
+		require.NoError(t, err)
+		require.True(t, result.Completed)
+
+		finished := result.Executed[len(result.Executed)-1]
+		require.Equal(t, history.EventType_WorkflowExecutionFinished, finished.Type)
+		require.Contains(t, finished.Attributes.(*history.ExecutionCompletedAttributes).Error, ErrWorkflowTaskTimeout.Error())
+	})
+
+	t.Run("RegistrationOverridesWorker", func(t *testing.T) {
+		r := NewRegistry()
+		r.RegisterWorkflow(slowWorkflow, func(o *core.WorkflowRegistrationOptions) {
+			o.TaskTimeout = time.Minute
+		})
+
+		task := startWorkflowTask("instanceID", slowWorkflow)
+
+		e, err := NewExecutor(logger.NewDefaultLogger(), mi.NewNoopMetricsClient(), converter.DefaultConverter, r, &testHistoryProvider{}, task.WorkflowInstance, clock.New(), ExecutorOptions{TaskTimeout: time.Millisecond})
+		require.NoError(t, err)
+
+		result, err := e.ExecuteTask(context.Background(), task)
+		require.NoError(t, err)
+		require.False(t, result.Completed)
+	})
+}
+
+func Test_Query(t *testing.T) {
+	r := NewRegistry()
+
+	workflow := func(ctx wf.Context) error {
+		status := "started"
+
+		if err := wf.HandleQuery(ctx, "status", func(prefix string) (string, error) {
+			return prefix + status, nil
+		}); err != nil {
+			return err
+		}
+
+		wf.NewSignalChannel[string](ctx, "signal").Receive(ctx)
+		status = "signaled"
+
+		return nil
+	}
+
+	r.RegisterWorkflow(workflow)
+
+	task1 := startWorkflowTask("instanceID", workflow)
+
+	e := newExecutor(r, task1.WorkflowInstance, workflow, &testHistoryProvider{})
+
+	r1, err := e.ExecuteTask(context.Background(), task1)
+	require.NoError(t, err)
+	require.False(t, r1.Completed)
+
+	input, _ := converter.DefaultConverter.To("status: ")
+
+	result, err := e.Query(context.Background(), "status", []payload.Payload{input})
+	require.NoError(t, err)
+
+	var status string
+	require.NoError(t, converter.DefaultConverter.From(result, &status))
+	require.Equal(t, "status: started", status)
+
+	// Queries don't add to the history
+	require.Equal(t, int64(len(r1.Executed)), e.LastSequenceID())
+
+	_, err = e.Query(context.Background(), "unknown", nil)
+	require.ErrorIs(t, err, ErrUnknownQuery)
+
+	// An executor without state replays the history before answering
+	e2 := newExecutor(r, task1.WorkflowInstance, workflow, &testHistoryProvider{r1.Executed})
+
+	result, err = e2.Query(context.Background(), "status", []payload.Payload{input})
+	require.NoError(t, err)
+	require.NoError(t, converter.DefaultConverter.From(result, &status))
+	require.Equal(t, "status: started", status)
+	require.Equal(t, int64(len(r1.Executed)), e2.LastSequenceID())
+
+	e2.Close()
+
+	_, err = e2.Query(context.Background(), "status", []payload.Payload{input})
+	require.ErrorIs(t, err, ErrExecutorClosed)
+}
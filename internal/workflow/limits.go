@@ -0,0 +1,32 @@
+package workflow
+
+import "errors"
+
+// HistoryLimits configures limits for the history of workflow instances. Limits of 0 are disabled.
+type HistoryLimits struct {
+	// WarnLength is the number of events after which a HistoryLimitWarning event is recorded and
+	// ContinueAsNew is suggested to the workflow
+	WarnLength int64
+
+	// WarnSize is the history size in bytes after which a HistoryLimitWarning event is recorded and
+	// ContinueAsNew is suggested to the workflow
+	WarnSize int64
+
+	// MaxLength is the number of events after which the workflow instance is failed instead of
+	// executing it further
+	MaxLength int64
+
+	// MaxSize is the history size in bytes after which the workflow instance is failed instead of
+	// executing it further
+	MaxSize int64
+}
+
+var ErrHistoryLimitExceeded = errors.New("workflow history limit exceeded")
+
+func (l HistoryLimits) warn(length, size int64) bool {
+	return (l.WarnLength > 0 && length >= l.WarnLength) || (l.WarnSize > 0 && size >= l.WarnSize)
+}
+
+func (l HistoryLimits) exceeded(length, size int64) bool {
+	return (l.MaxLength > 0 && length >= l.MaxLength) || (l.MaxSize > 0 && size >= l.MaxSize)
+}
@@ -1,107 +1,144 @@
 package workflow
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 
-	"github.com/cschleiden/go-workflows/internal/args"
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/fn"
+	"github.com/cschleiden/go-workflows/internal/payload"
 )
 
 type Activity interface{}
 
+// DynamicActivity handles activities without a specific registration. It receives the name of the scheduled
+// activity and its encoded inputs, and returns the encoded result.
+type DynamicActivity func(ctx context.Context, name string, inputs []payload.Payload) (payload.Payload, error)
+
 type Registry struct {
 	sync.Mutex
 
-	workflowMap map[string]Workflow
-	activityMap map[string]interface{}
+	workflowMap        map[string]Workflow
+	workflowOptionsMap map[string]core.WorkflowRegistrationOptions
+	activityMap        map[string]interface{}
+	activityOptionsMap map[string]core.ActivityRegistrationOptions
+
+	dynamicWorkflow        DynamicWorkflow
+	dynamicWorkflowOptions core.WorkflowRegistrationOptions
+
+	dynamicActivity        DynamicActivity
+	dynamicActivityOptions core.ActivityRegistrationOptions
 }
 
 func NewRegistry() *Registry {
 	return &Registry{
-		Mutex:       sync.Mutex{},
-		workflowMap: make(map[string]Workflow),
-		activityMap: make(map[string]interface{}),
+		Mutex:              sync.Mutex{},
+		workflowMap:        make(map[string]Workflow),
+		workflowOptionsMap: make(map[string]core.WorkflowRegistrationOptions),
+		activityMap:        make(map[string]interface{}),
+		activityOptionsMap: make(map[string]core.ActivityRegistrationOptions),
 	}
 }
 
-type ErrInvalidWorkflow struct {
-	msg string
-}
-
-func (e *ErrInvalidWorkflow) Error() string {
-	return e.msg
-}
-
-type ErrInvalidActivity struct {
-	msg string
-}
-
-func (e *ErrInvalidActivity) Error() string {
-	return e.msg
-}
-
-func (r *Registry) RegisterWorkflow(workflow Workflow) error {
+func (r *Registry) RegisterWorkflow(workflow Workflow, opts ...core.WorkflowRegistrationOption) error {
 	r.Lock()
 	defer r.Unlock()
 
-	wfType := reflect.TypeOf(workflow)
-	if wfType.Kind() != reflect.Func {
-		return &ErrInvalidWorkflow{"workflow is not a function"}
+	if err := checkWorkflow(workflow); err != nil {
+		return err
 	}
 
-	if wfType.NumIn() == 0 {
-		return &ErrInvalidWorkflow{"workflow does not accept context parameter"}
+	var options core.WorkflowRegistrationOptions
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	if !args.IsOwnContext(wfType.In(0)) {
-		return &ErrInvalidWorkflow{"workflow does not accept context as first parameter"}
-	}
+	name := fn.Name(workflow)
+	r.workflowMap[name] = workflow
+	r.workflowOptionsMap[name] = options
 
-	if wfType.NumOut() == 0 {
-		return &ErrInvalidWorkflow{"workflow must return error"}
-	}
+	return nil
+}
 
-	if wfType.NumOut() > 2 {
-		return &ErrInvalidWorkflow{"workflow must return at most two values"}
+// RegisterDynamicWorkflow sets the handler for workflows that have not been registered otherwise. Registering
+// another dynamic workflow replaces the previous one.
+func (r *Registry) RegisterDynamicWorkflow(workflow DynamicWorkflow, opts ...core.WorkflowRegistrationOption) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if workflow == nil {
+		return &ErrInvalidWorkflow{Name: "dynamic workflow", Reason: "workflow is nil"}
 	}
 
-	errType := reflect.TypeOf((*error)(nil)).Elem()
-	if (wfType.NumOut() == 1 && !wfType.Out(0).Implements(errType)) ||
-		(wfType.NumOut() == 2 && !wfType.Out(1).Implements(errType)) {
-		return &ErrInvalidWorkflow{"workflow must return error as last return value"}
+	var options core.WorkflowRegistrationOptions
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	name := fn.Name(workflow)
-	r.workflowMap[name] = workflow
+	r.dynamicWorkflow = workflow
+	r.dynamicWorkflowOptions = options
 
 	return nil
 }
 
-func (r *Registry) RegisterActivity(activity interface{}) error {
+func (r *Registry) RegisterActivity(activity interface{}, opts ...core.ActivityRegistrationOption) error {
 	r.Lock()
 	defer r.Unlock()
 
+	var options core.ActivityRegistrationOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	t := reflect.TypeOf(activity)
 
 	// Activities on struct
 	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
-		return r.registerActivitiesFromStruct(activity)
+		return r.registerActivitiesFromStruct(activity, options)
 	}
 
 	// Activity as function
-	if err := checkActivity(reflect.TypeOf(activity)); err != nil {
-		return err
+	if t.Kind() != reflect.Func {
+		return &ErrInvalidActivity{Name: fmt.Sprintf("%T", activity), Reason: "activity is not a function"}
 	}
 
 	name := fn.Name(activity)
+	if err := checkActivity(name, t); err != nil {
+		return err
+	}
+
 	r.activityMap[name] = activity
+	r.activityOptionsMap[name] = options
 
 	return nil
 }
 
-func (r *Registry) registerActivitiesFromStruct(a interface{}) error {
+// RegisterDynamicActivity sets the handler for activities that have not been registered otherwise. Registering
+// another dynamic activity replaces the previous one.
+func (r *Registry) RegisterDynamicActivity(activity DynamicActivity, opts ...core.ActivityRegistrationOption) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if activity == nil {
+		return &ErrInvalidActivity{Name: "dynamic activity", Reason: "activity is nil"}
+	}
+
+	var options core.ActivityRegistrationOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	r.dynamicActivity = activity
+	r.dynamicActivityOptions = options
+
+	return nil
+}
+
+func (r *Registry) registerActivitiesFromStruct(a interface{}, options core.ActivityRegistrationOptions) error {
 	// Enumerate functions defined on a
 	v := reflect.ValueOf(a)
 	t := v.Type()
@@ -114,29 +151,13 @@ func (r *Registry) registerActivitiesFromStruct(a interface{}) error {
 			continue
 		}
 
-		if err := checkActivity(mt.Type); err != nil {
+		name := mt.Name
+		if err := checkActivity(name, mv.Type()); err != nil {
 			return err
 		}
 
-		name := mt.Name
 		r.activityMap[name] = mv.Interface()
-	}
-
-	return nil
-}
-
-func checkActivity(actType reflect.Type) error {
-	if actType.Kind() != reflect.Func {
-		return &ErrInvalidActivity{"activity not a func"}
-	}
-
-	if actType.NumOut() == 0 {
-		return &ErrInvalidActivity{"activity must return error"}
-	}
-
-	errType := reflect.TypeOf((*error)(nil)).Elem()
-	if !actType.Out(actType.NumOut() - 1).Implements(errType) {
-		return &ErrInvalidWorkflow{"activity must return error as last return value"}
+		r.activityOptionsMap[name] = options
 	}
 
 	return nil
@@ -163,3 +184,64 @@ func (r *Registry) GetActivity(name string) (interface{}, error) {
 
 	return nil, errors.New("activity not found")
 }
+
+// GetDynamicWorkflow returns the dynamic workflow and the options it has been registered with, if any
+func (r *Registry) GetDynamicWorkflow() (DynamicWorkflow, core.WorkflowRegistrationOptions, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.dynamicWorkflow, r.dynamicWorkflowOptions, r.dynamicWorkflow != nil
+}
+
+// GetDynamicActivity returns the dynamic activity and the options it has been registered with, if any
+func (r *Registry) GetDynamicActivity() (DynamicActivity, core.ActivityRegistrationOptions, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.dynamicActivity, r.dynamicActivityOptions, r.dynamicActivity != nil
+}
+
+// GetWorkflowOptions returns the options the workflow with the given name has been registered with
+func (r *Registry) GetWorkflowOptions(name string) (core.WorkflowRegistrationOptions, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	options, ok := r.workflowOptionsMap[name]
+	return options, ok
+}
+
+// GetActivityOptions returns the options the activity with the given name has been registered with
+func (r *Registry) GetActivityOptions(name string) (core.ActivityRegistrationOptions, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	options, ok := r.activityOptionsMap[name]
+	return options, ok
+}
+
+// WorkflowNames returns the names of all registered workflows
+func (r *Registry) WorkflowNames() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	return sortedKeys(r.workflowMap)
+}
+
+// ActivityNames returns the names of all registered activities
+func (r *Registry) ActivityNames() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	return sortedKeys(r.activityMap)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -2,8 +2,11 @@ package workflow
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/stretchr/testify/require"
@@ -110,6 +113,37 @@ func Test_ActivityRegistration(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func Test_ActivityRegistration_Options(t *testing.T) {
+	r := NewRegistry()
+
+	retryOptions := core.RetryOptions{MaxAttempts: 5}
+
+	err := r.RegisterActivity(reg_activity, func(o *core.ActivityRegistrationOptions) {
+		o.RetryOptions = &retryOptions
+		o.StartToCloseTimeout = time.Minute
+	})
+	require.NoError(t, err)
+
+	options, ok := r.GetActivityOptions("reg_activity")
+	require.True(t, ok)
+	require.Equal(t, &retryOptions, options.RetryOptions)
+	require.Equal(t, time.Minute, options.StartToCloseTimeout)
+
+	_, ok = r.GetActivityOptions("unknown")
+	require.False(t, ok)
+}
+
+func Test_Registry_Names(t *testing.T) {
+	r := NewRegistry()
+
+	require.NoError(t, r.RegisterWorkflow(reg_workflow1))
+	require.NoError(t, r.RegisterActivity(reg_activity))
+	require.NoError(t, r.RegisterActivity(&reg_activities{}))
+
+	require.Equal(t, []string{"reg_workflow1"}, r.WorkflowNames())
+	require.Equal(t, []string{"Activity1", "reg_activity"}, r.ActivityNames())
+}
+
 func reg_activity_invalid(ctx context.Context) {
 }
 
@@ -178,3 +212,89 @@ func Test_ActivityRegistrationOnStruct_Invalid(t *testing.T) {
 	err := r.RegisterActivity(a)
 	require.Error(t, err)
 }
+
+func Test_Registration_SignatureErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(r *Registry) error
+		wantErr  string
+	}{
+		{
+			name: "workflow not a function",
+			register: func(r *Registry) error {
+				return r.RegisterWorkflow(42)
+			},
+			wantErr: "invalid workflow int: workflow is not a function",
+		},
+		{
+			name: "workflow with unserializable parameter",
+			register: func(r *Registry) error {
+				return r.RegisterWorkflow(func(ctx sync.Context, c chan int) error { return nil })
+			},
+			wantErr: "parameter 1 of type chan int cannot be serialized: chan values are not supported",
+		},
+		{
+			name: "workflow with std context",
+			register: func(r *Registry) error {
+				return r.RegisterWorkflow(func(ctx context.Context) error { return nil })
+			},
+			wantErr: "workflow must accept workflow.Context as first parameter, not context.Context",
+		},
+		{
+			name: "activity with workflow context",
+			register: func(r *Registry) error {
+				return r.RegisterActivity(func(ctx sync.Context) error { return nil })
+			},
+			wantErr: "activity must accept context.Context as first parameter, not workflow.Context",
+		},
+		{
+			name: "activity without context",
+			register: func(r *Registry) error {
+				return r.RegisterActivity(func(a int) error { return nil })
+			},
+			wantErr: "activity must accept context.Context as first parameter",
+		},
+		{
+			name: "activity with unserializable result",
+			register: func(r *Registry) error {
+				type result struct {
+					Callback func()
+				}
+
+				return r.RegisterActivity(func(ctx context.Context) (result, error) { return result{}, nil })
+			},
+			wantErr: "result 0 of type workflow.result cannot be serialized: field Callback: func values are not supported",
+		},
+		{
+			name: "activity without error result",
+			register: func(r *Registry) error {
+				return r.RegisterActivity(func(ctx context.Context) int { return 42 })
+			},
+			wantErr: "must return error as last return value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.register(NewRegistry())
+			require.ErrorContains(t, err, tt.wantErr)
+
+			var wfErr *ErrInvalidWorkflow
+			var actErr *ErrInvalidActivity
+			require.True(t, errors.As(err, &wfErr) || errors.As(err, &actErr))
+		})
+	}
+}
+
+func Test_Registration_SerializableTypes(t *testing.T) {
+	type node struct {
+		Next     *node
+		Children []node
+		Ignored  func() `json:"-"`
+		private  chan int
+	}
+
+	r := NewRegistry()
+	require.NoError(t, r.RegisterActivity(func(ctx context.Context, n node, m map[string]*node, at time.Time) (*node, error) {
+		return nil, nil
+	}))
+}
@@ -0,0 +1,64 @@
+package workflow
+
+import (
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	mi "github.com/cschleiden/go-workflows/internal/metrics"
+	"github.com/cschleiden/go-workflows/log"
+)
+
+// ReplayStep is the state of a workflow after replaying a single event of its history
+type ReplayStep struct {
+	Event history.Event
+
+	// Commands are the commands issued by the workflow that have not been matched by a history event yet
+	Commands []command.Command
+
+	// Completed is true once the workflow function has returned
+	Completed bool
+
+	// Err is the error replaying the event failed with, if any. Replaying stops at the first error.
+	Err error
+}
+
+// ReplaySteps replays the given history of a workflow instance one event at a time, and returns the state of the
+// workflow after every event. The workflow has to be registered with the given registry.
+func ReplaySteps(logger log.Logger, converter converter.Converter, registry *Registry, instance *core.WorkflowInstance, events []history.Event) []*ReplayStep {
+	we, _ := NewExecutor(logger, mi.NewNoopMetricsClient(), converter, registry, nil, instance, clock.New(), ExecutorOptions{})
+	e := we.(*executor)
+	defer e.Close()
+
+	// Versions recorded by markers are needed before the markers are replayed
+	if err := e.recordVersions(events); err != nil {
+		return []*ReplayStep{{Err: err}}
+	}
+
+	e.workflowState.SetReplaying(true)
+
+	steps := make([]*ReplayStep, 0, len(events))
+	for _, event := range events {
+		err := e.executeEvent(event)
+
+		step := &ReplayStep{
+			Event:     event,
+			Commands:  make([]command.Command, 0, len(e.workflowState.Commands())),
+			Completed: e.workflow != nil && e.workflow.Completed(),
+			Err:       err,
+		}
+
+		for _, c := range e.workflowState.Commands() {
+			step.Commands = append(step.Commands, *c)
+		}
+
+		steps = append(steps, step)
+
+		if err != nil {
+			break
+		}
+	}
+
+	return steps
+}
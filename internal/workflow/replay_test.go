@@ -0,0 +1,92 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/fn"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/logger"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ReplaySteps(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterWorkflow(workflowWithActivity)
+	r.RegisterActivity(activity1)
+
+	inputs, _ := converter.DefaultConverter.To(42)
+	result, _ := converter.DefaultConverter.To(42)
+
+	events := []history.Event{
+		history.NewHistoryEvent(
+			1,
+			time.Now(),
+			history.EventType_WorkflowExecutionStarted,
+			&history.ExecutionStartedAttributes{
+				Name:   fn.Name(workflowWithActivity),
+				Inputs: []payload.Payload{},
+			},
+		),
+		history.NewHistoryEvent(
+			2,
+			time.Now(),
+			history.EventType_ActivityScheduled,
+			&history.ActivityScheduledAttributes{
+				Name:   "activity1",
+				Inputs: []payload.Payload{inputs},
+			},
+			history.ScheduleEventID(1),
+		),
+		history.NewHistoryEvent(
+			3,
+			time.Now(),
+			history.EventType_ActivityCompleted,
+			&history.ActivityCompletedAttributes{
+				Result: result,
+			},
+			history.ScheduleEventID(1),
+		),
+	}
+
+	steps := ReplaySteps(logger.NewDefaultLogger(), converter.DefaultConverter, r, core.NewWorkflowInstance("instanceID", "executionID"), events)
+	require.Len(t, steps, 3)
+
+	// The workflow schedules the activity when it's started
+	require.NoError(t, steps[0].Err)
+	require.Len(t, steps[0].Commands, 1)
+	require.Equal(t, command.CommandType_ScheduleActivity, steps[0].Commands[0].Type)
+	require.False(t, steps[0].Completed)
+
+	// The scheduled event matches the command
+	require.NoError(t, steps[1].Err)
+	require.Empty(t, steps[1].Commands)
+
+	require.False(t, steps[1].Completed)
+
+	// The workflow completes once the activity result is available
+	require.NoError(t, steps[2].Err)
+	require.True(t, steps[2].Completed)
+}
+
+func Test_ReplaySteps_StopsAtError(t *testing.T) {
+	events := []history.Event{
+		history.NewHistoryEvent(
+			1,
+			time.Now(),
+			history.EventType_WorkflowExecutionStarted,
+			&history.ExecutionStartedAttributes{
+				Name: "unregistered",
+			},
+		),
+		history.NewHistoryEvent(2, time.Now(), history.EventType_TimerFired, &history.TimerFiredAttributes{}, history.ScheduleEventID(1)),
+	}
+
+	steps := ReplaySteps(logger.NewDefaultLogger(), converter.DefaultConverter, NewRegistry(), core.NewWorkflowInstance("instanceID", "executionID"), events)
+	require.Len(t, steps, 1)
+	require.EqualError(t, steps[0].Err, "workflow unregistered not found")
+}
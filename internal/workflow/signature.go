@@ -0,0 +1,141 @@
+package workflow
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/cschleiden/go-workflows/internal/args"
+	"github.com/cschleiden/go-workflows/internal/fn"
+)
+
+// ErrInvalidWorkflow is returned when registering a workflow with an unsupported signature
+type ErrInvalidWorkflow struct {
+	Name   string
+	Reason string
+}
+
+func (e *ErrInvalidWorkflow) Error() string {
+	return fmt.Sprintf("invalid workflow %s: %s", e.Name, e.Reason)
+}
+
+// ErrInvalidActivity is returned when registering an activity with an unsupported signature
+type ErrInvalidActivity struct {
+	Name   string
+	Reason string
+}
+
+func (e *ErrInvalidActivity) Error() string {
+	return fmt.Sprintf("invalid activity %s: %s", e.Name, e.Reason)
+}
+
+var errType = reflect.TypeOf((*error)(nil)).Elem()
+
+func checkWorkflow(workflow Workflow) error {
+	wfType := reflect.TypeOf(workflow)
+	if wfType == nil || wfType.Kind() != reflect.Func {
+		return &ErrInvalidWorkflow{Name: fmt.Sprintf("%T", workflow), Reason: "workflow is not a function"}
+	}
+
+	name := fn.Name(workflow)
+
+	if wfType.NumIn() > 0 && args.IsContext(wfType.In(0)) {
+		return &ErrInvalidWorkflow{Name: name, Reason: "workflow must accept workflow.Context as first parameter, not context.Context. Use workflow.StdContext to pass it to helpers expecting a context.Context"}
+	}
+
+	if wfType.NumIn() == 0 || !args.IsOwnContext(wfType.In(0)) {
+		return &ErrInvalidWorkflow{Name: name, Reason: "workflow must accept workflow.Context as first parameter"}
+	}
+
+	if reason := checkSignature(wfType); reason != "" {
+		return &ErrInvalidWorkflow{Name: name, Reason: reason}
+	}
+
+	return nil
+}
+
+func checkActivity(name string, actType reflect.Type) error {
+	if actType.NumIn() > 0 && args.IsOwnContext(actType.In(0)) {
+		return &ErrInvalidActivity{Name: name, Reason: "activity must accept context.Context as first parameter, not workflow.Context. Activities run outside of workflows"}
+	}
+
+	if actType.NumIn() == 0 || !args.IsContext(actType.In(0)) {
+		return &ErrInvalidActivity{Name: name, Reason: "activity must accept context.Context as first parameter"}
+	}
+
+	if reason := checkSignature(actType); reason != "" {
+		return &ErrInvalidActivity{Name: name, Reason: reason}
+	}
+
+	return nil
+}
+
+// checkSignature validates the parameters after the context and the results of a workflow or activity
+// function. It returns a description of the problem, or an empty string if the signature is supported.
+func checkSignature(t reflect.Type) string {
+	for i := 1; i < t.NumIn(); i++ {
+		if err := checkSerializable(t.In(i), map[reflect.Type]bool{}); err != "" {
+			return fmt.Sprintf("parameter %d of type %v cannot be serialized: %s", i, t.In(i), err)
+		}
+	}
+
+	if t.NumOut() == 0 {
+		return "must return error"
+	}
+
+	if !t.Out(t.NumOut() - 1).Implements(errType) {
+		return "must return error as last return value"
+	}
+
+	for i := 0; i < t.NumOut()-1; i++ {
+		if err := checkSerializable(t.Out(i), map[reflect.Type]bool{}); err != "" {
+			return fmt.Sprintf("result %d of type %v cannot be serialized: %s", i, t.Out(i), err)
+		}
+	}
+
+	return ""
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// checkSerializable checks whether values of the given type can be encoded by the default, JSON based converter
+func checkSerializable(t reflect.Type, seen map[reflect.Type]bool) string {
+	if seen[t] {
+		return ""
+	}
+	seen[t] = true
+
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return ""
+	}
+
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return fmt.Sprintf("%v values are not supported", t.Kind())
+
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return checkSerializable(t.Elem(), seen)
+
+	case reflect.Map:
+		return checkSerializable(t.Elem(), seen)
+
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Tag.Get("json") == "-" {
+				continue
+			}
+
+			if err := checkSerializable(f.Type, seen); err != "" {
+				return fmt.Sprintf("field %s: %s", f.Name, err)
+			}
+		}
+	}
+
+	return ""
+}
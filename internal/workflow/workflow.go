@@ -7,31 +7,54 @@ import (
 
 	"github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/sync"
 )
 
 type Workflow interface{}
 
+// DynamicWorkflow handles workflows without a specific registration. It receives the name of the started workflow
+// and its encoded inputs, and returns the encoded result. Like any workflow, it has to be deterministic.
+type DynamicWorkflow func(ctx sync.Context, name string, inputs []payload.Payload) (payload.Payload, error)
+
 type workflow struct {
-	s      sync.Scheduler
-	fn     reflect.Value
-	result payload.Payload
-	err    error
+	s         sync.Scheduler
+	fn        reflect.Value
+	dynamic   func(ctx sync.Context, inputs []payload.Payload) (payload.Payload, error)
+	converter converter.Converter
+	validator core.Validator
+	result    payload.Payload
+	err       error
 }
 
-func NewWorkflow(workflowFn reflect.Value) *workflow {
+func NewWorkflow(workflowFn reflect.Value, converter converter.Converter) *workflow {
 	s := sync.NewScheduler()
 
 	return &workflow{
-		s:  s,
-		fn: workflowFn,
+		s:         s,
+		fn:        workflowFn,
+		converter: converter,
+	}
+}
+
+// NewDynamicWorkflow returns a workflow executing the given dynamic workflow for the workflow with the given name
+func NewDynamicWorkflow(name string, dynamic DynamicWorkflow, converter converter.Converter) *workflow {
+	w := NewWorkflow(reflect.Value{}, converter)
+	w.dynamic = func(ctx sync.Context, inputs []payload.Payload) (payload.Payload, error) {
+		return dynamic(ctx, name, inputs)
 	}
+
+	return w
 }
 
 func (w *workflow) Execute(ctx sync.Context, inputs []payload.Payload) error {
 	w.s.NewCoroutine(ctx, func(ctx sync.Context) error {
-		args, addContext, err := args.InputsToArgs(converter.DefaultConverter, w.fn, inputs)
+		if w.dynamic != nil {
+			return w.executeDynamic(ctx, inputs)
+		}
+
+		args, addContext, err := args.InputsToArgs(w.converter, w.fn, inputs)
 		if err != nil {
 			return fmt.Errorf("converting workflow inputs: %w", err)
 		}
@@ -40,29 +63,50 @@ func (w *workflow) Execute(ctx sync.Context, inputs []payload.Payload) error {
 			return errors.New("workflow must accept context as first argument")
 		}
 
+		if w.validator != nil {
+			inputs := make([]interface{}, len(args)-1)
+			for i := range inputs {
+				inputs[i] = args[i+1].Interface()
+			}
+
+			// Fail the workflow instead of the workflow task, executing it again would fail the same way
+			if err := w.validator.ValidateInputs(inputs); err != nil {
+				w.err = fmt.Errorf("validating workflow inputs: %w", err)
+				return nil
+			}
+		}
+
 		args[0] = reflect.ValueOf(ctx)
 
 		// Call workflow function
 		r := w.fn.Call(args)
 
 		// Process result
-		if len(r) < 1 || len(r) > 2 {
-			return errors.New("workflow has to return either (error) or (result, error)")
+		if len(r) < 1 {
+			return errors.New("workflow has to return either (error) or (result..., error)")
+		}
+
+		values := make([]interface{}, len(r)-1)
+		for i := range values {
+			values[i] = r[i].Interface()
+		}
+
+		if w.validator != nil && r[len(r)-1].IsNil() {
+			if err := w.validator.ValidateResults(values); err != nil {
+				w.err = fmt.Errorf("validating workflow result: %w", err)
+				return nil
+			}
 		}
 
 		var result payload.Payload
 
 		if len(r) > 1 {
-			var err error
-			result, err = converter.DefaultConverter.To(r[0].Interface())
-			if err != nil {
-				return fmt.Errorf("converting workflow result: %w", err)
-			}
+			result, err = converter.EncodeResults(w.converter, values)
 		} else {
-			result, err = converter.DefaultConverter.To(nil)
-			if err != nil {
-				return fmt.Errorf("converting workflow result: %w", err)
-			}
+			result, err = w.converter.To(nil)
+		}
+		if err != nil {
+			return fmt.Errorf("converting workflow result: %w", err)
 		}
 
 		errResult := r[len(r)-1]
@@ -84,6 +128,24 @@ func (w *workflow) Execute(ctx sync.Context, inputs []payload.Payload) error {
 	return w.s.Execute(ctx)
 }
 
+func (w *workflow) executeDynamic(ctx sync.Context, inputs []payload.Payload) error {
+	result, err := w.dynamic(ctx, inputs)
+	if err != nil {
+		w.err = err
+		return nil
+	}
+
+	if result == nil {
+		if result, err = w.converter.To(nil); err != nil {
+			return fmt.Errorf("converting workflow result: %w", err)
+		}
+	}
+
+	w.result = result
+
+	return nil
+}
+
 func (w *workflow) Continue(ctx sync.Context) error {
 	return w.s.Execute(ctx)
 }
@@ -0,0 +1,19 @@
+package workflowstate
+
+import (
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// QueryHandler answers a query with the given inputs
+type QueryHandler func(inputs []payload.Payload) (payload.Payload, error)
+
+// SetQueryHandler registers the handler for queries with the given name, replacing any previous handler
+func (wf *WfState) SetQueryHandler(name string, handler QueryHandler) {
+	wf.queryHandlers[name] = handler
+}
+
+// QueryHandler returns the handler for queries with the given name
+func (wf *WfState) QueryHandler(name string) (QueryHandler, bool) {
+	h, ok := wf.queryHandlers[name]
+	return h, ok
+}
@@ -0,0 +1,40 @@
+package workflowstate
+
+import (
+	"github.com/cschleiden/go-workflows/metrics"
+)
+
+type replayMetricsClient struct {
+	state   *WfState
+	metrics metrics.Client
+}
+
+func NewReplayMetricsClient(state *WfState, metrics metrics.Client) metrics.Client {
+	return &replayMetricsClient{state, metrics}
+}
+
+// Counter implements metrics.Client
+func (r *replayMetricsClient) Counter(name string, tags map[string]string, value int64) {
+	if !r.state.replaying {
+		r.metrics.Counter(name, tags, value)
+	}
+}
+
+// Gauge implements metrics.Client
+func (r *replayMetricsClient) Gauge(name string, tags map[string]string, value int64) {
+	if !r.state.replaying {
+		r.metrics.Gauge(name, tags, value)
+	}
+}
+
+// Distribution implements metrics.Client
+func (r *replayMetricsClient) Distribution(name string, tags map[string]string, value float64) {
+	if !r.state.replaying {
+		r.metrics.Distribution(name, tags, value)
+	}
+}
+
+// WithTags implements metrics.Client
+func (r *replayMetricsClient) WithTags(tags map[string]string) metrics.Client {
+	return NewReplayMetricsClient(r.state, r.metrics.WithTags(tags))
+}
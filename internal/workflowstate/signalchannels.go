@@ -1,7 +1,6 @@
 package workflowstate
 
 import (
-	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/sync"
 )
@@ -36,7 +35,7 @@ func GetSignalChannel[T any](ctx sync.Context, wf *WfState, name string) sync.Ch
 	wf.signalChannels[name] = &signalChannel{
 		receive: func(ctx sync.Context, input payload.Payload) {
 			var t T
-			if err := converter.DefaultConverter.From(input, &t); err != nil {
+			if err := wf.converter.From(input, &t); err != nil {
 				panic(err)
 			}
 
@@ -54,7 +53,7 @@ func GetSignalChannel[T any](ctx sync.Context, wf *WfState, name string) sync.Ch
 			payload := pendingSignals[i]
 
 			var s T
-			if err := converter.DefaultConverter.From(payload, &s); err != nil {
+			if err := wf.converter.From(payload, &s); err != nil {
 				panic(err)
 			}
 
@@ -1,15 +1,18 @@
 package workflowstate
 
 import (
+	"context"
 	"time"
 
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/internal/command"
 	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
 )
 
 type key int
@@ -19,12 +22,12 @@ var workflowCtxKey key
 type DecodingSettable func(v payload.Payload, err error) error
 
 // Use this to track futures for the workflow state
-func AsDecodingSettable[T any](f sync.SettableFuture[T]) DecodingSettable {
+func AsDecodingSettable[T any](cv converter.Converter, f sync.SettableFuture[T]) DecodingSettable {
 	return func(v payload.Payload, err error) error {
 		var ferr error
 		if v != nil {
 			var t T
-			converter.DefaultConverter.From(v, &t)
+			converter.Decode(cv, v, &t)
 			ferr = f.Set(t, err)
 		} else {
 			ferr = f.Set(*new(T), err)
@@ -34,6 +37,30 @@ func AsDecodingSettable[T any](f sync.SettableFuture[T]) DecodingSettable {
 	}
 }
 
+// VersionMarker is the name of the markers recording the versions chosen by workflow code, see workflow.GetVersion
+const VersionMarker = "workflow-version"
+
+// VersionMarkerData is the data of a VersionMarker marker
+type VersionMarkerData struct {
+	ChangeID string `json:"change_id"`
+	Version  int    `json:"version"`
+}
+
+// LocalActivityMarker is the name of the markers recording the results of local activities, see
+// workflow.ExecuteLocalActivity
+const LocalActivityMarker = "local-activity"
+
+// LocalActivityMarkerData is the data of a LocalActivityMarker marker
+type LocalActivityMarkerData struct {
+	Name    string           `json:"name"`
+	Result  payload.Payload  `json:"result,omitempty"`
+	Failure *history.Failure `json:"failure,omitempty"`
+}
+
+// LocalActivityRunner executes a local activity with the given encoded inputs and returns its encoded result. A
+// timeout of 0 uses the timeout the activity has been registered with, if any.
+type LocalActivityRunner func(ctx context.Context, instance *core.WorkflowInstance, activity interface{}, inputs []payload.Payload, timeout time.Duration) (payload.Payload, error)
+
 type signalChannel struct {
 	receive func(sync.Context, payload.Payload)
 	channel interface{}
@@ -49,13 +76,31 @@ type WfState struct {
 	pendingSignals map[string][]payload.Payload
 	signalChannels map[string]*signalChannel
 
-	logger log.Logger
+	queryHandlers map[string]QueryHandler
+
+	// recordedVersions are the versions recorded in the history, versions are the versions workflow code has
+	// already asked for in this execution
+	recordedVersions map[string]int
+	versions         map[string]int
+
+	historyLength          int64
+	historySize            int64
+	continueAsNewSuggested bool
+
+	logger  log.Logger
+	metrics metrics.Client
+
+	activityOptions func(name string) (core.ActivityRegistrationOptions, bool)
+
+	localActivityRunner LocalActivityRunner
+
+	converter converter.Converter
 
 	clock clock.Clock
 	time  time.Time
 }
 
-func NewWorkflowState(instance *core.WorkflowInstance, logger log.Logger, clock clock.Clock) *WfState {
+func NewWorkflowState(instance *core.WorkflowInstance, logger log.Logger, mc metrics.Client, converter converter.Converter, clock clock.Clock) *WfState {
 	state := &WfState{
 		instance:        instance,
 		commands:        []*command.Command{},
@@ -65,6 +110,13 @@ func NewWorkflowState(instance *core.WorkflowInstance, logger log.Logger, clock
 		pendingSignals: map[string][]payload.Payload{},
 		signalChannels: make(map[string]*signalChannel),
 
+		queryHandlers: map[string]QueryHandler{},
+
+		recordedVersions: map[string]int{},
+		versions:         map[string]int{},
+
+		converter: converter,
+
 		clock: clock,
 	}
 
@@ -72,6 +124,11 @@ func NewWorkflowState(instance *core.WorkflowInstance, logger log.Logger, clock
 		"instance_id", instance.InstanceID,
 		"execution_id", instance.ExecutionID))
 
+	state.metrics = NewReplayMetricsClient(state, mc.WithTags(map[string]string{
+		"instance_id":  instance.InstanceID,
+		"execution_id": instance.ExecutionID,
+	}))
+
 	return state
 }
 
@@ -114,6 +171,10 @@ func (wf *WfState) RemoveCommandByEventID(eventID int64) *command.Command {
 	for i, c := range wf.commands {
 		if c.ID == eventID {
 			wf.commands = append(wf.commands[:i], wf.commands[i+1:]...)
+
+			// The command has been committed in a previous execution, it must not be removed when it's canceled
+			c.State = command.CommandState_Committed
+
 			return c
 		}
 	}
@@ -137,6 +198,28 @@ func (wf *WfState) ClearCommands() {
 	wf.commands = []*command.Command{}
 }
 
+// SetRecordedVersion remembers a version recorded in the history of the workflow instance
+func (wf *WfState) SetRecordedVersion(changeID string, version int) {
+	wf.recordedVersions[changeID] = version
+}
+
+// RecordedVersion returns the version recorded in the history for the given change, if any
+func (wf *WfState) RecordedVersion(changeID string) (int, bool) {
+	v, ok := wf.recordedVersions[changeID]
+	return v, ok
+}
+
+// SetVersion remembers the version workflow code uses for the given change
+func (wf *WfState) SetVersion(changeID string, version int) {
+	wf.versions[changeID] = version
+}
+
+// Version returns the version workflow code uses for the given change, if it has asked for it before
+func (wf *WfState) Version(changeID string) (int, bool) {
+	v, ok := wf.versions[changeID]
+	return v, ok
+}
+
 func (wf *WfState) SetReplaying(replaying bool) {
 	wf.replaying = replaying
 }
@@ -153,6 +236,30 @@ func (wf *WfState) Time() time.Time {
 	return wf.time
 }
 
+// AddHistoryEvent records an event with the given size as part of the history of the workflow instance
+func (wf *WfState) AddHistoryEvent(size int64) {
+	wf.historyLength++
+	wf.historySize += size
+}
+
+// HistoryLength returns the number of events in the history known to the workflow
+func (wf *WfState) HistoryLength() int64 {
+	return wf.historyLength
+}
+
+// HistorySize returns the size of the history known to the workflow in bytes
+func (wf *WfState) HistorySize() int64 {
+	return wf.historySize
+}
+
+func (wf *WfState) SetContinueAsNewSuggested(suggested bool) {
+	wf.continueAsNewSuggested = suggested
+}
+
+func (wf *WfState) ContinueAsNewSuggested() bool {
+	return wf.continueAsNewSuggested
+}
+
 func (wf *WfState) Instance() *core.WorkflowInstance {
 	return wf.instance
 }
@@ -160,3 +267,33 @@ func (wf *WfState) Instance() *core.WorkflowInstance {
 func (wf *WfState) Logger() log.Logger {
 	return wf.logger
 }
+
+func (wf *WfState) Metrics() metrics.Client {
+	return wf.metrics
+}
+
+func (wf *WfState) SetActivityOptions(activityOptions func(name string) (core.ActivityRegistrationOptions, bool)) {
+	wf.activityOptions = activityOptions
+}
+
+// ActivityOptions returns the options the activity with the given name has been registered with, if known
+func (wf *WfState) ActivityOptions(name string) (core.ActivityRegistrationOptions, bool) {
+	if wf.activityOptions == nil {
+		return core.ActivityRegistrationOptions{}, false
+	}
+
+	return wf.activityOptions(name)
+}
+
+func (wf *WfState) SetLocalActivityRunner(runner LocalActivityRunner) {
+	wf.localActivityRunner = runner
+}
+
+// LocalActivityRunner returns the runner executing local activities, nil if the executor does not support them
+func (wf *WfState) LocalActivityRunner() LocalActivityRunner {
+	return wf.localActivityRunner
+}
+
+func (wf *WfState) Converter() converter.Converter {
+	return wf.converter
+}
@@ -0,0 +1,232 @@
+// Package leader elects a single leader among the workers sharing a backend, to run background jobs like
+// retention cleanup on exactly one of them. If the leader stops or loses its connection to the backend, another
+// candidate takes over after the lease duration.
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/logger"
+	"github.com/cschleiden/go-workflows/log"
+	"github.com/google/uuid"
+)
+
+// ErrNotSupported is returned by New if the backend does not support leases
+var ErrNotSupported = errors.New("backend does not support leader election")
+
+// Job is a background job run by the leader. Its context is canceled when the leader loses its leadership or
+// Run's context is canceled. A job should run until then, if it returns earlier, the leadership is released.
+type Job func(ctx context.Context) error
+
+type Options struct {
+	Logger log.Logger
+
+	// ID identifies the candidate, defaults to a random ID
+	ID string
+
+	// LeaseDuration is how long the leadership lasts without being renewed. After a leader stops without
+	// releasing its leadership, another candidate takes over after at most this time. Should be larger than
+	// the clock skew between workers.
+	LeaseDuration time.Duration
+
+	// RenewInterval is how often the leader renews its leadership and candidates try to acquire it. Needs to
+	// be shorter than LeaseDuration.
+	RenewInterval time.Duration
+}
+
+var DefaultOptions = Options{
+	LeaseDuration: time.Second * 15,
+	RenewInterval: time.Second * 5,
+}
+
+type Option func(*Options)
+
+func WithLogger(logger log.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithID(id string) Option {
+	return func(o *Options) {
+		o.ID = id
+	}
+}
+
+func WithLeaseDuration(d time.Duration) Option {
+	return func(o *Options) {
+		o.LeaseDuration = d
+	}
+}
+
+func WithRenewInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.RenewInterval = interval
+	}
+}
+
+// Elector campaigns for the leadership with the given name, and runs a job while it's the leader
+type Elector struct {
+	leases  backend.LeaseProvider
+	name    string
+	options Options
+
+	leader int32
+}
+
+// New returns an elector for the leadership with the given name. All candidates for the same job need to use
+// the same name.
+func New(b backend.Backend, name string, opts ...Option) (*Elector, error) {
+	leases, ok := b.(backend.LeaseProvider)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	options := DefaultOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.Logger == nil {
+		options.Logger = logger.NewDefaultLogger()
+	}
+
+	if options.ID == "" {
+		options.ID = uuid.NewString()
+	}
+
+	if options.RenewInterval <= 0 || options.RenewInterval >= options.LeaseDuration {
+		return nil, errors.New("renew interval needs to be positive and shorter than the lease duration")
+	}
+
+	options.Logger = options.Logger.With("leader", name, "candidate", options.ID)
+
+	return &Elector{
+		leases:  leases,
+		name:    name,
+		options: options,
+	}, nil
+}
+
+// IsLeader returns whether the elector currently holds the leadership and runs its job
+func (e *Elector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+// Run campaigns for the leadership until the context is canceled, and runs the job while it's the leader.
+// When the context is canceled, the job is stopped and the leadership released.
+func (e *Elector) Run(ctx context.Context, job Job) error {
+	t := time.NewTicker(e.options.RenewInterval)
+	defer t.Stop()
+
+	var (
+		stop    context.CancelFunc
+		done    chan error
+		renewed time.Time
+	)
+
+	stepDown := func() {
+		if stop != nil {
+			stop()
+			<-done
+		}
+
+		stop, done = nil, nil
+		atomic.StoreInt32(&e.leader, 0)
+
+		// Release the leadership even if the context has been canceled, so another candidate can take over
+		// without waiting for the lease to expire
+		rctx, cancel := context.WithTimeout(context.Background(), e.options.RenewInterval)
+		defer cancel()
+
+		if err := e.leases.ReleaseLease(rctx, e.name, e.options.ID); err != nil {
+			e.options.Logger.Error("could not release leadership", "error", err)
+		}
+	}
+
+	for {
+		start := time.Now()
+		acquired, err := e.leases.AcquireLease(ctx, e.name, e.options.ID, e.options.LeaseDuration)
+
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				break
+			}
+
+			e.options.Logger.Error("could not acquire leadership", "error", err)
+
+			// Stop the job before the lease might expire and another candidate could take over
+			if stop != nil && time.Since(renewed)+e.options.RenewInterval >= e.options.LeaseDuration {
+				e.options.Logger.Warn("could not renew leadership in time, stepping down")
+				stepDown()
+			}
+
+		case acquired:
+			renewed = start
+
+			if stop == nil {
+				e.options.Logger.Debug("acquired leadership")
+
+				var jobCtx context.Context
+				jobCtx, stop = context.WithCancel(ctx)
+				done = make(chan error, 1)
+				atomic.StoreInt32(&e.leader, 1)
+
+				go func() {
+					done <- job(jobCtx)
+				}()
+			}
+
+		case stop != nil:
+			e.options.Logger.Warn("lost leadership")
+			stepDown()
+		}
+
+		select {
+		case <-ctx.Done():
+			if stop != nil {
+				stepDown()
+			}
+
+			return ctx.Err()
+
+		case err := <-done:
+			if err != nil && ctx.Err() == nil {
+				e.options.Logger.Error("leader job failed", "error", err)
+			}
+
+			// The job has finished, release the leadership so another candidate can run it
+			stop()
+			stop = nil
+			stepDown()
+
+		case <-t.C:
+		}
+	}
+}
+
+// RunPeriodically runs fn every interval while the elector is the leader, see Run. Errors returned by fn are
+// logged and don't end the leadership.
+func (e *Elector) RunPeriodically(ctx context.Context, interval time.Duration, fn func(ctx context.Context) error) error {
+	return e.Run(ctx, func(ctx context.Context) error {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				e.options.Logger.Error("periodic leader job failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-t.C:
+			}
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Elector(t *testing.T) {
+	b := sqlite.NewInMemoryBackend()
+
+	var running, maxRunning int32
+	job := func(ctx context.Context) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		if n > atomic.LoadInt32(&maxRunning) {
+			atomic.StoreInt32(&maxRunning, n)
+		}
+
+		<-ctx.Done()
+		return nil
+	}
+
+	opts := []Option{WithLeaseDuration(time.Second), WithRenewInterval(20 * time.Millisecond)}
+
+	e1, err := New(b, "cleanup", opts...)
+	require.NoError(t, err)
+
+	e2, err := New(b, "cleanup", opts...)
+	require.NoError(t, err)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	done1 := make(chan error, 1)
+	go func() { done1 <- e1.Run(ctx1, job) }()
+
+	require.Eventually(t, e1.IsLeader, time.Second, 10*time.Millisecond)
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	go e2.Run(ctx2, job)
+
+	// The second candidate doesn't take over while the first one renews its leadership
+	time.Sleep(100 * time.Millisecond)
+	require.False(t, e2.IsLeader())
+
+	// Stopping the leader releases the leadership
+	cancel1()
+	require.ErrorIs(t, <-done1, context.Canceled)
+	require.False(t, e1.IsLeader())
+
+	require.Eventually(t, e2.IsLeader, time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&maxRunning))
+}
+
+func Test_Elector_ReleasesWhenJobEnds(t *testing.T) {
+	b := sqlite.NewInMemoryBackend()
+
+	e, err := New(b, "job", WithLeaseDuration(time.Second), WithRenewInterval(20*time.Millisecond))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs int32
+	go e.Run(ctx, func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})
+
+	// The job is started again after it returned
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&runs) >= 2 }, time.Second, 10*time.Millisecond)
+}
+
+func Test_New_InvalidOptions(t *testing.T) {
+	_, err := New(sqlite.NewInMemoryBackend(), "job", WithLeaseDuration(time.Second), WithRenewInterval(time.Second))
+	require.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package log
+
+// Level is the severity of a logged message
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelWarn
+	LevelError
+)
+
+type levelLogger struct {
+	logger Logger
+	level  Level
+}
+
+// WithLevel returns a logger that only passes messages with at least the given level on to logger. Panic
+// messages are always passed on.
+func WithLevel(logger Logger, level Level) Logger {
+	return &levelLogger{logger: logger, level: level}
+}
+
+func (l *levelLogger) Debug(msg string, fields ...interface{}) {
+	if l.level <= LevelDebug {
+		l.logger.Debug(msg, fields...)
+	}
+}
+
+func (l *levelLogger) Warn(msg string, fields ...interface{}) {
+	if l.level <= LevelWarn {
+		l.logger.Warn(msg, fields...)
+	}
+}
+
+func (l *levelLogger) Error(msg string, fields ...interface{}) {
+	if l.level <= LevelError {
+		l.logger.Error(msg, fields...)
+	}
+}
+
+func (l *levelLogger) Panic(msg string, fields ...interface{}) {
+	l.logger.Panic(msg, fields...)
+}
+
+func (l *levelLogger) With(fields ...interface{}) Logger {
+	return &levelLogger{logger: l.logger.With(fields...), level: l.level}
+}
@@ -0,0 +1,59 @@
+package log
+
+import "github.com/cschleiden/go-workflows/internal/payload"
+
+type redactingLogger struct {
+	logger Logger
+	redact func(payload.Payload) payload.Payload
+}
+
+// WithPayloadRedactor returns a logger that applies redact to all payloads passed as field values, for example
+// the encoded inputs of a dynamic workflow, before passing them on to logger. See converter.PayloadRedactor.
+func WithPayloadRedactor(logger Logger, redact func(payload.Payload) payload.Payload) Logger {
+	return &redactingLogger{logger: logger, redact: redact}
+}
+
+func (l *redactingLogger) Debug(msg string, fields ...interface{}) {
+	l.logger.Debug(msg, l.fields(fields)...)
+}
+
+func (l *redactingLogger) Warn(msg string, fields ...interface{}) {
+	l.logger.Warn(msg, l.fields(fields)...)
+}
+
+func (l *redactingLogger) Error(msg string, fields ...interface{}) {
+	l.logger.Error(msg, l.fields(fields)...)
+}
+
+func (l *redactingLogger) Panic(msg string, fields ...interface{}) {
+	l.logger.Panic(msg, l.fields(fields)...)
+}
+
+func (l *redactingLogger) With(fields ...interface{}) Logger {
+	return &redactingLogger{logger: l.logger.With(l.fields(fields)...), redact: l.redact}
+}
+
+// fields returns the given fields with all payloads redacted. Payloads are rendered as strings.
+func (l *redactingLogger) fields(fields []interface{}) []interface{} {
+	r := make([]interface{}, len(fields))
+
+	for i, f := range fields {
+		switch v := f.(type) {
+		case payload.Payload:
+			r[i] = string(l.redact(v))
+
+		case []payload.Payload:
+			redacted := make([]string, len(v))
+			for j, p := range v {
+				redacted[j] = string(l.redact(p))
+			}
+
+			r[i] = redacted
+
+		default:
+			r[i] = f
+		}
+	}
+
+	return r
+}
@@ -0,0 +1,99 @@
+package metrics
+
+// Metrics emitted by workers using the backend's metrics client
+const (
+	// WorkflowTaskScheduleToStart is a distribution of the time in milliseconds workflow tasks wait between being
+	// enqueued and a worker starting to execute them
+	WorkflowTaskScheduleToStart = "workflow.task.schedule_to_start"
+
+	// ActivityTaskScheduleToStart is a distribution of the time in milliseconds activity tasks wait between being
+	// enqueued and a worker starting to execute them. Tagged with the activity name and queue.
+	ActivityTaskScheduleToStart = "activity.task.schedule_to_start"
+
+	// SlowWorkflowTasks counts workflow tasks running longer than the worker's SlowWorkflowTaskThreshold. Tagged
+	// with the workflow name.
+	SlowWorkflowTasks = "workflow.task.slow"
+
+	// SlowActivities counts activities running longer than the worker's SlowActivityThreshold. Tagged with the
+	// activity name and queue.
+	SlowActivities = "activity.task.slow"
+
+	// DeterminismViolations counts non-deterministic calls from workflow code detected by the worker's
+	// DeterminismGuard. Tagged with the workflow name.
+	DeterminismViolations = "workflow.determinism.violations"
+
+	// WorkflowTasksReleased counts workflow tasks released back to the backend because the worker's dispatch
+	// queue was full
+	WorkflowTasksReleased = "workflow.task.released"
+
+	// WorkflowTaskPollDuration is a distribution of the time in milliseconds a poll for a workflow task takes.
+	// Tagged with found, which is true if the poll returned a task.
+	WorkflowTaskPollDuration = "workflow.task.poll.duration"
+
+	// ActivityTaskPollDuration is a distribution of the time in milliseconds a poll for an activity task takes.
+	// Tagged with found, which is true if the poll returned a task.
+	ActivityTaskPollDuration = "activity.task.poll.duration"
+
+	// WorkflowTaskExecutionDuration is a distribution of the time in milliseconds workers take to execute workflow
+	// tasks, including replaying the history. Tagged with the workflow name.
+	WorkflowTaskExecutionDuration = "workflow.task.execution.duration"
+
+	// ActivityTaskExecutionDuration is a distribution of the time in milliseconds activities take to execute.
+	// Tagged with the activity name and queue.
+	ActivityTaskExecutionDuration = "activity.task.execution.duration"
+
+	// WorkflowTasksFailed counts workflow tasks the worker could not execute
+	WorkflowTasksFailed = "workflow.task.failed"
+
+	// ActivityTasksFailed counts activity executions returning an error. Tagged with the activity name and queue.
+	ActivityTasksFailed = "activity.task.failed"
+
+	// WorkflowExecutorCacheHits counts workflow tasks continuing from a cached executor
+	WorkflowExecutorCacheHits = "workflow.cache.hits"
+
+	// WorkflowExecutorCacheMisses counts workflow tasks that need a new executor replaying the full history
+	WorkflowExecutorCacheMisses = "workflow.cache.misses"
+
+	// WorkflowHistoryEvents is a distribution of the number of history events of workflow instances, recorded
+	// after every workflow task. Tagged with the workflow name.
+	WorkflowHistoryEvents = "workflow.history.events"
+
+	// WorkflowHistorySize is a distribution of the size of the history of workflow instances in bytes, recorded
+	// after every workflow task. Tagged with the workflow name.
+	WorkflowHistorySize = "workflow.history.size"
+
+	// PendingWorkflowTasks is a gauge of the number of workflow tasks that are ready to be processed or are being
+	// processed, reported by workers with a BacklogMetricsInterval
+	PendingWorkflowTasks = "backend.pending.workflow_tasks"
+
+	// PendingActivityTasks is a gauge of the number of activity tasks that are ready to be processed or are being
+	// processed, reported by workers with a BacklogMetricsInterval
+	PendingActivityTasks = "backend.pending.activity_tasks"
+
+	// FutureEvents is a gauge of the number of events, like timers, that are scheduled but not yet visible,
+	// reported by workers with a BacklogMetricsInterval
+	FutureEvents = "backend.pending.future_events"
+)
+
+// Metrics emitted by backends using their metrics client
+const (
+	// BackendOperationDuration is a distribution of the time in milliseconds backends take for operations on
+	// the hot path, like fetching and completing tasks. Tagged with the backend and the operation, see the
+	// Operation constants in the backend package.
+	BackendOperationDuration = "backend.operation.duration"
+)
+
+// Client is a basic interface for emitting metrics. Tags are added to the emitted metric as key/value pairs.
+type Client interface {
+	// Counter adds value to the counter with the given name
+	Counter(name string, tags map[string]string, value int64)
+
+	// Gauge sets the gauge with the given name to value
+	Gauge(name string, tags map[string]string, value int64)
+
+	// Distribution records value as a sample of the distribution with the given name
+	Distribution(name string, tags map[string]string, value float64)
+
+	// WithTags returns a client instance that adds the given tags to every emitted metric
+	WithTags(tags map[string]string) Client
+}
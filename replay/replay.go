@@ -0,0 +1,161 @@
+// Package replay re-executes workflows against recorded histories, without a backend or worker. Replaying the
+// exported history of a production instance locally shows whether, and at which event, the workflow code diverges
+// from the recorded execution.
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cschleiden/go-workflows/converter"
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/logger"
+	internal "github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// Registry holds the workflows histories can be replayed against
+type Registry struct {
+	r *internal.Registry
+}
+
+func NewRegistry() *Registry {
+	return &Registry{r: internal.NewRegistry()}
+}
+
+func (r *Registry) RegisterWorkflow(wf workflow.Workflow, opts ...workflow.RegistrationOption) error {
+	return r.r.RegisterWorkflow(wf, opts...)
+}
+
+func (r *Registry) RegisterDynamicWorkflow(wf workflow.DynamicWorkflow, opts ...workflow.RegistrationOption) error {
+	return r.r.RegisterDynamicWorkflow(wf, opts...)
+}
+
+// NonDeterminismError is returned when the workflow code does not issue the commands recorded in the history
+type NonDeterminismError struct {
+	// EventIndex is the index of the event in the replayed history at which the workflow diverged
+	EventIndex int
+
+	// Event is the history event at EventIndex
+	Event history.Event
+
+	Err error
+}
+
+func (e *NonDeterminismError) Error() string {
+	return fmt.Sprintf("workflow diverged from history at event %d (%s, sequence id %d): %v", e.EventIndex, e.Event.Type, e.Event.SequenceID, e.Err)
+}
+
+func (e *NonDeterminismError) Unwrap() error {
+	return e.Err
+}
+
+type options struct {
+	logger    log.Logger
+	converter converter.Converter
+	instance  *workflow.Instance
+}
+
+type Option func(*options)
+
+// WithLogger sets the logger used while replaying
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithConverter sets the converter the recorded inputs and results have been encoded with. It has to match the
+// converter of the backend the history was exported from.
+func WithConverter(converter converter.Converter) Option {
+	return func(o *options) {
+		o.converter = converter
+	}
+}
+
+// WithInstance sets the instance the workflow sees as its own while replaying. Histories do not record it, so
+// workflows reading their instance get a placeholder by default.
+func WithInstance(instance *workflow.Instance) Option {
+	return func(o *options) {
+		o.instance = instance
+	}
+}
+
+// Workflow replays the given history against the workflow registered for it. It returns a *NonDeterminismError
+// if the workflow code diverges from the history, or if it issues commands the history does not record.
+func Workflow(registry *Registry, events []history.Event, opts ...Option) error {
+	o := &options{
+		logger:    logger.NewDefaultLogger(),
+		converter: converter.DefaultConverter,
+		instance:  core.NewWorkflowInstance("replay", "replay"),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if err := checkWorkflow(registry, events); err != nil {
+		return err
+	}
+
+	steps := internal.ReplaySteps(o.logger, o.converter, registry.r, o.instance, events)
+	for i, step := range steps {
+		if step.Err != nil {
+			return &NonDeterminismError{EventIndex: i, Event: step.Event, Err: step.Err}
+		}
+	}
+
+	if len(steps) == 0 {
+		return nil
+	}
+
+	// Commands issued during the last workflow task are recorded with it, anything left over is missing from the
+	// history
+	last := steps[len(steps)-1]
+	for _, c := range last.Commands {
+		if c.State == command.CommandState_Pending {
+			return &NonDeterminismError{
+				EventIndex: len(steps) - 1,
+				Event:      last.Event,
+				Err:        fmt.Errorf("workflow issued a command not recorded in the history: %v", c.Type),
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkWorkflow makes sure the workflow of the history is registered, so a missing registration is not reported
+// as a non-determinism error
+func checkWorkflow(registry *Registry, events []history.Event) error {
+	for _, event := range events {
+		if event.Type != history.EventType_WorkflowExecutionStarted {
+			continue
+		}
+
+		name := event.Attributes.(*history.ExecutionStartedAttributes).Name
+		if _, err := registry.r.GetWorkflow(name); err != nil {
+			if _, _, ok := registry.r.GetDynamicWorkflow(); !ok {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("history does not contain a %v event", history.EventType_WorkflowExecutionStarted)
+}
+
+// ReadHistory decodes a history exported as JSON, for example with client.ExportWorkflowHistory
+func ReadHistory(r io.Reader) ([]history.Event, error) {
+	var events []history.Event
+	if err := json.NewDecoder(r).Decode(&events); err != nil {
+		return nil, fmt.Errorf("decoding history: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,139 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// variant changes the commands issued by wf, to simulate deploying changed workflow code
+var variant int
+
+func activity1(ctx context.Context, n int) (int, error) {
+	return n * 2, nil
+}
+
+func wf(ctx workflow.Context) (int, error) {
+	if variant == 1 {
+		// Waits for a timer where the recorded execution scheduled an activity
+		if _, err := workflow.ScheduleTimer(ctx, time.Millisecond).Get(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	r, err := workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity1, 21).Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := workflow.ScheduleTimer(ctx, time.Millisecond).Get(ctx); err != nil {
+		return 0, err
+	}
+
+	if variant == 2 {
+		// Schedules an activity the recorded execution did not
+		workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, activity1, r)
+	}
+
+	return r, nil
+}
+
+func exportHistory(t *testing.T) []byte {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := sqlite.NewInMemoryBackend()
+	w := worker.New(b, nil)
+	require.NoError(t, w.RegisterWorkflow(wf))
+	require.NoError(t, w.RegisterActivity(activity1))
+	require.NoError(t, w.Start(ctx))
+	defer w.Shutdown(context.Background())
+
+	c := client.New(b)
+	instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{InstanceID: uuid.NewString()}, wf)
+	require.NoError(t, err)
+
+	r, err := client.GetWorkflowResult[int](ctx, c, instance, 5*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 42, r)
+
+	var buf bytes.Buffer
+	require.NoError(t, client.ExportWorkflowHistory(ctx, c, instance, &buf))
+
+	return buf.Bytes()
+}
+
+func Test_Workflow(t *testing.T) {
+	variant = 0
+	exported := exportHistory(t)
+
+	events, err := ReadHistory(bytes.NewReader(exported))
+	require.NoError(t, err)
+
+	registry := NewRegistry()
+	require.NoError(t, registry.RegisterWorkflow(wf))
+
+	tests := []struct {
+		name    string
+		variant int
+		f       func(t *testing.T, err error)
+	}{
+		{
+			name:    "Unchanged workflow",
+			variant: 0,
+			f: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:    "Diverging command",
+			variant: 1,
+			f: func(t *testing.T, err error) {
+				var nde *NonDeterminismError
+				require.ErrorAs(t, err, &nde)
+				require.Equal(t, history.EventType_ActivityScheduled, nde.Event.Type)
+				require.Equal(t, history.EventType_ActivityScheduled, events[nde.EventIndex].Type)
+			},
+		},
+		{
+			name:    "Command missing from history",
+			variant: 2,
+			f: func(t *testing.T, err error) {
+				var nde *NonDeterminismError
+				require.ErrorAs(t, err, &nde)
+				require.Equal(t, len(events)-1, nde.EventIndex)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variant = tt.variant
+			defer func() { variant = 0 }()
+
+			tt.f(t, Workflow(registry, events))
+		})
+	}
+}
+
+func Test_Workflow_NotRegistered(t *testing.T) {
+	variant = 0
+	events, err := ReadHistory(bytes.NewReader(exportHistory(t)))
+	require.NoError(t, err)
+
+	err = Workflow(NewRegistry(), events)
+	require.Error(t, err)
+
+	var nde *NonDeterminismError
+	require.False(t, errors.As(err, &nde))
+}
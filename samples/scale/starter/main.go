@@ -64,18 +64,16 @@ func main() {
 }
 
 func startWorkflow(ctx context.Context, c client.Client, wg *sync.WaitGroup) {
-	wf, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
-		InstanceID: uuid.NewString(),
-	}, scale.Workflow1, "Hello world "+uuid.NewString())
-	if err != nil {
-		panic("could not start workflow")
-	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
 
-	// log.Println("Started workflow", wf.InstanceID)
+	instanceID := uuid.NewString()
 
-	err = c.WaitForWorkflowInstance(ctx, wf, time.Second*30)
+	_, err := client.ExecuteWorkflow[string](ctx, c, client.WorkflowInstanceOptions{
+		InstanceID: instanceID,
+	}, scale.Workflow1, "Hello world "+uuid.NewString())
 	if err != nil {
-		log.Println("Received error while waiting for workflow", wf.InstanceID, err)
+		log.Println("Received error while executing workflow", instanceID, err)
 	}
 
 	cn := atomic.AddInt32(&count, -1)
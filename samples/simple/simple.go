@@ -40,18 +40,15 @@ func main() {
 }
 
 func runWorkflow(ctx context.Context, c client.Client) {
-	wf, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	result, err := client.ExecuteWorkflow[int](ctx, c, client.WorkflowInstanceOptions{
 		InstanceID: uuid.NewString(),
 	}, Workflow1, "Hello world"+uuid.NewString(), 42, Inputs{
 		Msg:   "",
 		Times: 0,
 	})
-	if err != nil {
-		log.Fatal(err)
-		panic("could not start workflow")
-	}
-
-	result, err := client.GetWorkflowResult[int](ctx, c, wf, time.Second*10)
 	if err != nil {
 		log.Fatal(err)
 	}
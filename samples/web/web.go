@@ -32,8 +32,13 @@ func main() {
 	// }
 
 	// Start diagnostic server under /diag
+	diagMux, err := diag.NewServeMux(b)
+	if err != nil {
+		panic(err)
+	}
+
 	m := http.NewServeMux()
-	m.Handle("/diag/", http.StripPrefix("/diag", diag.NewServeMux(b)))
+	m.Handle("/diag/", http.StripPrefix("/diag", diagMux))
 	go http.ListenAndServe(":3000", m)
 
 	// Run worker
@@ -7,6 +7,19 @@ import (
 
 type WorkflowTester = internal.WorkflowTester
 
-func NewWorkflowTester(wf workflow.Workflow) WorkflowTester {
-	return internal.NewWorkflowTester(wf)
+type WorkflowTesterOption = internal.WorkflowTesterOption
+
+// WithDeterminismGuard checks the workflow code for non-deterministic calls while testing, see the
+// determinism package
+var WithDeterminismGuard = internal.WithDeterminismGuard
+
+func NewWorkflowTester(wf workflow.Workflow, opts ...WorkflowTesterOption) WorkflowTester {
+	return internal.NewWorkflowTester(wf, opts...)
+}
+
+// RunWorkflow executes the workflow under test to completion in-process and returns its result, without a
+// backend or worker. Activities registered with the tester's registry run inline, mocked activities and
+// sub-workflows return their mocked results.
+func RunWorkflow[TResult any](wt WorkflowTester, args ...interface{}) (TResult, error) {
+	return internal.RunWorkflow[TResult](wt, args...)
 }
@@ -0,0 +1,34 @@
+package trace
+
+import "context"
+
+// Tracer is a basic interface for creating trace spans
+type Tracer interface {
+	// Start starts a new span with the given name. If ctx contains a span, the new span is a child of it. The
+	// returned context contains the new span.
+	Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span)
+}
+
+// Span is an operation that is part of a trace
+type Span interface {
+	// SetAttributes adds the given attributes to the span
+	SetAttributes(attributes map[string]string)
+
+	// RecordError records err as having occurred during the span
+	RecordError(err error)
+
+	// End completes the span
+	End()
+}
+
+// Propagator is an optional interface a Tracer can implement to continue traces across processes. The trace
+// context of the span creating a workflow instance is stored with the instance, so the workflow tasks and
+// activities executed for it by any worker show up as children of that span. Adapters for tracing libraries like
+// OpenTelemetry implement it with the library's propagator, e.g. using the W3C traceparent header.
+type Propagator interface {
+	// Inject returns the trace context of the span in ctx as key-value pairs
+	Inject(ctx context.Context) map[string]string
+
+	// Extract returns a context with the remote span described by a trace context returned by Inject
+	Extract(ctx context.Context, carrier map[string]string) context.Context
+}
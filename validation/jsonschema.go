@@ -0,0 +1,465 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type jsonSchemaValidator struct {
+	inputs []*schema
+	result *schema
+}
+
+// NewJSONSchemaValidator returns a validator checking the JSON representation of inputs and results against
+// JSON schemas. inputs holds a schema per input, in order, and result the schema of the result. Empty schemas
+// are not checked. For workflows and activities with multiple results, the schema is checked against an array
+// of the results.
+//
+// The validator supports the keywords type, enum, const, properties, required, additionalProperties, items,
+// minItems, maxItems, minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum, and exclusiveMaximum.
+// Other keywords, like format or description, are ignored. Schemas using $ref, allOf, anyOf, oneOf, or not are
+// rejected, so they aren't silently not enforced.
+func NewJSONSchemaValidator(inputs []string, result string) (Validator, error) {
+	v := &jsonSchemaValidator{
+		inputs: make([]*schema, len(inputs)),
+	}
+
+	for i, s := range inputs {
+		compiled, err := compileSchema(s)
+		if err != nil {
+			return nil, fmt.Errorf("compiling schema of input %d: %w", i, err)
+		}
+
+		v.inputs[i] = compiled
+	}
+
+	compiled, err := compileSchema(result)
+	if err != nil {
+		return nil, fmt.Errorf("compiling schema of result: %w", err)
+	}
+
+	v.result = compiled
+
+	return v, nil
+}
+
+func (v *jsonSchemaValidator) ValidateInputs(inputs []interface{}) error {
+	for i, input := range inputs {
+		if i >= len(v.inputs) || v.inputs[i] == nil {
+			continue
+		}
+
+		if err := validateJSON(v.inputs[i], "input", i, input); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *jsonSchemaValidator) ValidateResults(results []interface{}) error {
+	if v.result == nil || len(results) == 0 {
+		return nil
+	}
+
+	var result interface{} = results
+	if len(results) == 1 {
+		result = results[0]
+	}
+
+	return validateJSON(v.result, "result", 0, result)
+}
+
+func validateJSON(s *schema, position string, index int, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding %s %d: %w", position, index, err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("decoding %s %d: %w", position, index, err)
+	}
+
+	if path, reason := s.check(doc, ""); reason != "" {
+		return invalid(position, index, path, reason)
+	}
+
+	return nil
+}
+
+type schema struct {
+	types []string
+	enum  []interface{}
+
+	constValue interface{}
+	hasConst   bool
+
+	properties           map[string]*schema
+	required             []string
+	additionalProperties *schema
+	noAdditional         bool
+
+	items              *schema
+	minItems, maxItems *int
+
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+}
+
+var unsupportedKeywords = []string{"$ref", "allOf", "anyOf", "oneOf", "not", "if", "patternProperties", "dependentSchemas"}
+
+func compileSchema(s string) (*schema, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	return parseSchema(raw)
+}
+
+func parseSchema(raw interface{}) (*schema, error) {
+	if b, ok := raw.(bool); ok {
+		// true accepts everything, false nothing
+		if b {
+			return &schema{}, nil
+		}
+
+		return &schema{types: []string{}}, nil
+	}
+
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema must be an object or boolean")
+	}
+
+	for _, k := range unsupportedKeywords {
+		if _, ok := m[k]; ok {
+			return nil, fmt.Errorf("unsupported keyword %q", k)
+		}
+	}
+
+	s := &schema{}
+
+	switch t := m["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		s.types = []string{}
+		for _, tt := range t {
+			name, ok := tt.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid type %v", tt)
+			}
+
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("invalid type %v", t)
+	}
+
+	if enum, ok := m["enum"]; ok {
+		values, ok := enum.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("enum must be an array")
+		}
+
+		s.enum = values
+	}
+
+	s.constValue, s.hasConst = m["const"]
+
+	if props, ok := m["properties"]; ok {
+		pm, ok := props.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("properties must be an object")
+		}
+
+		s.properties = make(map[string]*schema, len(pm))
+		for name, p := range pm {
+			ps, err := parseSchema(p)
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", name, err)
+			}
+
+			s.properties[name] = ps
+		}
+	}
+
+	if req, ok := m["required"]; ok {
+		names, ok := req.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("required must be an array")
+		}
+
+		for _, n := range names {
+			name, ok := n.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid required property %v", n)
+			}
+
+			s.required = append(s.required, name)
+		}
+	}
+
+	switch ap := m["additionalProperties"].(type) {
+	case nil:
+	case bool:
+		s.noAdditional = !ap
+	default:
+		aps, err := parseSchema(ap)
+		if err != nil {
+			return nil, fmt.Errorf("additionalProperties: %w", err)
+		}
+
+		s.additionalProperties = aps
+	}
+
+	if items, ok := m["items"]; ok {
+		is, err := parseSchema(items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+
+		s.items = is
+	}
+
+	var err error
+	if s.minItems, err = intKeyword(m, "minItems"); err != nil {
+		return nil, err
+	}
+	if s.maxItems, err = intKeyword(m, "maxItems"); err != nil {
+		return nil, err
+	}
+	if s.minLength, err = intKeyword(m, "minLength"); err != nil {
+		return nil, err
+	}
+	if s.maxLength, err = intKeyword(m, "maxLength"); err != nil {
+		return nil, err
+	}
+	if s.minimum, err = numberKeyword(m, "minimum"); err != nil {
+		return nil, err
+	}
+	if s.maximum, err = numberKeyword(m, "maximum"); err != nil {
+		return nil, err
+	}
+	if s.exclusiveMinimum, err = numberKeyword(m, "exclusiveMinimum"); err != nil {
+		return nil, err
+	}
+	if s.exclusiveMaximum, err = numberKeyword(m, "exclusiveMaximum"); err != nil {
+		return nil, err
+	}
+
+	if p, ok := m["pattern"]; ok {
+		ps, ok := p.(string)
+		if !ok {
+			return nil, fmt.Errorf("pattern must be a string")
+		}
+
+		if s.pattern, err = regexp.Compile(ps); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+func numberKeyword(m map[string]interface{}, name string) (*float64, error) {
+	v, ok := m[name]
+	if !ok {
+		return nil, nil
+	}
+
+	n, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a number", name)
+	}
+
+	return &n, nil
+}
+
+func intKeyword(m map[string]interface{}, name string) (*int, error) {
+	n, err := numberKeyword(m, name)
+	if err != nil || n == nil {
+		return nil, err
+	}
+
+	if *n < 0 || *n != math.Trunc(*n) {
+		return nil, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+
+	i := int(*n)
+	return &i, nil
+}
+
+// check checks a decoded JSON value against the schema, and returns the path of the first invalid value and why
+// it's invalid
+func (s *schema) check(v interface{}, path string) (string, string) {
+	if s.types != nil && !hasType(s.types, v) {
+		if len(s.types) == 0 {
+			return path, "no value allowed"
+		}
+
+		return path, fmt.Sprintf("must be of type %s", strings.Join(s.types, " or "))
+	}
+
+	if s.hasConst && !reflect.DeepEqual(v, s.constValue) {
+		return path, fmt.Sprintf("must be %v", s.constValue)
+	}
+
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if reflect.DeepEqual(v, e) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return path, fmt.Sprintf("must be one of %v", s.enum)
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return s.checkObject(v, path)
+
+	case []interface{}:
+		if s.minItems != nil && len(v) < *s.minItems {
+			return path, fmt.Sprintf("must have at least %d items", *s.minItems)
+		}
+
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			return path, fmt.Sprintf("must have at most %d items", *s.maxItems)
+		}
+
+		if s.items != nil {
+			for i, item := range v {
+				if p, reason := s.items.check(item, fmt.Sprintf("%s[%d]", path, i)); reason != "" {
+					return p, reason
+				}
+			}
+		}
+
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.minLength != nil && n < *s.minLength {
+			return path, fmt.Sprintf("must be at least %d characters", *s.minLength)
+		}
+
+		if s.maxLength != nil && n > *s.maxLength {
+			return path, fmt.Sprintf("must be at most %d characters", *s.maxLength)
+		}
+
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return path, fmt.Sprintf("must match %s", s.pattern)
+		}
+
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			return path, fmt.Sprintf("must be at least %v", *s.minimum)
+		}
+
+		if s.maximum != nil && v > *s.maximum {
+			return path, fmt.Sprintf("must be at most %v", *s.maximum)
+		}
+
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			return path, fmt.Sprintf("must be greater than %v", *s.exclusiveMinimum)
+		}
+
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			return path, fmt.Sprintf("must be less than %v", *s.exclusiveMaximum)
+		}
+	}
+
+	return "", ""
+}
+
+func (s *schema) checkObject(v map[string]interface{}, path string) (string, string) {
+	for _, name := range s.required {
+		if _, ok := v[name]; !ok {
+			return joinPath(path, name), "is required"
+		}
+	}
+
+	// Check properties in a stable order, so the same error is reported for the same value
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ps, ok := s.properties[name]
+		if !ok {
+			if s.noAdditional {
+				return joinPath(path, name), "is not allowed"
+			}
+
+			ps = s.additionalProperties
+		}
+
+		if ps == nil {
+			continue
+		}
+
+		if p, reason := ps.check(v[name], joinPath(path, name)); reason != "" {
+			return p, reason
+		}
+	}
+
+	return "", ""
+}
+
+func hasType(types []string, v interface{}) bool {
+	for _, t := range types {
+		switch t {
+		case "null":
+			if v == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "object":
+			if _, ok := v.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := v.(float64); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := v.(float64); ok && n == math.Trunc(n) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const orderSchema = `{
+	"type": "object",
+	"required": ["id", "items"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "pattern": "^ord-[0-9]+$"},
+		"priority": {"enum": ["low", "high"]},
+		"items": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"properties": {
+					"quantity": {"type": "integer", "minimum": 1}
+				}
+			}
+		}
+	}
+}`
+
+type orderItem struct {
+	Quantity float64 `json:"quantity"`
+}
+
+type order struct {
+	ID       string      `json:"id,omitempty"`
+	Priority string      `json:"priority,omitempty"`
+	Items    []orderItem `json:"items"`
+	Note     string      `json:"note,omitempty"`
+}
+
+func Test_JSONSchemaValidator(t *testing.T) {
+	v, err := NewJSONSchemaValidator([]string{orderSchema, `{"type": "integer", "exclusiveMinimum": 0}`}, `{"type": "string", "maxLength": 3}`)
+	require.NoError(t, err)
+
+	valid := func() order {
+		return order{ID: "ord-1", Items: []orderItem{{Quantity: 2}}}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(o *order)
+		err    string
+	}{
+		{
+			name:   "valid",
+			modify: func(o *order) {},
+		},
+		{
+			name:   "required",
+			modify: func(o *order) { o.ID = "" },
+			err:    "validation failed: input 0: id: is required",
+		},
+		{
+			name:   "pattern",
+			modify: func(o *order) { o.ID = "1" },
+			err:    "validation failed: input 0: id: must match ^ord-[0-9]+$",
+		},
+		{
+			name:   "enum",
+			modify: func(o *order) { o.Priority = "urgent" },
+			err:    "validation failed: input 0: priority: must be one of [low high]",
+		},
+		{
+			name:   "additional property",
+			modify: func(o *order) { o.Note = "fragile" },
+			err:    "validation failed: input 0: note: is not allowed",
+		},
+		{
+			name:   "min items",
+			modify: func(o *order) { o.Items = []orderItem{} },
+			err:    "validation failed: input 0: items: must have at least 1 items",
+		},
+		{
+			name:   "integer",
+			modify: func(o *order) { o.Items[0].Quantity = 1.5 },
+			err:    "validation failed: input 0: items[0].quantity: must be of type integer",
+		},
+		{
+			name:   "minimum",
+			modify: func(o *order) { o.Items[0].Quantity = 0 },
+			err:    "validation failed: input 0: items[0].quantity: must be at least 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := valid()
+			tt.modify(&o)
+
+			err := v.ValidateInputs([]interface{}{o, 1})
+			if tt.err == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrInvalid)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+
+	require.EqualError(t, v.ValidateInputs([]interface{}{valid(), 0}), "validation failed: input 1: must be greater than 0")
+
+	require.NoError(t, v.ValidateResults([]interface{}{"ok"}))
+	require.EqualError(t, v.ValidateResults([]interface{}{"too long"}), "validation failed: result 0: must be at most 3 characters")
+}
+
+func Test_JSONSchemaValidator_InvalidSchema(t *testing.T) {
+	_, err := NewJSONSchemaValidator([]string{`{"$ref": "#/definitions/order"}`}, "")
+	require.EqualError(t, err, `compiling schema of input 0: unsupported keyword "$ref"`)
+
+	_, err = NewJSONSchemaValidator(nil, `{"type": "string", "pattern": "("}`)
+	require.Error(t, err)
+}
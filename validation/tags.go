@@ -0,0 +1,232 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TagName is the struct tag holding validation rules
+const TagName = "validate"
+
+type tagValidator struct{}
+
+// NewStructTagValidator returns a validator checking the rules in the `validate` tags of struct fields, in inputs
+// and results and in structs nested in them. Rules are separated by commas:
+//
+//	required    the field must not have its zero value
+//	min=N       numbers must be at least N, strings, slices, and maps must have at least N elements
+//	max=N       numbers must be at most N, strings, slices, and maps must have at most N elements
+//	len=N       strings, slices, and maps must have exactly N elements
+//	oneof=a b   the field must have one of the space separated values
+//	omitempty   skip the following rules if the field has its zero value
+//
+//	type Payment struct {
+//		Currency string `validate:"required,oneof=EUR USD"`
+//		Amount   int    `validate:"min=1"`
+//	}
+func NewStructTagValidator() Validator {
+	return tagValidator{}
+}
+
+func (tagValidator) ValidateInputs(inputs []interface{}) error {
+	return validateTags("input", inputs)
+}
+
+func (tagValidator) ValidateResults(results []interface{}) error {
+	return validateTags("result", results)
+}
+
+func validateTags(position string, values []interface{}) error {
+	for i, v := range values {
+		if path, reason := checkTags(reflect.ValueOf(v), ""); reason != "" {
+			return invalid(position, i, path, reason)
+		}
+	}
+
+	return nil
+}
+
+// checkTags checks the tags of the structs in v, and returns the path of the first invalid field and why it's
+// invalid
+func checkTags(v reflect.Value, path string) (string, string) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return "", ""
+		}
+
+		return checkTags(v.Elem(), path)
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				// Unexported fields are not serialized
+				continue
+			}
+
+			fieldPath := joinPath(path, f.Name)
+
+			if tag, ok := f.Tag.Lookup(TagName); ok {
+				if reason := checkRules(v.Field(i), tag); reason != "" {
+					return fieldPath, reason
+				}
+			}
+
+			if p, reason := checkTags(v.Field(i), fieldPath); reason != "" {
+				return p, reason
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if p, reason := checkTags(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); reason != "" {
+				return p, reason
+			}
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if p, reason := checkTags(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key())); reason != "" {
+				return p, reason
+			}
+		}
+	}
+
+	return "", ""
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+
+	return path + "." + field
+}
+
+// checkRules checks the value of a field against the rules of its tag
+func checkRules(v reflect.Value, tag string) string {
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		switch name {
+		case "":
+			continue
+
+		case "required":
+			if v.IsZero() {
+				return "is required"
+			}
+
+			continue
+
+		case "omitempty":
+			if v.IsZero() {
+				return ""
+			}
+
+			continue
+		}
+
+		var reason string
+
+		switch name {
+		case "min", "max", "len":
+			reason = checkBound(v, name, arg)
+
+		case "oneof":
+			reason = checkOneOf(v, strings.Fields(arg))
+
+		default:
+			reason = fmt.Sprintf("unknown validation rule %q", name)
+		}
+
+		if reason != "" {
+			return reason
+		}
+	}
+
+	return ""
+}
+
+func checkBound(v reflect.Value, rule, arg string) string {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			// Use required to reject nil pointers
+			return ""
+		}
+
+		v = v.Elem()
+	}
+
+	bound, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return fmt.Sprintf("invalid %s rule %q", rule, arg)
+	}
+
+	var n float64
+	var size bool
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	case reflect.String:
+		n, size = float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, size = float64(v.Len()), true
+	default:
+		return fmt.Sprintf("%s rule not supported for %v", rule, v.Type())
+	}
+
+	if rule == "len" && !size {
+		return fmt.Sprintf("len rule not supported for %v", v.Type())
+	}
+
+	unit := ""
+	if size {
+		unit = " elements"
+		if v.Kind() == reflect.String {
+			unit = " characters"
+		}
+	}
+
+	switch {
+	case rule == "min" && n < bound:
+		return fmt.Sprintf("must be at least %v%s", arg, unit)
+	case rule == "max" && n > bound:
+		return fmt.Sprintf("must be at most %v%s", arg, unit)
+	case rule == "len" && n != bound:
+		return fmt.Sprintf("must have exactly %v%s", arg, unit)
+	}
+
+	return ""
+}
+
+func checkOneOf(v reflect.Value, allowed []string) string {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			// Use required to reject nil pointers
+			return ""
+		}
+
+		v = v.Elem()
+	}
+
+	s := fmt.Sprint(v.Interface())
+	for _, a := range allowed {
+		if s == a {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("must be one of %s", strings.Join(allowed, ", "))
+}
@@ -0,0 +1,96 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City string `validate:"required"`
+}
+
+type customer struct {
+	Name      string    `validate:"required,max=5"`
+	Age       int       `validate:"min=18,max=130"`
+	Plan      string    `validate:"omitempty,oneof=free pro"`
+	Tags      []string  `validate:"max=2"`
+	Addresses []address `validate:"min=1"`
+	Nickname  *string   `validate:"min=2"`
+}
+
+func Test_StructTagValidator(t *testing.T) {
+	valid := func() customer {
+		return customer{Name: "Ada", Age: 36, Addresses: []address{{City: "London"}}}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *customer)
+		err    string
+	}{
+		{
+			name:   "valid",
+			modify: func(c *customer) {},
+		},
+		{
+			name:   "required",
+			modify: func(c *customer) { c.Name = "" },
+			err:    "validation failed: input 1: Name: is required",
+		},
+		{
+			name:   "max length",
+			modify: func(c *customer) { c.Name = "Grace Hopper" },
+			err:    "validation failed: input 1: Name: must be at most 5 characters",
+		},
+		{
+			name:   "min number",
+			modify: func(c *customer) { c.Age = 0 },
+			err:    "validation failed: input 1: Age: must be at least 18",
+		},
+		{
+			name:   "oneof",
+			modify: func(c *customer) { c.Plan = "enterprise" },
+			err:    "validation failed: input 1: Plan: must be one of free, pro",
+		},
+		{
+			name:   "max elements",
+			modify: func(c *customer) { c.Tags = []string{"a", "b", "c"} },
+			err:    "validation failed: input 1: Tags: must be at most 2 elements",
+		},
+		{
+			name:   "nested struct",
+			modify: func(c *customer) { c.Addresses = append(c.Addresses, address{}) },
+			err:    "validation failed: input 1: Addresses[1].City: is required",
+		},
+		{
+			name: "pointer",
+			modify: func(c *customer) {
+				n := "A"
+				c.Nickname = &n
+			},
+			err: "validation failed: input 1: Nickname: must be at least 2 characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(&c)
+
+			err := NewStructTagValidator().ValidateInputs([]interface{}{42, &c})
+			if tt.err == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrInvalid)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func Test_StructTagValidator_Results(t *testing.T) {
+	err := NewStructTagValidator().ValidateResults([]interface{}{address{}})
+	require.EqualError(t, err, "validation failed: result 0: City: is required")
+}
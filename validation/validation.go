@@ -0,0 +1,52 @@
+// Package validation validates the inputs and results of workflows and activities, so malformed data fails a
+// workflow or activity before it's executed instead of halfway through. Register a validator with
+// workflow.WithValidator or activity.WithValidator.
+package validation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+// Validator validates the inputs and results of a workflow or activity
+type Validator = core.Validator
+
+// ErrInvalid is wrapped by the errors returned for invalid inputs and results
+var ErrInvalid = errors.New("validation failed")
+
+func invalid(position string, index int, path string, reason string) error {
+	if path != "" {
+		return fmt.Errorf("%w: %s %d: %s: %s", ErrInvalid, position, index, path, reason)
+	}
+
+	return fmt.Errorf("%w: %s %d: %s", ErrInvalid, position, index, reason)
+}
+
+type multiValidator []Validator
+
+// All returns a validator running the given validators in order, it returns the first error
+func All(validators ...Validator) Validator {
+	return multiValidator(validators)
+}
+
+func (m multiValidator) ValidateInputs(inputs []interface{}) error {
+	for _, v := range m {
+		if err := v.ValidateInputs(inputs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m multiValidator) ValidateResults(results []interface{}) error {
+	for _, v := range m {
+		if err := v.ValidateResults(results); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
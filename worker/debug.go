@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+	"time"
+
+	internal "github.com/cschleiden/go-workflows/internal/worker"
+	workflowinternal "github.com/cschleiden/go-workflows/internal/workflow"
+)
+
+// ExecutingTask is a workflow task or activity a worker is currently executing
+type ExecutingTask = internal.ExecutingTask
+
+// CacheEntry is a workflow executor cached by a worker
+type CacheEntry = workflowinternal.CacheEntry
+
+type PollerStatus = internal.PollerStatus
+
+type WorkflowWorkerStatus = internal.WorkflowWorkerStatus
+
+type ActivityWorkerStatus = internal.ActivityWorkerStatus
+
+// Status is a snapshot of the execution state of a worker
+type Status struct {
+	Workflows  WorkflowWorkerStatus `json:"workflows"`
+	Activities ActivityWorkerStatus `json:"activities"`
+}
+
+// DebugHandler returns a handler showing the execution state of the given worker: the workflow tasks and
+// activities it's executing, its cached workflow executors, and its pollers. Like net/http/pprof, it's meant to
+// be served on an internal port, for example at /debug/workflows:
+//
+//	http.Handle("/debug/workflows", worker.DebugHandler(w))
+//
+// The state is rendered as text, or as JSON with the query parameter format=json.
+func DebugHandler(w Worker) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		s := w.Status()
+
+		if r.URL.Query().Get("format") == "json" {
+			rw.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(rw).Encode(s)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeStatus(rw, s, time.Now())
+	})
+}
+
+func writeStatus(out io.Writer, s *Status, now time.Time) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintf(tw, "Workflow tasks: %d\n", len(s.Workflows.Tasks))
+	fmt.Fprintln(tw, "INSTANCE\tEXECUTION\tWORKFLOW\tSTARTED\tRUNNING")
+	for _, t := range s.Workflows.Tasks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%v\n",
+			t.InstanceID, t.ExecutionID, t.Name, t.StartedAt.Format(time.RFC3339), now.Sub(t.StartedAt).Round(time.Millisecond))
+	}
+
+	fmt.Fprintf(tw, "\nActivities: %d\n", len(s.Activities.Activities))
+	fmt.Fprintln(tw, "INSTANCE\tEXECUTION\tACTIVITY\tACTIVITY ID\tATTEMPT\tSTARTED\tRUNNING")
+	for _, t := range s.Activities.Activities {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%v\n",
+			t.InstanceID, t.ExecutionID, t.Name, t.ActivityID, t.Attempt, t.StartedAt.Format(time.RFC3339), now.Sub(t.StartedAt).Round(time.Millisecond))
+	}
+
+	fmt.Fprintf(tw, "\nCached workflow executors: %d\n", len(s.Workflows.Cache))
+	fmt.Fprintln(tw, "INSTANCE\tEXECUTION\tWORKFLOW\tHISTORY\tIN USE\tLAST ACCESS")
+	for _, e := range s.Workflows.Cache {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%v\t%s\n",
+			e.InstanceID, e.ExecutionID, e.WorkflowName, e.HistoryLen, e.InUse, e.LastAccess.Format(time.RFC3339))
+	}
+
+	fmt.Fprintln(tw, "\nPollers")
+	fmt.Fprintln(tw, "KIND\tPOLLERS\tAUTO SCALE\tQUEUED\tPAUSED")
+	fmt.Fprintf(tw, "workflow\t%d\t%v\t%d\t%v\n",
+		s.Workflows.Pollers.Pollers, s.Workflows.Pollers.AutoScale, s.Workflows.Pollers.Queued, s.Workflows.Pollers.Paused)
+	fmt.Fprintf(tw, "activity\t%d\t%v\t%d\t%v\n",
+		s.Activities.Pollers.Pollers, s.Activities.Pollers.AutoScale, s.Activities.Pollers.Queued, s.Activities.Pollers.Paused)
+}
@@ -2,21 +2,28 @@ package worker
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/activity"
 	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/session"
+	"github.com/cschleiden/go-workflows/internal/signals"
+	"github.com/cschleiden/go-workflows/internal/state"
+	"github.com/cschleiden/go-workflows/internal/stream"
 	internal "github.com/cschleiden/go-workflows/internal/worker"
 	workflowinternal "github.com/cschleiden/go-workflows/internal/workflow"
 	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
 )
 
 type WorkflowRegistry interface {
-	RegisterWorkflow(w workflow.Workflow) error
+	RegisterWorkflow(w workflow.Workflow, opts ...workflow.RegistrationOption) error
 }
 
 type ActivityRegistry interface {
-	RegisterActivity(a interface{}) error
+	RegisterActivity(a interface{}, opts ...activity.RegistrationOption) error
 }
 
 type Registry interface {
@@ -27,14 +34,45 @@ type Registry interface {
 type Worker interface {
 	Registry
 
+	// RegisterDynamicWorkflow registers a handler for all workflows not registered with RegisterWorkflow. Instead
+	// of failing with "workflow not found", such workflows are passed to the handler with their name and encoded
+	// inputs.
+	RegisterDynamicWorkflow(wf workflow.DynamicWorkflow, opts ...workflow.RegistrationOption) error
+
+	// RegisterDynamicActivity registers a handler for all activities not registered with RegisterActivity. Instead
+	// of failing with "activity not found", such activities are passed to the handler with their name and
+	// encoded inputs.
+	RegisterDynamicActivity(a activity.DynamicActivity, opts ...activity.RegistrationOption) error
+
 	// Start starts the worker.
 	//
 	// To stop the worker, cancel the context passed to Start. To wait for completion of the active
-	// work items, call `WaitForCompletion`.
+	// work items, call `WaitForCompletion`. To let the worker finish the tasks it has already polled, call
+	// `Shutdown` instead.
 	Start(ctx context.Context) error
 
 	// WaitForCompletion
 	WaitForCompletion() error
+
+	// Shutdown stops the worker from polling for new tasks, and waits until the workflow tasks and activities
+	// it has already polled have been completed, so their locks don't have to time out before another worker
+	// can pick them up. Cached workflow executors are flushed afterwards.
+	//
+	// If the context is canceled before all tasks are done, Shutdown returns the context's error and leaves the
+	// remaining tasks running. Cancel the context passed to Start to stop them.
+	Shutdown(ctx context.Context) error
+
+	// PauseActivities stops the worker from polling for new activity tasks, without stopping the worker. It waits
+	// until the activities already started have finished, or the context is canceled. The worker keeps
+	// processing workflow tasks, and stays paused until ResumeActivities is called.
+	PauseActivities(ctx context.Context) error
+
+	// ResumeActivities continues polling for activity tasks after PauseActivities
+	ResumeActivities()
+
+	// Status returns a snapshot of the workflow tasks and activities the worker is executing, its cached
+	// workflow executors, and the status of its pollers. See DebugHandler.
+	Status() *Status
 }
 
 type worker struct {
@@ -43,10 +81,16 @@ type worker struct {
 	done chan struct{}
 	wg   *sync.WaitGroup
 
+	// stop cancels the context the worker was started with, once it has been shut down
+	stop context.CancelFunc
+
 	registry *workflowinternal.Registry
 
 	workflowWorker internal.WorkflowWorker
 	activityWorker internal.ActivityWorker
+	janitor        *internal.Janitor
+
+	sessionHost *session.Host
 
 	workflows  map[string]interface{}
 	activities map[string]interface{}
@@ -54,8 +98,42 @@ type worker struct {
 
 type Options = internal.Options
 
+type AutoTuneOptions = internal.AutoTuneOptions
+
+type PollerAutoScaleOptions = internal.PollerAutoScaleOptions
+
+// SlotSupplier controls how many tasks a worker processes concurrently
+type SlotSupplier = internal.SlotSupplier
+
+type ResourceSlotSupplierOptions = internal.ResourceSlotSupplierOptions
+
+// DispatchOverflowPolicy determines what happens to a polled task when the worker's dispatch queue is full
+type DispatchOverflowPolicy = internal.DispatchOverflowPolicy
+
+const (
+	// DispatchOverflowBlock stops polling for new tasks until there is room in the dispatch queue
+	DispatchOverflowBlock = internal.DispatchOverflowBlock
+
+	// DispatchOverflowRelease releases polled tasks back to the backend while the dispatch queue is full
+	DispatchOverflowRelease = internal.DispatchOverflowRelease
+)
+
+type HistoryLimits = workflowinternal.HistoryLimits
+
+// ErrInvalidWorkflow is returned by RegisterWorkflow if the workflow's signature is not supported
+type ErrInvalidWorkflow = workflowinternal.ErrInvalidWorkflow
+
+// ErrInvalidActivity is returned by RegisterActivity if the activity's signature is not supported
+type ErrInvalidActivity = workflowinternal.ErrInvalidActivity
+
 var DefaultWorkerOptions = internal.DefaultOptions
 
+// NewResourceSlotSupplier creates a slot supplier that pauses processing new tasks while the memory or CPU
+// usage of the process exceeds the configured limits
+func NewResourceSlotSupplier(options ResourceSlotSupplierOptions) SlotSupplier {
+	return internal.NewResourceSlotSupplier(options)
+}
+
 func New(backend backend.Backend, options *Options) Worker {
 	if options == nil {
 		options = &internal.DefaultOptions
@@ -63,6 +141,38 @@ func New(backend backend.Backend, options *Options) Worker {
 
 	registry := workflowinternal.NewRegistry()
 
+	// Register the workflow running instances created with a cron schedule
+	registry.RegisterWorkflow(workflow.RunCronSchedule)
+
+	// Register internal activities delivering signals sent from workflow code
+	registry.RegisterActivity(&signals.Activities{Signaler: internal.NewBackendSignaler(backend)})
+
+	// Register internal activities reading and writing state from workflow code
+	stateActivities := &state.Activities{}
+	if s, ok := backend.(state.Store); ok {
+		stateActivities.Store = s
+	}
+	registry.RegisterActivity(stateActivities)
+
+	// Register internal activities reading and deleting streams from workflow code. Streams are stored as state.
+	registry.RegisterActivity(&stream.Activities{Store: stateActivities.Store})
+
+	// Host sessions on a queue only this worker polls
+	var sessionHost *session.Host
+	if options.EnableSessions {
+		sessionHost = session.NewHost("_session:" + uuid.NewString())
+
+		o := *options
+		o.ActivityQueues = sessionQueues(options.ActivityQueues, sessionHost.Queue)
+		options = &o
+	}
+
+	registry.RegisterActivity(&session.Activities{
+		Host:      sessionHost,
+		Signaler:  internal.NewBackendSignaler(backend),
+		Converter: options.PayloadConverter(backend),
+	})
+
 	return &worker{
 		backend: backend,
 
@@ -71,6 +181,9 @@ func New(backend backend.Backend, options *Options) Worker {
 
 		workflowWorker: internal.NewWorkflowWorker(backend, registry, options),
 		activityWorker: internal.NewActivityWorker(backend, registry, clock.New(), options),
+		janitor:        internal.NewJanitor(backend, options),
+
+		sessionHost: sessionHost,
 
 		registry: registry,
 
@@ -79,9 +192,38 @@ func New(backend backend.Backend, options *Options) Worker {
 	}
 }
 
+// sessionQueues returns the queues polled by a worker hosting sessions on the given host queue
+func sessionQueues(queues []string, hostQueue string) []string {
+	r := []string{backend.DefaultActivityQueue}
+	if len(queues) > 0 {
+		r = append([]string{}, queues...)
+	}
+
+	return append(r, session.Queue, hostQueue)
+}
+
 func (w *worker) Start(ctx context.Context) error {
-	w.workflowWorker.Start(ctx)
-	w.activityWorker.Start(ctx)
+	ctx, w.stop = context.WithCancel(ctx)
+
+	if err := w.workflowWorker.Start(ctx); err != nil {
+		return fmt.Errorf("starting workflow worker: %w", err)
+	}
+
+	if err := w.activityWorker.Start(ctx); err != nil {
+		return fmt.Errorf("starting activity worker: %w", err)
+	}
+
+	if err := w.janitor.Start(ctx); err != nil {
+		return fmt.Errorf("starting janitor: %w", err)
+	}
+
+	if w.sessionHost != nil {
+		// Fail hosted sessions when the worker stops, activities are not canceled
+		go func() {
+			<-ctx.Done()
+			w.sessionHost.Stop()
+		}()
+	}
 
 	return nil
 }
@@ -95,13 +237,82 @@ func (w *worker) WaitForCompletion() error {
 		return err
 	}
 
+	if err := w.janitor.WaitForCompletion(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (w *worker) RegisterWorkflow(wf workflow.Workflow) error {
-	return w.registry.RegisterWorkflow(wf)
+func (w *worker) Shutdown(ctx context.Context) error {
+	if w.stop == nil {
+		return nil
+	}
+
+	errs := make(chan error, 2)
+
+	go func() {
+		if err := w.workflowWorker.Shutdown(ctx); err != nil {
+			errs <- fmt.Errorf("shutting down workflow worker: %w", err)
+			return
+		}
+
+		errs <- nil
+	}()
+
+	go func() {
+		if err := w.activityWorker.Shutdown(ctx); err != nil {
+			errs <- fmt.Errorf("shutting down activity worker: %w", err)
+			return
+		}
+
+		errs <- nil
+	}()
+
+	var err error
+	for i := 0; i < 2; i++ {
+		if werr := <-errs; werr != nil && err == nil {
+			err = werr
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// All tasks are done, stop the janitor and everything else running with the worker
+	w.stop()
+
+	return w.janitor.WaitForCompletion()
+}
+
+func (w *worker) PauseActivities(ctx context.Context) error {
+	return w.activityWorker.Pause(ctx)
+}
+
+func (w *worker) ResumeActivities() {
+	w.activityWorker.Resume()
+}
+
+func (w *worker) Status() *Status {
+	return &Status{
+		Workflows:  w.workflowWorker.Status(),
+		Activities: w.activityWorker.Status(),
+	}
+}
+
+func (w *worker) RegisterWorkflow(wf workflow.Workflow, opts ...workflow.RegistrationOption) error {
+	return w.registry.RegisterWorkflow(wf, opts...)
+}
+
+func (w *worker) RegisterActivity(a interface{}, opts ...activity.RegistrationOption) error {
+	return w.registry.RegisterActivity(a, opts...)
+}
+
+func (w *worker) RegisterDynamicWorkflow(wf workflow.DynamicWorkflow, opts ...workflow.RegistrationOption) error {
+	return w.registry.RegisterDynamicWorkflow(wf, opts...)
 }
 
-func (w *worker) RegisterActivity(a interface{}) error {
-	return w.registry.RegisterActivity(a)
+func (w *worker) RegisterDynamicActivity(a activity.DynamicActivity, opts ...activity.RegistrationOption) error {
+	return w.registry.RegisterDynamicActivity(a, opts...)
 }
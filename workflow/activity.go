@@ -1,11 +1,12 @@
 package workflow
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	a "github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/command"
-	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
@@ -13,6 +14,30 @@ import (
 
 type ActivityOptions struct {
 	RetryOptions RetryOptions
+
+	// MemoizeFor caches the result of a successful activity execution for the given duration. Executions
+	// of the same activity with the same inputs during that time return the cached result instead of
+	// running the activity again. Only use for deterministic activities. Requires a backend that supports
+	// caching activity results, otherwise the activity is always executed.
+	MemoizeFor time.Duration
+
+	// StartToCloseTimeout limits how long a single execution of the activity may take. The timeout is
+	// set as deadline on the context passed to the activity. If 0, the timeout the activity has been
+	// registered with is used.
+	StartToCloseTimeout time.Duration
+
+	// HeartbeatTimeout fails a single execution of the activity if it does not call activity.RecordHeartbeat
+	// within the timeout, so a stuck activity is retried quickly instead of running until StartToCloseTimeout.
+	// If 0, the heartbeat timeout the activity has been registered with is used.
+	HeartbeatTimeout time.Duration
+
+	// Queue is the activity queue to schedule the activity on. Only workers polling that queue execute the
+	// activity, see the ActivityQueues worker option. If empty, the queue the activity has been registered with
+	// is used, if known to the worker executing the workflow, otherwise the default queue.
+	Queue string
+
+	// Session executes the activity on the worker hosting the session, see CreateSession. Overrides Queue.
+	Session *Session
 }
 
 var DefaultActivityOptions = ActivityOptions{
@@ -20,13 +45,43 @@ var DefaultActivityOptions = ActivityOptions{
 }
 
 // ExecuteActivity schedules the given activity to be executed
+//
+// If options does not specify any retry options, the retry options the activity has been registered with
+// are used, if known to the worker executing the workflow.
 func ExecuteActivity[TResult any](ctx sync.Context, options ActivityOptions, activity interface{}, args ...interface{}) Future[TResult] {
-	return withRetries(ctx, options.RetryOptions, func(ctx sync.Context) Future[TResult] {
-		return executeActivity[TResult](ctx, options, activity, args...)
+	if s := options.Session; s != nil {
+		if s.completed || s.Failed() {
+			f := sync.NewFuture[TResult]()
+			f.Set(*new(TResult), ErrSessionFailed)
+			return f
+		}
+
+		options.Queue = s.queue
+		options.Session = nil
+
+		return withSession(ctx, s, ExecuteActivity[TResult](ctx, options, activity, args...))
+	}
+
+	if options.RetryOptions.IsZero() {
+		wfState := workflowstate.WorkflowState(ctx)
+		if ro, ok := wfState.ActivityOptions(fn.Name(activity)); ok && ro.RetryOptions != nil {
+			options.RetryOptions = *ro.RetryOptions
+		}
+	}
+
+	return withRetries(ctx, options.RetryOptions, func(ctx sync.Context, attempt int) Future[TResult] {
+		return executeActivity[TResult](ctx, options, attempt, activity, args...)
 	})
 }
 
-func executeActivity[TResult any](ctx sync.Context, options ActivityOptions, activity interface{}, args ...interface{}) Future[TResult] {
+// ExecuteActivityFn schedules the given activity to be executed. In contrast to ExecuteActivity, the input and
+// result types are inferred from the activity function, so mismatched arguments are caught at compile time.
+// Use a struct to pass multiple values.
+func ExecuteActivityFn[TIn, TOut any](ctx sync.Context, options ActivityOptions, activity func(context.Context, TIn) (TOut, error), input TIn) Future[TOut] {
+	return ExecuteActivity[TOut](ctx, options, activity, input)
+}
+
+func executeActivity[TResult any](ctx sync.Context, options ActivityOptions, attempt int, activity interface{}, args ...interface{}) Future[TResult] {
 	f := sync.NewFuture[TResult]()
 
 	if ctx.Err() != nil {
@@ -34,19 +89,28 @@ func executeActivity[TResult any](ctx sync.Context, options ActivityOptions, act
 		return f
 	}
 
-	inputs, err := a.ArgsToInputs(converter.DefaultConverter, args...)
+	wfState := workflowstate.WorkflowState(ctx)
+
+	inputs, err := a.ArgsToInputs(wfState.Converter(), args...)
 	if err != nil {
 		f.Set(*new(TResult), fmt.Errorf("converting activity input: %w", err))
 		return f
 	}
 
-	wfState := workflowstate.WorkflowState(ctx)
 	scheduleEventID := wfState.GetNextScheduleEventID()
 
 	name := fn.Name(activity)
-	cmd := command.NewScheduleActivityTaskCommand(scheduleEventID, name, inputs)
+
+	queue := options.Queue
+	if queue == "" {
+		if ro, ok := wfState.ActivityOptions(name); ok {
+			queue = ro.Queue
+		}
+	}
+
+	cmd := command.NewScheduleActivityTaskCommand(scheduleEventID, name, inputs, options.MemoizeFor, options.StartToCloseTimeout, options.HeartbeatTimeout, queue, attempt)
 	wfState.AddCommand(&cmd)
-	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(f))
+	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(wfState.Converter(), f))
 
 	// Handle cancellation
 	if d := ctx.Done(); d != nil {
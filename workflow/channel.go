@@ -3,15 +3,21 @@ package workflow
 import "github.com/cschleiden/go-workflows/internal/sync"
 
 type Channel[T any] interface {
+	ReceiveChannel[T]
+
 	Send(ctx Context, v T)
 
 	SendNonblocking(ctx Context, v T) (ok bool)
 
+	Close()
+}
+
+// ReceiveChannel is the receiving side of a channel, for example of a signal channel. Use it with Receive to wait
+// for values in Select.
+type ReceiveChannel[T any] interface {
 	Receive(ctx Context) (v T, ok bool)
 
 	ReceiveNonBlocking(ctx Context) (v T, ok bool)
-
-	Close()
 }
 
 func NewChannel[T any]() Channel[T] {
@@ -1,6 +1,10 @@
 package workflow
 
-import "github.com/cschleiden/go-workflows/internal/sync"
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/sync"
+)
 
 type CancelFunc = sync.CancelFunc
 
@@ -17,3 +21,13 @@ func WithCancel(parent Context) (ctx Context, cancel CancelFunc) {
 func NewDisconnectedContext(ctx Context) Context {
 	return sync.NewDisconnectedContext(ctx)
 }
+
+// StdContext returns a context.Context for passing the workflow context to helpers that expect a standard
+// context, for example, to check for cancellation in a long running computation. The returned context is
+// canceled when ctx is canceled or the returned cancel function is called, and returns the values of ctx.
+//
+// The helpers still need to be deterministic: they must not start goroutines, perform I/O, or wait on the
+// Done channel of the returned context. Use activities for anything else.
+func StdContext(ctx Context) (context.Context, CancelFunc) {
+	return sync.NewStdContext(ctx)
+}
@@ -0,0 +1,37 @@
+package workflow
+
+import (
+	"fmt"
+
+	a "github.com/cschleiden/go-workflows/internal/args"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// ContinueAsNewError is the error returned by ContinueAsNew
+type ContinueAsNewError = core.ContinueAsNewError
+
+// ContinueAsNew returns an error that, when returned by the workflow, finishes the current execution and starts a
+// new execution of the same workflow instance with the given arguments. The new execution has a new execution ID
+// and an empty history, so workflows looping forever can keep their history small:
+//
+//	func Workflow(ctx workflow.Context, processed int) (int, error) {
+//		// ...
+//		if workflow.GetInfo(ctx).ContinueAsNewSuggested {
+//			return 0, workflow.ContinueAsNew(ctx, processed)
+//		}
+//	}
+//
+// The arguments need to match the parameters of the workflow. Signals not yet received by the workflow and
+// pending timers are discarded.
+func ContinueAsNew(ctx sync.Context, args ...interface{}) error {
+	wfState := workflowstate.WorkflowState(ctx)
+
+	inputs, err := a.ArgsToInputs(wfState.Converter(), args...)
+	if err != nil {
+		return fmt.Errorf("converting workflow inputs: %w", err)
+	}
+
+	return &ContinueAsNewError{Inputs: inputs}
+}
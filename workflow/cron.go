@@ -0,0 +1,163 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/converter"
+	a "github.com/cschleiden/go-workflows/internal/args"
+	"github.com/cschleiden/go-workflows/internal/cron"
+	"github.com/cschleiden/go-workflows/internal/fn"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// Signals pausing and resuming a cron schedule, see RunCron
+const (
+	// CronPauseSignal pauses the schedule. An active run is not affected, but no further runs are started until
+	// the schedule is resumed.
+	CronPauseSignal = "cron:pause"
+
+	// CronResumeSignal resumes a paused schedule. Runs that were due while the schedule was paused are skipped.
+	CronResumeSignal = "cron:resume"
+)
+
+type CronOptions struct {
+	// Schedule is a cron expression with the five fields minute, hour, day of month, month, and day of week, like
+	// "*/15 * * * *", or a descriptor like @daily or @every 90m. Schedules are evaluated in UTC.
+	Schedule string
+
+	// SubWorkflowOptions are used to start every run. InstanceID is ignored, every run gets a new instance.
+	SubWorkflowOptions SubWorkflowOptions
+}
+
+// RunCron runs the given workflow as a sub-workflow at the times of a cron schedule, until the context is
+// canceled. It then cancels the active run and waits for it to finish. Runs never overlap: the next run is
+// started at the first time of the schedule after the previous run has finished. Every run is recorded in the
+// history as a RecurringMarker marker. Failed runs are logged, they don't stop later runs.
+//
+// The schedule is paused and resumed by sending CronPauseSignal and CronResumeSignal to the workflow instance.
+func RunCron(ctx Context, options CronOptions, workflow interface{}, args ...interface{}) error {
+	inputs, err := a.ArgsToInputs(workflowstate.WorkflowState(ctx).Converter(), args...)
+	if err != nil {
+		return fmt.Errorf("converting cron workflow input: %w", err)
+	}
+
+	return runCron(ctx, options, fn.Name(workflow), inputs)
+}
+
+// CronSchedule is the input of RunCronSchedule
+type CronSchedule struct {
+	// Schedule is the cron expression, see CronOptions
+	Schedule string `json:"schedule"`
+
+	// Workflow is the name of the workflow to run
+	Workflow string `json:"workflow"`
+
+	// Inputs are the encoded inputs passed to every run
+	Inputs []converter.Payload `json:"inputs,omitempty"`
+}
+
+// RunCronSchedule runs the workflow of the given schedule with RunCron. It is the workflow started for workflow
+// instances created by the client with a CronSchedule, and is registered with every worker.
+func RunCronSchedule(ctx Context, schedule CronSchedule) error {
+	return runCron(ctx, CronOptions{
+		Schedule:           schedule.Schedule,
+		SubWorkflowOptions: DefaultSubWorkflowOptions,
+	}, schedule.Workflow, schedule.Inputs)
+}
+
+func runCron(ctx Context, options CronOptions, name string, inputs []payload.Payload) error {
+	schedule, err := cron.Parse(options.Schedule)
+	if err != nil {
+		return err
+	}
+
+	subWorkflowOptions := options.SubWorkflowOptions
+	subWorkflowOptions.InstanceID = ""
+
+	var (
+		run    int
+		paused bool
+	)
+
+	pause := NewSignalChannel[any](ctx, CronPauseSignal)
+	resume := NewSignalChannel[any](ctx, CronResumeSignal)
+
+	// Handle signals and cancellation while waiting for the next run or for a run to finish
+	cases := func(more ...SelectCase) []SelectCase {
+		return append([]SelectCase{
+			Receive(pause, func(ctx Context, _ any, _ bool) {
+				paused = true
+			}),
+			Receive(resume, func(ctx Context, _ any, _ bool) {
+				paused = false
+			}),
+			sync.Receive(ctx.Done(), func(ctx Context, _ struct{}, _ bool) {}),
+		}, more...)
+	}
+
+	for ctx.Err() == nil {
+		if paused {
+			Select(ctx, cases()...)
+			continue
+		}
+
+		due := schedule.Next(Now(ctx))
+		if due.IsZero() {
+			return errors.New("cron schedule does not fire anymore")
+		}
+
+		tctx, cancelTimer := WithCancel(ctx)
+		timer := ScheduleTimerAt(tctx, due)
+
+		fired := false
+		for !fired && !paused && ctx.Err() == nil {
+			Select(ctx, cases(Await(timer, func(ctx Context, f Future[struct{}]) {
+				_, err := f.Get(ctx)
+				fired = err == nil
+			}))...)
+		}
+
+		cancelTimer()
+
+		if !fired || ctx.Err() != nil {
+			continue
+		}
+
+		run++
+		runCronOnce(ctx, subWorkflowOptions, run, due, name, inputs, cases)
+	}
+
+	return ctx.Err()
+}
+
+// runCronOnce starts a run of the cron schedule and waits for it to finish, handling signals in the meantime
+func runCronOnce(ctx Context, options SubWorkflowOptions, run int, due time.Time, name string, inputs []payload.Payload, cases func(...SelectCase) []SelectCase) {
+	f := withRetries(ctx, options.RetryOptions, func(ctx sync.Context, _ int) Future[any] {
+		return scheduleSubWorkflow(ctx, sync.NewFuture[any](), options, name, inputs)
+	})
+
+	if err := RecordMarker(ctx, RecurringMarker, RecurringRun{Run: run, DueAt: due, Action: RecurringActionStarted}); err != nil {
+		Logger(ctx).Error("recording cron run", "run", run, "error", err)
+	}
+
+	finished := false
+	for !finished {
+		Select(ctx, cases(Await(f, func(ctx Context, f Future[any]) {
+			finished = true
+
+			if _, err := f.Get(ctx); err != nil && ctx.Err() == nil {
+				Logger(ctx).Error("cron run failed", "run", run, "error", err)
+			}
+		}))...)
+
+		if ctx.Err() != nil {
+			// The run is canceled with the context, wait for it to finish
+			f.Get(ctx)
+			return
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package workflow
+
+import "github.com/cschleiden/go-workflows/internal/history"
+
+// NewError creates an error with the given type and message. Details are encoded with the converter when the
+// error is recorded, and can be decoded with Error.Details.
+//
+// Return it from activities or workflows to keep the type and details when the error crosses activity and
+// workflow boundaries. Callers get a *Failure, use errors.As with a *Error target to restore the error. The type
+// can be listed in RetryOptions.NonRetryableErrorTypes.
+func NewError(errType, message string, details ...interface{}) *Error {
+	return history.NewApplicationError(1, errType, message, details...)
+}
@@ -0,0 +1,118 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type ForEachOptions struct {
+	// ActivityOptions are used to execute the activity for every item
+	ActivityOptions ActivityOptions
+
+	// MaxParallelism limits how many activities are executed at the same time. 0 executes the activities for all
+	// items at once.
+	MaxParallelism int
+
+	// MaxFailures is the number of failed items tolerated. If more items fail, no further activities are started
+	// and the batch is aborted. 0 aborts on the first failure, a negative value tolerates any number of failures.
+	MaxFailures int
+}
+
+// ForEachError is returned by ForEach if the activity failed for any item
+type ForEachError struct {
+	// Errors are the errors of the failed items, by the index of the item
+	Errors map[int]error
+
+	// Aborted is set if more items failed than tolerated. Activities for the remaining items were not executed.
+	Aborted bool
+}
+
+func (e *ForEachError) Error() string {
+	indexes := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		indexes = append(indexes, i)
+	}
+
+	sort.Ints(indexes)
+
+	msgs := make([]string, len(indexes))
+	for i, index := range indexes {
+		msgs[i] = fmt.Sprintf("item %d: %v", index, e.Errors[index])
+	}
+
+	msg := fmt.Sprintf("%d items failed", len(e.Errors))
+	if e.Aborted {
+		msg += ", aborted"
+	}
+
+	return msg + ": " + strings.Join(msgs, "; ")
+}
+
+// ForEach executes the given activity for every item, with at most MaxParallelism activities running at the same
+// time, and waits for all of them. The results are returned in the order of the items.
+//
+// If the activity failed for any item, a *ForEachError with the errors of all failed items is returned along
+// with the results, failed and skipped items have the zero value as result. As long as no more than MaxFailures
+// items failed, all items are executed, so the results can be used despite the error.
+func ForEach[TIn, TOut any](ctx Context, items []TIn, options ForEachOptions, activity func(context.Context, TIn) (TOut, error)) ([]TOut, error) {
+	type pending struct {
+		index int
+		f     Future[TOut]
+	}
+
+	results := make([]TOut, len(items))
+	errs := make(map[int]error)
+
+	limit := options.MaxParallelism
+	if limit <= 0 {
+		limit = len(items)
+	}
+
+	var active []pending
+	next := 0
+	aborted := false
+
+	for {
+		for !aborted && next < len(items) && len(active) < limit {
+			active = append(active, pending{
+				index: next,
+				f:     ExecuteActivityFn(ctx, options.ActivityOptions, activity, items[next]),
+			})
+			next++
+		}
+
+		if len(active) == 0 {
+			break
+		}
+
+		cases := make([]SelectCase, len(active))
+		for i, p := range active {
+			i, p := i, p
+
+			cases[i] = Await(p.f, func(ctx Context, f Future[TOut]) {
+				v, err := f.Get(ctx)
+				if err != nil {
+					errs[p.index] = err
+				} else {
+					results[p.index] = v
+				}
+
+				active = append(active[:i], active[i+1:]...)
+			})
+		}
+
+		Select(ctx, cases...)
+
+		if options.MaxFailures >= 0 && len(errs) > options.MaxFailures {
+			aborted = true
+		}
+	}
+
+	if len(errs) > 0 {
+		return results, &ForEachError{Errors: errs, Aborted: aborted}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,14 @@
+package workflow
+
+import (
+	"github.com/google/uuid"
+)
+
+// NewGUID returns a new random UUID. The UUID is generated once and recorded in the history of the workflow
+// instance, replaying the workflow returns the same UUID. Use it instead of generating UUIDs in workflow code,
+// which would make the workflow non-deterministic.
+func NewGUID(ctx Context) (string, error) {
+	return SideEffect(ctx, func(Context) string {
+		return uuid.NewString()
+	}).Get(ctx)
+}
@@ -0,0 +1,33 @@
+package workflow
+
+import (
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// Info describes the current state of the workflow instance
+type Info struct {
+	Instance *Instance
+
+	// HistoryLength is the number of events in the history of the workflow instance
+	HistoryLength int64
+
+	// HistorySize is the size of the history of the workflow instance in bytes
+	HistorySize int64
+
+	// ContinueAsNewSuggested is true once the history has grown beyond the warning threshold configured for
+	// the worker. Long-running workflows should finish and continue their work in a new instance.
+	ContinueAsNewSuggested bool
+}
+
+// GetInfo returns information about the current workflow instance. The information is deterministic,
+// it's the same when the workflow is replayed.
+func GetInfo(ctx Context) *Info {
+	wfState := workflowstate.WorkflowState(ctx)
+
+	return &Info{
+		Instance:               wfState.Instance(),
+		HistoryLength:          wfState.HistoryLength(),
+		HistorySize:            wfState.HistorySize(),
+		ContinueAsNewSuggested: wfState.ContinueAsNewSuggested(),
+	}
+}
@@ -0,0 +1,128 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	a "github.com/cschleiden/go-workflows/internal/args"
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/fn"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+type LocalActivityOptions struct {
+	RetryOptions RetryOptions
+
+	// StartToCloseTimeout limits how long a single execution of the local activity may take. The timeout is set
+	// as deadline on the context passed to the activity. If 0, the timeout the activity has been registered with
+	// is used, if any.
+	StartToCloseTimeout time.Duration
+}
+
+var DefaultLocalActivityOptions = LocalActivityOptions{
+	RetryOptions: DefaultRetryOptions,
+}
+
+// ErrLocalActivitiesNotSupported is returned when executing a local activity outside of a worker or the
+// workflow tester
+var ErrLocalActivitiesNotSupported = errors.New("local activities are not supported by the workflow executor")
+
+// ExecuteLocalActivity executes the given activity directly in the worker executing the workflow, as part of the
+// current workflow task. Only its result is recorded in the history, as a marker, there is no round trip through
+// the backend. When the workflow is replayed, the activity is not executed again and the recorded result is used.
+//
+// Use it for short operations like reading configuration, which do not warrant scheduling an activity. The
+// workflow task waits for the local activity, so it has to finish well within the workflow deadlock timeout.
+// Local activities do not have to be registered, but if they are, their registration options apply. Retries wait
+// for durable timers between attempts, like the retries of activities.
+func ExecuteLocalActivity[TResult any](ctx sync.Context, options LocalActivityOptions, activity interface{}, args ...interface{}) Future[TResult] {
+	if options.RetryOptions.IsZero() {
+		wfState := workflowstate.WorkflowState(ctx)
+		if ro, ok := wfState.ActivityOptions(fn.Name(activity)); ok && ro.RetryOptions != nil {
+			options.RetryOptions = *ro.RetryOptions
+		}
+	}
+
+	return withRetries(ctx, options.RetryOptions, func(ctx sync.Context, attempt int) Future[TResult] {
+		return executeLocalActivity[TResult](ctx, options, activity, args...)
+	})
+}
+
+func executeLocalActivity[TResult any](ctx sync.Context, options LocalActivityOptions, activity interface{}, args ...interface{}) Future[TResult] {
+	f := sync.NewFuture[TResult]()
+
+	if ctx.Err() != nil {
+		f.Set(*new(TResult), ctx.Err())
+		return f
+	}
+
+	wfState := workflowstate.WorkflowState(ctx)
+	cv := wfState.Converter()
+
+	inputs, err := a.ArgsToInputs(cv, args...)
+	if err != nil {
+		f.Set(*new(TResult), fmt.Errorf("converting local activity inputs: %w", err))
+		return f
+	}
+
+	scheduleEventID := wfState.GetNextScheduleEventID()
+
+	if Replaying(ctx) {
+		// The result has been recorded in the history
+		wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(cv, f))
+		return f
+	}
+
+	run := wfState.LocalActivityRunner()
+	if run == nil {
+		f.Set(*new(TResult), ErrLocalActivitiesNotSupported)
+		return f
+	}
+
+	// Execute the activity outside of the workflow goroutine, activity code is not subject to the determinism checks
+	// of workflow code
+	var result payload.Payload
+	var activityErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		result, activityErr = run(context.Background(), wfState.Instance(), activity, inputs, options.StartToCloseTimeout)
+	}()
+	<-done
+
+	if activityErr != nil {
+		result = nil
+	}
+
+	data := workflowstate.LocalActivityMarkerData{
+		Name:    fn.Name(activity),
+		Result:  result,
+		Failure: history.NewFailure(cv, activityErr, Now(ctx)),
+	}
+	if data.Failure != nil {
+		data.Failure.ActivityName = data.Name
+	}
+
+	marker, err := cv.To(data)
+	if err != nil {
+		f.Set(*new(TResult), fmt.Errorf("converting local activity result: %w", err))
+		return f
+	}
+
+	cmd := command.NewRecordMarkerCommand(scheduleEventID, workflowstate.LocalActivityMarker, marker)
+	wfState.AddCommand(&cmd)
+
+	// Resolve the future like replaying the marker does
+	if data.Failure != nil {
+		activityErr = history.FailureWithConverter(data.Failure, cv)
+	}
+
+	workflowstate.AsDecodingSettable(cv, f)(result, activityErr)
+
+	return f
+}
@@ -0,0 +1,32 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// RecordMarker records a named marker with the given data in the history of the current workflow instance.
+// Markers do not affect the execution of the workflow, they are meant for auditing and debugging and show up
+// wherever the history is inspected.
+func RecordMarker(ctx sync.Context, name string, data interface{}) error {
+	wfState := workflowstate.WorkflowState(ctx)
+	scheduleEventID := wfState.GetNextScheduleEventID()
+
+	if Replaying(ctx) {
+		// Marker has already been recorded in the history
+		return nil
+	}
+
+	payload, err := wfState.Converter().To(data)
+	if err != nil {
+		return fmt.Errorf("converting marker data: %w", err)
+	}
+
+	cmd := command.NewRecordMarkerCommand(scheduleEventID, name, payload)
+	wfState.AddCommand(&cmd)
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package workflow
+
+import (
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+	"github.com/cschleiden/go-workflows/metrics"
+)
+
+// Metrics returns a metrics client with the workflow instance set as default tags. Metrics are
+// not emitted while the workflow is replaying, so they are only counted once per execution.
+func Metrics(ctx Context) metrics.Client {
+	wfState := workflowstate.WorkflowState(ctx)
+	return wfState.Metrics()
+}
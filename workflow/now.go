@@ -7,6 +7,8 @@ import (
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
 )
 
+// Now returns the current time in the workflow, the time the current workflow task started. It's recorded in the
+// history, so the workflow sees the same time when it's replayed. Use it instead of time.Now.
 func Now(ctx sync.Context) time.Time {
 	wfState := workflowstate.WorkflowState(ctx)
 	return wfState.Time()
@@ -0,0 +1,26 @@
+package workflow
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+// RegistrationOption configures a workflow when registering it with a worker
+type RegistrationOption = core.WorkflowRegistrationOption
+
+// WithTaskTimeout limits how long a single workflow task of the workflow may take, including replaying its
+// history. Takes precedence over the WorkflowTaskTimeout worker option.
+func WithTaskTimeout(timeout time.Duration) RegistrationOption {
+	return func(o *core.WorkflowRegistrationOptions) {
+		o.TaskTimeout = timeout
+	}
+}
+
+// WithValidator validates the inputs of the workflow before it's executed, and its result before the workflow
+// completes. If validation fails, the workflow fails with the validation error. See the validation package.
+func WithValidator(v core.Validator) RegistrationOption {
+	return func(o *core.WorkflowRegistrationOptions) {
+		o.Validator = v
+	}
+}
@@ -0,0 +1,56 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	a "github.com/cschleiden/go-workflows/internal/args"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// HandleQuery registers a handler for queries with the given name, see client.QueryWorkflow. The handler is a
+// function receiving the arguments of the query and returning a result and an error:
+//
+//	var status string
+//	workflow.HandleQuery(ctx, "status", func() (string, error) {
+//		return status, nil
+//	})
+//
+// Handlers are called between workflow tasks, they can read the state of the workflow but must not change it,
+// block, or call workflow APIs. Queries are answered by a worker without being recorded in the history.
+func HandleQuery(ctx Context, name string, handler interface{}) error {
+	h := reflect.ValueOf(handler)
+	t := reflect.TypeOf(handler)
+	if t == nil || t.Kind() != reflect.Func || t.NumOut() != 2 || !t.Out(1).Implements(errorType) {
+		return errors.New("query handler needs to be a function returning a result and an error")
+	}
+
+	for i := 0; i < t.NumIn(); i++ {
+		if a.IsContext(t.In(i)) || a.IsOwnContext(t.In(i)) {
+			return errors.New("query handlers cannot receive a context")
+		}
+	}
+
+	wfState := workflowstate.WorkflowState(ctx)
+	cv := wfState.Converter()
+
+	wfState.SetQueryHandler(name, func(inputs []payload.Payload) (payload.Payload, error) {
+		args, _, err := a.InputsToArgs(cv, h, inputs)
+		if err != nil {
+			return nil, fmt.Errorf("converting query inputs: %w", err)
+		}
+
+		r := h.Call(args)
+		if !r[1].IsNil() {
+			return nil, r[1].Interface().(error)
+		}
+
+		return cv.To(r[0].Interface())
+	})
+
+	return nil
+}
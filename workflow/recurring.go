@@ -0,0 +1,173 @@
+package workflow
+
+import (
+	"errors"
+	"time"
+)
+
+// OverlapPolicy decides what happens when a run of a recurring workflow is due while the previous run is still
+// active
+type OverlapPolicy int
+
+const (
+	// OverlapSkip skips the run
+	OverlapSkip OverlapPolicy = iota
+
+	// OverlapBufferOne starts the run once the previous run has finished. Further runs due in the meantime are
+	// skipped.
+	OverlapBufferOne
+
+	// OverlapCancelPrevious cancels the previous run and starts the new run right away
+	OverlapCancelPrevious
+)
+
+// RecurringMarker is the name of the marker recording what happened to each run of a recurring workflow, see
+// RecurringRun
+const RecurringMarker = "recurring-run"
+
+// RecurringAction is what happened to a run of a recurring workflow
+type RecurringAction string
+
+const (
+	RecurringActionStarted          RecurringAction = "started"
+	RecurringActionSkipped          RecurringAction = "skipped"
+	RecurringActionBuffered         RecurringAction = "buffered"
+	RecurringActionCanceledPrevious RecurringAction = "canceled-previous"
+)
+
+// RecurringRun is recorded as marker data for every due run of a recurring workflow
+type RecurringRun struct {
+	// Run is the number of the run, starting at 1
+	Run int `json:"run"`
+
+	// DueAt is the time the run was due
+	DueAt time.Time `json:"due_at"`
+
+	Action RecurringAction `json:"action"`
+}
+
+type RecurringOptions struct {
+	// Interval between runs
+	Interval time.Duration
+
+	// Overlap decides what happens when a run is due while the previous run is still active. Defaults to
+	// OverlapSkip.
+	Overlap OverlapPolicy
+
+	// SubWorkflowOptions are used to start every run. InstanceID is ignored, every run gets a new instance.
+	SubWorkflowOptions SubWorkflowOptions
+}
+
+// RunRecurring runs the given workflow as a sub-workflow every interval, until the context is canceled. It
+// then cancels the active run and waits for it to finish. What happens to each due run is recorded in the
+// history as a RecurringMarker marker, so the behavior for overlapping runs can be audited. Failed runs are
+// logged, they don't stop later runs.
+func RunRecurring(ctx Context, options RecurringOptions, workflow interface{}, args ...interface{}) error {
+	if options.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+
+	subWorkflowOptions := options.SubWorkflowOptions
+	subWorkflowOptions.InstanceID = ""
+
+	tctx, cancelTicks := WithCancel(ctx)
+	defer cancelTicks()
+
+	ticks := Tick(tctx, options.Interval)
+
+	var (
+		run       int
+		active    Future[any]
+		cancelRun CancelFunc
+		buffered  *RecurringRun
+	)
+
+	record := func(r RecurringRun) {
+		if err := RecordMarker(ctx, RecurringMarker, r); err != nil {
+			Logger(ctx).Error("recording recurring run", "run", r.Run, "error", err)
+		}
+	}
+
+	start := func(r RecurringRun) {
+		rctx, cancel := WithCancel(ctx)
+		active = CreateSubWorkflowInstance[any](rctx, subWorkflowOptions, workflow, args...)
+		cancelRun = cancel
+
+		r.Action = RecurringActionStarted
+		record(r)
+	}
+
+	finished := func(ctx Context, f Future[any]) {
+		if _, err := f.Get(ctx); err != nil && ctx.Err() == nil {
+			Logger(ctx).Error("recurring run failed", "error", err)
+		}
+
+		active = nil
+		cancelRun()
+	}
+
+	for {
+		cases := []SelectCase{
+			Receive(ticks, func(ctx Context, t time.Time, ok bool) {
+				if !ok {
+					return
+				}
+
+				run++
+				r := RecurringRun{Run: run, DueAt: t}
+
+				if active == nil {
+					start(r)
+					return
+				}
+
+				switch options.Overlap {
+				case OverlapBufferOne:
+					if buffered == nil {
+						r.Action = RecurringActionBuffered
+						buffered = &r
+					} else {
+						r.Action = RecurringActionSkipped
+					}
+
+					record(r)
+
+				case OverlapCancelPrevious:
+					r.Action = RecurringActionCanceledPrevious
+					record(r)
+
+					cancelRun()
+					start(r)
+
+				default:
+					r.Action = RecurringActionSkipped
+					record(r)
+				}
+			}),
+		}
+
+		if active != nil {
+			cases = append(cases, Await(active, func(ctx Context, f Future[any]) {
+				finished(ctx, f)
+
+				if buffered != nil && ctx.Err() == nil {
+					start(*buffered)
+					buffered = nil
+				}
+			}))
+		}
+
+		Select(ctx, cases...)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if active != nil {
+		cancelRun()
+		active.Get(ctx)
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,119 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// ErrRequestTimeout is returned by the future of SendRequest if no reply arrived within the timeout
+var ErrRequestTimeout = errors.New("request timed out")
+
+// Request is delivered as a signal to the workflow instance receiving a request sent with SendRequest
+type Request[T any] struct {
+	// ID correlates the request with its reply
+	ID string `json:"id"`
+
+	// ReplyTo is the instance ID of the workflow instance waiting for the reply
+	ReplyTo string `json:"reply_to"`
+
+	Arg T `json:"arg"`
+}
+
+type RequestOptions struct {
+	// Timeout is the time to wait for a reply after the request has been delivered. If zero, wait
+	// until the workflow is canceled.
+	Timeout time.Duration
+}
+
+type response[T any] struct {
+	Result T      `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func replySignalName(requestID string) string {
+	return "__reply:" + requestID
+}
+
+// NewRequestChannel returns a channel receiving requests sent to the current workflow instance with the
+// given name
+func NewRequestChannel[T any](ctx Context, name string) ReceiveChannel[Request[T]] {
+	return NewSignalChannel[Request[T]](ctx, name)
+}
+
+// SendRequest sends a request with the given name to another workflow instance and returns a future resolving
+// with the reply. The receiving workflow reads requests from NewRequestChannel and answers them with Reply.
+func SendRequest[TResp, TReq any](ctx Context, instanceID, name string, arg TReq, options RequestOptions) Future[TResp] {
+	f := sync.NewFuture[TResp]()
+
+	if ctx.Err() != nil {
+		f.Set(*new(TResp), ctx.Err())
+		return f
+	}
+
+	// Derive the correlation ID from the current execution, it's stable when replaying
+	wfState := workflowstate.WorkflowState(ctx)
+	instance := wfState.Instance()
+	id := fmt.Sprintf("%s:%d", instance.ExecutionID, wfState.GetNextScheduleEventID())
+
+	replies := NewSignalChannel[response[TResp]](ctx, replySignalName(id))
+
+	sent := SignalWorkflow(ctx, instanceID, name, Request[TReq]{
+		ID:      id,
+		ReplyTo: instance.InstanceID,
+		Arg:     arg,
+	})
+
+	Go(ctx, func(ctx Context) {
+		if _, err := sent.Get(ctx); err != nil {
+			f.Set(*new(TResp), fmt.Errorf("sending request: %w", err))
+			return
+		}
+
+		timerCtx, cancelTimer := WithCancel(ctx)
+		defer cancelTimer()
+
+		cases := []SelectCase{
+			Receive(replies, func(ctx Context, r response[TResp], ok bool) {
+				if r.Error != "" {
+					f.Set(r.Result, errors.New(r.Error))
+					return
+				}
+
+				f.Set(r.Result, nil)
+			}),
+			sync.Receive(ctx.Done(), func(ctx sync.Context, _ struct{}, ok bool) {
+				f.Set(*new(TResp), Canceled)
+			}),
+		}
+
+		if options.Timeout > 0 {
+			cases = append(cases, Await(ScheduleTimer(timerCtx, options.Timeout), func(ctx Context, t Future[struct{}]) {
+				if _, err := t.Get(ctx); err != nil {
+					f.Set(*new(TResp), err)
+					return
+				}
+
+				f.Set(*new(TResp), ErrRequestTimeout)
+			}))
+		}
+
+		Select(ctx, cases...)
+	})
+
+	return f
+}
+
+// Reply answers a request received from NewRequestChannel. If err is not nil, the future returned by
+// SendRequest resolves with an error with the same message.
+func Reply[TReq, TResp any](ctx Context, req Request[TReq], result TResp, err error) Future[any] {
+	r := response[TResp]{Result: result}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	return SignalWorkflow(ctx, req.ReplyTo, replySignalName(req.ID), r)
+}
@@ -4,35 +4,24 @@ import (
 	"math"
 	"time"
 
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/sync"
 )
 
-type RetryOptions struct {
-	// Maximum number of times to retry
-	MaxAttempts int
-
-	// Time to wait before first retry
-	FirstRetryInterval time.Duration
-
-	// Maximum delay for any individual retry attempt
-	MaxRetryInterval time.Duration
-
-	// Coeffecient for calculation the next retry delay
-	BackoffCoefficient float64
-
-	// Timeout after which retries are aborted
-	RetryTimeout time.Duration
-}
+type RetryOptions = core.RetryOptions
 
 var DefaultRetryOptions = RetryOptions{
 	MaxAttempts:        3,
 	BackoffCoefficient: 1,
 }
 
-func withRetries[T any](ctx sync.Context, retryOptions RetryOptions, fn func(ctx sync.Context) Future[T]) Future[T] {
+// withRetries calls fn until it succeeds or the retry options are exhausted. fn is passed the attempt number,
+// starting at 1.
+func withRetries[T any](ctx sync.Context, retryOptions RetryOptions, fn func(ctx sync.Context, attempt int) Future[T]) Future[T] {
 	if retryOptions.MaxAttempts <= 1 {
 		// Short-circuit if we don't need to retry
-		return fn(ctx)
+		return fn(ctx, 1)
 	}
 
 	r := sync.NewFuture[T]()
@@ -48,18 +37,24 @@ func withRetries[T any](ctx sync.Context, retryOptions RetryOptions, fn func(ctx
 			retryExpiration = firstAttempt.Add(retryOptions.RetryTimeout)
 		}
 
-		for attempt := 0; attempt < retryOptions.MaxAttempts; attempt++ {
+		attempt := 0
+		for ; attempt < retryOptions.MaxAttempts; attempt++ {
 			if !retryExpiration.IsZero() && Now(ctx).After(retryExpiration) {
 				// Reached maximum retry time, abort retries
 				break
 			}
 
-			result, err = fn(ctx).Get(ctx)
+			result, err = fn(ctx, attempt+1).Get(ctx)
 			if err != nil {
 				if err == sync.Canceled {
 					break
 				}
 
+				if !isRetryable(retryOptions, err) {
+					attempt++
+					break
+				}
+
 				backoffDuration := time.Duration(float64(retryOptions.FirstRetryInterval) * math.Pow(retryOptions.BackoffCoefficient, float64(attempt)))
 				if retryOptions.MaxRetryInterval > 0 {
 					backoffDuration = time.Duration(math.Min(float64(backoffDuration), float64(retryOptions.MaxRetryInterval)))
@@ -76,8 +71,34 @@ func withRetries[T any](ctx sync.Context, retryOptions RetryOptions, fn func(ctx
 			break
 		}
 
+		if f, ok := err.(*history.Failure); ok {
+			// Record retry information on a copy of the final failure
+			rf := *f
+			rf.Attempt = attempt
+			rf.FirstAttemptAt = &firstAttempt
+			err = &rf
+		}
+
 		r.Set(result, err)
 	})
 
 	return r
 }
+
+// isRetryable returns whether the given error is retried with the retry options
+func isRetryable(retryOptions RetryOptions, err error) bool {
+	if len(retryOptions.NonRetryableErrorTypes) == 0 {
+		return true
+	}
+
+	f, ok := err.(*history.Failure)
+	for ; ok && f != nil; f = f.Cause {
+		for _, t := range retryOptions.NonRetryableErrorTypes {
+			if f.Type == t {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,151 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/session"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// ErrSessionFailed is returned for activities executed in a session if the worker hosting the session stopped or
+// is gone, or if the session has been completed
+var ErrSessionFailed = errors.New("session failed")
+
+// Session pins the activities executed in it to a single worker. Create a session with CreateSession, and execute
+// activities in it by setting the Session activity option.
+type Session struct {
+	id string
+
+	// queue is the activity queue of the worker hosting the session
+	queue string
+
+	keepalive Future[any]
+	failure   sync.SettableFuture[struct{}]
+	completed bool
+}
+
+// Failed returns true if the worker hosting the session stopped or is gone. Activities executed in a failed session
+// return ErrSessionFailed.
+func (s *Session) Failed() bool {
+	return s.failure.(sync.FutureInternal[struct{}]).Ready()
+}
+
+func (s *Session) fail() {
+	if !s.Failed() {
+		s.failure.Set(struct{}{}, nil)
+	}
+}
+
+// CreateSession starts a session on one of the workers with sessions enabled, see the EnableSessions worker option.
+// The session lasts until it's completed with CompleteSession. If the worker hosting the session stops, or is gone
+// and another worker with sessions enabled picks up the session, the session fails.
+func CreateSession(ctx Context) Future[*Session] {
+	f := sync.NewFuture[*Session]()
+
+	if ctx.Err() != nil {
+		f.Set(nil, ctx.Err())
+		return f
+	}
+
+	// Derive the session ID from the current execution, it's stable when replaying
+	wfState := workflowstate.WorkflowState(ctx)
+	instance := wfState.Instance()
+	id := fmt.Sprintf("%s:%d", instance.ExecutionID, wfState.GetNextScheduleEventID())
+
+	s := &Session{
+		id:      id,
+		failure: sync.NewFuture[struct{}](),
+	}
+
+	hosts := NewSignalChannel[string](ctx, session.SignalName(id))
+
+	var a *session.Activities
+	s.keepalive = ExecuteActivity[any](ctx, ActivityOptions{
+		RetryOptions: RetryOptions{MaxAttempts: 1},
+		Queue:        session.Queue,
+	}, a.KeepWorkflowSession, instance.InstanceID, id)
+
+	Go(ctx, func(ctx Context) {
+		// Wait for a worker to pick up the session
+		Select(ctx,
+			Receive(hosts, func(ctx Context, host string, ok bool) {
+				s.queue = host
+				f.Set(s, nil)
+			}),
+			Await(s.keepalive, func(ctx Context, k Future[any]) {
+				_, err := k.Get(ctx)
+				if err == nil {
+					err = errors.New("session ended before it started")
+				}
+
+				s.fail()
+				f.Set(nil, fmt.Errorf("%w: %v", ErrSessionFailed, err))
+			}),
+		)
+
+		for !s.keepalive.(sync.FutureInternal[any]).Ready() {
+			Select(ctx,
+				Receive(hosts, func(ctx Context, host string, ok bool) {
+					// The session was picked up again by another worker, the original host is gone. Remember the new
+					// host to end the session there.
+					s.queue = host
+					s.fail()
+				}),
+				Await(s.keepalive, func(ctx Context, k Future[any]) {
+					if !s.completed {
+						s.fail()
+					}
+				}),
+			)
+		}
+	})
+
+	return f
+}
+
+// CompleteSession ends the session. Activities cannot be executed in the session afterwards.
+func CompleteSession(ctx Context, s *Session) Future[any] {
+	s.completed = true
+
+	if s.keepalive.(sync.FutureInternal[any]).Ready() {
+		f := sync.NewFuture[any]()
+		f.Set(nil, nil)
+		return f
+	}
+
+	var a *session.Activities
+	end := ExecuteActivity[any](ctx, ActivityOptions{
+		RetryOptions: DefaultRetryOptions,
+		Queue:        s.queue,
+	}, a.EndWorkflowSession, s.id)
+
+	if s.Failed() {
+		// Don't wait for a host that might be gone
+		f := sync.NewFuture[any]()
+		f.Set(nil, nil)
+		return f
+	}
+
+	return end
+}
+
+// withSession returns a future resolving with the result of f, or with ErrSessionFailed if the session fails first
+func withSession[T any](ctx Context, s *Session, f Future[T]) Future[T] {
+	r := sync.NewFuture[T]()
+
+	Go(ctx, func(ctx Context) {
+		Select(ctx,
+			Await(f, func(ctx Context, f Future[T]) {
+				v, err := f.Get(ctx)
+				r.Set(v, err)
+			}),
+			Await[struct{}](s.failure, func(ctx Context, _ Future[struct{}]) {
+				r.Set(*new(T), ErrSessionFailed)
+			}),
+		)
+	})
+
+	return r
+}
@@ -2,11 +2,13 @@ package workflow
 
 import (
 	"github.com/cschleiden/go-workflows/internal/command"
-	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
 )
 
+// SideEffect executes f once and records its result in the history of the workflow instance. When the workflow is
+// replayed, f is not executed again and the future resolves with the recorded result. Use it for short
+// non-deterministic operations, like generating random numbers, and activities for anything else.
 func SideEffect[TResult any](ctx sync.Context, f func(ctx sync.Context) TResult) Future[TResult] {
 	future := sync.NewFuture[TResult]()
 
@@ -21,7 +23,7 @@ func SideEffect[TResult any](ctx sync.Context, f func(ctx sync.Context) TResult)
 	if Replaying(ctx) {
 		// There has to be a message in the history with the result, create a new future
 		// and block on it
-		wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(future))
+		wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(wfState.Converter(), future))
 		return future
 	}
 
@@ -29,7 +31,7 @@ func SideEffect[TResult any](ctx sync.Context, f func(ctx sync.Context) TResult)
 	r := f(ctx)
 
 	// Create command to add it to the history
-	payload, err := converter.DefaultConverter.To(r)
+	payload, err := wfState.Converter().To(r)
 	if err != nil {
 		future.Set(*new(TResult), err)
 	}
@@ -1,10 +1,34 @@
 package workflow
 
 import (
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/signals"
+	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
 )
 
-func NewSignalChannel[T any](ctx Context, name string) Channel[T] {
+// NewSignalChannel returns a channel receiving the signals with the given name sent to the current workflow
+// instance. Signals are buffered until they are received, use Select to wait for a signal together with
+// futures, timers, and other channels.
+func NewSignalChannel[T any](ctx Context, name string) ReceiveChannel[T] {
 	wfState := workflowstate.WorkflowState(ctx)
 	return workflowstate.GetSignalChannel[T](ctx, wfState, name)
 }
+
+// SignalWorkflow sends a signal to another workflow instance. The returned future resolves once the signal
+// has been delivered, or with an error if it could not be delivered, for example because the instance does
+// not exist.
+func SignalWorkflow[T any](ctx Context, instanceID, name string, arg T) Future[any] {
+	wfState := workflowstate.WorkflowState(ctx)
+
+	input, err := wfState.Converter().To(arg)
+	if err != nil {
+		f := sync.NewFuture[any]()
+		f.Set(nil, fmt.Errorf("converting signal argument: %w", err))
+		return f
+	}
+
+	var a *signals.Activities
+	return ExecuteActivity[any](ctx, DefaultActivityOptions, a.DeliverWorkflowSignal, instanceID, name, input)
+}
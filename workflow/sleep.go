@@ -6,7 +6,14 @@ import (
 	"github.com/cschleiden/go-workflows/internal/sync"
 )
 
-func Sleep(ctx sync.Context, d time.Duration) error {
-	_, err := ScheduleTimer(ctx, d).Get(ctx)
+// Sleep blocks the workflow for the given duration
+func Sleep(ctx sync.Context, d time.Duration, opts ...TimerOption) error {
+	_, err := ScheduleTimer(ctx, d, opts...).Get(ctx)
+	return err
+}
+
+// SleepUntil blocks the workflow until the given time
+func SleepUntil(ctx sync.Context, t time.Time, opts ...TimerOption) error {
+	_, err := ScheduleTimerAt(ctx, t, opts...).Get(ctx)
 	return err
 }
@@ -0,0 +1,69 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/state"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// GetState reads the value stored for the key in the given key space. State is shared between all workflow
+// instances using the same backend, and is persisted by the backend. The read is recorded in the history like
+// an activity, so replaying the workflow returns the same value. If the key does not exist, the future resolves
+// with the zero value of T.
+func GetState[T any](ctx Context, space, key string) Future[T] {
+	f := sync.NewFuture[T]()
+
+	var a *state.Activities
+	r := ExecuteActivity[state.Value](ctx, DefaultActivityOptions, a.GetWorkflowState, space, key)
+
+	Go(ctx, func(ctx Context) {
+		v, err := r.Get(ctx)
+		if err != nil || !v.Found {
+			f.Set(*new(T), err)
+			return
+		}
+
+		var t T
+		if err := workflowstate.WorkflowState(ctx).Converter().From(v.Value, &t); err != nil {
+			f.Set(*new(T), fmt.Errorf("converting state value: %w", err))
+			return
+		}
+
+		f.Set(t, nil)
+	})
+
+	return f
+}
+
+// SetState stores the value for the key in the given key space, replacing any existing value. See GetState.
+func SetState[T any](ctx Context, space, key string, value T) Future[any] {
+	wfState := workflowstate.WorkflowState(ctx)
+
+	v, err := wfState.Converter().To(value)
+	if err != nil {
+		f := sync.NewFuture[any]()
+		f.Set(nil, fmt.Errorf("converting state value: %w", err))
+		return f
+	}
+
+	var a *state.Activities
+	return ExecuteActivity[any](ctx, DefaultActivityOptions, a.SetWorkflowState, space, key, v)
+}
+
+// DeleteState removes the key from the given key space. See GetState.
+func DeleteState(ctx Context, space, key string) Future[any] {
+	var a *state.Activities
+	return ExecuteActivity[any](ctx, DefaultActivityOptions, a.DeleteWorkflowState, space, key)
+}
+
+// IncrementState atomically adds delta to the counter stored for the key in the given key space, and resolves
+// with the new value. Keys that do not exist start at 0. Counters can be read with GetState[int64].
+//
+// Incrementing is retried like an activity, so a retry after a failure that occurred after the counter was
+// updated increments it again.
+func IncrementState(ctx Context, space, key string, delta int64) Future[int64] {
+	var a *state.Activities
+	return ExecuteActivity[int64](ctx, DefaultActivityOptions, a.IncrementWorkflowState, space, key, delta)
+}
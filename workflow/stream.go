@@ -0,0 +1,22 @@
+package workflow
+
+import (
+	"github.com/cschleiden/go-workflows/internal/stream"
+)
+
+// Stream references a large activity result stored in chunks, see activity.NewStream
+type Stream = stream.Stream
+
+// ReadStreamChunk reads the chunk with the given index of the stream. Every chunk is read with an activity and
+// recorded in the history, so workflows should only read the chunks they need and leave consuming the whole stream
+// to activities or clients.
+func ReadStreamChunk(ctx Context, s Stream, index int) Future[[]byte] {
+	var a *stream.Activities
+	return ExecuteActivity[[]byte](ctx, DefaultActivityOptions, a.ReadWorkflowStreamChunk, s, index)
+}
+
+// DeleteStream removes the stream from the backend. Streams are kept until they are deleted.
+func DeleteStream(ctx Context, s Stream) Future[any] {
+	var a *stream.Activities
+	return ExecuteActivity[any](ctx, DefaultActivityOptions, a.DeleteWorkflowStream, s)
+}
@@ -5,8 +5,9 @@ import (
 
 	a "github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/command"
-	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/fn"
+	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
 )
@@ -15,18 +16,47 @@ type SubWorkflowOptions struct {
 	InstanceID string
 
 	RetryOptions RetryOptions
+
+	// ParentClosePolicy determines what happens to the sub-workflow if it's still running when the parent
+	// workflow finishes, fails, is canceled, or continues as new. The default abandons the sub-workflow.
+	ParentClosePolicy ParentClosePolicy
+
+	// Queue is the workflow queue to place the sub-workflow on, see the WorkflowQueues worker option. If empty,
+	// the sub-workflow is placed on the queue of its parent.
+	Queue string
 }
 
+// ParentClosePolicy determines what happens to a running sub-workflow when its parent finishes
+type ParentClosePolicy = core.ParentClosePolicy
+
+const (
+	// ParentClosePolicyAbandon leaves the sub-workflow running
+	ParentClosePolicyAbandon = core.ParentClosePolicyAbandon
+
+	// ParentClosePolicyRequestCancel cancels the sub-workflow, which can still clean up before it finishes
+	ParentClosePolicyRequestCancel = core.ParentClosePolicyRequestCancel
+
+	// ParentClosePolicyTerminate terminates the sub-workflow right away, without running any more of its code
+	ParentClosePolicyTerminate = core.ParentClosePolicyTerminate
+)
+
 var DefaultSubWorkflowOptions = SubWorkflowOptions{
 	RetryOptions: DefaultRetryOptions,
 }
 
 func CreateSubWorkflowInstance[TResult any](ctx sync.Context, options SubWorkflowOptions, workflow interface{}, args ...interface{}) Future[TResult] {
-	return withRetries(ctx, options.RetryOptions, func(ctx sync.Context) Future[TResult] {
+	return withRetries(ctx, options.RetryOptions, func(ctx sync.Context, _ int) Future[TResult] {
 		return createSubWorkflowInstance[TResult](ctx, options, workflow, args...)
 	})
 }
 
+// CreateSubWorkflowInstanceFn creates a new sub-workflow instance of the given workflow. In contrast to
+// CreateSubWorkflowInstance, the input and result types are inferred from the workflow function, so mismatched
+// arguments are caught at compile time. Use a struct to pass multiple values.
+func CreateSubWorkflowInstanceFn[TIn, TOut any](ctx sync.Context, options SubWorkflowOptions, workflow func(Context, TIn) (TOut, error), input TIn) Future[TOut] {
+	return CreateSubWorkflowInstance[TOut](ctx, options, workflow, input)
+}
+
 func createSubWorkflowInstance[TResult any](ctx sync.Context, options SubWorkflowOptions, workflow interface{}, args ...interface{}) Future[TResult] {
 	f := sync.NewFuture[TResult]()
 
@@ -36,24 +66,35 @@ func createSubWorkflowInstance[TResult any](ctx sync.Context, options SubWorkflo
 		return f
 	}
 
-	name := fn.Name(workflow)
+	wfState := workflowstate.WorkflowState(ctx)
 
-	inputs, err := a.ArgsToInputs(converter.DefaultConverter, args...)
+	inputs, err := a.ArgsToInputs(wfState.Converter(), args...)
 	if err != nil {
 		f.Set(*new(TResult), fmt.Errorf("converting subworkflow input: %w", err))
 		return f
 	}
 
+	return scheduleSubWorkflow(ctx, f, options, fn.Name(workflow), inputs)
+}
+
+// scheduleSubWorkflow starts the sub-workflow with the given name and encoded inputs, and resolves f with its result
+func scheduleSubWorkflow[TResult any](ctx sync.Context, f sync.SettableFuture[TResult], options SubWorkflowOptions, name string, inputs []payload.Payload) Future[TResult] {
 	wfState := workflowstate.WorkflowState(ctx)
+
 	scheduleEventID := wfState.GetNextScheduleEventID()
-	cmd := command.NewScheduleSubWorkflowCommand(scheduleEventID, wfState.Instance(), options.InstanceID, name, inputs)
+	cmd := command.NewScheduleSubWorkflowCommand(scheduleEventID, wfState.Instance(), options.InstanceID, name, inputs, options.ParentClosePolicy, options.Queue)
 	wfState.AddCommand(&cmd)
 
-	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(f))
+	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(wfState.Converter(), f))
 
 	// Check if the channel is cancelable
 	if c, cancelable := ctx.Done().(sync.CancelChannel); cancelable {
 		c.AddReceiveCallback(func(v struct{}, ok bool) {
+			if fi, ok := f.(sync.FutureInternal[TResult]); ok && fi.Ready() {
+				// The sub-workflow has already finished, nothing to cancel
+				return
+			}
+
 			if cmd.State == command.CommandState_Committed {
 				// The command is committed, that means the sub-workflow is already started. Create and add a cancel command
 				// to stop the sub-workflow execution.
@@ -25,7 +25,7 @@ func Await[T any](f Future[T], handler func(Context, Future[T])) SelectCase {
 	})
 }
 
-func Receive[T any](c Channel[T], handler func(ctx Context, v T, ok bool)) SelectCase {
+func Receive[T any, C ReceiveChannel[T]](c C, handler func(ctx Context, v T, ok bool)) SelectCase {
 	return sync.Receive[T](c, func(ctx sync.Context, v T, ok bool) {
 		handler(ctx, v, ok)
 	})
@@ -0,0 +1,26 @@
+package workflow
+
+import (
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// AddTags adds the given tags to the current workflow instance. Backends implementing backend.InstanceTagIndex
+// allow looking up, canceling, and signaling instances by their tags.
+func AddTags(ctx sync.Context, tags ...string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	wfState := workflowstate.WorkflowState(ctx)
+	scheduleEventID := wfState.GetNextScheduleEventID()
+
+	if Replaying(ctx) {
+		// Tags have already been recorded in the history
+		return
+	}
+
+	cmd := command.NewAddTagsCommand(scheduleEventID, tags)
+	wfState.AddCommand(&cmd)
+}
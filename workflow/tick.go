@@ -0,0 +1,28 @@
+package workflow
+
+import (
+	"time"
+)
+
+// Tick returns a channel that receives the current workflow time every interval. Ticks are based on durable
+// timers, so they are deterministic and safe across replays.
+//
+// Like time.Ticker, ticks are dropped if the workflow does not receive them fast enough. The ticker stops and
+// the channel is closed when ctx is canceled.
+func Tick(ctx Context, interval time.Duration) Channel[time.Time] {
+	c := NewBufferedChannel[time.Time](1)
+
+	Go(ctx, func(ctx Context) {
+		defer c.Close()
+
+		for {
+			if err := Sleep(ctx, interval); err != nil {
+				return
+			}
+
+			c.SendNonblocking(ctx, Now(ctx))
+		}
+	})
+
+	return c
+}
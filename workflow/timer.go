@@ -1,6 +1,8 @@
 package workflow
 
 import (
+	"encoding/binary"
+	"hash/fnv"
 	"time"
 
 	"github.com/cschleiden/go-workflows/internal/command"
@@ -8,7 +10,32 @@ import (
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
 )
 
-func ScheduleTimer(ctx Context, delay time.Duration) Future[struct{}] {
+type timerOptions struct {
+	jitter time.Duration
+}
+
+type TimerOption func(*timerOptions)
+
+// WithJitter delays the timer by a random duration between 0 and max. This spreads out timers of
+// instances that were started at the same time, so they don't all fire at the same instant. The jitter
+// is derived from the workflow instance and the timer, so it's stable across replays.
+func WithJitter(max time.Duration) TimerOption {
+	return func(o *timerOptions) {
+		o.jitter = max
+	}
+}
+
+// ScheduleTimer schedules a timer that fires after the given delay
+func ScheduleTimer(ctx Context, delay time.Duration, opts ...TimerOption) Future[struct{}] {
+	return scheduleTimer(ctx, Now(ctx).Add(delay), opts...)
+}
+
+// ScheduleTimerAt schedules a timer that fires at the given time
+func ScheduleTimerAt(ctx Context, at time.Time, opts ...TimerOption) Future[struct{}] {
+	return scheduleTimer(ctx, at, opts...)
+}
+
+func scheduleTimer(ctx Context, at time.Time, opts ...TimerOption) Future[struct{}] {
 	f := sync.NewFuture[struct{}]()
 
 	// If the context is already canceled, return immediately.
@@ -20,10 +47,20 @@ func ScheduleTimer(ctx Context, delay time.Duration) Future[struct{}] {
 	wfState := workflowstate.WorkflowState(ctx)
 
 	scheduleEventID := wfState.GetNextScheduleEventID()
-	timerCmd := command.NewScheduleTimerCommand(scheduleEventID, Now(ctx).Add(delay))
+
+	var options timerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.jitter > 0 {
+		at = at.Add(timerJitter(wfState.Instance().InstanceID, scheduleEventID, options.jitter))
+	}
+
+	timerCmd := command.NewScheduleTimerCommand(scheduleEventID, at)
 	wfState.AddCommand(&timerCmd)
 
-	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(f))
+	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(wfState.Converter(), f))
 
 	// Check if the context is cancelable
 	if c, cancelable := ctx.Done().(sync.CancelChannel); cancelable {
@@ -39,13 +76,14 @@ func ScheduleTimer(ctx Context, delay time.Duration) Future[struct{}] {
 			} else {
 				// Remove command that would've scheduled the timer
 				wfState.RemoveCommand(&timerCmd)
+			}
 
-				// Remove the timer future from the workflow state and mark it as canceled if it hasn't already fired
-				if fi, ok := f.(sync.FutureInternal[struct{}]); ok {
-					if !fi.Ready() {
-						wfState.RemoveFuture(scheduleEventID)
-						f.Set(v, sync.Canceled)
-					}
+			// Remove the timer future from the workflow state and mark it as canceled if it hasn't already fired.
+			// Any fired event delivered later for this timer is ignored.
+			if fi, ok := f.(sync.FutureInternal[struct{}]); ok {
+				if !fi.Ready() {
+					wfState.RemoveFuture(scheduleEventID)
+					f.Set(v, sync.Canceled)
 				}
 			}
 		})
@@ -53,3 +91,12 @@ func ScheduleTimer(ctx Context, delay time.Duration) Future[struct{}] {
 
 	return f
 }
+
+// timerJitter returns a deterministic duration in [0, max) for the given instance and timer
+func timerJitter(instanceID string, scheduleEventID int64, max time.Duration) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(instanceID))
+	binary.Write(h, binary.LittleEndian, scheduleEventID)
+
+	return time.Duration(h.Sum64() % uint64(max))
+}
@@ -0,0 +1,17 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TimerJitter_IsDeterministic(t *testing.T) {
+	j1 := timerJitter("instance", 1, time.Minute)
+	j2 := timerJitter("instance", 1, time.Minute)
+	require.Equal(t, j1, j2)
+
+	require.GreaterOrEqual(t, j1, time.Duration(0))
+	require.Less(t, j1, time.Minute)
+}
@@ -0,0 +1,31 @@
+package workflow
+
+import "github.com/cschleiden/go-workflows/internal/converter"
+
+// Tuple2 holds the results of an activity or workflow returning two values in addition to an error, for
+// example:
+//
+//	r, err := workflow.ExecuteActivity[workflow.Tuple2[int, string]](ctx, options, Activity).Get(ctx)
+type Tuple2[T1, T2 any] struct {
+	V1 T1
+	V2 T2
+}
+
+var _ converter.MultiValue = (*Tuple2[int, int])(nil)
+
+func (t *Tuple2[T1, T2]) ValuePointers() []interface{} {
+	return []interface{}{&t.V1, &t.V2}
+}
+
+// Tuple3 holds the results of an activity or workflow returning three values in addition to an error
+type Tuple3[T1, T2, T3 any] struct {
+	V1 T1
+	V2 T2
+	V3 T3
+}
+
+var _ converter.MultiValue = (*Tuple3[int, int, int])(nil)
+
+func (t *Tuple3[T1, T2, T3]) ValuePointers() []interface{} {
+	return []interface{}{&t.V1, &t.V2, &t.V3}
+}
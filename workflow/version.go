@@ -0,0 +1,88 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+// Version is a version of workflow code, see GetVersion
+type Version int
+
+// DefaultVersion is the version of workflow code executed before GetVersion was called for a change
+const DefaultVersion Version = -1
+
+// VersionMarker is the name of the markers recording the versions returned by GetVersion
+const VersionMarker = workflowstate.VersionMarker
+
+var ErrVersionNotSupported = errors.New("workflow version not supported")
+
+// GetVersion returns the version of the workflow code to execute for the change with the given ID. This allows
+// to change workflow code while instances are in flight:
+//
+//	v, err := workflow.GetVersion(ctx, "use-new-activity", workflow.DefaultVersion, 1)
+//	if err != nil {
+//		return err
+//	}
+//
+//	if v == workflow.DefaultVersion {
+//		// Old code path
+//	} else {
+//		// New code path
+//	}
+//
+// The first time an instance reaches the call, maxSupported is recorded as a marker in its history and returned.
+// When the history is replayed, the recorded version is returned, and DefaultVersion for histories recorded before
+// the call was added. Once no instances use an old version anymore, raise minSupported and remove its code path.
+// Returns an error matching ErrVersionNotSupported if the version of the instance is no longer supported.
+func GetVersion(ctx sync.Context, changeID string, minSupported, maxSupported Version) (Version, error) {
+	wfState := workflowstate.WorkflowState(ctx)
+
+	version, err := getVersion(ctx, wfState, changeID, maxSupported)
+	if err != nil {
+		return DefaultVersion, err
+	}
+
+	if version < minSupported || version > maxSupported {
+		return version, fmt.Errorf("%w: version %d of change %q is not between %d and %d",
+			ErrVersionNotSupported, version, changeID, minSupported, maxSupported)
+	}
+
+	return version, nil
+}
+
+func getVersion(ctx sync.Context, wfState *workflowstate.WfState, changeID string, maxSupported Version) (Version, error) {
+	// Workflow code has asked for this change before
+	if v, ok := wfState.Version(changeID); ok {
+		return Version(v), nil
+	}
+
+	if v, ok := wfState.RecordedVersion(changeID); ok {
+		// Keep schedule event IDs in line with the execution that recorded the marker
+		wfState.GetNextScheduleEventID()
+		wfState.SetVersion(changeID, v)
+
+		return Version(v), nil
+	}
+
+	if Replaying(ctx) {
+		// The history has been recorded by workflow code before the change
+		wfState.SetVersion(changeID, int(DefaultVersion))
+
+		return DefaultVersion, nil
+	}
+
+	payload, err := wfState.Converter().To(workflowstate.VersionMarkerData{ChangeID: changeID, Version: int(maxSupported)})
+	if err != nil {
+		return DefaultVersion, fmt.Errorf("converting version marker: %w", err)
+	}
+
+	cmd := command.NewRecordMarkerCommand(wfState.GetNextScheduleEventID(), VersionMarker, payload)
+	wfState.AddCommand(&cmd)
+	wfState.SetVersion(changeID, int(maxSupported))
+
+	return maxSupported, nil
+}
@@ -1,10 +1,24 @@
 package workflow
 
 import (
+	"github.com/cschleiden/go-workflows/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
 )
 
 type (
 	Instance = core.WorkflowInstance
 	Workflow = interface{}
+
+	// Failure is the error returned for failed activities, sub-workflows, and workflows. Use errors.As to
+	// access the structured failure information.
+	Failure = history.Failure
+
+	// Error is an error with an application defined type and optional details, see NewError
+	Error = history.ApplicationError
+
+	// DynamicWorkflow handles workflows without a specific registration. It receives the name of the started
+	// workflow and its inputs encoded by the converter, and returns the encoded result. Like any workflow, it has
+	// to be deterministic. See Worker.RegisterDynamicWorkflow.
+	DynamicWorkflow = func(ctx Context, name string, inputs []converter.Payload) (converter.Payload, error)
 )